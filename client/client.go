@@ -6,41 +6,120 @@ package client
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"io"
+	"os"
+	"path"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/lasthyphen/djtx-tester/pkg/color"
+	"github.com/lasthyphen/djtx-tester/pkg/discovery"
 	"github.com/lasthyphen/djtx-tester/pkg/logutil"
+	"github.com/lasthyphen/djtx-tester/pkg/tracing"
 	"github.com/lasthyphen/djtx-tester/rpcpb"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	_ "google.golang.org/grpc/health"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/resolver/manual"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 )
 
 type Config struct {
-	LogLevel    string
-	Endpoint    string
-	DialTimeout time.Duration
+	LogLevel string
+	// Endpoint is the server host and port,
+	// or its Unix domain socket (e.g., "unix:///tmp/djtx.sock"),
+	// or a DNS name resolving to several servers (e.g., "dns:///runner:8080").
+	// A comma-separated list is dialed as Endpoints.
+	// If empty, the endpoint of the local server is discovered
+	// (ref. "pkg/discovery").
+	Endpoint string
+	// Endpoints are the host and port of several servers (e.g., behind
+	// a VIP), balanced by the LoadBalancingPolicy. Overrides the Endpoint.
+	Endpoints []string
+	// LoadBalancingPolicy is "pick_first" (the default), which fails over to
+	// the next server once the current one is unreachable, or "round_robin",
+	// which spreads the requests over the servers reporting healthy.
+	LoadBalancingPolicy string
+	DialTimeout         time.Duration
+	// Token authenticates the tenant on a shared server, if not empty.
+	Token string
+	// RequestTimeout is the deadline of the (non-streaming) requests whose
+	// context has none, so that a dead server does not block forever.
+	// No deadline is added if zero.
+	RequestTimeout time.Duration
+	// MethodTimeouts overrides the RequestTimeout by method name
+	// (e.g., "Start" or "RestartNodes").
+	MethodTimeouts map[string]time.Duration
 }
 
 type Client interface {
 	Ping(ctx context.Context) (*rpcpb.PingResponse, error)
 	Start(ctx context.Context, execPath string, opts ...OpOption) (*rpcpb.StartResponse, error)
-	Health(ctx context.Context) (*rpcpb.HealthResponse, error)
+	Health(ctx context.Context, opts ...OpOption) (*rpcpb.HealthResponse, error)
+	WaitNodesHealthy(ctx context.Context, names []string, timeout time.Duration) (*rpcpb.WaitNodesHealthyResponse, error)
 	URIs(ctx context.Context) ([]string, error)
 	Status(ctx context.Context) (*rpcpb.StatusResponse, error)
-	StreamStatus(ctx context.Context, pushInterval time.Duration) (<-chan *rpcpb.ClusterInfo, error)
+	StreamStatus(ctx context.Context, pushInterval time.Duration, opts ...OpOption) (<-chan *rpcpb.ClusterInfo, error)
 	RemoveNode(ctx context.Context, name string) (*rpcpb.RemoveNodeResponse, error)
 	RestartNode(ctx context.Context, name string, execPath string, opts ...OpOption) (*rpcpb.RestartNodeResponse, error)
-	Stop(ctx context.Context) (*rpcpb.StopResponse, error)
+	RemoveNodes(ctx context.Context, names []string, opts ...OpOption) (*rpcpb.RemoveNodesResponse, error)
+	RestartNodes(ctx context.Context, names []string, execPath string, opts ...OpOption) (*rpcpb.RestartNodesResponse, error)
+	Stop(ctx context.Context, opts ...OpOption) (*rpcpb.StopResponse, error)
+	AbortStart(ctx context.Context) (*rpcpb.AbortStartResponse, error)
+	CheckBinary(ctx context.Context, execPath string, opts ...OpOption) (*rpcpb.CheckBinaryResponse, error)
+	CreateChainIPC(ctx context.Context, blockchainID string, opts ...OpOption) (*rpcpb.CreateChainIPCResponse, error)
+	RemoveChainIPC(ctx context.Context, blockchainID string, opts ...OpOption) (*rpcpb.RemoveChainIPCResponse, error)
+	ListChainIPCs(ctx context.Context, opts ...OpOption) (*rpcpb.ListChainIPCsResponse, error)
+	TailChainIPC(ctx context.Context, name string, blockchainID string, feed rpcpb.ChainIPCFeed, opts ...OpOption) (<-chan *rpcpb.ChainIPCEvent, error)
+	AttachConsole(ctx context.Context, name string, opts ...OpOption) (<-chan *rpcpb.AttachConsoleResponse, error)
+	InjectFault(ctx context.Context, name string, mode rpcpb.CrashMode) (*rpcpb.InjectFaultResponse, error)
+	SetDiskFault(ctx context.Context, name string, fault *rpcpb.DiskFault) (*rpcpb.SetDiskFaultResponse, error)
+	GetValidators(ctx context.Context, subnetID string, opts ...OpOption) (*rpcpb.GetValidatorsResponse, error)
+	SetTime(ctx context.Context, t time.Time) (*rpcpb.SetTimeResponse, error)
+	AdvanceTime(ctx context.Context, d time.Duration) (*rpcpb.AdvanceTimeResponse, error)
+	RenderTopology(ctx context.Context, format rpcpb.TopologyFormat) (*rpcpb.RenderTopologyResponse, error)
+	CreateBlockchain(ctx context.Context, subnetID string, vmName string, genesis []byte, opts ...OpOption) (*rpcpb.CreateBlockchainResponse, error)
+	BlockTraffic(ctx context.Context, nodeA string, nodeB string, direction rpcpb.TrafficDirection) (*rpcpb.BlockTrafficResponse, error)
+	UnblockTraffic(ctx context.Context, nodeA string, nodeB string, direction rpcpb.TrafficDirection) (*rpcpb.UnblockTrafficResponse, error)
+	UploadFile(ctx context.Context, localPath string, remotePath string) (*rpcpb.UploadFileResponse, error)
+	DownloadLogs(ctx context.Context, name string, since time.Time) (io.ReadCloser, error)
+	GetNodeConfig(ctx context.Context, name string) (*rpcpb.GetNodeConfigResponse, error)
+	RotateNodeKey(ctx context.Context, name string) (*rpcpb.RotateNodeKeyResponse, error)
+	AddNode(ctx context.Context, name string, execPath string, opts ...OpOption) (*rpcpb.AddNodeResponse, error)
+	EstimateResources(ctx context.Context, execPath string, opts ...OpOption) (*rpcpb.EstimateResourcesResponse, error)
+	SearchLogs(ctx context.Context, query string, opts ...OpOption) (*rpcpb.SearchLogsResponse, error)
+	GetSyncProgress(ctx context.Context, name string) (*rpcpb.GetSyncProgressResponse, error)
+	GCNow(ctx context.Context, opts ...OpOption) (*rpcpb.GCNowResponse, error)
+	GetRunManifest(ctx context.Context) (*rpcpb.GetRunManifestResponse, error)
+	StopValidatingSubnet(ctx context.Context, name string, subnetID string) (*rpcpb.StopValidatingSubnetResponse, error)
+	GetGenesis(ctx context.Context) (*rpcpb.GetGenesisResponse, error)
+	GetNetworkParams(ctx context.Context) (*rpcpb.GetNetworkParamsResponse, error)
 	Close() error
 }
 
+var (
+	ErrServerUnreachable          = errors.New("server unreachable")
+	ErrInvalidLoadBalancingPolicy = errors.New("invalid load balancing policy")
+)
+
+const (
+	LoadBalancingPickFirst  = "pick_first"
+	LoadBalancingRoundRobin = "round_robin"
+)
+
+// scheme of the resolver of the Endpoints
+const endpointsScheme = "network-runner"
+
 type client struct {
 	cfg Config
 
@@ -62,17 +141,57 @@ func New(cfg Config) (Client, error) {
 	}
 	_ = zap.ReplaceGlobals(logger)
 
-	color.Outf("{{blue}}dialing endpoint %q{{/}}\n", cfg.Endpoint)
+	policy := cfg.LoadBalancingPolicy
+	if policy == "" {
+		policy = LoadBalancingPickFirst
+	}
+	if policy != LoadBalancingPickFirst && policy != LoadBalancingRoundRobin {
+		return nil, fmt.Errorf("%w: %q (expected %s or %s)", ErrInvalidLoadBalancingPolicy, policy, LoadBalancingPickFirst, LoadBalancingRoundRobin)
+	}
+	endpoints := cfg.Endpoints
+	if len(endpoints) == 0 {
+		if cfg.Endpoint == "" {
+			cfg.Endpoint = discovery.Endpoint()
+		}
+		endpoints = strings.Split(cfg.Endpoint, ",")
+	}
+	cfg.Endpoint = strings.Join(endpoints, ",")
+	// to stderr, so the machine-readable outputs on stdout are not mixed up
+	color.Errf("{{blue}}dialing endpoint %q{{/}}\n", cfg.Endpoint)
 	ctx, cancel := context.WithTimeout(context.Background(), cfg.DialTimeout)
-	conn, err := grpc.DialContext(
-		ctx,
-		cfg.Endpoint,
+	dialOpts := []grpc.DialOption{
 		grpc.WithBlock(),
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
-	)
+		// no-op unless a tracer provider is set (e.g., by "tracing.Init")
+		grpc.WithChainUnaryInterceptor(
+			tracing.UnaryClientInterceptor(),
+			timeoutInterceptor(cfg.RequestTimeout, cfg.MethodTimeouts),
+		),
+		grpc.WithStreamInterceptor(tracing.StreamClientInterceptor()),
+	}
+	if cfg.Token != "" {
+		dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(tokenCreds(cfg.Token)))
+	}
+	// the health checks (of the server, not the network) only apply to
+	// "round_robin", as "pick_first" does not check its connection
+	dialOpts = append(dialOpts, grpc.WithDefaultServiceConfig(fmt.Sprintf(
+		`{"loadBalancingConfig":[{%q:{}}],"healthCheckConfig":{"serviceName":""}}`, policy,
+	)))
+	target := endpoints[0]
+	if len(endpoints) > 1 {
+		r := manual.NewBuilderWithScheme(endpointsScheme)
+		addrs := make([]resolver.Address, 0, len(endpoints))
+		for _, ep := range endpoints {
+			addrs = append(addrs, resolver.Address{Addr: strings.TrimSpace(ep)})
+		}
+		r.InitialState(resolver.State{Addresses: addrs})
+		dialOpts = append(dialOpts, grpc.WithResolvers(r))
+		target = endpointsScheme + ":///"
+	}
+	conn, err := grpc.DialContext(ctx, target, dialOpts...)
 	cancel()
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %q: %v", ErrServerUnreachable, cfg.Endpoint, err)
 	}
 
 	return &client{
@@ -97,15 +216,144 @@ func (c *client) Start(ctx context.Context, execPath string, opts ...OpOption) (
 	ret.applyOpts(opts)
 
 	zap.L().Info("start")
-	return c.controlc.Start(ctx, &rpcpb.StartRequest{
+	return c.controlc.Start(ret.withIdempotencyKey(ctx), ret.startRequest(execPath))
+}
+
+// EstimateResources returns the expected footprint of the network that
+// "Start" would create with the options, and whether the server host can
+// run it.
+func (c *client) EstimateResources(ctx context.Context, execPath string, opts ...OpOption) (*rpcpb.EstimateResourcesResponse, error) {
+	ret := &Op{}
+	ret.applyOpts(opts)
+
+	zap.L().Info("estimate resources")
+	return c.controlc.EstimateResources(ctx, &rpcpb.EstimateResourcesRequest{Spec: ret.startRequest(execPath)})
+}
+
+// SearchLogs returns the node log lines containing the query (matching it,
+// with "WithRegex"), of the nodes of "WithNodeNames" (all if not set).
+func (c *client) SearchLogs(ctx context.Context, query string, opts ...OpOption) (*rpcpb.SearchLogsResponse, error) {
+	ret := &Op{}
+	ret.applyOpts(opts)
+
+	req := &rpcpb.SearchLogsRequest{
+		Query:      query,
+		Regex:      ret.regex,
+		NodeNames:  ret.nodeNames,
+		MaxMatches: ret.maxMatches,
+	}
+	if !ret.since.IsZero() {
+		since := ret.since.Format(time.RFC3339)
+		req.Since = &since
+	}
+	if !ret.until.IsZero() {
+		until := ret.until.Format(time.RFC3339)
+		req.Until = &until
+	}
+	zap.L().Info("search logs", zap.String("query", query))
+	return c.controlc.SearchLogs(ctx, req)
+}
+
+// GCNow removes the data directories of the stopped networks, and the
+// rotated node logs, by the server retention, unless overridden.
+func (c *client) GCNow(ctx context.Context, opts ...OpOption) (*rpcpb.GCNowResponse, error) {
+	ret := &Op{}
+	ret.applyOpts(opts)
+
+	req := &rpcpb.GCNowRequest{
+		MaxBytes: ret.gcMaxBytes,
+		DryRun:   ret.dryRun,
+	}
+	if ret.gcMaxAge != nil {
+		maxAge := ret.gcMaxAge.String()
+		req.MaxAge = &maxAge
+	}
+	zap.L().Info("gc now", zap.Bool("dryRun", ret.dryRun))
+	return c.controlc.GCNow(ctx, req)
+}
+
+// startRequest builds the start request of the options.
+func (op *Op) startRequest(execPath string) *rpcpb.StartRequest {
+	req := &rpcpb.StartRequest{
 		ExecPath:           execPath,
-		WhitelistedSubnets: &ret.whitelistedSubnets,
-	})
+		WhitelistedSubnets: &op.whitelistedSubnets,
+	}
+	if op.preset != "" {
+		req.Preset = &op.preset
+	}
+	if op.template != "" {
+		req.Template = &op.template
+		req.TemplateParams = op.templateParams
+	}
+	if op.numNodes > 0 {
+		req.NumNodes = &op.numNodes
+	}
+	if op.globalNodeConfig != "" {
+		req.GlobalNodeConfig = &op.globalNodeConfig
+	}
+	if op.cChainConfig != "" {
+		req.CChainConfig = &op.cChainConfig
+	}
+	if op.artifactsPath != "" {
+		req.ArtifactsPath = &op.artifactsPath
+	}
+	req.StakingParams = op.stakingParams
+	req.LogSinks = op.logSinks
+	if op.basePort > 0 {
+		req.BasePort = &op.basePort
+	}
+	req.GenesisBalances = op.genesisBalances
+	req.ResourceLimits = op.resourceLimits
+	req.NodeResourceLimits = op.nodeResourceLimits
+	req.DatabaseConfig = op.databaseConfig
+	req.NodeDatabaseConfigs = op.nodeDatabases
+	req.NodeLogLevels = op.nodeLogLevels
+	req.BootstrapBeacons = op.bootstrapBeacons
+	req.StaticPeers = op.staticPeers
+	req.NodeBootstrapPeers = op.nodeBootstrapPeers
+	req.LivenessMonitor = op.livenessMonitor
+	req.HealthPolling = op.healthPolling
+	req.SeedActions = op.seedActions
+	req.SkipResourceCheck = op.skipResourceCheck
+	req.StateSyncNodes = op.stateSyncNodes
+	req.NodeSidecars = op.nodeSidecars
+	req.NodeArchival = op.nodeArchival
+	req.MaxUnhealthyDuration = op.maxUnhealthy
+	req.LaunchInterval = op.launchInterval
+	req.DiskFaultNodes = op.diskFaultNodes
+	req.DiskFaultDeviceBytes = op.diskBytes
+	req.CheckpointInterval = op.checkpointInterval
+	req.CheckpointRetention = op.checkpointKeep
+	if op.resumeCheckpoint != "" {
+		req.ResumeCheckpoint = &op.resumeCheckpoint
+	}
+	if op.pluginDir != "" {
+		req.PluginDir = &op.pluginDir
+	}
+	if op.buildDir != "" {
+		req.BuildDir = &op.buildDir
+	}
+	return req
 }
 
-func (c *client) Health(ctx context.Context) (*rpcpb.HealthResponse, error) {
-	zap.L().Info("health")
-	return c.controlc.Health(ctx, &rpcpb.HealthRequest{})
+func (c *client) Health(ctx context.Context, opts ...OpOption) (*rpcpb.HealthResponse, error) {
+	ret := &Op{}
+	ret.applyOpts(opts)
+
+	zap.L().Info("health", zap.Bool("noWait", ret.noWait))
+	return c.controlc.Health(ctx, &rpcpb.HealthRequest{NoWait: ret.noWait})
+}
+
+// WaitNodesHealthy waits for the named nodes only to report healthy, within
+// the timeout (the server default if zero).
+func (c *client) WaitNodesHealthy(ctx context.Context, names []string, timeout time.Duration) (*rpcpb.WaitNodesHealthyResponse, error) {
+	zap.L().Info("wait nodes healthy", zap.Strings("names", names), zap.Duration("timeout", timeout))
+	req := &rpcpb.WaitNodesHealthyRequest{Names: names}
+	if timeout > 0 {
+		s := timeout.String()
+		req.Timeout = &s
+	}
+	return c.controlc.WaitNodesHealthy(ctx, req)
 }
 
 func (c *client) URIs(ctx context.Context) ([]string, error) {
@@ -122,9 +370,14 @@ func (c *client) Status(ctx context.Context) (*rpcpb.StatusResponse, error) {
 	return c.controlc.Status(ctx, &rpcpb.StatusRequest{})
 }
 
-func (c *client) StreamStatus(ctx context.Context, pushInterval time.Duration) (<-chan *rpcpb.ClusterInfo, error) {
+func (c *client) StreamStatus(ctx context.Context, pushInterval time.Duration, opts ...OpOption) (<-chan *rpcpb.ClusterInfo, error) {
+	ret := &Op{}
+	ret.applyOpts(opts)
+
 	stream, err := c.controlc.StreamStatus(ctx, &rpcpb.StreamStatusRequest{
-		PushInterval: int64(pushInterval),
+		PushInterval:     int64(pushInterval),
+		SendOnlyOnChange: ret.sendOnlyOnChange,
+		Delta:            ret.statusDelta,
 	})
 	if err != nil {
 		return nil, err
@@ -137,6 +390,9 @@ func (c *client) StreamStatus(ctx context.Context, pushInterval time.Duration) (
 			close(ch)
 		}()
 		zap.L().Info("start receive routine")
+
+		// full cluster info, with the deltas applied
+		var info *rpcpb.ClusterInfo
 		for {
 			select {
 			case <-ctx.Done():
@@ -147,10 +403,16 @@ func (c *client) StreamStatus(ctx context.Context, pushInterval time.Duration) (
 			}
 
 			// receive data from stream
-			msg := new(rpcpb.StatusResponse)
+			msg := new(rpcpb.StreamStatusResponse)
 			err := stream.RecvMsg(msg)
 			if err == nil {
-				ch <- msg.GetClusterInfo()
+				info = applyStatusDelta(info, msg)
+				// the delta is applied in place, so send a copy
+				var cur *rpcpb.ClusterInfo
+				if info != nil {
+					cur = proto.Clone(info).(*rpcpb.ClusterInfo)
+				}
+				ch <- cur
 				continue
 			}
 
@@ -169,9 +431,54 @@ func (c *client) StreamStatus(ctx context.Context, pushInterval time.Duration) (
 	return ch, nil
 }
 
-func (c *client) Stop(ctx context.Context) (*rpcpb.StopResponse, error) {
+// applyStatusDelta returns the full cluster info after the status response,
+// updating "info" in place for delta responses.
+func applyStatusDelta(info *rpcpb.ClusterInfo, resp *rpcpb.StreamStatusResponse) *rpcpb.ClusterInfo {
+	if !resp.Delta || info == nil {
+		return resp.GetClusterInfo()
+	}
+	delta := resp.GetClusterInfo()
+	for _, f := range resp.ChangedFields {
+		switch f {
+		case "node_names":
+			info.NodeNames = delta.NodeNames
+		case "pid":
+			info.Pid = delta.Pid
+		case "root_data_dir":
+			info.RootDataDir = delta.RootDataDir
+		case "healthy":
+			info.Healthy = delta.Healthy
+		case "state":
+			info.State = delta.State
+		case "error":
+			info.Error = delta.Error
+		default:
+			zap.L().Warn("ignoring unknown cluster info field in status delta", zap.String("field", f))
+		}
+	}
+	for name, ni := range delta.GetNodeInfos() {
+		if info.NodeInfos == nil {
+			info.NodeInfos = make(map[string]*rpcpb.NodeInfo)
+		}
+		info.NodeInfos[name] = ni
+	}
+	for _, name := range resp.RemovedNodes {
+		delete(info.NodeInfos, name)
+	}
+	return info
+}
+
+func (c *client) Stop(ctx context.Context, opts ...OpOption) (*rpcpb.StopResponse, error) {
+	ret := &Op{}
+	ret.applyOpts(opts)
+
 	zap.L().Info("stop")
-	return c.controlc.Stop(ctx, &rpcpb.StopRequest{})
+	return c.controlc.Stop(ret.withIdempotencyKey(ctx), &rpcpb.StopRequest{})
+}
+
+func (c *client) AbortStart(ctx context.Context) (*rpcpb.AbortStartResponse, error) {
+	zap.L().Info("abort start")
+	return c.controlc.AbortStart(ctx, &rpcpb.AbortStartRequest{})
 }
 
 func (c *client) RemoveNode(ctx context.Context, name string) (*rpcpb.RemoveNodeResponse, error) {
@@ -193,59 +500,999 @@ func (c *client) RestartNode(ctx context.Context, name string, execPath string,
 	})
 }
 
-func (c *client) Close() error {
-	c.closeOnce.Do(func() {
-		close(c.closed)
+func (c *client) RemoveNodes(ctx context.Context, names []string, opts ...OpOption) (*rpcpb.RemoveNodesResponse, error) {
+	ret := &Op{}
+	ret.applyOpts(opts)
+
+	zap.L().Info("remove nodes", zap.Strings("names", names))
+	return c.controlc.RemoveNodes(ctx, &rpcpb.RemoveNodesRequest{Names: names, Parallelism: ret.parallelism})
+}
+
+func (c *client) RestartNodes(ctx context.Context, names []string, execPath string, opts ...OpOption) (*rpcpb.RestartNodesResponse, error) {
+	ret := &Op{}
+	ret.applyOpts(opts)
+
+	zap.L().Info("restart nodes", zap.Strings("names", names))
+	return c.controlc.RestartNodes(ctx, &rpcpb.RestartNodesRequest{
+		Names: names,
+		StartRequest: &rpcpb.StartRequest{
+			ExecPath:           execPath,
+			WhitelistedSubnets: &ret.whitelistedSubnets,
+		},
+		Parallelism: ret.parallelism,
 	})
-	return c.conn.Close()
 }
 
-type Op struct {
-	whitelistedSubnets string
+func (c *client) CheckBinary(ctx context.Context, execPath string, opts ...OpOption) (*rpcpb.CheckBinaryResponse, error) {
+	ret := &Op{}
+	ret.applyOpts(opts)
+
+	req := &rpcpb.CheckBinaryRequest{ExecPath: execPath}
+	if ret.pluginDir != "" {
+		req.PluginDir = &ret.pluginDir
+	}
+
+	zap.L().Info("check binary", zap.String("execPath", execPath))
+	return c.controlc.CheckBinary(ctx, req)
 }
 
-type OpOption func(*Op)
+func (c *client) CreateChainIPC(ctx context.Context, blockchainID string, opts ...OpOption) (*rpcpb.CreateChainIPCResponse, error) {
+	ret := &Op{}
+	ret.applyOpts(opts)
 
-func (op *Op) applyOpts(opts []OpOption) {
-	for _, opt := range opts {
-		opt(op)
+	req := &rpcpb.CreateChainIPCRequest{BlockchainId: blockchainID}
+	if ret.nodeName != "" {
+		req.NodeName = &ret.nodeName
 	}
+
+	zap.L().Info("create chain IPC", zap.String("blockchainID", blockchainID))
+	return c.controlc.CreateChainIPC(ctx, req)
 }
 
-func WithWhitelistedSubnets(whitelistedSubnets string) OpOption {
-	return func(op *Op) {
-		op.whitelistedSubnets = whitelistedSubnets
+func (c *client) ListChainIPCs(ctx context.Context, opts ...OpOption) (*rpcpb.ListChainIPCsResponse, error) {
+	ret := &Op{}
+	ret.applyOpts(opts)
+
+	req := &rpcpb.ListChainIPCsRequest{}
+	if ret.nodeName != "" {
+		req.NodeName = &ret.nodeName
 	}
+
+	zap.L().Info("list chain IPCs", zap.String("nodeName", ret.nodeName))
+	return c.controlc.ListChainIPCs(ctx, req)
 }
 
-func isClientCanceled(ctxErr error, err error) bool {
-	if ctxErr != nil {
-		return true
+// TailChainIPC streams the events of the chain IPC feed of the node (both
+// feeds if unspecified), until the context is done or the server ends the
+// stream (e.g., the chain is unpublished). "WithIncludeBytes" adds the
+// container bytes to the events.
+func (c *client) TailChainIPC(ctx context.Context, name string, blockchainID string, feed rpcpb.ChainIPCFeed, opts ...OpOption) (<-chan *rpcpb.ChainIPCEvent, error) {
+	ret := &Op{}
+	ret.applyOpts(opts)
+
+	stream, err := c.controlc.TailChainIPC(ctx, &rpcpb.TailChainIPCRequest{
+		NodeName:     name,
+		BlockchainId: blockchainID,
+		Feed:         feed,
+		IncludeBytes: ret.includeBytes,
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	ev, ok := status.FromError(err)
-	if !ok {
-		return false
+	ch := make(chan *rpcpb.ChainIPCEvent, 1)
+	go func() {
+		defer func() {
+			zap.L().Debug("closing stream send", zap.Error(stream.CloseSend()))
+			close(ch)
+		}()
+		zap.L().Info("start chain IPC receive routine", zap.String("name", name), zap.String("blockchainID", blockchainID))
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-c.closed:
+				return
+			default:
+			}
+
+			msg, err := stream.Recv()
+			if err == nil {
+				ch <- msg.Event
+				continue
+			}
+
+			if errors.Is(err, io.EOF) {
+				zap.L().Debug("received EOF from server; returning to close the stream")
+				return
+			}
+			if isClientCanceled(stream.Context().Err(), err) {
+				zap.L().Warn("failed to receive chain IPC events due to client cancellation", zap.Error(err))
+			} else {
+				zap.L().Warn("failed to receive chain IPC events", zap.Error(err))
+			}
+			return
+		}
+	}()
+	return ch, nil
+}
+
+func (c *client) RemoveChainIPC(ctx context.Context, blockchainID string, opts ...OpOption) (*rpcpb.RemoveChainIPCResponse, error) {
+	ret := &Op{}
+	ret.applyOpts(opts)
+
+	req := &rpcpb.RemoveChainIPCRequest{BlockchainId: blockchainID}
+	if ret.nodeName != "" {
+		req.NodeName = &ret.nodeName
 	}
 
-	switch ev.Code() {
-	case codes.Canceled, codes.DeadlineExceeded:
-		// client-side context cancel or deadline exceeded
-		// "rpc error: code = Canceled desc = context canceled"
-		// "rpc error: code = DeadlineExceeded desc = context deadline exceeded"
-		return true
-	case codes.Unavailable:
-		msg := ev.Message()
-		// client-side context cancel or deadline exceeded with TLS ("http2.errClientDisconnected")
-		// "rpc error: code = Unavailable desc = client disconnected"
-		if msg == "client disconnected" {
-			return true
+	zap.L().Info("remove chain IPC", zap.String("blockchainID", blockchainID))
+	return c.controlc.RemoveChainIPC(ctx, req)
+}
+
+func (c *client) GetValidators(ctx context.Context, subnetID string, opts ...OpOption) (*rpcpb.GetValidatorsResponse, error) {
+	ret := &Op{}
+	ret.applyOpts(opts)
+
+	req := &rpcpb.GetValidatorsRequest{SubnetId: subnetID}
+	if ret.nodeName != "" {
+		req.NodeName = &ret.nodeName
+	}
+
+	zap.L().Info("get validators", zap.String("subnetID", subnetID))
+	return c.controlc.GetValidators(ctx, req)
+}
+
+func (c *client) InjectFault(ctx context.Context, name string, mode rpcpb.CrashMode) (*rpcpb.InjectFaultResponse, error) {
+	zap.L().Info("inject fault", zap.String("name", name), zap.String("crashMode", mode.String()))
+	return c.controlc.InjectFault(ctx, &rpcpb.InjectFaultRequest{NodeName: name, CrashMode: mode})
+}
+
+// SetDiskFault sets the fault of the disk device of the node, or clears it
+// if nil.
+func (c *client) SetDiskFault(ctx context.Context, name string, fault *rpcpb.DiskFault) (*rpcpb.SetDiskFaultResponse, error) {
+	zap.L().Info("set disk fault", zap.String("name", name), zap.String("mode", fault.GetMode().String()))
+	return c.controlc.SetDiskFault(ctx, &rpcpb.SetDiskFaultRequest{NodeName: name, Fault: fault})
+}
+
+func (c *client) SetTime(ctx context.Context, t time.Time) (*rpcpb.SetTimeResponse, error) {
+	zap.L().Info("set time", zap.Time("time", t))
+	return c.controlc.SetTime(ctx, &rpcpb.SetTimeRequest{Time: t.Unix()})
+}
+
+func (c *client) AdvanceTime(ctx context.Context, d time.Duration) (*rpcpb.AdvanceTimeResponse, error) {
+	zap.L().Info("advance time", zap.Duration("duration", d))
+	return c.controlc.AdvanceTime(ctx, &rpcpb.AdvanceTimeRequest{Duration: d.String()})
+}
+
+func (c *client) RenderTopology(ctx context.Context, format rpcpb.TopologyFormat) (*rpcpb.RenderTopologyResponse, error) {
+	zap.L().Info("render topology", zap.String("format", format.String()))
+	return c.controlc.RenderTopology(ctx, &rpcpb.RenderTopologyRequest{Format: format})
+}
+
+func (c *client) CreateBlockchain(ctx context.Context, subnetID string, vmName string, genesis []byte, opts ...OpOption) (*rpcpb.CreateBlockchainResponse, error) {
+	ret := &Op{}
+	ret.applyOpts(opts)
+
+	req := &rpcpb.CreateBlockchainRequest{
+		SubnetId: subnetID,
+		VmName:   vmName,
+		Genesis:  genesis,
+	}
+	if ret.chainName != "" {
+		req.ChainName = &ret.chainName
+	}
+
+	zap.L().Info("create blockchain", zap.String("subnetID", subnetID), zap.String("vmName", vmName))
+	return c.controlc.CreateBlockchain(ctx, req)
+}
+
+func (c *client) BlockTraffic(ctx context.Context, nodeA string, nodeB string, direction rpcpb.TrafficDirection) (*rpcpb.BlockTrafficResponse, error) {
+	zap.L().Info("block traffic", zap.String("nodeA", nodeA), zap.String("nodeB", nodeB), zap.String("direction", direction.String()))
+	return c.controlc.BlockTraffic(ctx, &rpcpb.BlockTrafficRequest{NodeA: nodeA, NodeB: nodeB, Direction: direction})
+}
+
+func (c *client) UnblockTraffic(ctx context.Context, nodeA string, nodeB string, direction rpcpb.TrafficDirection) (*rpcpb.UnblockTrafficResponse, error) {
+	zap.L().Info("unblock traffic", zap.String("nodeA", nodeA), zap.String("nodeB", nodeB), zap.String("direction", direction.String()))
+	return c.controlc.UnblockTraffic(ctx, &rpcpb.UnblockTrafficRequest{NodeA: nodeA, NodeB: nodeB, Direction: direction})
+}
+
+func (c *client) GetNodeConfig(ctx context.Context, name string) (*rpcpb.GetNodeConfigResponse, error) {
+	zap.L().Info("get node config", zap.String("name", name))
+	return c.controlc.GetNodeConfig(ctx, &rpcpb.GetNodeConfigRequest{Name: name})
+}
+
+func (c *client) AddNode(ctx context.Context, name string, execPath string, opts ...OpOption) (*rpcpb.AddNodeResponse, error) {
+	ret := &Op{}
+	ret.applyOpts(opts)
+
+	zap.L().Info("add node", zap.String("name", name), zap.Bool("observer", ret.observer), zap.Bool("stateSync", ret.stateSync))
+	return c.controlc.AddNode(ret.withIdempotencyKey(ctx), &rpcpb.AddNodeRequest{
+		Name:      name,
+		ExecPath:  execPath,
+		Observer:  ret.observer,
+		StateSync: ret.stateSync,
+		Archival:  ret.archival,
+	})
+}
+
+func (c *client) GetSyncProgress(ctx context.Context, name string) (*rpcpb.GetSyncProgressResponse, error) {
+	zap.L().Debug("get sync progress", zap.String("name", name))
+	return c.controlc.GetSyncProgress(ctx, &rpcpb.GetSyncProgressRequest{Name: name})
+}
+
+func (c *client) GetRunManifest(ctx context.Context) (*rpcpb.GetRunManifestResponse, error) {
+	zap.L().Debug("get run manifest")
+	return c.controlc.GetRunManifest(ctx, &rpcpb.GetRunManifestRequest{})
+}
+
+func (c *client) StopValidatingSubnet(ctx context.Context, name string, subnetID string) (*rpcpb.StopValidatingSubnetResponse, error) {
+	zap.L().Info("stop validating subnet", zap.String("name", name), zap.String("subnetID", subnetID))
+	return c.controlc.StopValidatingSubnet(ctx, &rpcpb.StopValidatingSubnetRequest{Name: name, SubnetId: subnetID})
+}
+
+func (c *client) GetGenesis(ctx context.Context) (*rpcpb.GetGenesisResponse, error) {
+	zap.L().Debug("get genesis")
+	return c.controlc.GetGenesis(ctx, &rpcpb.GetGenesisRequest{})
+}
+
+func (c *client) GetNetworkParams(ctx context.Context) (*rpcpb.GetNetworkParamsResponse, error) {
+	zap.L().Debug("get network params")
+	return c.controlc.GetNetworkParams(ctx, &rpcpb.GetNetworkParamsRequest{})
+}
+
+func (c *client) RotateNodeKey(ctx context.Context, name string) (*rpcpb.RotateNodeKeyResponse, error) {
+	zap.L().Info("rotate node key", zap.String("name", name))
+	return c.controlc.RotateNodeKey(ctx, &rpcpb.RotateNodeKeyRequest{Name: name})
+}
+
+// size of the streamed upload chunks
+const uploadChunkSize = 1024 * 1024
+
+// UploadFile uploads the local file to the path relative to the upload
+// directory of the server, with the same permissions. The returned absolute
+// path on the server host can be used as an exec path or a genesis path.
+func (c *client) UploadFile(ctx context.Context, localPath string, remotePath string) (*rpcpb.UploadFileResponse, error) {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	// the checksum is sent first, for the server to verify the upload
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	zap.L().Info("upload file", zap.String("localPath", localPath), zap.String("remotePath", remotePath), zap.Int64("size", fi.Size()))
+	stream, err := c.controlc.UploadFile(ctx)
+	if err != nil {
+		return nil, err
+	}
+	req := &rpcpb.UploadFileRequest{
+		Path:   remotePath,
+		Mode:   uint32(fi.Mode().Perm()),
+		Sha256: hex.EncodeToString(h.Sum(nil)),
+	}
+	buf := make([]byte, uploadChunkSize)
+	for {
+		n, err := f.Read(buf)
+		if n > 0 || req.Path != "" {
+			req.Chunk = buf[:n]
+			if err := stream.Send(req); err != nil {
+				return nil, err
+			}
+			req = &rpcpb.UploadFileRequest{}
 		}
-		// "grpc/transport.ClientTransport.CloseStream" on canceled streams
-		// "rpc error: code = Unavailable desc = stream error: stream ID 21; CANCEL")
-		if strings.HasPrefix(msg, "stream error: ") && strings.HasSuffix(msg, "; CANCEL") {
-			return true
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
 		}
 	}
-	return false
+	return stream.CloseAndRecv()
+}
+
+// DownloadLogs returns the gzipped tarball of the log files of the node
+// modified since the time (all of them if zero), as streamed by the server.
+// Closing the reader cancels the download.
+func (c *client) DownloadLogs(ctx context.Context, name string, since time.Time) (io.ReadCloser, error) {
+	req := &rpcpb.DownloadLogsRequest{NodeName: name}
+	if !since.IsZero() {
+		s := since.Format(time.RFC3339)
+		req.Since = &s
+	}
+	zap.L().Info("download logs", zap.String("name", name))
+	ctx, cancel := context.WithCancel(ctx)
+	stream, err := c.controlc.DownloadLogs(ctx, req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		for {
+			msg, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				pw.Close()
+				return
+			}
+			if err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+			if _, err := pw.Write(msg.Chunk); err != nil {
+				// the reader was closed
+				return
+			}
+		}
+	}()
+	return &logsReader{PipeReader: pr, cancel: cancel}, nil
+}
+
+type logsReader struct {
+	*io.PipeReader
+	cancel context.CancelFunc
+}
+
+func (r *logsReader) Close() error {
+	r.cancel()
+	return r.PipeReader.Close()
+}
+
+func (c *client) AttachConsole(ctx context.Context, name string, opts ...OpOption) (<-chan *rpcpb.AttachConsoleResponse, error) {
+	ret := &Op{}
+	ret.applyOpts(opts)
+
+	stream, err := c.controlc.AttachConsole(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := stream.Send(&rpcpb.AttachConsoleRequest{NodeName: name}); err != nil {
+		return nil, err
+	}
+	// the stream is only sent to (and closed) by one routine
+	if ret.stdin != nil {
+		go func() {
+			buf := make([]byte, 4096)
+			for {
+				n, err := ret.stdin.Read(buf)
+				if n > 0 {
+					if serr := stream.Send(&rpcpb.AttachConsoleRequest{Stdin: append([]byte(nil), buf[:n]...)}); serr != nil {
+						zap.L().Debug("failed to send console stdin", zap.Error(serr))
+						return
+					}
+				}
+				if err != nil {
+					if !errors.Is(err, io.EOF) {
+						zap.L().Warn("failed to read console stdin", zap.Error(err))
+					}
+					zap.L().Debug("closing stream send", zap.Error(stream.CloseSend()))
+					return
+				}
+			}
+		}()
+	}
+
+	ch := make(chan *rpcpb.AttachConsoleResponse, 1)
+	go func() {
+		defer func() {
+			if ret.stdin == nil {
+				zap.L().Debug("closing stream send", zap.Error(stream.CloseSend()))
+			}
+			close(ch)
+		}()
+		zap.L().Info("start console receive routine", zap.String("name", name))
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-c.closed:
+				return
+			default:
+			}
+
+			msg, err := stream.Recv()
+			if err == nil {
+				ch <- msg
+				continue
+			}
+
+			if errors.Is(err, io.EOF) {
+				zap.L().Debug("received EOF from server; returning to close the stream")
+				return
+			}
+			if isClientCanceled(stream.Context().Err(), err) {
+				zap.L().Warn("failed to receive console output due to client cancellation", zap.Error(err))
+			} else {
+				zap.L().Warn("failed to receive console output", zap.Error(err))
+			}
+			return
+		}
+	}()
+	return ch, nil
+}
+
+func (c *client) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+	})
+	return c.conn.Close()
+}
+
+// tokenCreds sends the tenant token with every request.
+type tokenCreds string
+
+func (t tokenCreds) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(t)}, nil
+}
+
+// the server is served without TLS
+func (tokenCreds) RequireTransportSecurity() bool { return false }
+
+type Op struct {
+	whitelistedSubnets string
+	pluginDir          string
+	buildDir           string
+	nodeName           string
+	preset             string
+	template           string
+	templateParams     map[string]string
+	numNodes           uint32
+	globalNodeConfig   string
+	cChainConfig       string
+	artifactsPath      string
+	stakingParams      *rpcpb.StakingParams
+	logSinks           []*rpcpb.LogSink
+	basePort           uint32
+	genesisBalances    []*rpcpb.GenesisBalance
+	resourceLimits     *rpcpb.ResourceLimits
+	nodeResourceLimits map[string]*rpcpb.ResourceLimits
+	databaseConfig     *rpcpb.DatabaseConfig
+	nodeDatabases      map[string]*rpcpb.DatabaseConfig
+	nodeLogLevels      map[string]*rpcpb.NodeLogLevels
+	bootstrapBeacons   []string
+	staticPeers        bool
+	nodeBootstrapPeers map[string]*rpcpb.NodeBootstrapPeers
+	chainName          string
+	parallelism        uint32
+	sendOnlyOnChange   bool
+	statusDelta        bool
+	noWait             bool
+	observer           bool
+	livenessMonitor    *rpcpb.LivenessMonitor
+	healthPolling      *rpcpb.HealthPolling
+	seedActions        []*rpcpb.SeedAction
+	idempotencyKey     string
+	skipResourceCheck  bool
+	regex              bool
+	nodeNames          []string
+	since              time.Time
+	until              time.Time
+	maxMatches         uint32
+	stateSync          bool
+	stateSyncNodes     []string
+	nodeSidecars       map[string]*rpcpb.NodeSidecars
+	nodeArchival       map[string]bool
+	maxUnhealthy       *string
+	launchInterval     *string
+	diskFaultNodes     []string
+	diskBytes          *uint64
+	checkpointInterval *string
+	checkpointKeep     *uint32
+	resumeCheckpoint   string
+	includeBytes       bool
+	archival           *bool
+	gcMaxAge           *time.Duration
+	gcMaxBytes         *uint64
+	dryRun             bool
+	stdin              io.Reader
+}
+
+type OpOption func(*Op)
+
+func (op *Op) applyOpts(opts []OpOption) {
+	for _, opt := range opts {
+		opt(op)
+	}
+}
+
+func WithWhitelistedSubnets(whitelistedSubnets string) OpOption {
+	return func(op *Op) {
+		op.whitelistedSubnets = whitelistedSubnets
+	}
+}
+
+// WithPluginDir sets the VM plugin directory of the nodes on start, or to
+// check, defaults to the "plugins" directory next to the binary.
+func WithPluginDir(pluginDir string) OpOption {
+	return func(op *Op) {
+		op.pluginDir = pluginDir
+	}
+}
+
+// WithBuildDir sets the build directory of the nodes on start.
+func WithBuildDir(buildDir string) OpOption {
+	return func(op *Op) {
+		op.buildDir = buildDir
+	}
+}
+
+// WithNodeName limits the operation to a single node.
+func WithNodeName(nodeName string) OpOption {
+	return func(op *Op) {
+		op.nodeName = nodeName
+	}
+}
+
+// WithPreset selects a named set of start options on the server
+// (e.g., "minimal-1node"), overridden by the other options.
+func WithPreset(preset string) OpOption {
+	return func(op *Op) {
+		op.preset = preset
+	}
+}
+
+// WithTemplate starts from a start template of the server, with its param
+// values (e.g., {"numValidators": "3"}), overridden by the other options.
+func WithTemplate(name string, params map[string]string) OpOption {
+	return func(op *Op) {
+		op.template = name
+		op.templateParams = params
+	}
+}
+
+// WithNumNodes sets the number of nodes to start.
+func WithNumNodes(numNodes uint32) OpOption {
+	return func(op *Op) {
+		op.numNodes = numNodes
+	}
+}
+
+// WithGlobalNodeConfig sets a JSON object merged into every node config.
+func WithGlobalNodeConfig(nodeConfig string) OpOption {
+	return func(op *Op) {
+		op.globalNodeConfig = nodeConfig
+	}
+}
+
+// WithCChainConfig sets the JSON C-chain config of every node.
+func WithCChainConfig(cChainConfig string) OpOption {
+	return func(op *Op) {
+		op.cChainConfig = cChainConfig
+	}
+}
+
+// WithCollectArtifactsOnStop makes the server archive all node logs and
+// configs into a gzipped tarball at the given path (relative to the
+// artifacts directory of the server), when the network stops or fails to
+// start, and next to it when a node crashes.
+func WithCollectArtifactsOnStop(path string) OpOption {
+	return func(op *Op) {
+		op.artifactsPath = path
+	}
+}
+
+// WithStakingParams sets the network-wide staking parameters
+// (e.g., min stake amounts and durations, uptime requirement).
+func WithStakingParams(p *rpcpb.StakingParams) OpOption {
+	return func(op *Op) {
+		op.stakingParams = p
+	}
+}
+
+// WithLogSinks sets the destinations of the node outputs,
+// replacing the default colored stdout of the server.
+func WithLogSinks(sinks ...*rpcpb.LogSink) OpOption {
+	return func(op *Op) {
+		op.logSinks = sinks
+	}
+}
+
+// WithGenesisBalances adds the balances (in nAVAX) to the genesis,
+// for "X-", "P-", or "0x" (C-chain) addresses.
+func WithGenesisBalances(balances ...*rpcpb.GenesisBalance) OpOption {
+	return func(op *Op) {
+		op.genesisBalances = balances
+	}
+}
+
+// WithResourceLimits limits the CPU and memory of every node (Linux only).
+func WithResourceLimits(l *rpcpb.ResourceLimits) OpOption {
+	return func(op *Op) {
+		op.resourceLimits = l
+	}
+}
+
+// WithNodeResourceLimits limits the CPU and memory of the nodes by name
+// (e.g., "node1"), overriding the limits of "WithResourceLimits".
+func WithNodeResourceLimits(limits map[string]*rpcpb.ResourceLimits) OpOption {
+	return func(op *Op) {
+		op.nodeResourceLimits = limits
+	}
+}
+
+// WithDatabaseConfig sets the database of every node (e.g., the LevelDB
+// cache sizes), validated by the server.
+func WithDatabaseConfig(db *rpcpb.DatabaseConfig) OpOption {
+	return func(op *Op) {
+		op.databaseConfig = db
+	}
+}
+
+// WithNodeDatabaseConfigs sets the databases of the nodes by name (e.g.,
+// "node1"), overriding the fields of "WithDatabaseConfig".
+func WithNodeDatabaseConfigs(dbs map[string]*rpcpb.DatabaseConfig) OpOption {
+	return func(op *Op) {
+		op.nodeDatabases = dbs
+	}
+}
+
+// WithNodeLogLevels sets the log levels of the nodes by name (e.g., "node1"),
+// overriding the log level of the network.
+func WithNodeLogLevels(levels map[string]*rpcpb.NodeLogLevels) OpOption {
+	return func(op *Op) {
+		op.nodeLogLevels = levels
+	}
+}
+
+// WithLivenessMonitor reports the chains that stop advancing, once the
+// network is healthy, in the cluster info.
+func WithLivenessMonitor(m *rpcpb.LivenessMonitor) OpOption {
+	return func(op *Op) {
+		op.livenessMonitor = m
+	}
+}
+
+// WithHealthPolling sets the backoff of the node health polls, while
+// waiting for the network to be healthy.
+func WithHealthPolling(p *rpcpb.HealthPolling) OpOption {
+	return func(op *Op) {
+		op.healthPolling = p
+	}
+}
+
+// WithSeedActions creates the test fixtures (e.g., a deployed contract) with
+// the genesis key before the network is reported healthy. The results are
+// in the cluster info "SeedResults".
+func WithSeedActions(actions ...*rpcpb.SeedAction) OpOption {
+	return func(op *Op) {
+		op.seedActions = actions
+	}
+}
+
+// WithSkipResourceCheck starts the network even if the server host clearly
+// cannot run it, as estimated by "EstimateResources".
+func WithSkipResourceCheck() OpOption {
+	return func(op *Op) {
+		op.skipResourceCheck = true
+	}
+}
+
+// WithStateSync makes the added node state-sync its C-chain from the
+// network, instead of replaying the history.
+func WithStateSync() OpOption {
+	return func(op *Op) {
+		op.stateSync = true
+	}
+}
+
+// WithStateSyncNodes sets the nodes (e.g., "node5") that state-sync
+// their C-chain on start.
+func WithStateSyncNodes(names ...string) OpOption {
+	return func(op *Op) {
+		op.stateSyncNodes = names
+	}
+}
+
+// WithMaxUnhealthyDuration fails the network (errored) once a node stays
+// unhealthy for longer, after the network was healthy. Zero disables the
+// server default.
+func WithMaxUnhealthyDuration(d time.Duration) OpOption {
+	return func(op *Op) {
+		s := d.String()
+		op.maxUnhealthy = &s
+	}
+}
+
+// WithStaggeredStart launches the nodes one by one, the interval apart,
+// instead of all at once, so the later nodes join a running network.
+func WithStaggeredStart(interval time.Duration) OpOption {
+	return func(op *Op) {
+		s := interval.String()
+		op.launchInterval = &s
+	}
+}
+
+// WithIncludeBytes adds the container bytes to the chain IPC events.
+func WithIncludeBytes() OpOption {
+	return func(op *Op) {
+		op.includeBytes = true
+	}
+}
+
+// WithCheckpointInterval checkpoints the node databases of the running
+// network every interval, with the nodes paused for the copy.
+func WithCheckpointInterval(d time.Duration) OpOption {
+	return func(op *Op) {
+		s := d.String()
+		op.checkpointInterval = &s
+	}
+}
+
+// WithCheckpointRetention sets the number of checkpoints kept, the oldest
+// removed first.
+func WithCheckpointRetention(n uint32) OpOption {
+	return func(op *Op) {
+		op.checkpointKeep = &n
+	}
+}
+
+// WithResumeCheckpoint starts the nodes from the databases of the
+// checkpoint directory (on the server host) instead of from genesis.
+func WithResumeCheckpoint(path string) OpOption {
+	return func(op *Op) {
+		op.resumeCheckpoint = path
+	}
+}
+
+// WithDiskFaultNodes mounts the database directory of the nodes on disk
+// devices, so "SetDiskFault" can slow down or fail their I/O.
+func WithDiskFaultNodes(names ...string) OpOption {
+	return func(op *Op) {
+		op.diskFaultNodes = names
+	}
+}
+
+// WithDiskDeviceBytes sets the size of each disk fault device.
+func WithDiskDeviceBytes(n uint64) OpOption {
+	return func(op *Op) {
+		op.diskBytes = &n
+	}
+}
+
+// WithNodeArchival makes the nodes by name archival (true) or pruned
+// (false), e.g., an archival "node1" for the historical queries, and the
+// other nodes pruned for the load tests.
+func WithNodeArchival(archival map[string]bool) OpOption {
+	return func(op *Op) {
+		op.nodeArchival = archival
+	}
+}
+
+// WithArchival makes the added node archival (true) or pruned (false),
+// instead of following the C-chain config of the network.
+func WithArchival(archival bool) OpOption {
+	return func(op *Op) {
+		op.archival = &archival
+	}
+}
+
+// WithNodeSidecars launches the companion processes of the nodes by name
+// (e.g., a metrics exporter of "node1"), with the nodes.
+func WithNodeSidecars(sidecars map[string]*rpcpb.NodeSidecars) OpOption {
+	return func(op *Op) {
+		op.nodeSidecars = sidecars
+	}
+}
+
+// WithRegex searches the logs for the lines matching the query
+// as a regular expression.
+func WithRegex() OpOption {
+	return func(op *Op) {
+		op.regex = true
+	}
+}
+
+// WithNodeNames limits the log search to the nodes.
+func WithNodeNames(names ...string) OpOption {
+	return func(op *Op) {
+		op.nodeNames = names
+	}
+}
+
+// WithTimeRange limits the log search to the lines in the time range,
+// unbounded on a zero time.
+func WithTimeRange(since time.Time, until time.Time) OpOption {
+	return func(op *Op) {
+		op.since = since
+		op.until = until
+	}
+}
+
+// WithMaxMatches sets the maximum number of the log search matches.
+func WithMaxMatches(n uint32) OpOption {
+	return func(op *Op) {
+		op.maxMatches = n
+	}
+}
+
+// WithGCMaxAge overrides the server retention age of "GCNow",
+// zero to keep the files of any age.
+func WithGCMaxAge(d time.Duration) OpOption {
+	return func(op *Op) {
+		op.gcMaxAge = &d
+	}
+}
+
+// WithGCMaxBytes overrides the total size of the stopped network data
+// directories kept by "GCNow", zero for any size.
+func WithGCMaxBytes(n uint64) OpOption {
+	return func(op *Op) {
+		op.gcMaxBytes = &n
+	}
+}
+
+// WithDryRun makes "GCNow" only report the files it would remove.
+func WithDryRun() OpOption {
+	return func(op *Op) {
+		op.dryRun = true
+	}
+}
+
+// WithStdin writes the reader to the stdin of the node of "AttachConsole",
+// until its EOF.
+func WithStdin(r io.Reader) OpOption {
+	return func(op *Op) {
+		op.stdin = r
+	}
+}
+
+// WithIdempotencyKey sets the idempotency key of a "Start", "Stop", or
+// "AddNode" request: the server runs the first request of a key, and returns
+// its result to the retries (e.g., after a timeout) with the same key and
+// request, for the server idempotency window. Another request with the key
+// fails.
+func WithIdempotencyKey(key string) OpOption {
+	return func(op *Op) {
+		op.idempotencyKey = key
+	}
+}
+
+func (op *Op) withIdempotencyKey(ctx context.Context) context.Context {
+	if op.idempotencyKey == "" {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, "idempotency-key", op.idempotencyKey)
+}
+
+// WithBootstrapBeacons sets the nodes (e.g., "node1") that the other nodes
+// bootstrap from, all of the nodes by default.
+func WithBootstrapBeacons(names ...string) OpOption {
+	return func(op *Op) {
+		op.bootstrapBeacons = names
+	}
+}
+
+// WithStaticPeers disables the peer gossip, so that the nodes only
+// connect to their bootstrap beacons (and the nodes bootstrapping from them).
+func WithStaticPeers() OpOption {
+	return func(op *Op) {
+		op.staticPeers = true
+	}
+}
+
+// WithNodeBootstrapPeers sets the nodes that a node bootstraps from, by
+// node name, instead of the bootstrap beacons (e.g., a ring with the
+// static peers).
+func WithNodeBootstrapPeers(peers map[string]*rpcpb.NodeBootstrapPeers) OpOption {
+	return func(op *Op) {
+		op.nodeBootstrapPeers = peers
+	}
+}
+
+// WithChainName sets the name of the created blockchain, the VM name by default.
+func WithChainName(name string) OpOption {
+	return func(op *Op) {
+		op.chainName = name
+	}
+}
+
+// WithBasePort sets the static ports of the nodes, starting from the base port.
+// By default, free ports are assigned, and reassigned on conflicts.
+func WithBasePort(port uint32) OpOption {
+	return func(op *Op) {
+		op.basePort = port
+	}
+}
+
+// WithNoWait reports the node health right away, instead of
+// waiting for all nodes to be healthy.
+func WithNoWait() OpOption {
+	return func(op *Op) {
+		op.noWait = true
+	}
+}
+
+// WithParallelism sets the maximum number of concurrent node operations
+// of the batch requests (all at once if zero).
+func WithParallelism(n uint32) OpOption {
+	return func(op *Op) {
+		op.parallelism = n
+	}
+}
+
+// WithSendOnlyOnChange makes the server skip the status pushes
+// where the cluster info has not changed.
+func WithSendOnlyOnChange() OpOption {
+	return func(op *Op) {
+		op.sendOnlyOnChange = true
+	}
+}
+
+// WithStatusDelta makes the server push only the changed nodes and fields
+// after the first status push. The client still returns full cluster infos.
+func WithStatusDelta() OpOption {
+	return func(op *Op) {
+		op.statusDelta = true
+	}
+}
+
+// timeoutInterceptor sets the default deadline of the method on the
+// requests without one.
+func timeoutInterceptor(timeout time.Duration, methodTimeouts map[string]time.Duration) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if _, ok := ctx.Deadline(); !ok {
+			d := timeout
+			// "/<service>/<method>"
+			if md, ok := methodTimeouts[path.Base(method)]; ok {
+				d = md
+			}
+			if d > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, d)
+				defer cancel()
+			}
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+func isClientCanceled(ctxErr error, err error) bool {
+	if ctxErr != nil {
+		return true
+	}
+
+	ev, ok := status.FromError(err)
+	if !ok {
+		return false
+	}
+
+	switch ev.Code() {
+	case codes.Canceled, codes.DeadlineExceeded:
+		// client-side context cancel or deadline exceeded
+		// "rpc error: code = Canceled desc = context canceled"
+		// "rpc error: code = DeadlineExceeded desc = context deadline exceeded"
+		return true
+	case codes.Unavailable:
+		msg := ev.Message()
+		// client-side context cancel or deadline exceeded with TLS ("http2.errClientDisconnected")
+		// "rpc error: code = Unavailable desc = client disconnected"
+		if msg == "client disconnected" {
+			return true
+		}
+		// "grpc/transport.ClientTransport.CloseStream" on canceled streams
+		// "rpc error: code = Unavailable desc = stream error: stream ID 21; CANCEL")
+		if strings.HasPrefix(msg, "stream error: ") && strings.HasSuffix(msg, "; CANCEL") {
+			return true
+		}
+	}
+	return false
+}
+
+// WithObserver adds the node as a non-validating observer,
+// marked in its node info.
+func WithObserver() OpOption {
+	return func(op *Op) {
+		op.observer = true
+	}
 }
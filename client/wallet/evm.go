@@ -0,0 +1,149 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package wallet
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/lasthyphen/djtx-tester/pkg/jsonrpc"
+	"golang.org/x/crypto/sha3"
+)
+
+const evmEndpoint = "/ext/bc/C/rpc"
+
+// DeployContract deploys the contract bytecode from the key address on the
+// C-chain, with a legacy (EIP-155) tx signed by the key, and waits for its
+// receipt. The gas limit is estimated if zero. It returns the tx hash and
+// the contract address.
+func (w *Wallet) DeployContract(ctx context.Context, bytecode []byte, gasLimit uint64) (txHash string, contractAddr string, err error) {
+	from := w.Address(ChainC)
+	chainID, err := w.evmQuantity(ctx, "eth_chainId")
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := w.evmQuantity(ctx, "eth_getTransactionCount", from, "pending")
+	if err != nil {
+		return "", "", err
+	}
+	gasPrice, err := w.evmQuantity(ctx, "eth_gasPrice")
+	if err != nil {
+		return "", "", err
+	}
+	gas := new(big.Int).SetUint64(gasLimit)
+	if gasLimit == 0 {
+		gas, err = w.evmQuantity(ctx, "eth_estimateGas", map[string]string{
+			"from": from,
+			"data": "0x" + hex.EncodeToString(bytecode),
+		})
+		if err != nil {
+			return "", "", fmt.Errorf("failed to estimate the gas: %w", err)
+		}
+	}
+
+	// ref. EIP-155, the contract creation has no recipient and no value
+	fields := [][]byte{
+		rlpUint(nonce),
+		rlpUint(gasPrice),
+		rlpUint(gas),
+		rlpBytes(nil),
+		rlpUint(new(big.Int)),
+		rlpBytes(bytecode),
+	}
+	h := sha3.NewLegacyKeccak256()
+	h.Write(rlpList(append(fields, rlpUint(chainID), rlpUint(new(big.Int)), rlpUint(new(big.Int)))...))
+	sig, err := w.key.SignHash(h.Sum(nil))
+	if err != nil {
+		return "", "", err
+	}
+	if len(sig) != 65 {
+		return "", "", fmt.Errorf("invalid signature length %d", len(sig))
+	}
+	// the signature is [r || s || recovery ID]
+	v := new(big.Int).Mul(chainID, big.NewInt(2))
+	v.Add(v, big.NewInt(35+int64(sig[64])))
+	raw := rlpList(append(fields,
+		rlpUint(v),
+		rlpUint(new(big.Int).SetBytes(sig[:32])),
+		rlpUint(new(big.Int).SetBytes(sig[32:64])),
+	)...)
+
+	if err := jsonrpc.Call(ctx, w.uri, evmEndpoint, "eth_sendRawTransaction", []interface{}{"0x" + hex.EncodeToString(raw)}, &txHash); err != nil {
+		return "", "", err
+	}
+	contractAddr, err = w.waitReceipt(ctx, txHash)
+	return txHash, contractAddr, err
+}
+
+// waitReceipt waits for the receipt of the C-chain tx,
+// and returns the address of the created contract.
+func (w *Wallet) waitReceipt(ctx context.Context, txHash string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, txWait)
+	defer cancel()
+	for {
+		var receipt *struct {
+			Status          string `json:"status"`
+			ContractAddress string `json:"contractAddress"`
+		}
+		if err := jsonrpc.Call(ctx, w.uri, evmEndpoint, "eth_getTransactionReceipt", []interface{}{txHash}, &receipt); err != nil {
+			return "", err
+		}
+		if receipt != nil {
+			if receipt.Status != "0x1" {
+				return "", fmt.Errorf("%w: C-chain tx %q reverted", ErrTxNotAccepted, txHash)
+			}
+			return receipt.ContractAddress, nil
+		}
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("%w: C-chain tx %q: %v", ErrTxNotAccepted, txHash, ctx.Err())
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+}
+
+// evmQuantity calls the eth API method returning a hex quantity.
+func (w *Wallet) evmQuantity(ctx context.Context, method string, params ...interface{}) (*big.Int, error) {
+	var reply string
+	if err := jsonrpc.Call(ctx, w.uri, evmEndpoint, method, append([]interface{}{}, params...), &reply); err != nil {
+		return nil, err
+	}
+	n, ok := new(big.Int).SetString(strings.TrimPrefix(reply, "0x"), 16)
+	if !ok {
+		return nil, fmt.Errorf("invalid %s reply %q", method, reply)
+	}
+	return n, nil
+}
+
+// rlpUint encodes the integer as an RLP string, big-endian without leading zeros.
+func rlpUint(n *big.Int) []byte { return rlpBytes(n.Bytes()) }
+
+// rlpBytes encodes the RLP string.
+func rlpBytes(b []byte) []byte {
+	if len(b) == 1 && b[0] < 0x80 {
+		return b
+	}
+	return append(rlpHeader(0x80, len(b)), b...)
+}
+
+// rlpList encodes the RLP list of the encoded items.
+func rlpList(items ...[]byte) []byte {
+	var payload []byte
+	for _, item := range items {
+		payload = append(payload, item...)
+	}
+	return append(rlpHeader(0xc0, len(payload)), payload...)
+}
+
+func rlpHeader(offset byte, n int) []byte {
+	if n < 56 {
+		return []byte{offset + byte(n)}
+	}
+	l := big.NewInt(int64(n)).Bytes()
+	return append([]byte{offset + 55 + byte(len(l))}, l...)
+}
@@ -0,0 +1,343 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// Package wallet moves the funds of a test key across the X, P, and C chains
+// of a network runner cluster, and queries the balances. The txs are signed
+// by a temporary keystore user of a cluster node, holding the key.
+package wallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lasthyphen/djtx-tester/pkg/jsonrpc"
+	"github.com/lasthyphen/djtx-tester/pkg/randutil"
+	"github.com/lasthyphen/djtx-tester/pkg/testkeys"
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+)
+
+const (
+	ChainX = "X"
+	ChainP = "P"
+	ChainC = "C"
+)
+
+// time for a tx to be accepted
+const txWait = time.Minute
+
+var (
+	ErrNoNodeURI     = errors.New("no node URI in the cluster info")
+	ErrInvalidChain  = errors.New("invalid chain (expected X, P, or C)")
+	ErrSameChain     = errors.New("same source and destination chain")
+	ErrTxNotAccepted = errors.New("tx not accepted")
+)
+
+// chain API endpoints and methods, ref. avm, platformvm, and coreth "avax" APIs
+var (
+	endpoints = map[string]string{
+		ChainX: "/ext/bc/X",
+		ChainP: "/ext/bc/P",
+		ChainC: "/ext/bc/C/avax",
+	}
+	importKeyMethods = map[string]string{
+		ChainX: "avm.importKey",
+		ChainP: "platform.importKey",
+		ChainC: "avax.importKey",
+	}
+	exportMethods = map[string]string{
+		ChainX: "avm.export",
+		ChainP: "platform.exportAVAX",
+		ChainC: "avax.export",
+	}
+	importMethods = map[string]string{
+		ChainX: "avm.import",
+		ChainP: "platform.importAVAX",
+		ChainC: "avax.import",
+	}
+	issueTxMethods = map[string]string{
+		ChainX: "avm.issueTx",
+		ChainP: "platform.issueTx",
+		ChainC: "avax.issueTx",
+	}
+)
+
+// Wallet holds a key in a keystore user of a node.
+type Wallet struct {
+	uri     string
+	key     *testkeys.Key
+	user    map[string]string
+	assetID string
+}
+
+// New creates a wallet of the key (the genesis "ewoq" key if nil)
+// on the node at "uri" (e.g., "http://127.0.0.1:9650").
+// Close deletes its keystore user.
+func New(ctx context.Context, uri string, key *testkeys.Key) (*Wallet, error) {
+	if key == nil {
+		key = testkeys.Ewoq()
+	}
+	w := &Wallet{
+		uri: uri,
+		key: key,
+		user: map[string]string{
+			"username": "network-runner-wallet-" + randutil.String(8),
+			"password": randutil.String(32),
+		},
+	}
+	if err := jsonrpc.Call(ctx, uri, "/ext/keystore", "keystore.createUser", w.user, nil); err != nil {
+		return nil, err
+	}
+	for _, chain := range []string{ChainX, ChainP, ChainC} {
+		params := w.params(map[string]interface{}{"privateKey": key.String()})
+		if err := jsonrpc.Call(ctx, uri, endpoints[chain], importKeyMethods[chain], params, nil); err != nil {
+			w.Close(ctx)
+			return nil, fmt.Errorf("failed to import the key on the %s-chain: %w", chain, err)
+		}
+	}
+	var reply struct {
+		AssetID string `json:"assetID"`
+	}
+	if err := jsonrpc.Call(ctx, uri, "/ext/bc/P", "platform.getStakingAssetID", nil, &reply); err != nil {
+		w.Close(ctx)
+		return nil, err
+	}
+	w.assetID = reply.AssetID
+	return w, nil
+}
+
+// FromClusterInfo creates a wallet of the key on the first node of the cluster
+// (e.g., of "client.Status").
+func FromClusterInfo(ctx context.Context, info *rpcpb.ClusterInfo, key *testkeys.Key) (*Wallet, error) {
+	names := make([]string, 0, len(info.GetNodeInfos()))
+	for name, ni := range info.GetNodeInfos() {
+		if ni.Uri != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, ErrNoNodeURI
+	}
+	sort.Strings(names)
+	return New(ctx, info.NodeInfos[names[0]].Uri, key)
+}
+
+// Key returns the key of the wallet.
+func (w *Wallet) Key() *testkeys.Key { return w.key }
+
+// AssetID returns the ID of the AVAX asset.
+func (w *Wallet) AssetID() string { return w.assetID }
+
+// Close deletes the keystore user of the wallet.
+func (w *Wallet) Close(ctx context.Context) error {
+	return jsonrpc.Call(ctx, w.uri, "/ext/keystore", "keystore.deleteUser", w.user, nil)
+}
+
+// Address returns the address of the key on the chain: bech32 with the chain
+// prefix on the X and P chains (e.g., "X-local1..."), hex on the C-chain.
+func (w *Wallet) Address(chain string) string {
+	switch chain {
+	case ChainX:
+		return w.key.XAddress()
+	case ChainP:
+		return w.key.PAddress()
+	case ChainC:
+		return w.key.EthAddress()
+	}
+	return ""
+}
+
+// atomicAddress returns the address of the key that receives
+// the exports to the chain.
+func (w *Wallet) atomicAddress(chain string) string {
+	if chain == ChainC {
+		return w.key.CAddress()
+	}
+	return w.Address(chain)
+}
+
+// Balance returns the AVAX balance of the address on the chain (the key address
+// if empty): nAVAX on the X-chain, unlocked nAVAX on the P-chain, wei on the C-chain.
+func (w *Wallet) Balance(ctx context.Context, chain string, addr string) (*big.Int, error) {
+	if addr == "" {
+		addr = w.Address(chain)
+	}
+	switch chain {
+	case ChainX:
+		// ref. avm "GetBalance"
+		var reply struct {
+			Balance string `json:"balance"`
+		}
+		params := map[string]interface{}{"address": addr, "assetID": w.assetID}
+		if err := jsonrpc.Call(ctx, w.uri, "/ext/bc/X", "avm.getBalance", params, &reply); err != nil {
+			return nil, err
+		}
+		return parseBalance(reply.Balance, 10)
+	case ChainP:
+		// ref. platformvm "GetBalance"
+		var reply struct {
+			Unlocked string `json:"unlocked"`
+		}
+		params := map[string]interface{}{"addresses": []string{addr}}
+		if err := jsonrpc.Call(ctx, w.uri, "/ext/bc/P", "platform.getBalance", params, &reply); err != nil {
+			return nil, err
+		}
+		return parseBalance(reply.Unlocked, 10)
+	case ChainC:
+		var reply string
+		if err := jsonrpc.Call(ctx, w.uri, "/ext/bc/C/rpc", "eth_getBalance", []interface{}{addr, "latest"}, &reply); err != nil {
+			return nil, err
+		}
+		return parseBalance(strings.TrimPrefix(reply, "0x"), 16)
+	}
+	return nil, fmt.Errorf("%w: %q", ErrInvalidChain, chain)
+}
+
+func parseBalance(s string, base int) (*big.Int, error) {
+	b, ok := new(big.Int).SetString(s, base)
+	if !ok {
+		return nil, fmt.Errorf("invalid balance %q", s)
+	}
+	return b, nil
+}
+
+// Send sends nAVAX to the X-chain address, and waits for the tx acceptance.
+func (w *Wallet) Send(ctx context.Context, to string, amount uint64) (string, error) {
+	var reply txReply
+	if err := jsonrpc.Call(ctx, w.uri, "/ext/bc/X", "avm.send", w.params(map[string]interface{}{
+		"assetID": w.assetID,
+		"amount":  amount,
+		"to":      to,
+	}), &reply); err != nil {
+		return "", err
+	}
+	return reply.TxID, w.waitTx(ctx, ChainX, reply.TxID)
+}
+
+// Transfer exports nAVAX of the key from a chain to another, and imports them
+// to the address on the destination chain (the key address if empty), paying
+// the import fee from the transferred funds. It waits for both txs acceptance,
+// and returns their IDs.
+func (w *Wallet) Transfer(ctx context.Context, from string, to string, amount uint64, toAddr string) (exportTxID string, importTxID string, err error) {
+	if _, ok := endpoints[from]; !ok {
+		return "", "", fmt.Errorf("%w: %q", ErrInvalidChain, from)
+	}
+	if _, ok := endpoints[to]; !ok {
+		return "", "", fmt.Errorf("%w: %q", ErrInvalidChain, to)
+	}
+	if from == to {
+		return "", "", fmt.Errorf("%w: %q", ErrSameChain, from)
+	}
+	if toAddr == "" {
+		toAddr = w.Address(to)
+	}
+
+	exportParams := map[string]interface{}{
+		"amount": amount,
+		"to":     w.atomicAddress(to),
+	}
+	if from != ChainP {
+		exportParams["assetID"] = w.assetID
+	}
+	var exportReply txReply
+	if err := jsonrpc.Call(ctx, w.uri, endpoints[from], exportMethods[from], w.params(exportParams), &exportReply); err != nil {
+		return "", "", fmt.Errorf("failed to export from the %s-chain: %w", from, err)
+	}
+	if err := w.waitTx(ctx, from, exportReply.TxID); err != nil {
+		return exportReply.TxID, "", err
+	}
+
+	var importReply txReply
+	if err := jsonrpc.Call(ctx, w.uri, endpoints[to], importMethods[to], w.params(map[string]interface{}{
+		"sourceChain": from,
+		"to":          toAddr,
+	}), &importReply); err != nil {
+		return exportReply.TxID, "", fmt.Errorf("failed to import to the %s-chain: %w", to, err)
+	}
+	return exportReply.TxID, importReply.TxID, w.waitTx(ctx, to, importReply.TxID)
+}
+
+// CreateAsset creates a fixed cap X-chain asset, with the whole supply held
+// by the key, and waits for the tx acceptance. It returns the asset ID.
+func (w *Wallet) CreateAsset(ctx context.Context, name string, symbol string, denomination uint32, supply uint64) (string, error) {
+	// ref. avm "CreateFixedCapAsset"
+	var reply struct {
+		AssetID string `json:"assetID"`
+	}
+	if err := jsonrpc.Call(ctx, w.uri, "/ext/bc/X", "avm.createFixedCapAsset", w.params(map[string]interface{}{
+		"name":         name,
+		"symbol":       symbol,
+		"denomination": denomination,
+		"initialHolders": []map[string]interface{}{
+			{"amount": supply, "address": w.Address(ChainX)},
+		},
+	}), &reply); err != nil {
+		return "", err
+	}
+	// the asset ID is the ID of the creation tx
+	return reply.AssetID, w.waitTx(ctx, ChainX, reply.AssetID)
+}
+
+// IssueTx issues a signed tx (hex with checksum, as formatted by the node
+// APIs) to the chain, and waits for its acceptance. On the C-chain,
+// the tx is an atomic tx.
+func (w *Wallet) IssueTx(ctx context.Context, chain string, tx string) (string, error) {
+	method, ok := issueTxMethods[chain]
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrInvalidChain, chain)
+	}
+	var reply txReply
+	params := map[string]interface{}{"tx": tx, "encoding": "hex"}
+	if err := jsonrpc.Call(ctx, w.uri, endpoints[chain], method, params, &reply); err != nil {
+		return "", err
+	}
+	return reply.TxID, w.waitTx(ctx, chain, reply.TxID)
+}
+
+type txReply struct {
+	TxID string `json:"txID"`
+}
+
+// params adds the keystore user credentials to the API params.
+func (w *Wallet) params(p map[string]interface{}) map[string]interface{} {
+	p["username"] = w.user["username"]
+	p["password"] = w.user["password"]
+	return p
+}
+
+// waitTx waits for the tx of the chain to be accepted.
+func (w *Wallet) waitTx(ctx context.Context, chain string, txID string) error {
+	method, accepted := "avm.getTxStatus", "Accepted"
+	switch chain {
+	case ChainP:
+		method, accepted = "platform.getTxStatus", "Committed"
+	case ChainC:
+		method = "avax.getAtomicTxStatus"
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, txWait)
+	defer cancel()
+	for {
+		var reply struct {
+			Status string `json:"status"`
+		}
+		if err := jsonrpc.Call(ctx, w.uri, endpoints[chain], method, map[string]string{"txID": txID}, &reply); err != nil {
+			return err
+		}
+		switch reply.Status {
+		case accepted:
+			return nil
+		case "Rejected", "Aborted", "Dropped":
+			return fmt.Errorf("%w: %s-chain tx %q %s", ErrTxNotAccepted, chain, txID, strings.ToLower(reply.Status))
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w: %s-chain tx %q %s: %v", ErrTxNotAccepted, chain, txID, reply.Status, ctx.Err())
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+}
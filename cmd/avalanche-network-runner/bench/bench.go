@@ -0,0 +1,173 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package bench
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lasthyphen/djtx-tester/client"
+	"github.com/lasthyphen/djtx-tester/client/wallet"
+	"github.com/lasthyphen/djtx-tester/pkg/color"
+	"github.com/lasthyphen/djtx-tester/pkg/logutil"
+	"github.com/spf13/cobra"
+)
+
+var (
+	logLevel       string
+	endpoints      []string
+	token          string
+	dialTimeout    time.Duration
+	requestTimeout time.Duration
+
+	avalancheGoBinPath string
+	numNodes           uint32
+	numTxs             int
+	keep               bool
+)
+
+func NewCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "bench [options]",
+		Short: "Starts a network on each server, runs a fixed workload, and reports the times and TPS.",
+		RunE:  benchFunc,
+	}
+
+	cmd.PersistentFlags().StringVar(&logLevel, "log-level", logutil.DefaultLogLevel, "log level")
+	cmd.PersistentFlags().StringSliceVar(&endpoints, "endpoints", []string{"0.0.0.0:8080"}, "server endpoints, one network per server (e.g., '0.0.0.0:8080,0.0.0.0:8090')")
+	cmd.PersistentFlags().StringVar(&token, "token", "", "tenant token of the shared servers")
+	cmd.PersistentFlags().DurationVar(&dialTimeout, "dial-timeout", 10*time.Second, "server dial timeout")
+	cmd.PersistentFlags().DurationVar(&requestTimeout, "request-timeout", 10*time.Minute, "timeout of each network benchmark")
+	cmd.PersistentFlags().StringVar(&avalancheGoBinPath, "avalanchego-path", "", "avalanchego binary path")
+	cmd.PersistentFlags().Uint32Var(&numNodes, "num-nodes", 5, "number of nodes of each network")
+	cmd.PersistentFlags().IntVar(&numTxs, "txs", 100, "number of X-chain transfers of the workload, issued one after another")
+	cmd.PersistentFlags().BoolVar(&keep, "keep", false, "true to keep the networks running after the benchmark")
+
+	return cmd
+}
+
+// result is the benchmark of a network.
+type result struct {
+	endpoint string
+	// from the start request to its response
+	startup time.Duration
+	// from the start request to the healthy network
+	health time.Duration
+	// accepted workload txs per second
+	tps float64
+	err error
+}
+
+func benchFunc(cmd *cobra.Command, args []string) error {
+	if len(endpoints) == 0 {
+		return errors.New("no server endpoints")
+	}
+	if numTxs <= 0 {
+		return fmt.Errorf("invalid number of txs %d", numTxs)
+	}
+
+	results := make([]*result, len(endpoints))
+	var wg sync.WaitGroup
+	for i, ep := range endpoints {
+		wg.Add(1)
+		go func(i int, ep string) {
+			defer wg.Done()
+			results[i] = benchNetwork(ep)
+		}(i, ep)
+	}
+	wg.Wait()
+
+	color.Outf("{{blue}}{{bold}}%d networks of %d nodes, %d txs each{{/}}\n", len(endpoints), numNodes, numTxs)
+	var failed error
+	for _, r := range results {
+		if r.err != nil {
+			color.Outf("{{red}}%s: failed: %v{{/}}\n", r.endpoint, r.err)
+			failed = fmt.Errorf("benchmark of %q failed: %w", r.endpoint, r.err)
+			continue
+		}
+		color.Outf("{{green}}%s:{{/}} startup %v, health %v, %.2f TPS\n",
+			r.endpoint,
+			r.startup.Round(time.Millisecond),
+			r.health.Round(time.Millisecond),
+			r.tps,
+		)
+	}
+	return failed
+}
+
+func benchNetwork(ep string) *result {
+	r := &result{endpoint: ep}
+	cli, err := client.New(client.Config{
+		LogLevel:    logLevel,
+		Endpoint:    ep,
+		DialTimeout: dialTimeout,
+		Token:       token,
+	})
+	if err != nil {
+		r.err = err
+		return r
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := cli.Start(ctx, avalancheGoBinPath, client.WithNumNodes(numNodes)); err != nil {
+		r.err = err
+		return r
+	}
+	r.startup = time.Since(start)
+	if !keep {
+		defer func() {
+			// even if the benchmark timed out
+			stopCtx, stopCancel := context.WithTimeout(context.Background(), time.Minute)
+			defer stopCancel()
+			if _, err := cli.Stop(stopCtx); err != nil {
+				color.Outf("{{red}}%s: failed to stop the network: %v{{/}}\n", ep, err)
+			}
+		}()
+	}
+
+	// the server only accepts the health requests once the nodes are launched,
+	// and each request waits a bounded time
+	for {
+		_, err := cli.Health(ctx)
+		if err == nil {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			r.err = fmt.Errorf("network not healthy: %w", err)
+			return r
+		case <-time.After(time.Second):
+		}
+	}
+	r.health = time.Since(start)
+
+	status, err := cli.Status(ctx)
+	if err != nil {
+		r.err = err
+		return r
+	}
+	w, err := wallet.FromClusterInfo(ctx, status.ClusterInfo, nil)
+	if err != nil {
+		r.err = err
+		return r
+	}
+	defer w.Close(context.Background())
+
+	workloadStart := time.Now()
+	for i := 0; i < numTxs; i++ {
+		if _, err := w.Send(ctx, w.Address(wallet.ChainX), 1); err != nil {
+			r.err = fmt.Errorf("tx %d failed: %w", i, err)
+			return r
+		}
+	}
+	r.tps = float64(numTxs) / time.Since(workloadStart).Seconds()
+	return r
+}
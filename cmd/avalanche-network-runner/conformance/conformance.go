@@ -0,0 +1,100 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package conformance
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lasthyphen/djtx-tester/pkg/color"
+	"github.com/lasthyphen/djtx-tester/pkg/conformance"
+	"github.com/lasthyphen/djtx-tester/pkg/discovery"
+	"github.com/lasthyphen/djtx-tester/pkg/secrets"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+var (
+	endpoint    string
+	token       string
+	dialTimeout time.Duration
+	callTimeout time.Duration
+	fixtures    string
+	ignorePaths []string
+)
+
+func NewCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify-fixtures [options]",
+		Short: "Replays the recorded control calls (of 'server --record-fixtures') against a server, and reports the incompatible results.",
+		RunE:  verifyFunc,
+	}
+
+	cmd.PersistentFlags().StringVar(&endpoint, "endpoint", "", "server endpoint (or unix://<socket path>), discovered from $NETWORK_RUNNER_ENDPOINT or the local server state file if empty")
+	cmd.PersistentFlags().StringVar(&token, "token", "", "tenant token of a shared server, or its secret reference (env:<name>, file:<path>, or vault:<path>#<field>)")
+	cmd.PersistentFlags().DurationVar(&dialTimeout, "dial-timeout", 10*time.Second, "server dial timeout")
+	cmd.PersistentFlags().DurationVar(&callTimeout, "call-timeout", 5*time.Minute, "timeout of each replayed call (0 for none)")
+	cmd.PersistentFlags().StringVar(&fixtures, "fixtures", "", "fixture file to replay")
+	cmd.PersistentFlags().StringSliceVar(&ignorePaths, "ignore-paths", nil, "response field paths not compared, by their JSON names, '*' matching any field or map key (e.g., 'clusterInfo.nodeInfos.*.chainIpcs')")
+
+	return cmd
+}
+
+func verifyFunc(cmd *cobra.Command, args []string) error {
+	if fixtures == "" {
+		return fmt.Errorf("no fixture file")
+	}
+	calls, err := conformance.ReadFixtures(fixtures)
+	if err != nil {
+		return err
+	}
+	if endpoint == "" {
+		endpoint = discovery.Endpoint()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dialTimeout)
+	conn, err := grpc.DialContext(ctx, endpoint, grpc.WithBlock(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	cancel()
+	if err != nil {
+		return fmt.Errorf("failed to dial %q: %w", endpoint, err)
+	}
+	defer conn.Close()
+
+	cfg := conformance.VerifyConfig{
+		CallTimeout: callTimeout,
+		IgnorePaths: ignorePaths,
+	}
+	if token != "" {
+		if token, err = secrets.Resolve(context.Background(), token); err != nil {
+			return err
+		}
+		cfg.CallOptions = append(cfg.CallOptions, grpc.PerRPCCredentials(tokenCreds(token)))
+	}
+	color.Outf("{{blue}}replaying %d calls of %q against %q{{/}}\n", len(calls), fixtures, endpoint)
+	mismatches, err := conformance.Verify(context.Background(), conn, calls, cfg)
+	if err != nil {
+		return err
+	}
+	for _, m := range mismatches {
+		color.Outf("{{red}}call %d %s:{{/}} %s\n", m.Index+1, m.Method, m.Reason)
+	}
+	if len(mismatches) > 0 {
+		return fmt.Errorf("%d incompatible results of the %d calls", len(mismatches), len(calls))
+	}
+	color.Outf("{{green}}all %d calls compatible{{/}}\n", len(calls))
+	return nil
+}
+
+// tokenCreds sends the tenant token with every call, as the client does.
+type tokenCreds string
+
+func (t tokenCreds) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(t)}, nil
+}
+
+func (t tokenCreds) RequireTransportSecurity() bool {
+	return false
+}
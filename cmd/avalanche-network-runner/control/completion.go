@@ -0,0 +1,82 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package control
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lasthyphen/djtx-tester/client"
+	"github.com/spf13/cobra"
+)
+
+// bounds the dial and status request of a completion, as the shell waits
+const completionTimeout = 3 * time.Second
+
+// registerNodeNameCompletions completes the node names of the flags of the
+// commands from the status of the running network.
+func registerNodeNameCompletions(cmd *cobra.Command) {
+	for _, c := range cmd.Commands() {
+		// the name of a new node
+		if c.Name() == "add-node" {
+			continue
+		}
+		for _, name := range []string{"node-name", "node-names"} {
+			if c.Flag(name) == nil {
+				continue
+			}
+			_ = c.RegisterFlagCompletionFunc(name, completeNodeNames)
+		}
+	}
+}
+
+// completeNodeNames returns the node names starting with the value to
+// complete, after its last comma for the comma-separated flags.
+func completeNodeNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	names, err := fetchNodeNames()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveError
+	}
+	prefix, partial := "", toComplete
+	if idx := strings.LastIndex(toComplete, ","); idx >= 0 {
+		prefix, partial = toComplete[:idx+1], toComplete[idx+1:]
+	}
+	listed := make(map[string]bool)
+	for _, name := range strings.Split(prefix, ",") {
+		listed[name] = true
+	}
+	var comps []string
+	for _, name := range names {
+		if !listed[name] && strings.HasPrefix(name, partial) {
+			comps = append(comps, prefix+name)
+		}
+	}
+	return comps, cobra.ShellCompDirectiveNoFileComp
+}
+
+func fetchNodeNames() ([]string, error) {
+	cli, err := client.New(client.Config{
+		// the request logs would be printed over the line being completed
+		LogLevel:    "error",
+		Endpoint:    endpoint,
+		DialTimeout: completionTimeout,
+		Token:       token,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), completionTimeout)
+	resp, err := cli.Status(ctx)
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+	names := append([]string(nil), resp.GetClusterInfo().GetNodeNames()...)
+	sort.Strings(names)
+	return names, nil
+}
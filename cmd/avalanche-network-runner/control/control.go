@@ -5,16 +5,27 @@ package control
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/lasthyphen/djtx-tester/client"
 	"github.com/lasthyphen/djtx-tester/pkg/color"
 	"github.com/lasthyphen/djtx-tester/pkg/logutil"
+	"github.com/lasthyphen/djtx-tester/pkg/secrets"
+	"github.com/lasthyphen/djtx-tester/pkg/tracing"
+	"github.com/lasthyphen/djtx-tester/pkg/traffic"
+	"github.com/lasthyphen/djtx-tester/rpcpb"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protojson"
 )
 
 func init() {
@@ -26,29 +37,93 @@ var (
 	endpoint       string
 	dialTimeout    time.Duration
 	requestTimeout time.Duration
+	token          string
+	otlpEndpoint   string
+
+	// flushes the client spans before exit
+	shutdownTracing func(context.Context) error
 )
 
 func NewCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "control [options]",
 		Short: "Start a network runner controller.",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) (err error) {
+			if err := checkOutputFormat(); err != nil {
+				return err
+			}
+			// e.g., "--token env:RUNNER_TOKEN", so the token is not in the shell history
+			if token, err = secrets.Resolve(context.Background(), token); err != nil {
+				return invalidErr(err)
+			}
+			shutdownTracing, err = tracing.Init(context.Background(), "network-runner-control", otlpEndpoint)
+			return err
+		},
+		PersistentPostRunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			return shutdownTracing(ctx)
+		},
 	}
 
 	cmd.PersistentFlags().StringVar(&logLevel, "log-level", logutil.DefaultLogLevel, "log level")
-	cmd.PersistentFlags().StringVar(&endpoint, "endpoint", "0.0.0.0:8080", "server endpoint")
+	cmd.PersistentFlags().StringVar(&endpoint, "endpoint", "", "server endpoint (or unix://<socket path>), discovered from $NETWORK_RUNNER_ENDPOINT or the local server state file if empty")
 	cmd.PersistentFlags().DurationVar(&dialTimeout, "dial-timeout", 10*time.Second, "server dial timeout")
 	cmd.PersistentFlags().DurationVar(&requestTimeout, "request-timeout", time.Minute, "client request timeout")
+	cmd.PersistentFlags().StringVar(&token, "token", "", "tenant token of a shared server, or its secret reference (env:<name>, file:<path>, or vault:<path>#<field>)")
+	cmd.PersistentFlags().StringVar(&otlpEndpoint, "otlp-endpoint", "", "OTLP gRPC collector endpoint to export traces to (e.g., localhost:4317), disabled if empty")
+	cmd.PersistentFlags().StringVar(&outputFormat, "output-format", outputText, "output format of the responses and errors (text or json)")
+	cmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return invalidErr(err)
+	})
 
 	cmd.AddCommand(
 		newStartCommand(),
 		newHealthCommand(),
+		newWaitNodesHealthyCommand(),
 		newURIsCommand(),
 		newStatusCommand(),
 		newStreamStatusCommand(),
 		newRemoveNodeCommand(),
 		newRestartNodeCommand(),
+		newRemoveNodesCommand(),
+		newRestartNodesCommand(),
 		newStopCommand(),
+		newAbortStartCommand(),
+		newCheckBinaryCommand(),
+		newCreateChainIPCCommand(),
+		newRemoveChainIPCCommand(),
+		newListChainIPCsCommand(),
+		newTailChainIPCCommand(),
+		newAttachConsoleCommand(),
+		newInjectFaultCommand(),
+		newSetDiskFaultCommand(),
+		newGetValidatorsCommand(),
+		newSetTimeCommand(),
+		newAdvanceTimeCommand(),
+		newRenderTopologyCommand(),
+		newCreateBlockchainCommand(),
+		newRecordTrafficCommand(),
+		newReplayTrafficCommand(),
+		newBlockTrafficCommand(),
+		newUnblockTrafficCommand(),
+		newUploadFileCommand(),
+		newGetNodeConfigCommand(),
+		newRotateNodeKeyCommand(),
+		newAddNodeCommand(),
+		newEstimateResourcesCommand(),
+		newSearchLogsCommand(),
+		newGetSyncProgressCommand(),
+		newGCNowCommand(),
+		newDownloadLogsCommand(),
+		newGetRunManifestCommand(),
+		newStopValidatingSubnetCommand(),
+		newGetGenesisCommand(),
+		newGetNetworkParamsCommand(),
+		newShellCommand(),
 	)
+	withJSONErrors(cmd)
+	registerNodeNameCompletions(cmd)
 
 	return cmd
 }
@@ -56,6 +131,42 @@ func NewCommand() *cobra.Command {
 var (
 	avalancheGoBinPath string
 	whitelistedSubnets string
+	preset             string
+	template           string
+	templateParams     map[string]string
+	numNodes           uint32
+	globalNodeConfig   string
+	cChainConfig       string
+	artifactsPath      string
+	stakingParams      string
+	logSinks           []string
+	basePort           uint32
+	genesisBalances    []string
+	resourceLimits     string
+	nodeResourceLimits string
+	nodeLogLevels      string
+	bootstrapBeacons   []string
+	staticPeers        bool
+	nodeBootstrapPeers string
+	livenessMonitor    string
+	healthPolling      string
+	seedActions        string
+	idempotencyKey     string
+	skipResourceCheck  bool
+	stateSyncNodes     []string
+	nodeSidecars       string
+	archivalNodes      []string
+	prunedNodes        []string
+	databaseConfig     string
+	nodeDatabases      string
+
+	maxUnhealthyDuration time.Duration
+	staggeredStart       time.Duration
+	diskFaultNodes       []string
+	diskDeviceBytes      uint64
+	checkpointInterval   time.Duration
+	checkpointRetention  uint32
+	resumeCheckpoint     string
 )
 
 func newStartCommand() *cobra.Command {
@@ -76,37 +187,386 @@ func newStartCommand() *cobra.Command {
 		"",
 		"whitelisted subnets (comma-separated)",
 	)
+	cmd.PersistentFlags().StringVar(
+		&preset,
+		"preset",
+		"",
+		"start preset (e.g., minimal-1node, default-5node, heavy-indexer, archival)",
+	)
+	cmd.PersistentFlags().StringVar(&template, "template", "", "start template of the server")
+	cmd.PersistentFlags().StringToStringVar(&templateParams, "template-params", nil, "template param values (e.g., 'numValidators=3,vm=timestampvm')")
+	cmd.PersistentFlags().Uint32Var(
+		&numNodes,
+		"num-nodes",
+		0,
+		"number of nodes (0 for the preset or default)",
+	)
+	cmd.PersistentFlags().StringVar(
+		&globalNodeConfig,
+		"global-node-config",
+		"",
+		"JSON object merged into every node config",
+	)
+	cmd.PersistentFlags().StringVar(
+		&cChainConfig,
+		"c-chain-config",
+		"",
+		"JSON C-chain config of every node",
+	)
+	cmd.PersistentFlags().StringVar(
+		&artifactsPath,
+		"collect-artifacts-on-stop",
+		"",
+		"path (relative to the server artifacts directory) to archive logs and configs to, when the network stops",
+	)
+	cmd.PersistentFlags().StringVar(
+		&stakingParams,
+		"staking-params",
+		"",
+		"JSON staking params (e.g., '{\"minStakeDuration\":\"1h\",\"uptimeRequirement\":0.5}')",
+	)
+	cmd.PersistentFlags().StringSliceVar(
+		&logSinks,
+		"log-sinks",
+		nil,
+		"node output sinks (e.g., 'stdout', 'noop', 'file:/tmp/nodes.log', 'syslog:udp://localhost:514', 'loki:http://localhost:3100/loki/api/v1/push')",
+	)
+	cmd.PersistentFlags().Uint32Var(
+		&basePort,
+		"base-port",
+		0,
+		"first static node port (HTTP and staking ports interleaved), 0 to assign free ports",
+	)
+	cmd.PersistentFlags().StringSliceVar(
+		&genesisBalances,
+		"genesis-balances",
+		nil,
+		"genesis balances in nAVAX (e.g., 'X-local1...=1000000000,0x8db9...=5000000000')",
+	)
+	cmd.PersistentFlags().StringVar(
+		&resourceLimits,
+		"resource-limits",
+		"",
+		"JSON resource limits of every node (e.g., '{\"cpuMillicores\":500,\"memoryMaxBytes\":\"2147483648\"}')",
+	)
+	cmd.PersistentFlags().StringVar(
+		&nodeResourceLimits,
+		"node-resource-limits",
+		"",
+		"JSON resource limits by node name (e.g., '{\"node1\":{\"cpuWeight\":10}}')",
+	)
+	cmd.PersistentFlags().StringVar(
+		&nodeLogLevels,
+		"node-log-levels",
+		"",
+		"JSON log levels by node name (e.g., '{\"node1\":{\"logLevel\":\"DEBUG\",\"logDisplayLevel\":\"DEBUG\"}}')",
+	)
+	cmd.PersistentFlags().StringSliceVar(
+		&bootstrapBeacons,
+		"bootstrap-beacons",
+		nil,
+		"names of the nodes to bootstrap from (e.g., 'node1'), all nodes if empty",
+	)
+	cmd.PersistentFlags().BoolVar(
+		&staticPeers,
+		"static-peers",
+		false,
+		"true to disable the peer gossip, so that the nodes only connect to their bootstrap beacons",
+	)
+	cmd.PersistentFlags().StringVar(
+		&nodeBootstrapPeers,
+		"node-bootstrap-peers",
+		"",
+		"JSON bootstrap peers by node name, instead of the bootstrap beacons (e.g., '{\"node1\":{\"names\":[\"node2\",\"node5\"]}}')",
+	)
+	cmd.PersistentFlags().StringVar(
+		&livenessMonitor,
+		"liveness-monitor",
+		"",
+		"JSON liveness monitor of the chain heights (e.g., '{\"chains\":[\"C\"],\"stallThreshold\":\"5m\",\"artifactsDir\":\"stalls\"}')",
+	)
+	cmd.PersistentFlags().StringVar(
+		&healthPolling,
+		"health-polling",
+		"",
+		"JSON backoff of the node health polls (e.g., '{\"initialInterval\":\"250ms\",\"maxInterval\":\"10s\"}')",
+	)
+	cmd.PersistentFlags().StringVar(
+		&seedActions,
+		"seed-actions",
+		"",
+		"JSON list of the seed actions run once healthy (e.g., '[{\"type\":\"create_asset\",\"name\":\"test\",\"symbol\":\"TST\",\"initialSupply\":\"1000\"}]')",
+	)
+	cmd.PersistentFlags().StringVar(&idempotencyKey, "idempotency-key", "", "idempotency key, for a retried request to get the result of the first one")
+	cmd.PersistentFlags().BoolVar(&skipResourceCheck, "skip-resource-check", false, "true to start even if the server host clearly cannot run the network")
+	cmd.PersistentFlags().StringSliceVar(&stateSyncNodes, "state-sync-nodes", nil, "names of the nodes that state-sync their C-chain (comma-separated)")
+	cmd.PersistentFlags().StringVar(
+		&nodeSidecars,
+		"node-sidecars",
+		"",
+		"JSON sidecar processes by node name (e.g., '{\"node1\":{\"sidecars\":[{\"name\":\"exporter\",\"execPath\":\"/usr/local/bin/exporter\",\"args\":[\"--target=$NODE_URI\"]}]}}')",
+	)
+	cmd.PersistentFlags().StringVar(
+		&databaseConfig,
+		"database-config",
+		"",
+		"JSON database of every node (e.g., '{\"type\":\"leveldb\",\"blockCacheBytes\":\"67108864\",\"writeBufferBytes\":\"33554432\"}')",
+	)
+	cmd.PersistentFlags().StringVar(
+		&nodeDatabases,
+		"node-database-configs",
+		"",
+		"JSON database configs by node name (e.g., '{\"node1\":{\"type\":\"memdb\"}}')",
+	)
+	cmd.PersistentFlags().StringSliceVar(&archivalNodes, "archival-nodes", nil, "names of the nodes that keep the full C-chain state history (comma-separated)")
+	cmd.PersistentFlags().StringSliceVar(&prunedNodes, "pruned-nodes", nil, "names of the nodes that prune the C-chain state (comma-separated), e.g., with an archival C-chain config")
+	cmd.PersistentFlags().DurationVar(&maxUnhealthyDuration, "max-unhealthy-duration", 0, "fail the network once a node stays unhealthy for longer (0 to disable), the server default if not set")
+	cmd.PersistentFlags().DurationVar(&staggeredStart, "staggered-start", 0, "delay between the node launches (e.g., '30s'), all at once if not set")
+	cmd.PersistentFlags().StringSliceVar(&diskFaultNodes, "disk-fault-nodes", nil, "names of the nodes whose database directory is on a disk device for set-disk-fault (comma-separated, Linux only)")
+	cmd.PersistentFlags().Uint64Var(&diskDeviceBytes, "disk-device-bytes", 0, "size of each disk fault device in bytes, 2 GiB if not set")
+	cmd.PersistentFlags().DurationVar(&checkpointInterval, "checkpoint-interval", 0, "checkpoint the node databases every interval (e.g., '30m'), none if not set")
+	cmd.PersistentFlags().Uint32Var(&checkpointRetention, "checkpoint-retention", 0, "number of checkpoints kept, 3 if not set")
+	cmd.PersistentFlags().StringVar(&resumeCheckpoint, "resume-checkpoint", "", "checkpoint directory (on the server host) to resume the node databases from")
+	cmd.PersistentFlags().StringVar(&pluginDir, "plugin-dir", "", "VM plugin directory of the nodes (defaults to the node default)")
+	cmd.PersistentFlags().StringVar(&buildDir, "build-dir", "", "build directory of the nodes (defaults to the node default)")
 	return cmd
 }
 
+// parseResourceLimits parses the JSON resource limits flags.
+func parseResourceLimits() (*rpcpb.ResourceLimits, map[string]*rpcpb.ResourceLimits, error) {
+	var rl *rpcpb.ResourceLimits
+	if resourceLimits != "" {
+		rl = &rpcpb.ResourceLimits{}
+		if err := protojson.Unmarshal([]byte(resourceLimits), rl); err != nil {
+			return nil, nil, invalidErr(fmt.Errorf("invalid resource limits: %w", err))
+		}
+	}
+	var nrl map[string]*rpcpb.ResourceLimits
+	if nodeResourceLimits != "" {
+		// parsed as the start request field, for the JSON names of the limits
+		req := &rpcpb.StartRequest{}
+		if err := protojson.Unmarshal([]byte(`{"nodeResourceLimits":`+nodeResourceLimits+`}`), req); err != nil {
+			return nil, nil, invalidErr(fmt.Errorf("invalid node resource limits: %w", err))
+		}
+		nrl = req.NodeResourceLimits
+	}
+	return rl, nrl, nil
+}
+
 func startFunc(cmd *cobra.Command, args []string) error {
+	var sp *rpcpb.StakingParams
+	if stakingParams != "" {
+		sp = &rpcpb.StakingParams{}
+		if err := protojson.Unmarshal([]byte(stakingParams), sp); err != nil {
+			return invalidErr(fmt.Errorf("invalid staking params: %w", err))
+		}
+	}
+
+	rl, nrl, err := parseResourceLimits()
+	if err != nil {
+		return err
+	}
+	var nll map[string]*rpcpb.NodeLogLevels
+	if nodeLogLevels != "" {
+		req := &rpcpb.StartRequest{}
+		if err := protojson.Unmarshal([]byte(`{"nodeLogLevels":`+nodeLogLevels+`}`), req); err != nil {
+			return invalidErr(fmt.Errorf("invalid node log levels: %w", err))
+		}
+		nll = req.NodeLogLevels
+	}
+	var nbp map[string]*rpcpb.NodeBootstrapPeers
+	if nodeBootstrapPeers != "" {
+		req := &rpcpb.StartRequest{}
+		if err := protojson.Unmarshal([]byte(`{"nodeBootstrapPeers":`+nodeBootstrapPeers+`}`), req); err != nil {
+			return invalidErr(fmt.Errorf("invalid node bootstrap peers: %w", err))
+		}
+		nbp = req.NodeBootstrapPeers
+	}
+	var lm *rpcpb.LivenessMonitor
+	if livenessMonitor != "" {
+		lm = &rpcpb.LivenessMonitor{}
+		if err := protojson.Unmarshal([]byte(livenessMonitor), lm); err != nil {
+			return invalidErr(fmt.Errorf("invalid liveness monitor: %w", err))
+		}
+	}
+	var hp *rpcpb.HealthPolling
+	if healthPolling != "" {
+		hp = &rpcpb.HealthPolling{}
+		if err := protojson.Unmarshal([]byte(healthPolling), hp); err != nil {
+			return invalidErr(fmt.Errorf("invalid health polling: %w", err))
+		}
+	}
+	var sa []*rpcpb.SeedAction
+	if seedActions != "" {
+		req := &rpcpb.StartRequest{}
+		if err := protojson.Unmarshal([]byte(`{"seedActions":`+seedActions+`}`), req); err != nil {
+			return invalidErr(fmt.Errorf("invalid seed actions: %w", err))
+		}
+		sa = req.SeedActions
+	}
+	var ns map[string]*rpcpb.NodeSidecars
+	if nodeSidecars != "" {
+		req := &rpcpb.StartRequest{}
+		if err := protojson.Unmarshal([]byte(`{"nodeSidecars":`+nodeSidecars+`}`), req); err != nil {
+			return invalidErr(fmt.Errorf("invalid node sidecars: %w", err))
+		}
+		ns = req.NodeSidecars
+	}
+	var db *rpcpb.DatabaseConfig
+	if databaseConfig != "" {
+		db = &rpcpb.DatabaseConfig{}
+		if err := protojson.Unmarshal([]byte(databaseConfig), db); err != nil {
+			return invalidErr(fmt.Errorf("invalid database config: %w", err))
+		}
+	}
+	var ndb map[string]*rpcpb.DatabaseConfig
+	if nodeDatabases != "" {
+		req := &rpcpb.StartRequest{}
+		if err := protojson.Unmarshal([]byte(`{"nodeDatabaseConfigs":`+nodeDatabases+`}`), req); err != nil {
+			return invalidErr(fmt.Errorf("invalid node database configs: %w", err))
+		}
+		ndb = req.NodeDatabaseConfigs
+	}
+	var na map[string]bool
+	for _, name := range archivalNodes {
+		if na == nil {
+			na = make(map[string]bool)
+		}
+		na[name] = true
+	}
+	for _, name := range prunedNodes {
+		if na[name] {
+			return invalidErr(fmt.Errorf("node %q is both archival and pruned", name))
+		}
+		if na == nil {
+			na = make(map[string]bool)
+		}
+		na[name] = false
+	}
+
+	sinks := make([]*rpcpb.LogSink, 0, len(logSinks))
+	for _, s := range logSinks {
+		sinks = append(sinks, parseLogSink(s))
+	}
+
+	balances := make([]*rpcpb.GenesisBalance, 0, len(genesisBalances))
+	for _, s := range genesisBalances {
+		ss := strings.SplitN(s, "=", 2)
+		if len(ss) != 2 {
+			return invalidErr(fmt.Errorf("invalid genesis balance %q (expected <address>=<amount>)", s))
+		}
+		amount, err := strconv.ParseUint(ss[1], 10, 64)
+		if err != nil {
+			return invalidErr(fmt.Errorf("invalid genesis balance amount %q: %w", ss[1], err))
+		}
+		balances = append(balances, &rpcpb.GenesisBalance{Address: ss[0], Amount: amount})
+	}
+
 	cli, err := client.New(client.Config{
 		LogLevel:    logLevel,
 		Endpoint:    endpoint,
 		DialTimeout: dialTimeout,
+		Token:       token,
 	})
 	if err != nil {
 		return err
 	}
 	defer cli.Close()
 
+	opts := []client.OpOption{
+		client.WithWhitelistedSubnets(whitelistedSubnets),
+		client.WithPreset(preset),
+		client.WithTemplate(template, templateParams),
+		client.WithNumNodes(numNodes),
+		client.WithGlobalNodeConfig(globalNodeConfig),
+		client.WithCChainConfig(cChainConfig),
+		client.WithCollectArtifactsOnStop(artifactsPath),
+		client.WithStakingParams(sp),
+		client.WithLogSinks(sinks...),
+		client.WithBasePort(basePort),
+		client.WithGenesisBalances(balances...),
+		client.WithResourceLimits(rl),
+		client.WithNodeResourceLimits(nrl),
+		client.WithNodeLogLevels(nll),
+		client.WithBootstrapBeacons(bootstrapBeacons...),
+		client.WithNodeBootstrapPeers(nbp),
+		client.WithLivenessMonitor(lm),
+		client.WithHealthPolling(hp),
+		client.WithSeedActions(sa...),
+		client.WithIdempotencyKey(idempotencyKey),
+		client.WithStateSyncNodes(stateSyncNodes...),
+		client.WithPluginDir(pluginDir),
+		client.WithBuildDir(buildDir),
+		client.WithNodeSidecars(ns),
+		client.WithNodeArchival(na),
+		client.WithDatabaseConfig(db),
+		client.WithNodeDatabaseConfigs(ndb),
+	}
+	if staticPeers {
+		opts = append(opts, client.WithStaticPeers())
+	}
+	if skipResourceCheck {
+		opts = append(opts, client.WithSkipResourceCheck())
+	}
+	if cmd.Flags().Changed("max-unhealthy-duration") {
+		opts = append(opts, client.WithMaxUnhealthyDuration(maxUnhealthyDuration))
+	}
+	if cmd.Flags().Changed("staggered-start") {
+		opts = append(opts, client.WithStaggeredStart(staggeredStart))
+	}
+	if cmd.Flags().Changed("disk-fault-nodes") {
+		opts = append(opts, client.WithDiskFaultNodes(diskFaultNodes...))
+	}
+	if cmd.Flags().Changed("disk-device-bytes") {
+		opts = append(opts, client.WithDiskDeviceBytes(diskDeviceBytes))
+	}
+	if cmd.Flags().Changed("checkpoint-interval") {
+		opts = append(opts, client.WithCheckpointInterval(checkpointInterval))
+	}
+	if cmd.Flags().Changed("checkpoint-retention") {
+		opts = append(opts, client.WithCheckpointRetention(checkpointRetention))
+	}
+	if resumeCheckpoint != "" {
+		opts = append(opts, client.WithResumeCheckpoint(resumeCheckpoint))
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
-	info, err := cli.Start(ctx, avalancheGoBinPath, client.WithWhitelistedSubnets(whitelistedSubnets))
+	info, err := cli.Start(ctx, avalancheGoBinPath, opts...)
 	cancel()
 	if err != nil {
 		return err
 	}
 
-	color.Outf("{{green}}start response:{{/}} %+v\n", info)
-	return nil
+	return printResponse("start", info)
+}
+
+// parseLogSink parses "<type>[:<path, address, or URL>]".
+func parseLogSink(s string) *rpcpb.LogSink {
+	ss := strings.SplitN(s, ":", 2)
+	sink := &rpcpb.LogSink{Type: ss[0]}
+	if len(ss) == 1 {
+		return sink
+	}
+	switch sink.Type {
+	case "file":
+		sink.Path = ss[1]
+	case "syslog":
+		sink.Address = ss[1]
+	case "loki":
+		sink.Url = ss[1]
+	}
+	return sink
 }
 
+var noWait bool
+
 func newHealthCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "health [options]",
 		Short: "Requests server health.",
 		RunE:  healthFunc,
 	}
+	cmd.PersistentFlags().BoolVar(&noWait, "no-wait", false, "report the node liveness and readiness without waiting for healthy")
 	return cmd
 }
 
@@ -115,6 +575,7 @@ func healthFunc(cmd *cobra.Command, args []string) error {
 		LogLevel:    logLevel,
 		Endpoint:    endpoint,
 		DialTimeout: dialTimeout,
+		Token:       token,
 	})
 	if err != nil {
 		return err
@@ -122,16 +583,61 @@ func healthFunc(cmd *cobra.Command, args []string) error {
 	defer cli.Close()
 
 	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
-	resp, err := cli.Health(ctx)
+	opts := []client.OpOption{}
+	if noWait {
+		opts = append(opts, client.WithNoWait())
+	}
+	resp, err := cli.Health(ctx, opts...)
 	cancel()
 	if err != nil {
 		return err
 	}
 
-	color.Outf("{{green}}health response:{{/}} %+v\n", resp)
+	if err := printResponse("health", resp); err != nil {
+		return err
+	}
+	if noWait && !resp.ClusterInfo.GetHealthy() {
+		return &exitError{code: ExitUnhealthy, err: errors.New("network is not healthy")}
+	}
 	return nil
 }
 
+var waitTimeout time.Duration
+
+func newWaitNodesHealthyCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "wait-nodes-healthy [options]",
+		Short: "Waits for the given nodes only to report healthy.",
+		RunE:  waitNodesHealthyFunc,
+	}
+	cmd.PersistentFlags().StringSliceVar(&nodeNames, "node-names", nil, "node names to wait for (comma-separated)")
+	cmd.PersistentFlags().DurationVar(&waitTimeout, "timeout", 2*time.Minute, "wait timeout")
+	return cmd
+}
+
+func waitNodesHealthyFunc(cmd *cobra.Command, args []string) error {
+	cli, err := client.New(client.Config{
+		LogLevel:    logLevel,
+		Endpoint:    endpoint,
+		DialTimeout: dialTimeout,
+		Token:       token,
+	})
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	// the wait may outlast the request timeout
+	ctx, cancel := context.WithTimeout(context.Background(), waitTimeout+requestTimeout)
+	resp, err := cli.WaitNodesHealthy(ctx, nodeNames, waitTimeout)
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	return printResponse("wait nodes healthy", resp)
+}
+
 func newURIsCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "uris [options]",
@@ -146,6 +652,7 @@ func urisFunc(cmd *cobra.Command, args []string) error {
 		LogLevel:    logLevel,
 		Endpoint:    endpoint,
 		DialTimeout: dialTimeout,
+		Token:       token,
 	})
 	if err != nil {
 		return err
@@ -159,6 +666,9 @@ func urisFunc(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	if outputFormat == outputJSON {
+		return printJSON(map[string][]string{"uris": uris})
+	}
 	color.Outf("{{green}}URIs:{{/}} %q\n", uris)
 	return nil
 }
@@ -177,6 +687,7 @@ func statusFunc(cmd *cobra.Command, args []string) error {
 		LogLevel:    logLevel,
 		Endpoint:    endpoint,
 		DialTimeout: dialTimeout,
+		Token:       token,
 	})
 	if err != nil {
 		return err
@@ -190,11 +701,14 @@ func statusFunc(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	color.Outf("{{green}}status response:{{/}} %+v\n", resp)
-	return nil
+	return printResponse("status", resp)
 }
 
-var pushInterval time.Duration
+var (
+	pushInterval     time.Duration
+	sendOnlyOnChange bool
+	statusDelta      bool
+)
 
 func newStreamStatusCommand() *cobra.Command {
 	cmd := &cobra.Command{
@@ -208,6 +722,18 @@ func newStreamStatusCommand() *cobra.Command {
 		5*time.Second,
 		"interval that server pushes status updates to the client",
 	)
+	cmd.PersistentFlags().BoolVar(
+		&sendOnlyOnChange,
+		"send-only-on-change",
+		false,
+		"true to skip the status updates where the cluster info has not changed",
+	)
+	cmd.PersistentFlags().BoolVar(
+		&statusDelta,
+		"delta",
+		false,
+		"true to receive only the changed nodes and fields after the first status update",
+	)
 	return cmd
 }
 
@@ -216,6 +742,7 @@ func streamStatusFunc(cmd *cobra.Command, args []string) error {
 		LogLevel:    logLevel,
 		Endpoint:    endpoint,
 		DialTimeout: dialTimeout,
+		Token:       token,
 	})
 	if err != nil {
 		return err
@@ -238,11 +765,24 @@ func streamStatusFunc(cmd *cobra.Command, args []string) error {
 		close(donec)
 	}()
 
-	ch, err := cli.StreamStatus(ctx, pushInterval)
+	opts := []client.OpOption{}
+	if sendOnlyOnChange {
+		opts = append(opts, client.WithSendOnlyOnChange())
+	}
+	if statusDelta {
+		opts = append(opts, client.WithStatusDelta())
+	}
+	ch, err := cli.StreamStatus(ctx, pushInterval, opts...)
 	if err != nil {
 		return err
 	}
 	for info := range ch {
+		if outputFormat == outputJSON {
+			if err := printResponse("cluster info", info); err != nil {
+				zap.L().Warn("failed to print the cluster info", zap.Error(err))
+			}
+			continue
+		}
 		color.Outf("{{cyan}}cluster info:{{/}} %+v\n", info)
 	}
 	cancel() // receiver channel is closed, so cancel goroutine
@@ -267,6 +807,7 @@ func removeNodeFunc(cmd *cobra.Command, args []string) error {
 		LogLevel:    logLevel,
 		Endpoint:    endpoint,
 		DialTimeout: dialTimeout,
+		Token:       token,
 	})
 	if err != nil {
 		return err
@@ -280,8 +821,7 @@ func removeNodeFunc(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	color.Outf("{{green}}remove node response:{{/}} %+v\n", info)
-	return nil
+	return printResponse("remove node", info)
 }
 
 func newRestartNodeCommand() *cobra.Command {
@@ -316,6 +856,7 @@ func restartNodeFunc(cmd *cobra.Command, args []string) error {
 		LogLevel:    logLevel,
 		Endpoint:    endpoint,
 		DialTimeout: dialTimeout,
+		Token:       token,
 	})
 	if err != nil {
 		return err
@@ -329,8 +870,93 @@ func restartNodeFunc(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	color.Outf("{{green}}restart node response:{{/}} %+v\n", info)
-	return nil
+	return printResponse("restart node", info)
+}
+
+var (
+	nodeNames   []string
+	parallelism uint32
+)
+
+func newRemoveNodesCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "remove-nodes [options]",
+		Short: "Removes nodes in parallel.",
+		RunE:  removeNodesFunc,
+	}
+	cmd.PersistentFlags().StringSliceVar(&nodeNames, "node-names", nil, "node names to remove (comma-separated)")
+	cmd.PersistentFlags().Uint32Var(&parallelism, "parallelism", 0, "maximum number of concurrent node operations (0 for all at once)")
+	return cmd
+}
+
+func removeNodesFunc(cmd *cobra.Command, args []string) error {
+	cli, err := client.New(client.Config{
+		LogLevel:    logLevel,
+		Endpoint:    endpoint,
+		DialTimeout: dialTimeout,
+		Token:       token,
+	})
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	resp, err := cli.RemoveNodes(ctx, nodeNames, client.WithParallelism(parallelism))
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	return printResponse("remove nodes", resp)
+}
+
+func newRestartNodesCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "restart-nodes [options]",
+		Short: "Restarts nodes in parallel.",
+		RunE:  restartNodesFunc,
+	}
+	cmd.PersistentFlags().StringSliceVar(&nodeNames, "node-names", nil, "node names to restart (comma-separated)")
+	cmd.PersistentFlags().Uint32Var(&parallelism, "parallelism", 0, "maximum number of concurrent node operations (0 for all at once)")
+	cmd.PersistentFlags().StringVar(
+		&avalancheGoBinPath,
+		"avalanchego-path",
+		"",
+		"avalanchego binary path",
+	)
+	cmd.PersistentFlags().StringVar(
+		&whitelistedSubnets,
+		"whitelisted-subnets",
+		"",
+		"whitelisted subnets (comma-separated)",
+	)
+	return cmd
+}
+
+func restartNodesFunc(cmd *cobra.Command, args []string) error {
+	cli, err := client.New(client.Config{
+		LogLevel:    logLevel,
+		Endpoint:    endpoint,
+		DialTimeout: dialTimeout,
+		Token:       token,
+	})
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	resp, err := cli.RestartNodes(ctx, nodeNames, avalancheGoBinPath,
+		client.WithWhitelistedSubnets(whitelistedSubnets),
+		client.WithParallelism(parallelism),
+	)
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	return printResponse("restart nodes", resp)
 }
 
 func newStopCommand() *cobra.Command {
@@ -339,6 +965,7 @@ func newStopCommand() *cobra.Command {
 		Short: "Requests server stop.",
 		RunE:  stopFunc,
 	}
+	cmd.PersistentFlags().StringVar(&idempotencyKey, "idempotency-key", "", "idempotency key, for a retried request to get the result of the first one")
 	return cmd
 }
 
@@ -347,6 +974,7 @@ func stopFunc(cmd *cobra.Command, args []string) error {
 		LogLevel:    logLevel,
 		Endpoint:    endpoint,
 		DialTimeout: dialTimeout,
+		Token:       token,
 	})
 	if err != nil {
 		return err
@@ -354,12 +982,1482 @@ func stopFunc(cmd *cobra.Command, args []string) error {
 	defer cli.Close()
 
 	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
-	info, err := cli.Stop(ctx)
+	info, err := cli.Stop(ctx, client.WithIdempotencyKey(idempotencyKey))
 	cancel()
 	if err != nil {
 		return err
 	}
 
-	color.Outf("{{green}}stop response:{{/}} %+v\n", info)
-	return nil
+	return printResponse("stop", info)
+}
+
+func newAbortStartCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "abort-start [options]",
+		Short: "Aborts an in-flight start, tearing down the launched nodes.",
+		RunE:  abortStartFunc,
+	}
+	return cmd
+}
+
+func abortStartFunc(cmd *cobra.Command, args []string) error {
+	cli, err := client.New(client.Config{
+		LogLevel:    logLevel,
+		Endpoint:    endpoint,
+		DialTimeout: dialTimeout,
+		Token:       token,
+	})
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	resp, err := cli.AbortStart(ctx)
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	return printResponse("abort start", resp)
+}
+
+var (
+	pluginDir string
+	buildDir  string
+)
+
+func newCheckBinaryCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "check-binary [options]",
+		Short: "Validates an avalanchego binary on the server host.",
+		RunE:  checkBinaryFunc,
+	}
+	cmd.PersistentFlags().StringVar(
+		&avalancheGoBinPath,
+		"avalanchego-path",
+		"",
+		"avalanchego binary path",
+	)
+	cmd.PersistentFlags().StringVar(
+		&pluginDir,
+		"plugin-dir",
+		"",
+		"VM plugin directory (defaults to the 'plugins' directory next to the binary)",
+	)
+	return cmd
+}
+
+func checkBinaryFunc(cmd *cobra.Command, args []string) error {
+	cli, err := client.New(client.Config{
+		LogLevel:    logLevel,
+		Endpoint:    endpoint,
+		DialTimeout: dialTimeout,
+		Token:       token,
+	})
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	resp, err := cli.CheckBinary(ctx, avalancheGoBinPath, client.WithPluginDir(pluginDir))
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	return printResponse("check binary", resp)
+}
+
+var blockchainID string
+
+func newCreateChainIPCCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "create-chain-ipc [options]",
+		Short: "Publishes IPC sockets for a chain.",
+		RunE:  createChainIPCFunc,
+	}
+	cmd.PersistentFlags().StringVar(&blockchainID, "blockchain-id", "", "blockchain ID (or alias) to publish")
+	cmd.PersistentFlags().StringVar(&nodeName, "node-name", "", "node name to publish on (empty for all nodes)")
+	return cmd
+}
+
+func createChainIPCFunc(cmd *cobra.Command, args []string) error {
+	cli, err := client.New(client.Config{
+		LogLevel:    logLevel,
+		Endpoint:    endpoint,
+		DialTimeout: dialTimeout,
+		Token:       token,
+	})
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	resp, err := cli.CreateChainIPC(ctx, blockchainID, client.WithNodeName(nodeName))
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	return printResponse("create chain IPC", resp)
+}
+
+func newRemoveChainIPCCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "remove-chain-ipc [options]",
+		Short: "Unpublishes IPC sockets for a chain.",
+		RunE:  removeChainIPCFunc,
+	}
+	cmd.PersistentFlags().StringVar(&blockchainID, "blockchain-id", "", "blockchain ID (or alias) to unpublish")
+	cmd.PersistentFlags().StringVar(&nodeName, "node-name", "", "node name to unpublish on (empty for all nodes)")
+	return cmd
+}
+
+func removeChainIPCFunc(cmd *cobra.Command, args []string) error {
+	cli, err := client.New(client.Config{
+		LogLevel:    logLevel,
+		Endpoint:    endpoint,
+		DialTimeout: dialTimeout,
+		Token:       token,
+	})
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	resp, err := cli.RemoveChainIPC(ctx, blockchainID, client.WithNodeName(nodeName))
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	return printResponse("remove chain IPC", resp)
+}
+
+func newListChainIPCsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list-chain-ipcs [options]",
+		Short: "Lists the published IPC sockets of the chains.",
+		RunE:  listChainIPCsFunc,
+	}
+	cmd.PersistentFlags().StringVar(&nodeName, "node-name", "", "node name to list (empty for all nodes)")
+	return cmd
+}
+
+func listChainIPCsFunc(cmd *cobra.Command, args []string) error {
+	cli, err := client.New(client.Config{
+		LogLevel:    logLevel,
+		Endpoint:    endpoint,
+		DialTimeout: dialTimeout,
+		Token:       token,
+	})
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	resp, err := cli.ListChainIPCs(ctx, client.WithNodeName(nodeName))
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	return printResponse("list chain IPCs", resp)
+}
+
+var (
+	ipcFeed      string
+	includeBytes bool
+)
+
+func newTailChainIPCCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tail-chain-ipc [options]",
+		Short: "Streams the accepted containers of a published chain IPC.",
+		RunE:  tailChainIPCFunc,
+	}
+	cmd.PersistentFlags().StringVar(&nodeName, "node-name", "", "node name to tail")
+	cmd.PersistentFlags().StringVar(&blockchainID, "blockchain-id", "", "blockchain ID (or alias), as published")
+	cmd.PersistentFlags().StringVar(&ipcFeed, "feed", "both", "feed to tail (consensus, decisions, or both)")
+	cmd.PersistentFlags().BoolVar(&includeBytes, "include-bytes", false, "true to receive the container bytes")
+	return cmd
+}
+
+func tailChainIPCFunc(cmd *cobra.Command, args []string) error {
+	feed := rpcpb.ChainIPCFeed_CHAIN_IPC_FEED_UNSPECIFIED
+	if ipcFeed != "both" {
+		v, ok := rpcpb.ChainIPCFeed_value["CHAIN_IPC_FEED_"+strings.ToUpper(ipcFeed)]
+		if !ok || v == int32(rpcpb.ChainIPCFeed_CHAIN_IPC_FEED_UNSPECIFIED) {
+			return invalidErr(fmt.Errorf("invalid feed %q", ipcFeed))
+		}
+		feed = rpcpb.ChainIPCFeed(v)
+	}
+
+	cli, err := client.New(client.Config{
+		LogLevel:    logLevel,
+		Endpoint:    endpoint,
+		DialTimeout: dialTimeout,
+		Token:       token,
+	})
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	// stream until the request timeout or os signal
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, syscall.SIGINT, syscall.SIGTERM)
+
+	donec := make(chan struct{})
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	go func() {
+		select {
+		case sig := <-sigc:
+			zap.L().Warn("received signal", zap.String("signal", sig.String()))
+		case <-ctx.Done():
+		}
+		cancel()
+		close(donec)
+	}()
+
+	opts := []client.OpOption{}
+	if includeBytes {
+		opts = append(opts, client.WithIncludeBytes())
+	}
+	ch, err := cli.TailChainIPC(ctx, nodeName, blockchainID, feed, opts...)
+	if err != nil {
+		cancel()
+		return err
+	}
+	for ev := range ch {
+		if outputFormat == outputJSON {
+			if err := printResponse("chain IPC event", ev); err != nil {
+				zap.L().Warn("failed to print the chain IPC event", zap.Error(err))
+			}
+			continue
+		}
+		feedName := strings.ToLower(strings.TrimPrefix(ev.Feed.String(), "CHAIN_IPC_FEED_"))
+		if ev.ContainerType == rpcpb.ChainIPCContainerType_CHAIN_IPC_CONTAINER_TYPE_UNSPECIFIED {
+			color.Outf("{{cyan}}[%s %s %s #%d]{{/}} %s (%d bytes)\n", ev.NodeName, ev.BlockchainId, feedName, ev.Seq, ev.ContainerId, ev.Size)
+			continue
+		}
+		typeName := strings.ToLower(strings.TrimPrefix(ev.ContainerType.String(), "CHAIN_IPC_CONTAINER_TYPE_"))
+		color.Outf("{{cyan}}[%s %s %s #%d]{{/}} %s %s (%d bytes, height %d, parents %v, %d txs)\n", ev.NodeName, ev.BlockchainId, feedName, ev.Seq, typeName, ev.ContainerId, ev.Size, ev.Height, ev.ParentIds, len(ev.TxIds))
+	}
+	cancel() // receiver channel is closed, so cancel goroutine
+	<-donec
+	return nil
+}
+
+var consoleStdin bool
+
+func newAttachConsoleCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "attach-console [options]",
+		Short: "Streams the outputs of a node.",
+		RunE:  attachConsoleFunc,
+	}
+	cmd.PersistentFlags().StringVar(&nodeName, "node-name", "", "node name to attach to")
+	cmd.PersistentFlags().BoolVar(&consoleStdin, "stdin", false, "true to write the standard input to the stdin of the node")
+	return cmd
+}
+
+func attachConsoleFunc(cmd *cobra.Command, args []string) error {
+	cli, err := client.New(client.Config{
+		LogLevel:    logLevel,
+		Endpoint:    endpoint,
+		DialTimeout: dialTimeout,
+		Token:       token,
+	})
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	// stream until the request timeout or os signal
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, syscall.SIGINT, syscall.SIGTERM)
+
+	donec := make(chan struct{})
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	go func() {
+		select {
+		case sig := <-sigc:
+			zap.L().Warn("received signal", zap.String("signal", sig.String()))
+		case <-ctx.Done():
+		}
+		cancel()
+		close(donec)
+	}()
+
+	opts := []client.OpOption{}
+	if consoleStdin {
+		opts = append(opts, client.WithStdin(os.Stdin))
+	}
+	ch, err := cli.AttachConsole(ctx, nodeName, opts...)
+	if err != nil {
+		cancel()
+		return err
+	}
+	for resp := range ch {
+		if outputFormat == outputJSON {
+			if err := printResponse("attach console", resp); err != nil {
+				zap.L().Warn("failed to print the console line", zap.Error(err))
+			}
+			continue
+		}
+		if resp.Stream == "stderr" {
+			color.Errf("{{red}}[%s]{{/}} %s\n", resp.NodeName, resp.Line)
+			continue
+		}
+		color.Outf("{{cyan}}[%s]{{/}} %s\n", resp.NodeName, resp.Line)
+	}
+	cancel() // receiver channel is closed, so cancel goroutine
+	<-donec
+	return nil
+}
+
+var crashMode string
+
+func newInjectFaultCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "inject-fault [options]",
+		Short: "Crashes a node.",
+		RunE:  injectFaultFunc,
+	}
+	cmd.PersistentFlags().StringVar(&nodeName, "node-name", "", "node name to crash")
+	cmd.PersistentFlags().StringVar(&crashMode, "crash-mode", "sigkill", "crash mode (sigkill, sigsegv, or oom)")
+	return cmd
+}
+
+func injectFaultFunc(cmd *cobra.Command, args []string) error {
+	mode, ok := rpcpb.CrashMode_value["CRASH_MODE_"+strings.ToUpper(crashMode)]
+	if !ok || mode == int32(rpcpb.CrashMode_CRASH_MODE_UNSPECIFIED) {
+		return invalidErr(fmt.Errorf("invalid crash mode %q", crashMode))
+	}
+
+	cli, err := client.New(client.Config{
+		LogLevel:    logLevel,
+		Endpoint:    endpoint,
+		DialTimeout: dialTimeout,
+		Token:       token,
+	})
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	resp, err := cli.InjectFault(ctx, nodeName, rpcpb.CrashMode(mode))
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	return printResponse("inject fault", resp)
+}
+
+var (
+	diskFaultMode string
+	readDelay     time.Duration
+	writeDelay    time.Duration
+	upInterval    time.Duration
+	downInterval  time.Duration
+	errorWrites   bool
+)
+
+func newSetDiskFaultCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "set-disk-fault [options]",
+		Short: "Slows down or fails the database I/O of a node.",
+		RunE:  setDiskFaultFunc,
+	}
+	cmd.PersistentFlags().StringVar(&nodeName, "node-name", "", "node name (started with --disk-fault-nodes)")
+	cmd.PersistentFlags().StringVar(&diskFaultMode, "mode", "none", "fault mode (none, delay, error, or flakey), none clears the fault")
+	cmd.PersistentFlags().DurationVar(&readDelay, "read-delay", 0, "delay mode: delay of the reads (e.g., '100ms')")
+	cmd.PersistentFlags().DurationVar(&writeDelay, "write-delay", 0, "delay mode: delay of the writes (e.g., '100ms')")
+	cmd.PersistentFlags().DurationVar(&upInterval, "up-interval", 0, "flakey mode: time the I/O works (whole seconds)")
+	cmd.PersistentFlags().DurationVar(&downInterval, "down-interval", 0, "flakey mode: time the I/O fails (whole seconds)")
+	cmd.PersistentFlags().BoolVar(&errorWrites, "error-writes", false, "flakey mode: fail the writes only")
+	return cmd
+}
+
+func setDiskFaultFunc(cmd *cobra.Command, args []string) error {
+	var fault *rpcpb.DiskFault
+	switch diskFaultMode {
+	case "none":
+	case "delay":
+		fault = &rpcpb.DiskFault{Mode: rpcpb.DiskFaultMode_DISK_FAULT_MODE_DELAY}
+		if readDelay > 0 {
+			fault.ReadDelay = readDelay.String()
+		}
+		if writeDelay > 0 {
+			fault.WriteDelay = writeDelay.String()
+		}
+	case "error":
+		fault = &rpcpb.DiskFault{Mode: rpcpb.DiskFaultMode_DISK_FAULT_MODE_ERROR}
+	case "flakey":
+		fault = &rpcpb.DiskFault{
+			Mode:         rpcpb.DiskFaultMode_DISK_FAULT_MODE_FLAKEY,
+			UpInterval:   upInterval.String(),
+			DownInterval: downInterval.String(),
+			ErrorWrites:  errorWrites,
+		}
+	default:
+		return invalidErr(fmt.Errorf("invalid disk fault mode %q", diskFaultMode))
+	}
+
+	cli, err := client.New(client.Config{
+		LogLevel:    logLevel,
+		Endpoint:    endpoint,
+		DialTimeout: dialTimeout,
+		Token:       token,
+	})
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	resp, err := cli.SetDiskFault(ctx, nodeName, fault)
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	return printResponse("set disk fault", resp)
+}
+
+var subnetID string
+
+func newGetValidatorsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "get-validators [options]",
+		Short: "Lists the current and pending validators of a subnet.",
+		RunE:  getValidatorsFunc,
+	}
+	cmd.PersistentFlags().StringVar(&subnetID, "subnet-id", "", "subnet ID (empty for the primary network)")
+	cmd.PersistentFlags().StringVar(&nodeName, "node-name", "", "node name to query (empty for the first node)")
+	return cmd
+}
+
+func getValidatorsFunc(cmd *cobra.Command, args []string) error {
+	cli, err := client.New(client.Config{
+		LogLevel:    logLevel,
+		Endpoint:    endpoint,
+		DialTimeout: dialTimeout,
+		Token:       token,
+	})
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	resp, err := cli.GetValidators(ctx, subnetID, client.WithNodeName(nodeName))
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	return printResponse("get validators", resp)
+}
+
+var setTime string
+
+func newSetTimeCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "set-time [options]",
+		Short: "Sets the clock of all nodes (test builds only).",
+		RunE:  setTimeFunc,
+	}
+	cmd.PersistentFlags().StringVar(&setTime, "time", "", "RFC 3339 time (e.g., 2022-01-01T00:00:00Z)")
+	return cmd
+}
+
+func setTimeFunc(cmd *cobra.Command, args []string) error {
+	t, err := time.Parse(time.RFC3339, setTime)
+	if err != nil {
+		return invalidErr(fmt.Errorf("invalid time %q: %w", setTime, err))
+	}
+
+	cli, err := client.New(client.Config{
+		LogLevel:    logLevel,
+		Endpoint:    endpoint,
+		DialTimeout: dialTimeout,
+		Token:       token,
+	})
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	resp, err := cli.SetTime(ctx, t)
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	return printResponse("set time", resp)
+}
+
+var advanceDuration time.Duration
+
+func newAdvanceTimeCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "advance-time [options]",
+		Short: "Advances the clock of all nodes (test builds only).",
+		RunE:  advanceTimeFunc,
+	}
+	cmd.PersistentFlags().DurationVar(&advanceDuration, "duration", 0, "duration to advance the clocks by (e.g., 1h)")
+	return cmd
+}
+
+func advanceTimeFunc(cmd *cobra.Command, args []string) error {
+	cli, err := client.New(client.Config{
+		LogLevel:    logLevel,
+		Endpoint:    endpoint,
+		DialTimeout: dialTimeout,
+		Token:       token,
+	})
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	resp, err := cli.AdvanceTime(ctx, advanceDuration)
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	return printResponse("advance time", resp)
+}
+
+var (
+	topologyFormat string
+	topologyOutput string
+)
+
+func newRenderTopologyCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "render-topology [options]",
+		Short: "Renders the nodes, peers, subnets, chains, and validators as a graph.",
+		RunE:  renderTopologyFunc,
+	}
+	cmd.PersistentFlags().StringVar(&topologyFormat, "format", "dot", "graph format (dot or mermaid)")
+	cmd.PersistentFlags().StringVar(&topologyOutput, "output", "", "file to write the graph to, stdout if empty")
+	return cmd
+}
+
+func renderTopologyFunc(cmd *cobra.Command, args []string) error {
+	format, ok := rpcpb.TopologyFormat_value["TOPOLOGY_FORMAT_"+strings.ToUpper(topologyFormat)]
+	if !ok || format == int32(rpcpb.TopologyFormat_TOPOLOGY_FORMAT_UNSPECIFIED) {
+		return invalidErr(fmt.Errorf("invalid topology format %q", topologyFormat))
+	}
+
+	cli, err := client.New(client.Config{
+		LogLevel:    logLevel,
+		Endpoint:    endpoint,
+		DialTimeout: dialTimeout,
+		Token:       token,
+	})
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	resp, err := cli.RenderTopology(ctx, rpcpb.TopologyFormat(format))
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	if outputFormat == outputJSON && topologyOutput == "" {
+		return printResponse("render topology", resp)
+	}
+	if topologyOutput == "" {
+		color.Outf("{{green}}topology:{{/}}\n")
+		fmt.Print(resp.Graph)
+		return nil
+	}
+	if err := ioutil.WriteFile(topologyOutput, []byte(resp.Graph), 0o644); err != nil {
+		return err
+	}
+	if outputFormat == outputJSON {
+		return printJSON(map[string]string{"output": topologyOutput})
+	}
+	color.Outf("{{green}}wrote topology to %q{{/}}\n", topologyOutput)
+	return nil
+}
+
+var (
+	vmName      string
+	genesisPath string
+	chainName   string
+)
+
+func newCreateBlockchainCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "create-blockchain [options]",
+		Short: "Creates a blockchain on a subnet of the running network.",
+		RunE:  createBlockchainFunc,
+	}
+	cmd.PersistentFlags().StringVar(&subnetID, "subnet-id", "", "subnet ID (controlled by the genesis key)")
+	cmd.PersistentFlags().StringVar(&vmName, "vm-name", "", "VM name (e.g., subnetevm)")
+	cmd.PersistentFlags().StringVar(&genesisPath, "genesis-path", "", "blockchain genesis file path")
+	cmd.PersistentFlags().StringVar(&chainName, "chain-name", "", "blockchain name, the VM name if empty")
+	return cmd
+}
+
+func createBlockchainFunc(cmd *cobra.Command, args []string) error {
+	var genesis []byte
+	if genesisPath != "" {
+		var err error
+		genesis, err = ioutil.ReadFile(genesisPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	cli, err := client.New(client.Config{
+		LogLevel:    logLevel,
+		Endpoint:    endpoint,
+		DialTimeout: dialTimeout,
+		Token:       token,
+	})
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	resp, err := cli.CreateBlockchain(ctx, subnetID, vmName, genesis, client.WithChainName(chainName))
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	return printResponse("create blockchain", resp)
+}
+
+var (
+	trafficChain string
+	trafficPath  string
+	speedup      float64
+)
+
+func newRecordTrafficCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "record-traffic [options]",
+		Short: "Records the accepted txs of a chain, until the request timeout or os signal.",
+		RunE:  recordTrafficFunc,
+	}
+	cmd.PersistentFlags().StringVar(&trafficChain, "chain", traffic.ChainC, "chain to record ('X' requires the node flag 'index-enabled', or 'C')")
+	cmd.PersistentFlags().StringVar(&trafficPath, "output", "", "file to write the txs to (JSON lines)")
+	return cmd
+}
+
+func recordTrafficFunc(cmd *cobra.Command, args []string) error {
+	if trafficPath == "" {
+		return invalidErr(errors.New("empty output path"))
+	}
+	uri, err := firstURI()
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(trafficPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// record until the request timeout or os signal
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, syscall.SIGINT, syscall.SIGTERM)
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	go func() {
+		select {
+		case sig := <-sigc:
+			zap.L().Warn("received signal", zap.String("signal", sig.String()))
+		case <-ctx.Done():
+		}
+		cancel()
+	}()
+
+	n, err := traffic.Record(ctx, uri, trafficChain, f)
+	if err != nil {
+		return err
+	}
+	if outputFormat == outputJSON {
+		return printJSON(map[string]interface{}{"txs": n, "output": trafficPath})
+	}
+	color.Outf("{{green}}recorded %d txs to %q{{/}}\n", n, trafficPath)
+	return nil
+}
+
+func newReplayTrafficCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "replay-traffic [options]",
+		Short: "Replays the recorded txs against the running network.",
+		RunE:  replayTrafficFunc,
+	}
+	cmd.PersistentFlags().StringVar(&trafficPath, "input", "", "file of the recorded txs")
+	cmd.PersistentFlags().Float64Var(&speedup, "speedup", 1, "pacing speedup against the recording (e.g., 2 for twice as fast), 0 to replay as fast as possible")
+	return cmd
+}
+
+func replayTrafficFunc(cmd *cobra.Command, args []string) error {
+	uri, err := firstURI()
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(trafficPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	n, err := traffic.Replay(ctx, uri, f, speedup)
+	cancel()
+	if err != nil {
+		return fmt.Errorf("replayed %d txs: %w", n, err)
+	}
+	if outputFormat == outputJSON {
+		return printJSON(map[string]interface{}{"txs": n, "input": trafficPath})
+	}
+	color.Outf("{{green}}replayed %d txs from %q{{/}}\n", n, trafficPath)
+	return nil
+}
+
+// firstURI returns the first URI of the running network.
+func firstURI() (string, error) {
+	cli, err := client.New(client.Config{
+		LogLevel:    logLevel,
+		Endpoint:    endpoint,
+		DialTimeout: dialTimeout,
+		Token:       token,
+	})
+	if err != nil {
+		return "", err
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	uris, err := cli.URIs(ctx)
+	cancel()
+	if err != nil {
+		return "", err
+	}
+	if len(uris) == 0 {
+		return "", errors.New("no node URIs")
+	}
+	return uris[0], nil
+}
+
+var (
+	nodeA            string
+	nodeB            string
+	trafficDirection string
+)
+
+func newBlockTrafficCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "block-traffic [options]",
+		Short: "Blocks the staking connections between two nodes.",
+		RunE:  blockTrafficFunc,
+	}
+	addTrafficFlags(cmd)
+	return cmd
+}
+
+func newUnblockTrafficCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "unblock-traffic [options]",
+		Short: "Unblocks the staking connections between two nodes.",
+		RunE:  unblockTrafficFunc,
+	}
+	addTrafficFlags(cmd)
+	return cmd
+}
+
+func addTrafficFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&nodeA, "node-a", "", "node name (e.g., node1)")
+	cmd.PersistentFlags().StringVar(&nodeB, "node-b", "", "other node name (e.g., node2)")
+	cmd.PersistentFlags().StringVar(&trafficDirection, "direction", "both", "blocked connections, only 'both' (the nodes would reconnect the other way over a one-way block)")
+}
+
+func parseTrafficDirection() (rpcpb.TrafficDirection, error) {
+	dir, ok := rpcpb.TrafficDirection_value["TRAFFIC_DIRECTION_"+strings.ToUpper(trafficDirection)]
+	if !ok || dir == int32(rpcpb.TrafficDirection_TRAFFIC_DIRECTION_UNSPECIFIED) {
+		return 0, invalidErr(fmt.Errorf("invalid traffic direction %q", trafficDirection))
+	}
+	return rpcpb.TrafficDirection(dir), nil
+}
+
+func blockTrafficFunc(cmd *cobra.Command, args []string) error {
+	dir, err := parseTrafficDirection()
+	if err != nil {
+		return err
+	}
+
+	cli, err := client.New(client.Config{
+		LogLevel:    logLevel,
+		Endpoint:    endpoint,
+		DialTimeout: dialTimeout,
+		Token:       token,
+	})
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	resp, err := cli.BlockTraffic(ctx, nodeA, nodeB, dir)
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	return printResponse("block traffic", resp)
+}
+
+func unblockTrafficFunc(cmd *cobra.Command, args []string) error {
+	dir, err := parseTrafficDirection()
+	if err != nil {
+		return err
+	}
+
+	cli, err := client.New(client.Config{
+		LogLevel:    logLevel,
+		Endpoint:    endpoint,
+		DialTimeout: dialTimeout,
+		Token:       token,
+	})
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	resp, err := cli.UnblockTraffic(ctx, nodeA, nodeB, dir)
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	return printResponse("unblock traffic", resp)
+}
+
+var (
+	uploadLocalPath  string
+	uploadRemotePath string
+)
+
+func newUploadFileCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "upload-file [options]",
+		Short: "Uploads a file (e.g., a node binary, a VM plugin, or a genesis) to the server host.",
+		RunE:  uploadFileFunc,
+	}
+	cmd.PersistentFlags().StringVar(&uploadLocalPath, "path", "", "local file to upload")
+	cmd.PersistentFlags().StringVar(&uploadRemotePath, "remote-path", "", "path relative to the server upload directory (e.g., v1.7.3/avalanchego)")
+	return cmd
+}
+
+func uploadFileFunc(cmd *cobra.Command, args []string) error {
+	cli, err := client.New(client.Config{
+		LogLevel:    logLevel,
+		Endpoint:    endpoint,
+		DialTimeout: dialTimeout,
+		Token:       token,
+	})
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	resp, err := cli.UploadFile(ctx, uploadLocalPath, uploadRemotePath)
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	return printResponse("upload file", resp)
+}
+
+func newGetNodeConfigCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "get-node-config [options]",
+		Short: "Prints the config files that a node runs with.",
+		RunE:  getNodeConfigFunc,
+	}
+	cmd.PersistentFlags().StringVar(&nodeName, "node-name", "", "node name")
+	return cmd
+}
+
+func getNodeConfigFunc(cmd *cobra.Command, args []string) error {
+	cli, err := client.New(client.Config{
+		LogLevel:    logLevel,
+		Endpoint:    endpoint,
+		DialTimeout: dialTimeout,
+		Token:       token,
+	})
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	resp, err := cli.GetNodeConfig(ctx, nodeName)
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	if outputFormat == outputJSON {
+		return printResponse("get node config", resp)
+	}
+	color.Outf("{{green}}exec path:{{/}} %s\n", resp.ExecPath)
+	color.Outf("{{green}}node config:{{/}}\n%s\n", resp.Config)
+	if resp.CChainConfig != "" {
+		color.Outf("{{green}}C-chain config:{{/}}\n%s\n", resp.CChainConfig)
+	}
+	return nil
+}
+
+func newRotateNodeKeyCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rotate-node-key [options]",
+		Short: "Restarts a node with a new staking key (node ID).",
+		RunE:  rotateNodeKeyFunc,
+	}
+	cmd.PersistentFlags().StringVar(&nodeName, "node-name", "", "node name to rotate the key of")
+	return cmd
+}
+
+func rotateNodeKeyFunc(cmd *cobra.Command, args []string) error {
+	cli, err := client.New(client.Config{
+		LogLevel:    logLevel,
+		Endpoint:    endpoint,
+		DialTimeout: dialTimeout,
+		Token:       token,
+	})
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	resp, err := cli.RotateNodeKey(ctx, nodeName)
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	return printResponse("rotate node key", resp)
+}
+
+var (
+	observer  bool
+	stateSync bool
+	archival  bool
+)
+
+func newAddNodeCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "add-node [options]",
+		Short: "Adds a node to the network.",
+		RunE:  addNodeFunc,
+	}
+	cmd.PersistentFlags().StringVar(&nodeName, "node-name", "", "node name to add")
+	cmd.PersistentFlags().StringVar(&avalancheGoBinPath, "avalanchego-path", "", "avalanchego binary path (empty for the network binary)")
+	cmd.PersistentFlags().BoolVar(&observer, "observer", false, "true to add a non-validating observer node")
+	cmd.PersistentFlags().BoolVar(&stateSync, "state-sync", false, "true to state-sync the C-chain of the node from the network")
+	cmd.PersistentFlags().BoolVar(&archival, "archival", false, "true for an archival node, false for a pruned node (as the C-chain config of the network if not set)")
+	cmd.PersistentFlags().StringVar(&idempotencyKey, "idempotency-key", "", "idempotency key, for a retried request to get the result of the first one")
+	return cmd
+}
+
+func addNodeFunc(cmd *cobra.Command, args []string) error {
+	cli, err := client.New(client.Config{
+		LogLevel:    logLevel,
+		Endpoint:    endpoint,
+		DialTimeout: dialTimeout,
+		Token:       token,
+	})
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	opts := []client.OpOption{client.WithIdempotencyKey(idempotencyKey)}
+	if observer {
+		opts = append(opts, client.WithObserver())
+	}
+	if stateSync {
+		opts = append(opts, client.WithStateSync())
+	}
+	if cmd.Flags().Changed("archival") {
+		opts = append(opts, client.WithArchival(archival))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	resp, err := cli.AddNode(ctx, nodeName, avalancheGoBinPath, opts...)
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	return printResponse("add node", resp)
+}
+
+func newEstimateResourcesCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "estimate-resources [options]",
+		Short: "Estimates the resources of a network, and whether the server host can run it.",
+		RunE:  estimateResourcesFunc,
+	}
+	cmd.PersistentFlags().StringVar(&preset, "preset", "", "start preset (e.g., minimal-1node, default-5node, heavy-indexer, archival)")
+	cmd.PersistentFlags().Uint32Var(&numNodes, "num-nodes", 0, "number of nodes (0 for the preset or default)")
+	cmd.PersistentFlags().StringVar(&resourceLimits, "resource-limits", "", "JSON resource limits of every node")
+	cmd.PersistentFlags().StringVar(&nodeResourceLimits, "node-resource-limits", "", "JSON resource limits by node name")
+	return cmd
+}
+
+func estimateResourcesFunc(cmd *cobra.Command, args []string) error {
+	rl, nrl, err := parseResourceLimits()
+	if err != nil {
+		return err
+	}
+
+	cli, err := client.New(client.Config{
+		LogLevel:    logLevel,
+		Endpoint:    endpoint,
+		DialTimeout: dialTimeout,
+		Token:       token,
+	})
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	resp, err := cli.EstimateResources(ctx, "",
+		client.WithPreset(preset),
+		client.WithNumNodes(numNodes),
+		client.WithResourceLimits(rl),
+		client.WithNodeResourceLimits(nrl),
+	)
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	return printResponse("estimate resources", resp)
+}
+
+var (
+	logQuery      string
+	logRegex      bool
+	logSince      string
+	logUntil      string
+	logMaxMatches uint32
+)
+
+func newSearchLogsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "search-logs [options]",
+		Short: "Searches the node logs on the server host.",
+		RunE:  searchLogsFunc,
+	}
+	cmd.PersistentFlags().StringVar(&logQuery, "query", "", "substring of the matching lines (or regular expression, with --regex)")
+	cmd.PersistentFlags().BoolVar(&logRegex, "regex", false, "true to match the query as a regular expression")
+	cmd.PersistentFlags().StringSliceVar(&nodeNames, "node-names", nil, "node names to search (comma-separated), all nodes if empty")
+	cmd.PersistentFlags().StringVar(&logSince, "since", "", "RFC3339 time of the first lines to search")
+	cmd.PersistentFlags().StringVar(&logUntil, "until", "", "RFC3339 time of the last lines to search")
+	cmd.PersistentFlags().Uint32Var(&logMaxMatches, "max-matches", 0, "maximum number of matches (0 for the server default)")
+	return cmd
+}
+
+func searchLogsFunc(cmd *cobra.Command, args []string) error {
+	var since, until time.Time
+	if logSince != "" {
+		t, err := time.Parse(time.RFC3339, logSince)
+		if err != nil {
+			return invalidErr(fmt.Errorf("invalid since: %w", err))
+		}
+		since = t
+	}
+	if logUntil != "" {
+		t, err := time.Parse(time.RFC3339, logUntil)
+		if err != nil {
+			return invalidErr(fmt.Errorf("invalid until: %w", err))
+		}
+		until = t
+	}
+
+	cli, err := client.New(client.Config{
+		LogLevel:    logLevel,
+		Endpoint:    endpoint,
+		DialTimeout: dialTimeout,
+		Token:       token,
+	})
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	opts := []client.OpOption{
+		client.WithNodeNames(nodeNames...),
+		client.WithTimeRange(since, until),
+		client.WithMaxMatches(logMaxMatches),
+	}
+	if logRegex {
+		opts = append(opts, client.WithRegex())
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	resp, err := cli.SearchLogs(ctx, logQuery, opts...)
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	if outputFormat == outputJSON {
+		return printResponse("search logs", resp)
+	}
+	for _, m := range resp.Matches {
+		color.Outf("{{cyan}}%s %s:%d{{/}} %s\n", m.NodeName, m.File, m.LineNumber, m.Line)
+	}
+	color.Outf("{{green}}search logs response:{{/}} %d matches (truncated %v)\n", len(resp.Matches), resp.Truncated)
+	return nil
+}
+
+func newGetSyncProgressCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "get-sync-progress [options]",
+		Short: "Reports the C-chain sync progress of a node.",
+		RunE:  getSyncProgressFunc,
+	}
+	cmd.PersistentFlags().StringVar(&nodeName, "node-name", "", "node name")
+	return cmd
+}
+
+func getSyncProgressFunc(cmd *cobra.Command, args []string) error {
+	cli, err := client.New(client.Config{
+		LogLevel:    logLevel,
+		Endpoint:    endpoint,
+		DialTimeout: dialTimeout,
+		Token:       token,
+	})
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	resp, err := cli.GetSyncProgress(ctx, nodeName)
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	return printResponse("get sync progress", resp)
+}
+
+var (
+	gcMaxAge   string
+	gcMaxBytes uint64
+	gcDryRun   bool
+)
+
+func newGCNowCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "gc-now [options]",
+		Short: "Removes the data directories of the stopped networks, and the rotated node logs.",
+		RunE:  gcNowFunc,
+	}
+	cmd.PersistentFlags().StringVar(&gcMaxAge, "max-age", "", "remove the files not modified for longer (e.g., 24h, 0s to keep any age), the server retention if empty")
+	cmd.PersistentFlags().Uint64Var(&gcMaxBytes, "max-bytes", 0, "total size of the stopped network data directories to keep (0 for any size), the server retention if not set")
+	cmd.PersistentFlags().BoolVar(&gcDryRun, "dry-run", false, "true to only report the files that would be removed")
+	return cmd
+}
+
+func gcNowFunc(cmd *cobra.Command, args []string) error {
+	opts := []client.OpOption{}
+	if gcMaxAge != "" {
+		d, err := time.ParseDuration(gcMaxAge)
+		if err != nil {
+			return invalidErr(fmt.Errorf("invalid max age: %w", err))
+		}
+		opts = append(opts, client.WithGCMaxAge(d))
+	}
+	if cmd.Flags().Changed("max-bytes") {
+		opts = append(opts, client.WithGCMaxBytes(gcMaxBytes))
+	}
+	if gcDryRun {
+		opts = append(opts, client.WithDryRun())
+	}
+
+	cli, err := client.New(client.Config{
+		LogLevel:    logLevel,
+		Endpoint:    endpoint,
+		DialTimeout: dialTimeout,
+		Token:       token,
+	})
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	resp, err := cli.GCNow(ctx, opts...)
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	if outputFormat == outputJSON {
+		return printResponse("gc now", resp)
+	}
+	for _, r := range resp.Removed {
+		color.Outf("{{cyan}}%s{{/}} %d bytes (%s)\n", r.Path, r.SizeBytes, r.Reason)
+	}
+	color.Outf("{{green}}gc now response:{{/}} %d removed, %d bytes freed\n", len(resp.Removed), resp.FreedBytes)
+	return nil
+}
+
+var (
+	downloadSince  string
+	downloadOutput string
+)
+
+func newDownloadLogsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "download-logs [options]",
+		Short: "Downloads the log files of a node, as a gzipped tarball.",
+		RunE:  downloadLogsFunc,
+	}
+	cmd.PersistentFlags().StringVar(&nodeName, "node-name", "", "node name")
+	cmd.PersistentFlags().StringVar(&downloadSince, "since", "", "RFC3339 time, only the log files modified since are downloaded")
+	cmd.PersistentFlags().StringVar(&downloadOutput, "output", "", "path of the downloaded tarball (e.g., node1-logs.tar.gz)")
+	return cmd
+}
+
+func downloadLogsFunc(cmd *cobra.Command, args []string) error {
+	if downloadOutput == "" {
+		return invalidErr(errors.New("empty output path"))
+	}
+	var since time.Time
+	if downloadSince != "" {
+		t, err := time.Parse(time.RFC3339, downloadSince)
+		if err != nil {
+			return invalidErr(fmt.Errorf("invalid since: %w", err))
+		}
+		since = t
+	}
+
+	cli, err := client.New(client.Config{
+		LogLevel:    logLevel,
+		Endpoint:    endpoint,
+		DialTimeout: dialTimeout,
+		Token:       token,
+	})
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	r, err := cli.DownloadLogs(ctx, nodeName, since)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	f, err := os.Create(downloadOutput)
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(f, r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	if outputFormat == outputJSON {
+		return printJSON(map[string]interface{}{"bytes": n, "output": downloadOutput})
+	}
+	color.Outf("{{green}}download logs response:{{/}} %d bytes written to %q\n", n, downloadOutput)
+	return nil
+}
+
+var manifestOutput string
+
+func newGetRunManifestCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "get-run-manifest [options]",
+		Short: "Gets the binary, plugin, and genesis hashes, the start request, and the node configs of the network.",
+		RunE:  getRunManifestFunc,
+	}
+	cmd.PersistentFlags().StringVar(&manifestOutput, "output", "", "file to write the manifest to (JSON), printed if empty")
+	return cmd
+}
+
+func getRunManifestFunc(cmd *cobra.Command, args []string) error {
+	cli, err := client.New(client.Config{
+		LogLevel:    logLevel,
+		Endpoint:    endpoint,
+		DialTimeout: dialTimeout,
+		Token:       token,
+	})
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	resp, err := cli.GetRunManifest(ctx)
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	if manifestOutput == "" {
+		return printResponse("get run manifest", resp)
+	}
+	// indented, as written by the server, to diff with the other runs
+	b, err := protojson.MarshalOptions{Multiline: true, Indent: "  "}.Marshal(resp.Manifest)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(manifestOutput, b, 0o644); err != nil {
+		return err
+	}
+	if outputFormat == outputJSON {
+		return printJSON(map[string]string{"output": manifestOutput})
+	}
+	color.Outf("{{green}}wrote run manifest to %q{{/}}\n", manifestOutput)
+	return nil
+}
+
+func newStopValidatingSubnetCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "stop-validating-subnet [options]",
+		Short: "Restarts a node without a subnet whitelisted, keeping it on the primary network.",
+		RunE:  stopValidatingSubnetFunc,
+	}
+	cmd.PersistentFlags().StringVar(&nodeName, "node-name", "", "node name to stop validating the subnet")
+	cmd.PersistentFlags().StringVar(&subnetID, "subnet-id", "", "whitelisted subnet ID of the node")
+	return cmd
+}
+
+func stopValidatingSubnetFunc(cmd *cobra.Command, args []string) error {
+	cli, err := client.New(client.Config{
+		LogLevel:    logLevel,
+		Endpoint:    endpoint,
+		DialTimeout: dialTimeout,
+		Token:       token,
+	})
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	resp, err := cli.StopValidatingSubnet(ctx, nodeName, subnetID)
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	return printResponse("stop validating subnet", resp)
+}
+
+var genesisOutput string
+
+func newGetGenesisCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "get-genesis [options]",
+		Short: "Gets the genesis and the network ID of the network.",
+		RunE:  getGenesisFunc,
+	}
+	cmd.PersistentFlags().StringVar(&genesisOutput, "output", "", "file to write the genesis to (JSON), printed if empty")
+	return cmd
+}
+
+func getGenesisFunc(cmd *cobra.Command, args []string) error {
+	cli, err := client.New(client.Config{
+		LogLevel:    logLevel,
+		Endpoint:    endpoint,
+		DialTimeout: dialTimeout,
+		Token:       token,
+	})
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	resp, err := cli.GetGenesis(ctx)
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	if genesisOutput == "" {
+		return printResponse("get genesis", resp)
+	}
+	if err := ioutil.WriteFile(genesisOutput, []byte(resp.Genesis), 0o644); err != nil {
+		return err
+	}
+	if outputFormat == outputJSON {
+		return printJSON(map[string]string{"output": genesisOutput})
+	}
+	color.Outf("{{green}}wrote genesis to %q{{/}}\n", genesisOutput)
+	return nil
+}
+
+func newGetNetworkParamsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "get-network-params [options]",
+		Short: "Gets the network and chain IDs, fees, and staking and consensus parameters of the network.",
+		RunE:  getNetworkParamsFunc,
+	}
+	return cmd
+}
+
+func getNetworkParamsFunc(cmd *cobra.Command, args []string) error {
+	cli, err := client.New(client.Config{
+		LogLevel:    logLevel,
+		Endpoint:    endpoint,
+		DialTimeout: dialTimeout,
+		Token:       token,
+	})
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	resp, err := cli.GetNetworkParams(ctx)
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	return printResponse("get network params", resp)
 }
@@ -0,0 +1,184 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package control
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/lasthyphen/djtx-tester/client"
+	"github.com/lasthyphen/djtx-tester/pkg/color"
+	"github.com/lasthyphen/djtx-tester/server"
+	"github.com/spf13/cobra"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// exit codes of the control commands, for the scripts to branch on
+const (
+	ExitOK = iota
+	// any other failure
+	ExitFailure
+	// invalid flags or request fields
+	ExitInvalid
+	// request (or dial) timeout
+	ExitTimeout
+	// the network failed (or reported failing) the health checks
+	ExitUnhealthy
+	// e.g., no node of the name
+	ExitNotFound
+	// e.g., no network started, or the network is not in a state to accept the request
+	ExitPrecondition
+	// the server is not reachable
+	ExitUnavailable
+	// missing or unknown tenant token
+	ExitUnauthenticated
+)
+
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+var outputFormat string
+
+// exitError sets the exit code of a command error.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+
+func (e *exitError) Unwrap() error { return e.err }
+
+// invalidErr marks the error as an invalid flag or argument.
+func invalidErr(err error) error {
+	return &exitError{code: ExitInvalid, err: err}
+}
+
+// ExitCode returns the exit code of the command error.
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	var ee *exitError
+	if errors.As(err, &ee) {
+		return ee.code
+	}
+	if errors.Is(err, client.ErrServerUnreachable) {
+		return ExitUnavailable
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ExitTimeout
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		return ExitFailure
+	}
+	switch st.Code() {
+	case codes.InvalidArgument:
+		return ExitInvalid
+	case codes.DeadlineExceeded:
+		return ExitTimeout
+	case codes.NotFound:
+		return ExitNotFound
+	case codes.FailedPrecondition:
+		return ExitPrecondition
+	case codes.Unauthenticated, codes.PermissionDenied:
+		return ExitUnauthenticated
+	case codes.Unavailable:
+		if strings.HasPrefix(st.Message(), server.ErrNetworkUnhealthy.Error()) {
+			return ExitUnhealthy
+		}
+		return ExitUnavailable
+	}
+	return ExitFailure
+}
+
+func checkOutputFormat() error {
+	if outputFormat != outputText && outputFormat != outputJSON {
+		return invalidErr(fmt.Errorf("invalid output format %q (expected %s or %s)", outputFormat, outputText, outputJSON))
+	}
+	return nil
+}
+
+// printResponse prints the response of the command, as text or as a line
+// of JSON.
+func printResponse(name string, resp proto.Message) error {
+	if outputFormat != outputJSON {
+		color.Outf("{{green}}%s response:{{/}} %+v\n", name, resp)
+		return nil
+	}
+	b, err := protojson.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(b))
+	return nil
+}
+
+// printJSON prints the result of a command without a response message
+// (e.g., a file written), as a line of JSON.
+func printJSON(v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(b))
+	return nil
+}
+
+// withJSONErrors prints the errors of the commands as JSON to stdout,
+// in the JSON output format, so the scripts can parse both results and
+// failures from stdout.
+func withJSONErrors(cmd *cobra.Command) {
+	for _, c := range cmd.Commands() {
+		run := c.RunE
+		if run == nil {
+			continue
+		}
+		c.RunE = func(cmd *cobra.Command, args []string) error {
+			err := run(cmd, args)
+			if err != nil && outputFormat == outputJSON {
+				printJSONError(err)
+			}
+			return err
+		}
+	}
+}
+
+func printJSONError(err error) {
+	e := struct {
+		ExitCode int    `json:"exitCode"`
+		Code     string `json:"code,omitempty"`
+		Message  string `json:"message"`
+		// path of the invalid request field, if any
+		Field string `json:"field,omitempty"`
+	}{
+		ExitCode: ExitCode(err),
+		Message:  err.Error(),
+	}
+	if st, ok := status.FromError(err); ok {
+		e.Code = st.Code().String()
+		e.Message = st.Message()
+		for _, d := range st.Details() {
+			if br, ok := d.(*errdetails.BadRequest); ok && len(br.FieldViolations) > 0 {
+				e.Field = br.FieldViolations[0].Field
+			}
+		}
+	}
+	if perr := printJSON(struct {
+		Error interface{} `json:"error"`
+	}{e}); perr != nil {
+		fmt.Fprintf(os.Stderr, "failed to print the error: %v\n", perr)
+	}
+}
@@ -0,0 +1,271 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package control
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	formatter "github.com/onsi/ginkgo/v2/formatter"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"golang.org/x/term"
+)
+
+const shellPrompt = "network-runner> "
+
+var errUnterminatedArg = errors.New("unterminated quote or escape")
+
+func newShellCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "shell [options]",
+		Short: "Runs the control commands interactively, with tab completion.",
+		Args:  cobra.NoArgs,
+		RunE:  shellFunc,
+	}
+	return cmd
+}
+
+// shell runs each line as a control command (e.g., "status" or
+// "restart-node --node-name node1"), with the control flags of the shell
+// command (e.g., "--endpoint"), unless set on the line.
+type shell struct {
+	baseArgs []string
+	// nil if the input is not a terminal
+	term *term.Terminal
+}
+
+func shellFunc(cmd *cobra.Command, args []string) error {
+	sh := &shell{}
+	cmd.InheritedFlags().VisitAll(func(f *pflag.Flag) {
+		if f.Changed {
+			sh.baseArgs = append(sh.baseArgs, "--"+f.Name+"="+f.Value.String())
+		}
+	})
+	// replaced by the commands of the shell, then called on exit
+	shutdown := shutdownTracing
+	defer func() {
+		shutdownTracing = shutdown
+	}()
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		// e.g., a script piped to the shell
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			if !sh.run(scanner.Text()) {
+				return nil
+			}
+		}
+		return scanner.Err()
+	}
+
+	sh.term = term.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{os.Stdin, os.Stdout}, shellPrompt)
+	sh.term.AutoCompleteCallback = sh.autoComplete
+	fmt.Println(`type "help" for the commands, tab to complete, and "exit" or ctrl-D to quit`)
+	for {
+		// restored while the commands run, so their output is not raw
+		st, err := term.MakeRaw(fd)
+		if err != nil {
+			return err
+		}
+		line, err := sh.term.ReadLine()
+		_ = term.Restore(fd, st)
+		if errors.Is(err, io.EOF) {
+			fmt.Println()
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !sh.run(line) {
+			return nil
+		}
+	}
+}
+
+// newCommandTree returns a new control command, so the flags of a line
+// do not carry over to the next.
+func newCommandTree() *cobra.Command {
+	cmd := NewCommand()
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+	return cmd
+}
+
+// run runs the command of the line, and returns false to exit the shell.
+func (sh *shell) run(line string) bool {
+	args, err := splitArgs(line)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid command: %v\n", err)
+		return true
+	}
+	if len(args) == 0 {
+		return true
+	}
+	if args[0] == "exit" || args[0] == "quit" {
+		return false
+	}
+	cmd := newCommandTree()
+	cmd.SetArgs(append(append([]string{}, sh.baseArgs...), args...))
+	if err := cmd.Execute(); err != nil {
+		fmt.Fprintf(os.Stderr, "failed %v\n", err)
+	}
+	return true
+}
+
+// autoComplete completes the word before the cursor on tab, with the
+// completions of the shell completion scripts (e.g., the node names from
+// the status of the network). The candidates are listed if ambiguous.
+func (sh *shell) autoComplete(line string, pos int, key rune) (string, int, bool) {
+	if key != '\t' {
+		return "", 0, false
+	}
+	head := line[:pos]
+	from := strings.LastIndex(head, " ") + 1
+	toComplete := head[from:]
+	words, err := splitArgs(head[:from])
+	if err != nil {
+		return line, pos, true
+	}
+	comps, directive := sh.complete(words, toComplete)
+
+	// only the value is completed for "--flag=value"
+	partial := toComplete
+	if strings.HasPrefix(partial, "-") {
+		if idx := strings.Index(partial, "="); idx >= 0 {
+			from += idx + 1
+			partial = partial[idx+1:]
+		}
+	}
+	var ins string
+	switch len(comps) {
+	case 0:
+		return line, pos, true
+	case 1:
+		ins = comps[0]
+		if directive&cobra.ShellCompDirectiveNoSpace == 0 {
+			ins += " "
+		}
+	default:
+		ins = commonPrefix(comps)
+		if len(ins) <= len(partial) {
+			fmt.Fprintln(sh.term, strings.Join(comps, "  "))
+			return line, pos, true
+		}
+	}
+	return line[:from] + ins + line[pos:], from + len(ins), true
+}
+
+// complete returns the completions of the hidden cobra completion command.
+func (sh *shell) complete(words []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	// the client messages would be printed over the line being completed
+	stderr := formatter.ColorableStdErr
+	formatter.ColorableStdErr = ioutil.Discard
+	defer func() {
+		formatter.ColorableStdErr = stderr
+	}()
+
+	var out bytes.Buffer
+	cmd := newCommandTree()
+	cmd.SetOut(&out)
+	cmd.SetErr(ioutil.Discard)
+	args := append([]string{cobra.ShellCompRequestCmd}, sh.baseArgs...)
+	args = append(append(args, words...), toComplete)
+	cmd.SetArgs(args)
+	if err := cmd.Execute(); err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	// one completion per line (with a tab before the description),
+	// then the directive (":<directive>")
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	last := lines[len(lines)-1]
+	if !strings.HasPrefix(last, ":") {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	d, err := strconv.Atoi(last[1:])
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	directive := cobra.ShellCompDirective(d)
+	if directive&cobra.ShellCompDirectiveError != 0 {
+		return nil, directive
+	}
+	comps := make([]string, 0, len(lines)-1)
+	for _, l := range lines[:len(lines)-1] {
+		if idx := strings.Index(l, "\t"); idx >= 0 {
+			l = l[:idx]
+		}
+		if l != "" {
+			comps = append(comps, l)
+		}
+	}
+	return comps, directive
+}
+
+func commonPrefix(ss []string) string {
+	prefix := ss[0]
+	for _, s := range ss[1:] {
+		for !strings.HasPrefix(s, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
+
+// splitArgs splits the line on spaces, except in the single or double
+// quotes (e.g., JSON flags), with backslash escapes outside single quotes.
+func splitArgs(line string) ([]string, error) {
+	var (
+		args    []string
+		cur     strings.Builder
+		inArg   bool
+		quote   rune
+		escaped bool
+	)
+	for _, r := range line {
+		switch {
+		case escaped:
+			cur.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inArg = true, true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inArg = r, true
+		case r == ' ' || r == '\t':
+			if inArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				inArg = false
+			}
+		default:
+			cur.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, errUnterminatedArg
+	}
+	if inArg {
+		args = append(args, cur.String())
+	}
+	return args, nil
+}
@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/lasthyphen/djtx-tester/cmd/avalanche-network-runner/bench"
+	"github.com/lasthyphen/djtx-tester/cmd/avalanche-network-runner/conformance"
 	"github.com/lasthyphen/djtx-tester/cmd/avalanche-network-runner/control"
 	"github.com/lasthyphen/djtx-tester/cmd/avalanche-network-runner/ping"
 	"github.com/lasthyphen/djtx-tester/cmd/avalanche-network-runner/server"
@@ -28,13 +30,15 @@ func init() {
 		server.NewCommand(),
 		ping.NewCommand(),
 		control.NewCommand(),
+		bench.NewCommand(),
+		conformance.NewCommand(),
 	)
 }
 
 func main() {
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "avalanche-network-runner failed %v\n", err)
-		os.Exit(1)
+		os.Exit(control.ExitCode(err))
 	}
 	os.Exit(0)
 }
@@ -0,0 +1,128 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+	"github.com/lasthyphen/djtx-tester/server"
+	"github.com/spf13/pflag"
+	"google.golang.org/protobuf/encoding/protojson"
+	"gopkg.in/yaml.v3"
+)
+
+// prefix of the environment variables of the flags
+// (e.g., "NETWORK_RUNNER_LOG_LEVEL" for "--log-level")
+const envPrefix = "NETWORK_RUNNER_"
+
+// config file sections that are not flags
+const (
+	presetsKey        = "presets"
+	templatesKey      = "templates"
+	tenantsKey        = "tenants"
+	resourceLimitsKey = "resource-limits"
+	webhooksKey       = "webhooks"
+)
+
+var (
+	configPath string
+
+	configPresets        []server.Preset
+	configTemplates      []server.Template
+	configTenants        []server.Tenant
+	configResourceLimits *rpcpb.ResourceLimits
+	configWebhooks       []server.Webhook
+)
+
+// loadConfig sets the flags that are not on the command line from their
+// environment variables, or else from the config file, if any. The config
+// file (YAML, or JSON) maps the flag names to their values, and has the
+// nested sections of the start presets and templates, of the tenants, of
+// the default resource limits, and of the webhooks.
+func loadConfig(fs *pflag.FlagSet) error {
+	var err error
+	fs.VisitAll(func(f *pflag.Flag) {
+		v, ok := os.LookupEnv(envName(f.Name))
+		if !ok || f.Changed || err != nil {
+			return
+		}
+		if serr := fs.Set(f.Name, v); serr != nil {
+			err = fmt.Errorf("invalid %s: %v", envName(f.Name), serr)
+		}
+	})
+	if err != nil || configPath == "" {
+		return err
+	}
+
+	b, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		return err
+	}
+	cfg := map[string]interface{}{}
+	if err := yaml.Unmarshal(b, &cfg); err != nil {
+		return fmt.Errorf("invalid config %q: %w", configPath, err)
+	}
+	keys := make([]string, 0, len(cfg))
+	for k := range cfg {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := setConfigKey(fs, k, cfg[k]); err != nil {
+			return fmt.Errorf("invalid config %q: %q: %w", configPath, k, err)
+		}
+	}
+	return nil
+}
+
+func setConfigKey(fs *pflag.FlagSet, key string, v interface{}) error {
+	// nested sections, decoded from JSON for the field names to match
+	// the presets directory and tenants files
+	switch key {
+	case presetsKey:
+		return decodeSection(v, &configPresets)
+	case templatesKey:
+		return decodeSection(v, &configTemplates)
+	case tenantsKey:
+		return decodeSection(v, &configTenants)
+	case webhooksKey:
+		return decodeSection(v, &configWebhooks)
+	case resourceLimitsKey:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		configResourceLimits = &rpcpb.ResourceLimits{}
+		return protojson.Unmarshal(b, configResourceLimits)
+	}
+
+	f := fs.Lookup(key)
+	if f == nil || key == "config" {
+		return errors.New("unknown key")
+	}
+	// the command line and the environment take precedence
+	if f.Changed {
+		return nil
+	}
+	return fs.Set(key, fmt.Sprint(v))
+}
+
+func decodeSection(v interface{}, out interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, out)
+}
+
+func envName(flag string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flag, "-", "_"))
+}
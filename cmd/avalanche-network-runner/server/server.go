@@ -11,7 +11,9 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/lasthyphen/djtx-tester/pkg/discovery"
 	"github.com/lasthyphen/djtx-tester/pkg/logutil"
+	"github.com/lasthyphen/djtx-tester/pkg/tracing"
 	"github.com/lasthyphen/djtx-tester/server"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
@@ -26,6 +28,48 @@ var (
 	port        string
 	gwPort      string
 	dialTimeout time.Duration
+
+	enableFaucet   bool
+	faucetAmount   uint64
+	faucetInterval time.Duration
+
+	fundedKey string
+
+	enableNodeProxy bool
+	enableLogStream bool
+
+	enableGRPCWeb         bool
+	grpcWebAllowedOrigins []string
+
+	presetsDir   string
+	templatesDir string
+	tenantsFile  string
+
+	shutdownGracePeriod  time.Duration
+	maxUnhealthyDuration time.Duration
+	orphanPolicy         string
+
+	uploadDir      string
+	artifactsDir   string
+	execDirs       []string
+	nodeSupervisor string
+
+	idempotencyWindow time.Duration
+	recordFixtures    string
+
+	schedulerDir           string
+	schedulerQueueTimeout  time.Duration
+	schedulerCPUOvercommit float64
+
+	gcInterval time.Duration
+	gcMaxAge   time.Duration
+	gcMaxBytes uint64
+
+	webhookURLs []string
+
+	stateFile string
+
+	otlpEndpoint string
 )
 
 func NewCommand() *cobra.Command {
@@ -35,15 +79,49 @@ func NewCommand() *cobra.Command {
 		RunE:  serverFunc,
 	}
 
+	cmd.PersistentFlags().StringVar(&configPath, "config", "", "YAML (or JSON) file of the flag values, and of the presets, templates, tenants, resource-limits, and webhooks sections")
 	cmd.PersistentFlags().StringVar(&logLevel, "log-level", logutil.DefaultLogLevel, "log level")
-	cmd.PersistentFlags().StringVar(&port, "port", ":8080", "server port")
-	cmd.PersistentFlags().StringVar(&gwPort, "grpc-gateway-port", ":8081", "grpc-gateway server port")
+	cmd.PersistentFlags().StringVar(&port, "port", ":8080", "server listen addresses, comma-separated (e.g., :8080, 127.0.0.1:8080, 0.0.0.0:8080,[::]:8080, or unix://<socket path>)")
+	cmd.PersistentFlags().StringVar(&gwPort, "grpc-gateway-port", ":8081", "grpc-gateway server listen addresses, comma-separated (e.g., :8081, 127.0.0.1:8081, or unix://<socket path>)")
 	cmd.PersistentFlags().DurationVar(&dialTimeout, "dial-timeout", 10*time.Second, "server dial timeout")
+	cmd.PersistentFlags().BoolVar(&enableFaucet, "enable-faucet", false, "serve a test funds faucet on the grpc-gateway port")
+	cmd.PersistentFlags().Uint64Var(&faucetAmount, "faucet-amount", 1000000000, "amount (nano) dispensed per faucet request")
+	cmd.PersistentFlags().DurationVar(&faucetInterval, "faucet-interval", time.Minute, "minimum interval between faucet requests for the same address")
+	cmd.PersistentFlags().StringVar(&fundedKey, "funded-key", "", "secret reference (env:<name>, file:<path>, or vault:<path>#<field>) of the private key the faucet, blockchain creation, and seed actions spend from, the ewoq key if empty")
+	cmd.PersistentFlags().BoolVar(&enableNodeProxy, "enable-node-proxy", false, "serve the node APIs on the grpc-gateway port (/node/<name>/ext/...)")
+	cmd.PersistentFlags().BoolVar(&enableLogStream, "enable-log-stream", false, "stream the node logs over a websocket on the grpc-gateway port (/v1/logs/stream)")
+	cmd.PersistentFlags().BoolVar(&enableGRPCWeb, "enable-grpc-web", false, "serve gRPC-Web on the grpc-gateway port, for browser clients")
+	cmd.PersistentFlags().StringSliceVar(&grpcWebAllowedOrigins, "grpc-web-allowed-origins", nil, "origins of the browser clients allowed to call gRPC-Web (comma-separated, '*' for all)")
+	cmd.PersistentFlags().StringVar(&presetsDir, "presets-dir", "", "directory of custom start presets (<name>.json)")
+	cmd.PersistentFlags().StringVar(&templatesDir, "templates-dir", "", "directory of start templates (<name>.json)")
+	cmd.PersistentFlags().StringVar(&otlpEndpoint, "otlp-endpoint", "", "OTLP gRPC collector endpoint to export traces to (e.g., localhost:4317), disabled if empty")
+	cmd.PersistentFlags().StringVar(&tenantsFile, "tenants-file", "", "JSON list of tenants (name, token, role, tokens, maxNodes) to share the server among users")
+	cmd.PersistentFlags().DurationVar(&shutdownGracePeriod, "shutdown-grace-period", 30*time.Second, "time for the nodes to exit on stop before they are killed (0 to wait as long as the runner does)")
+	cmd.PersistentFlags().DurationVar(&maxUnhealthyDuration, "max-unhealthy-duration", 0, "fail the networks once a node stays unhealthy for longer, unless set by the start request (0 to disable)")
+	cmd.PersistentFlags().StringVar(&orphanPolicy, "orphan-policy", server.OrphanPolicyIgnore, "on startup, 'ignore' (log), 'kill', or 'adopt' (if supervised) the node processes left by a previous server")
+	cmd.PersistentFlags().StringVar(&nodeSupervisor, "node-supervisor", server.SupervisorNone, "'none', or 'systemd' to run the nodes in transient systemd units that survive the server")
+	cmd.PersistentFlags().DurationVar(&idempotencyWindow, "idempotency-window", 10*time.Minute, "time the results of the start, stop, and add-node requests are kept by idempotency key")
+	cmd.PersistentFlags().StringVar(&recordFixtures, "record-fixtures", "", "file to append every control call to, as the conformance fixtures of 'verify-fixtures' (disabled if empty)")
+	cmd.PersistentFlags().StringVar(&schedulerDir, "scheduler-dir", "", "directory of the host resource reservations, shared by the servers of the host to queue the start requests that would overcommit it (disabled if empty)")
+	cmd.PersistentFlags().DurationVar(&schedulerQueueTimeout, "scheduler-queue-timeout", 10*time.Minute, "time a start request may wait for the host resources (0 to reject rather than queue)")
+	cmd.PersistentFlags().Float64Var(&schedulerCPUOvercommit, "scheduler-cpu-overcommit", 1, "ratio of the CPU the networks may reserve to the host CPUs")
+	cmd.PersistentFlags().DurationVar(&gcInterval, "gc-interval", time.Hour, "interval of the janitor removing the data directories of the stopped networks and the rotated node logs (0 to disable)")
+	cmd.PersistentFlags().DurationVar(&gcMaxAge, "gc-max-age", 0, "remove the data directories of the stopped networks and the rotated node logs not modified for longer (0 to keep any age)")
+	cmd.PersistentFlags().Uint64Var(&gcMaxBytes, "gc-max-bytes", 0, "remove the oldest data directories of the stopped networks beyond the total size (0 for any size)")
+	cmd.PersistentFlags().StringSliceVar(&webhookURLs, "webhook-urls", nil, "URLs to POST all of the network lifecycle events to (comma-separated), in addition to the webhooks section of the config file")
+	cmd.PersistentFlags().StringVar(&stateFile, "state-file", discovery.StateFile(), "file the server endpoints are written to, for the clients to discover them (empty to disable)")
+	cmd.PersistentFlags().StringVar(&uploadDir, "upload-dir", "", "directory of the uploaded files, 'network-runner-uploads' in the temporary directory if empty")
+	cmd.PersistentFlags().StringVar(&artifactsDir, "artifacts-dir", "", "directory the network artifacts are archived to, 'network-runner-artifacts' in the temporary directory if empty")
+	cmd.PersistentFlags().StringSliceVar(&execDirs, "exec-dirs", nil, "directories the exec paths of the nodes and sidecars, and their plugin and build directories, must be in, e.g., the upload directory (any path if empty)")
 
 	return cmd
 }
 
 func serverFunc(cmd *cobra.Command, args []string) (err error) {
+	if err := loadConfig(cmd.PersistentFlags()); err != nil {
+		return err
+	}
+
 	lcfg := logutil.GetDefaultZapLoggerConfig()
 	lcfg.Level = zap.NewAtomicLevelAt(logutil.ConvertToZapLevel(logLevel))
 	logger, err := lcfg.Build()
@@ -52,10 +130,68 @@ func serverFunc(cmd *cobra.Command, args []string) (err error) {
 	}
 	_ = zap.ReplaceGlobals(logger)
 
+	shutdownTracing, err := tracing.Init(context.Background(), "network-runner-server", otlpEndpoint)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		zap.L().Info("flushed traces", zap.Error(shutdownTracing(ctx)))
+		cancel()
+	}()
+
+	webhooks := configWebhooks
+	for _, u := range webhookURLs {
+		webhooks = append(webhooks, server.Webhook{URL: u})
+	}
 	s, err := server.New(server.Config{
 		Port:        port,
 		GwPort:      gwPort,
 		DialTimeout: dialTimeout,
+
+		EnableFaucet:   enableFaucet,
+		FaucetAmount:   faucetAmount,
+		FaucetInterval: faucetInterval,
+		FundedKey:      fundedKey,
+
+		EnableNodeProxy: enableNodeProxy,
+		EnableLogStream: enableLogStream,
+
+		EnableGRPCWeb:         enableGRPCWeb,
+		GRPCWebAllowedOrigins: grpcWebAllowedOrigins,
+
+		PresetsDir:   presetsDir,
+		Presets:      configPresets,
+		TemplatesDir: templatesDir,
+		Templates:    configTemplates,
+		TenantsFile:  tenantsFile,
+		Tenants:      configTenants,
+
+		DefaultResourceLimits: configResourceLimits,
+
+		ShutdownGracePeriod:  shutdownGracePeriod,
+		MaxUnhealthyDuration: maxUnhealthyDuration,
+		OrphanPolicy:         orphanPolicy,
+
+		UploadDir:      uploadDir,
+		ArtifactsDir:   artifactsDir,
+		ExecDirs:       execDirs,
+		NodeSupervisor: nodeSupervisor,
+
+		IdempotencyWindow: idempotencyWindow,
+		RecordFixtures:    recordFixtures,
+
+		SchedulerDir:           schedulerDir,
+		SchedulerQueueTimeout:  schedulerQueueTimeout,
+		SchedulerCPUOvercommit: schedulerCPUOvercommit,
+
+		GCInterval: gcInterval,
+		GCMaxAge:   gcMaxAge,
+		GCMaxBytes: gcMaxBytes,
+
+		Webhooks: webhooks,
+
+		StateFile: stateFile,
 	})
 	if err != nil {
 		return err
@@ -0,0 +1,242 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// Package asserts has the polling assertions on the network conditions of
+// the e2e tests, for go test and ginkgo suites alike. Each assertion returns
+// nil once it holds, or an error describing the last observed state, when the
+// timeout or the context expires first (e.g., "Expect(err).Should(BeNil())").
+package asserts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lasthyphen/djtx-tester/client"
+	"github.com/lasthyphen/djtx-tester/pkg/jsonrpc"
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+)
+
+// DefaultInterval is the polling interval, unless "WithInterval" is set.
+const DefaultInterval = time.Second
+
+const (
+	ChainP = "P"
+	ChainC = "C"
+)
+
+var (
+	ErrInvalidChain    = errors.New("invalid chain (expected P or C)")
+	ErrNotHealthy      = errors.New("network not healthy")
+	ErrHeightTooLow    = errors.New("chain height too low")
+	ErrBecameUnhealthy = errors.New("network became unhealthy")
+)
+
+type Op struct {
+	interval time.Duration
+}
+
+type OpOption func(*Op)
+
+func (op *Op) applyOpts(opts []OpOption) {
+	op.interval = DefaultInterval
+	for _, opt := range opts {
+		opt(op)
+	}
+}
+
+// WithInterval sets the polling interval.
+func WithInterval(d time.Duration) OpOption {
+	return func(op *Op) {
+		op.interval = d
+	}
+}
+
+// EventuallyHealthy waits for all nodes of the network to be ready,
+// as probed by "Health". The errors of the polls (e.g., while the network
+// is still being created) are retried until the timeout.
+func EventuallyHealthy(ctx context.Context, cli client.Client, timeout time.Duration, opts ...OpOption) error {
+	ret := &Op{}
+	ret.applyOpts(opts)
+
+	start := time.Now()
+	last := ""
+	err := poll(ctx, timeout, ret.interval, func(ctx context.Context) (bool, error) {
+		resp, err := cli.Health(ctx, client.WithNoWait())
+		if err != nil {
+			last = err.Error()
+			return false, nil
+		}
+		last = describeHealth(resp)
+		return isHealthy(resp), nil
+	})
+	if err != nil {
+		return fmt.Errorf("%w after %v: %v (last: %s)", ErrNotHealthy, time.Since(start).Round(time.Millisecond), err, last)
+	}
+	return nil
+}
+
+// EventuallyHeightAtLeast waits for the chain ("P" or "C") of every node
+// to reach the height.
+func EventuallyHeightAtLeast(ctx context.Context, cli client.Client, chain string, height uint64, timeout time.Duration, opts ...OpOption) error {
+	if chain != ChainP && chain != ChainC {
+		return fmt.Errorf("%w: %q", ErrInvalidChain, chain)
+	}
+	ret := &Op{}
+	ret.applyOpts(opts)
+
+	start := time.Now()
+	heights := map[string]string{}
+	err := poll(ctx, timeout, ret.interval, func(ctx context.Context) (bool, error) {
+		resp, err := cli.Status(ctx)
+		if err != nil {
+			heights = map[string]string{"status": err.Error()}
+			return false, nil
+		}
+		infos := resp.GetClusterInfo().GetNodeInfos()
+		if len(infos) == 0 {
+			heights = map[string]string{"status": "no nodes"}
+			return false, nil
+		}
+		heights = make(map[string]string, len(infos))
+		done := true
+		for name, info := range infos {
+			h, err := chainHeight(ctx, info.Uri, chain)
+			if err != nil {
+				heights[name] = err.Error()
+				done = false
+				continue
+			}
+			heights[name] = strconv.FormatUint(h, 10)
+			if h < height {
+				done = false
+			}
+		}
+		return done, nil
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %s-chain below %d after %v: %v (last: %s)", ErrHeightTooLow, chain, height, time.Since(start).Round(time.Millisecond), err, describe(heights))
+	}
+	return nil
+}
+
+// NeverUnhealthyFor checks that all nodes of the network stay ready for the
+// duration, and fails on the first poll that finds otherwise.
+func NeverUnhealthyFor(ctx context.Context, cli client.Client, d time.Duration, opts ...OpOption) error {
+	ret := &Op{}
+	ret.applyOpts(opts)
+
+	start := time.Now()
+	t := time.NewTimer(d)
+	defer t.Stop()
+	for {
+		resp, err := cli.Health(ctx, client.WithNoWait())
+		if err != nil {
+			return fmt.Errorf("%w after %v: %v", ErrBecameUnhealthy, time.Since(start).Round(time.Millisecond), err)
+		}
+		if !isHealthy(resp) {
+			return fmt.Errorf("%w after %v: %s", ErrBecameUnhealthy, time.Since(start).Round(time.Millisecond), describeHealth(resp))
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+			return nil
+		case <-time.After(ret.interval):
+		}
+	}
+}
+
+// poll calls "f" until it returns true or an error,
+// or until the timeout (if not zero) or the context expires.
+func poll(ctx context.Context, timeout time.Duration, interval time.Duration, f func(context.Context) (bool, error)) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	for {
+		done, err := f(ctx)
+		if err != nil || done {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+func isHealthy(resp *rpcpb.HealthResponse) bool {
+	if len(resp.NodeHealth) == 0 {
+		return false
+	}
+	for _, h := range resp.NodeHealth {
+		if !h.Live || !h.Ready {
+			return false
+		}
+	}
+	return true
+}
+
+// describeHealth lists the network state, and the nodes that are not ready.
+func describeHealth(resp *rpcpb.HealthResponse) string {
+	info := resp.GetClusterInfo()
+	s := info.GetState().String()
+	if info.GetError() != "" {
+		s += " (" + info.GetError() + ")"
+	}
+	nodes := map[string]string{}
+	for name, h := range resp.NodeHealth {
+		switch {
+		case !h.Live:
+			nodes[name] = "not live: " + strings.Join(h.Reasons, ", ")
+		case !h.Ready:
+			nodes[name] = "not ready: " + strings.Join(h.Reasons, ", ")
+		}
+	}
+	if len(nodes) > 0 {
+		s += ", " + describe(nodes)
+	}
+	return s
+}
+
+// describe formats the values by node name, sorted by name.
+func describe(m map[string]string) string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	ss := make([]string, 0, len(names))
+	for _, name := range names {
+		ss = append(ss, name+": "+m[name])
+	}
+	return strings.Join(ss, "; ")
+}
+
+func chainHeight(ctx context.Context, uri string, chain string) (uint64, error) {
+	if uri == "" {
+		return 0, errors.New("no node URI")
+	}
+	if chain == ChainC {
+		var reply string
+		if err := jsonrpc.Call(ctx, uri, "/ext/bc/C/rpc", "eth_blockNumber", []interface{}{}, &reply); err != nil {
+			return 0, err
+		}
+		return strconv.ParseUint(strings.TrimPrefix(reply, "0x"), 16, 64)
+	}
+	// ref. platformvm "GetHeight"
+	var reply struct {
+		Height string `json:"height"`
+	}
+	if err := jsonrpc.Call(ctx, uri, "/ext/bc/P", "platform.getHeight", nil, &reply); err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(reply.Height, 10, 64)
+}
@@ -0,0 +1,460 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// Package conformance records the control API calls of a test session to a
+// fixture file, and replays the fixtures against another server build, to
+// detect the backward-incompatible changes of the control API (e.g., a
+// removed or retyped response field, or another error code).
+package conformance
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// MaxStreamResponses is the maximum number of responses recorded per server
+// stream (e.g., of "StreamStatus", which never ends on its own).
+const MaxStreamResponses = 16
+
+var ErrInvalidFixtures = errors.New("invalid fixtures")
+
+// Call is a recorded control call: the unary calls have one response, the
+// server streams their responses in order.
+type Call struct {
+	// full method name (e.g., "/rpcpb.ControlService/Start")
+	Method    string            `json:"method"`
+	Time      string            `json:"time"`
+	Request   json.RawMessage   `json:"request"`
+	Responses []json.RawMessage `json:"responses,omitempty"`
+	// true if the stream had more than "MaxStreamResponses" responses
+	Truncated bool `json:"truncated,omitempty"`
+	// gRPC status code name (e.g., "OK", "NotFound")
+	Code    string `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+// Recorder appends the calls to a fixture file, one JSON call per line.
+type Recorder struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+// NewRecorder appends to the fixture file, created if it does not exist.
+func NewRecorder(path string) (*Recorder, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &Recorder{f: f}, nil
+}
+
+func (r *Recorder) Close() error {
+	return r.f.Close()
+}
+
+// isControlMethod returns true for the control service methods, leaving out
+// ping, health, and reflection.
+func isControlMethod(fullMethod string) bool {
+	return strings.HasPrefix(fullMethod, "/"+rpcpb.ControlService_ServiceDesc.ServiceName+"/")
+}
+
+func (r *Recorder) record(method string, req interface{}, resps []interface{}, truncated bool, err error) error {
+	call := &Call{
+		Method:    method,
+		Time:      time.Now().UTC().Format(time.RFC3339Nano),
+		Truncated: truncated,
+	}
+	m, ok := req.(proto.Message)
+	if !ok {
+		return fmt.Errorf("request of %q is not a proto message", method)
+	}
+	b, merr := protojson.Marshal(m)
+	if merr != nil {
+		return merr
+	}
+	call.Request = b
+	for _, resp := range resps {
+		m, ok := resp.(proto.Message)
+		if !ok {
+			continue
+		}
+		b, err := protojson.Marshal(m)
+		if err != nil {
+			return err
+		}
+		call.Responses = append(call.Responses, b)
+	}
+	st := status.Convert(err)
+	call.Code, call.Message = st.Code().String(), st.Message()
+
+	line, jerr := json.Marshal(call)
+	if jerr != nil {
+		return jerr
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, werr := r.f.Write(append(line, '\n'))
+	return werr
+}
+
+// UnaryServerInterceptor records the unary control calls. It must be the
+// first interceptor, so the calls are recorded with their final status
+// (e.g., of the authentication).
+func (r *Recorder) UnaryServerInterceptor(onErr func(error)) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		if isControlMethod(info.FullMethod) {
+			var resps []interface{}
+			if err == nil {
+				resps = []interface{}{resp}
+			}
+			if rerr := r.record(info.FullMethod, req, resps, false, err); rerr != nil {
+				onErr(rerr)
+			}
+		}
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor records the server stream control calls. The
+// client streams (e.g., the uploads) are not recorded.
+func (r *Recorder) StreamServerInterceptor(onErr func(error)) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if info.IsClientStream || !isControlMethod(info.FullMethod) {
+			return handler(srv, ss)
+		}
+		rs := &recordingStream{ServerStream: ss}
+		err := handler(srv, rs)
+		// rejected before the request was received (e.g., unauthenticated)
+		req := rs.req
+		if req == nil {
+			req = newMessage(info.FullMethod, true)
+		}
+		if req != nil {
+			if rerr := r.record(info.FullMethod, req, rs.resps, rs.truncated, err); rerr != nil {
+				onErr(rerr)
+			}
+		}
+		return err
+	}
+}
+
+type recordingStream struct {
+	grpc.ServerStream
+
+	req       interface{}
+	resps     []interface{}
+	truncated bool
+}
+
+func (s *recordingStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	s.req = m
+	return nil
+}
+
+func (s *recordingStream) SendMsg(m interface{}) error {
+	if len(s.resps) < MaxStreamResponses {
+		// the message may be reused by the handler once sent
+		if pm, ok := m.(proto.Message); ok {
+			s.resps = append(s.resps, proto.Clone(pm))
+		}
+	} else {
+		s.truncated = true
+	}
+	return s.ServerStream.SendMsg(m)
+}
+
+// newMessage returns a new request (or response) message of the method,
+// nil if the method is unknown.
+func newMessage(fullMethod string, input bool) proto.Message {
+	name := protoreflect.FullName(strings.ReplaceAll(strings.TrimPrefix(fullMethod, "/"), "/", "."))
+	d, err := protoregistry.GlobalFiles.FindDescriptorByName(name)
+	if err != nil {
+		return nil
+	}
+	md, ok := d.(protoreflect.MethodDescriptor)
+	if !ok {
+		return nil
+	}
+	msg := md.Output()
+	if input {
+		msg = md.Input()
+	}
+	mt, err := protoregistry.GlobalTypes.FindMessageByName(msg.FullName())
+	if err != nil {
+		return nil
+	}
+	return mt.New().Interface()
+}
+
+// ReadFixtures reads the calls of a fixture file, in order.
+func ReadFixtures(path string) ([]*Call, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var calls []*Call
+	sc := bufio.NewScanner(f)
+	// the responses may be large (e.g., the cluster info of many nodes)
+	sc.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for line := 1; sc.Scan(); line++ {
+		if len(strings.TrimSpace(sc.Text())) == 0 {
+			continue
+		}
+		c := &Call{}
+		if err := json.Unmarshal(sc.Bytes(), c); err != nil {
+			return nil, fmt.Errorf("%w %q: line %d: %v", ErrInvalidFixtures, path, line, err)
+		}
+		calls = append(calls, c)
+	}
+	return calls, sc.Err()
+}
+
+// Mismatch is a replayed call whose result is incompatible with its fixture.
+type Mismatch struct {
+	// index of the call in the fixtures
+	Index  int
+	Method string
+	Reason string
+}
+
+// VerifyConfig are the options of the replay.
+type VerifyConfig struct {
+	// timeout of each call, none if zero
+	CallTimeout time.Duration
+	// response field paths not compared, by their JSON names (e.g.,
+	// "clusterInfo.nodeInfos.*.chainIpcs"), "*" matching any field or
+	// map key
+	IgnorePaths []string
+	// per-call options (e.g., the tenant token)
+	CallOptions []grpc.CallOption
+}
+
+// Verify replays the calls in order against the server, and returns the
+// calls with another status code than recorded, a recorded response that
+// the server messages no longer parse (e.g., a removed field), or a field
+// set as recorded but not as replayed. The values themselves (e.g., the
+// ports, the process IDs, or the paths) vary across runs, and are not
+// compared, nor are the fields added since the recording.
+func Verify(ctx context.Context, conn *grpc.ClientConn, calls []*Call, cfg VerifyConfig) ([]Mismatch, error) {
+	var mismatches []Mismatch
+	for i, c := range calls {
+		req := newMessage(c.Method, true)
+		if req == nil {
+			mismatches = append(mismatches, Mismatch{Index: i, Method: c.Method, Reason: "unknown method"})
+			continue
+		}
+		if err := protojson.Unmarshal(c.Request, req); err != nil {
+			mismatches = append(mismatches, Mismatch{Index: i, Method: c.Method, Reason: fmt.Sprintf("incompatible request: %v", err)})
+			continue
+		}
+		cctx, cancel := ctx, context.CancelFunc(func() {})
+		if cfg.CallTimeout > 0 {
+			cctx, cancel = context.WithTimeout(ctx, cfg.CallTimeout)
+		}
+		resps, err := replay(cctx, conn, c, req, cfg.CallOptions)
+		cancel()
+		if ctx.Err() != nil {
+			return mismatches, ctx.Err()
+		}
+		for _, reason := range compare(c, resps, err, cfg.IgnorePaths) {
+			mismatches = append(mismatches, Mismatch{Index: i, Method: c.Method, Reason: reason})
+		}
+	}
+	return mismatches, nil
+}
+
+// replay calls the method with the request, and returns the responses, up
+// to the number recorded for a truncated (or canceled) stream.
+func replay(ctx context.Context, conn *grpc.ClientConn, c *Call, req proto.Message, opts []grpc.CallOption) ([]proto.Message, error) {
+	desc, ok := streamDesc(c.Method)
+	if !ok {
+		resp := newMessage(c.Method, false)
+		if err := conn.Invoke(ctx, c.Method, req, resp, opts...); err != nil {
+			return nil, err
+		}
+		return []proto.Message{resp}, nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := conn.NewStream(ctx, desc, c.Method, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := stream.SendMsg(req); err != nil {
+		return nil, err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return nil, err
+	}
+	open := stoppedByClient(c)
+	var resps []proto.Message
+	for !open || len(resps) < len(c.Responses) {
+		resp := newMessage(c.Method, false)
+		err := stream.RecvMsg(resp)
+		if err == io.EOF {
+			return resps, nil
+		}
+		if err != nil {
+			return resps, err
+		}
+		resps = append(resps, resp)
+	}
+	return resps, status.FromContextError(context.Canceled).Err()
+}
+
+func streamDesc(fullMethod string) (*grpc.StreamDesc, bool) {
+	name := strings.TrimPrefix(fullMethod, "/"+rpcpb.ControlService_ServiceDesc.ServiceName+"/")
+	for i := range rpcpb.ControlService_ServiceDesc.Streams {
+		if d := &rpcpb.ControlService_ServiceDesc.Streams[i]; d.StreamName == name {
+			return d, true
+		}
+	}
+	return nil, false
+}
+
+// compare returns the reasons the replayed result is incompatible with the
+// recorded one, none if compatible.
+func compare(c *Call, resps []proto.Message, err error, ignore []string) []string {
+	code := status.Code(err).String()
+	// the replayed stream was canceled as the recorded one
+	if stoppedByClient(c) && code == codes.Canceled.String() {
+		code = c.Code
+	}
+	var reasons []string
+	if code != c.Code {
+		reasons = append(reasons, fmt.Sprintf("status %s (%q), recorded %s (%q)", code, status.Convert(err).Message(), c.Code, c.Message))
+	}
+	if len(resps) < len(c.Responses) {
+		reasons = append(reasons, fmt.Sprintf("%d responses, recorded %d", len(resps), len(c.Responses)))
+	}
+	for i := 0; i < len(resps) && i < len(c.Responses); i++ {
+		prefix := ""
+		if len(c.Responses) > 1 {
+			prefix = fmt.Sprintf("response %d: ", i+1)
+		}
+		// fails on the removed fields and enum values, and on the fields
+		// of another type
+		recorded := newMessage(c.Method, false)
+		if err := protojson.Unmarshal(c.Responses[i], recorded); err != nil {
+			reasons = append(reasons, fmt.Sprintf("%sincompatible recorded response: %v", prefix, err))
+			continue
+		}
+		for _, diff := range compareFields("", recorded.ProtoReflect(), resps[i].ProtoReflect(), ignore) {
+			reasons = append(reasons, prefix+diff)
+		}
+	}
+	return reasons
+}
+
+// stoppedByClient returns true if the recorded client stopped the stream
+// (e.g., once it had enough), rather than the server.
+func stoppedByClient(c *Call) bool {
+	return c.Truncated || c.Code == codes.Canceled.String() || c.Code == codes.DeadlineExceeded.String()
+}
+
+// compareFields returns the fields set in the recorded message but not in
+// the replayed one, by their JSON path. The list elements are compared as
+// far as both lists go, and the map values of the keys in both maps (as
+// the keys, e.g., the blockchain IDs, may vary).
+func compareFields(path string, recorded protoreflect.Message, replayed protoreflect.Message, ignore []string) []string {
+	var diffs []string
+	recorded.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		fp := joinPath(path, fd.JSONName())
+		if ignored(fp, ignore) {
+			return true
+		}
+		if !replayed.Has(fd) {
+			diffs = append(diffs, fmt.Sprintf("%s: unset, recorded set", fp))
+			return true
+		}
+		rv := replayed.Get(fd)
+		switch {
+		case fd.IsList():
+			if fd.Message() == nil {
+				return true
+			}
+			l, rl := v.List(), rv.List()
+			for i := 0; i < l.Len() && i < rl.Len(); i++ {
+				diffs = append(diffs, compareFields(joinPath(fp, fmt.Sprint(i)), l.Get(i).Message(), rl.Get(i).Message(), ignore)...)
+			}
+		case fd.IsMap():
+			if fd.MapValue().Message() == nil {
+				return true
+			}
+			rm := rv.Map()
+			keys := make([]protoreflect.MapKey, 0, v.Map().Len())
+			v.Map().Range(func(k protoreflect.MapKey, _ protoreflect.Value) bool {
+				if rm.Has(k) {
+					keys = append(keys, k)
+				}
+				return true
+			})
+			sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+			for _, k := range keys {
+				diffs = append(diffs, compareFields(joinPath(fp, k.String()), v.Map().Get(k).Message(), rm.Get(k).Message(), ignore)...)
+			}
+		case fd.Message() != nil:
+			diffs = append(diffs, compareFields(fp, v.Message(), rv.Message(), ignore)...)
+		}
+		return true
+	})
+	return diffs
+}
+
+func joinPath(path string, k string) string {
+	if path == "" {
+		return k
+	}
+	return path + "." + k
+}
+
+// ignored returns true if the path matches one of the ignored paths, or
+// is a field under one.
+func ignored(path string, ignore []string) bool {
+	if path == "" {
+		return false
+	}
+	segs := strings.Split(path, ".")
+	for _, pattern := range ignore {
+		ps := strings.Split(pattern, ".")
+		if len(ps) > len(segs) {
+			continue
+		}
+		match := true
+		for i, p := range ps {
+			if p != "*" && p != segs[i] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
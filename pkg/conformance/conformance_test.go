@@ -0,0 +1,192 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package conformance
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// fakeControl serves the cluster info, and fails to remove the nodes.
+type fakeControl struct {
+	rpcpb.UnimplementedControlServiceServer
+
+	mu        sync.Mutex
+	info      *rpcpb.ClusterInfo
+	removeErr error
+}
+
+func (c *fakeControl) Status(context.Context, *rpcpb.StatusRequest) (*rpcpb.StatusResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return &rpcpb.StatusResponse{ClusterInfo: proto.Clone(c.info).(*rpcpb.ClusterInfo)}, nil
+}
+
+func (c *fakeControl) RemoveNode(context.Context, *rpcpb.RemoveNodeRequest) (*rpcpb.RemoveNodeResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.removeErr != nil {
+		return nil, c.removeErr
+	}
+	return &rpcpb.RemoveNodeResponse{ClusterInfo: proto.Clone(c.info).(*rpcpb.ClusterInfo)}, nil
+}
+
+func (c *fakeControl) set(f func(c *fakeControl)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	f(c)
+}
+
+func serve(t *testing.T, srv rpcpb.ControlServiceServer, opts ...grpc.ServerOption) *grpc.ClientConn {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gs := grpc.NewServer(opts...)
+	rpcpb.RegisterControlServiceServer(gs, srv)
+	go gs.Serve(ln)
+	t.Cleanup(gs.Stop)
+
+	conn, err := grpc.Dial(ln.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestRecordVerify(t *testing.T) {
+	fc := &fakeControl{
+		info: &rpcpb.ClusterInfo{
+			NodeNames:   []string{"node1"},
+			NodeInfos:   map[string]*rpcpb.NodeInfo{"node1": {Name: "node1", Uri: "http://127.0.0.1:9650"}},
+			Pid:         100,
+			RootDataDir: "/tmp/network-runner-root-data-1",
+			Healthy:     true,
+		},
+		removeErr: status.Error(codes.NotFound, "node not found"),
+	}
+	fixtures := filepath.Join(t.TempDir(), "fixtures.jsonl")
+	r, err := NewRecorder(fixtures)
+	if err != nil {
+		t.Fatal(err)
+	}
+	onErr := func(err error) { t.Errorf("failed to record: %v", err) }
+	conn := serve(t, fc, grpc.UnaryInterceptor(r.UnaryServerInterceptor(onErr)))
+	cli := rpcpb.NewControlServiceClient(conn)
+	if _, err := cli.Status(context.Background(), &rpcpb.StatusRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cli.RemoveNode(context.Background(), &rpcpb.RemoveNodeRequest{Name: "node2"}); status.Code(err) != codes.NotFound {
+		t.Fatalf("expected %v, got %v", codes.NotFound, err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	calls, err := ReadFixtures(fixtures)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) != 2 || calls[0].Method != "/rpcpb.ControlService/Status" || calls[1].Code != codes.NotFound.String() {
+		t.Fatalf("unexpected recorded calls %+v", calls)
+	}
+
+	// another run, with other values
+	fc.set(func(c *fakeControl) {
+		c.info.Pid, c.info.RootDataDir = 200, "/tmp/network-runner-root-data-2"
+	})
+	conn = serve(t, fc)
+	mismatches, err := Verify(context.Background(), conn, calls, VerifyConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mismatches) != 0 {
+		t.Fatalf("expected no mismatches, got %+v", mismatches)
+	}
+
+	// a build no longer setting the fields, nor failing the call
+	fc.set(func(c *fakeControl) {
+		c.info.RootDataDir = ""
+		c.info.NodeInfos["node1"].Uri = ""
+		c.removeErr = nil
+	})
+	for i, tv := range []struct {
+		ignore  []string
+		reasons []string
+	}{
+		{
+			reasons: []string{
+				"clusterInfo.nodeInfos.node1.uri: unset, recorded set",
+				"clusterInfo.rootDataDir: unset, recorded set",
+				`status OK (""), recorded NotFound ("node not found")`,
+			},
+		},
+		{
+			ignore:  []string{"clusterInfo.rootDataDir", "clusterInfo.nodeInfos.*.uri"},
+			reasons: []string{`status OK (""), recorded NotFound ("node not found")`},
+		},
+		{
+			ignore: []string{"clusterInfo.nodeInfos"},
+			reasons: []string{
+				"clusterInfo.rootDataDir: unset, recorded set",
+				`status OK (""), recorded NotFound ("node not found")`,
+			},
+		},
+	} {
+		mismatches, err := Verify(context.Background(), conn, calls, VerifyConfig{IgnorePaths: tv.ignore})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var reasons []string
+		for _, m := range mismatches {
+			reasons = append(reasons, m.Reason)
+		}
+		if !reflect.DeepEqual(reasons, tv.reasons) {
+			t.Fatalf("#%d: expected %q, got %q", i, tv.reasons, reasons)
+		}
+	}
+}
+
+func TestIgnored(t *testing.T) {
+	for i, tv := range []struct {
+		path    string
+		ignore  []string
+		ignored bool
+	}{
+		{path: "clusterInfo.pid", ignore: []string{"clusterInfo.pid"}, ignored: true},
+		{path: "clusterInfo.nodeInfos.node1.uri", ignore: []string{"clusterInfo.nodeInfos.*.uri"}, ignored: true},
+		{path: "clusterInfo.nodeInfos.node1.uri", ignore: []string{"clusterInfo.nodeInfos"}, ignored: true},
+		{path: "clusterInfo.nodeInfos.node1.uri", ignore: []string{"clusterInfo.nodeInfos.*.id"}},
+		{path: "clusterInfo", ignore: []string{"clusterInfo.pid"}},
+		{path: "clusterInfo.pid"},
+	} {
+		if ignored(tv.path, tv.ignore) != tv.ignored {
+			t.Fatalf("#%d: expected %v, got %v", i, tv.ignored, !tv.ignored)
+		}
+	}
+}
+
+func TestReadFixturesInvalid(t *testing.T) {
+	fixtures := filepath.Join(t.TempDir(), "fixtures.jsonl")
+	if err := ioutil.WriteFile(fixtures, []byte("{\"method\":\"/rpcpb.ControlService/Status\"}\n\nnot json\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadFixtures(fixtures); !errors.Is(err, ErrInvalidFixtures) {
+		t.Fatalf("expected %v, got %v", ErrInvalidFixtures, err)
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// Package discovery finds the endpoint of the local network runner server,
+// so the clients do not need to be passed it.
+package discovery
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// EndpointEnv overrides the discovered endpoint.
+	EndpointEnv = "NETWORK_RUNNER_ENDPOINT"
+	// StateFileEnv overrides the path of the server state file.
+	StateFileEnv = "NETWORK_RUNNER_STATE_FILE"
+
+	// DefaultEndpoint is dialed if no server is discovered.
+	DefaultEndpoint = "0.0.0.0:8080"
+)
+
+// State is written by the server on startup, and removed on exit.
+type State struct {
+	// gRPC endpoint (e.g., "127.0.0.1:8080" or "unix:///tmp/djtx.sock")
+	Endpoint string `json:"endpoint"`
+	// gRPC gateway (HTTP) endpoint
+	GatewayEndpoint string `json:"gatewayEndpoint"`
+	Pid             int    `json:"pid"`
+}
+
+// StateFile returns the path of the server state file.
+func StateFile() string {
+	if p := os.Getenv(StateFileEnv); p != "" {
+		return p
+	}
+	return filepath.Join(os.TempDir(), "network-runner-server.json")
+}
+
+// Endpoint returns the endpoint of the environment variable, or else of
+// the state file of a running server, or else the default endpoint.
+func Endpoint() string {
+	if ep := os.Getenv(EndpointEnv); ep != "" {
+		zap.L().Debug("discovered endpoint", zap.String("env", EndpointEnv), zap.String("endpoint", ep))
+		return ep
+	}
+	path := StateFile()
+	st, err := ReadState(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			zap.L().Debug("failed to read the server state file", zap.String("path", path), zap.Error(err))
+		}
+		return DefaultEndpoint
+	}
+	// a server killed without removing its state file
+	if !processExists(st.Pid) {
+		zap.L().Debug("stale server state file", zap.String("path", path), zap.Int("pid", st.Pid))
+		return DefaultEndpoint
+	}
+	zap.L().Debug("discovered endpoint", zap.String("stateFile", path), zap.String("endpoint", st.Endpoint))
+	return st.Endpoint
+}
+
+// ReadState reads the server state file.
+func ReadState(path string) (State, error) {
+	var st State
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return st, err
+	}
+	if err := json.Unmarshal(b, &st); err != nil {
+		return st, err
+	}
+	if st.Endpoint == "" {
+		return st, errors.New("empty endpoint")
+	}
+	return st, nil
+}
+
+// WriteState writes the state file atomically, so the clients never read
+// a partial one.
+func WriteState(path string, st State) error {
+	b, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// RemoveState removes the state file, unless another server (pid) has
+// since replaced it.
+func RemoveState(path string, pid int) error {
+	st, err := ReadState(path)
+	if err != nil || st.Pid != pid {
+		return nil
+	}
+	return os.Remove(path)
+}
+
+func processExists(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	err = p.Signal(syscall.Signal(0))
+	// owned by another user
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
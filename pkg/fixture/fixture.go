@@ -0,0 +1,202 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// Package fixture shares a network of the network runner server
+// across the Go test packages of a CI job, so it bootstraps only once.
+package fixture
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/lasthyphen/djtx-tester/client"
+	"github.com/lasthyphen/djtx-tester/pkg/logutil"
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+	"google.golang.org/grpc/status"
+)
+
+// KeepEnv is the environment variable that, if set, keeps the network
+// created by a test package running for the later packages. The CI job
+// then stops it at the end (e.g., "avalanche-network-runner control stop").
+const KeepEnv = "NETWORK_RUNNER_KEEP_SHARED"
+
+const (
+	defaultEndpoint     = "0.0.0.0:8080"
+	defaultDialTimeout  = 10 * time.Second
+	defaultStartTimeout = 5 * time.Minute
+	pollInterval        = 5 * time.Second
+)
+
+// server error messages, ref. "server.ErrNotBootstrapped" and "server.ErrAlreadyBootstrapped"
+const (
+	notBootstrappedMsg     = "not bootstrapped"
+	alreadyBootstrappedMsg = "already bootstrapped"
+)
+
+// Spec describes the shared network.
+type Spec struct {
+	// Endpoint is the server endpoint, "0.0.0.0:8080" if empty.
+	Endpoint string
+	// Token authenticates the tenant on a shared server, if not empty.
+	Token    string
+	LogLevel string
+
+	ExecPath string
+	// NumNodes is the number of nodes, the server default if zero.
+	NumNodes           uint32
+	WhitelistedSubnets string
+	// StartOpts are the other start options.
+	// They are not compared when reusing a running network.
+	StartOpts []client.OpOption
+
+	// StartTimeout bounds the wait for the network to be healthy, 5 minutes if zero.
+	StartTimeout time.Duration
+}
+
+// Network is the shared network.
+type Network struct {
+	Client      client.Client
+	ClusterInfo *rpcpb.ClusterInfo
+	URIs        []string
+}
+
+// GetOrCreateSharedNetwork returns the running network matching the spec,
+// or starts it if the server has none, and waits for it to be healthy.
+// A network started by the test is stopped when it completes,
+// unless "KeepEnv" is set. A running network that does not match
+// the spec fails the test, and is left running.
+func GetOrCreateSharedNetwork(t testing.TB, spec Spec) *Network {
+	t.Helper()
+
+	if spec.Endpoint == "" {
+		spec.Endpoint = defaultEndpoint
+	}
+	if spec.LogLevel == "" {
+		spec.LogLevel = logutil.DefaultLogLevel
+	}
+	if spec.StartTimeout == 0 {
+		spec.StartTimeout = defaultStartTimeout
+	}
+
+	cli, err := client.New(client.Config{
+		LogLevel:    spec.LogLevel,
+		Endpoint:    spec.Endpoint,
+		DialTimeout: defaultDialTimeout,
+		Token:       spec.Token,
+	})
+	if err != nil {
+		t.Fatalf("failed to connect to the network runner server %q: %v", spec.Endpoint, err)
+	}
+	t.Cleanup(func() { _ = cli.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), spec.StartTimeout)
+	defer cancel()
+
+	created, err := getOrStart(ctx, cli, spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created && os.Getenv(KeepEnv) == "" {
+		t.Cleanup(func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+			defer cancel()
+			if _, err := cli.Stop(ctx); err != nil {
+				t.Errorf("failed to stop the shared network: %v", err)
+			}
+		})
+	}
+
+	info, err := waitForHealthy(ctx, cli)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := match(info, spec); err != nil {
+		t.Fatal(err)
+	}
+	uris, err := cli.URIs(ctx)
+	if err != nil {
+		t.Fatalf("failed to get the shared network URIs: %v", err)
+	}
+	return &Network{Client: cli, ClusterInfo: info, URIs: uris}
+}
+
+// waitForHealthy polls the network status until it is healthy,
+// since "Health" rejects the networks still being created.
+func waitForHealthy(ctx context.Context, cli client.Client) (*rpcpb.ClusterInfo, error) {
+	for {
+		resp, err := cli.Status(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the shared network status: %w", err)
+		}
+		info := resp.ClusterInfo
+		switch info.State {
+		case rpcpb.NetworkState_NETWORK_STATE_HEALTHY:
+			// the node infos are recorded right after the network is healthy
+			if len(info.NodeInfos) > 0 {
+				return info, nil
+			}
+		case rpcpb.NetworkState_NETWORK_STATE_CREATING, rpcpb.NetworkState_NETWORK_STATE_BOOTSTRAPPING:
+		default:
+			return nil, fmt.Errorf("shared network is %s: %s", info.State, info.Error)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("shared network is not healthy: %w", ctx.Err())
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// getOrStart starts the network unless the server already runs one,
+// and returns whether it was started.
+func getOrStart(ctx context.Context, cli client.Client, spec Spec) (bool, error) {
+	_, err := cli.Status(ctx)
+	if err == nil {
+		return false, nil
+	}
+	if status.Convert(err).Message() != notBootstrappedMsg {
+		return false, fmt.Errorf("failed to get the network status: %w", err)
+	}
+
+	opts := make([]client.OpOption, 0, len(spec.StartOpts)+2)
+	if spec.NumNodes > 0 {
+		opts = append(opts, client.WithNumNodes(spec.NumNodes))
+	}
+	if spec.WhitelistedSubnets != "" {
+		opts = append(opts, client.WithWhitelistedSubnets(spec.WhitelistedSubnets))
+	}
+	opts = append(opts, spec.StartOpts...)
+	_, err = cli.Start(ctx, spec.ExecPath, opts...)
+	switch {
+	case err == nil:
+		return true, nil
+	case status.Convert(err).Message() == alreadyBootstrappedMsg:
+		// another test package started it in the meantime
+		return false, nil
+	default:
+		return false, fmt.Errorf("failed to start the shared network: %w", err)
+	}
+}
+
+// match returns an error if the running network does not match the spec.
+func match(info *rpcpb.ClusterInfo, spec Spec) error {
+	if spec.NumNodes > 0 && len(info.NodeNames) != int(spec.NumNodes) {
+		return fmt.Errorf("running network has %d nodes, expected %d", len(info.NodeNames), spec.NumNodes)
+	}
+	for _, name := range info.NodeNames {
+		node := info.NodeInfos[name]
+		if node == nil {
+			return fmt.Errorf("running network has no info of node %q", name)
+		}
+		if node.ExecPath != spec.ExecPath {
+			return fmt.Errorf("running network node %q has binary %q, expected %q", name, node.ExecPath, spec.ExecPath)
+		}
+		if spec.WhitelistedSubnets != "" && node.WhitelistedSubnets != spec.WhitelistedSubnets {
+			return fmt.Errorf("running network node %q whitelists subnets %q, expected %q", name, node.WhitelistedSubnets, spec.WhitelistedSubnets)
+		}
+	}
+	return nil
+}
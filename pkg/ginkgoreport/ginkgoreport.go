@@ -0,0 +1,153 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// Package ginkgoreport attaches the network state and the recent node logs
+// to the reports of the failed ginkgo specs, so a CI failure can be debugged
+// from its report (e.g., "--json-report" or "--junit-report") after the
+// network is gone.
+package ginkgoreport
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lasthyphen/djtx-tester/client"
+	ginkgo "github.com/onsi/ginkgo/v2"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const (
+	// DefaultLogLines is the number of log lines of each node,
+	// unless "WithLogLines" is set.
+	DefaultLogLines = 100
+	// DefaultTimeout bounds the collection, unless "WithTimeout" is set.
+	DefaultTimeout = 30 * time.Second
+
+	// most lines searched for the last ones, ref. "server.maxLogSearchMatches"
+	maxSearchMatches = 10000
+)
+
+type Op struct {
+	logLines  int
+	nodeNames []string
+	timeout   time.Duration
+}
+
+type OpOption func(*Op)
+
+func (op *Op) applyOpts(opts []OpOption) {
+	op.logLines = DefaultLogLines
+	op.timeout = DefaultTimeout
+	for _, opt := range opts {
+		opt(op)
+	}
+}
+
+// WithLogLines sets the number of log lines of each node, zero for none.
+func WithLogLines(n int) OpOption {
+	return func(op *Op) {
+		op.logLines = n
+	}
+}
+
+// WithNodeNames limits the logs to the nodes, all nodes if empty.
+func WithNodeNames(names ...string) OpOption {
+	return func(op *Op) {
+		op.nodeNames = names
+	}
+}
+
+// WithTimeout bounds the collection of the network state and logs.
+func WithTimeout(d time.Duration) OpOption {
+	return func(op *Op) {
+		op.timeout = d
+	}
+}
+
+// ReportFailures registers a "JustAfterEach" that attaches the network
+// state and the recent node logs to the report of each failed spec, before
+// the "AfterEach" cleanups change the network. The client is got once the
+// spec fails, as the suites usually create it in "BeforeSuite":
+//
+//	var _ = ginkgoreport.ReportFailures(func() client.Client { return cli })
+func ReportFailures(getClient func() client.Client, opts ...OpOption) bool {
+	return ginkgo.JustAfterEach(func() {
+		report := ginkgo.CurrentSpecReport()
+		if !report.Failed() {
+			return
+		}
+		cli := getClient()
+		if cli == nil {
+			return
+		}
+		if err := Attach(cli, report.StartTime, opts...); err != nil {
+			// the report has the spec failure already
+			fmt.Fprintf(ginkgo.GinkgoWriter, "failed to attach the network state: %v\n", err)
+		}
+	})
+}
+
+// Attach adds the network status ("network status") and the last log lines
+// of each node since the given time ("node logs <name>") to the report of
+// the current spec. It must be called in a setup or subject node (e.g.,
+// "AfterEach"). The entries are shown on the console for the failed specs
+// (or with "-v"), and are in all machine-readable reports.
+func Attach(cli client.Client, since time.Time, opts ...OpOption) error {
+	ret := &Op{}
+	ret.applyOpts(opts)
+
+	ctx, cancel := context.WithTimeout(context.Background(), ret.timeout)
+	defer cancel()
+
+	resp, err := cli.Status(ctx)
+	if err != nil {
+		return err
+	}
+	b, err := protojson.MarshalOptions{Multiline: true, Indent: "  "}.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	ginkgo.AddReportEntry("network status", string(b), ginkgo.ReportEntryVisibilityFailureOrVerbose)
+
+	if ret.logLines == 0 {
+		return nil
+	}
+	names := ret.nodeNames
+	if len(names) == 0 {
+		for name := range resp.GetClusterInfo().GetNodeInfos() {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+	// the search time range is in seconds
+	since = since.Truncate(time.Second)
+	var errs []string
+	for _, name := range names {
+		sresp, err := cli.SearchLogs(ctx, "^",
+			client.WithRegex(),
+			client.WithNodeNames(name),
+			client.WithTimeRange(since, time.Time{}),
+			client.WithMaxMatches(maxSearchMatches),
+		)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+		matches := sresp.Matches
+		if len(matches) > ret.logLines {
+			matches = matches[len(matches)-ret.logLines:]
+		}
+		lines := make([]string, 0, len(matches))
+		for _, m := range matches {
+			lines = append(lines, m.File+": "+m.Line)
+		}
+		ginkgo.AddReportEntry("node logs "+name, strings.Join(lines, "\n"), ginkgo.ReportEntryVisibilityFailureOrVerbose)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to search the logs of %d nodes: %s", len(errs), strings.Join(errs, "; "))
+	}
+	return nil
+}
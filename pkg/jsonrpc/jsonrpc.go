@@ -0,0 +1,91 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// Package jsonrpc implements a minimal JSON-RPC 2.0 client for node APIs.
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+type request struct {
+	JSONRPC string      `json:"jsonrpc"`
+	ID      uint64      `json:"id"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+type response struct {
+	Result json.RawMessage `json:"result"`
+	Error  *Error          `json:"error"`
+}
+
+// Error is the error object returned by the node.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("jsonrpc error %d: %s", e.Code, e.Message)
+}
+
+var reqID uint64
+
+// Call sends a JSON-RPC request to the node endpoint
+// (e.g., "http://127.0.0.1:9650" + "/ext/bc/X") and decodes
+// the result into "reply", if not nil.
+func Call(ctx context.Context, uri string, endpoint string, method string, params interface{}, reply interface{}) error {
+	if params == nil {
+		params = struct{}{}
+	}
+	b, err := json.Marshal(request{
+		JSONRPC: "2.0",
+		ID:      atomic.AddUint64(&reqID, 1),
+		Method:  method,
+		Params:  params,
+	})
+	if err != nil {
+		return err
+	}
+
+	url := strings.TrimSuffix(uri, "/") + endpoint
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	rb, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s %s: unexpected status %d (%s)", url, method, resp.StatusCode, strings.TrimSpace(string(rb)))
+	}
+
+	var rs response
+	if err := json.Unmarshal(rb, &rs); err != nil {
+		return err
+	}
+	if rs.Error != nil {
+		return rs.Error
+	}
+	if reply == nil {
+		return nil
+	}
+	return json.Unmarshal(rs.Result, reply)
+}
@@ -0,0 +1,173 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// Package secrets resolves the secret references of the configs (e.g.,
+// "env:RUNNER_TOKEN" or "vault:secret/data/runner#token"), so the secrets
+// are not in the flags, the config files, or the logs.
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrNotFound     = errors.New("secret not found")
+	ErrInvalidVault = errors.New("invalid vault response")
+)
+
+// Provider returns the secrets of its references.
+type Provider interface {
+	// Get returns the secret of the reference, without the scheme
+	// (e.g., "RUNNER_TOKEN" of "env:RUNNER_TOKEN").
+	Get(ctx context.Context, ref string) (string, error)
+}
+
+var (
+	mu        sync.RWMutex
+	providers = map[string]Provider{
+		"env":   Env{},
+		"file":  File{},
+		"vault": &Vault{},
+	}
+)
+
+// Register adds (or replaces) the provider of the scheme.
+func Register(scheme string, p Provider) {
+	mu.Lock()
+	defer mu.Unlock()
+	providers[scheme] = p
+}
+
+// Resolve returns the secret of the reference ("<scheme>:<ref>"), or the
+// value as is if it has no scheme of a registered provider.
+func Resolve(ctx context.Context, v string) (string, error) {
+	p, scheme, ref, ok := lookup(v)
+	if !ok {
+		return v, nil
+	}
+	s, err := p.Get(ctx, ref)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve the %s secret %q: %w", scheme, ref, err)
+	}
+	return s, nil
+}
+
+func lookup(v string) (p Provider, scheme string, ref string, ok bool) {
+	idx := strings.Index(v, ":")
+	if idx <= 0 {
+		return nil, "", "", false
+	}
+	scheme = v[:idx]
+	mu.RLock()
+	p, ok = providers[scheme]
+	mu.RUnlock()
+	return p, scheme, v[idx+1:], ok
+}
+
+// Env reads the secrets from the environment variables ("env:<name>").
+type Env struct{}
+
+func (Env) Get(_ context.Context, name string) (string, error) {
+	v, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("%w: no environment variable %q", ErrNotFound, name)
+	}
+	return v, nil
+}
+
+// File reads the secrets from the files ("file:<path>"), without the
+// trailing newline, as written by the secret mounts (e.g., of Kubernetes).
+type File struct{}
+
+func (File) Get(_ context.Context, path string) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
+// default vault request timeout
+const vaultTimeout = 10 * time.Second
+
+// Vault reads the secrets from the HashiCorp Vault KV secrets engine
+// ("vault:<path>#<field>", e.g., "vault:secret/data/runner#token"), the
+// "value" field if none. The version 1 and 2 engines are both supported.
+type Vault struct {
+	// "VAULT_ADDR" if empty
+	Addr string
+	// "VAULT_TOKEN" if empty
+	Token string
+	// "VAULT_NAMESPACE" if empty, for the Vault Enterprise namespaces
+	Namespace string
+	Client    *http.Client
+}
+
+func (v *Vault) Get(ctx context.Context, ref string) (string, error) {
+	path, field := ref, "value"
+	if idx := strings.LastIndex(ref, "#"); idx >= 0 {
+		path, field = ref[:idx], ref[idx+1:]
+	}
+	addr := orEnv(v.Addr, "VAULT_ADDR")
+	if addr == "" {
+		return "", errors.New("empty vault address (set VAULT_ADDR)")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(addr, "/")+"/v1/"+strings.TrimPrefix(path, "/"), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", orEnv(v.Token, "VAULT_TOKEN"))
+	if ns := orEnv(v.Namespace, "VAULT_NAMESPACE"); ns != "" {
+		req.Header.Set("X-Vault-Namespace", ns)
+	}
+	cli := v.Client
+	if cli == nil {
+		cli = &http.Client{Timeout: vaultTimeout}
+	}
+	resp, err := cli.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return "", fmt.Errorf("%w: no vault secret %q", ErrNotFound, path)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault secret %q: unexpected status %s", path, resp.Status)
+	}
+
+	var body struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidVault, err)
+	}
+	data := body.Data
+	// the version 2 engine nests the secret data, with its metadata
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		if _, ok := data["metadata"]; ok {
+			data = nested
+		}
+	}
+	s, ok := data[field].(string)
+	if !ok {
+		return "", fmt.Errorf("%w: no string field %q in vault secret %q", ErrNotFound, field, path)
+	}
+	return s, nil
+}
+
+func orEnv(v string, env string) string {
+	if v != "" {
+		return v
+	}
+	return os.Getenv(env)
+}
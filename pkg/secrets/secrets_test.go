@@ -0,0 +1,128 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	for i, tv := range []struct {
+		v      string
+		scheme string
+		ref    string
+		ok     bool
+	}{
+		{v: "env:RUNNER_TOKEN", scheme: "env", ref: "RUNNER_TOKEN", ok: true},
+		{v: "vault:secret/data/runner#token", scheme: "vault", ref: "secret/data/runner#token", ok: true},
+		// only split on the first colon
+		{v: "file:/run/secrets/a:b", scheme: "file", ref: "/run/secrets/a:b", ok: true},
+		{v: "env:", scheme: "env", ref: "", ok: true},
+		{v: "PrivateKey-ewoqjP7PxY4yr3iLTpLisriqt94hdyDFNgchSxGGztUrTXtNN"},
+		{v: "http://127.0.0.1:8080"},
+		{v: ":token"},
+		{v: "token"},
+	} {
+		_, scheme, ref, ok := lookup(tv.v)
+		if ok != tv.ok || (ok && (scheme != tv.scheme || ref != tv.ref)) {
+			t.Fatalf("#%d: expected %q %q %v, got %q %q %v", i, tv.scheme, tv.ref, tv.ok, scheme, ref, ok)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	t.Setenv("SECRETS_TEST_TOKEN", "env-token")
+	path := filepath.Join(t.TempDir(), "token")
+	if err := ioutil.WriteFile(path, []byte("file-token\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for i, tv := range []struct {
+		v   string
+		exp string
+		err error
+	}{
+		{v: "env:SECRETS_TEST_TOKEN", exp: "env-token"},
+		{v: "env:SECRETS_TEST_MISSING", err: ErrNotFound},
+		{v: "file:" + path, exp: "file-token"},
+		{v: "plain-token", exp: "plain-token"},
+	} {
+		s, err := Resolve(context.Background(), tv.v)
+		if !errors.Is(err, tv.err) || s != tv.exp {
+			t.Fatalf("#%d: expected %q %v, got %q %v", i, tv.exp, tv.err, s, err)
+		}
+	}
+}
+
+func TestVault(t *testing.T) {
+	var gotToken, gotNamespace string
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotToken, gotNamespace = r.Header.Get("X-Vault-Token"), r.Header.Get("X-Vault-Namespace")
+		switch r.URL.Path {
+		case "/v1/kv/runner":
+			fmt.Fprint(w, `{"data":{"value":"v1-value","token":"v1-token"}}`)
+		case "/v1/secret/data/runner":
+			fmt.Fprint(w, `{"data":{"data":{"token":"v2-token"},"metadata":{"version":3}}}`)
+		case "/v1/kv/nested":
+			// a version 1 secret with a "data" field, without metadata
+			fmt.Fprint(w, `{"data":{"data":{"token":"nested"},"token":"v1-nested"}}`)
+		case "/v1/kv/invalid":
+			fmt.Fprint(w, `not json`)
+		case "/v1/kv/forbidden":
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer vault.Close()
+
+	v := &Vault{Addr: vault.URL + "/", Token: "vault-token", Namespace: "ns1"}
+	for i, tv := range []struct {
+		ref  string
+		exp  string
+		err  error
+		fail bool
+	}{
+		{ref: "kv/runner", exp: "v1-value"},
+		{ref: "kv/runner#token", exp: "v1-token"},
+		{ref: "/secret/data/runner#token", exp: "v2-token"},
+		{ref: "kv/nested#token", exp: "v1-nested"},
+		{ref: "secret/data/runner", err: ErrNotFound},
+		{ref: "kv/missing#token", err: ErrNotFound},
+		{ref: "kv/invalid", err: ErrInvalidVault},
+		{ref: "kv/forbidden", fail: true},
+	} {
+		s, err := v.Get(context.Background(), tv.ref)
+		if tv.fail {
+			if err == nil {
+				t.Fatalf("#%d: expected an error, got %q", i, s)
+			}
+			continue
+		}
+		if !errors.Is(err, tv.err) || s != tv.exp {
+			t.Fatalf("#%d: expected %q %v, got %q %v", i, tv.exp, tv.err, s, err)
+		}
+	}
+	if gotToken != "vault-token" || gotNamespace != "ns1" {
+		t.Fatalf("unexpected vault headers %q %q", gotToken, gotNamespace)
+	}
+
+	// from the environment variables
+	t.Setenv("VAULT_ADDR", vault.URL)
+	t.Setenv("VAULT_TOKEN", "env-vault-token")
+	t.Setenv("VAULT_NAMESPACE", "")
+	s, err := Resolve(context.Background(), "vault:secret/data/runner#token")
+	if err != nil || s != "v2-token" {
+		t.Fatalf("expected %q, got %q %v", "v2-token", s, err)
+	}
+	if gotToken != "env-vault-token" || gotNamespace != "" {
+		t.Fatalf("unexpected vault headers %q %q", gotToken, gotNamespace)
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// Package testkeys exports the keys prefunded in the default local network
+// genesis, with their addresses on each chain and signing helpers.
+package testkeys
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lasthyphen/dijetsnodego/ids"
+	"github.com/lasthyphen/dijetsnodego/utils/cb58"
+	"github.com/lasthyphen/dijetsnodego/utils/constants"
+	"github.com/lasthyphen/dijetsnodego/utils/crypto"
+	"github.com/lasthyphen/dijetsnodego/utils/formatting/address"
+	"golang.org/x/crypto/sha3"
+)
+
+const (
+	// EwoqPrivateKey is prefunded on the X, P, and C chains
+	// of the default local network genesis.
+	// ref. "local.NewDefaultConfig"
+	EwoqPrivateKey = crypto.PrivateKeyPrefix + "ewoqjP7PxY4yr3iLTpLisriqt94hdyDFNgchSxGGztUrTXtNN"
+
+	// HRP is the bech32 human-readable part of the local network addresses.
+	HRP = constants.LocalHRP
+)
+
+var ErrInvalidPrivateKey = errors.New("invalid private key")
+
+// Key is a secp256k1 key with its addresses on the local network.
+type Key struct {
+	PrivateKey *crypto.PrivateKeySECP256K1R
+
+	xAddr   string
+	pAddr   string
+	cAddr   string
+	ethAddr string
+}
+
+// Genesis returns the keys prefunded in the default local network genesis.
+func Genesis() []*Key {
+	return []*Key{Ewoq()}
+}
+
+// Ewoq returns the "ewoq" genesis key.
+func Ewoq() *Key {
+	k, err := Parse(EwoqPrivateKey)
+	if err != nil {
+		panic(err)
+	}
+	return k
+}
+
+// Parse parses a "PrivateKey-" prefixed, CB58-encoded secp256k1 key.
+func Parse(s string) (*Key, error) {
+	if !strings.HasPrefix(s, crypto.PrivateKeyPrefix) {
+		return nil, fmt.Errorf("%w: missing %q prefix", ErrInvalidPrivateKey, crypto.PrivateKeyPrefix)
+	}
+	b, err := cb58.Decode(strings.TrimPrefix(s, crypto.PrivateKeyPrefix))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPrivateKey, err)
+	}
+	factory := crypto.FactorySECP256K1R{}
+	pk, err := factory.ToPrivateKey(b)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPrivateKey, err)
+	}
+	return newKey(pk.(*crypto.PrivateKeySECP256K1R))
+}
+
+func newKey(pk *crypto.PrivateKeySECP256K1R) (*Key, error) {
+	k := &Key{PrivateKey: pk}
+	addr := pk.Address().Bytes()
+
+	var err error
+	if k.xAddr, err = address.Format("X", HRP, addr); err != nil {
+		return nil, err
+	}
+	if k.pAddr, err = address.Format("P", HRP, addr); err != nil {
+		return nil, err
+	}
+	if k.cAddr, err = address.Format("C", HRP, addr); err != nil {
+		return nil, err
+	}
+	k.ethAddr = ethAddress(pk)
+	return k, nil
+}
+
+// ethAddress returns the hex-encoded EVM address of the key,
+// the last 20 bytes of the Keccak-256 hash of the uncompressed public key.
+func ethAddress(pk *crypto.PrivateKeySECP256K1R) string {
+	pub := pk.ToECDSA().PublicKey
+	b := make([]byte, 64)
+	pub.X.FillBytes(b[:32])
+	pub.Y.FillBytes(b[32:])
+
+	h := sha3.NewLegacyKeccak256()
+	h.Write(b)
+	return "0x" + hex.EncodeToString(h.Sum(nil)[12:])
+}
+
+// ShortID returns the address of the key shared by the X, P, and C chains.
+func (k *Key) ShortID() ids.ShortID { return k.PrivateKey.Address() }
+
+// XAddress returns the X-chain address (e.g., "X-local1...").
+func (k *Key) XAddress() string { return k.xAddr }
+
+// PAddress returns the P-chain address (e.g., "P-local1...").
+func (k *Key) PAddress() string { return k.pAddr }
+
+// CAddress returns the bech32 C-chain address used by atomic
+// import/export transactions (e.g., "C-local1...").
+func (k *Key) CAddress() string { return k.cAddr }
+
+// EthAddress returns the hex-encoded EVM address on the C-chain.
+func (k *Key) EthAddress() string { return k.ethAddr }
+
+// Sign signs the SHA-256 hash of the message, as expected by the X and P chain transactions.
+func (k *Key) Sign(msg []byte) ([]byte, error) { return k.PrivateKey.Sign(msg) }
+
+// SignHash signs the given hash.
+func (k *Key) SignHash(hash []byte) ([]byte, error) { return k.PrivateKey.SignHash(hash) }
+
+// String returns the "PrivateKey-" prefixed, CB58-encoded key.
+func (k *Key) String() string { return k.PrivateKey.String() }
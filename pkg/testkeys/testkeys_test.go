@@ -0,0 +1,46 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package testkeys
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestEwoqAddresses(t *testing.T) {
+	k := Ewoq()
+	for i, tv := range []struct {
+		got string
+		exp string
+	}{
+		{got: k.XAddress(), exp: "X-local18jma8ppw3nhx5r4ap8clazz0dps7rv5u00z96u"},
+		{got: k.PAddress(), exp: "P-local18jma8ppw3nhx5r4ap8clazz0dps7rv5u00z96u"},
+		{got: k.CAddress(), exp: "C-local18jma8ppw3nhx5r4ap8clazz0dps7rv5u00z96u"},
+		{got: k.ShortID().String(), exp: "6Y3kysjF9jnHnYkdS9yGAuoHyae2eNmeV"},
+		// lower-case hex of the checksummed address
+		{got: k.EthAddress(), exp: strings.ToLower("0x8db97C7cEcE249c2b98bDC0226Cc4C2A57BF52FC")},
+		{got: k.String(), exp: EwoqPrivateKey},
+	} {
+		if tv.got != tv.exp {
+			t.Fatalf("#%d: expected %q, got %q", i, tv.exp, tv.got)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	for i, tv := range []struct {
+		s   string
+		err error
+	}{
+		{s: EwoqPrivateKey},
+		{s: strings.TrimPrefix(EwoqPrivateKey, "PrivateKey-"), err: ErrInvalidPrivateKey},
+		{s: "PrivateKey-invalid", err: ErrInvalidPrivateKey},
+		{s: "PrivateKey-", err: ErrInvalidPrivateKey},
+	} {
+		if _, err := Parse(tv.s); !errors.Is(err, tv.err) {
+			t.Fatalf("#%d: expected %v, got %v", i, tv.err, err)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// Package traffic records the accepted txs of a chain of a network, and
+// replays them against a fresh network (started with the same genesis),
+// to benchmark a node version against a realistic workload.
+package traffic
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lasthyphen/djtx-tester/pkg/jsonrpc"
+)
+
+const (
+	// ChainX is the X-chain, recorded from its tx index
+	// (the nodes must run with "index-enabled").
+	ChainX = "X"
+	// ChainC is the C-chain, recorded from its blocks.
+	ChainC = "C"
+)
+
+const (
+	pollInterval = time.Second
+	// maximum containers per index request
+	indexPageSize = 1024
+	// time for a replayed X-chain tx to be accepted
+	acceptTimeout = time.Minute
+)
+
+var (
+	ErrInvalidChain    = errors.New("invalid chain (expected X or C)")
+	ErrTxNotAccepted   = errors.New("replayed tx not accepted")
+	ErrInvalidRecord   = errors.New("invalid record")
+	ErrNegativeSpeedup = errors.New("negative speedup")
+)
+
+// Tx is a recorded tx, written as one JSON object per line.
+type Tx struct {
+	Chain string `json:"chain"`
+	// signed tx, as returned by the node: checksummed hex for the X-chain,
+	// "0x"-prefixed RLP for the C-chain
+	Bytes string `json:"bytes"`
+	// acceptance time (of the block, for the C-chain)
+	Time time.Time `json:"time"`
+}
+
+// Record writes the accepted txs of the chain from the node at "uri"
+// (e.g., "http://127.0.0.1:9650") to "w", from the genesis on, and follows
+// the chain until the context is done. It returns the number of recorded txs,
+// and no error if the recording stopped on the context.
+func Record(ctx context.Context, uri string, chain string, w io.Writer) (int, error) {
+	var next func(context.Context, string, uint64) ([]Tx, uint64, error)
+	var from uint64
+	switch chain {
+	case ChainX:
+		next = nextXTxs
+	case ChainC:
+		// skipping the genesis block
+		next, from = nextCTxs, 1
+	default:
+		return 0, fmt.Errorf("%w: %q", ErrInvalidChain, chain)
+	}
+
+	enc := json.NewEncoder(w)
+	n := 0
+	for {
+		txs, nextFrom, err := next(ctx, uri, from)
+		if err != nil {
+			if ctx.Err() != nil {
+				return n, nil
+			}
+			return n, err
+		}
+		for _, tx := range txs {
+			if err := enc.Encode(tx); err != nil {
+				return n, err
+			}
+			n++
+		}
+		if nextFrom > from {
+			// more may be accepted already
+			from = nextFrom
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return n, nil
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// index container, ref. indexer "FormattedContainer"
+type container struct {
+	Bytes     string    `json:"bytes"`
+	Timestamp time.Time `json:"timestamp"`
+	Index     string    `json:"index"`
+}
+
+// nextXTxs returns the accepted X-chain txs from the index, and the next index.
+func nextXTxs(ctx context.Context, uri string, from uint64) ([]Tx, uint64, error) {
+	var last container
+	err := jsonrpc.Call(ctx, uri, "/ext/index/X/tx", "index.getLastAccepted", map[string]string{"encoding": "hex"}, &last)
+	var rerr *jsonrpc.Error
+	if errors.As(err, &rerr) {
+		// nothing accepted yet
+		return nil, from, nil
+	}
+	if err != nil {
+		return nil, from, fmt.Errorf("failed to query the X-chain tx index (is \"index-enabled\" set?): %w", err)
+	}
+	lastIndex, err := strconv.ParseUint(last.Index, 10, 64)
+	if err != nil {
+		return nil, from, err
+	}
+	if from > lastIndex {
+		return nil, from, nil
+	}
+
+	var reply struct {
+		Containers []container `json:"containers"`
+	}
+	if err := jsonrpc.Call(ctx, uri, "/ext/index/X/tx", "index.getContainerRange", map[string]interface{}{
+		"startIndex": from,
+		"numToFetch": indexPageSize,
+		"encoding":   "hex",
+	}, &reply); err != nil {
+		return nil, from, err
+	}
+	txs := make([]Tx, 0, len(reply.Containers))
+	for _, c := range reply.Containers {
+		txs = append(txs, Tx{Chain: ChainX, Bytes: c.Bytes, Time: c.Timestamp})
+	}
+	return txs, from + uint64(len(txs)), nil
+}
+
+// nextCTxs returns the txs of the next accepted C-chain block, and the next height.
+func nextCTxs(ctx context.Context, uri string, from uint64) ([]Tx, uint64, error) {
+	var tip string
+	if err := jsonrpc.Call(ctx, uri, "/ext/bc/C/rpc", "eth_blockNumber", []interface{}{}, &tip); err != nil {
+		return nil, from, err
+	}
+	height, err := strconv.ParseUint(strings.TrimPrefix(tip, "0x"), 16, 64)
+	if err != nil {
+		return nil, from, err
+	}
+	if from > height {
+		return nil, from, nil
+	}
+
+	var block struct {
+		Timestamp    string   `json:"timestamp"`
+		Transactions []string `json:"transactions"`
+	}
+	if err := jsonrpc.Call(ctx, uri, "/ext/bc/C/rpc", "eth_getBlockByNumber", []interface{}{fmt.Sprintf("0x%x", from), false}, &block); err != nil {
+		return nil, from, err
+	}
+	ts, err := strconv.ParseInt(strings.TrimPrefix(block.Timestamp, "0x"), 16, 64)
+	if err != nil {
+		return nil, from, err
+	}
+	txs := make([]Tx, 0, len(block.Transactions))
+	for _, hash := range block.Transactions {
+		var raw string
+		if err := jsonrpc.Call(ctx, uri, "/ext/bc/C/rpc", "eth_getRawTransactionByHash", []interface{}{hash}, &raw); err != nil {
+			return nil, from, err
+		}
+		txs = append(txs, Tx{Chain: ChainC, Bytes: raw, Time: time.Unix(ts, 0).UTC()})
+	}
+	return txs, from + 1, nil
+}
+
+// Replay issues the recorded txs read from "r" to the node at "uri", in order.
+// The txs are paced as recorded, accelerated by "speedup" (e.g., 2 for twice as
+// fast), or issued as fast as possible if it is zero. Each X-chain tx is
+// accepted before the next tx is issued, as it may spend the outputs of the
+// previous one. It returns the number of replayed txs.
+func Replay(ctx context.Context, uri string, r io.Reader, speedup float64) (int, error) {
+	if speedup < 0 {
+		return 0, ErrNegativeSpeedup
+	}
+	sc := bufio.NewScanner(r)
+	// large C-chain txs (e.g., contract deployments)
+	sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	var first time.Time
+	start := time.Now()
+	n := 0
+	for line := 1; sc.Scan(); line++ {
+		if len(strings.TrimSpace(sc.Text())) == 0 {
+			continue
+		}
+		var tx Tx
+		if err := json.Unmarshal(sc.Bytes(), &tx); err != nil {
+			return n, fmt.Errorf("%w: line %d: %v", ErrInvalidRecord, line, err)
+		}
+		if n == 0 {
+			first = tx.Time
+		}
+		if speedup > 0 {
+			at := start.Add(time.Duration(float64(tx.Time.Sub(first)) / speedup))
+			select {
+			case <-ctx.Done():
+				return n, ctx.Err()
+			case <-time.After(time.Until(at)):
+			}
+		}
+		if err := issue(ctx, uri, tx); err != nil {
+			return n, fmt.Errorf("failed to replay line %d: %w", line, err)
+		}
+		n++
+	}
+	return n, sc.Err()
+}
+
+func issue(ctx context.Context, uri string, tx Tx) error {
+	switch tx.Chain {
+	case ChainX:
+		// ref. avm "IssueTx"
+		var reply struct {
+			TxID string `json:"txID"`
+		}
+		if err := jsonrpc.Call(ctx, uri, "/ext/bc/X", "avm.issueTx", map[string]string{"tx": tx.Bytes, "encoding": "hex"}, &reply); err != nil {
+			return err
+		}
+		return waitForXTx(ctx, uri, reply.TxID)
+	case ChainC:
+		return jsonrpc.Call(ctx, uri, "/ext/bc/C/rpc", "eth_sendRawTransaction", []interface{}{tx.Bytes}, nil)
+	}
+	return fmt.Errorf("%w: %q", ErrInvalidChain, tx.Chain)
+}
+
+func waitForXTx(ctx context.Context, uri string, txID string) error {
+	ctx, cancel := context.WithTimeout(ctx, acceptTimeout)
+	defer cancel()
+	for {
+		// ref. avm "GetTxStatus"
+		var reply struct {
+			Status string `json:"status"`
+		}
+		if err := jsonrpc.Call(ctx, uri, "/ext/bc/X", "avm.getTxStatus", map[string]string{"txID": txID}, &reply); err != nil {
+			return err
+		}
+		switch reply.Status {
+		case "Accepted":
+			return nil
+		case "Rejected":
+			return fmt.Errorf("%w: %q rejected", ErrTxNotAccepted, txID)
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w: %q %s: %v", ErrTxNotAccepted, txID, reply.Status, ctx.Err())
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
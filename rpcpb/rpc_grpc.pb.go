@@ -110,12 +110,44 @@ var PingService_ServiceDesc = grpc.ServiceDesc{
 type ControlServiceClient interface {
 	Start(ctx context.Context, in *StartRequest, opts ...grpc.CallOption) (*StartResponse, error)
 	Health(ctx context.Context, in *HealthRequest, opts ...grpc.CallOption) (*HealthResponse, error)
+	WaitNodesHealthy(ctx context.Context, in *WaitNodesHealthyRequest, opts ...grpc.CallOption) (*WaitNodesHealthyResponse, error)
 	URIs(ctx context.Context, in *URIsRequest, opts ...grpc.CallOption) (*URIsResponse, error)
 	Status(ctx context.Context, in *StatusRequest, opts ...grpc.CallOption) (*StatusResponse, error)
 	StreamStatus(ctx context.Context, in *StreamStatusRequest, opts ...grpc.CallOption) (ControlService_StreamStatusClient, error)
 	RemoveNode(ctx context.Context, in *RemoveNodeRequest, opts ...grpc.CallOption) (*RemoveNodeResponse, error)
 	RestartNode(ctx context.Context, in *RestartNodeRequest, opts ...grpc.CallOption) (*RestartNodeResponse, error)
+	RemoveNodes(ctx context.Context, in *RemoveNodesRequest, opts ...grpc.CallOption) (*RemoveNodesResponse, error)
+	RestartNodes(ctx context.Context, in *RestartNodesRequest, opts ...grpc.CallOption) (*RestartNodesResponse, error)
 	Stop(ctx context.Context, in *StopRequest, opts ...grpc.CallOption) (*StopResponse, error)
+	AbortStart(ctx context.Context, in *AbortStartRequest, opts ...grpc.CallOption) (*AbortStartResponse, error)
+	CheckBinary(ctx context.Context, in *CheckBinaryRequest, opts ...grpc.CallOption) (*CheckBinaryResponse, error)
+	CreateChainIPC(ctx context.Context, in *CreateChainIPCRequest, opts ...grpc.CallOption) (*CreateChainIPCResponse, error)
+	ListChainIPCs(ctx context.Context, in *ListChainIPCsRequest, opts ...grpc.CallOption) (*ListChainIPCsResponse, error)
+	TailChainIPC(ctx context.Context, in *TailChainIPCRequest, opts ...grpc.CallOption) (ControlService_TailChainIPCClient, error)
+	RemoveChainIPC(ctx context.Context, in *RemoveChainIPCRequest, opts ...grpc.CallOption) (*RemoveChainIPCResponse, error)
+	AttachConsole(ctx context.Context, opts ...grpc.CallOption) (ControlService_AttachConsoleClient, error)
+	GetValidators(ctx context.Context, in *GetValidatorsRequest, opts ...grpc.CallOption) (*GetValidatorsResponse, error)
+	InjectFault(ctx context.Context, in *InjectFaultRequest, opts ...grpc.CallOption) (*InjectFaultResponse, error)
+	SetDiskFault(ctx context.Context, in *SetDiskFaultRequest, opts ...grpc.CallOption) (*SetDiskFaultResponse, error)
+	SetTime(ctx context.Context, in *SetTimeRequest, opts ...grpc.CallOption) (*SetTimeResponse, error)
+	AdvanceTime(ctx context.Context, in *AdvanceTimeRequest, opts ...grpc.CallOption) (*AdvanceTimeResponse, error)
+	RenderTopology(ctx context.Context, in *RenderTopologyRequest, opts ...grpc.CallOption) (*RenderTopologyResponse, error)
+	CreateBlockchain(ctx context.Context, in *CreateBlockchainRequest, opts ...grpc.CallOption) (*CreateBlockchainResponse, error)
+	BlockTraffic(ctx context.Context, in *BlockTrafficRequest, opts ...grpc.CallOption) (*BlockTrafficResponse, error)
+	UnblockTraffic(ctx context.Context, in *UnblockTrafficRequest, opts ...grpc.CallOption) (*UnblockTrafficResponse, error)
+	UploadFile(ctx context.Context, opts ...grpc.CallOption) (ControlService_UploadFileClient, error)
+	GetNodeConfig(ctx context.Context, in *GetNodeConfigRequest, opts ...grpc.CallOption) (*GetNodeConfigResponse, error)
+	RotateNodeKey(ctx context.Context, in *RotateNodeKeyRequest, opts ...grpc.CallOption) (*RotateNodeKeyResponse, error)
+	AddNode(ctx context.Context, in *AddNodeRequest, opts ...grpc.CallOption) (*AddNodeResponse, error)
+	EstimateResources(ctx context.Context, in *EstimateResourcesRequest, opts ...grpc.CallOption) (*EstimateResourcesResponse, error)
+	SearchLogs(ctx context.Context, in *SearchLogsRequest, opts ...grpc.CallOption) (*SearchLogsResponse, error)
+	GetSyncProgress(ctx context.Context, in *GetSyncProgressRequest, opts ...grpc.CallOption) (*GetSyncProgressResponse, error)
+	GCNow(ctx context.Context, in *GCNowRequest, opts ...grpc.CallOption) (*GCNowResponse, error)
+	DownloadLogs(ctx context.Context, in *DownloadLogsRequest, opts ...grpc.CallOption) (ControlService_DownloadLogsClient, error)
+	GetRunManifest(ctx context.Context, in *GetRunManifestRequest, opts ...grpc.CallOption) (*GetRunManifestResponse, error)
+	StopValidatingSubnet(ctx context.Context, in *StopValidatingSubnetRequest, opts ...grpc.CallOption) (*StopValidatingSubnetResponse, error)
+	GetGenesis(ctx context.Context, in *GetGenesisRequest, opts ...grpc.CallOption) (*GetGenesisResponse, error)
+	GetNetworkParams(ctx context.Context, in *GetNetworkParamsRequest, opts ...grpc.CallOption) (*GetNetworkParamsResponse, error)
 }
 
 type controlServiceClient struct {
@@ -144,6 +176,15 @@ func (c *controlServiceClient) Health(ctx context.Context, in *HealthRequest, op
 	return out, nil
 }
 
+func (c *controlServiceClient) WaitNodesHealthy(ctx context.Context, in *WaitNodesHealthyRequest, opts ...grpc.CallOption) (*WaitNodesHealthyResponse, error) {
+	out := new(WaitNodesHealthyResponse)
+	err := c.cc.Invoke(ctx, "/rpcpb.ControlService/WaitNodesHealthy", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *controlServiceClient) URIs(ctx context.Context, in *URIsRequest, opts ...grpc.CallOption) (*URIsResponse, error) {
 	out := new(URIsResponse)
 	err := c.cc.Invoke(ctx, "/rpcpb.ControlService/URIs", in, out, opts...)
@@ -159,267 +200,1365 @@ func (c *controlServiceClient) Status(ctx context.Context, in *StatusRequest, op
 	if err != nil {
 		return nil, err
 	}
-	return out, nil
+	return out, nil
+}
+
+func (c *controlServiceClient) StreamStatus(ctx context.Context, in *StreamStatusRequest, opts ...grpc.CallOption) (ControlService_StreamStatusClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ControlService_ServiceDesc.Streams[0], "/rpcpb.ControlService/StreamStatus", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &controlServiceStreamStatusClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ControlService_StreamStatusClient interface {
+	Recv() (*StreamStatusResponse, error)
+	grpc.ClientStream
+}
+
+type controlServiceStreamStatusClient struct {
+	grpc.ClientStream
+}
+
+func (x *controlServiceStreamStatusClient) Recv() (*StreamStatusResponse, error) {
+	m := new(StreamStatusResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *controlServiceClient) RemoveNode(ctx context.Context, in *RemoveNodeRequest, opts ...grpc.CallOption) (*RemoveNodeResponse, error) {
+	out := new(RemoveNodeResponse)
+	err := c.cc.Invoke(ctx, "/rpcpb.ControlService/RemoveNode", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlServiceClient) RestartNode(ctx context.Context, in *RestartNodeRequest, opts ...grpc.CallOption) (*RestartNodeResponse, error) {
+	out := new(RestartNodeResponse)
+	err := c.cc.Invoke(ctx, "/rpcpb.ControlService/RestartNode", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlServiceClient) RemoveNodes(ctx context.Context, in *RemoveNodesRequest, opts ...grpc.CallOption) (*RemoveNodesResponse, error) {
+	out := new(RemoveNodesResponse)
+	err := c.cc.Invoke(ctx, "/rpcpb.ControlService/RemoveNodes", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlServiceClient) RestartNodes(ctx context.Context, in *RestartNodesRequest, opts ...grpc.CallOption) (*RestartNodesResponse, error) {
+	out := new(RestartNodesResponse)
+	err := c.cc.Invoke(ctx, "/rpcpb.ControlService/RestartNodes", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlServiceClient) Stop(ctx context.Context, in *StopRequest, opts ...grpc.CallOption) (*StopResponse, error) {
+	out := new(StopResponse)
+	err := c.cc.Invoke(ctx, "/rpcpb.ControlService/Stop", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlServiceClient) AbortStart(ctx context.Context, in *AbortStartRequest, opts ...grpc.CallOption) (*AbortStartResponse, error) {
+	out := new(AbortStartResponse)
+	err := c.cc.Invoke(ctx, "/rpcpb.ControlService/AbortStart", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlServiceClient) CheckBinary(ctx context.Context, in *CheckBinaryRequest, opts ...grpc.CallOption) (*CheckBinaryResponse, error) {
+	out := new(CheckBinaryResponse)
+	err := c.cc.Invoke(ctx, "/rpcpb.ControlService/CheckBinary", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlServiceClient) CreateChainIPC(ctx context.Context, in *CreateChainIPCRequest, opts ...grpc.CallOption) (*CreateChainIPCResponse, error) {
+	out := new(CreateChainIPCResponse)
+	err := c.cc.Invoke(ctx, "/rpcpb.ControlService/CreateChainIPC", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlServiceClient) ListChainIPCs(ctx context.Context, in *ListChainIPCsRequest, opts ...grpc.CallOption) (*ListChainIPCsResponse, error) {
+	out := new(ListChainIPCsResponse)
+	err := c.cc.Invoke(ctx, "/rpcpb.ControlService/ListChainIPCs", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlServiceClient) TailChainIPC(ctx context.Context, in *TailChainIPCRequest, opts ...grpc.CallOption) (ControlService_TailChainIPCClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ControlService_ServiceDesc.Streams[1], "/rpcpb.ControlService/TailChainIPC", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &controlServiceTailChainIPCClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ControlService_TailChainIPCClient interface {
+	Recv() (*TailChainIPCResponse, error)
+	grpc.ClientStream
+}
+
+type controlServiceTailChainIPCClient struct {
+	grpc.ClientStream
+}
+
+func (x *controlServiceTailChainIPCClient) Recv() (*TailChainIPCResponse, error) {
+	m := new(TailChainIPCResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *controlServiceClient) RemoveChainIPC(ctx context.Context, in *RemoveChainIPCRequest, opts ...grpc.CallOption) (*RemoveChainIPCResponse, error) {
+	out := new(RemoveChainIPCResponse)
+	err := c.cc.Invoke(ctx, "/rpcpb.ControlService/RemoveChainIPC", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlServiceClient) AttachConsole(ctx context.Context, opts ...grpc.CallOption) (ControlService_AttachConsoleClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ControlService_ServiceDesc.Streams[2], "/rpcpb.ControlService/AttachConsole", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &controlServiceAttachConsoleClient{stream}
+	return x, nil
+}
+
+type ControlService_AttachConsoleClient interface {
+	Send(*AttachConsoleRequest) error
+	Recv() (*AttachConsoleResponse, error)
+	grpc.ClientStream
+}
+
+type controlServiceAttachConsoleClient struct {
+	grpc.ClientStream
+}
+
+func (x *controlServiceAttachConsoleClient) Send(m *AttachConsoleRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *controlServiceAttachConsoleClient) Recv() (*AttachConsoleResponse, error) {
+	m := new(AttachConsoleResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *controlServiceClient) GetValidators(ctx context.Context, in *GetValidatorsRequest, opts ...grpc.CallOption) (*GetValidatorsResponse, error) {
+	out := new(GetValidatorsResponse)
+	err := c.cc.Invoke(ctx, "/rpcpb.ControlService/GetValidators", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlServiceClient) InjectFault(ctx context.Context, in *InjectFaultRequest, opts ...grpc.CallOption) (*InjectFaultResponse, error) {
+	out := new(InjectFaultResponse)
+	err := c.cc.Invoke(ctx, "/rpcpb.ControlService/InjectFault", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlServiceClient) SetDiskFault(ctx context.Context, in *SetDiskFaultRequest, opts ...grpc.CallOption) (*SetDiskFaultResponse, error) {
+	out := new(SetDiskFaultResponse)
+	err := c.cc.Invoke(ctx, "/rpcpb.ControlService/SetDiskFault", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlServiceClient) SetTime(ctx context.Context, in *SetTimeRequest, opts ...grpc.CallOption) (*SetTimeResponse, error) {
+	out := new(SetTimeResponse)
+	err := c.cc.Invoke(ctx, "/rpcpb.ControlService/SetTime", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlServiceClient) AdvanceTime(ctx context.Context, in *AdvanceTimeRequest, opts ...grpc.CallOption) (*AdvanceTimeResponse, error) {
+	out := new(AdvanceTimeResponse)
+	err := c.cc.Invoke(ctx, "/rpcpb.ControlService/AdvanceTime", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlServiceClient) RenderTopology(ctx context.Context, in *RenderTopologyRequest, opts ...grpc.CallOption) (*RenderTopologyResponse, error) {
+	out := new(RenderTopologyResponse)
+	err := c.cc.Invoke(ctx, "/rpcpb.ControlService/RenderTopology", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlServiceClient) CreateBlockchain(ctx context.Context, in *CreateBlockchainRequest, opts ...grpc.CallOption) (*CreateBlockchainResponse, error) {
+	out := new(CreateBlockchainResponse)
+	err := c.cc.Invoke(ctx, "/rpcpb.ControlService/CreateBlockchain", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlServiceClient) BlockTraffic(ctx context.Context, in *BlockTrafficRequest, opts ...grpc.CallOption) (*BlockTrafficResponse, error) {
+	out := new(BlockTrafficResponse)
+	err := c.cc.Invoke(ctx, "/rpcpb.ControlService/BlockTraffic", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlServiceClient) UnblockTraffic(ctx context.Context, in *UnblockTrafficRequest, opts ...grpc.CallOption) (*UnblockTrafficResponse, error) {
+	out := new(UnblockTrafficResponse)
+	err := c.cc.Invoke(ctx, "/rpcpb.ControlService/UnblockTraffic", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlServiceClient) UploadFile(ctx context.Context, opts ...grpc.CallOption) (ControlService_UploadFileClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ControlService_ServiceDesc.Streams[3], "/rpcpb.ControlService/UploadFile", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &controlServiceUploadFileClient{stream}
+	return x, nil
+}
+
+type ControlService_UploadFileClient interface {
+	Send(*UploadFileRequest) error
+	CloseAndRecv() (*UploadFileResponse, error)
+	grpc.ClientStream
+}
+
+type controlServiceUploadFileClient struct {
+	grpc.ClientStream
+}
+
+func (x *controlServiceUploadFileClient) Send(m *UploadFileRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *controlServiceUploadFileClient) CloseAndRecv() (*UploadFileResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(UploadFileResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *controlServiceClient) GetNodeConfig(ctx context.Context, in *GetNodeConfigRequest, opts ...grpc.CallOption) (*GetNodeConfigResponse, error) {
+	out := new(GetNodeConfigResponse)
+	err := c.cc.Invoke(ctx, "/rpcpb.ControlService/GetNodeConfig", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlServiceClient) RotateNodeKey(ctx context.Context, in *RotateNodeKeyRequest, opts ...grpc.CallOption) (*RotateNodeKeyResponse, error) {
+	out := new(RotateNodeKeyResponse)
+	err := c.cc.Invoke(ctx, "/rpcpb.ControlService/RotateNodeKey", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlServiceClient) AddNode(ctx context.Context, in *AddNodeRequest, opts ...grpc.CallOption) (*AddNodeResponse, error) {
+	out := new(AddNodeResponse)
+	err := c.cc.Invoke(ctx, "/rpcpb.ControlService/AddNode", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlServiceClient) EstimateResources(ctx context.Context, in *EstimateResourcesRequest, opts ...grpc.CallOption) (*EstimateResourcesResponse, error) {
+	out := new(EstimateResourcesResponse)
+	err := c.cc.Invoke(ctx, "/rpcpb.ControlService/EstimateResources", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlServiceClient) SearchLogs(ctx context.Context, in *SearchLogsRequest, opts ...grpc.CallOption) (*SearchLogsResponse, error) {
+	out := new(SearchLogsResponse)
+	err := c.cc.Invoke(ctx, "/rpcpb.ControlService/SearchLogs", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlServiceClient) GetSyncProgress(ctx context.Context, in *GetSyncProgressRequest, opts ...grpc.CallOption) (*GetSyncProgressResponse, error) {
+	out := new(GetSyncProgressResponse)
+	err := c.cc.Invoke(ctx, "/rpcpb.ControlService/GetSyncProgress", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlServiceClient) GCNow(ctx context.Context, in *GCNowRequest, opts ...grpc.CallOption) (*GCNowResponse, error) {
+	out := new(GCNowResponse)
+	err := c.cc.Invoke(ctx, "/rpcpb.ControlService/GCNow", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlServiceClient) DownloadLogs(ctx context.Context, in *DownloadLogsRequest, opts ...grpc.CallOption) (ControlService_DownloadLogsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ControlService_ServiceDesc.Streams[4], "/rpcpb.ControlService/DownloadLogs", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &controlServiceDownloadLogsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ControlService_DownloadLogsClient interface {
+	Recv() (*DownloadLogsResponse, error)
+	grpc.ClientStream
+}
+
+type controlServiceDownloadLogsClient struct {
+	grpc.ClientStream
+}
+
+func (x *controlServiceDownloadLogsClient) Recv() (*DownloadLogsResponse, error) {
+	m := new(DownloadLogsResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *controlServiceClient) GetRunManifest(ctx context.Context, in *GetRunManifestRequest, opts ...grpc.CallOption) (*GetRunManifestResponse, error) {
+	out := new(GetRunManifestResponse)
+	err := c.cc.Invoke(ctx, "/rpcpb.ControlService/GetRunManifest", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlServiceClient) StopValidatingSubnet(ctx context.Context, in *StopValidatingSubnetRequest, opts ...grpc.CallOption) (*StopValidatingSubnetResponse, error) {
+	out := new(StopValidatingSubnetResponse)
+	err := c.cc.Invoke(ctx, "/rpcpb.ControlService/StopValidatingSubnet", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlServiceClient) GetGenesis(ctx context.Context, in *GetGenesisRequest, opts ...grpc.CallOption) (*GetGenesisResponse, error) {
+	out := new(GetGenesisResponse)
+	err := c.cc.Invoke(ctx, "/rpcpb.ControlService/GetGenesis", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlServiceClient) GetNetworkParams(ctx context.Context, in *GetNetworkParamsRequest, opts ...grpc.CallOption) (*GetNetworkParamsResponse, error) {
+	out := new(GetNetworkParamsResponse)
+	err := c.cc.Invoke(ctx, "/rpcpb.ControlService/GetNetworkParams", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ControlServiceServer is the server API for ControlService service.
+// All implementations must embed UnimplementedControlServiceServer
+// for forward compatibility
+type ControlServiceServer interface {
+	Start(context.Context, *StartRequest) (*StartResponse, error)
+	Health(context.Context, *HealthRequest) (*HealthResponse, error)
+	WaitNodesHealthy(context.Context, *WaitNodesHealthyRequest) (*WaitNodesHealthyResponse, error)
+	URIs(context.Context, *URIsRequest) (*URIsResponse, error)
+	Status(context.Context, *StatusRequest) (*StatusResponse, error)
+	StreamStatus(*StreamStatusRequest, ControlService_StreamStatusServer) error
+	RemoveNode(context.Context, *RemoveNodeRequest) (*RemoveNodeResponse, error)
+	RestartNode(context.Context, *RestartNodeRequest) (*RestartNodeResponse, error)
+	RemoveNodes(context.Context, *RemoveNodesRequest) (*RemoveNodesResponse, error)
+	RestartNodes(context.Context, *RestartNodesRequest) (*RestartNodesResponse, error)
+	Stop(context.Context, *StopRequest) (*StopResponse, error)
+	AbortStart(context.Context, *AbortStartRequest) (*AbortStartResponse, error)
+	CheckBinary(context.Context, *CheckBinaryRequest) (*CheckBinaryResponse, error)
+	CreateChainIPC(context.Context, *CreateChainIPCRequest) (*CreateChainIPCResponse, error)
+	ListChainIPCs(context.Context, *ListChainIPCsRequest) (*ListChainIPCsResponse, error)
+	TailChainIPC(*TailChainIPCRequest, ControlService_TailChainIPCServer) error
+	RemoveChainIPC(context.Context, *RemoveChainIPCRequest) (*RemoveChainIPCResponse, error)
+	AttachConsole(ControlService_AttachConsoleServer) error
+	GetValidators(context.Context, *GetValidatorsRequest) (*GetValidatorsResponse, error)
+	InjectFault(context.Context, *InjectFaultRequest) (*InjectFaultResponse, error)
+	SetDiskFault(context.Context, *SetDiskFaultRequest) (*SetDiskFaultResponse, error)
+	SetTime(context.Context, *SetTimeRequest) (*SetTimeResponse, error)
+	AdvanceTime(context.Context, *AdvanceTimeRequest) (*AdvanceTimeResponse, error)
+	RenderTopology(context.Context, *RenderTopologyRequest) (*RenderTopologyResponse, error)
+	CreateBlockchain(context.Context, *CreateBlockchainRequest) (*CreateBlockchainResponse, error)
+	BlockTraffic(context.Context, *BlockTrafficRequest) (*BlockTrafficResponse, error)
+	UnblockTraffic(context.Context, *UnblockTrafficRequest) (*UnblockTrafficResponse, error)
+	UploadFile(ControlService_UploadFileServer) error
+	GetNodeConfig(context.Context, *GetNodeConfigRequest) (*GetNodeConfigResponse, error)
+	RotateNodeKey(context.Context, *RotateNodeKeyRequest) (*RotateNodeKeyResponse, error)
+	AddNode(context.Context, *AddNodeRequest) (*AddNodeResponse, error)
+	EstimateResources(context.Context, *EstimateResourcesRequest) (*EstimateResourcesResponse, error)
+	SearchLogs(context.Context, *SearchLogsRequest) (*SearchLogsResponse, error)
+	GetSyncProgress(context.Context, *GetSyncProgressRequest) (*GetSyncProgressResponse, error)
+	GCNow(context.Context, *GCNowRequest) (*GCNowResponse, error)
+	DownloadLogs(*DownloadLogsRequest, ControlService_DownloadLogsServer) error
+	GetRunManifest(context.Context, *GetRunManifestRequest) (*GetRunManifestResponse, error)
+	StopValidatingSubnet(context.Context, *StopValidatingSubnetRequest) (*StopValidatingSubnetResponse, error)
+	GetGenesis(context.Context, *GetGenesisRequest) (*GetGenesisResponse, error)
+	GetNetworkParams(context.Context, *GetNetworkParamsRequest) (*GetNetworkParamsResponse, error)
+	mustEmbedUnimplementedControlServiceServer()
+}
+
+// UnimplementedControlServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedControlServiceServer struct {
+}
+
+func (UnimplementedControlServiceServer) Start(context.Context, *StartRequest) (*StartResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Start not implemented")
+}
+func (UnimplementedControlServiceServer) Health(context.Context, *HealthRequest) (*HealthResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Health not implemented")
+}
+func (UnimplementedControlServiceServer) WaitNodesHealthy(context.Context, *WaitNodesHealthyRequest) (*WaitNodesHealthyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method WaitNodesHealthy not implemented")
+}
+func (UnimplementedControlServiceServer) URIs(context.Context, *URIsRequest) (*URIsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method URIs not implemented")
+}
+func (UnimplementedControlServiceServer) Status(context.Context, *StatusRequest) (*StatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Status not implemented")
+}
+func (UnimplementedControlServiceServer) StreamStatus(*StreamStatusRequest, ControlService_StreamStatusServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamStatus not implemented")
+}
+func (UnimplementedControlServiceServer) RemoveNode(context.Context, *RemoveNodeRequest) (*RemoveNodeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveNode not implemented")
+}
+func (UnimplementedControlServiceServer) RestartNode(context.Context, *RestartNodeRequest) (*RestartNodeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RestartNode not implemented")
+}
+func (UnimplementedControlServiceServer) RemoveNodes(context.Context, *RemoveNodesRequest) (*RemoveNodesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveNodes not implemented")
+}
+func (UnimplementedControlServiceServer) RestartNodes(context.Context, *RestartNodesRequest) (*RestartNodesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RestartNodes not implemented")
+}
+func (UnimplementedControlServiceServer) Stop(context.Context, *StopRequest) (*StopResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Stop not implemented")
+}
+func (UnimplementedControlServiceServer) AbortStart(context.Context, *AbortStartRequest) (*AbortStartResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AbortStart not implemented")
+}
+func (UnimplementedControlServiceServer) CheckBinary(context.Context, *CheckBinaryRequest) (*CheckBinaryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CheckBinary not implemented")
+}
+func (UnimplementedControlServiceServer) CreateChainIPC(context.Context, *CreateChainIPCRequest) (*CreateChainIPCResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateChainIPC not implemented")
+}
+func (UnimplementedControlServiceServer) ListChainIPCs(context.Context, *ListChainIPCsRequest) (*ListChainIPCsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListChainIPCs not implemented")
+}
+func (UnimplementedControlServiceServer) TailChainIPC(*TailChainIPCRequest, ControlService_TailChainIPCServer) error {
+	return status.Errorf(codes.Unimplemented, "method TailChainIPC not implemented")
+}
+func (UnimplementedControlServiceServer) RemoveChainIPC(context.Context, *RemoveChainIPCRequest) (*RemoveChainIPCResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveChainIPC not implemented")
+}
+func (UnimplementedControlServiceServer) AttachConsole(ControlService_AttachConsoleServer) error {
+	return status.Errorf(codes.Unimplemented, "method AttachConsole not implemented")
+}
+func (UnimplementedControlServiceServer) GetValidators(context.Context, *GetValidatorsRequest) (*GetValidatorsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetValidators not implemented")
+}
+func (UnimplementedControlServiceServer) InjectFault(context.Context, *InjectFaultRequest) (*InjectFaultResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method InjectFault not implemented")
+}
+func (UnimplementedControlServiceServer) SetDiskFault(context.Context, *SetDiskFaultRequest) (*SetDiskFaultResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetDiskFault not implemented")
+}
+func (UnimplementedControlServiceServer) SetTime(context.Context, *SetTimeRequest) (*SetTimeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetTime not implemented")
+}
+func (UnimplementedControlServiceServer) AdvanceTime(context.Context, *AdvanceTimeRequest) (*AdvanceTimeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AdvanceTime not implemented")
+}
+func (UnimplementedControlServiceServer) RenderTopology(context.Context, *RenderTopologyRequest) (*RenderTopologyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RenderTopology not implemented")
+}
+func (UnimplementedControlServiceServer) CreateBlockchain(context.Context, *CreateBlockchainRequest) (*CreateBlockchainResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateBlockchain not implemented")
+}
+func (UnimplementedControlServiceServer) BlockTraffic(context.Context, *BlockTrafficRequest) (*BlockTrafficResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BlockTraffic not implemented")
+}
+func (UnimplementedControlServiceServer) UnblockTraffic(context.Context, *UnblockTrafficRequest) (*UnblockTrafficResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UnblockTraffic not implemented")
+}
+func (UnimplementedControlServiceServer) UploadFile(ControlService_UploadFileServer) error {
+	return status.Errorf(codes.Unimplemented, "method UploadFile not implemented")
+}
+func (UnimplementedControlServiceServer) GetNodeConfig(context.Context, *GetNodeConfigRequest) (*GetNodeConfigResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetNodeConfig not implemented")
+}
+func (UnimplementedControlServiceServer) RotateNodeKey(context.Context, *RotateNodeKeyRequest) (*RotateNodeKeyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RotateNodeKey not implemented")
+}
+func (UnimplementedControlServiceServer) AddNode(context.Context, *AddNodeRequest) (*AddNodeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddNode not implemented")
+}
+func (UnimplementedControlServiceServer) EstimateResources(context.Context, *EstimateResourcesRequest) (*EstimateResourcesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method EstimateResources not implemented")
+}
+func (UnimplementedControlServiceServer) SearchLogs(context.Context, *SearchLogsRequest) (*SearchLogsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchLogs not implemented")
+}
+func (UnimplementedControlServiceServer) GetSyncProgress(context.Context, *GetSyncProgressRequest) (*GetSyncProgressResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSyncProgress not implemented")
+}
+func (UnimplementedControlServiceServer) GCNow(context.Context, *GCNowRequest) (*GCNowResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GCNow not implemented")
+}
+func (UnimplementedControlServiceServer) DownloadLogs(*DownloadLogsRequest, ControlService_DownloadLogsServer) error {
+	return status.Errorf(codes.Unimplemented, "method DownloadLogs not implemented")
+}
+func (UnimplementedControlServiceServer) GetRunManifest(context.Context, *GetRunManifestRequest) (*GetRunManifestResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRunManifest not implemented")
+}
+func (UnimplementedControlServiceServer) StopValidatingSubnet(context.Context, *StopValidatingSubnetRequest) (*StopValidatingSubnetResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StopValidatingSubnet not implemented")
+}
+func (UnimplementedControlServiceServer) GetGenesis(context.Context, *GetGenesisRequest) (*GetGenesisResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetGenesis not implemented")
+}
+func (UnimplementedControlServiceServer) GetNetworkParams(context.Context, *GetNetworkParamsRequest) (*GetNetworkParamsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetNetworkParams not implemented")
+}
+func (UnimplementedControlServiceServer) mustEmbedUnimplementedControlServiceServer() {}
+
+// UnsafeControlServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ControlServiceServer will
+// result in compilation errors.
+type UnsafeControlServiceServer interface {
+	mustEmbedUnimplementedControlServiceServer()
+}
+
+func RegisterControlServiceServer(s grpc.ServiceRegistrar, srv ControlServiceServer) {
+	s.RegisterService(&ControlService_ServiceDesc, srv)
+}
+
+func _ControlService_Start_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StartRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServiceServer).Start(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpcpb.ControlService/Start",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServiceServer).Start(ctx, req.(*StartRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ControlService_Health_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HealthRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServiceServer).Health(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpcpb.ControlService/Health",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServiceServer).Health(ctx, req.(*HealthRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ControlService_WaitNodesHealthy_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WaitNodesHealthyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServiceServer).WaitNodesHealthy(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpcpb.ControlService/WaitNodesHealthy",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServiceServer).WaitNodesHealthy(ctx, req.(*WaitNodesHealthyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ControlService_URIs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(URIsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServiceServer).URIs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpcpb.ControlService/URIs",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServiceServer).URIs(ctx, req.(*URIsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ControlService_Status_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServiceServer).Status(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpcpb.ControlService/Status",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServiceServer).Status(ctx, req.(*StatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ControlService_StreamStatus_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamStatusRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ControlServiceServer).StreamStatus(m, &controlServiceStreamStatusServer{stream})
+}
+
+type ControlService_StreamStatusServer interface {
+	Send(*StreamStatusResponse) error
+	grpc.ServerStream
+}
+
+type controlServiceStreamStatusServer struct {
+	grpc.ServerStream
+}
+
+func (x *controlServiceStreamStatusServer) Send(m *StreamStatusResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _ControlService_RemoveNode_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemoveNodeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServiceServer).RemoveNode(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpcpb.ControlService/RemoveNode",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServiceServer).RemoveNode(ctx, req.(*RemoveNodeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ControlService_RestartNode_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RestartNodeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServiceServer).RestartNode(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpcpb.ControlService/RestartNode",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServiceServer).RestartNode(ctx, req.(*RestartNodeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ControlService_RemoveNodes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemoveNodesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServiceServer).RemoveNodes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpcpb.ControlService/RemoveNodes",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServiceServer).RemoveNodes(ctx, req.(*RemoveNodesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ControlService_RestartNodes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RestartNodesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServiceServer).RestartNodes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpcpb.ControlService/RestartNodes",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServiceServer).RestartNodes(ctx, req.(*RestartNodesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ControlService_Stop_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StopRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServiceServer).Stop(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpcpb.ControlService/Stop",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServiceServer).Stop(ctx, req.(*StopRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ControlService_AbortStart_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AbortStartRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServiceServer).AbortStart(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpcpb.ControlService/AbortStart",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServiceServer).AbortStart(ctx, req.(*AbortStartRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ControlService_CheckBinary_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CheckBinaryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServiceServer).CheckBinary(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpcpb.ControlService/CheckBinary",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServiceServer).CheckBinary(ctx, req.(*CheckBinaryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ControlService_CreateChainIPC_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateChainIPCRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServiceServer).CreateChainIPC(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpcpb.ControlService/CreateChainIPC",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServiceServer).CreateChainIPC(ctx, req.(*CreateChainIPCRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ControlService_ListChainIPCs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListChainIPCsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServiceServer).ListChainIPCs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpcpb.ControlService/ListChainIPCs",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServiceServer).ListChainIPCs(ctx, req.(*ListChainIPCsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ControlService_TailChainIPC_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(TailChainIPCRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ControlServiceServer).TailChainIPC(m, &controlServiceTailChainIPCServer{stream})
+}
+
+type ControlService_TailChainIPCServer interface {
+	Send(*TailChainIPCResponse) error
+	grpc.ServerStream
+}
+
+type controlServiceTailChainIPCServer struct {
+	grpc.ServerStream
+}
+
+func (x *controlServiceTailChainIPCServer) Send(m *TailChainIPCResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _ControlService_RemoveChainIPC_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemoveChainIPCRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServiceServer).RemoveChainIPC(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpcpb.ControlService/RemoveChainIPC",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServiceServer).RemoveChainIPC(ctx, req.(*RemoveChainIPCRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ControlService_AttachConsole_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ControlServiceServer).AttachConsole(&controlServiceAttachConsoleServer{stream})
+}
+
+type ControlService_AttachConsoleServer interface {
+	Send(*AttachConsoleResponse) error
+	Recv() (*AttachConsoleRequest, error)
+	grpc.ServerStream
+}
+
+type controlServiceAttachConsoleServer struct {
+	grpc.ServerStream
+}
+
+func (x *controlServiceAttachConsoleServer) Send(m *AttachConsoleResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *controlServiceAttachConsoleServer) Recv() (*AttachConsoleRequest, error) {
+	m := new(AttachConsoleRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _ControlService_GetValidators_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetValidatorsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServiceServer).GetValidators(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpcpb.ControlService/GetValidators",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServiceServer).GetValidators(ctx, req.(*GetValidatorsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ControlService_InjectFault_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InjectFaultRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServiceServer).InjectFault(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpcpb.ControlService/InjectFault",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServiceServer).InjectFault(ctx, req.(*InjectFaultRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ControlService_SetDiskFault_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetDiskFaultRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServiceServer).SetDiskFault(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpcpb.ControlService/SetDiskFault",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServiceServer).SetDiskFault(ctx, req.(*SetDiskFaultRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ControlService_SetTime_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetTimeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServiceServer).SetTime(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpcpb.ControlService/SetTime",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServiceServer).SetTime(ctx, req.(*SetTimeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ControlService_AdvanceTime_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AdvanceTimeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServiceServer).AdvanceTime(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpcpb.ControlService/AdvanceTime",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServiceServer).AdvanceTime(ctx, req.(*AdvanceTimeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ControlService_RenderTopology_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RenderTopologyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServiceServer).RenderTopology(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpcpb.ControlService/RenderTopology",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServiceServer).RenderTopology(ctx, req.(*RenderTopologyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ControlService_CreateBlockchain_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateBlockchainRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServiceServer).CreateBlockchain(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpcpb.ControlService/CreateBlockchain",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServiceServer).CreateBlockchain(ctx, req.(*CreateBlockchainRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ControlService_BlockTraffic_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BlockTrafficRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServiceServer).BlockTraffic(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpcpb.ControlService/BlockTraffic",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServiceServer).BlockTraffic(ctx, req.(*BlockTrafficRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func (c *controlServiceClient) StreamStatus(ctx context.Context, in *StreamStatusRequest, opts ...grpc.CallOption) (ControlService_StreamStatusClient, error) {
-	stream, err := c.cc.NewStream(ctx, &ControlService_ServiceDesc.Streams[0], "/rpcpb.ControlService/StreamStatus", opts...)
-	if err != nil {
+func _ControlService_UnblockTraffic_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnblockTrafficRequest)
+	if err := dec(in); err != nil {
 		return nil, err
 	}
-	x := &controlServiceStreamStatusClient{stream}
-	if err := x.ClientStream.SendMsg(in); err != nil {
-		return nil, err
+	if interceptor == nil {
+		return srv.(ControlServiceServer).UnblockTraffic(ctx, in)
 	}
-	if err := x.ClientStream.CloseSend(); err != nil {
-		return nil, err
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpcpb.ControlService/UnblockTraffic",
 	}
-	return x, nil
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServiceServer).UnblockTraffic(ctx, req.(*UnblockTrafficRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-type ControlService_StreamStatusClient interface {
-	Recv() (*StreamStatusResponse, error)
-	grpc.ClientStream
+func _ControlService_UploadFile_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ControlServiceServer).UploadFile(&controlServiceUploadFileServer{stream})
 }
 
-type controlServiceStreamStatusClient struct {
-	grpc.ClientStream
+type ControlService_UploadFileServer interface {
+	SendAndClose(*UploadFileResponse) error
+	Recv() (*UploadFileRequest, error)
+	grpc.ServerStream
 }
 
-func (x *controlServiceStreamStatusClient) Recv() (*StreamStatusResponse, error) {
-	m := new(StreamStatusResponse)
-	if err := x.ClientStream.RecvMsg(m); err != nil {
+type controlServiceUploadFileServer struct {
+	grpc.ServerStream
+}
+
+func (x *controlServiceUploadFileServer) SendAndClose(m *UploadFileResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *controlServiceUploadFileServer) Recv() (*UploadFileRequest, error) {
+	m := new(UploadFileRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
 		return nil, err
 	}
 	return m, nil
 }
 
-func (c *controlServiceClient) RemoveNode(ctx context.Context, in *RemoveNodeRequest, opts ...grpc.CallOption) (*RemoveNodeResponse, error) {
-	out := new(RemoveNodeResponse)
-	err := c.cc.Invoke(ctx, "/rpcpb.ControlService/RemoveNode", in, out, opts...)
-	if err != nil {
+func _ControlService_GetNodeConfig_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetNodeConfigRequest)
+	if err := dec(in); err != nil {
 		return nil, err
 	}
-	return out, nil
+	if interceptor == nil {
+		return srv.(ControlServiceServer).GetNodeConfig(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpcpb.ControlService/GetNodeConfig",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServiceServer).GetNodeConfig(ctx, req.(*GetNodeConfigRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func (c *controlServiceClient) RestartNode(ctx context.Context, in *RestartNodeRequest, opts ...grpc.CallOption) (*RestartNodeResponse, error) {
-	out := new(RestartNodeResponse)
-	err := c.cc.Invoke(ctx, "/rpcpb.ControlService/RestartNode", in, out, opts...)
-	if err != nil {
+func _ControlService_RotateNodeKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RotateNodeKeyRequest)
+	if err := dec(in); err != nil {
 		return nil, err
 	}
-	return out, nil
+	if interceptor == nil {
+		return srv.(ControlServiceServer).RotateNodeKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpcpb.ControlService/RotateNodeKey",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServiceServer).RotateNodeKey(ctx, req.(*RotateNodeKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func (c *controlServiceClient) Stop(ctx context.Context, in *StopRequest, opts ...grpc.CallOption) (*StopResponse, error) {
-	out := new(StopResponse)
-	err := c.cc.Invoke(ctx, "/rpcpb.ControlService/Stop", in, out, opts...)
-	if err != nil {
+func _ControlService_AddNode_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddNodeRequest)
+	if err := dec(in); err != nil {
 		return nil, err
 	}
-	return out, nil
-}
-
-// ControlServiceServer is the server API for ControlService service.
-// All implementations must embed UnimplementedControlServiceServer
-// for forward compatibility
-type ControlServiceServer interface {
-	Start(context.Context, *StartRequest) (*StartResponse, error)
-	Health(context.Context, *HealthRequest) (*HealthResponse, error)
-	URIs(context.Context, *URIsRequest) (*URIsResponse, error)
-	Status(context.Context, *StatusRequest) (*StatusResponse, error)
-	StreamStatus(*StreamStatusRequest, ControlService_StreamStatusServer) error
-	RemoveNode(context.Context, *RemoveNodeRequest) (*RemoveNodeResponse, error)
-	RestartNode(context.Context, *RestartNodeRequest) (*RestartNodeResponse, error)
-	Stop(context.Context, *StopRequest) (*StopResponse, error)
-	mustEmbedUnimplementedControlServiceServer()
-}
-
-// UnimplementedControlServiceServer must be embedded to have forward compatible implementations.
-type UnimplementedControlServiceServer struct {
-}
-
-func (UnimplementedControlServiceServer) Start(context.Context, *StartRequest) (*StartResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method Start not implemented")
-}
-func (UnimplementedControlServiceServer) Health(context.Context, *HealthRequest) (*HealthResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method Health not implemented")
-}
-func (UnimplementedControlServiceServer) URIs(context.Context, *URIsRequest) (*URIsResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method URIs not implemented")
-}
-func (UnimplementedControlServiceServer) Status(context.Context, *StatusRequest) (*StatusResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method Status not implemented")
-}
-func (UnimplementedControlServiceServer) StreamStatus(*StreamStatusRequest, ControlService_StreamStatusServer) error {
-	return status.Errorf(codes.Unimplemented, "method StreamStatus not implemented")
-}
-func (UnimplementedControlServiceServer) RemoveNode(context.Context, *RemoveNodeRequest) (*RemoveNodeResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method RemoveNode not implemented")
-}
-func (UnimplementedControlServiceServer) RestartNode(context.Context, *RestartNodeRequest) (*RestartNodeResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method RestartNode not implemented")
-}
-func (UnimplementedControlServiceServer) Stop(context.Context, *StopRequest) (*StopResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method Stop not implemented")
-}
-func (UnimplementedControlServiceServer) mustEmbedUnimplementedControlServiceServer() {}
-
-// UnsafeControlServiceServer may be embedded to opt out of forward compatibility for this service.
-// Use of this interface is not recommended, as added methods to ControlServiceServer will
-// result in compilation errors.
-type UnsafeControlServiceServer interface {
-	mustEmbedUnimplementedControlServiceServer()
-}
-
-func RegisterControlServiceServer(s grpc.ServiceRegistrar, srv ControlServiceServer) {
-	s.RegisterService(&ControlService_ServiceDesc, srv)
+	if interceptor == nil {
+		return srv.(ControlServiceServer).AddNode(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpcpb.ControlService/AddNode",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServiceServer).AddNode(ctx, req.(*AddNodeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func _ControlService_Start_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(StartRequest)
+func _ControlService_EstimateResources_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EstimateResourcesRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(ControlServiceServer).Start(ctx, in)
+		return srv.(ControlServiceServer).EstimateResources(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/rpcpb.ControlService/Start",
+		FullMethod: "/rpcpb.ControlService/EstimateResources",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(ControlServiceServer).Start(ctx, req.(*StartRequest))
+		return srv.(ControlServiceServer).EstimateResources(ctx, req.(*EstimateResourcesRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _ControlService_Health_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(HealthRequest)
+func _ControlService_SearchLogs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchLogsRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(ControlServiceServer).Health(ctx, in)
+		return srv.(ControlServiceServer).SearchLogs(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/rpcpb.ControlService/Health",
+		FullMethod: "/rpcpb.ControlService/SearchLogs",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(ControlServiceServer).Health(ctx, req.(*HealthRequest))
+		return srv.(ControlServiceServer).SearchLogs(ctx, req.(*SearchLogsRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _ControlService_URIs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(URIsRequest)
+func _ControlService_GetSyncProgress_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetSyncProgressRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(ControlServiceServer).URIs(ctx, in)
+		return srv.(ControlServiceServer).GetSyncProgress(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/rpcpb.ControlService/URIs",
+		FullMethod: "/rpcpb.ControlService/GetSyncProgress",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(ControlServiceServer).URIs(ctx, req.(*URIsRequest))
+		return srv.(ControlServiceServer).GetSyncProgress(ctx, req.(*GetSyncProgressRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _ControlService_Status_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(StatusRequest)
+func _ControlService_GCNow_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GCNowRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(ControlServiceServer).Status(ctx, in)
+		return srv.(ControlServiceServer).GCNow(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/rpcpb.ControlService/Status",
+		FullMethod: "/rpcpb.ControlService/GCNow",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(ControlServiceServer).Status(ctx, req.(*StatusRequest))
+		return srv.(ControlServiceServer).GCNow(ctx, req.(*GCNowRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _ControlService_StreamStatus_Handler(srv interface{}, stream grpc.ServerStream) error {
-	m := new(StreamStatusRequest)
+func _ControlService_DownloadLogs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(DownloadLogsRequest)
 	if err := stream.RecvMsg(m); err != nil {
 		return err
 	}
-	return srv.(ControlServiceServer).StreamStatus(m, &controlServiceStreamStatusServer{stream})
+	return srv.(ControlServiceServer).DownloadLogs(m, &controlServiceDownloadLogsServer{stream})
 }
 
-type ControlService_StreamStatusServer interface {
-	Send(*StreamStatusResponse) error
+type ControlService_DownloadLogsServer interface {
+	Send(*DownloadLogsResponse) error
 	grpc.ServerStream
 }
 
-type controlServiceStreamStatusServer struct {
+type controlServiceDownloadLogsServer struct {
 	grpc.ServerStream
 }
 
-func (x *controlServiceStreamStatusServer) Send(m *StreamStatusResponse) error {
+func (x *controlServiceDownloadLogsServer) Send(m *DownloadLogsResponse) error {
 	return x.ServerStream.SendMsg(m)
 }
 
-func _ControlService_RemoveNode_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(RemoveNodeRequest)
+func _ControlService_GetRunManifest_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRunManifestRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(ControlServiceServer).RemoveNode(ctx, in)
+		return srv.(ControlServiceServer).GetRunManifest(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/rpcpb.ControlService/RemoveNode",
+		FullMethod: "/rpcpb.ControlService/GetRunManifest",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(ControlServiceServer).RemoveNode(ctx, req.(*RemoveNodeRequest))
+		return srv.(ControlServiceServer).GetRunManifest(ctx, req.(*GetRunManifestRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _ControlService_RestartNode_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(RestartNodeRequest)
+func _ControlService_StopValidatingSubnet_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StopValidatingSubnetRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(ControlServiceServer).RestartNode(ctx, in)
+		return srv.(ControlServiceServer).StopValidatingSubnet(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/rpcpb.ControlService/RestartNode",
+		FullMethod: "/rpcpb.ControlService/StopValidatingSubnet",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(ControlServiceServer).RestartNode(ctx, req.(*RestartNodeRequest))
+		return srv.(ControlServiceServer).StopValidatingSubnet(ctx, req.(*StopValidatingSubnetRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _ControlService_Stop_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(StopRequest)
+func _ControlService_GetGenesis_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetGenesisRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(ControlServiceServer).Stop(ctx, in)
+		return srv.(ControlServiceServer).GetGenesis(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/rpcpb.ControlService/Stop",
+		FullMethod: "/rpcpb.ControlService/GetGenesis",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(ControlServiceServer).Stop(ctx, req.(*StopRequest))
+		return srv.(ControlServiceServer).GetGenesis(ctx, req.(*GetGenesisRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ControlService_GetNetworkParams_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetNetworkParamsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServiceServer).GetNetworkParams(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpcpb.ControlService/GetNetworkParams",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServiceServer).GetNetworkParams(ctx, req.(*GetNetworkParamsRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
@@ -439,6 +1578,10 @@ var ControlService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Health",
 			Handler:    _ControlService_Health_Handler,
 		},
+		{
+			MethodName: "WaitNodesHealthy",
+			Handler:    _ControlService_WaitNodesHealthy_Handler,
+		},
 		{
 			MethodName: "URIs",
 			Handler:    _ControlService_URIs_Handler,
@@ -455,10 +1598,118 @@ var ControlService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "RestartNode",
 			Handler:    _ControlService_RestartNode_Handler,
 		},
+		{
+			MethodName: "RemoveNodes",
+			Handler:    _ControlService_RemoveNodes_Handler,
+		},
+		{
+			MethodName: "RestartNodes",
+			Handler:    _ControlService_RestartNodes_Handler,
+		},
 		{
 			MethodName: "Stop",
 			Handler:    _ControlService_Stop_Handler,
 		},
+		{
+			MethodName: "AbortStart",
+			Handler:    _ControlService_AbortStart_Handler,
+		},
+		{
+			MethodName: "CheckBinary",
+			Handler:    _ControlService_CheckBinary_Handler,
+		},
+		{
+			MethodName: "CreateChainIPC",
+			Handler:    _ControlService_CreateChainIPC_Handler,
+		},
+		{
+			MethodName: "ListChainIPCs",
+			Handler:    _ControlService_ListChainIPCs_Handler,
+		},
+		{
+			MethodName: "RemoveChainIPC",
+			Handler:    _ControlService_RemoveChainIPC_Handler,
+		},
+		{
+			MethodName: "GetValidators",
+			Handler:    _ControlService_GetValidators_Handler,
+		},
+		{
+			MethodName: "InjectFault",
+			Handler:    _ControlService_InjectFault_Handler,
+		},
+		{
+			MethodName: "SetDiskFault",
+			Handler:    _ControlService_SetDiskFault_Handler,
+		},
+		{
+			MethodName: "SetTime",
+			Handler:    _ControlService_SetTime_Handler,
+		},
+		{
+			MethodName: "AdvanceTime",
+			Handler:    _ControlService_AdvanceTime_Handler,
+		},
+		{
+			MethodName: "RenderTopology",
+			Handler:    _ControlService_RenderTopology_Handler,
+		},
+		{
+			MethodName: "CreateBlockchain",
+			Handler:    _ControlService_CreateBlockchain_Handler,
+		},
+		{
+			MethodName: "BlockTraffic",
+			Handler:    _ControlService_BlockTraffic_Handler,
+		},
+		{
+			MethodName: "UnblockTraffic",
+			Handler:    _ControlService_UnblockTraffic_Handler,
+		},
+		{
+			MethodName: "GetNodeConfig",
+			Handler:    _ControlService_GetNodeConfig_Handler,
+		},
+		{
+			MethodName: "RotateNodeKey",
+			Handler:    _ControlService_RotateNodeKey_Handler,
+		},
+		{
+			MethodName: "AddNode",
+			Handler:    _ControlService_AddNode_Handler,
+		},
+		{
+			MethodName: "EstimateResources",
+			Handler:    _ControlService_EstimateResources_Handler,
+		},
+		{
+			MethodName: "SearchLogs",
+			Handler:    _ControlService_SearchLogs_Handler,
+		},
+		{
+			MethodName: "GetSyncProgress",
+			Handler:    _ControlService_GetSyncProgress_Handler,
+		},
+		{
+			MethodName: "GCNow",
+			Handler:    _ControlService_GCNow_Handler,
+		},
+		{
+			MethodName: "GetRunManifest",
+			Handler:    _ControlService_GetRunManifest_Handler,
+		},
+		{
+			MethodName: "StopValidatingSubnet",
+			Handler:    _ControlService_StopValidatingSubnet_Handler,
+		},
+		{
+			MethodName: "GetGenesis",
+			Handler:    _ControlService_GetGenesis_Handler,
+		},
+		{
+			MethodName: "GetNetworkParams",
+			Handler:    _ControlService_GetNetworkParams_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -466,6 +1717,27 @@ var ControlService_ServiceDesc = grpc.ServiceDesc{
 			Handler:       _ControlService_StreamStatus_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "TailChainIPC",
+			Handler:       _ControlService_TailChainIPC_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "AttachConsole",
+			Handler:       _ControlService_AttachConsole_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "UploadFile",
+			Handler:       _ControlService_UploadFile_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "DownloadLogs",
+			Handler:       _ControlService_DownloadLogs_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "rpcpb/rpc.proto",
 }
@@ -81,7 +81,8 @@ find /tmp/avalanchego-v${VERSION_2}
 
 ############################
 echo "building runner"
-go build -v -o /tmp/network.runner ./cmd/avalanche-network-runner
+# with the race detector, as the e2e tests exercise the concurrent requests
+go build -v -race -o /tmp/network.runner ./cmd/avalanche-network-runner
 
 echo "building e2e.test"
 # to install the ginkgo binary (required for test build and run)
@@ -90,6 +91,9 @@ ACK_GINKGO_RC=true ginkgo build ./tests/e2e
 ./tests/e2e/e2e.test --help
 
 echo "launch local test cluster in the background"
+RACE_LOG=/tmp/network.runner.race
+rm -f ${RACE_LOG}.*
+GORACE="halt_on_error=1 log_path=${RACE_LOG}" \
 /tmp/network.runner \
 server \
 --log-level debug \
@@ -107,4 +111,9 @@ echo "running e2e tests"
 --avalanchego-path-2=/tmp/avalanchego-v${VERSION_2}/avalanchego
 
 kill -9 ${PID}
+if ls ${RACE_LOG}.* > /dev/null 2>&1; then
+  echo "data races detected:"
+  cat ${RACE_LOG}.*
+  exit 1
+fi
 echo "ALL SUCCESS!"
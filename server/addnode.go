@@ -0,0 +1,144 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lasthyphen/dijetsnode-go-runner/network/node"
+	"github.com/lasthyphen/dijetsnodego/staking"
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+	"go.uber.org/zap"
+)
+
+// AddNode adds a node to the running network, with the config of the network
+// nodes. The node has a new staking key, so it has no stake and does not
+// validate (unless staked by the caller). An observer is marked as such in
+// its node info, for the tests to send their API traffic to it, away from
+// the validators.
+func (s *server) AddNode(ctx context.Context, req *rpcpb.AddNodeRequest) (*rpcpb.AddNodeResponse, error) {
+	zap.L().Info("received add node request",
+		zap.String("name", req.Name),
+		zap.String("execPath", req.ExecPath),
+		zap.Bool("observer", req.Observer),
+		zap.Bool("stateSync", req.StateSync),
+		zap.Boolp("archival", req.Archival),
+	)
+	if req.Name == "" {
+		return nil, ErrEmptyNodeName
+	}
+	if info := s.getClusterInfo(); info == nil {
+		return nil, ErrNotBootstrapped
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkStateLocked(stateHealthy, stateDegraded); err != nil {
+		return nil, err
+	}
+	if _, ok := s.network.nodeInfos[req.Name]; ok {
+		return nil, fmt.Errorf("%w: %q", ErrDuplicateNodeName, req.Name)
+	}
+	opts := s.network.opts
+	if opts.maxNodes > 0 && len(s.network.nodeInfos) >= int(opts.maxNodes) {
+		return nil, fmt.Errorf("%w: tenant %q allows %d nodes", ErrTenantLimitReached, opts.tenant, opts.maxNodes)
+	}
+	if req.ExecPath != "" {
+		if err := s.checkExecPath(req.ExecPath); err != nil {
+			return nil, err
+		}
+		opts.execPath = req.ExecPath
+	}
+	opts.stateSyncNodes = nil
+	if req.StateSync {
+		opts.stateSyncNodes = map[string]bool{req.Name: true}
+	}
+	opts.nodeArchival = nil
+	if req.Archival != nil {
+		opts.nodeArchival = map[string]bool{req.Name: req.GetArchival()}
+	}
+
+	// a removed node of the same name gives its slot (and static ports) back
+	idx := len(s.network.cfg.NodeConfigs)
+	for i, cfg := range s.network.cfg.NodeConfigs {
+		if cfg.Name == req.Name {
+			idx = i
+			break
+		}
+	}
+	ports, err := assignPorts(idx+1, opts.basePort)
+	if err != nil {
+		return nil, err
+	}
+
+	nodeConfig := node.Config{Name: req.Name}
+	nodeConfig.StakingCert, nodeConfig.StakingKey, err = staking.NewCertAndKeyBytes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate the staking key: %w", err)
+	}
+	// as set up on start, without the state sync of the other nodes
+	var cChainConfig []byte
+	if len(opts.cChainConfig) > 0 {
+		cChainConfig, err = json.Marshal(opts.cChainConfig)
+		if err != nil {
+			return nil, err
+		}
+	}
+	info, writers, err := setupNode(opts, &nodeConfig, ports[idx], cChainConfig, s.network.sink, s.network.portConflictc)
+	if err != nil {
+		return nil, err
+	}
+	info.Observer = req.Observer
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	addStart := time.Now()
+	if info.StateSync {
+		s.syncs.add(info, s.network.nodeInfos)
+	}
+	s.network.transition(stateBootstrapping, nil)
+	if err := s.network.addNode(nodeConfig); err != nil {
+		s.syncs.remove(req.Name)
+		s.network.transition(stateDegraded, err)
+		return nil, err
+	}
+	if idx < len(s.network.cfg.NodeConfigs) {
+		s.network.cfg.NodeConfigs[idx] = nodeConfig
+	} else {
+		s.network.cfg.NodeConfigs = append(s.network.cfg.NodeConfigs, nodeConfig)
+	}
+	s.network.writers[req.Name] = writers
+	s.network.nodeInfos[req.Name] = info
+	// a removed peer added back, with a new key
+	if err := s.network.writeBootstrapPeers(); err != nil {
+		zap.L().Warn("failed to write the bootstrap peers", zap.String("name", req.Name), zap.Error(err))
+	}
+	s.network.nodeNames = append(s.network.nodeNames, req.Name)
+	s.clusterInfo.NodeNames = s.network.nodeNames
+	s.clusterInfo.NodeInfos = s.network.nodeInfos
+	if err := s.network.limitNodes(ctx, []string{req.Name}); err != nil {
+		s.network.transition(stateDegraded, err)
+		return nil, err
+	}
+
+	zap.L().Info("waiting for healthy")
+	if err := s.waitForHealthyLocked(ctx); err != nil {
+		if !errors.Is(err, errAborted) {
+			s.network.transition(stateDegraded, err)
+		}
+		return nil, err
+	}
+	if info.StateSync {
+		s.syncs.markSynced(req.Name)
+	}
+	s.latencies.observe(opAddNode, addStart)
+
+	return &rpcpb.AddNodeResponse{ClusterInfo: s.copyClusterInfo()}, nil
+}
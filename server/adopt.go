@@ -0,0 +1,390 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lasthyphen/dijetsnode-go-runner/api"
+	"github.com/lasthyphen/dijetsnode-go-runner/network/node"
+	"github.com/lasthyphen/dijetsnodego/ids"
+	"github.com/lasthyphen/dijetsnodego/utils/constants"
+	"github.com/lasthyphen/djtx-tester/pkg/jsonrpc"
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// time for the adopted nodes to exit on stop, without a shutdown grace
+// period (as the default "--shutdown-grace-period")
+const adoptedStopWait = 30 * time.Second
+
+var ErrAdoptedNetwork = errors.New("the nodes of an adopted network cannot be added, removed, or restarted")
+
+// adoptOrphans adopts the supervised node processes of a previous server
+// into the network of this one. The network is rebuilt from the run
+// manifest of its root data directory, so only the networks with a
+// manifest are adopted; of several, the latest started. Failures are
+// logged, not returned, and the processes left running.
+func (s *server) adoptOrphans(orphans []orphan) {
+	networks := groupOrphans(orphans)
+	manifests := make(map[string]*rpcpb.RunManifest, len(networks))
+	dirs := make([]string, 0, len(networks))
+	for dir := range networks {
+		m, err := readRunManifest(dir)
+		if err != nil {
+			zap.L().Warn("cannot adopt orphaned network", zap.String("rootDataDir", dir), zap.Error(err))
+			continue
+		}
+		manifests[dir] = m
+		dirs = append(dirs, dir)
+	}
+	// RFC 3339 times in UTC sort lexically
+	sort.Slice(dirs, func(i, j int) bool { return manifests[dirs[i]].CreatedAt > manifests[dirs[j]].CreatedAt })
+	for i, dir := range dirs {
+		if err := s.adoptNetwork(manifests[dir], networks[dir]); err != nil {
+			zap.L().Warn("failed to adopt orphaned network", zap.String("rootDataDir", dir), zap.Error(err))
+			continue
+		}
+		for _, other := range dirs[i+1:] {
+			zap.L().Warn("found orphaned network (may conflict with the network ports)", zap.String("rootDataDir", other))
+		}
+		return
+	}
+}
+
+// groupOrphans groups the orphans by node name, by network root data
+// directory. The processes outside of a node directory are skipped.
+func groupOrphans(orphans []orphan) map[string]map[string]orphan {
+	networks := make(map[string]map[string]orphan)
+	for _, o := range orphans {
+		rel, err := filepath.Rel(o.rootDataDir, filepath.Clean(o.dbDir))
+		if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		name := strings.Split(rel, string(filepath.Separator))[0]
+		if networks[o.rootDataDir] == nil {
+			networks[o.rootDataDir] = make(map[string]orphan)
+		}
+		networks[o.rootDataDir][name] = o
+	}
+	return networks
+}
+
+func readRunManifest(rootDataDir string) (*rpcpb.RunManifest, error) {
+	b, err := ioutil.ReadFile(filepath.Join(rootDataDir, runManifestFile))
+	if err != nil {
+		return nil, err
+	}
+	m := &rpcpb.RunManifest{}
+	if err := protojson.Unmarshal(b, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// adoptNetwork rebuilds the network of the manifest with the running nodes
+// (by node name), and waits for them to be healthy in the background. The
+// nodes missing from the processes (e.g., crashed) are left out. As the
+// server did not launch the nodes, their outputs are not captured, and
+// they cannot be added, removed, or restarted; the network is stopped as
+// any other. The sidecars, disk devices, and seed actions of the start
+// request are not restored.
+func (s *server) adoptNetwork(m *rpcpb.RunManifest, procs map[string]orphan) error {
+	req := &rpcpb.StartRequest{}
+	if err := protojson.Unmarshal([]byte(m.StartRequest), req); err != nil {
+		return err
+	}
+	rootDataDir := m.RootDataDir
+	opts, err := s.resolveNetworkOptions(req, rootDataDir)
+	if err != nil {
+		return err
+	}
+	opts.tenant = rootDataDirTenant(rootDataDir)
+	opts.supervisor = SupervisorSystemd
+	opts.resumeCheckpoint, opts.launchInterval = "", 0
+	opts.nodeSidecars, opts.diskFaultNodes, opts.seedActions = nil, nil, nil
+
+	lc, err := newNetwork(opts)
+	if err != nil {
+		return err
+	}
+	// the network owns the sink once adopted
+	adopted := false
+	defer func() {
+		if !adopted {
+			lc.stopCancel()
+			lc.sink.Close()
+		}
+	}()
+	nodes := make(map[string]*adoptedNode, len(procs))
+	nodeNames := make([]string, 0, len(procs))
+	nodeConfigs := lc.cfg.NodeConfigs[:0]
+	for _, nc := range lc.cfg.NodeConfigs {
+		o, ok := procs[nc.Name]
+		nm, inManifest := m.Nodes[nc.Name]
+		if !ok || !inManifest {
+			delete(lc.nodeInfos, nc.Name)
+			delete(lc.writers, nc.Name)
+			continue
+		}
+		info := lc.nodeInfos[nc.Name]
+		nc.ConfigFile, nc.CChainConfigFile = []byte(nm.Config), []byte(nm.CChainConfig)
+		info.ExecPath, info.Config = nm.ExecPath, nc.ConfigFile
+		info.UnitName = o.unit
+		if info.HttpPort, info.StakingPort, err = nodeConfigPorts(nc.ConfigFile); err != nil {
+			return fmt.Errorf("invalid config of %q: %w", nc.Name, err)
+		}
+		setNodeAddresses(info)
+		nodes[nc.Name] = &adoptedNode{
+			name:        nc.Name,
+			pid:         o.pid,
+			httpPort:    uint16(info.HttpPort),
+			stakingPort: uint16(info.StakingPort),
+		}
+		nodeNames = append(nodeNames, nc.Name)
+		nodeConfigs = append(nodeConfigs, nc)
+	}
+	if len(nodes) == 0 {
+		return errors.New("no node of the manifest is running")
+	}
+	lc.cfg.NodeConfigs = nodeConfigs
+	lc.nodeNames = nodeNames
+	lc.nw = &adoptedNetwork{nodes: nodes}
+	lc.manifest = m
+	if t, err := time.Parse(time.RFC3339, m.CreatedAt); err == nil {
+		lc.createdAt = t
+	}
+	lc.provenance = &rpcpb.NetworkProvenance{
+		Kind:          rpcpb.ProvenanceKind_PROVENANCE_KIND_ADOPTED,
+		Preset:        req.GetPreset(),
+		Template:      req.GetTemplate(),
+		GenesisSha256: m.GenesisSha256,
+		StartedAt:     m.CreatedAt,
+	}
+
+	adopted = true
+	s.mu.Lock()
+	s.network = lc
+	s.readiness.setNetwork(lc.lifecycle)
+	s.clusterInfo = &rpcpb.ClusterInfo{
+		Pid:         int32(os.Getpid()),
+		RootDataDir: rootDataDir,
+		NodeNames:   lc.nodeNames,
+		NodeInfos:   lc.nodeInfos,
+		State:       stateCreating,
+	}
+	s.syncs.reset()
+	s.mu.Unlock()
+	zap.L().Info("adopted orphaned network",
+		zap.String("rootDataDir", rootDataDir),
+		zap.Strings("nodeNames", nodeNames),
+	)
+
+	lc.startWg.Add(1)
+	go func() {
+		if err := lc.adopt(); err != nil {
+			zap.L().Warn("adopted network not healthy", zap.Error(err))
+			return
+		}
+		s.watchNetwork(lc)
+	}()
+	return nil
+}
+
+// rootDataDirTenant returns the tenant of the root data directory (ref.
+// "Start"), or "" if not in a tenant directory.
+func rootDataDirTenant(rootDataDir string) string {
+	parent := filepath.Dir(rootDataDir)
+	if parent == filepath.Clean(os.TempDir()) {
+		return ""
+	}
+	return strings.TrimPrefix(filepath.Base(parent), "network-runner-")
+}
+
+// nodeConfigPorts returns the HTTP and staking ports of the node config.
+func nodeConfigPorts(config []byte) (uint32, uint32, error) {
+	var cfg struct {
+		HTTPPort    uint32 `json:"http-port"`
+		StakingPort uint32 `json:"staking-port"`
+	}
+	if err := json.Unmarshal(config, &cfg); err != nil {
+		return 0, 0, err
+	}
+	if cfg.HTTPPort == 0 || cfg.StakingPort == 0 {
+		return 0, 0, errors.New("missing ports")
+	}
+	return cfg.HTTPPort, cfg.StakingPort, nil
+}
+
+// adopt waits for the adopted nodes to be healthy, as "start" does for
+// the launched nodes. The caller must call "startWg.Add(1)" before
+// starting the routine.
+func (lc *localNetwork) adopt() error {
+	defer lc.startWg.Done()
+
+	lc.transition(stateBootstrapping, nil)
+	if err := lc.waitForHealthyUnlocked(lc.stopCtx); err != nil {
+		lc.transition(stateErrored, err)
+		return err
+	}
+	return nil
+}
+
+// adoptedNetwork is the network of the nodes launched by a previous server.
+type adoptedNetwork struct {
+	mu    sync.Mutex
+	nodes map[string]*adoptedNode
+}
+
+func (an *adoptedNetwork) Healthy(ctx context.Context) chan error {
+	errc := make(chan error, 1)
+	go func() {
+		errc <- an.awaitHealthy(ctx)
+	}()
+	return errc
+}
+
+// awaitHealthy polls the health API of each node until it reports healthy,
+// and reads its node ID.
+func (an *adoptedNetwork) awaitHealthy(ctx context.Context) error {
+	an.mu.Lock()
+	nodes := make([]*adoptedNode, 0, len(an.nodes))
+	for _, nd := range an.nodes {
+		nodes = append(nodes, nd)
+	}
+	an.mu.Unlock()
+	for _, nd := range nodes {
+		for {
+			if healthy, _ := pollNodeHealth(ctx, nd.uri()); healthy {
+				break
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(defaultHealthPollMaxInterval):
+			}
+		}
+		// ref. api/info "GetNodeID"
+		var reply struct {
+			NodeID string `json:"nodeID"`
+		}
+		if err := jsonrpc.Call(ctx, nd.uri(), "/ext/info", "info.getNodeID", nil, &reply); err != nil {
+			return fmt.Errorf("failed to get the node ID of %q: %w", nd.name, err)
+		}
+		id, err := ids.ShortFromPrefixedString(reply.NodeID, constants.NodeIDPrefix)
+		if err != nil {
+			return fmt.Errorf("invalid node ID of %q: %w", nd.name, err)
+		}
+		an.mu.Lock()
+		nd.nodeID = id
+		an.mu.Unlock()
+	}
+	return nil
+}
+
+// Stop interrupts the node processes, and kills the ones still running
+// when the context is done.
+func (an *adoptedNetwork) Stop(ctx context.Context) error {
+	wait := adoptedStopWait
+	if deadline, ok := ctx.Deadline(); ok {
+		wait = time.Until(deadline)
+	}
+	an.mu.Lock()
+	pids := make(map[string]int, len(an.nodes))
+	names := make([]string, 0, len(an.nodes))
+	for name, nd := range an.nodes {
+		pids[name] = nd.pid
+		names = append(names, name)
+	}
+	an.nodes = make(map[string]*adoptedNode)
+	an.mu.Unlock()
+
+	errs := runParallel(context.Background(), names, 0, func(name string) error {
+		return stopProcess(pids[name], wait)
+	})
+	for _, name := range names {
+		if err := errs[name]; err != nil {
+			return fmt.Errorf("failed to stop %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func (an *adoptedNetwork) AddNode(node.Config) (node.Node, error) {
+	return nil, ErrAdoptedNetwork
+}
+
+func (an *adoptedNetwork) RemoveNode(name string) error {
+	return ErrAdoptedNetwork
+}
+
+func (an *adoptedNetwork) GetNode(name string) (node.Node, error) {
+	an.mu.Lock()
+	defer an.mu.Unlock()
+	nd, ok := an.nodes[name]
+	if !ok {
+		return nil, ErrNodeNotFound
+	}
+	return nd.snapshot(), nil
+}
+
+func (an *adoptedNetwork) GetAllNodes() (map[string]node.Node, error) {
+	an.mu.Lock()
+	defer an.mu.Unlock()
+	nodes := make(map[string]node.Node, len(an.nodes))
+	for name, nd := range an.nodes {
+		nodes[name] = nd.snapshot()
+	}
+	return nodes, nil
+}
+
+func (an *adoptedNetwork) GetNodesNames() ([]string, error) {
+	an.mu.Lock()
+	defer an.mu.Unlock()
+	names := make([]string, 0, len(an.nodes))
+	for name := range an.nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// adoptedNode is a node process of an adopted network.
+type adoptedNode struct {
+	name        string
+	pid         int
+	httpPort    uint16
+	stakingPort uint16
+	// read once healthy
+	nodeID ids.ShortID
+}
+
+// snapshot returns a copy of the node, safe to read without the lock of
+// its network.
+func (nd *adoptedNode) snapshot() *adoptedNode {
+	cp := *nd
+	return &cp
+}
+
+func (nd *adoptedNode) uri() string {
+	return fmt.Sprintf("http://%s:%d", nd.GetURL(), nd.httpPort)
+}
+
+func (nd *adoptedNode) GetName() string          { return nd.name }
+func (nd *adoptedNode) GetNodeID() ids.ShortID   { return nd.nodeID }
+func (nd *adoptedNode) GetAPIClient() api.Client { return api.NewAPIClient(nd.GetURL(), nd.httpPort) }
+func (nd *adoptedNode) GetURL() string           { return "127.0.0.1" }
+func (nd *adoptedNode) GetP2PPort() uint16       { return nd.stakingPort }
+func (nd *adoptedNode) GetAPIPort() uint16       { return nd.httpPort }
@@ -0,0 +1,152 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/lasthyphen/dijetsnode-go-runner/network/node"
+)
+
+func TestGroupOrphans(t *testing.T) {
+	a := filepath.Join(os.TempDir(), rootDataDirPrefix+"1")
+	b := filepath.Join(os.TempDir(), "network-runner-team", rootDataDirPrefix+"2")
+	networks := groupOrphans([]orphan{
+		{pid: 1, rootDataDir: a, dbDir: filepath.Join(a, "node1", "db-dir"), unit: "u1"},
+		{pid: 2, rootDataDir: a, dbDir: filepath.Join(a, "node2", "db-dir") + "/", unit: "u2"},
+		{pid: 3, rootDataDir: b, dbDir: filepath.Join(b, "node1", "db-dir"), unit: "u3"},
+		// not in a node directory
+		{pid: 4, rootDataDir: b, dbDir: b, unit: "u4"},
+	})
+	if len(networks) != 2 || len(networks[a]) != 2 || len(networks[b]) != 1 {
+		t.Fatalf("unexpected networks %v", networks)
+	}
+	if networks[a]["node2"].pid != 2 || networks[b]["node1"].pid != 3 {
+		t.Fatalf("unexpected nodes %v", networks)
+	}
+	if tenant := rootDataDirTenant(a); tenant != "" {
+		t.Fatalf("expected no tenant, got %q", tenant)
+	}
+	if tenant := rootDataDirTenant(b); tenant != "team" {
+		t.Fatalf("expected tenant %q, got %q", "team", tenant)
+	}
+}
+
+func TestNodeConfigPorts(t *testing.T) {
+	for i, tv := range []struct {
+		config      string
+		http, stake uint32
+		fail        bool
+	}{
+		{config: `{"http-port":9650,"staking-port":9651,"log-level":"INFO"}`, http: 9650, stake: 9651},
+		{config: `{"http-port":9650}`, fail: true},
+		{config: `{`, fail: true},
+	} {
+		http, stake, err := nodeConfigPorts([]byte(tv.config))
+		if (err != nil) != tv.fail {
+			t.Fatalf("#%d: expected failure %v, got %v", i, tv.fail, err)
+		}
+		if http != tv.http || stake != tv.stake {
+			t.Fatalf("#%d: expected %d %d, got %d %d", i, tv.http, tv.stake, http, stake)
+		}
+	}
+}
+
+func TestAdoptedNetwork(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("stopping the orphans requires Linux")
+	}
+	healthy := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Method string `json:"method"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		var result interface{}
+		switch req.Method {
+		case "health.health":
+			select {
+			case <-healthy:
+				result = map[string]bool{"healthy": true}
+			default:
+				result = map[string]bool{"healthy": false}
+			}
+		case "info.getNodeID":
+			result = map[string]string{"nodeID": "NodeID-7Xhw2mDxuDS44j42TCB6U5579esbSt3Lg"}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "result": result})
+	}))
+	defer srv.Close()
+	_, port, err := net.SplitHostPort(srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpPort, err := strconv.Atoi(port)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proc := exec.Command("sleep", "60")
+	if err := proc.Start(); err != nil {
+		t.Fatal(err)
+	}
+	exited := make(chan struct{})
+	go func() {
+		_ = proc.Wait()
+		close(exited)
+	}()
+
+	an := &adoptedNetwork{nodes: map[string]*adoptedNode{
+		"node1": {name: "node1", pid: proc.Process.Pid, httpPort: uint16(httpPort), stakingPort: 9651},
+	}}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	err = <-an.Healthy(ctx)
+	cancel()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected an unhealthy node, got %v", err)
+	}
+	close(healthy)
+	if err := <-an.Healthy(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	nodes, err := an.GetAllNodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 1 || nodes["node1"].GetAPIPort() != uint16(httpPort) || nodes["node1"].GetP2PPort() != 9651 {
+		t.Fatalf("unexpected nodes %v", nodes)
+	}
+	if _, err := an.AddNode(node.Config{Name: "node2"}); !errors.Is(err, ErrAdoptedNetwork) {
+		t.Fatalf("expected %v, got %v", ErrAdoptedNetwork, err)
+	}
+	if err := an.RemoveNode("node1"); !errors.Is(err, ErrAdoptedNetwork) {
+		t.Fatalf("expected %v, got %v", ErrAdoptedNetwork, err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := an.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-exited:
+	case <-time.After(5 * time.Second):
+		t.Fatal("node process not stopped")
+	}
+	if names, _ := an.GetNodesNames(); len(names) != 0 {
+		t.Fatalf("expected no nodes once stopped, got %q", names)
+	}
+}
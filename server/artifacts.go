@@ -0,0 +1,189 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lasthyphen/djtx-tester/pkg/conformance"
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+var ErrInvalidArtifactsPath = errors.New("invalid artifacts path")
+
+// artifactsPath returns the absolute path of the artifacts in the directory,
+// rejecting the paths outside of it (ref. "uploadPath").
+func artifactsPath(dir string, rel string) (string, error) {
+	path, err := uploadPath(dir, rel)
+	if errors.Is(err, ErrInvalidUploadPath) {
+		return "", fmt.Errorf("%w: %q (expected a relative path in the artifacts directory)", ErrInvalidArtifactsPath, rel)
+	}
+	return path, err
+}
+
+// crashArtifactsPath returns the path of the artifacts collected when the
+// node crashes, next to the artifacts on stop (e.g., "artifacts.tar.gz"
+// for "artifacts-node1-crash-1665748800.tar.gz").
+func crashArtifactsPath(path string, name string, now time.Time) string {
+	base := strings.TrimSuffix(strings.TrimSuffix(path, ".tar.gz"), ".tgz")
+	return fmt.Sprintf("%s-%s-crash-%d.tar.gz", base, name, now.Unix())
+}
+
+// recordedCalls returns the lines of the recorded control calls (ref.
+// "conformance.Recorder") made since the given time.
+func recordedCalls(path string, since time.Time) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var buf bytes.Buffer
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		// a partial last line is still being written
+		if err == io.EOF {
+			return buf.Bytes(), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		var call conformance.Call
+		if json.Unmarshal(line, &call) != nil {
+			continue
+		}
+		t, terr := time.Parse(time.RFC3339Nano, call.Time)
+		if terr != nil || t.Before(since) {
+			continue
+		}
+		buf.Write(line)
+	}
+}
+
+// collectArtifacts writes a gzipped tarball of the network logs (node logs
+// and the network runner log in the root data directory), node configs, and
+// the cluster info to the given path, with the control calls made since the
+// given time, if the server records them to "fixtures". Database
+// directories are skipped.
+func collectArtifacts(path string, rootDataDir string, info *rpcpb.ClusterInfo, fixtures string, since time.Time) error {
+	zap.L().Info("collecting artifacts", zap.String("path", path), zap.String("rootDataDir", rootDataDir))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+
+	now := time.Now()
+	addFile := func(name string, b []byte) error {
+		if err := tw.WriteHeader(&tar.Header{
+			Name:    name,
+			Mode:    0o644,
+			Size:    int64(len(b)),
+			ModTime: now,
+		}); err != nil {
+			return err
+		}
+		_, err := tw.Write(b)
+		return err
+	}
+
+	if info != nil {
+		b, err := protojson.MarshalOptions{Multiline: true}.Marshal(info)
+		if err != nil {
+			return err
+		}
+		if err := addFile("cluster-info.json", b); err != nil {
+			return err
+		}
+
+		names := make([]string, 0, len(info.NodeInfos))
+		for name := range info.NodeInfos {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if err := addFile(filepath.Join(name, "config.json"), info.NodeInfos[name].Config); err != nil {
+				return err
+			}
+		}
+	}
+
+	if fixtures != "" {
+		b, err := recordedCalls(fixtures, since)
+		if err != nil {
+			return err
+		}
+		if err := addFile("control-calls.jsonl", b); err != nil {
+			return err
+		}
+	}
+
+	err = filepath.Walk(rootDataDir, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			if fi.Name() == "db-dir" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		// e.g., skip IPC sockets
+		if !fi.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(rootDataDir, p)
+		if err != nil {
+			return err
+		}
+		hdr, err := tar.FileInfoHeader(fi, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.Join("root-data-dir", rel)
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		src, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		// the file may still grow, so copy only the size in the header
+		_, err = io.CopyN(tw, src, hdr.Size)
+		src.Close()
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gw.Close(); err != nil {
+		return err
+	}
+	return f.Sync()
+}
@@ -0,0 +1,176 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+)
+
+func TestArtifactsPath(t *testing.T) {
+	dir := t.TempDir()
+	for i, tv := range []struct {
+		rel  string
+		path string
+		err  error
+	}{
+		{rel: "ci-1234/artifacts.tar.gz", path: filepath.Join(dir, "ci-1234", "artifacts.tar.gz")},
+		{rel: "", err: ErrInvalidArtifactsPath},
+		{rel: "/tmp/artifacts.tar.gz", err: ErrInvalidArtifactsPath},
+		{rel: "../artifacts.tar.gz", err: ErrInvalidArtifactsPath},
+	} {
+		path, err := artifactsPath(dir, tv.rel)
+		if !errors.Is(err, tv.err) {
+			t.Fatalf("#%d: expected %v, got %v", i, tv.err, err)
+		}
+		if path != tv.path {
+			t.Fatalf("#%d: expected %q, got %q", i, tv.path, path)
+		}
+	}
+
+	now := time.Unix(1665748800, 0)
+	if path := crashArtifactsPath("/a/artifacts.tar.gz", "node1", now); path != "/a/artifacts-node1-crash-1665748800.tar.gz" {
+		t.Fatalf("unexpected crash artifacts path %q", path)
+	}
+}
+
+func TestCollectArtifacts(t *testing.T) {
+	dir := t.TempDir()
+	rootDataDir := filepath.Join(dir, "root")
+	for name, data := range map[string]string{
+		"main.log":                "runner\n",
+		"node1/log/main.log":      "node\n",
+		"node1/db-dir/000001.ldb": "table",
+	} {
+		p := filepath.Join(rootDataDir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	since := time.Date(2022, 10, 14, 12, 0, 0, 0, time.UTC)
+	fixtures := filepath.Join(dir, "fixtures.jsonl")
+	calls := `{"method":"/rpcpb.ControlService/Status","time":"2022-10-14T11:00:00Z","request":{},"code":"OK"}
+{"method":"/rpcpb.ControlService/Start","time":"2022-10-14T12:00:01Z","request":{},"code":"OK"}
+{"method":"/rpcpb.ControlService/Stop","time":"2022-10-14T12:`
+	if err := ioutil.WriteFile(fixtures, []byte(calls), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "artifacts", "artifacts.tar.gz")
+	if err := collectArtifacts(path, rootDataDir, nil, fixtures, since); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]string)
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			break
+		}
+		b, err := ioutil.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[hdr.Name] = string(b)
+	}
+	expected := map[string]string{
+		"control-calls.jsonl":              `{"method":"/rpcpb.ControlService/Start","time":"2022-10-14T12:00:01Z","request":{},"code":"OK"}` + "\n",
+		"root-data-dir/main.log":           "runner\n",
+		"root-data-dir/node1/log/main.log": "node\n",
+	}
+	if len(files) != len(expected) {
+		t.Fatalf("expected %d files, got %v", len(expected), files)
+	}
+	for name, data := range expected {
+		if files[name] != data {
+			t.Fatalf("%s: expected %q, got %q", name, data, files[name])
+		}
+	}
+}
+
+func TestStopCollectsArtifacts(t *testing.T) {
+	s, _ := newStartingServer(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "artifacts", "artifacts.tar.gz")
+	s.network.opts.rootDataDir = filepath.Join(dir, "root")
+	s.network.opts.artifactsPath = path
+	if err := os.MkdirAll(s.network.opts.rootDataDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error)
+	go func() {
+		_, err := s.Stop(context.Background(), &rpcpb.StopRequest{})
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("stop blocked collecting the artifacts")
+	}
+	// written once stopped, before the response
+	if _, err := os.Stat(path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResolveArtifactsPaths(t *testing.T) {
+	dir := t.TempDir()
+	s := &server{cfg: Config{ArtifactsDir: dir}}
+	rootDataDir := filepath.Join(os.TempDir(), "network-runner-team", rootDataDirPrefix+"1")
+	for i, tv := range []struct {
+		path, stalls string
+		err          error
+	}{
+		{path: "artifacts.tar.gz", stalls: "stalls"},
+		{path: "../artifacts.tar.gz", err: ErrInvalidArtifactsPath},
+		{stalls: "/tmp/stalls", err: ErrInvalidArtifactsPath},
+	} {
+		req := &rpcpb.StartRequest{LivenessMonitor: &rpcpb.LivenessMonitor{StallThreshold: "5m"}}
+		if tv.path != "" {
+			req.ArtifactsPath = &tv.path
+		}
+		if tv.stalls != "" {
+			req.LivenessMonitor.ArtifactsDir = &tv.stalls
+		}
+		opts, err := s.resolveNetworkOptions(req, rootDataDir)
+		if !errors.Is(err, tv.err) {
+			t.Fatalf("#%d: expected %v, got %v", i, tv.err, err)
+		}
+		if err != nil {
+			continue
+		}
+		// in the directory of the tenant
+		if expected := filepath.Join(dir, "team", tv.path); opts.artifactsPath != expected {
+			t.Fatalf("#%d: expected %q, got %q", i, expected, opts.artifactsPath)
+		}
+		if expected := filepath.Join(dir, "team", tv.stalls); opts.liveness.artifactsDir != expected {
+			t.Fatalf("#%d: expected %q, got %q", i, expected, opts.liveness.artifactsDir)
+		}
+	}
+}
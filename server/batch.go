@@ -0,0 +1,248 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lasthyphen/djtx-tester/pkg/tracing"
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrEmptyNodeNames     = errors.New("empty node names")
+	ErrDuplicateNodeName  = errors.New("duplicate node name")
+	ErrBatchNodesCanceled = errors.New("canceled before the node operation")
+)
+
+// RemoveNodes removes the nodes in parallel, and waits for the remaining
+// nodes to be healthy. A node failure does not abort the other nodes.
+func (s *server) RemoveNodes(ctx context.Context, req *rpcpb.RemoveNodesRequest) (*rpcpb.RemoveNodesResponse, error) {
+	zap.L().Info("received remove nodes request", zap.Strings("names", req.Names), zap.Uint32("parallelism", req.Parallelism))
+	if err := checkNodeNames(req.Names); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkStateLocked(stateHealthy, stateDegraded); err != nil {
+		return nil, err
+	}
+	results := newNodeResults(req.Names)
+	names := make([]string, 0, len(req.Names))
+	for _, name := range req.Names {
+		if _, ok := s.network.nodeInfos[name]; !ok {
+			results[name].Error = ErrNodeNotFound.Error()
+			continue
+		}
+		names = append(names, name)
+	}
+
+	errs := runParallel(ctx, names, req.Parallelism, func(name string) error {
+		_, span := tracing.Start(ctx, "remove node", trace.WithAttributes(attribute.String("name", name)))
+		err := s.network.removeNode(name)
+		tracing.End(span, err)
+		return err
+	})
+	removed := 0
+	for _, name := range names {
+		if err := errs[name]; err != nil {
+			results[name].Error = err.Error()
+			continue
+		}
+		s.deleteNodeInfoLocked(name)
+		removed++
+	}
+
+	if removed > 0 {
+		zap.L().Info("waiting for healthy")
+		if err := s.waitForHealthyLocked(ctx); err != nil {
+			if ctx.Err() == nil && !errors.Is(err, errAborted) {
+				s.network.markUnhealthy(err)
+			}
+			return nil, err
+		}
+	}
+	return &rpcpb.RemoveNodesResponse{ClusterInfo: s.copyClusterInfo(), Results: results.list(req.Names)}, nil
+}
+
+// RestartNodes restarts the nodes in parallel with the same start request,
+// and waits for the network to be healthy. A node failure does not abort
+// the other nodes, but leaves the network degraded.
+func (s *server) RestartNodes(ctx context.Context, req *rpcpb.RestartNodesRequest) (*rpcpb.RestartNodesResponse, error) {
+	zap.L().Info("received restart nodes request", zap.Strings("names", req.Names), zap.Uint32("parallelism", req.Parallelism))
+	if err := checkNodeNames(req.Names); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkStateLocked(stateHealthy, stateDegraded); err != nil {
+		return nil, err
+	}
+	results := newNodeResults(req.Names)
+	plans := make(map[string]*restartPlan)
+	names := make([]string, 0, len(req.Names))
+	for _, name := range req.Names {
+		plan, err := s.planRestartLocked(name, req.StartRequest)
+		if err != nil {
+			results[name].Error = err.Error()
+			continue
+		}
+		results[name].ConfigChanges = plan.changes
+		plans[name] = plan
+		names = append(names, name)
+	}
+
+	// do not remove the nodes if the client already gave up
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if len(names) > 0 {
+		s.network.transition(stateBootstrapping, nil)
+	}
+	restartStart := time.Now()
+	errs := runParallel(ctx, names, req.Parallelism, func(name string) (err error) {
+		_, span := tracing.Start(ctx, "restart node", trace.WithAttributes(attribute.String("name", name)))
+		defer func() { tracing.End(span, err) }()
+
+		if err := s.network.removeNode(name); err != nil {
+			return fmt.Errorf("failed to remove node: %w", err)
+		}
+		if err := s.network.addNode(plans[name].nodeConfig); err != nil {
+			return fmt.Errorf("failed to add node: %w", err)
+		}
+		return nil
+	})
+
+	var failed error
+	restarted := make([]string, 0, len(names))
+	for _, name := range names {
+		if err := errs[name]; err != nil {
+			results[name].Error = err.Error()
+			failed = fmt.Errorf("failed to restart %q: %w", name, err)
+			continue
+		}
+		restarted = append(restarted, name)
+	}
+
+	for _, name := range restarted {
+		s.applyRestartLocked(plans[name])
+	}
+	if err := s.network.limitNodes(ctx, restarted); err != nil && failed == nil {
+		failed = err
+	}
+	if len(names) > 0 {
+		zap.L().Info("waiting for healthy")
+		if err := s.waitForHealthyLocked(ctx); err != nil {
+			if !errors.Is(err, errAborted) {
+				s.network.transition(stateDegraded, err)
+			}
+			return nil, err
+		}
+	}
+	if failed != nil {
+		s.network.transition(stateDegraded, failed)
+	} else if len(names) > 0 {
+		s.latencies.observe(opRestartNodes, restartStart)
+	}
+	return &rpcpb.RestartNodesResponse{ClusterInfo: s.copyClusterInfo(), Results: results.list(req.Names)}, nil
+}
+
+// deleteNodeInfoLocked removes a node from the cluster info.
+// Must be called with the write lock held.
+func (s *server) deleteNodeInfoLocked(name string) {
+	delete(s.network.nodeInfos, name)
+	s.syncs.remove(name)
+	s.network.nodeNames = make([]string, 0)
+	for name := range s.network.nodeInfos {
+		s.network.nodeNames = append(s.network.nodeNames, name)
+	}
+	s.clusterInfo.NodeNames = s.network.nodeNames
+	s.clusterInfo.NodeInfos = s.network.nodeInfos
+}
+
+func checkNodeNames(names []string) error {
+	if len(names) == 0 {
+		return ErrEmptyNodeNames
+	}
+	seen := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		if name == "" {
+			return ErrEmptyNodeName
+		}
+		if _, ok := seen[name]; ok {
+			return fmt.Errorf("%w: %q", ErrDuplicateNodeName, name)
+		}
+		seen[name] = struct{}{}
+	}
+	return nil
+}
+
+type nodeResults map[string]*rpcpb.NodeResult
+
+func newNodeResults(names []string) nodeResults {
+	results := make(nodeResults, len(names))
+	for _, name := range names {
+		results[name] = &rpcpb.NodeResult{Name: name}
+	}
+	return results
+}
+
+// list returns the results in the request order.
+func (rs nodeResults) list(names []string) []*rpcpb.NodeResult {
+	l := make([]*rpcpb.NodeResult, 0, len(names))
+	for _, name := range names {
+		l = append(l, rs[name])
+	}
+	return l
+}
+
+// runParallel runs "f" for each name, at most "parallelism" at a time
+// (all at once if zero). The names not yet started when the context is
+// done fail with "ErrBatchNodesCanceled".
+func runParallel(ctx context.Context, names []string, parallelism uint32, f func(name string) error) map[string]error {
+	if parallelism == 0 || int(parallelism) > len(names) {
+		parallelism = uint32(len(names))
+	}
+
+	var (
+		mu   sync.Mutex
+		errs = make(map[string]error, len(names))
+		wg   sync.WaitGroup
+		sema = make(chan struct{}, parallelism)
+	)
+	for _, name := range names {
+		select {
+		case sema <- struct{}{}:
+		case <-ctx.Done():
+			mu.Lock()
+			errs[name] = ErrBatchNodesCanceled
+			mu.Unlock()
+			continue
+		}
+		wg.Add(1)
+		go func(name string) {
+			defer func() {
+				<-sema
+				wg.Done()
+			}()
+			err := f(name)
+			mu.Lock()
+			errs[name] = err
+			mu.Unlock()
+		}(name)
+	}
+	wg.Wait()
+	return errs
+}
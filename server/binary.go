@@ -0,0 +1,248 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+)
+
+const binaryCheckTimeout = 10 * time.Second
+
+var (
+	ErrInvalidBinary      = errors.New("invalid node binary")
+	ErrInvalidBinaryDir   = errors.New("invalid binary directory")
+	ErrExecPathNotAllowed = errors.New("exec path not allowed")
+)
+
+// checkExecPath returns an error if the server restricts the exec paths
+// (ref. "Config.ExecDirs"), and the path is not in one of the directories.
+// The symlinks are resolved first, so a link does not leave a directory.
+func (s *server) checkExecPath(path string) error {
+	return s.checkExecDirs(path, false)
+}
+
+// checkBinaryDir is "checkExecPath" for the plugin and build directories,
+// which may also be one of the exec directories.
+func (s *server) checkBinaryDir(dir string) error {
+	return s.checkExecDirs(dir, true)
+}
+
+func (s *server) checkExecDirs(path string, orDir bool) error {
+	if len(s.cfg.ExecDirs) == 0 {
+		return nil
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return ErrNotExists
+		}
+		return fmt.Errorf("%w: %q: %v", ErrExecPathNotAllowed, path, err)
+	}
+	if resolved, err = filepath.Abs(resolved); err != nil {
+		return err
+	}
+	for _, dir := range s.cfg.ExecDirs {
+		if d, err := filepath.EvalSymlinks(dir); err == nil {
+			dir = d
+		}
+		if dir, err = filepath.Abs(dir); err != nil {
+			return err
+		}
+		if strings.HasPrefix(resolved, dir+string(filepath.Separator)) || (orDir && resolved == dir) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %q is outside of the exec directories", ErrExecPathNotAllowed, path)
+}
+
+// binaryDirKeys are the node config keys of the binary directories, with
+// the start request fields that set them.
+var binaryDirKeys = []struct{ key, field string }{
+	{key: "plugin-dir", field: "plugin_dir"},
+	{key: "build-dir", field: "build_dir"},
+}
+
+// checkNodeConfigDirs rejects the binary directories of a node config of
+// the request if the server restricts the exec paths, as they would skip
+// the checks of "binaryDirsConfig".
+func (s *server) checkNodeConfigDirs(field string, cfg map[string]interface{}) error {
+	if len(s.cfg.ExecDirs) == 0 {
+		return nil
+	}
+	for _, d := range binaryDirKeys {
+		if _, ok := cfg[d.key]; ok {
+			return fmt.Errorf("%w: %q of the %s (use the %s)", ErrExecPathNotAllowed, d.key, field, d.field)
+		}
+	}
+	return nil
+}
+
+// binaryDirsConfig returns the node config entries of the plugin and build
+// directories, as absolute paths without symlinks, since the nodes resolve
+// the relative ones from their own working directory. The nodes run the
+// binaries of the directories, so they must be in the exec directories,
+// as the exec paths.
+func (s *server) binaryDirsConfig(pluginDir string, buildDir string) (map[string]interface{}, error) {
+	cfg := make(map[string]interface{})
+	for _, d := range []struct{ key, dir string }{{"plugin-dir", pluginDir}, {"build-dir", buildDir}} {
+		if d.dir == "" {
+			continue
+		}
+		abs, err := filepath.Abs(d.dir)
+		if err != nil {
+			return nil, err
+		}
+		if fi, err := os.Stat(abs); err != nil || !fi.IsDir() {
+			return nil, fmt.Errorf("%w: %s %q is not a directory", ErrInvalidBinaryDir, d.key, d.dir)
+		}
+		resolved, err := filepath.EvalSymlinks(abs)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s %q: %v", ErrInvalidBinaryDir, d.key, d.dir, err)
+		}
+		if err := s.checkBinaryDir(resolved); err != nil {
+			return nil, err
+		}
+		cfg[d.key] = resolved
+	}
+	return cfg, nil
+}
+
+// e.g., "avalanche/1.7.3 [database=v1.4.5, commit=b4e9b8a9e2bd3e2bdd3d2cb8a421fbd777de6ba5]"
+var (
+	versionRegex  = regexp.MustCompile(`^(avalanche|dijets)[a-z]*/(\d+\.\d+\.\d+)`)
+	commitRegex   = regexp.MustCompile(`commit=([0-9a-f]+)`)
+	databaseRegex = regexp.MustCompile(`database=(v[0-9.]+)`)
+)
+
+type binaryCacheKey struct {
+	execPath  string
+	pluginDir string
+	size      int64
+	modTime   time.Time
+}
+
+// binaryChecker caches "--version" results keyed by the binary path and
+// its file stats, so repeated checks do not exec the binary again.
+type binaryChecker struct {
+	mu    sync.Mutex
+	cache map[binaryCacheKey]*rpcpb.CheckBinaryResponse
+}
+
+func newBinaryChecker() *binaryChecker {
+	return &binaryChecker{cache: make(map[binaryCacheKey]*rpcpb.CheckBinaryResponse)}
+}
+
+func (bc *binaryChecker) check(ctx context.Context, execPath string, pluginDir string) *rpcpb.CheckBinaryResponse {
+	if pluginDir == "" {
+		pluginDir = filepath.Join(filepath.Dir(execPath), "plugins")
+	}
+	resp := &rpcpb.CheckBinaryResponse{
+		ExecPath:  execPath,
+		PluginDir: pluginDir,
+	}
+
+	fi, err := os.Stat(execPath)
+	if err != nil {
+		resp.Error = err.Error()
+		return resp
+	}
+	if fi.IsDir() || fi.Mode()&0o111 == 0 {
+		resp.Error = fmt.Sprintf("%q is not an executable file", execPath)
+		return resp
+	}
+
+	key := binaryCacheKey{
+		execPath:  execPath,
+		pluginDir: pluginDir,
+		size:      fi.Size(),
+		modTime:   fi.ModTime(),
+	}
+	bc.mu.Lock()
+	cached, ok := bc.cache[key]
+	bc.mu.Unlock()
+	if ok {
+		cp := proto.Clone(cached).(*rpcpb.CheckBinaryResponse)
+		cp.Cached = true
+		return cp
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, binaryCheckTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, execPath, "--version").CombinedOutput()
+	if err != nil {
+		// e.g., "exec format error" for a binary built for another platform
+		resp.Error = fmt.Sprintf("failed to run %q --version: %v (output %q)", execPath, err, strings.TrimSpace(string(out)))
+		return resp
+	}
+	out = bytes.TrimSpace(out)
+
+	matches := versionRegex.FindSubmatch(out)
+	if matches == nil {
+		resp.Error = fmt.Sprintf("unexpected --version output %q", string(out))
+		return resp
+	}
+	resp.Version = string(matches[2])
+	if m := commitRegex.FindSubmatch(out); m != nil {
+		resp.Commit = string(m[1])
+	}
+	if m := databaseRegex.FindSubmatch(out); m != nil {
+		resp.DatabaseVersion = string(m[1])
+	}
+
+	if pfi, err := os.Stat(pluginDir); err == nil && pfi.IsDir() {
+		entries, err := ioutil.ReadDir(pluginDir)
+		if err != nil {
+			resp.Error = err.Error()
+			return resp
+		}
+		for _, e := range entries {
+			if e.IsDir() || e.Mode()&0o111 == 0 {
+				continue
+			}
+			resp.Plugins = append(resp.Plugins, e.Name())
+		}
+		sort.Strings(resp.Plugins)
+	}
+	resp.Valid = true
+
+	zap.L().Info("checked binary",
+		zap.String("execPath", execPath),
+		zap.String("version", resp.Version),
+		zap.String("commit", resp.Commit),
+		zap.Strings("plugins", resp.Plugins),
+	)
+	bc.mu.Lock()
+	bc.cache[key] = resp
+	bc.mu.Unlock()
+	return resp
+}
+
+func (s *server) CheckBinary(ctx context.Context, req *rpcpb.CheckBinaryRequest) (*rpcpb.CheckBinaryResponse, error) {
+	zap.L().Info("received check binary request", zap.String("execPath", req.ExecPath))
+	if err := s.checkExecPath(req.ExecPath); err != nil {
+		return nil, err
+	}
+	if req.PluginDir != nil {
+		if err := s.checkBinaryDir(req.GetPluginDir()); err != nil {
+			return nil, err
+		}
+	}
+	return s.binaries.check(ctx, req.ExecPath, req.GetPluginDir()), nil
+}
@@ -0,0 +1,148 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+)
+
+func TestCheckExecPath(t *testing.T) {
+	dir := t.TempDir()
+	allowed := filepath.Join(dir, "uploads")
+	other := filepath.Join(dir, "other")
+	for _, d := range []string{allowed, other} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(d, "avalanchego"), nil, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(other, "avalanchego"), filepath.Join(allowed, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &server{}
+	if err := s.checkExecPath(filepath.Join(other, "avalanchego")); err != nil {
+		t.Fatalf("expected any path without exec dirs, got %v", err)
+	}
+	s.cfg.ExecDirs = []string{allowed}
+	for i, tv := range []struct {
+		path string
+		err  error
+	}{
+		{path: filepath.Join(allowed, "avalanchego")},
+		{path: filepath.Join(other, "avalanchego"), err: ErrExecPathNotAllowed},
+		{path: filepath.Join(allowed, "..", "other", "avalanchego"), err: ErrExecPathNotAllowed},
+		// a link out of the directory
+		{path: filepath.Join(allowed, "link"), err: ErrExecPathNotAllowed},
+		{path: allowed, err: ErrExecPathNotAllowed},
+		{path: filepath.Join(allowed, "missing"), err: ErrNotExists},
+	} {
+		if err := s.checkExecPath(tv.path); !errors.Is(err, tv.err) {
+			t.Fatalf("#%d: expected %v, got %v", i, tv.err, err)
+		}
+	}
+}
+
+func TestBinaryDirsConfig(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	allowed := filepath.Join(dir, "uploads")
+	plugins := filepath.Join(allowed, "plugins")
+	other := filepath.Join(dir, "other")
+	for _, d := range []string{plugins, other} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(allowed, "avalanchego"), nil, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(other, filepath.Join(allowed, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &server{}
+	if _, err := s.binaryDirsConfig(other, ""); err != nil {
+		t.Fatalf("expected any directory without exec dirs, got %v", err)
+	}
+	s.cfg.ExecDirs = []string{allowed}
+	for i, tv := range []struct {
+		pluginDir string
+		buildDir  string
+		exp       map[string]interface{}
+		err       error
+	}{
+		{pluginDir: plugins, buildDir: allowed, exp: map[string]interface{}{"plugin-dir": plugins, "build-dir": allowed}},
+		{pluginDir: other, err: ErrExecPathNotAllowed},
+		{buildDir: other, err: ErrExecPathNotAllowed},
+		{pluginDir: filepath.Join(plugins, "..", "..", "other"), err: ErrExecPathNotAllowed},
+		// a link out of the directory
+		{pluginDir: filepath.Join(allowed, "link"), err: ErrExecPathNotAllowed},
+		{pluginDir: filepath.Join(allowed, "avalanchego"), err: ErrInvalidBinaryDir},
+		{pluginDir: filepath.Join(allowed, "missing"), err: ErrInvalidBinaryDir},
+	} {
+		cfg, err := s.binaryDirsConfig(tv.pluginDir, tv.buildDir)
+		if !errors.Is(err, tv.err) {
+			t.Fatalf("#%d: expected %v, got %v", i, tv.err, err)
+		}
+		if tv.err == nil && !reflect.DeepEqual(cfg, tv.exp) {
+			t.Fatalf("#%d: expected %v, got %v", i, tv.exp, cfg)
+		}
+	}
+}
+
+func TestStartBinaryDirsNotAllowed(t *testing.T) {
+	dir := t.TempDir()
+	allowed := filepath.Join(dir, "uploads")
+	other := filepath.Join(dir, "other")
+	for _, d := range []string{allowed, other} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(allowed, "avalanchego"), nil, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	s := &server{cfg: Config{ExecDirs: []string{allowed}}}
+	rootDataDir := filepath.Join(dir, rootDataDirPrefix+"1")
+	for i, tv := range []struct {
+		pluginDir        string
+		globalNodeConfig string
+		err              error
+	}{
+		{pluginDir: allowed},
+		{pluginDir: other, err: ErrExecPathNotAllowed},
+		{globalNodeConfig: `{"plugin-dir":"` + other + `"}`, err: ErrExecPathNotAllowed},
+		{globalNodeConfig: `{"build-dir":"` + allowed + `"}`, err: ErrExecPathNotAllowed},
+		{globalNodeConfig: `{"log-level":"debug"}`},
+	} {
+		req := &rpcpb.StartRequest{ExecPath: filepath.Join(allowed, "avalanchego")}
+		if tv.pluginDir != "" {
+			req.PluginDir = &tv.pluginDir
+		}
+		if tv.globalNodeConfig != "" {
+			req.GlobalNodeConfig = &tv.globalNodeConfig
+		}
+		if _, err := s.resolveNetworkOptions(req, rootDataDir); !errors.Is(err, tv.err) {
+			t.Fatalf("#%d: expected %v, got %v", i, tv.err, err)
+		}
+	}
+
+	// and the listed plugins
+	if _, err := s.CheckBinary(context.Background(), &rpcpb.CheckBinaryRequest{ExecPath: filepath.Join(allowed, "avalanchego"), PluginDir: &other}); !errors.Is(err, ErrExecPathNotAllowed) {
+		t.Fatalf("expected %v, got %v", ErrExecPathNotAllowed, err)
+	}
+}
@@ -0,0 +1,390 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lasthyphen/dijetsnodego/ids"
+	"github.com/lasthyphen/dijetsnodego/utils/formatting"
+	"github.com/lasthyphen/djtx-tester/pkg/jsonrpc"
+	"github.com/lasthyphen/djtx-tester/pkg/randutil"
+	"github.com/lasthyphen/djtx-tester/pkg/testkeys"
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+	"go.uber.org/zap"
+)
+
+const (
+	// time for the chain to be validated by all subnet validators
+	blockchainWait = 2 * time.Minute
+
+	maxVMNameLen = 32
+)
+
+var (
+	ErrEmptySubnetID        = errors.New("empty subnet ID")
+	ErrInvalidVMName        = errors.New("invalid VM name")
+	ErrNoSubnetValidators   = errors.New("no subnet validators in the network")
+	ErrBlockchainNotCreated = errors.New("blockchain not created")
+	ErrSubnetNotWhitelisted = errors.New("subnet not whitelisted")
+)
+
+// CreateBlockchain creates a blockchain on a subnet of the running network.
+// The subnet validators that do not whitelist the subnet are restarted
+// to whitelist it, and the call returns once all of them validate the chain.
+// The genesis key must control the subnet.
+func (s *server) CreateBlockchain(ctx context.Context, req *rpcpb.CreateBlockchainRequest) (*rpcpb.CreateBlockchainResponse, error) {
+	zap.L().Info("received create blockchain request",
+		zap.String("subnetID", req.SubnetId),
+		zap.String("vmName", req.VmName),
+	)
+	if req.SubnetId == "" {
+		return nil, ErrEmptySubnetID
+	}
+	vmID, err := vmIDFromName(req.VmName)
+	if err != nil {
+		return nil, err
+	}
+	chainName := req.VmName
+	if req.ChainName != nil {
+		chainName = req.GetChainName()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkStateLocked(stateHealthy, stateDegraded); err != nil {
+		return nil, err
+	}
+	createStart := time.Now()
+	validators, err := s.subnetValidatorsLocked(ctx, req.SubnetId)
+	if err != nil {
+		return nil, err
+	}
+
+	// the nodes only run the chains of the whitelisted subnets
+	unlisted := make([]string, 0)
+	for _, name := range validators {
+		if !containsSubnet(s.network.nodeInfos[name].WhitelistedSubnets, req.SubnetId) {
+			unlisted = append(unlisted, name)
+		}
+	}
+	if len(unlisted) > 0 {
+		if err := s.whitelistSubnetLocked(ctx, unlisted, req.SubnetId); err != nil {
+			return nil, fmt.Errorf("failed to whitelist the subnet: %w", err)
+		}
+	}
+
+	uri := s.network.nodeInfos[validators[0]].Uri
+	blockchainID, err := issueCreateBlockchain(ctx, uri, s.fundedKey, req.SubnetId, vmID, chainName, req.Genesis)
+	if err != nil {
+		return nil, err
+	}
+	zap.L().Info("created blockchain", zap.String("blockchainID", blockchainID))
+
+	endpoints := make(map[string]string, len(validators))
+	for _, name := range validators {
+		endpoints[name] = s.network.nodeInfos[name].Uri + "/ext/bc/" + blockchainID
+	}
+	if err := waitForValidating(ctx, s.network.nodeInfos, validators, blockchainID); err != nil {
+		return nil, err
+	}
+	s.latencies.observe(opCreateBlockchain, createStart)
+	return &rpcpb.CreateBlockchainResponse{
+		ClusterInfo:    s.copyClusterInfo(),
+		BlockchainId:   blockchainID,
+		VmId:           vmID,
+		Endpoints:      endpoints,
+		RestartedNodes: unlisted,
+	}, nil
+}
+
+// vmIDFromName returns the ID of the VM, as the node derives it from the plugin name.
+func vmIDFromName(name string) (string, error) {
+	if name == "" || len(name) > maxVMNameLen {
+		return "", fmt.Errorf("%w: %q (expected 1 to %d bytes)", ErrInvalidVMName, name, maxVMNameLen)
+	}
+	b := make([]byte, maxVMNameLen)
+	copy(b, name)
+	id, err := ids.ToID(b)
+	if err != nil {
+		return "", err
+	}
+	return id.String(), nil
+}
+
+// subnetValidatorsLocked returns the names of the nodes validating the subnet,
+// sorted by name. Must be called with the lock held.
+func (s *server) subnetValidatorsLocked(ctx context.Context, subnetID string) ([]string, error) {
+	names := make(map[string]string, len(s.network.nodeInfos))
+	uri, uriName := "", ""
+	for name, info := range s.network.nodeInfos {
+		names[info.Id] = name
+		if info.Uri != "" && (uriName == "" || name < uriName) {
+			uri, uriName = info.Uri, name
+		}
+	}
+	if uri == "" {
+		return nil, ErrNoNodeURI
+	}
+
+	// ref. platformvm "GetCurrentValidators"
+	var reply struct {
+		Validators []apiValidator `json:"validators"`
+	}
+	params := map[string]interface{}{"subnetID": subnetID}
+	if err := jsonrpc.Call(ctx, uri, "/ext/bc/P", "platform.getCurrentValidators", params, &reply); err != nil {
+		return nil, err
+	}
+	validators := make([]string, 0, len(reply.Validators))
+	for _, v := range reply.Validators {
+		if name, ok := names[v.NodeID]; ok {
+			validators = append(validators, name)
+		}
+	}
+	if len(validators) == 0 {
+		return nil, fmt.Errorf("%w: %q", ErrNoSubnetValidators, subnetID)
+	}
+	sort.Strings(validators)
+	for _, name := range validators {
+		if s.network.nodeInfos[name].Uri == "" {
+			return nil, fmt.Errorf("%w: %q", ErrNoNodeURI, name)
+		}
+	}
+	return validators, nil
+}
+
+func containsSubnet(whitelistedSubnets string, subnetID string) bool {
+	for _, id := range strings.Split(whitelistedSubnets, ",") {
+		if id == subnetID {
+			return true
+		}
+	}
+	return false
+}
+
+// whitelistSubnetLocked restarts the nodes with the subnet added to their
+// whitelisted subnets, and waits for the network to be healthy.
+// Must be called with the write lock held, which is released during the
+// health wait (ref. "waitForHealthyLocked").
+func (s *server) whitelistSubnetLocked(ctx context.Context, names []string, subnetID string) error {
+	subnets := make(map[string]string, len(names))
+	for _, name := range names {
+		subnets[name] = subnetID
+		if ws := s.network.nodeInfos[name].WhitelistedSubnets; ws != "" {
+			subnets[name] = ws + "," + subnetID
+		}
+	}
+	zap.L().Info("restarting nodes to whitelist the subnet",
+		zap.Strings("names", names),
+		zap.String("subnetID", subnetID),
+	)
+	return s.restartWithSubnetsLocked(ctx, names, subnets)
+}
+
+// StopValidatingSubnet restarts the node with the subnet removed from its
+// whitelisted subnets, so it stops validating the subnet chains but keeps
+// validating the primary network. The node stays a validator of the subnet
+// on the P-chain, as a validator gone offline for the subnet would.
+func (s *server) StopValidatingSubnet(ctx context.Context, req *rpcpb.StopValidatingSubnetRequest) (*rpcpb.StopValidatingSubnetResponse, error) {
+	zap.L().Info("received stop validating subnet request",
+		zap.String("name", req.Name),
+		zap.String("subnetID", req.SubnetId),
+	)
+	if req.Name == "" {
+		return nil, ErrEmptyNodeName
+	}
+	if req.SubnetId == "" {
+		return nil, ErrEmptySubnetID
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkStateLocked(stateHealthy, stateDegraded); err != nil {
+		return nil, err
+	}
+	info, ok := s.network.nodeInfos[req.Name]
+	if !ok {
+		return nil, ErrNodeNotFound
+	}
+	if !containsSubnet(info.WhitelistedSubnets, req.SubnetId) {
+		return nil, fmt.Errorf("%w: %q by %q", ErrSubnetNotWhitelisted, req.SubnetId, req.Name)
+	}
+	kept := make([]string, 0)
+	for _, id := range strings.Split(info.WhitelistedSubnets, ",") {
+		if id != req.SubnetId {
+			kept = append(kept, id)
+		}
+	}
+
+	zap.L().Info("restarting node to stop validating the subnet",
+		zap.String("name", req.Name),
+		zap.String("subnetID", req.SubnetId),
+	)
+	subnets := map[string]string{req.Name: strings.Join(kept, ",")}
+	if err := s.restartWithSubnetsLocked(ctx, []string{req.Name}, subnets); err != nil {
+		return nil, err
+	}
+	return &rpcpb.StopValidatingSubnetResponse{ClusterInfo: s.copyClusterInfo()}, nil
+}
+
+// restartWithSubnetsLocked restarts the nodes with the given whitelisted
+// subnets (by node name), and waits for the network to be healthy.
+// Must be called with the write lock held, which is released during the
+// health wait (ref. "waitForHealthyLocked").
+func (s *server) restartWithSubnetsLocked(ctx context.Context, names []string, subnets map[string]string) error {
+	plans := make([]*restartPlan, 0, len(names))
+	for _, name := range names {
+		ws := subnets[name]
+		plan, err := s.planRestartLocked(name, &rpcpb.StartRequest{
+			ExecPath:           s.network.nodeInfos[name].ExecPath,
+			WhitelistedSubnets: &ws,
+		})
+		if err != nil {
+			return err
+		}
+		plans = append(plans, plan)
+	}
+
+	s.network.transition(stateBootstrapping, nil)
+	for _, plan := range plans {
+		if err := s.network.removeNode(plan.name); err != nil {
+			s.network.transition(stateDegraded, err)
+			return err
+		}
+		if err := s.network.addNode(plan.nodeConfig); err != nil {
+			s.network.transition(stateDegraded, err)
+			return err
+		}
+	}
+	if err := s.waitForHealthyLocked(ctx); err != nil {
+		if !errors.Is(err, errAborted) {
+			s.network.transition(stateDegraded, err)
+		}
+		return err
+	}
+	for _, plan := range plans {
+		s.applyRestartLocked(plan)
+	}
+	if err := s.network.limitNodes(ctx, names); err != nil {
+		s.network.transition(stateDegraded, err)
+		return err
+	}
+	return nil
+}
+
+// issueCreateBlockchain issues the blockchain creation tx with the funded key,
+// from a temporary keystore user of the node, and waits for its commit.
+// The blockchain ID is the tx ID.
+func issueCreateBlockchain(ctx context.Context, uri string, key *testkeys.Key, subnetID string, vmID string, name string, genesis []byte) (string, error) {
+	genesisData, err := formatting.Encode(formatting.Hex, genesis)
+	if err != nil {
+		return "", err
+	}
+
+	user := map[string]string{
+		"username": "network-runner-chains-" + randutil.String(8),
+		"password": randutil.String(32),
+	}
+	if err := jsonrpc.Call(ctx, uri, "/ext/keystore", "keystore.createUser", user, nil); err != nil {
+		return "", err
+	}
+	defer func() {
+		if err := jsonrpc.Call(context.Background(), uri, "/ext/keystore", "keystore.deleteUser", user, nil); err != nil {
+			zap.L().Warn("failed to delete keystore user", zap.String("username", user["username"]), zap.Error(err))
+		}
+	}()
+	importReq := map[string]string{
+		"username":   user["username"],
+		"password":   user["password"],
+		"privateKey": key.String(),
+	}
+	if err := jsonrpc.Call(ctx, uri, "/ext/bc/P", "platform.importKey", importReq, nil); err != nil {
+		return "", err
+	}
+
+	// ref. platformvm "CreateBlockchain"
+	var reply struct {
+		TxID string `json:"txID"`
+	}
+	if err := jsonrpc.Call(ctx, uri, "/ext/bc/P", "platform.createBlockchain", map[string]interface{}{
+		"username":    user["username"],
+		"password":    user["password"],
+		"subnetID":    subnetID,
+		"vmID":        vmID,
+		"name":        name,
+		"genesisData": genesisData,
+		"encoding":    "hex",
+	}, &reply); err != nil {
+		return "", err
+	}
+	if err := waitForPTx(ctx, uri, reply.TxID); err != nil {
+		return "", err
+	}
+	return reply.TxID, nil
+}
+
+func waitForPTx(ctx context.Context, uri string, txID string) error {
+	ctx, cancel := context.WithTimeout(ctx, blockchainWait)
+	defer cancel()
+	for {
+		// ref. platformvm "GetTxStatus"
+		var reply struct {
+			Status string `json:"status"`
+			Reason string `json:"reason"`
+		}
+		if err := jsonrpc.Call(ctx, uri, "/ext/bc/P", "platform.getTxStatus", map[string]string{"txID": txID}, &reply); err != nil {
+			return err
+		}
+		switch reply.Status {
+		case "Committed":
+			return nil
+		case "Aborted", "Dropped":
+			return fmt.Errorf("%w: tx %q %s: %s", ErrBlockchainNotCreated, txID, strings.ToLower(reply.Status), reply.Reason)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+}
+
+// waitForValidating waits for all the nodes to validate the blockchain.
+func waitForValidating(ctx context.Context, infos map[string]*rpcpb.NodeInfo, names []string, blockchainID string) error {
+	ctx, cancel := context.WithTimeout(ctx, blockchainWait)
+	defer cancel()
+	errs := runParallel(ctx, names, 0, func(name string) error {
+		for {
+			// ref. platformvm "GetBlockchainStatus"
+			var reply struct {
+				Status string `json:"status"`
+			}
+			params := map[string]string{"blockchainID": blockchainID}
+			if err := jsonrpc.Call(ctx, infos[name].Uri, "/ext/bc/P", "platform.getBlockchainStatus", params, &reply); err != nil {
+				return err
+			}
+			if reply.Status == "Validating" {
+				return nil
+			}
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("blockchain status %q: %w", reply.Status, ctx.Err())
+			case <-time.After(time.Second):
+			}
+		}
+	})
+	for _, name := range names {
+		if err := errs[name]; err != nil {
+			return fmt.Errorf("%q does not validate the blockchain: %w", name, err)
+		}
+	}
+	return nil
+}
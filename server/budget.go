@@ -0,0 +1,113 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const unhealthyBudgetPollInterval = 5 * time.Second
+
+var ErrUnhealthyBudgetExceeded = errors.New("unhealthy budget exceeded")
+
+// parseMaxUnhealthyDuration returns the max unhealthy duration of the start
+// request, or the server default if not set. Zero disables the budget.
+func parseMaxUnhealthyDuration(v *string, def time.Duration) (time.Duration, error) {
+	if v == nil {
+		return def, nil
+	}
+	d, err := time.ParseDuration(*v)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("%w: invalid max unhealthy duration %q", ErrInvalidDuration, *v)
+	}
+	return d, nil
+}
+
+// enforceUnhealthyBudget polls the node health until the network stops, and
+// fails the network (errored, with the "network_errored" event) once a node
+// stays unhealthy (or unreachable) for longer than the max unhealthy
+// duration. As with the liveness monitor, the time spent outside of the
+// healthy and degraded states (e.g., restarting nodes) does not count.
+func (s *server) enforceUnhealthyBudget(nw *localNetwork) {
+	budget := nw.opts.maxUnhealthyDuration
+	zap.L().Info("enforcing the unhealthy budget", zap.Duration("maxUnhealthyDuration", budget))
+
+	unhealthySince := make(map[string]time.Time)
+	tc := time.NewTicker(unhealthyBudgetPollInterval)
+	defer tc.Stop()
+	for {
+		select {
+		case <-nw.stopCtx.Done():
+			return
+		case <-tc.C:
+		}
+
+		s.mu.RLock()
+		if s.network != nw {
+			s.mu.RUnlock()
+			return
+		}
+		state, _ := nw.lifecycle.get()
+		uris := make(map[string]string, len(nw.nodeInfos))
+		for name, info := range nw.nodeInfos {
+			if info.Uri != "" {
+				uris[name] = info.Uri
+			}
+		}
+		s.mu.RUnlock()
+		if state != stateHealthy && state != stateDegraded {
+			unhealthySince = make(map[string]time.Time)
+			continue
+		}
+
+		for name := range unhealthySince {
+			if _, ok := uris[name]; !ok {
+				delete(unhealthySince, name)
+			}
+		}
+		var exceeded error
+		for name, uri := range uris {
+			healthy, err := pollNodeHealth(nw.stopCtx, uri)
+			if nw.stopCtx.Err() != nil {
+				return
+			}
+			if healthy {
+				delete(unhealthySince, name)
+				continue
+			}
+			now := time.Now()
+			since, ok := unhealthySince[name]
+			if !ok {
+				unhealthySince[name] = now
+				continue
+			}
+			if d := now.Sub(since); d > budget {
+				reason := "not healthy"
+				if err != nil {
+					reason = err.Error()
+				}
+				exceeded = fmt.Errorf("%w: node %q unhealthy for %v (max %v): %s", ErrUnhealthyBudgetExceeded, name, d.Round(time.Second), budget, reason)
+				break
+			}
+		}
+		if exceeded == nil {
+			continue
+		}
+
+		zap.L().Warn("failing the network", zap.Error(exceeded))
+		s.mu.Lock()
+		if s.network != nw {
+			s.mu.Unlock()
+			return
+		}
+		nw.transition(stateErrored, exceeded)
+		s.mu.Unlock()
+		nw.collectArtifacts()
+		return
+	}
+}
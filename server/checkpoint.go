@@ -0,0 +1,413 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const (
+	defaultCheckpointRetention = 3
+
+	checkpointsDir        = "checkpoints"
+	checkpointInfoFile    = "cluster-info.json"
+	checkpointPartial     = ".partial"
+	checkpointTimeLayout  = "20060102T150405Z"
+	checkpointDBDirectory = "db-dir"
+	// table files of the database file system, copied once the nodes resume
+	checkpointStaging = ".checkpoint-staging"
+)
+
+var ErrInvalidCheckpoint = errors.New("invalid checkpoint")
+
+type checkpointOptions struct {
+	interval time.Duration
+	// number of checkpoints kept, the oldest removed first
+	retention int
+}
+
+// parseCheckpoints returns the checkpoint options of the start request, nil
+// without an interval.
+func parseCheckpoints(interval *string, retention *uint32) (*checkpointOptions, error) {
+	if interval == nil {
+		if retention != nil {
+			return nil, fmt.Errorf("%w: retention without an interval", ErrInvalidCheckpoint)
+		}
+		return nil, nil
+	}
+	d, err := time.ParseDuration(*interval)
+	if err != nil || d <= 0 {
+		return nil, fmt.Errorf("%w: invalid checkpoint interval %q", ErrInvalidDuration, *interval)
+	}
+	opts := &checkpointOptions{interval: d, retention: defaultCheckpointRetention}
+	if retention != nil {
+		if *retention == 0 {
+			return nil, fmt.Errorf("%w: zero retention", ErrInvalidCheckpoint)
+		}
+		opts.retention = int(*retention)
+	}
+	return opts, nil
+}
+
+// checkCheckpoint checks that the directory is a checkpoint to resume from.
+func checkCheckpoint(dir string) error {
+	if _, err := os.Stat(filepath.Join(dir, checkpointInfoFile)); err != nil {
+		return fmt.Errorf("%w: %q is not a checkpoint: %v", ErrInvalidCheckpoint, dir, err)
+	}
+	return nil
+}
+
+// runCheckpoints checkpoints the network every interval while it is healthy
+// or degraded, until it stops, and keeps the latest checkpoints in the
+// cluster info.
+func (s *server) runCheckpoints(nw *localNetwork) {
+	opts := nw.opts.checkpoints
+	zap.L().Info("checkpointing the network",
+		zap.Duration("interval", opts.interval),
+		zap.Int("retention", opts.retention),
+	)
+	dir := filepath.Join(nw.opts.rootDataDir, checkpointsDir)
+	tc := time.NewTicker(opts.interval)
+	defer tc.Stop()
+	for {
+		select {
+		case <-nw.stopCtx.Done():
+			return
+		case <-tc.C:
+		}
+
+		cp, err := s.checkpoint(nw, dir)
+		if errors.Is(err, errAborted) {
+			return
+		}
+		if err != nil {
+			zap.L().Warn("failed to checkpoint the network", zap.Error(err))
+			continue
+		}
+		if cp == nil {
+			continue
+		}
+		zap.L().Info("checkpointed the network", zap.String("path", cp.Path), zap.String("pause", cp.Pause))
+		cps, err := rotateCheckpoints(dir, opts.retention)
+		if err != nil {
+			zap.L().Warn("failed to rotate the checkpoints", zap.String("dir", dir), zap.Error(err))
+		}
+		if n := len(cps); n > 0 && cps[n-1].Path == cp.Path {
+			cps[n-1] = cp
+		}
+		s.mu.Lock()
+		if s.network == nw {
+			s.clusterInfo.Checkpoints = cps
+		}
+		s.mu.Unlock()
+	}
+}
+
+// checkpoint pauses the node processes (SIGSTOP), copies their database
+// directories, and resumes them, so each copy is consistent with itself and
+// with the others. The immutable table files are hard-linked rather than
+// copied, which keeps the pause short. The table files on another file
+// system than the checkpoint (e.g., of a disk device) are hard-linked into
+// a staging directory of their own file system instead, and copied once the
+// nodes resume, without the lock. The lock is held (read) during the pause,
+// so the nodes are not restarted or stopped while paused. Returns nil if
+// the network is neither healthy nor degraded.
+func (s *server) checkpoint(nw *localNetwork, dir string) (*rpcpb.Checkpoint, error) {
+	now := time.Now().UTC()
+	path := filepath.Join(dir, now.Format(checkpointTimeLayout))
+	partial := path + checkpointPartial
+	if err := os.MkdirAll(partial, 0o755); err != nil {
+		return nil, err
+	}
+	done := false
+	defer func() {
+		if !done {
+			os.RemoveAll(partial)
+		}
+	}()
+
+	s.mu.RLock()
+	if s.network != nw {
+		s.mu.RUnlock()
+		return nil, errAborted
+	}
+	if state, _ := nw.lifecycle.get(); state != stateHealthy && state != stateDegraded {
+		s.mu.RUnlock()
+		return nil, nil
+	}
+	info := s.copyClusterInfo()
+	pause, staged, err := pauseAndCopy(nw.nodeProcesses(), info.NodeInfos, partial)
+	s.mu.RUnlock()
+	if err == nil {
+		err = copyStaged(staged)
+	}
+	removeStaging(staged)
+	if err != nil {
+		return nil, err
+	}
+
+	cp := &rpcpb.Checkpoint{
+		Path:  path,
+		Time:  now.Format(time.RFC3339),
+		Pause: pause.String(),
+	}
+	// the checkpoint itself is not in its cluster info
+	b, err := protojson.MarshalOptions{Multiline: true}.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(filepath.Join(partial, checkpointInfoFile), b, 0o644); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(partial, path); err != nil {
+		return nil, err
+	}
+	done = true
+	return cp, nil
+}
+
+// pauseAndCopy pauses the processes, copies the database directory of
+// each node into its directory under "dst", and resumes them. The nodes
+// without a process (e.g., crashed) are left out. Returns how long the
+// nodes were paused, and the staged table files to copy (ref.
+// "checkpointDBDir"), even on failure, for their staging to be removed.
+func pauseAndCopy(procs map[string]nodeProcess, infos map[string]*rpcpb.NodeInfo, dst string) (time.Duration, []stagedFile, error) {
+	pids := make(map[string]int, len(procs))
+	for name, proc := range procs {
+		pid, err := findNodePID(proc.execPath, proc.logDir)
+		if errors.Is(err, ErrFaultUnsupported) {
+			return 0, nil, err
+		}
+		if err != nil {
+			zap.L().Warn("node left out of the checkpoint", zap.String("name", name), zap.Error(err))
+			continue
+		}
+		pids[name] = pid
+	}
+
+	start := time.Now()
+	defer func() {
+		for name, pid := range pids {
+			if err := pauseProcess(pid, false); err != nil {
+				zap.L().Warn("failed to resume the node", zap.String("name", name), zap.Int("pid", pid), zap.Error(err))
+			}
+		}
+	}()
+	for name, pid := range pids {
+		if err := pauseProcess(pid, true); err != nil {
+			return 0, nil, fmt.Errorf("failed to pause %q: %w", name, err)
+		}
+	}
+	var staged []stagedFile
+	for name := range pids {
+		files, err := checkpointDBDir(infos[name].DbDir, filepath.Join(dst, name, checkpointDBDirectory))
+		staged = append(staged, files...)
+		if err != nil {
+			return 0, staged, fmt.Errorf("failed to copy the database of %q: %w", name, err)
+		}
+	}
+	return time.Since(start), staged, nil
+}
+
+// stagedFile is a table file of a checkpoint, hard-linked into the staging
+// directory of its database, to copy once the node resumes.
+type stagedFile struct {
+	staging string
+	src     string
+	dst     string
+	fi      os.FileInfo
+}
+
+// checkpointDBDir is "copyDBDir" for a paused node. The table files that
+// cannot be hard-linked into the checkpoint (e.g., on a disk device) are
+// hard-linked into the staging directory of the database instead, which
+// keeps them once the node resumes and compacts them away, and returned to
+// be copied then. Those that cannot be linked at all are copied.
+func checkpointDBDir(src string, dst string) ([]stagedFile, error) {
+	staging := filepath.Join(src, checkpointStaging)
+	// left by a failed checkpoint
+	if err := os.RemoveAll(staging); err != nil {
+		return nil, err
+	}
+	var staged []stagedFile
+	err := filepath.Walk(src, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == staging {
+			return filepath.SkipDir
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if fi.IsDir() {
+			return os.MkdirAll(target, 0o755)
+		}
+		if !fi.Mode().IsRegular() {
+			return nil
+		}
+		if ext := filepath.Ext(p); ext == ".ldb" || ext == ".sst" {
+			if err := os.Link(p, target); err == nil {
+				return nil
+			}
+			link := filepath.Join(staging, rel)
+			if err := os.MkdirAll(filepath.Dir(link), 0o755); err != nil {
+				return err
+			}
+			if err := os.Link(p, link); err == nil {
+				staged = append(staged, stagedFile{staging: staging, src: link, dst: target, fi: fi})
+				return nil
+			}
+		}
+		return copyFile(p, target, fi)
+	})
+	return staged, err
+}
+
+// copyStaged copies the staged table files into the checkpoint.
+func copyStaged(staged []stagedFile) error {
+	for _, f := range staged {
+		if err := copyFile(f.src, f.dst, f.fi); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeStaging removes the staging directories of the files.
+func removeStaging(staged []stagedFile) {
+	removed := make(map[string]bool)
+	for _, f := range staged {
+		if removed[f.staging] {
+			continue
+		}
+		removed[f.staging] = true
+		if err := os.RemoveAll(f.staging); err != nil {
+			zap.L().Warn("failed to remove the checkpoint staging", zap.String("dir", f.staging), zap.Error(err))
+		}
+	}
+}
+
+// copyDBDir copies the database directory, hard-linking the table files
+// (of leveldb, ".ldb", and of pebble or rocksdb, ".sst"), which are never
+// modified once written, only removed. The other files (e.g., the write
+// ahead log and the manifest) are copied.
+func copyDBDir(src string, dst string) error {
+	return filepath.Walk(src, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if fi.IsDir() {
+			return os.MkdirAll(target, 0o755)
+		}
+		// e.g., skip the sockets
+		if !fi.Mode().IsRegular() {
+			return nil
+		}
+		if ext := filepath.Ext(p); ext == ".ldb" || ext == ".sst" {
+			// copied if on another file system (e.g., a disk device)
+			if err := os.Link(p, target); err == nil {
+				return nil
+			}
+		}
+		return copyFile(p, target, fi)
+	})
+}
+
+func copyFile(src string, dst string, fi os.FileInfo) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fi.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// rotateCheckpoints removes the oldest checkpoints of the directory beyond
+// the retention (and the partial ones of a failed checkpoint), and returns
+// the ones kept, the oldest first.
+func rotateCheckpoints(dir string, retention int) ([]*rpcpb.Checkpoint, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		if strings.HasSuffix(e.Name(), checkpointPartial) {
+			os.RemoveAll(filepath.Join(dir, e.Name()))
+			continue
+		}
+		names = append(names, e.Name())
+	}
+	// by time, as named
+	sort.Strings(names)
+	var errs []string
+	for len(names) > retention {
+		if err := os.RemoveAll(filepath.Join(dir, names[0])); err != nil {
+			errs = append(errs, err.Error())
+		}
+		names = names[1:]
+	}
+
+	cps := make([]*rpcpb.Checkpoint, 0, len(names))
+	for _, name := range names {
+		cp := &rpcpb.Checkpoint{Path: filepath.Join(dir, name)}
+		if t, err := time.Parse(checkpointTimeLayout, name); err == nil {
+			cp.Time = t.Format(time.RFC3339)
+		}
+		cps = append(cps, cp)
+	}
+	if len(errs) > 0 {
+		return cps, errors.New(strings.Join(errs, "; "))
+	}
+	return cps, nil
+}
+
+// resumeCheckpoint copies the database directories of the checkpoint into
+// the nodes of the same name, before they are launched. The nodes missing
+// from the checkpoint start from genesis.
+func (lc *localNetwork) resumeCheckpoint(dir string) error {
+	for _, name := range lc.nodeNames {
+		src := filepath.Join(dir, name, checkpointDBDirectory)
+		if _, err := os.Stat(src); err != nil {
+			zap.L().Warn("node not in the checkpoint, starting from genesis", zap.String("name", name), zap.String("checkpoint", dir))
+			continue
+		}
+		if err := copyDBDir(src, lc.nodeInfos[name].DbDir); err != nil {
+			return fmt.Errorf("failed to resume %q from %q: %w", name, dir, err)
+		}
+	}
+	zap.L().Info("resumed the databases from the checkpoint", zap.String("checkpoint", dir))
+	return nil
+}
@@ -0,0 +1,95 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckpointDBDir(t *testing.T) {
+	dir := t.TempDir()
+	src, dst := filepath.Join(dir, "db"), filepath.Join(dir, "checkpoint")
+	for name, data := range map[string]string{
+		"v1.4.5/000001.ldb":                 "table",
+		"v1.4.5/MANIFEST-000002":            "manifest",
+		checkpointStaging + "/v1.4.5/0.ldb": "leftover",
+	} {
+		p := filepath.Join(src, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	staged, err := checkpointDBDir(src, dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// same file system, nothing to copy after resuming
+	if len(staged) != 0 {
+		t.Fatalf("expected no staged files, got %d", len(staged))
+	}
+	for i, tv := range []struct {
+		name   string
+		linked bool
+	}{
+		{name: "v1.4.5/000001.ldb", linked: true},
+		{name: "v1.4.5/MANIFEST-000002"},
+	} {
+		sfi, err := os.Stat(filepath.Join(src, tv.name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		dfi, err := os.Stat(filepath.Join(dst, tv.name))
+		if err != nil {
+			t.Fatalf("#%d: %v", i, err)
+		}
+		if os.SameFile(sfi, dfi) != tv.linked {
+			t.Fatalf("#%d: expected linked %v, got %v", i, tv.linked, !tv.linked)
+		}
+	}
+	for _, p := range []string{filepath.Join(src, checkpointStaging), filepath.Join(dst, checkpointStaging)} {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Fatalf("expected %q removed, got %v", p, err)
+		}
+	}
+}
+
+func TestCopyStaged(t *testing.T) {
+	dir := t.TempDir()
+	staging := filepath.Join(dir, "db", checkpointStaging)
+	if err := os.MkdirAll(staging, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	src := filepath.Join(staging, "000001.ldb")
+	if err := ioutil.WriteFile(src, []byte("table"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst := filepath.Join(dir, "000001.ldb")
+	staged := []stagedFile{{staging: staging, src: src, dst: dst, fi: fi}}
+	if err := copyStaged(staged); err != nil {
+		t.Fatal(err)
+	}
+	removeStaging(staged)
+
+	b, err := ioutil.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "table" {
+		t.Fatalf("expected %q, got %q", "table", b)
+	}
+	if _, err := os.Stat(staging); !os.IsNotExist(err) {
+		t.Fatalf("expected the staging removed, got %v", err)
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lasthyphen/djtx-tester/pkg/jsonrpc"
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+	"go.uber.org/zap"
+)
+
+// JSON-RPC "method not found"
+const methodNotFoundCode = -32601
+
+var (
+	ErrInvalidTime             = errors.New("invalid time")
+	ErrInvalidDuration         = errors.New("invalid duration")
+	ErrClockControlUnsupported = errors.New("node build does not support clock control")
+)
+
+// SetTime sets the clock of every node (e.g., the proposervm and P-chain
+// clocks), so block timestamps follow the given time. Only the test builds
+// of the node expose the clock APIs, and a restarted node resets its clock.
+func (s *server) SetTime(ctx context.Context, req *rpcpb.SetTimeRequest) (*rpcpb.SetTimeResponse, error) {
+	zap.L().Info("received set time request", zap.Int64("time", req.Time))
+	if req.Time <= 0 {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidTime, req.Time)
+	}
+
+	times, err := s.callClocks(ctx, "admin.setTime", map[string]interface{}{"time": req.Time})
+	if err != nil {
+		return nil, err
+	}
+	return &rpcpb.SetTimeResponse{ClusterInfo: s.getClusterInfo(), NodeTimes: times}, nil
+}
+
+// AdvanceTime moves the clock of every node forward by the duration.
+func (s *server) AdvanceTime(ctx context.Context, req *rpcpb.AdvanceTimeRequest) (*rpcpb.AdvanceTimeResponse, error) {
+	zap.L().Info("received advance time request", zap.String("duration", req.Duration))
+	d, err := time.ParseDuration(req.Duration)
+	if err != nil || d <= 0 {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidDuration, req.Duration)
+	}
+
+	times, err := s.callClocks(ctx, "admin.advanceTime", map[string]interface{}{"duration": d.String()})
+	if err != nil {
+		return nil, err
+	}
+	return &rpcpb.AdvanceTimeResponse{ClusterInfo: s.getClusterInfo(), NodeTimes: times}, nil
+}
+
+// callClocks calls the admin clock method on all nodes at once, to keep
+// the clocks close, and returns the resulting node clocks.
+func (s *server) callClocks(ctx context.Context, method string, params interface{}) (map[string]int64, error) {
+	s.mu.RLock()
+	if err := s.checkStateLocked(stateHealthy, stateDegraded); err != nil {
+		s.mu.RUnlock()
+		return nil, err
+	}
+	uris := make(map[string]string, len(s.network.nodeInfos))
+	for name, info := range s.network.nodeInfos {
+		if info.Uri == "" {
+			s.mu.RUnlock()
+			return nil, fmt.Errorf("%w: %q", ErrNoNodeURI, name)
+		}
+		uris[name] = info.Uri
+	}
+	s.mu.RUnlock()
+
+	var (
+		mu    sync.Mutex
+		times = make(map[string]int64, len(uris))
+	)
+	names := make([]string, 0, len(uris))
+	for name := range uris {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	errs := runParallel(ctx, names, 0, func(name string) error {
+		var reply struct {
+			// unix seconds
+			Time int64 `json:"time,string"`
+		}
+		if err := jsonrpc.Call(ctx, uris[name], "/ext/admin", method, params, &reply); err != nil {
+			var jerr *jsonrpc.Error
+			if errors.As(err, &jerr) && jerr.Code == methodNotFoundCode {
+				return ErrClockControlUnsupported
+			}
+			return err
+		}
+		mu.Lock()
+		times[name] = reply.Time
+		mu.Unlock()
+		return nil
+	})
+
+	failed := make([]string, 0)
+	for _, name := range names {
+		if err := errs[name]; err != nil {
+			if errors.Is(err, ErrClockControlUnsupported) {
+				return nil, fmt.Errorf("%w: %q", err, name)
+			}
+			failed = append(failed, fmt.Sprintf("%s: %v", name, err))
+		}
+	}
+	if len(failed) > 0 {
+		// the clocks may now disagree, so the caller should retry or restart
+		return nil, fmt.Errorf("failed to call %s on %d node(s): %s", method, len(failed), strings.Join(failed, "; "))
+	}
+	zap.L().Info("updated node clocks", zap.String("method", method), zap.Any("times", times))
+	return times, nil
+}
@@ -0,0 +1,181 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var ErrNetworkUnhealthy = errors.New("network unhealthy")
+
+// gRPC codes of the server errors, so the clients (e.g., the control CLI
+// exit codes) and the gateway HTTP statuses can tell the failures apart
+var errorCodes = []struct {
+	code codes.Code
+	errs []error
+}{
+	{codes.InvalidArgument, []error{
+		ErrEmptyExecPath,
+		ErrInvalidSubnetID,
+		ErrInvalidID,
+		ErrEmptyNodeNames,
+		ErrDuplicateNodeName,
+		ErrEmptyNodeName,
+		ErrInvalidBinary,
+		ErrInvalidBinaryDir,
+		ErrEmptySubnetID,
+		ErrInvalidVMName,
+		ErrEmptyBlockchainID,
+		ErrInvalidTime,
+		ErrInvalidDuration,
+		ErrInvalidNodeConfig,
+		ErrFaucetInvalidChain,
+		ErrFaucetInvalidAddress,
+		ErrInvalidCrashMode,
+		ErrInvalidGCPolicy,
+		ErrInvalidGenesisBalance,
+		ErrIdempotencyKeyReused,
+		ErrInvalidResourceLimits,
+		ErrInvalidDatabaseConfig,
+		ErrInvalidDiskFault,
+		ErrInvalidCheckpoint,
+		ErrInvalidLivenessMonitor,
+		ErrInvalidHealthPolling,
+		ErrInvalidLogDownload,
+		ErrInvalidLogSearch,
+		ErrInvalidLogSink,
+		ErrInvalidNumNodes,
+		ErrInvalidBootstrapBeacons,
+		ErrInvalidBootstrapPeers,
+		ErrInvalidBasePort,
+		ErrEmptyPresetName,
+		ErrEmptyTemplateName,
+		ErrInvalidTemplate,
+		ErrInvalidTemplateParams,
+		ErrInvalidSeedAction,
+		ErrInvalidSidecar,
+		ErrInvalidStakingParams,
+		ErrInvalidTopologyFormat,
+		ErrInvalidTrafficDirection,
+		ErrSameNode,
+		ErrInvalidUploadPath,
+		ErrInvalidArtifactsPath,
+		ErrChecksumMismatch,
+	}},
+	{codes.NotFound, []error{
+		ErrNodeNotFound,
+		ErrChainIPCNotFound,
+		ErrPresetNotFound,
+		ErrTemplateNotFound,
+		ErrNotExists,
+	}},
+	{codes.FailedPrecondition, []error{
+		ErrAlreadyBootstrapped,
+		ErrNotBootstrapped,
+		ErrNoStartInProgress,
+		ErrInvalidState,
+		ErrInvalidTransition,
+		ErrNoSubnetValidators,
+		ErrBlockchainNotCreated,
+		ErrSupervisedCgroups,
+		ErrSubnetNotWhitelisted,
+		ErrNoDiskDevice,
+		ErrConsoleStdin,
+		ErrAdoptedNetwork,
+	}},
+	{codes.PermissionDenied, []error{
+		ErrExecPathNotAllowed,
+		ErrConsoleStdinDenied,
+	}},
+	{codes.Unimplemented, []error{
+		ErrFaultUnsupported,
+		ErrResourceLimitsUnsupported,
+		ErrTrafficRulesUnsupported,
+		ErrDiskFaultsUnsupported,
+		ErrHostResourcesUnsupported,
+		ErrOrphansUnsupported,
+		ErrClockControlUnsupported,
+		ErrSupervisorUnsupported,
+	}},
+	{codes.ResourceExhausted, []error{
+		ErrInsufficientResources,
+		ErrTenantLimitReached,
+		ErrServerBusy,
+		ErrFaucetRateLimited,
+	}},
+	{codes.Unavailable, []error{
+		ErrNetworkUnhealthy,
+		ErrNodesUnhealthy,
+		ErrNoNodeURI,
+		ErrFaucetNoHealthyNode,
+		ErrClosed,
+	}},
+	{codes.Aborted, []error{
+		errAborted,
+	}},
+	{codes.Canceled, []error{
+		ErrBatchNodesCanceled,
+		context.Canceled,
+	}},
+	{codes.DeadlineExceeded, []error{
+		context.DeadlineExceeded,
+	}},
+}
+
+// toStatus returns the error with the gRPC code of its server error,
+// keeping its message. The errors with a status (e.g., of a node
+// supervisor call) are kept as they are. The invalid request fields are
+// set as the "BadRequest" details, with their path.
+func toStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	var fe *fieldError
+	isField := errors.As(err, &fe)
+	for _, c := range errorCodes {
+		for _, e := range c.errs {
+			if errors.Is(err, e) {
+				return withFieldViolation(status.New(c.code, err.Error()), fe)
+			}
+		}
+	}
+	if isField {
+		return withFieldViolation(status.New(codes.InvalidArgument, err.Error()), fe)
+	}
+	return err
+}
+
+func withFieldViolation(st *status.Status, fe *fieldError) error {
+	if fe == nil {
+		return st.Err()
+	}
+	detailed, err := st.WithDetails(&errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{{
+			Field:       fe.path,
+			Description: fe.err.Error(),
+		}},
+	})
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+func unaryStatusInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	resp, err := handler(ctx, req)
+	return resp, toStatus(err)
+}
+
+func streamStatusInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return toStatus(handler(srv, ss))
+}
@@ -0,0 +1,169 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lasthyphen/dijetsnodego/config"
+	"github.com/lasthyphen/dijetsnodego/ids"
+)
+
+var ErrInvalidNodeConfig = errors.New("invalid node config")
+
+var (
+	nodeFlagsOnce sync.Once
+	nodeFlags     *flag.FlagSet
+)
+
+// nodeFlagSet returns the flags of the node, whose names are the config file keys.
+func nodeFlagSet() *flag.FlagSet {
+	nodeFlagsOnce.Do(func() {
+		nodeFlags = config.BuildFlagSet()
+	})
+	return nodeFlags
+}
+
+// validateNodeConfig checks the node config against the node flags,
+// so that unknown keys or mistyped values fail the request instead of
+// crashing the node at launch. All of the invalid fields are reported.
+func validateNodeConfig(cfg map[string]interface{}) error {
+	fs := nodeFlagSet()
+	keys := make([]string, 0, len(cfg))
+	for k := range cfg {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	errs := make([]string, 0)
+	for _, k := range keys {
+		if managed, ok := runnerFlags[k]; ok {
+			errs = append(errs, fmt.Sprintf("%q: set by the runner (use the %s)", k, managed))
+			continue
+		}
+		f := fs.Lookup(k)
+		if f == nil {
+			errs = append(errs, fmt.Sprintf("%q: unknown key", k))
+			continue
+		}
+		if err := validateFlagValue(f, cfg[k]); err != nil {
+			errs = append(errs, fmt.Sprintf("%q: %v", k, err))
+		}
+	}
+	if v, ok := cfg["whitelisted-subnets"].(string); ok {
+		if err := validateSubnetIDs(v); err != nil {
+			errs = append(errs, fmt.Sprintf("%q: %v", "whitelisted-subnets", err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidNodeConfig, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// validateFlagValue checks the value type against the flag type,
+// accepting the string forms that the node config parser accepts.
+func validateFlagValue(f *flag.Flag, v interface{}) error {
+	// normalized to the JSON types, whichever config the value came from
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	if err := d.Decode(&v); err != nil {
+		return err
+	}
+
+	getter, ok := f.Value.(flag.Getter)
+	if !ok {
+		return nil
+	}
+	switch getter.Get().(type) {
+	case bool:
+		switch tv := v.(type) {
+		case bool:
+			return nil
+		case string:
+			if _, err := strconv.ParseBool(tv); err == nil {
+				return nil
+			}
+		}
+		return fmt.Errorf("expected a boolean, got %s", b)
+
+	case time.Duration:
+		switch tv := v.(type) {
+		case string:
+			if _, err := time.ParseDuration(tv); err == nil {
+				return nil
+			}
+		case json.Number:
+			// in nanoseconds
+			if _, err := tv.Int64(); err == nil {
+				return nil
+			}
+		}
+		return fmt.Errorf("expected a duration (e.g., \"1s\"), got %s", b)
+
+	case int, int64:
+		if _, err := strconv.ParseInt(numberString(v), 10, 64); err != nil {
+			return fmt.Errorf("expected an integer, got %s", b)
+		}
+		return nil
+
+	case uint, uint64:
+		if _, err := strconv.ParseUint(numberString(v), 10, 64); err != nil {
+			return fmt.Errorf("expected a non-negative integer, got %s", b)
+		}
+		return nil
+
+	case float64:
+		if _, err := strconv.ParseFloat(numberString(v), 64); err != nil {
+			return fmt.Errorf("expected a number, got %s", b)
+		}
+		return nil
+
+	case string:
+		switch v.(type) {
+		case string, json.Number, bool:
+			return nil
+		}
+		return fmt.Errorf("expected a string, got %s", b)
+	}
+	return nil
+}
+
+// numberString returns the JSON number, or the string holding it.
+func numberString(v interface{}) string {
+	switch tv := v.(type) {
+	case json.Number:
+		return tv.String()
+	case string:
+		return tv
+	}
+	return ""
+}
+
+// validateSubnetIDs checks the comma-separated subnet IDs,
+// skipping the empty ones as the node does.
+func validateSubnetIDs(s string) error {
+	for _, id := range strings.Split(s, ",") {
+		if id == "" {
+			continue
+		}
+		if _, err := ids.FromString(id); err != nil {
+			return fmt.Errorf("%w %q: %v", ErrInvalidSubnetID, id, err)
+		}
+	}
+	return nil
+}
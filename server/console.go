@@ -0,0 +1,171 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+	"go.uber.org/zap"
+)
+
+const (
+	consoleBufferSize = 1024
+
+	// FIFO of the node directory, read by the node as its stdin
+	stdinFifo         = "stdin"
+	stdinWriteTimeout = 10 * time.Second
+)
+
+var (
+	ErrEmptyNodeName      = errors.New("empty node name")
+	ErrConsoleStdin       = errors.New("console stdin unavailable")
+	ErrConsoleStdinDenied = errors.New("console stdin requires the operator role")
+)
+
+// stdinScript returns the launcher lines that create the stdin FIFO of the
+// node, and open it as the file descriptor. The FIFO is opened for reading
+// and writing, so the launcher does not wait for a writer, and the node
+// does not read the end of its input once a console detaches.
+func stdinScript(nodeDir string, fd int) string {
+	fifo := shellQuote(filepath.Join(nodeDir, stdinFifo))
+	return "rm -f " + fifo + "\n" +
+		"mkfifo -m 600 " + fifo + "\n" +
+		"exec " + strconv.Itoa(fd) + "<>" + fifo + "\n"
+}
+
+// openStdin opens the stdin FIFO of the node for writing. It fails at once
+// if the node does not run (no reader), rather than waiting for one.
+func openStdin(nodeDir string) (*os.File, error) {
+	f, err := os.OpenFile(filepath.Join(nodeDir, stdinFifo), os.O_WRONLY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrConsoleStdin, err)
+	}
+	return f, nil
+}
+
+// AttachConsole streams the stdout and stderr lines of a node, and writes
+// the stdin chunks sent by the client to the stdin of the node, through
+// the FIFO its launcher opened as its stdin (ref. "stdinScript"). The
+// sidecars are launched without stdin. With tenancy enabled, the viewer
+// tokens only read the outputs, the stdin requires the operator role.
+func (s *server) AttachConsole(stream rpcpb.ControlService_AttachConsoleServer) error {
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	zap.L().Info("received attach console request", zap.String("name", first.NodeName))
+	if first.NodeName == "" {
+		return ErrEmptyNodeName
+	}
+
+	s.mu.RLock()
+	if err := s.checkStateLocked(stateBootstrapping, stateHealthy, stateDegraded); err != nil {
+		s.mu.RUnlock()
+		return err
+	}
+	if err := s.checkOwnerLocked(stream.Context()); err != nil {
+		s.mu.RUnlock()
+		return err
+	}
+	writers, ok := s.network.writers[first.NodeName]
+	nodeDir := ""
+	if ni, isNode := s.network.nodeInfos[first.NodeName]; isNode {
+		nodeDir = filepath.Dir(ni.DbDir)
+	}
+	s.mu.RUnlock()
+	if !ok {
+		return ErrNodeNotFound
+	}
+
+	var stdin *os.File
+	defer func() {
+		if stdin != nil {
+			stdin.Close()
+		}
+	}()
+	writeStdin := func(b []byte) error {
+		if t := tenantFromContext(stream.Context()); t != nil && !t.allows(RoleOperator) {
+			return fmt.Errorf("%w: the tenant %q token is %s", ErrConsoleStdinDenied, t.Name, t.Role)
+		}
+		if nodeDir == "" {
+			return fmt.Errorf("%w: sidecar %q has no stdin", ErrConsoleStdin, first.NodeName)
+		}
+		if stdin == nil {
+			f, err := openStdin(nodeDir)
+			if err != nil {
+				return err
+			}
+			stdin = f
+		}
+		if err := stdin.SetWriteDeadline(time.Now().Add(stdinWriteTimeout)); err != nil {
+			zap.L().Debug("stdin write deadline unsupported", zap.Error(err))
+		}
+		// fails once the node exits (e.g., restarted on a new FIFO)
+		if _, err := stdin.Write(b); err != nil {
+			return fmt.Errorf("%w: %v", ErrConsoleStdin, err)
+		}
+		return nil
+	}
+	if len(first.Stdin) > 0 {
+		if err := writeStdin(first.Stdin); err != nil {
+			return err
+		}
+	}
+
+	ch := make(chan *rpcpb.AttachConsoleResponse, consoleBufferSize)
+	for _, wr := range writers {
+		wr.subscribe(ch)
+		defer wr.unsubscribe(ch)
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		for {
+			req, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				// client closed its side, keep streaming outputs
+				return
+			}
+			if err != nil {
+				errc <- err
+				return
+			}
+			if len(req.Stdin) > 0 {
+				if err := writeStdin(req.Stdin); err != nil {
+					errc <- err
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-s.rootCtx.Done():
+			return s.rootCtx.Err()
+		case <-s.closed:
+			return ErrClosed
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case err := <-errc:
+			if isClientCanceled(stream.Context().Err(), err) {
+				zap.L().Debug("console client canceled", zap.Error(err))
+				return nil
+			}
+			return err
+		case resp := <-ch:
+			if err := stream.Send(resp); err != nil {
+				return err
+			}
+		}
+	}
+}
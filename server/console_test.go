@@ -0,0 +1,122 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+	"google.golang.org/grpc"
+)
+
+func TestExitScriptStdin(t *testing.T) {
+	dir := t.TempDir()
+	// echoes its first stdin line
+	bin := filepath.Join(dir, "node.sh")
+	out := filepath.Join(dir, "out")
+	if err := ioutil.WriteFile(bin, []byte("#!/bin/sh\nread line\necho \"$line\" > "+shellQuote(out)+"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	nodeDir := filepath.Join(dir, "node1")
+	if _, err := openStdin(nodeDir); !errors.Is(err, ErrConsoleStdin) {
+		t.Fatalf("expected %v, got %v", ErrConsoleStdin, err)
+	}
+	launcher, err := writeExitScript(nodeDir, bin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(launcher)
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer cmd.Process.Kill()
+
+	var stdin *os.File
+	for deadline := time.Now().Add(5 * time.Second); stdin == nil; {
+		if stdin, err = openStdin(nodeDir); err != nil && time.Now().After(deadline) {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	defer stdin.Close()
+	if _, err := stdin.Write([]byte("hello\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "hello\n" {
+		t.Fatalf("expected %q, got %q", "hello\n", b)
+	}
+}
+
+// fakeConsoleStream is the "AttachConsole" stream of a client that sends
+// the requests, then closes its side.
+type fakeConsoleStream struct {
+	grpc.ServerStream
+
+	ctx  context.Context
+	reqs []*rpcpb.AttachConsoleRequest
+}
+
+func (s *fakeConsoleStream) Context() context.Context { return s.ctx }
+
+func (s *fakeConsoleStream) Send(*rpcpb.AttachConsoleResponse) error { return nil }
+
+func (s *fakeConsoleStream) Recv() (*rpcpb.AttachConsoleRequest, error) {
+	if len(s.reqs) == 0 {
+		return nil, io.EOF
+	}
+	req := s.reqs[0]
+	s.reqs = s.reqs[1:]
+	return req, nil
+}
+
+func TestAttachConsoleStdinRole(t *testing.T) {
+	s := &server{rootCtx: context.Background(), closed: make(chan struct{})}
+	nw := &localNetwork{
+		opts:      networkOptions{mu: &s.mu, tenant: "a"},
+		lifecycle: newLifecycle(),
+		nodeInfos: map[string]*rpcpb.NodeInfo{"node1": {Name: "node1", DbDir: filepath.Join(t.TempDir(), "node1", "db")}},
+		writers: map[string][2]*writer{"node1": {
+			newWriter("node1", "stdout", noopSink{}, nil),
+			newWriter("node1", "stderr", noopSink{}, nil),
+		}},
+	}
+	if err := nw.lifecycle.transition(stateBootstrapping, nil); err != nil {
+		t.Fatal(err)
+	}
+	s.network = nw
+
+	for i, tv := range []struct {
+		role string
+		err  error
+	}{
+		{role: RoleViewer, err: ErrConsoleStdinDenied},
+		// allowed, the node does not run
+		{role: RoleOperator, err: ErrConsoleStdin},
+		{role: RoleAdmin, err: ErrConsoleStdin},
+	} {
+		ctx := context.WithValue(context.Background(), tenantKey{}, &Tenant{Name: "a", Role: tv.role})
+		stream := &fakeConsoleStream{ctx: ctx, reqs: []*rpcpb.AttachConsoleRequest{
+			{NodeName: "node1"},
+			{Stdin: []byte("hello\n")},
+		}}
+		if err := s.AttachConsole(stream); !errors.Is(err, tv.err) {
+			t.Fatalf("#%d: expected %v, got %v", i, tv.err, err)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/lasthyphen/dijetsnodego/config"
+	"github.com/lasthyphen/dijetsnodego/database/leveldb"
+	"github.com/lasthyphen/dijetsnodego/database/memdb"
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+	"google.golang.org/protobuf/proto"
+)
+
+var ErrInvalidDatabaseConfig = errors.New("invalid database config")
+
+// nodeDatabaseConfig returns the database config of the node, the per-node
+// fields overriding the network-wide ones, or nil for the node defaults.
+func nodeDatabaseConfig(all *rpcpb.DatabaseConfig, node *rpcpb.DatabaseConfig) (*rpcpb.DatabaseConfig, error) {
+	if all == nil && node == nil {
+		return nil, nil
+	}
+	db := &rpcpb.DatabaseConfig{}
+	if all != nil {
+		db = proto.Clone(all).(*rpcpb.DatabaseConfig)
+	}
+	if node != nil {
+		proto.Merge(db, node)
+	}
+	dbType := leveldb.Name
+	if db.Type != nil {
+		dbType = db.GetType()
+	}
+	if dbType != leveldb.Name && dbType != memdb.Name {
+		return nil, fmt.Errorf("%w: unknown type %q (expected %s or %s)", ErrInvalidDatabaseConfig, dbType, leveldb.Name, memdb.Name)
+	}
+	tuned := db.BlockCacheBytes != nil || db.WriteBufferBytes != nil || db.OpenFilesCacheCapacity != nil || db.FilterBitsPerKey != nil
+	if tuned && dbType != leveldb.Name {
+		return nil, fmt.Errorf("%w: the cache and buffer sizes only apply to %s", ErrInvalidDatabaseConfig, leveldb.Name)
+	}
+	for field, v := range map[string]*uint64{
+		"block cache":  db.BlockCacheBytes,
+		"write buffer": db.WriteBufferBytes,
+	} {
+		if v != nil && (*v == 0 || *v > math.MaxInt32) {
+			return nil, fmt.Errorf("%w: %s of %d bytes not in [1, %d]", ErrInvalidDatabaseConfig, field, *v, math.MaxInt32)
+		}
+	}
+	if db.OpenFilesCacheCapacity != nil && db.GetOpenFilesCacheCapacity() == 0 {
+		return nil, fmt.Errorf("%w: zero open files cache capacity", ErrInvalidDatabaseConfig)
+	}
+	if db.FilterBitsPerKey != nil && db.GetFilterBitsPerKey() > math.MaxInt32 {
+		return nil, fmt.Errorf("%w: %d filter bits per key", ErrInvalidDatabaseConfig, db.GetFilterBitsPerKey())
+	}
+	return db, nil
+}
+
+// databaseFlags returns the node flags of the database config: the type,
+// and the LevelDB options as the base64-encoded database config content.
+// The node config may not set them too.
+func databaseFlags(db *rpcpb.DatabaseConfig, nodeConfig map[string]interface{}) (map[string]interface{}, error) {
+	if db == nil {
+		return nil, nil
+	}
+	for _, key := range []string{config.DBTypeKey, config.DBConfigFileKey, config.DBConfigContentKey} {
+		if _, ok := nodeConfig[key]; ok {
+			return nil, fmt.Errorf("%w: the node config sets %q", ErrInvalidDatabaseConfig, key)
+		}
+	}
+	flags := make(map[string]interface{})
+	if db.Type != nil {
+		flags[config.DBTypeKey] = db.GetType()
+	}
+	// as the keys of the LevelDB config
+	levelDB := make(map[string]interface{})
+	if db.BlockCacheBytes != nil {
+		levelDB["blockCacheCapacity"] = db.GetBlockCacheBytes()
+	}
+	if db.WriteBufferBytes != nil {
+		levelDB["writeBuffer"] = db.GetWriteBufferBytes()
+	}
+	if db.OpenFilesCacheCapacity != nil {
+		levelDB["openFilesCacheCapacity"] = db.GetOpenFilesCacheCapacity()
+	}
+	if db.FilterBitsPerKey != nil {
+		levelDB["filterBitsPerKey"] = db.GetFilterBitsPerKey()
+	}
+	if len(levelDB) > 0 {
+		b, err := json.Marshal(levelDB)
+		if err != nil {
+			return nil, err
+		}
+		flags[config.DBConfigContentKey] = base64.StdEncoding.EncodeToString(b)
+	}
+	return flags, nil
+}
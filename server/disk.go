@@ -0,0 +1,201 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// sparse, so only the written blocks take space
+	defaultDiskDeviceBytes = 2 << 30
+	minDiskDeviceBytes     = 64 << 20
+
+	diskImageFile = "db.img"
+)
+
+var (
+	ErrInvalidDiskFault      = errors.New("invalid disk fault")
+	ErrNoDiskDevice          = errors.New("node has no disk device")
+	ErrDiskFaultsUnsupported = errors.New("disk faults are not supported on this platform")
+)
+
+// diskDevice is the device-mapper device that the database directory of a
+// node is mounted on, over a loop device of an image file. The faults swap
+// the device table, from the plain mapping of the image to a delaying or
+// failing one.
+type diskDevice struct {
+	// device-mapper name
+	name string
+	// backing loop device
+	loop     string
+	sectors  uint64
+	mountDir string
+	// nil if none
+	fault *rpcpb.DiskFault
+}
+
+// diskFaultParams are the parsed "DiskFault", in the units of the
+// device-mapper targets.
+type diskFaultParams struct {
+	mode         rpcpb.DiskFaultMode
+	readDelayMs  int64
+	writeDelayMs int64
+	upSeconds    int64
+	downSeconds  int64
+	errorWrites  bool
+}
+
+// diskDeviceName is unique per network, so the devices of the networks
+// sharing the host (e.g., of the tenants) do not collide.
+func diskDeviceName(rootDataDir string, nodeName string) string {
+	h := sha256.Sum256([]byte(rootDataDir))
+	return fmt.Sprintf("nr-%x-%s", h[:6], nodeName)
+}
+
+func diskDevicePath(rootDataDir string, nodeName string) string {
+	return filepath.Join("/dev/mapper", diskDeviceName(rootDataDir, nodeName))
+}
+
+func parseDiskFault(f *rpcpb.DiskFault) (diskFaultParams, error) {
+	p := diskFaultParams{mode: f.GetMode()}
+	switch p.mode {
+	case rpcpb.DiskFaultMode_DISK_FAULT_MODE_UNSPECIFIED, rpcpb.DiskFaultMode_DISK_FAULT_MODE_ERROR:
+	case rpcpb.DiskFaultMode_DISK_FAULT_MODE_DELAY:
+		for _, d := range []struct {
+			field string
+			v     string
+			ms    *int64
+		}{{"read delay", f.ReadDelay, &p.readDelayMs}, {"write delay", f.WriteDelay, &p.writeDelayMs}} {
+			if d.v == "" {
+				continue
+			}
+			dur, err := time.ParseDuration(d.v)
+			if err != nil || dur < 0 {
+				return p, fmt.Errorf("%w: invalid %s %q", ErrInvalidDiskFault, d.field, d.v)
+			}
+			*d.ms = dur.Milliseconds()
+		}
+		if p.readDelayMs == 0 && p.writeDelayMs == 0 {
+			return p, fmt.Errorf("%w: no read or write delay of at least 1ms", ErrInvalidDiskFault)
+		}
+	case rpcpb.DiskFaultMode_DISK_FAULT_MODE_FLAKEY:
+		for _, d := range []struct {
+			field string
+			v     string
+			s     *int64
+		}{{"up interval", f.UpInterval, &p.upSeconds}, {"down interval", f.DownInterval, &p.downSeconds}} {
+			dur, err := time.ParseDuration(d.v)
+			if err != nil || dur < time.Second || dur%time.Second != 0 {
+				return p, fmt.Errorf("%w: %s %q is not a whole number of seconds", ErrInvalidDiskFault, d.field, d.v)
+			}
+			*d.s = int64(dur / time.Second)
+		}
+		p.errorWrites = f.ErrorWrites
+	default:
+		return p, fmt.Errorf("%w: unknown mode %s", ErrInvalidDiskFault, p.mode)
+	}
+	return p, nil
+}
+
+// table returns the device-mapper table of the fault ("dmsetup" format):
+// the plain mapping of the loop device without a fault.
+func (d *diskDevice) table(p diskFaultParams) string {
+	switch p.mode {
+	case rpcpb.DiskFaultMode_DISK_FAULT_MODE_DELAY:
+		return fmt.Sprintf("0 %d delay %s 0 %d %s 0 %d", d.sectors, d.loop, p.readDelayMs, d.loop, p.writeDelayMs)
+	case rpcpb.DiskFaultMode_DISK_FAULT_MODE_ERROR:
+		return fmt.Sprintf("0 %d error", d.sectors)
+	case rpcpb.DiskFaultMode_DISK_FAULT_MODE_FLAKEY:
+		t := fmt.Sprintf("0 %d flakey %s 0 %d %d", d.sectors, d.loop, p.upSeconds, p.downSeconds)
+		if p.errorWrites {
+			t += " 1 error_writes"
+		}
+		return t
+	}
+	return fmt.Sprintf("0 %d linear %s 0", d.sectors, d.loop)
+}
+
+// SetDiskFault delays or fails the database I/O of a node started with a
+// disk device (ref. "disk_fault_nodes"), or clears its fault with the
+// unspecified mode. The I/O in flight completes under the previous table.
+func (s *server) SetDiskFault(ctx context.Context, req *rpcpb.SetDiskFaultRequest) (*rpcpb.SetDiskFaultResponse, error) {
+	zap.L().Info("received set disk fault request",
+		zap.String("name", req.NodeName),
+		zap.String("mode", req.GetFault().GetMode().String()),
+	)
+	p, err := parseDiskFault(req.Fault)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkStateLocked(stateHealthy, stateDegraded); err != nil {
+		return nil, err
+	}
+	info, ok := s.network.nodeInfos[req.NodeName]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrNodeNotFound, req.NodeName)
+	}
+	d, ok := s.network.diskDevices[req.NodeName]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrNoDiskDevice, req.NodeName)
+	}
+	if err := setDiskTable(d.name, d.table(p)); err != nil {
+		return nil, fmt.Errorf("failed to set the disk fault of %q: %w", req.NodeName, err)
+	}
+	d.fault = nil
+	if p.mode != rpcpb.DiskFaultMode_DISK_FAULT_MODE_UNSPECIFIED {
+		d.fault = proto.Clone(req.Fault).(*rpcpb.DiskFault)
+	}
+	info.DiskFault = d.fault
+	zap.L().Info("set disk fault", zap.String("name", req.NodeName), zap.String("device", d.name), zap.String("mode", p.mode.String()))
+	return &rpcpb.SetDiskFaultResponse{ClusterInfo: s.copyClusterInfo()}, nil
+}
+
+// attachDiskDevices mounts the database directory of each disk fault node
+// on its device, before the nodes are launched.
+func (lc *localNetwork) attachDiskDevices() error {
+	size := lc.opts.diskDeviceBytes
+	if size == 0 {
+		size = defaultDiskDeviceBytes
+	}
+	for _, name := range lc.opts.diskFaultNodes {
+		info := lc.nodeInfos[name]
+		d, err := attachDiskDevice(
+			diskDeviceName(lc.opts.rootDataDir, name),
+			filepath.Join(filepath.Dir(info.DbDir), diskImageFile),
+			info.DbDir,
+			size,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to attach the disk device of %q: %w", name, err)
+		}
+		lc.diskDevices[name] = d
+		zap.L().Info("attached disk device", zap.String("name", name), zap.String("device", d.name), zap.String("loop", d.loop))
+	}
+	return nil
+}
+
+// detachDiskDevices unmounts and removes the devices, once the nodes have
+// exited.
+func (lc *localNetwork) detachDiskDevices() {
+	for name, d := range lc.diskDevices {
+		if err := detachDiskDevice(d); err != nil {
+			zap.L().Warn("failed to detach the disk device", zap.String("name", name), zap.String("device", d.name), zap.Error(err))
+		}
+		delete(lc.diskDevices, name)
+	}
+}
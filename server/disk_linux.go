@@ -0,0 +1,115 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+//go:build linux
+// +build linux
+
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// attachDiskDevice creates the sparse image of the size, maps it through a
+// loop device to the device-mapper device of the name, and mounts it (as
+// ext4) on the directory. Requires losetup, dmsetup, mkfs.ext4, and mount
+// (e.g., root privileges).
+func attachDiskDevice(name string, image string, mountDir string, size uint64) (d *diskDevice, err error) {
+	if err := os.MkdirAll(filepath.Dir(image), 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(image, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	err = f.Truncate(int64(size))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	loop, err := diskTool("losetup", "--find", "--show", image)
+	if err != nil {
+		return nil, err
+	}
+	d = &diskDevice{name: name, loop: loop, sectors: size / 512, mountDir: mountDir}
+	defer func() {
+		if err != nil {
+			_, _ = diskTool("dmsetup", "remove", name)
+			_, _ = diskTool("losetup", "--detach", loop)
+		}
+	}()
+	if _, err = diskTool("dmsetup", "create", name, "--table", d.table(diskFaultParams{})); err != nil {
+		return nil, err
+	}
+	dev := filepath.Join("/dev/mapper", name)
+	if _, err = diskTool("mkfs.ext4", "-q", "-F", dev); err != nil {
+		return nil, err
+	}
+	if err = os.MkdirAll(mountDir, 0o755); err != nil {
+		return nil, err
+	}
+	if _, err = diskTool("mount", dev, mountDir); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// detachDiskDevice unmounts the device, and removes it and its loop device.
+func detachDiskDevice(d *diskDevice) error {
+	var errs []string
+	// a failing table fails the unmount, so the plain mapping is restored
+	if d.fault != nil {
+		if err := setDiskTable(d.name, d.table(diskFaultParams{})); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	for _, args := range [][]string{
+		{"umount", d.mountDir},
+		{"dmsetup", "remove", d.name},
+		{"losetup", "--detach", d.loop},
+	} {
+		if _, err := diskTool(args[0], args[1:]...); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// setDiskTable swaps the table of the device. The device is suspended
+// without flushing, as a failing table may not complete the flush.
+func setDiskTable(name string, table string) error {
+	if _, err := diskTool("dmsetup", "suspend", "--noflush", name); err != nil {
+		return err
+	}
+	_, rerr := diskTool("dmsetup", "reload", name, "--table", table)
+	// resumed with the previous table if the reload failed
+	if _, err := diskTool("dmsetup", "resume", name); err != nil && rerr == nil {
+		rerr = err
+	}
+	return rerr
+}
+
+func diskTool(name string, args ...string) (string, error) {
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrDiskFaultsUnsupported, err)
+	}
+	cmd := exec.Command(path, args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%s %s: %w (%s)", name, strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
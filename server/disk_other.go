@@ -0,0 +1,19 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+//go:build !linux
+// +build !linux
+
+package server
+
+func attachDiskDevice(name string, image string, mountDir string, size uint64) (*diskDevice, error) {
+	return nil, ErrDiskFaultsUnsupported
+}
+
+func detachDiskDevice(d *diskDevice) error {
+	return nil
+}
+
+func setDiskTable(name string, table string) error {
+	return ErrDiskFaultsUnsupported
+}
@@ -0,0 +1,350 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lasthyphen/djtx-tester/pkg/jsonrpc"
+	"github.com/lasthyphen/djtx-tester/pkg/randutil"
+	"github.com/lasthyphen/djtx-tester/pkg/testkeys"
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	faucetUsername = "network-runner-faucet"
+
+	// default tx fee on the local network
+	faucetTxFee = 1000000
+
+	faucetTxWait = 30 * time.Second
+)
+
+var (
+	ErrFaucetInvalidChain   = errors.New("invalid chain (expected X, P, or C)")
+	ErrFaucetInvalidAddress = errors.New("invalid address")
+	ErrFaucetRateLimited    = errors.New("rate limited")
+	ErrFaucetNoHealthyNode  = errors.New("no node URI available")
+)
+
+type faucetRequest struct {
+	Chain   string `json:"chain"`
+	Address string `json:"address"`
+}
+
+type faucetResponse struct {
+	Chain   string `json:"chain"`
+	Address string `json:"address"`
+	Amount  uint64 `json:"amount"`
+	TxID    string `json:"txID"`
+	Error   string `json:"error,omitempty"`
+}
+
+// faucet dispenses test funds from the funded key
+// via the keystore APIs of the running network.
+type faucet struct {
+	amount   uint64
+	interval time.Duration
+	key      *testkeys.Key
+	// authenticates the requests, if tenancy is enabled
+	tenants tenants
+
+	// returns the cluster of the tenant, nil if none (or of another tenant)
+	getClusterInfo func(tenant string) *rpcpb.ClusterInfo
+
+	// serializes dispensing, so that UTXOs are not double-spent
+	mu sync.Mutex
+
+	// root data directory of the network the keystore user was created for
+	rootDataDir string
+	// node the keystore user was created on, kept with its password for
+	// the retries once the user exists
+	userURI  string
+	password string
+	// set once the funded key is imported
+	uri     string
+	xAddr   string
+	assetID string
+
+	lastSent map[string]time.Time
+}
+
+func newFaucet(amount uint64, interval time.Duration, key *testkeys.Key, ts tenants, getClusterInfo func(tenant string) *rpcpb.ClusterInfo) *faucet {
+	return &faucet{
+		amount:         amount,
+		interval:       interval,
+		key:            key,
+		tenants:        ts,
+		getClusterInfo: getClusterInfo,
+		lastSent:       make(map[string]time.Time),
+	}
+}
+
+// ServeHTTP dispenses the funds on the cluster of the caller, authenticated
+// as the owner of the cluster (if tenancy is enabled). The funds are spent
+// by transactions, so the operator role is required, as for the node proxy.
+func (f *faucet) ServeHTTP(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	tenant := ""
+	if f.tenants != nil {
+		md := metadata.Pairs("authorization", r.Header.Get("Authorization"))
+		t, err := f.tenants.authenticate(metadata.NewIncomingContext(r.Context(), md))
+		if err != nil {
+			writeFaucetResponse(w, http.StatusUnauthorized, &faucetResponse{Error: "missing or unknown tenant token"})
+			return
+		}
+		if !t.allows(RoleOperator) {
+			writeFaucetResponse(w, http.StatusForbidden, &faucetResponse{Error: "the faucet requires the operator role"})
+			return
+		}
+		tenant = t.Name
+	}
+
+	var req faucetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeFaucetResponse(w, http.StatusBadRequest, &faucetResponse{Error: err.Error()})
+		return
+	}
+
+	resp, err := f.dispense(r.Context(), tenant, req.Chain, req.Address)
+	if err != nil {
+		code := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, ErrFaucetInvalidChain), errors.Is(err, ErrFaucetInvalidAddress):
+			code = http.StatusBadRequest
+		case errors.Is(err, ErrFaucetRateLimited):
+			code = http.StatusTooManyRequests
+		case errors.Is(err, ErrNotBootstrapped), errors.Is(err, ErrFaucetNoHealthyNode):
+			code = http.StatusServiceUnavailable
+		}
+		zap.L().Warn("faucet request failed",
+			zap.String("chain", req.Chain),
+			zap.String("address", req.Address),
+			zap.Error(err),
+		)
+		writeFaucetResponse(w, code, &faucetResponse{Chain: req.Chain, Address: req.Address, Error: err.Error()})
+		return
+	}
+	writeFaucetResponse(w, http.StatusOK, resp)
+}
+
+func writeFaucetResponse(w http.ResponseWriter, code int, resp *faucetResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func (f *faucet) dispense(ctx context.Context, tenant string, chain string, addr string) (*faucetResponse, error) {
+	chain = strings.ToUpper(chain)
+	switch chain {
+	case "X", "P":
+		if !strings.HasPrefix(addr, chain+"-") {
+			return nil, ErrFaucetInvalidAddress
+		}
+	case "C":
+		if !strings.HasPrefix(addr, "0x") || len(addr) != 42 {
+			return nil, ErrFaucetInvalidAddress
+		}
+	default:
+		return nil, ErrFaucetInvalidChain
+	}
+
+	info := f.getClusterInfo(tenant)
+	if info == nil {
+		return nil, ErrNotBootstrapped
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := chain + ":" + strings.ToLower(addr)
+	if last, ok := f.lastSent[key]; ok && time.Since(last) < f.interval {
+		return nil, fmt.Errorf("%w: %q may request again in %v", ErrFaucetRateLimited, addr, f.interval-time.Since(last))
+	}
+
+	if err := f.ensureUser(ctx, info); err != nil {
+		return nil, err
+	}
+
+	zap.L().Info("dispensing funds",
+		zap.String("chain", chain),
+		zap.String("address", addr),
+		zap.Uint64("amount", f.amount),
+	)
+	var (
+		txID string
+		err  error
+	)
+	switch chain {
+	case "X":
+		txID, err = f.sendX(ctx, addr)
+	case "P":
+		txID, err = f.exportImport(ctx, "P", "/ext/bc/P", "platform.importAVAX", addr)
+	case "C":
+		txID, err = f.exportImport(ctx, "C", "/ext/bc/C/avax", "avax.import", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	f.lastSent[key] = time.Now()
+	return &faucetResponse{
+		Chain:   chain,
+		Address: addr,
+		Amount:  f.amount,
+		TxID:    txID,
+	}, nil
+}
+
+// ensureUser creates the faucet keystore user and imports the funded key,
+// once per network, and again on another node once the node of the user
+// leaves the network. After a failed import, the retries reuse the created
+// user (and its password), as the keystore rejects creating it again.
+func (f *faucet) ensureUser(ctx context.Context, info *rpcpb.ClusterInfo) error {
+	if f.rootDataDir == info.RootDataDir && f.uri != "" {
+		for _, ni := range info.NodeInfos {
+			if ni.Uri == f.uri {
+				return nil
+			}
+		}
+		// e.g., removed, or rebound to another port on a restart
+		zap.L().Info("faucet node left the network", zap.String("uri", f.uri))
+		f.uri = ""
+	}
+
+	names := make([]string, 0, len(info.NodeInfos))
+	for name, ni := range info.NodeInfos {
+		if ni.Uri != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return ErrFaucetNoHealthyNode
+	}
+	sort.Strings(names)
+	uri := info.NodeInfos[names[0]].Uri
+
+	if f.rootDataDir != info.RootDataDir || f.userURI != uri {
+		password := randutil.String(32)
+		user := map[string]string{"username": faucetUsername, "password": password}
+		if err := jsonrpc.Call(ctx, uri, "/ext/keystore", "keystore.createUser", user, nil); err != nil {
+			return err
+		}
+		f.rootDataDir, f.userURI, f.password = info.RootDataDir, uri, password
+		f.uri = ""
+	}
+	password := f.password
+
+	importReq := map[string]string{
+		"username":   faucetUsername,
+		"password":   password,
+		"privateKey": f.key.String(),
+	}
+	var xReply struct {
+		Address string `json:"address"`
+	}
+	if err := jsonrpc.Call(ctx, uri, "/ext/bc/X", "avm.importKey", importReq, &xReply); err != nil {
+		return err
+	}
+	if err := jsonrpc.Call(ctx, uri, "/ext/bc/P", "platform.importKey", importReq, nil); err != nil {
+		return err
+	}
+	if err := jsonrpc.Call(ctx, uri, "/ext/bc/C/avax", "avax.importKey", importReq, nil); err != nil {
+		return err
+	}
+
+	var assetReply struct {
+		AssetID string `json:"assetID"`
+	}
+	if err := jsonrpc.Call(ctx, uri, "/ext/bc/P", "platform.getStakingAssetID", nil, &assetReply); err != nil {
+		return err
+	}
+
+	zap.L().Info("created faucet keystore user",
+		zap.String("uri", uri),
+		zap.String("xAddress", xReply.Address),
+		zap.String("assetID", assetReply.AssetID),
+	)
+	f.uri = uri
+	f.xAddr = xReply.Address
+	f.assetID = assetReply.AssetID
+	f.lastSent = make(map[string]time.Time)
+	return nil
+}
+
+type faucetTxReply struct {
+	TxID string `json:"txID"`
+}
+
+func (f *faucet) sendX(ctx context.Context, to string) (string, error) {
+	var reply faucetTxReply
+	err := jsonrpc.Call(ctx, f.uri, "/ext/bc/X", "avm.send", map[string]interface{}{
+		"username": faucetUsername,
+		"password": f.password,
+		"assetID":  f.assetID,
+		"amount":   f.amount,
+		"to":       to,
+	}, &reply)
+	return reply.TxID, err
+}
+
+// exportImport exports funds from the X-chain to the faucet's own address
+// on the destination chain, and imports them to the requested address.
+func (f *faucet) exportImport(ctx context.Context, chain string, endpoint string, importMethod string, to string) (string, error) {
+	var exportReply faucetTxReply
+	if err := jsonrpc.Call(ctx, f.uri, "/ext/bc/X", "avm.export", map[string]interface{}{
+		"username": faucetUsername,
+		"password": f.password,
+		"assetID":  f.assetID,
+		"amount":   f.amount + faucetTxFee,
+		"to":       chain + strings.TrimPrefix(f.xAddr, "X"),
+	}, &exportReply); err != nil {
+		return "", err
+	}
+	if err := f.waitXTx(ctx, exportReply.TxID); err != nil {
+		return "", err
+	}
+
+	var importReply faucetTxReply
+	err := jsonrpc.Call(ctx, f.uri, endpoint, importMethod, map[string]interface{}{
+		"username":    faucetUsername,
+		"password":    f.password,
+		"to":          to,
+		"sourceChain": "X",
+	}, &importReply)
+	return importReply.TxID, err
+}
+
+func (f *faucet) waitXTx(ctx context.Context, txID string) error {
+	ctx, cancel := context.WithTimeout(ctx, faucetTxWait)
+	defer cancel()
+	for {
+		var reply struct {
+			Status string `json:"status"`
+		}
+		if err := jsonrpc.Call(ctx, f.uri, "/ext/bc/X", "avm.getTxStatus", map[string]string{"txID": txID}, &reply); err != nil {
+			return err
+		}
+		switch reply.Status {
+		case "Accepted":
+			return nil
+		case "Rejected":
+			return fmt.Errorf("export tx %q rejected", txID)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+}
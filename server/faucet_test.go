@@ -0,0 +1,119 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/lasthyphen/djtx-tester/pkg/testkeys"
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+)
+
+// fakeKeystore is the keystore of a node, failing the first key import.
+type fakeKeystore struct {
+	mu        sync.Mutex
+	passwords map[string]string
+	imports   int
+}
+
+func (k *fakeKeystore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Method string            `json:"method"`
+		Params map[string]string `json:"params"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	var result interface{} = struct{}{}
+	var rpcErr interface{}
+	switch req.Method {
+	case "keystore.createUser":
+		if _, ok := k.passwords[req.Params["username"]]; ok {
+			rpcErr = map[string]interface{}{"code": -32000, "message": "user already exists"}
+			break
+		}
+		k.passwords[req.Params["username"]] = req.Params["password"]
+	case "avm.importKey", "platform.importKey", "avax.importKey":
+		if k.passwords[req.Params["username"]] != req.Params["password"] {
+			rpcErr = map[string]interface{}{"code": -32000, "message": "incorrect password"}
+			break
+		}
+		k.imports++
+		if k.imports == 1 {
+			rpcErr = map[string]interface{}{"code": -32000, "message": "node not bootstrapped"}
+			break
+		}
+		result = map[string]string{"address": "X-local1"}
+	case "platform.getStakingAssetID":
+		result = map[string]string{"assetID": "asset"}
+	}
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "result": result, "error": rpcErr})
+}
+
+func TestFaucetEnsureUserRetry(t *testing.T) {
+	ks := &fakeKeystore{passwords: make(map[string]string)}
+	node := httptest.NewServer(ks)
+	defer node.Close()
+
+	f := newFaucet(1, 0, testkeys.Ewoq(), nil, nil)
+	info := &rpcpb.ClusterInfo{
+		RootDataDir: "/tmp/network-runner-root-data",
+		NodeInfos:   map[string]*rpcpb.NodeInfo{"node1": {Uri: node.URL}},
+	}
+	if err := f.ensureUser(context.Background(), info); err == nil {
+		t.Fatal("expected the first import to fail")
+	}
+	if err := f.ensureUser(context.Background(), info); err != nil {
+		t.Fatalf("expected the retry to reuse the user, got %v", err)
+	}
+	if f.uri != node.URL || f.xAddr != "X-local1" || f.assetID != "asset" {
+		t.Fatalf("unexpected faucet user %q %q %q", f.uri, f.xAddr, f.assetID)
+	}
+	if f.password != ks.passwords[faucetUsername] {
+		t.Fatal("password of the created user not kept")
+	}
+}
+
+func TestFaucetEnsureUserNodeRemoved(t *testing.T) {
+	var nodes []*httptest.Server
+	for i := 0; i < 2; i++ {
+		// past the failed first import
+		ks := &fakeKeystore{passwords: make(map[string]string), imports: 1}
+		node := httptest.NewServer(ks)
+		defer node.Close()
+		nodes = append(nodes, node)
+	}
+
+	f := newFaucet(1, 0, testkeys.Ewoq(), nil, nil)
+	info := &rpcpb.ClusterInfo{
+		RootDataDir: "/tmp/network-runner-root-data",
+		NodeInfos: map[string]*rpcpb.NodeInfo{
+			"node1": {Uri: nodes[0].URL},
+			"node2": {Uri: nodes[1].URL},
+		},
+	}
+	if err := f.ensureUser(context.Background(), info); err != nil {
+		t.Fatal(err)
+	}
+	if f.uri != nodes[0].URL {
+		t.Fatalf("expected %q, got %q", nodes[0].URL, f.uri)
+	}
+
+	// the node of the user is removed from the same network
+	delete(info.NodeInfos, "node1")
+	if err := f.ensureUser(context.Background(), info); err != nil {
+		t.Fatal(err)
+	}
+	if f.uri != nodes[1].URL || f.userURI != nodes[1].URL {
+		t.Fatalf("expected the user on %q, got %q %q", nodes[1].URL, f.uri, f.userURI)
+	}
+}
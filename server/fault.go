@@ -0,0 +1,61 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrInvalidCrashMode    = errors.New("invalid crash mode")
+	ErrNodeProcessNotFound = errors.New("node process not found")
+	ErrFaultUnsupported    = errors.New("fault injection is not supported on this platform")
+)
+
+// InjectFault crashes a node process with the requested crash mode.
+// The network is marked degraded, and the node can be brought back with "RestartNode".
+func (s *server) InjectFault(ctx context.Context, req *rpcpb.InjectFaultRequest) (*rpcpb.InjectFaultResponse, error) {
+	zap.L().Info("received inject fault request",
+		zap.String("name", req.NodeName),
+		zap.String("crashMode", req.CrashMode.String()),
+	)
+	if req.NodeName == "" {
+		return nil, ErrEmptyNodeName
+	}
+	if req.CrashMode == rpcpb.CrashMode_CRASH_MODE_UNSPECIFIED {
+		return nil, ErrInvalidCrashMode
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkStateLocked(stateHealthy, stateDegraded); err != nil {
+		return nil, err
+	}
+	info, ok := s.network.nodeInfos[req.NodeName]
+	if !ok {
+		return nil, ErrNodeNotFound
+	}
+
+	pid, err := findNodePID(info.ExecPath, info.LogDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := crashProcess(pid, req.NodeName, req.CrashMode); err != nil {
+		return nil, err
+	}
+	zap.L().Info("injected fault",
+		zap.String("name", req.NodeName),
+		zap.Int("pid", pid),
+		zap.String("crashMode", req.CrashMode.String()),
+	)
+
+	s.network.markUnhealthy(fmt.Errorf("fault injected into %q: %s", req.NodeName, req.CrashMode))
+	return &rpcpb.InjectFaultResponse{ClusterInfo: s.copyClusterInfo(), Pid: int32(pid)}, nil
+}
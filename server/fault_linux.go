@@ -0,0 +1,143 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+//go:build linux
+// +build linux
+
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+	"go.uber.org/zap"
+)
+
+// findNodePID returns the process ID of the node binary
+// holding a file open in the node log directory.
+func findNodePID(execPath string, logDir string) (int, error) {
+	exe, err := filepath.EvalSymlinks(execPath)
+	if err != nil {
+		return 0, err
+	}
+	exe, err = filepath.Abs(exe)
+	if err != nil {
+		return 0, err
+	}
+	logDir = filepath.Clean(logDir) + string(filepath.Separator)
+
+	entries, err := ioutil.ReadDir("/proc")
+	if err != nil {
+		return 0, err
+	}
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil {
+			continue
+		}
+		procDir := filepath.Join("/proc", e.Name())
+		// the process may exit at any time, so skip any read errors
+		pexe, err := os.Readlink(filepath.Join(procDir, "exe"))
+		if err != nil || pexe != exe {
+			continue
+		}
+		fds, err := ioutil.ReadDir(filepath.Join(procDir, "fd"))
+		if err != nil {
+			continue
+		}
+		for _, fd := range fds {
+			target, err := os.Readlink(filepath.Join(procDir, "fd", fd.Name()))
+			if err == nil && strings.HasPrefix(target, logDir) {
+				return pid, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("%w: no %q process with open files in %q", ErrNodeProcessNotFound, exe, logDir)
+}
+
+// pauseProcess stops (SIGSTOP) or continues (SIGCONT) the process.
+func pauseProcess(pid int, pause bool) error {
+	if pause {
+		return syscall.Kill(pid, syscall.SIGSTOP)
+	}
+	return syscall.Kill(pid, syscall.SIGCONT)
+}
+
+func crashProcess(pid int, name string, mode rpcpb.CrashMode) error {
+	switch mode {
+	case rpcpb.CrashMode_CRASH_MODE_SIGKILL:
+		return syscall.Kill(pid, syscall.SIGKILL)
+	case rpcpb.CrashMode_CRASH_MODE_SIGSEGV:
+		// the Go runtime treats an external SIGSEGV like a segmentation fault,
+		// crashing with the goroutine stack dump
+		return syscall.Kill(pid, syscall.SIGSEGV)
+	case rpcpb.CrashMode_CRASH_MODE_OOM:
+		return oomKill(pid, name)
+	default:
+		return fmt.Errorf("%w: %s", ErrInvalidCrashMode, mode)
+	}
+}
+
+const (
+	cgroupRoot = "/sys/fs/cgroup"
+
+	cgroupCleanupTimeout = time.Minute
+)
+
+// oomKill moves the process into a new cgroup (v2) with a zero memory limit,
+// so that the kernel OOM killer kills it on its next allocation.
+// Requires write access to the cgroup root (e.g., root privileges).
+func oomKill(pid int, name string) error {
+	if _, err := os.Stat(filepath.Join(cgroupRoot, "cgroup.controllers")); err != nil {
+		return fmt.Errorf("%w: cgroup v2 is not mounted at %q", ErrFaultUnsupported, cgroupRoot)
+	}
+	if err := ioutil.WriteFile(filepath.Join(cgroupRoot, "cgroup.subtree_control"), []byte("+memory"), 0o644); err != nil {
+		return fmt.Errorf("failed to enable the memory controller: %w", err)
+	}
+
+	dir := filepath.Join(cgroupRoot, fmt.Sprintf("network-runner-%s-%d", name, pid))
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		return err
+	}
+	// swap accounting may be disabled, in which case the file does not exist
+	if err := ioutil.WriteFile(filepath.Join(dir, "memory.swap.max"), []byte("0"), 0o644); err != nil && !os.IsNotExist(err) {
+		os.Remove(dir)
+		return err
+	}
+	for _, kv := range [][2]string{
+		{"memory.oom.group", "1"},
+		{"memory.max", "0"},
+		{"cgroup.procs", strconv.Itoa(pid)},
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, kv[0]), []byte(kv[1]), 0o644); err != nil {
+			os.Remove(dir)
+			return fmt.Errorf("failed to write %q: %w", kv[0], err)
+		}
+	}
+
+	go removeCgroup(dir)
+	return nil
+}
+
+// removeCgroup removes the cgroup once its processes have exited.
+func removeCgroup(dir string) {
+	deadline := time.Now().Add(cgroupCleanupTimeout)
+	for time.Now().Before(deadline) {
+		b, err := ioutil.ReadFile(filepath.Join(dir, "cgroup.events"))
+		if err == nil && bytes.Contains(b, []byte("populated 0")) {
+			break
+		}
+		time.Sleep(time.Second)
+	}
+	if err := os.Remove(dir); err != nil {
+		zap.L().Warn("failed to remove cgroup", zap.String("dir", dir), zap.Error(err))
+	}
+}
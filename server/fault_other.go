@@ -0,0 +1,21 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+//go:build !linux
+// +build !linux
+
+package server
+
+import "github.com/lasthyphen/djtx-tester/rpcpb"
+
+func findNodePID(execPath string, logDir string) (int, error) {
+	return 0, ErrFaultUnsupported
+}
+
+func pauseProcess(pid int, pause bool) error {
+	return ErrFaultUnsupported
+}
+
+func crashProcess(pid int, name string, mode rpcpb.CrashMode) error {
+	return ErrFaultUnsupported
+}
@@ -0,0 +1,251 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+	"go.uber.org/zap"
+)
+
+const (
+	gcReasonAge  = "age"
+	gcReasonSize = "size"
+	// the directories modified more recently may be of a starting network
+	gcGracePeriod = time.Minute
+)
+
+var ErrInvalidGCPolicy = errors.New("invalid gc policy")
+
+// gcPolicy is the retention of the data directories of the stopped
+// networks, and of the rotated logs of the running one.
+type gcPolicy struct {
+	// zero keeps the files of any age
+	maxAge time.Duration
+	// zero keeps the stopped networks of any total size
+	maxBytes uint64
+}
+
+type gcDir struct {
+	path string
+	size uint64
+	// of the most recently modified file
+	modTime time.Time
+}
+
+// GCNow removes the data directories of the stopped networks (of the
+// tenant, if tenancy is enabled), and the rotated logs of the running one,
+// by the retention of the server or of the request.
+func (s *server) GCNow(ctx context.Context, req *rpcpb.GCNowRequest) (*rpcpb.GCNowResponse, error) {
+	zap.L().Info("received gc now request", zap.Bool("dryRun", req.DryRun))
+	policy := gcPolicy{maxAge: s.cfg.GCMaxAge, maxBytes: s.cfg.GCMaxBytes}
+	if req.MaxAge != nil {
+		d, err := time.ParseDuration(req.GetMaxAge())
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("%w: invalid max age %q", ErrInvalidGCPolicy, req.GetMaxAge())
+		}
+		policy.maxAge = d
+	}
+	if req.MaxBytes != nil {
+		policy.maxBytes = req.GetMaxBytes()
+	}
+	return s.gc(policy, tenantFromContext(ctx), req.DryRun)
+}
+
+// runJanitor collects the garbage by the server retention every interval,
+// until the server closes.
+func (s *server) runJanitor(rootCtx context.Context) {
+	zap.L().Info("running gc janitor",
+		zap.Duration("interval", s.cfg.GCInterval),
+		zap.Duration("maxAge", s.cfg.GCMaxAge),
+		zap.Uint64("maxBytes", s.cfg.GCMaxBytes),
+	)
+	policy := gcPolicy{maxAge: s.cfg.GCMaxAge, maxBytes: s.cfg.GCMaxBytes}
+	t := time.NewTicker(s.cfg.GCInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-rootCtx.Done():
+			return
+		case <-s.closed:
+			return
+		case <-t.C:
+		}
+		if _, err := s.gc(policy, nil, false); err != nil {
+			zap.L().Warn("failed to collect garbage", zap.Error(err))
+		}
+	}
+}
+
+// gc removes the files out of the policy, of all the tenants if nil.
+// The directories of the running network, and of the orphaned node
+// processes, are kept.
+func (s *server) gc(policy gcPolicy, t *Tenant, dryRun bool) (*rpcpb.GCNowResponse, error) {
+	s.gcMu.Lock()
+	defer s.gcMu.Unlock()
+
+	active := make(map[string]bool)
+	var logDirs []string
+	s.mu.RLock()
+	if s.clusterInfo != nil {
+		active[s.clusterInfo.RootDataDir] = true
+	}
+	if s.network != nil {
+		active[s.network.opts.rootDataDir] = true
+		if t == nil || s.network.opts.tenant == t.Name {
+			for _, info := range s.network.nodeInfos {
+				logDirs = append(logDirs, info.LogDir)
+			}
+		}
+	}
+	s.mu.RUnlock()
+
+	orphans, err := findOrphans()
+	if err != nil && !errors.Is(err, ErrOrphansUnsupported) {
+		// a directory might still be in use
+		return nil, err
+	}
+	for _, o := range orphans {
+		active[o.rootDataDir] = true
+	}
+
+	paths, err := rootDataDirs(t)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	dirs := make([]gcDir, 0, len(paths))
+	for _, p := range paths {
+		if active[p] {
+			continue
+		}
+		d, err := dirUsage(p)
+		if err != nil {
+			zap.L().Warn("failed to read data directory", zap.String("path", p), zap.Error(err))
+			continue
+		}
+		if now.Sub(d.modTime) < gcGracePeriod {
+			continue
+		}
+		dirs = append(dirs, d)
+	}
+	// oldest first
+	sort.Slice(dirs, func(i, j int) bool { return dirs[i].modTime.Before(dirs[j].modTime) })
+
+	resp := &rpcpb.GCNowResponse{}
+	remove := func(path string, size uint64, reason string) {
+		if !dryRun {
+			if err := os.RemoveAll(path); err != nil {
+				zap.L().Warn("failed to remove", zap.String("path", path), zap.Error(err))
+				return
+			}
+		}
+		zap.L().Info("collected garbage",
+			zap.String("path", path),
+			zap.Uint64("sizeBytes", size),
+			zap.String("reason", reason),
+			zap.Bool("dryRun", dryRun),
+		)
+		resp.Removed = append(resp.Removed, &rpcpb.GCRemoved{Path: path, SizeBytes: size, Reason: reason})
+		resp.FreedBytes += size
+	}
+
+	kept := dirs[:0]
+	for _, d := range dirs {
+		if policy.maxAge > 0 && now.Sub(d.modTime) > policy.maxAge {
+			remove(d.path, d.size, gcReasonAge)
+			continue
+		}
+		kept = append(kept, d)
+	}
+	if policy.maxBytes > 0 {
+		var total uint64
+		for _, d := range kept {
+			total += d.size
+		}
+		for _, d := range kept {
+			if total <= policy.maxBytes {
+				break
+			}
+			remove(d.path, d.size, gcReasonSize)
+			total -= d.size
+		}
+	}
+
+	if policy.maxAge > 0 {
+		for _, logDir := range logDirs {
+			err := filepath.Walk(logDir, func(p string, fi os.FileInfo, err error) error {
+				if err != nil {
+					return err
+				}
+				if fi.Mode().IsRegular() && isRotatedLog(fi.Name()) && now.Sub(fi.ModTime()) > policy.maxAge {
+					remove(p, uint64(fi.Size()), gcReasonAge)
+				}
+				return nil
+			})
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				zap.L().Warn("failed to list log files", zap.String("logDir", logDir), zap.Error(err))
+			}
+		}
+	}
+	return resp, nil
+}
+
+// rootDataDirs returns the network root data directories in the temporary
+// directory, of the tenant if not nil.
+func rootDataDirs(t *Tenant) ([]string, error) {
+	patterns := []string{
+		filepath.Join(os.TempDir(), rootDataDirPrefix+"*"),
+		filepath.Join(os.TempDir(), "network-runner-*", rootDataDirPrefix+"*"),
+	}
+	if t != nil {
+		patterns = []string{filepath.Join(os.TempDir(), "network-runner-"+t.Name, rootDataDirPrefix+"*")}
+	}
+	var dirs []string
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range matches {
+			if fi, err := os.Stat(m); err == nil && fi.IsDir() {
+				dirs = append(dirs, m)
+			}
+		}
+	}
+	return dirs, nil
+}
+
+// dirUsage returns the total size of the files of the directory,
+// and the time of the most recently modified one.
+func dirUsage(path string) (gcDir, error) {
+	d := gcDir{path: path}
+	err := filepath.Walk(path, func(_ string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.ModTime().After(d.modTime) {
+			d.modTime = fi.ModTime()
+		}
+		if fi.Mode().IsRegular() {
+			d.size += uint64(fi.Size())
+		}
+		return nil
+	})
+	return d, err
+}
+
+// isRotatedLog returns true for the log files the node rotated out
+// (e.g., "main.log.1", or compressed), which it no longer writes to.
+func isRotatedLog(name string) bool {
+	return strings.HasSuffix(name, ".gz") || strings.Contains(name, ".log.")
+}
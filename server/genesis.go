@@ -0,0 +1,175 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/lasthyphen/dijetsnodego/utils/constants"
+	"github.com/lasthyphen/dijetsnodego/utils/formatting/address"
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+)
+
+// wei per nAVAX on the C-chain
+var weiPerNAVAX = big.NewInt(1_000_000_000)
+
+// placeholder ETH address of the X/P-chain allocations
+const zeroETHAddr = "0x0000000000000000000000000000000000000000"
+
+var ErrInvalidGenesisBalance = errors.New("invalid genesis balance")
+
+// addGenesisBalances returns the genesis (the node "genesis" config JSON)
+// with the balances added: "X-" and "P-" addresses as allocations (unlocked
+// on the P-chain), and "0x" addresses to the C-chain genesis "alloc".
+func addGenesisBalances(genesis string, balances []*rpcpb.GenesisBalance) (string, error) {
+	if len(balances) == 0 {
+		return genesis, nil
+	}
+
+	g, err := decodeJSON(genesis)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse genesis: %w", err)
+	}
+	networkID, err := genesisNetworkID(g)
+	if err != nil {
+		return "", err
+	}
+	hrp := constants.GetHRP(networkID)
+
+	allocations, _ := g["allocations"].([]interface{})
+	var cGenesis map[string]interface{}
+	for _, b := range balances {
+		if b.Amount == 0 {
+			return "", fmt.Errorf("%w: zero amount for %q", ErrInvalidGenesisBalance, b.Address)
+		}
+
+		if strings.HasPrefix(b.Address, "0x") {
+			if cGenesis == nil {
+				if cGenesis, err = parseCChainGenesis(g); err != nil {
+					return "", err
+				}
+			}
+			if err := addCChainBalance(cGenesis, b); err != nil {
+				return "", err
+			}
+			continue
+		}
+
+		chain, _, addr, err := address.Parse(b.Address)
+		if err != nil {
+			return "", fmt.Errorf("%w: %q: %v", ErrInvalidGenesisBalance, b.Address, err)
+		}
+		// the address HRP must match the network, whatever the request used
+		avaxAddr, err := address.Format("X", hrp, addr)
+		if err != nil {
+			return "", err
+		}
+		alloc := map[string]interface{}{
+			"ethAddr":        zeroETHAddr,
+			"avaxAddr":       avaxAddr,
+			"initialAmount":  0,
+			"unlockSchedule": []interface{}{},
+		}
+		switch chain {
+		case "X":
+			alloc["initialAmount"] = b.Amount
+		case "P":
+			alloc["unlockSchedule"] = []interface{}{
+				map[string]interface{}{"amount": b.Amount, "locktime": 0},
+			}
+		default:
+			return "", fmt.Errorf("%w: unsupported chain %q of %q", ErrInvalidGenesisBalance, chain, b.Address)
+		}
+		allocations = append(allocations, alloc)
+	}
+	g["allocations"] = allocations
+
+	if cGenesis != nil {
+		cb, err := json.Marshal(cGenesis)
+		if err != nil {
+			return "", err
+		}
+		// the C-chain genesis is embedded as a JSON string
+		g["cChainGenesis"] = string(cb)
+	}
+	b, err := json.Marshal(g)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func genesisNetworkID(g map[string]interface{}) (uint32, error) {
+	networkID, err := strconv.ParseUint(fmt.Sprint(g["networkID"]), 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid genesis network ID %v", g["networkID"])
+	}
+	return uint32(networkID), nil
+}
+
+func parseCChainGenesis(g map[string]interface{}) (map[string]interface{}, error) {
+	s, ok := g["cChainGenesis"].(string)
+	if !ok {
+		return nil, fmt.Errorf("unexpected C-chain genesis type %T", g["cChainGenesis"])
+	}
+	cGenesis, err := decodeJSON(s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse C-chain genesis: %w", err)
+	}
+	return cGenesis, nil
+}
+
+// decodeJSON decodes a JSON object, keeping the numbers as is
+// (the genesis amounts overflow the float64 precision).
+func decodeJSON(s string) (map[string]interface{}, error) {
+	d := json.NewDecoder(strings.NewReader(s))
+	d.UseNumber()
+	var m map[string]interface{}
+	if err := d.Decode(&m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// addCChainBalance adds the balance (in nAVAX) to the "alloc" of the C-chain genesis.
+func addCChainBalance(cGenesis map[string]interface{}, b *rpcpb.GenesisBalance) error {
+	hexAddr := strings.ToLower(strings.TrimPrefix(b.Address, "0x"))
+	if raw, err := hex.DecodeString(hexAddr); err != nil || len(raw) != 20 {
+		return fmt.Errorf("%w: invalid C-chain address %q", ErrInvalidGenesisBalance, b.Address)
+	}
+
+	alloc, _ := cGenesis["alloc"].(map[string]interface{})
+	if alloc == nil {
+		alloc = make(map[string]interface{})
+		cGenesis["alloc"] = alloc
+	}
+	balance := new(big.Int).Mul(new(big.Int).SetUint64(b.Amount), weiPerNAVAX)
+	// adds to the existing balance, e.g., of the prefunded test key,
+	// whose address may be checksummed (mixed case)
+	for k := range alloc {
+		if strings.EqualFold(strings.TrimPrefix(k, "0x"), hexAddr) {
+			hexAddr = k
+			break
+		}
+	}
+	if account, ok := alloc[hexAddr].(map[string]interface{}); ok {
+		if s, ok := account["balance"].(string); ok {
+			existing, ok := new(big.Int).SetString(strings.TrimPrefix(s, "0x"), 16)
+			if !ok {
+				return fmt.Errorf("invalid C-chain genesis balance %q of %q", s, b.Address)
+			}
+			balance.Add(balance, existing)
+		}
+		account["balance"] = "0x" + balance.Text(16)
+		return nil
+	}
+	alloc[hexAddr] = map[string]interface{}{"balance": "0x" + balance.Text(16)}
+	return nil
+}
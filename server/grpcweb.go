@@ -0,0 +1,42 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"net/http"
+
+	"github.com/improbable-eng/grpc-web/go/grpcweb"
+	"google.golang.org/grpc"
+)
+
+// grpcWebHandler serves the gRPC-Web requests (and their CORS preflights)
+// with the gRPC server, so browser clients call the services directly, and
+// the other requests with the next handler. The bidirectional streams (e.g.,
+// "AttachConsole") go over websockets. Browsers on the other origins are
+// denied, unless allowed ("*" for all).
+func grpcWebHandler(gRPCServer *grpc.Server, allowedOrigins []string, next http.Handler) http.Handler {
+	allowed := func(origin string) bool {
+		for _, o := range allowedOrigins {
+			if o == "*" || o == origin {
+				return true
+			}
+		}
+		return false
+	}
+	wrapped := grpcweb.WrapServer(
+		gRPCServer,
+		grpcweb.WithOriginFunc(allowed),
+		grpcweb.WithWebsockets(true),
+		grpcweb.WithWebsocketOriginFunc(func(req *http.Request) bool {
+			return allowed(req.Header.Get("Origin"))
+		}),
+	)
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if wrapped.IsGrpcWebRequest(req) || wrapped.IsAcceptableGrpcCorsRequest(req) || wrapped.IsGrpcWebSocketRequest(req) {
+			wrapped.ServeHTTP(w, req)
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
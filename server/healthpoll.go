@@ -0,0 +1,188 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/lasthyphen/dijetsnode-go-runner/network/node"
+	"github.com/lasthyphen/djtx-tester/pkg/jsonrpc"
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultHealthPollInitialInterval = 500 * time.Millisecond
+	// the fixed interval of the network runner polls
+	defaultHealthPollMaxInterval = 3 * time.Second
+)
+
+var ErrInvalidHealthPolling = errors.New("invalid health polling")
+
+// healthPollOptions are the parsed "HealthPolling" of the start request.
+type healthPollOptions struct {
+	initialInterval time.Duration
+	maxInterval     time.Duration
+}
+
+func parseHealthPolling(p *rpcpb.HealthPolling) (healthPollOptions, error) {
+	opts := healthPollOptions{
+		initialInterval: defaultHealthPollInitialInterval,
+		maxInterval:     defaultHealthPollMaxInterval,
+	}
+	if p == nil {
+		return opts, nil
+	}
+	if p.InitialInterval != nil {
+		d, err := time.ParseDuration(p.GetInitialInterval())
+		if err != nil || d <= 0 {
+			return opts, fmt.Errorf("%w: invalid initial interval %q", ErrInvalidHealthPolling, p.GetInitialInterval())
+		}
+		opts.initialInterval = d
+	}
+	if p.MaxInterval != nil {
+		d, err := time.ParseDuration(p.GetMaxInterval())
+		if err != nil || d <= 0 {
+			return opts, fmt.Errorf("%w: invalid max interval %q", ErrInvalidHealthPolling, p.GetMaxInterval())
+		}
+		opts.maxInterval = d
+	}
+	if opts.maxInterval < opts.initialInterval {
+		return opts, fmt.Errorf("%w: max interval %v below the initial interval %v", ErrInvalidHealthPolling, opts.maxInterval, opts.initialInterval)
+	}
+	return opts, nil
+}
+
+// nodeProcess identifies the process of a node (ref. "findNodePID").
+type nodeProcess struct {
+	execPath string
+	logDir   string
+}
+
+// nodeProcesses returns the processes of the nodes, for the health polls
+// to detect the nodes that exit. Must be called with the lock held.
+func (lc *localNetwork) nodeProcesses() map[string]nodeProcess {
+	procs := make(map[string]nodeProcess, len(lc.nodeInfos))
+	for name, info := range lc.nodeInfos {
+		procs[name] = nodeProcess{execPath: info.ExecPath, logDir: info.LogDir}
+	}
+	return procs
+}
+
+// pollHealthy polls the health API of each node of the processes until all
+// report healthy, a node exits, or the context is done. Each node is polled
+// with its own exponential backoff (from the initial to the max interval)
+// and jitter, so the nodes launched at once are not all polled at once.
+func (lc *localNetwork) pollHealthy(ctx context.Context, procs map[string]nodeProcess) error {
+	nodes, err := lc.nw.GetAllNodes()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	for name, nd := range nodes {
+		if _, ok := procs[name]; !ok {
+			continue
+		}
+		wg.Add(1)
+		go func(name string, nd node.Node) {
+			defer wg.Done()
+			if err := lc.pollNodeHealthy(ctx, name, nd, procs[name]); err != nil {
+				errOnce.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}(name, nd)
+	}
+	wg.Wait()
+	return firstErr
+}
+
+func (lc *localNetwork) pollNodeHealthy(ctx context.Context, name string, nd node.Node, proc nodeProcess) error {
+	uri := fmt.Sprintf("http://%s:%d", nd.GetURL(), nd.GetAPIPort())
+	popts := lc.opts.healthPoll
+	if popts.initialInterval <= 0 {
+		popts = healthPollOptions{initialInterval: defaultHealthPollInitialInterval, maxInterval: defaultHealthPollMaxInterval}
+	}
+	interval := popts.initialInterval
+	pid := 0
+	for polls := 1; ; polls++ {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(jitter(interval)):
+		}
+
+		healthy, err := pollNodeHealth(ctx, uri)
+		if healthy {
+			zap.L().Debug("node became healthy", zap.String("name", name), zap.Int("polls", polls))
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		// unreachable, so the node may have exited
+		if err != nil && proc.execPath != "" {
+			if pid == 0 {
+				// not found until the node opens its logs,
+				// or on the platforms without the process lookup
+				pid, _ = findNodePID(proc.execPath, proc.logDir)
+			} else if !processRunning(pid) {
+				return fmt.Errorf("node %q stopped unexpectedly", name)
+			}
+		}
+
+		interval *= 2
+		if interval > popts.maxInterval {
+			interval = popts.maxInterval
+		}
+	}
+}
+
+// pollNodeHealth returns whether the node reports healthy, and the error
+// if the node is unreachable.
+func pollNodeHealth(ctx context.Context, uri string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, nodeProbeTimeout)
+	defer cancel()
+
+	// ref. api/health "Health"
+	var health struct {
+		Healthy bool `json:"healthy"`
+	}
+	if err := jsonrpc.Call(ctx, uri, "/ext/health", "health.health", nil, &health); err != nil {
+		return false, err
+	}
+	return health.Healthy, nil
+}
+
+// jitter returns a random duration in [d/2, d).
+func jitter(d time.Duration) time.Duration {
+	half := d / 2
+	if half <= 0 {
+		return d
+	}
+	return half + time.Duration(rand.Int63n(int64(half)))
+}
+
+func processRunning(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	return p.Signal(syscall.Signal(0)) == nil
+}
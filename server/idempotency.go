@@ -0,0 +1,164 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/textproto"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/lasthyphen/djtx-tester/pkg/tracing"
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+// metadata key of the idempotency key, also read from the
+// "Idempotency-Key" HTTP header by the gRPC gateway
+const idempotencyKeyHeader = "idempotency-key"
+
+// time the results are kept, if not configured
+const defaultIdempotencyWindow = 10 * time.Minute
+
+var ErrIdempotencyKeyReused = errors.New("idempotency key reused with a different request")
+
+// idempotentMethods are the control methods that accept an idempotency key.
+var idempotentMethods = map[string]bool{
+	"/" + rpcpb.ControlService_ServiceDesc.ServiceName + "/Start":   true,
+	"/" + rpcpb.ControlService_ServiceDesc.ServiceName + "/Stop":    true,
+	"/" + rpcpb.ControlService_ServiceDesc.ServiceName + "/AddNode": true,
+}
+
+// idempotencyCache keeps the results of the requests by idempotency key,
+// so a retried request (e.g., after a client timeout) gets the result of the
+// first one, instead of running again.
+type idempotencyCache struct {
+	window time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*idempotencyEntry
+}
+
+type idempotencyEntry struct {
+	method string
+	// serialized request, to detect a key reused for another request
+	req     []byte
+	expires time.Time
+
+	// closed once the first request returns
+	done chan struct{}
+	resp interface{}
+	err  error
+}
+
+func newIdempotencyCache(window time.Duration) *idempotencyCache {
+	if window <= 0 {
+		window = defaultIdempotencyWindow
+	}
+	return &idempotencyCache{
+		window:  window,
+		entries: make(map[string]*idempotencyEntry),
+	}
+}
+
+// unaryIdempotencyInterceptor runs the first request of a key, and returns
+// its result to the following ones within the window, waiting for it if the
+// first request is still running. The first request runs to completion even
+// if its client gives up, so its retry does not find a half-done operation.
+// It must run after the authentication, so the keys of a tenant do not match
+// the ones of another, and before the operation tracking, so an operation
+// stays in flight until its detached handler returns.
+func (s *server) unaryIdempotencyInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if !idempotentMethods[info.FullMethod] {
+		return handler(ctx, req)
+	}
+	key := idempotencyKey(ctx)
+	if key == "" {
+		return handler(ctx, req)
+	}
+	msg, ok := req.(proto.Message)
+	if !ok {
+		return handler(ctx, req)
+	}
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	if t := tenantFromContext(ctx); t != nil {
+		key = t.Name + "/" + key
+	}
+
+	c := s.idempotency
+	now := time.Now()
+	c.mu.Lock()
+	for k, e := range c.entries {
+		if now.After(e.expires) {
+			delete(c.entries, k)
+		}
+	}
+	e, ok := c.entries[key]
+	if ok {
+		c.mu.Unlock()
+		if e.method != info.FullMethod || !bytes.Equal(e.req, b) {
+			return nil, ErrIdempotencyKeyReused
+		}
+		zap.L().Info("replaying the result of the idempotency key",
+			zap.String("method", info.FullMethod),
+			zap.String("key", key),
+		)
+		select {
+		case <-e.done:
+			return e.resp, e.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	e = &idempotencyEntry{
+		method:  info.FullMethod,
+		req:     b,
+		expires: now.Add(c.window),
+		done:    make(chan struct{}),
+	}
+	c.entries[key] = e
+	c.mu.Unlock()
+
+	// detached from the client cancellation, keeping the trace and the tenant
+	hctx := context.WithValue(tracing.Detach(ctx), tenantKey{}, tenantFromContext(ctx))
+	go func() {
+		e.resp, e.err = handler(hctx, req)
+		close(e.done)
+	}()
+	select {
+	case <-e.done:
+		return e.resp, e.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func idempotencyKey(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, v := range md.Get(idempotencyKeyHeader) {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// gatewayHeaderMatcher forwards the "Idempotency-Key" HTTP header
+// as the gRPC metadata, besides the default headers.
+func gatewayHeaderMatcher(key string) (string, bool) {
+	if textproto.CanonicalMIMEHeaderKey(key) == "Idempotency-Key" {
+		return idempotencyKeyHeader, true
+	}
+	return runtime.DefaultHeaderMatcher(key)
+}
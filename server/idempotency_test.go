@@ -0,0 +1,62 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/metadata"
+)
+
+// chainUnary chains the interceptors as the gRPC server does.
+func chainUnary(interceptors []grpc.UnaryServerInterceptor, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) grpc.UnaryHandler {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], handler
+		handler = func(ctx context.Context, req interface{}) (interface{}, error) {
+			return interceptor(ctx, req, info, next)
+		}
+	}
+	return handler
+}
+
+func TestIdempotentOperationInFlight(t *testing.T) {
+	s := &server{
+		readiness:   newReadiness(health.NewServer()),
+		idempotency: newIdempotencyCache(0),
+	}
+	entered, release, returned := make(chan struct{}), make(chan struct{}), make(chan struct{})
+	info := &grpc.UnaryServerInfo{FullMethod: "/" + rpcpb.ControlService_ServiceDesc.ServiceName + "/Stop"}
+	call := chainUnary(s.unaryInterceptors(), info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		defer close(returned)
+		close(entered)
+		<-release
+		return &rpcpb.StopResponse{}, nil
+	})
+
+	ctx, cancel := context.WithCancel(metadata.NewIncomingContext(context.Background(), metadata.Pairs(idempotencyKeyHeader, "stop-1")))
+	cancel()
+	if _, err := call(ctx, &rpcpb.StopRequest{}); err == nil {
+		t.Fatal("expected the canceled call to fail")
+	}
+	<-entered
+	// the client gave up, the operation did not
+	if ops := s.readiness.report().Operations; len(ops) != 1 || ops[0] != "Stop" {
+		t.Fatalf("expected the stop in flight, got %q", ops)
+	}
+
+	close(release)
+	<-returned
+	deadline := time.Now().Add(5 * time.Second)
+	for len(s.readiness.report().Operations) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected no operation in flight, got %q", s.readiness.report().Operations)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,266 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"time"
+
+	"github.com/lasthyphen/dijetsnodego/ids"
+	"github.com/lasthyphen/dijetsnodego/snow/engine/avalanche/vertex"
+	"github.com/lasthyphen/dijetsnodego/vms/platformvm/blocks"
+	"github.com/lasthyphen/dijetsnodego/vms/proposervm/block"
+	"github.com/lasthyphen/dijetsnodego/wallet/chain/x"
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+	"go.uber.org/zap"
+)
+
+// the max message size of the node IPC sockets
+const maxIPCMessageBytes = 2 << 20
+
+var ErrChainIPCNotFound = errors.New("chain IPC not found")
+
+// ListChainIPCs lists the chain IPC publishers of the nodes, as published
+// by "CreateChainIPC".
+func (s *server) ListChainIPCs(ctx context.Context, req *rpcpb.ListChainIPCsRequest) (*rpcpb.ListChainIPCsResponse, error) {
+	zap.L().Debug("received list chain IPCs request", zap.String("nodeName", req.GetNodeName()))
+	if info := s.getClusterInfo(); info == nil {
+		return nil, ErrNotBootstrapped
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.network == nil {
+		return nil, ErrNotBootstrapped
+	}
+	names, err := s.ipcNodeNames(req.GetNodeName())
+	if err != nil {
+		return nil, err
+	}
+	resp := &rpcpb.ListChainIPCsResponse{}
+	for _, name := range names {
+		chains := s.network.nodeInfos[name].ChainIpcs
+		chainIDs := make([]string, 0, len(chains))
+		for id := range chains {
+			chainIDs = append(chainIDs, id)
+		}
+		sort.Strings(chainIDs)
+		for _, id := range chainIDs {
+			c := chains[id]
+			resp.Publishers = append(resp.Publishers, &rpcpb.NodeChainIPC{
+				NodeName: name,
+				Ipc: &rpcpb.ChainIPC{
+					BlockchainId: c.BlockchainId,
+					ConsensusUrl: c.ConsensusUrl,
+					DecisionsUrl: c.DecisionsUrl,
+				},
+			})
+		}
+	}
+	return resp, nil
+}
+
+// TailChainIPC connects to the IPC sockets of a published chain of the node
+// and streams the accepted containers, with their IDs and as decoded (ref.
+// "decodeContainer"), until the client
+// cancels, the chain is unpublished, or the node exits. Only the events
+// accepted after the connection are streamed.
+func (s *server) TailChainIPC(req *rpcpb.TailChainIPCRequest, stream rpcpb.ControlService_TailChainIPCServer) error {
+	zap.L().Info("received tail chain IPC request",
+		zap.String("nodeName", req.NodeName),
+		zap.String("blockchainID", req.BlockchainId),
+		zap.String("feed", req.Feed.String()),
+	)
+	if req.BlockchainId == "" {
+		return ErrEmptyBlockchainID
+	}
+
+	s.mu.RLock()
+	if s.network == nil {
+		s.mu.RUnlock()
+		return ErrNotBootstrapped
+	}
+	if err := s.checkOwnerLocked(stream.Context()); err != nil {
+		s.mu.RUnlock()
+		return err
+	}
+	info, ok := s.network.nodeInfos[req.NodeName]
+	if !ok {
+		s.mu.RUnlock()
+		return ErrNodeNotFound
+	}
+	c, ok := info.ChainIpcs[req.BlockchainId]
+	if !ok {
+		s.mu.RUnlock()
+		return fmt.Errorf("%w: %q on %q", ErrChainIPCNotFound, req.BlockchainId, req.NodeName)
+	}
+	feeds := make(map[rpcpb.ChainIPCFeed]string, 2)
+	if req.Feed != rpcpb.ChainIPCFeed_CHAIN_IPC_FEED_DECISIONS {
+		feeds[rpcpb.ChainIPCFeed_CHAIN_IPC_FEED_CONSENSUS] = c.ConsensusUrl
+	}
+	if req.Feed != rpcpb.ChainIPCFeed_CHAIN_IPC_FEED_CONSENSUS {
+		feeds[rpcpb.ChainIPCFeed_CHAIN_IPC_FEED_DECISIONS] = c.DecisionsUrl
+	}
+	stopCtx := s.network.stopCtx
+	s.mu.RUnlock()
+
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+	go func() {
+		select {
+		case <-stopCtx.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	conns := make([]net.Conn, 0, len(feeds))
+	defer func() {
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}()
+	eventc := make(chan *rpcpb.ChainIPCEvent)
+	errc := make(chan error, len(feeds))
+	for feed, path := range feeds {
+		conn, err := net.Dial("unix", path)
+		if err != nil {
+			return fmt.Errorf("failed to connect to the %s socket %q: %w", feed, path, err)
+		}
+		conns = append(conns, conn)
+		go func(feed rpcpb.ChainIPCFeed, conn net.Conn) {
+			errc <- readIPCEvents(ctx, conn, eventc, &rpcpb.ChainIPCEvent{
+				NodeName:     req.NodeName,
+				BlockchainId: req.BlockchainId,
+				Feed:         feed,
+			}, req.IncludeBytes)
+		}(feed, conn)
+	}
+	// unblocks the reads
+	go func() {
+		<-ctx.Done()
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}()
+
+	for done := 0; done < len(conns); {
+		select {
+		case ev := <-eventc:
+			if err := stream.Send(&rpcpb.TailChainIPCResponse{Event: ev}); err != nil {
+				return err
+			}
+		case err := <-errc:
+			done++
+			if err != nil && ctx.Err() == nil {
+				return err
+			}
+		}
+	}
+	zap.L().Info("chain IPC tail done", zap.String("nodeName", req.NodeName), zap.String("blockchainID", req.BlockchainId))
+	if stopCtx.Err() != nil {
+		return errAborted
+	}
+	return nil
+}
+
+// readIPCEvents reads the messages of the socket (a big-endian uint64 size,
+// then the container) into events of the template, until the socket closes.
+// Returns nil once closed by the node (e.g., unpublished).
+func readIPCEvents(ctx context.Context, r io.Reader, eventc chan<- *rpcpb.ChainIPCEvent, tmpl *rpcpb.ChainIPCEvent, includeBytes bool) error {
+	var seq uint64
+	for {
+		var size uint64
+		if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+			if errors.Is(err, io.EOF) || ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		if size > maxIPCMessageBytes {
+			return fmt.Errorf("%s message of %d bytes, above %d", tmpl.Feed, size, maxIPCMessageBytes)
+		}
+		container := make([]byte, size)
+		if _, err := io.ReadFull(r, container); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		seq++
+		ev := &rpcpb.ChainIPCEvent{
+			NodeName:     tmpl.NodeName,
+			BlockchainId: tmpl.BlockchainId,
+			Feed:         tmpl.Feed,
+			ContainerId:  ids.ID(sha256.Sum256(container)).String(),
+			Size:         size,
+			ReceivedAt:   time.Now().UTC().Format(time.RFC3339Nano),
+			Seq:          seq,
+		}
+		decodeContainer(ev, container)
+		if includeBytes {
+			ev.Container = container
+		}
+		select {
+		case eventc <- ev:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// decodeContainer sets the type, height, parents, and txs of the event from
+// its container, if of the dijetsnodego codecs: the vertices of the DAG
+// chains (consensus feed), the txs of the X-chain (decisions feed), and the
+// blocks of the P-chain and of the proposer VM. The blocks of the other VMs
+// (e.g., the C-chain) are only decoded if wrapped by the proposer VM, without
+// their height and txs. The other containers are left undecoded.
+func decodeContainer(ev *rpcpb.ChainIPCEvent, container []byte) {
+	if ev.Feed == rpcpb.ChainIPCFeed_CHAIN_IPC_FEED_CONSENSUS {
+		if vtx, err := vertex.Parse(container); err == nil && vtx.Verify() == nil {
+			ev.ContainerType = rpcpb.ChainIPCContainerType_CHAIN_IPC_CONTAINER_TYPE_VERTEX
+			ev.Height = vtx.Height()
+			for _, id := range vtx.ParentIDs() {
+				ev.ParentIds = append(ev.ParentIds, id.String())
+			}
+			for _, tx := range vtx.Txs() {
+				ev.TxIds = append(ev.TxIds, ids.ID(sha256.Sum256(tx)).String())
+			}
+			return
+		}
+	}
+
+	// once activated, the proposer VM wraps the blocks, chained by its IDs
+	inner, wrapped := container, false
+	if blk, err := block.Parse(container); err == nil {
+		ev.ContainerType = rpcpb.ChainIPCContainerType_CHAIN_IPC_CONTAINER_TYPE_BLOCK
+		ev.ParentIds = []string{blk.ParentID().String()}
+		inner, wrapped = blk.Block(), true
+	}
+	if blk, err := blocks.Parse(blocks.Codec, inner); err == nil {
+		ev.ContainerType = rpcpb.ChainIPCContainerType_CHAIN_IPC_CONTAINER_TYPE_BLOCK
+		ev.Height = blk.Height()
+		if !wrapped {
+			ev.ParentIds = []string{blk.Parent().String()}
+		}
+		for _, tx := range blk.Txs() {
+			ev.TxIds = append(ev.TxIds, tx.ID().String())
+		}
+		return
+	}
+	if !wrapped && ev.Feed == rpcpb.ChainIPCFeed_CHAIN_IPC_FEED_DECISIONS {
+		if tx, err := x.Parser.Parse(container); err == nil {
+			ev.ContainerType = rpcpb.ChainIPCContainerType_CHAIN_IPC_CONTAINER_TYPE_TX
+			ev.TxIds = []string{tx.ID().String()}
+		}
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lasthyphen/dijetsnodego/ids"
+	"github.com/lasthyphen/dijetsnodego/snow/engine/avalanche/vertex"
+	avmtxs "github.com/lasthyphen/dijetsnodego/vms/avm/txs"
+	"github.com/lasthyphen/dijetsnodego/vms/components/djtx"
+	"github.com/lasthyphen/dijetsnodego/vms/platformvm/blocks"
+	"github.com/lasthyphen/dijetsnodego/vms/platformvm/txs"
+	"github.com/lasthyphen/dijetsnodego/vms/proposervm/block"
+	"github.com/lasthyphen/dijetsnodego/wallet/chain/x"
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+)
+
+func TestDecodeContainer(t *testing.T) {
+	xTx := &avmtxs.Tx{Unsigned: &avmtxs.BaseTx{BaseTx: djtx.BaseTx{NetworkID: 1337, BlockchainID: ids.GenerateTestID()}}}
+	if err := xTx.SignSECP256K1Fx(x.Parser.Codec(), nil); err != nil {
+		t.Fatal(err)
+	}
+	vtxParent := ids.GenerateTestID()
+	vtx, err := vertex.Build(ids.GenerateTestID(), 3, []ids.ID{vtxParent}, [][]byte{xTx.Bytes()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pTx, err := txs.NewSigned(&txs.AdvanceTimeTx{Time: 1}, txs.Codec, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pParent := ids.GenerateTestID()
+	pBlk, err := blocks.NewApricotProposalBlock(pParent, 7, pTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proParent := ids.GenerateTestID()
+	proBlk, err := block.BuildUnsigned(proParent, time.Unix(1, 0), 0, pBlk.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// e.g., a C-chain block
+	otherBlk, err := block.BuildUnsigned(proParent, time.Unix(1, 0), 0, []byte("block"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	consensus, decisions := rpcpb.ChainIPCFeed_CHAIN_IPC_FEED_CONSENSUS, rpcpb.ChainIPCFeed_CHAIN_IPC_FEED_DECISIONS
+	for i, tv := range []struct {
+		feed      rpcpb.ChainIPCFeed
+		container []byte
+		exp       *rpcpb.ChainIPCEvent
+	}{
+		{
+			feed:      consensus,
+			container: vtx.Bytes(),
+			exp: &rpcpb.ChainIPCEvent{
+				ContainerType: rpcpb.ChainIPCContainerType_CHAIN_IPC_CONTAINER_TYPE_VERTEX,
+				Height:        3,
+				ParentIds:     []string{vtxParent.String()},
+				TxIds:         []string{xTx.ID().String()},
+			},
+		},
+		{
+			feed:      decisions,
+			container: xTx.Bytes(),
+			exp: &rpcpb.ChainIPCEvent{
+				ContainerType: rpcpb.ChainIPCContainerType_CHAIN_IPC_CONTAINER_TYPE_TX,
+				TxIds:         []string{xTx.ID().String()},
+			},
+		},
+		{
+			feed:      decisions,
+			container: pBlk.Bytes(),
+			exp: &rpcpb.ChainIPCEvent{
+				ContainerType: rpcpb.ChainIPCContainerType_CHAIN_IPC_CONTAINER_TYPE_BLOCK,
+				Height:        7,
+				ParentIds:     []string{pParent.String()},
+				TxIds:         []string{pTx.ID().String()},
+			},
+		},
+		{
+			feed:      consensus,
+			container: proBlk.Bytes(),
+			exp: &rpcpb.ChainIPCEvent{
+				ContainerType: rpcpb.ChainIPCContainerType_CHAIN_IPC_CONTAINER_TYPE_BLOCK,
+				Height:        7,
+				ParentIds:     []string{proParent.String()},
+				TxIds:         []string{pTx.ID().String()},
+			},
+		},
+		{
+			feed:      decisions,
+			container: otherBlk.Bytes(),
+			exp: &rpcpb.ChainIPCEvent{
+				ContainerType: rpcpb.ChainIPCContainerType_CHAIN_IPC_CONTAINER_TYPE_BLOCK,
+				ParentIds:     []string{proParent.String()},
+			},
+		},
+		// the vertices are only accepted by the consensus feed
+		{feed: decisions, container: vtx.Bytes(), exp: &rpcpb.ChainIPCEvent{}},
+		{feed: consensus, container: []byte("container"), exp: &rpcpb.ChainIPCEvent{}},
+	} {
+		ev := &rpcpb.ChainIPCEvent{Feed: tv.feed}
+		decodeContainer(ev, tv.container)
+		if ev.ContainerType != tv.exp.ContainerType || ev.Height != tv.exp.Height ||
+			!reflect.DeepEqual(ev.ParentIds, tv.exp.ParentIds) || !reflect.DeepEqual(ev.TxIds, tv.exp.TxIds) {
+			t.Fatalf("#%d: expected %v, got %v", i, tv.exp, ev)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/lasthyphen/djtx-tester/pkg/jsonrpc"
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+	"go.uber.org/zap"
+)
+
+var ErrEmptyBlockchainID = errors.New("empty blockchain ID")
+
+func (s *server) CreateChainIPC(ctx context.Context, req *rpcpb.CreateChainIPCRequest) (*rpcpb.CreateChainIPCResponse, error) {
+	zap.L().Info("received create chain IPC request",
+		zap.String("blockchainID", req.BlockchainId),
+		zap.String("nodeName", req.GetNodeName()),
+	)
+	if info := s.getClusterInfo(); info == nil {
+		return nil, ErrNotBootstrapped
+	}
+	if req.BlockchainId == "" {
+		return nil, ErrEmptyBlockchainID
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkStateLocked(stateHealthy, stateDegraded); err != nil {
+		return nil, err
+	}
+	names, err := s.ipcNodeNames(req.GetNodeName())
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		nodeInfo := s.network.nodeInfos[name]
+
+		// ref. api/ipcs "PublishBlockchain"
+		var reply struct {
+			ConsensusURL string `json:"consensusURL"`
+			DecisionsURL string `json:"decisionsURL"`
+		}
+		if err := jsonrpc.Call(ctx, nodeInfo.Uri, "/ext/ipcs", "ipcs.publishBlockchain", map[string]string{
+			"blockchainID": req.BlockchainId,
+		}, &reply); err != nil {
+			return nil, err
+		}
+
+		if nodeInfo.ChainIpcs == nil {
+			nodeInfo.ChainIpcs = make(map[string]*rpcpb.ChainIPC)
+		}
+		nodeInfo.ChainIpcs[req.BlockchainId] = &rpcpb.ChainIPC{
+			BlockchainId: req.BlockchainId,
+			ConsensusUrl: reply.ConsensusURL,
+			DecisionsUrl: reply.DecisionsURL,
+		}
+		zap.L().Info("published chain IPC",
+			zap.String("node", name),
+			zap.String("consensusURL", reply.ConsensusURL),
+			zap.String("decisionsURL", reply.DecisionsURL),
+		)
+	}
+	s.clusterInfo.NodeInfos = s.network.nodeInfos
+
+	return &rpcpb.CreateChainIPCResponse{ClusterInfo: s.copyClusterInfo()}, nil
+}
+
+func (s *server) RemoveChainIPC(ctx context.Context, req *rpcpb.RemoveChainIPCRequest) (*rpcpb.RemoveChainIPCResponse, error) {
+	zap.L().Info("received remove chain IPC request",
+		zap.String("blockchainID", req.BlockchainId),
+		zap.String("nodeName", req.GetNodeName()),
+	)
+	if info := s.getClusterInfo(); info == nil {
+		return nil, ErrNotBootstrapped
+	}
+	if req.BlockchainId == "" {
+		return nil, ErrEmptyBlockchainID
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkStateLocked(stateHealthy, stateDegraded); err != nil {
+		return nil, err
+	}
+	names, err := s.ipcNodeNames(req.GetNodeName())
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		nodeInfo := s.network.nodeInfos[name]
+		if _, ok := nodeInfo.ChainIpcs[req.BlockchainId]; !ok {
+			continue
+		}
+
+		// ref. api/ipcs "UnpublishBlockchain"
+		if err := jsonrpc.Call(ctx, nodeInfo.Uri, "/ext/ipcs", "ipcs.unpublishBlockchain", map[string]string{
+			"blockchainID": req.BlockchainId,
+		}, nil); err != nil {
+			return nil, err
+		}
+		delete(nodeInfo.ChainIpcs, req.BlockchainId)
+		zap.L().Info("unpublished chain IPC", zap.String("node", name))
+	}
+	s.clusterInfo.NodeInfos = s.network.nodeInfos
+
+	return &rpcpb.RemoveChainIPCResponse{ClusterInfo: s.copyClusterInfo()}, nil
+}
+
+// ipcNodeNames returns the sorted node names to apply an IPC request to,
+// or all nodes if the name is empty. Must be called with the lock held.
+func (s *server) ipcNodeNames(name string) ([]string, error) {
+	if name != "" {
+		if _, ok := s.network.nodeInfos[name]; !ok {
+			return nil, ErrNodeNotFound
+		}
+		return []string{name}, nil
+	}
+	names := make([]string, 0, len(s.network.nodeInfos))
+	for name := range s.network.nodeInfos {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
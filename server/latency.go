@@ -0,0 +1,93 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+)
+
+// runner operations whose latencies are reported by "Status"
+const (
+	opStart            = "start"
+	opWaitHealthy      = "wait_healthy"
+	opAddNode          = "add_node"
+	opRestartNode      = "restart_node"
+	opRestartNodes     = "restart_nodes"
+	opCreateBlockchain = "create_blockchain"
+)
+
+// upper bounds of the latency buckets, in seconds
+var latencyBounds = []float64{0.5, 1, 2.5, 5, 10, 20, 30, 60, 120, 300}
+
+// latencies records the latencies of the successful runner operations,
+// across the networks of the server (e.g., to compare node versions).
+type latencies struct {
+	mu    sync.Mutex
+	hists map[string]*rpcpb.LatencyHistogram
+}
+
+func newLatencies() *latencies {
+	return &latencies{hists: make(map[string]*rpcpb.LatencyHistogram)}
+}
+
+// observe records the latency of the operation started at the given time.
+// A nil receiver records nothing.
+func (l *latencies) observe(op string, start time.Time) {
+	if l == nil {
+		return
+	}
+	d := time.Since(start).Seconds()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	h, ok := l.hists[op]
+	if !ok {
+		h = &rpcpb.LatencyHistogram{
+			Operation: op,
+			Buckets:   make([]*rpcpb.LatencyBucket, len(latencyBounds)+1),
+		}
+		for i, b := range latencyBounds {
+			h.Buckets[i] = &rpcpb.LatencyBucket{UpperBoundSeconds: b}
+		}
+		h.Buckets[len(latencyBounds)] = &rpcpb.LatencyBucket{}
+		l.hists[op] = h
+	}
+	if h.Count == 0 || d < h.MinSeconds {
+		h.MinSeconds = d
+	}
+	if d > h.MaxSeconds {
+		h.MaxSeconds = d
+	}
+	h.Count++
+	h.SumSeconds += d
+	i := sort.SearchFloat64s(latencyBounds, d)
+	h.Buckets[i].Count++
+}
+
+// snapshot returns a copy of the histograms, sorted by operation.
+func (l *latencies) snapshot() []*rpcpb.LatencyHistogram {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	hists := make([]*rpcpb.LatencyHistogram, 0, len(l.hists))
+	for _, h := range l.hists {
+		c := &rpcpb.LatencyHistogram{
+			Operation:  h.Operation,
+			Count:      h.Count,
+			SumSeconds: h.SumSeconds,
+			MinSeconds: h.MinSeconds,
+			MaxSeconds: h.MaxSeconds,
+			Buckets:    make([]*rpcpb.LatencyBucket, len(h.Buckets)),
+		}
+		for i, b := range h.Buckets {
+			c.Buckets[i] = &rpcpb.LatencyBucket{UpperBoundSeconds: b.UpperBoundSeconds, Count: b.Count}
+		}
+		hists = append(hists, c)
+	}
+	sort.Slice(hists, func(i, j int) bool { return hists[i].Operation < hists[j].Operation })
+	return hists
+}
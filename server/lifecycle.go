@@ -0,0 +1,104 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrInvalidTransition = errors.New("invalid network state transition")
+	ErrInvalidState      = errors.New("invalid network state")
+)
+
+const (
+	stateCreating      = rpcpb.NetworkState_NETWORK_STATE_CREATING
+	stateBootstrapping = rpcpb.NetworkState_NETWORK_STATE_BOOTSTRAPPING
+	stateHealthy       = rpcpb.NetworkState_NETWORK_STATE_HEALTHY
+	stateDegraded      = rpcpb.NetworkState_NETWORK_STATE_DEGRADED
+	stateStopping      = rpcpb.NetworkState_NETWORK_STATE_STOPPING
+	stateStopped       = rpcpb.NetworkState_NETWORK_STATE_STOPPED
+	stateErrored       = rpcpb.NetworkState_NETWORK_STATE_ERRORED
+)
+
+// validTransitions maps each state to the states it may move to.
+var validTransitions = map[rpcpb.NetworkState][]rpcpb.NetworkState{
+	stateCreating:      {stateBootstrapping, stateStopping, stateErrored},
+	stateBootstrapping: {stateHealthy, stateDegraded, stateStopping, stateErrored},
+	stateHealthy:       {stateBootstrapping, stateDegraded, stateStopping, stateErrored},
+	stateDegraded:      {stateBootstrapping, stateHealthy, stateStopping, stateErrored},
+	stateErrored:       {stateStopping},
+	stateStopping:      {stateStopped},
+	stateStopped:       {},
+}
+
+// lifecycle tracks the state of a local network.
+type lifecycle struct {
+	mu    sync.RWMutex
+	state rpcpb.NetworkState
+	err   error
+}
+
+func newLifecycle() *lifecycle {
+	return &lifecycle{state: stateCreating}
+}
+
+// transition moves to the given state, recording the error (if any)
+// that caused it. Transitioning to the current state only updates the error.
+func (l *lifecycle) transition(to rpcpb.NetworkState, err error) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.state != to {
+		valid := false
+		for _, st := range validTransitions[l.state] {
+			if st == to {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, stateName(l.state), stateName(to))
+		}
+		zap.L().Info("network state transition",
+			zap.String("from", stateName(l.state)),
+			zap.String("to", stateName(to)),
+			zap.Error(err),
+		)
+	}
+	l.state, l.err = to, err
+	return nil
+}
+
+func (l *lifecycle) get() (rpcpb.NetworkState, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.state, l.err
+}
+
+// check returns an error if the current state is not one of the allowed ones.
+func (l *lifecycle) check(allowed ...rpcpb.NetworkState) error {
+	state, _ := l.get()
+	for _, st := range allowed {
+		if st == state {
+			return nil
+		}
+	}
+	names := make([]string, len(allowed))
+	for i, st := range allowed {
+		names[i] = stateName(st)
+	}
+	return fmt.Errorf("%w: network is %s (expected one of %s)", ErrInvalidState, stateName(state), strings.Join(names, ", "))
+}
+
+// e.g., "NETWORK_STATE_HEALTHY" becomes "healthy"
+func stateName(st rpcpb.NetworkState) string {
+	return strings.ToLower(strings.TrimPrefix(st.String(), "NETWORK_STATE_"))
+}
@@ -0,0 +1,46 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+)
+
+func TestLifecycle(t *testing.T) {
+	l := newLifecycle()
+	if err := l.check(stateHealthy); !errors.Is(err, ErrInvalidState) {
+		t.Fatalf("expected %v, got %v", ErrInvalidState, err)
+	}
+
+	for i, tv := range []struct {
+		to    rpcpb.NetworkState
+		valid bool
+	}{
+		{to: stateHealthy, valid: false},
+		{to: stateBootstrapping, valid: true},
+		{to: stateHealthy, valid: true},
+		{to: stateHealthy, valid: true},
+		{to: stateDegraded, valid: true},
+		{to: stateStopped, valid: false},
+		{to: stateStopping, valid: true},
+		{to: stateErrored, valid: false},
+		{to: stateStopped, valid: true},
+		{to: stateCreating, valid: false},
+	} {
+		err := l.transition(tv.to, nil)
+		if tv.valid && err != nil {
+			t.Fatalf("#%d: unexpected error %v", i, err)
+		}
+		if !tv.valid && !errors.Is(err, ErrInvalidTransition) {
+			t.Fatalf("#%d: expected %v, got %v", i, ErrInvalidTransition, err)
+		}
+	}
+
+	if st, _ := l.get(); st != stateStopped {
+		t.Fatalf("expected %s, got %s", stateName(stateStopped), stateName(st))
+	}
+}
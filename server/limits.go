@@ -0,0 +1,188 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	maxCPUWeight = 10000
+
+	// the node must open its log files within this time after launch
+	nodeProcessWait = 30 * time.Second
+)
+
+var (
+	ErrInvalidResourceLimits     = errors.New("invalid resource limits")
+	ErrResourceLimitsUnsupported = errors.New("resource limits are not supported on this platform")
+)
+
+// nodeResourceLimits returns the limits of the node, the per-node fields
+// overriding the network-wide ones, or nil if the node is unlimited.
+func nodeResourceLimits(all *rpcpb.ResourceLimits, node *rpcpb.ResourceLimits) (*rpcpb.ResourceLimits, error) {
+	if all == nil && node == nil {
+		return nil, nil
+	}
+	l := &rpcpb.ResourceLimits{}
+	if all != nil {
+		l = proto.Clone(all).(*rpcpb.ResourceLimits)
+	}
+	if node != nil {
+		proto.Merge(l, node)
+	}
+	if l.CpuWeight != nil && (l.GetCpuWeight() < 1 || l.GetCpuWeight() > maxCPUWeight) {
+		return nil, fmt.Errorf("%w: CPU weight %d not in [1, %d]", ErrInvalidResourceLimits, l.GetCpuWeight(), maxCPUWeight)
+	}
+	if l.CpuMillicores != nil && l.GetCpuMillicores() == 0 {
+		return nil, fmt.Errorf("%w: zero CPU millicores", ErrInvalidResourceLimits)
+	}
+	if l.MemoryMaxBytes != nil && l.GetMemoryMaxBytes() == 0 {
+		return nil, fmt.Errorf("%w: zero memory cap", ErrInvalidResourceLimits)
+	}
+	if l.Cpuset != nil {
+		if _, err := parseCPUList(l.GetCpuset()); err != nil {
+			return nil, err
+		}
+	}
+	if l.CpuWeight == nil && l.CpuMillicores == nil && l.MemoryMaxBytes == nil && l.Cpuset == nil {
+		return nil, nil
+	}
+	return l, nil
+}
+
+// parseCPUList returns the sorted cores of the cpuset list (e.g., "0-1,4"),
+// which must be CPUs of the host.
+func parseCPUList(list string) ([]int, error) {
+	seen := make(map[int]bool)
+	for _, part := range strings.Split(list, ",") {
+		lo, hi := part, part
+		if i := strings.Index(part, "-"); i >= 0 {
+			lo, hi = part[:i], part[i+1:]
+		}
+		first, err := strconv.Atoi(strings.TrimSpace(lo))
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid cpuset %q", ErrInvalidResourceLimits, list)
+		}
+		last, err := strconv.Atoi(strings.TrimSpace(hi))
+		if err != nil || first < 0 || last < first {
+			return nil, fmt.Errorf("%w: invalid cpuset %q", ErrInvalidResourceLimits, list)
+		}
+		if last >= runtime.NumCPU() {
+			return nil, fmt.Errorf("%w: cpuset %q beyond the %d host CPUs", ErrInvalidResourceLimits, list, runtime.NumCPU())
+		}
+		for c := first; c <= last; c++ {
+			seen[c] = true
+		}
+	}
+	cpus := make([]int, 0, len(seen))
+	for c := range seen {
+		cpus = append(cpus, c)
+	}
+	sort.Ints(cpus)
+	return cpus, nil
+}
+
+// nodeCgroupName is unique per network and node, and stable across node restarts.
+func nodeCgroupName(rootDataDir string, name string) string {
+	return fmt.Sprintf("network-runner-%s-%s", filepath.Base(rootDataDir), name)
+}
+
+// limitNodes moves the processes of the nodes into their cgroups.
+// The nodes without resource limits (nor traffic rules) are skipped.
+func (lc *localNetwork) limitNodes(ctx context.Context, names []string) error {
+	limited := make([]string, 0, len(names))
+	for _, name := range names {
+		if info, ok := lc.nodeInfos[name]; ok && (info.ResourceLimits != nil || lc.contained[name]) {
+			limited = append(limited, name)
+		}
+	}
+	if len(limited) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, nodeProcessWait)
+	defer cancel()
+	errs := runParallel(ctx, limited, 0, func(name string) error {
+		info := lc.nodeInfos[name]
+		pid, err := waitForNodePID(ctx, info)
+		if err != nil {
+			return err
+		}
+		cgroup := nodeCgroupName(lc.opts.rootDataDir, name)
+		limits := info.ResourceLimits
+		if limits == nil {
+			limits = &rpcpb.ResourceLimits{}
+		}
+		if err := limitProcess(cgroup, pid, limits); err != nil {
+			return err
+		}
+		zap.L().Info("limited node resources",
+			zap.String("name", name),
+			zap.Int("pid", pid),
+			zap.String("cgroup", cgroup),
+		)
+		return nil
+	})
+	for _, name := range limited {
+		if err := errs[name]; err != nil {
+			return fmt.Errorf("failed to limit the resources of %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// waitForNodePID waits for the node process to open its log files.
+func waitForNodePID(ctx context.Context, info *rpcpb.NodeInfo) (int, error) {
+	for {
+		pid, err := findNodePID(info.ExecPath, info.LogDir)
+		if errors.Is(err, ErrFaultUnsupported) {
+			return 0, ErrResourceLimitsUnsupported
+		}
+		if err == nil || !errors.Is(err, ErrNodeProcessNotFound) {
+			return pid, err
+		}
+		select {
+		case <-ctx.Done():
+			return 0, err
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+}
+
+// releaseCgroups removes the node cgroups, once their processes have exited.
+func (lc *localNetwork) releaseCgroups() {
+	for name, info := range lc.nodeInfos {
+		if info.ResourceLimits != nil || lc.contained[name] {
+			releaseCgroup(nodeCgroupName(lc.opts.rootDataDir, name))
+		}
+	}
+}
+
+// addResourceUsage sets the live resource usage of the limited nodes.
+func addResourceUsage(info *rpcpb.ClusterInfo) {
+	for name, ni := range info.NodeInfos {
+		if ni.ResourceLimits == nil {
+			continue
+		}
+		usage, err := readUsage(nodeCgroupName(info.RootDataDir, name))
+		if err != nil {
+			zap.L().Debug("failed to read node resource usage", zap.String("name", name), zap.Error(err))
+			continue
+		}
+		ni.ResourceUsage = usage
+	}
+}
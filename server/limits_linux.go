@@ -0,0 +1,109 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+//go:build linux
+// +build linux
+
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+)
+
+// cgroup v2 CPU period, in microseconds
+const cpuPeriodUsec = 100000
+
+// limitProcess moves the process into the cgroup (v2) with the limits,
+// creating the cgroup if it does not exist (e.g., on the first node launch).
+// Requires write access to the cgroup root (e.g., root privileges).
+func limitProcess(cgroup string, pid int, l *rpcpb.ResourceLimits) error {
+	if _, err := os.Stat(filepath.Join(cgroupRoot, "cgroup.controllers")); err != nil {
+		return fmt.Errorf("%w: cgroup v2 is not mounted at %q", ErrResourceLimitsUnsupported, cgroupRoot)
+	}
+	if err := ioutil.WriteFile(filepath.Join(cgroupRoot, "cgroup.subtree_control"), []byte("+cpu +memory"), 0o644); err != nil {
+		return fmt.Errorf("failed to enable the cpu and memory controllers: %w", err)
+	}
+	// only enabled when pinning, as some hosts do not delegate it
+	if l.Cpuset != nil {
+		if err := ioutil.WriteFile(filepath.Join(cgroupRoot, "cgroup.subtree_control"), []byte("+cpuset"), 0o644); err != nil {
+			return fmt.Errorf("%w: failed to enable the cpuset controller: %v", ErrResourceLimitsUnsupported, err)
+		}
+	}
+
+	dir := filepath.Join(cgroupRoot, cgroup)
+	if err := os.Mkdir(dir, 0o755); err != nil && !os.IsExist(err) {
+		return err
+	}
+	files := make([][2]string, 0, 5)
+	if l.CpuWeight != nil {
+		files = append(files, [2]string{"cpu.weight", strconv.FormatUint(uint64(l.GetCpuWeight()), 10)})
+	}
+	if l.CpuMillicores != nil {
+		quota := uint64(l.GetCpuMillicores()) * cpuPeriodUsec / 1000
+		files = append(files, [2]string{"cpu.max", fmt.Sprintf("%d %d", quota, cpuPeriodUsec)})
+	}
+	if l.MemoryMaxBytes != nil {
+		// swap accounting may be disabled, in which case the file does not exist
+		if err := ioutil.WriteFile(filepath.Join(dir, "memory.swap.max"), []byte("0"), 0o644); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		files = append(files, [2]string{"memory.max", strconv.FormatUint(l.GetMemoryMaxBytes(), 10)})
+	}
+	if l.Cpuset != nil {
+		files = append(files, [2]string{"cpuset.cpus", l.GetCpuset()})
+	}
+	files = append(files, [2]string{"cgroup.procs", strconv.Itoa(pid)})
+	for _, kv := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, kv[0]), []byte(kv[1]), 0o644); err != nil {
+			return fmt.Errorf("failed to write %q: %w", kv[0], err)
+		}
+	}
+	return nil
+}
+
+func readUsage(cgroup string) (*rpcpb.ResourceUsage, error) {
+	dir := filepath.Join(cgroupRoot, cgroup)
+	b, err := ioutil.ReadFile(filepath.Join(dir, "memory.current"))
+	if err != nil {
+		return nil, err
+	}
+	memory, err := strconv.ParseUint(string(bytes.TrimSpace(b)), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err = ioutil.ReadFile(filepath.Join(dir, "cpu.stat"))
+	if err != nil {
+		return nil, err
+	}
+	usage := &rpcpb.ResourceUsage{MemoryBytes: memory}
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	for sc.Scan() {
+		// e.g., "usage_usec 1234"
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 2 && fields[0] == "usage_usec" {
+			usage.CpuUsageUsec, err = strconv.ParseUint(fields[1], 10, 64)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return usage, sc.Err()
+}
+
+func releaseCgroup(cgroup string) {
+	dir := filepath.Join(cgroupRoot, cgroup)
+	if _, err := os.Stat(dir); err != nil {
+		return
+	}
+	go removeCgroup(dir)
+}
@@ -0,0 +1,19 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+//go:build !linux
+// +build !linux
+
+package server
+
+import "github.com/lasthyphen/djtx-tester/rpcpb"
+
+func limitProcess(cgroup string, pid int, l *rpcpb.ResourceLimits) error {
+	return ErrResourceLimitsUnsupported
+}
+
+func readUsage(cgroup string) (*rpcpb.ResourceUsage, error) {
+	return nil, ErrResourceLimitsUnsupported
+}
+
+func releaseCgroup(cgroup string) {}
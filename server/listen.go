@@ -0,0 +1,133 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// unixScheme prefixes the Unix domain socket ports (e.g., "unix:///tmp/djtx.sock"),
+// the same target syntax gRPC clients dial.
+const unixScheme = "unix://"
+
+// splitPorts splits the comma-separated listen addresses of a port flag
+// (e.g., "127.0.0.1:8080,[::1]:8080").
+func splitPorts(ports string) ([]string, error) {
+	var addrs []string
+	for _, port := range strings.Split(ports, ",") {
+		port = strings.TrimSpace(port)
+		if port == "" {
+			return nil, fmt.Errorf("%w: empty address in %q", ErrInvalidPort, ports)
+		}
+		if _, ok := unixSocketPath(port); !ok {
+			if _, _, err := net.SplitHostPort(port); err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidPort, err)
+			}
+		}
+		addrs = append(addrs, port)
+	}
+	return addrs, nil
+}
+
+// listenAll listens on each address, or none if any fails.
+func listenAll(ports []string) ([]net.Listener, error) {
+	lns := make([]net.Listener, 0, len(ports))
+	for _, port := range ports {
+		ln, err := listen(port)
+		if err != nil {
+			for _, ln := range lns {
+				ln.Close()
+			}
+			return nil, err
+		}
+		lns = append(lns, ln)
+	}
+	return lns, nil
+}
+
+// serveAll serves each listener, and returns the channel of their errors,
+// buffered so the listeners returning after the first one do not block.
+func serveAll(lns []net.Listener, serve func(net.Listener) error) <-chan error {
+	errc := make(chan error, len(lns))
+	for _, ln := range lns {
+		go func(ln net.Listener) {
+			errc <- serve(ln)
+		}(ln)
+	}
+	return errc
+}
+
+// listen listens on the TCP address (e.g., ":8080" or "127.0.0.1:8080") or
+// the Unix domain socket. The IPv4 and IPv6 addresses only accept their own
+// family, so both unspecified addresses (e.g., "0.0.0.0:8080,[::]:8080")
+// can be listened on, while ":8080" accepts both (where supported). A
+// socket file left over by a previous server is removed first.
+func listen(port string) (net.Listener, error) {
+	path, ok := unixSocketPath(port)
+	if !ok {
+		return net.Listen(tcpNetwork(port), port)
+	}
+	if path == "" {
+		return nil, fmt.Errorf("%w: empty socket path %q", ErrInvalidPort, port)
+	}
+	if fi, err := os.Lstat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	} else if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	return net.Listen("unix", path)
+}
+
+func tcpNetwork(port string) string {
+	host, _, err := net.SplitHostPort(port)
+	if err != nil {
+		return "tcp"
+	}
+	ip := net.ParseIP(host)
+	switch {
+	case ip == nil:
+		// empty, or a host name
+		return "tcp"
+	case ip.To4() != nil:
+		return "tcp4"
+	default:
+		return "tcp6"
+	}
+}
+
+// advertisedAddr returns the address the local clients dial for the port,
+// with the port of the listener address if not nil (e.g., for ":0").
+func advertisedAddr(port string, addr net.Addr) string {
+	if _, ok := unixSocketPath(port); ok {
+		return port
+	}
+	host, p, err := net.SplitHostPort(port)
+	if err != nil {
+		return port
+	}
+	if tcpAddr, ok := addr.(*net.TCPAddr); ok {
+		p = strconv.Itoa(tcpAddr.Port)
+	}
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "127.0.0.1"
+		if ip != nil && ip.To4() == nil {
+			host = "::1"
+		}
+	}
+	return net.JoinHostPort(host, p)
+}
+
+func unixSocketPath(port string) (string, bool) {
+	if !strings.HasPrefix(port, unixScheme) {
+		return "", false
+	}
+	return strings.TrimPrefix(port, unixScheme), true
+}
@@ -0,0 +1,213 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lasthyphen/djtx-tester/pkg/jsonrpc"
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+	"go.uber.org/zap"
+)
+
+const defaultLivenessPollInterval = 10 * time.Second
+
+var ErrInvalidLivenessMonitor = errors.New("invalid liveness monitor")
+
+// livenessOptions are the parsed "LivenessMonitor" of the start request.
+type livenessOptions struct {
+	chains         []string
+	stallThreshold time.Duration
+	pollInterval   time.Duration
+	artifactsDir   string
+}
+
+func parseLivenessMonitor(m *rpcpb.LivenessMonitor) (*livenessOptions, error) {
+	if m == nil {
+		return nil, nil
+	}
+	opts := &livenessOptions{
+		chains:       m.Chains,
+		pollInterval: defaultLivenessPollInterval,
+		artifactsDir: m.GetArtifactsDir(),
+	}
+	if len(opts.chains) == 0 {
+		opts.chains = []string{"P", "C"}
+	}
+	for _, chain := range opts.chains {
+		if chain != "P" && chain != "C" {
+			return nil, fmt.Errorf("%w: invalid chain %q (expected P or C)", ErrInvalidLivenessMonitor, chain)
+		}
+	}
+	d, err := time.ParseDuration(m.StallThreshold)
+	if err != nil || d <= 0 {
+		return nil, fmt.Errorf("%w: invalid stall threshold %q", ErrInvalidLivenessMonitor, m.StallThreshold)
+	}
+	opts.stallThreshold = d
+	if m.PollInterval != nil {
+		d, err := time.ParseDuration(m.GetPollInterval())
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("%w: invalid poll interval %q", ErrInvalidLivenessMonitor, m.GetPollInterval())
+		}
+		opts.pollInterval = d
+	}
+	return opts, nil
+}
+
+// chainProgress is the last height increase of a chain.
+type chainProgress struct {
+	height uint64
+	since  time.Time
+	alert  *rpcpb.LivenessAlert
+}
+
+// monitorLiveness polls the chain heights of the network until it stops, and
+// reports the chains without a height increase for the stall threshold in
+// the cluster info (so "StreamStatus" pushes them). The time spent outside
+// of the healthy and degraded states (e.g., restarting nodes) does not count.
+func (s *server) monitorLiveness(nw *localNetwork) {
+	opts := nw.opts.liveness
+	zap.L().Info("monitoring chain liveness",
+		zap.Strings("chains", opts.chains),
+		zap.Duration("stallThreshold", opts.stallThreshold),
+		zap.Duration("pollInterval", opts.pollInterval),
+	)
+	progress := make(map[string]*chainProgress, len(opts.chains))
+	now := time.Now()
+	for _, chain := range opts.chains {
+		progress[chain] = &chainProgress{since: now}
+	}
+
+	tc := time.NewTicker(opts.pollInterval)
+	defer tc.Stop()
+	for {
+		select {
+		case <-nw.stopCtx.Done():
+			return
+		case <-tc.C:
+		}
+
+		s.mu.RLock()
+		if s.network != nw {
+			s.mu.RUnlock()
+			return
+		}
+		state, _ := nw.lifecycle.get()
+		uris := make([]string, 0, len(nw.nodeInfos))
+		for _, info := range nw.nodeInfos {
+			if info.Uri != "" {
+				uris = append(uris, info.Uri)
+			}
+		}
+		s.mu.RUnlock()
+
+		now := time.Now()
+		if state != stateHealthy && state != stateDegraded {
+			for _, p := range progress {
+				p.since = now
+			}
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(nw.stopCtx, opts.pollInterval)
+		changed := false
+		for _, chain := range opts.chains {
+			p := progress[chain]
+			height, ok := maxChainHeight(ctx, uris, chain)
+			if !ok {
+				continue
+			}
+			if height > p.height {
+				p.height, p.since = height, now
+				if p.alert != nil {
+					zap.L().Info("chain advanced again", zap.String("chain", chain), zap.Uint64("height", height))
+					p.alert = nil
+					changed = true
+				}
+				continue
+			}
+			if p.alert != nil || now.Sub(p.since) < opts.stallThreshold {
+				continue
+			}
+			p.alert = &rpcpb.LivenessAlert{
+				Chain:        chain,
+				Height:       p.height,
+				StalledSince: p.since.UTC().Format(time.RFC3339),
+			}
+			zap.L().Warn("chain stalled",
+				zap.String("chain", chain),
+				zap.Uint64("height", p.height),
+				zap.Duration("stalledFor", now.Sub(p.since)),
+			)
+			if opts.artifactsDir != "" {
+				path := filepath.Join(opts.artifactsDir, fmt.Sprintf("stall-%s-%d.tar.gz", chain, now.Unix()))
+				if err := nw.archiveArtifacts(path); err != nil {
+					zap.L().Warn("failed to collect stall artifacts", zap.String("path", path), zap.Error(err))
+				} else {
+					p.alert.ArtifactsPath = path
+				}
+			}
+			changed = true
+		}
+		cancel()
+		if !changed {
+			continue
+		}
+
+		alerts := []*rpcpb.LivenessAlert{}
+		for _, chain := range opts.chains {
+			if a := progress[chain].alert; a != nil {
+				alerts = append(alerts, a)
+			}
+		}
+		s.mu.Lock()
+		if s.network == nw {
+			s.clusterInfo.LivenessAlerts = alerts
+		}
+		s.mu.Unlock()
+	}
+}
+
+// maxChainHeight returns the highest height of the chain across the nodes,
+// false if no node replied.
+func maxChainHeight(ctx context.Context, uris []string, chain string) (uint64, bool) {
+	var max uint64
+	ok := false
+	for _, uri := range uris {
+		h, err := chainHeight(ctx, uri, chain)
+		if err != nil {
+			zap.L().Debug("failed to get chain height", zap.String("uri", uri), zap.String("chain", chain), zap.Error(err))
+			continue
+		}
+		if !ok || h > max {
+			max = h
+		}
+		ok = true
+	}
+	return max, ok
+}
+
+func chainHeight(ctx context.Context, uri string, chain string) (uint64, error) {
+	if chain == "C" {
+		var reply string
+		if err := jsonrpc.Call(ctx, uri, "/ext/bc/C/rpc", "eth_blockNumber", []interface{}{}, &reply); err != nil {
+			return 0, err
+		}
+		return strconv.ParseUint(strings.TrimPrefix(reply, "0x"), 16, 64)
+	}
+	// ref. platformvm "GetHeight"
+	var reply struct {
+		Height string `json:"height"`
+	}
+	if err := jsonrpc.Call(ctx, uri, "/ext/bc/P", "platform.getHeight", nil, &reply); err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(reply.Height, 10, 64)
+}
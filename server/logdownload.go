@@ -0,0 +1,159 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+	"go.uber.org/zap"
+)
+
+// size of the streamed log chunks
+const downloadLogsChunkSize = 256 * 1024
+
+var ErrInvalidLogDownload = errors.New("invalid log download")
+
+// DownloadLogs streams a gzipped tarball of the log files of the node, in
+// chunks, so the clients (e.g., CI after a failed test) can pull the complete
+// logs over the control channel. The rotated (already compressed) logs are
+// included as they are. The files removed by a rotation during the download
+// are skipped.
+func (s *server) DownloadLogs(req *rpcpb.DownloadLogsRequest, stream rpcpb.ControlService_DownloadLogsServer) error {
+	zap.L().Info("received download logs request", zap.String("name", req.NodeName), zap.String("since", req.GetSince()))
+	var since time.Time
+	if req.Since != nil {
+		t, err := time.Parse(time.RFC3339, req.GetSince())
+		if err != nil {
+			return fmt.Errorf("%w: invalid since %q", ErrInvalidLogDownload, req.GetSince())
+		}
+		since = t
+	}
+
+	s.mu.RLock()
+	if s.network == nil {
+		s.mu.RUnlock()
+		return ErrNotBootstrapped
+	}
+	if err := s.checkOwnerLocked(stream.Context()); err != nil {
+		s.mu.RUnlock()
+		return err
+	}
+	info, ok := s.network.nodeInfos[req.NodeName]
+	if !ok {
+		s.mu.RUnlock()
+		return ErrNodeNotFound
+	}
+	logDir := info.LogDir
+	s.mu.RUnlock()
+
+	bw := bufio.NewWriterSize(&logStreamWriter{stream: stream}, downloadLogsChunkSize)
+	gw := gzip.NewWriter(bw)
+	tw := tar.NewWriter(gw)
+	err := filepath.Walk(logDir, func(p string, fi os.FileInfo, err error) error {
+		// the node has not opened its logs yet, or the file was rotated away
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := stream.Context().Err(); err != nil {
+			return err
+		}
+		// a file last written before has no lines since
+		if !fi.Mode().IsRegular() || fi.ModTime().Before(since) {
+			return nil
+		}
+		rel, err := filepath.Rel(logDir, p)
+		if err != nil {
+			return err
+		}
+		return addLogFile(tw, p, filepath.Join(req.NodeName, rel))
+	})
+	if err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gw.Close(); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// addLogFile adds the log file to the tarball, unless it was removed (e.g.,
+// rotated) since it was listed. The size is that of the opened file, which
+// may still grow, so only that size is copied. A file truncated during the
+// copy keeps its header size, padded with zeros, as the header is already
+// written, rather than failing the download.
+func addLogFile(tw *tar.Writer, path string, name string) error {
+	src, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	fi, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	hdr, err := tar.FileInfoHeader(fi, "")
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	n, err := io.CopyN(tw, src, hdr.Size)
+	if errors.Is(err, io.EOF) {
+		zap.L().Warn("log file truncated during the download", zap.String("path", path), zap.Int64("size", hdr.Size), zap.Int64("copied", n))
+		_, err = io.CopyN(tw, zeroReader{}, hdr.Size-n)
+	}
+	return err
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
+
+// logStreamWriter sends the writes as chunks of the log download,
+// of at most the chunk size.
+type logStreamWriter struct {
+	stream rpcpb.ControlService_DownloadLogsServer
+}
+
+func (w *logStreamWriter) Write(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		end := n + downloadLogsChunkSize
+		if end > len(p) {
+			end = len(p)
+		}
+		// the message may be encoded after the buffer is reused
+		chunk := append([]byte(nil), p[n:end]...)
+		if err := w.stream.Send(&rpcpb.DownloadLogsResponse{Chunk: chunk}); err != nil {
+			return n, err
+		}
+		n = end
+	}
+	return n, nil
+}
@@ -0,0 +1,53 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestAddLogFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.log")
+	if err := ioutil.WriteFile(path, []byte("line\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	// rotated away since it was listed
+	if err := addLogFile(tw, filepath.Join(dir, "main.log.1"), "node1/main.log.1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := addLogFile(tw, path, "node1/main.log"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	tr := tar.NewReader(&buf)
+	hdr, err := tr.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hdr.Name != "node1/main.log" {
+		t.Fatalf("expected %q, got %q", "node1/main.log", hdr.Name)
+	}
+	b, err := ioutil.ReadAll(tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "line\n" {
+		t.Fatalf("expected %q, got %q", "line\n", b)
+	}
+	if _, err := tr.Next(); err != io.EOF {
+		t.Fatalf("expected a single file, got %v", err)
+	}
+}
@@ -0,0 +1,210 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultLogSearchMatches = 1000
+	maxLogSearchMatches     = 10000
+	// longer lines are skipped, with the rest of their file
+	maxLogLineSize = 1024 * 1024
+)
+
+var ErrInvalidLogSearch = errors.New("invalid log search")
+
+// timestamp of the avalanchego log lines (e.g., "[09-14|12:00:00.123]"),
+// without the year
+var logTimeRegexp = regexp.MustCompile(`\[(\d{2}-\d{2}\|\d{2}:\d{2}:\d{2}(?:\.\d{3})?)\]`)
+
+// SearchLogs greps the log files of the nodes, so the clients do not need
+// to download the logs to find a line.
+func (s *server) SearchLogs(ctx context.Context, req *rpcpb.SearchLogsRequest) (*rpcpb.SearchLogsResponse, error) {
+	zap.L().Info("received search logs request",
+		zap.String("query", req.Query),
+		zap.Bool("regex", req.Regex),
+		zap.Strings("nodeNames", req.NodeNames),
+	)
+	if req.Query == "" {
+		return nil, fmt.Errorf("%w: empty query", ErrInvalidLogSearch)
+	}
+	match := func(line string) bool { return strings.Contains(line, req.Query) }
+	if req.Regex {
+		re, err := regexp.Compile(req.Query)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidLogSearch, err)
+		}
+		match = re.MatchString
+	}
+	var since, until time.Time
+	if req.Since != nil {
+		t, err := time.Parse(time.RFC3339, req.GetSince())
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid since %q", ErrInvalidLogSearch, req.GetSince())
+		}
+		since = t
+	}
+	if req.Until != nil {
+		t, err := time.Parse(time.RFC3339, req.GetUntil())
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid until %q", ErrInvalidLogSearch, req.GetUntil())
+		}
+		until = t
+	}
+	maxMatches := int(req.MaxMatches)
+	if maxMatches == 0 {
+		maxMatches = defaultLogSearchMatches
+	}
+	if maxMatches > maxLogSearchMatches {
+		maxMatches = maxLogSearchMatches
+	}
+
+	// the log directories, read once, so the search does not hold the lock
+	s.mu.RLock()
+	if s.network == nil {
+		s.mu.RUnlock()
+		return nil, ErrNotBootstrapped
+	}
+	logDirs := make(map[string]string)
+	for name, info := range s.network.nodeInfos {
+		logDirs[name] = info.LogDir
+	}
+	s.mu.RUnlock()
+
+	names := req.NodeNames
+	if len(names) == 0 {
+		for name := range logDirs {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	resp := &rpcpb.SearchLogsResponse{}
+	for _, name := range names {
+		logDir, ok := logDirs[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrNodeNotFound, name)
+		}
+		files, err := logFiles(logDir)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			if err := searchLogFile(logDir, file, name, match, since, until, maxMatches, resp); err != nil {
+				zap.L().Warn("failed to search log file", zap.String("file", file), zap.Error(err))
+			}
+			if resp.Truncated {
+				return resp, nil
+			}
+		}
+	}
+	return resp, nil
+}
+
+// logFiles returns the uncompressed files of the log directory,
+// sorted by path.
+func logFiles(logDir string) ([]string, error) {
+	files := []string{}
+	err := filepath.Walk(logDir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !fi.Mode().IsRegular() || strings.HasSuffix(path, ".gz") {
+			return nil
+		}
+		rel, err := filepath.Rel(logDir, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, rel)
+		return nil
+	})
+	if errors.Is(err, os.ErrNotExist) {
+		// the node has not opened its logs yet
+		return nil, nil
+	}
+	return files, err
+}
+
+// searchLogFile appends the matching lines of the file to the response,
+// until the max matches.
+func searchLogFile(logDir string, file string, name string, match func(string) bool, since time.Time, until time.Time, maxMatches int, resp *rpcpb.SearchLogsResponse) error {
+	f, err := os.Open(filepath.Join(logDir, file))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	now := time.Now()
+	var lineTime time.Time
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), maxLogLineSize)
+	for n := uint64(1); sc.Scan(); n++ {
+		line := sc.Text()
+		// the lines without a timestamp (e.g., of a stack trace) have the
+		// time of the previous line
+		if t, ok := parseLogTime(line, now); ok {
+			lineTime = t
+		}
+		if !since.IsZero() && (lineTime.IsZero() || lineTime.Before(since)) {
+			continue
+		}
+		if !until.IsZero() && (lineTime.IsZero() || lineTime.After(until)) {
+			continue
+		}
+		if !match(line) {
+			continue
+		}
+		if len(resp.Matches) == maxMatches {
+			resp.Truncated = true
+			return nil
+		}
+		m := &rpcpb.LogMatch{
+			NodeName:   name,
+			File:       file,
+			LineNumber: n,
+			Line:       line,
+		}
+		if !lineTime.IsZero() {
+			m.Time = lineTime.Format(time.RFC3339Nano)
+		}
+		resp.Matches = append(resp.Matches, m)
+	}
+	return sc.Err()
+}
+
+// parseLogTime parses the timestamp of the log line, in the local time
+// zone of the nodes, and of the last year before "now".
+func parseLogTime(line string, now time.Time) (time.Time, bool) {
+	m := logTimeRegexp.FindStringSubmatch(line)
+	if m == nil {
+		return time.Time{}, false
+	}
+	t, err := time.ParseInLocation("2006-01-02|15:04:05", fmt.Sprintf("%d-%s", now.Year(), m[1]), time.Local)
+	if err != nil {
+		return time.Time{}, false
+	}
+	// e.g., a December line read in January
+	if t.After(now.Add(24 * time.Hour)) {
+		t = t.AddDate(-1, 0, 0)
+	}
+	return t, true
+}
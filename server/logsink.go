@@ -0,0 +1,316 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+	formatter "github.com/onsi/ginkgo/v2/formatter"
+	"go.uber.org/zap"
+)
+
+var ErrInvalidLogSink = errors.New("invalid log sink")
+
+// logSink receives the complete output lines of the nodes.
+type logSink interface {
+	// WriteLine writes a line (without the trailing newline) of the node
+	// output stream ("stdout" or "stderr").
+	WriteLine(name string, stream string, line string) error
+	Close() error
+}
+
+// newLogSink returns the sink for the configured sinks, or the colored
+// stdout sink if none is configured.
+func newLogSink(cfgs []*rpcpb.LogSink) (logSink, error) {
+	if len(cfgs) == 0 {
+		return newStdoutSink(), nil
+	}
+	sinks := make(multiSink, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		var (
+			sink logSink
+			err  error
+		)
+		switch cfg.Type {
+		case "stdout":
+			sink = newStdoutSink()
+		case "file":
+			sink, err = newFileSink(cfg.Path)
+		case "syslog":
+			sink, err = newSyslogSink(cfg.Address)
+		case "loki":
+			sink, err = newLokiSink(cfg.Url, cfg.Labels)
+		case "noop":
+			sink = noopSink{}
+		default:
+			err = fmt.Errorf("%w: unknown type %q", ErrInvalidLogSink, cfg.Type)
+		}
+		if err != nil {
+			sinks.Close()
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+	if len(sinks) == 1 {
+		return sinks[0], nil
+	}
+	return sinks, nil
+}
+
+type multiSink []logSink
+
+func (ms multiSink) WriteLine(name string, stream string, line string) error {
+	var errs []error
+	for _, s := range ms {
+		if err := s.WriteLine(name, stream, line); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%d of %d sinks failed: %v", len(errs), len(ms), errs)
+	}
+	return nil
+}
+
+func (ms multiSink) Close() error {
+	var errs []error
+	for _, s := range ms {
+		if err := s.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to close %d of %d sinks: %v", len(errs), len(ms), errs)
+	}
+	return nil
+}
+
+type noopSink struct{}
+
+func (noopSink) WriteLine(string, string, string) error { return nil }
+func (noopSink) Close() error                           { return nil }
+
+// https://github.com/onsi/ginkgo/blob/v2.0.0/formatter/formatter.go#L52-L73
+var colors = []string{
+	"{{green}}",
+	"{{orange}}",
+	"{{blue}}",
+	"{{magenta}}",
+	"{{cyan}}",
+}
+
+// stdoutSink prefixes the lines with the colored node name,
+// the node colors assigned in the order of their first line.
+type stdoutSink struct {
+	mu     sync.Mutex
+	colors map[string]string
+}
+
+func newStdoutSink() *stdoutSink {
+	return &stdoutSink{colors: make(map[string]string)}
+}
+
+func (s *stdoutSink) WriteLine(name string, stream string, line string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.colors[name]
+	if !ok {
+		c = colors[len(s.colors)%len(colors)]
+		s.colors[name] = c
+	}
+	fmt.Fprint(formatter.ColorableStdOut, formatter.F(c+"[%s]{{/}}	", name))
+	_, err := fmt.Fprintln(os.Stdout, line)
+	return err
+}
+
+func (s *stdoutSink) Close() error { return nil }
+
+// fileSink appends the timestamped lines of all nodes to a single file.
+type fileSink struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+func newFileSink(path string) (*fileSink, error) {
+	if path == "" {
+		return nil, fmt.Errorf("%w: empty file path", ErrInvalidLogSink)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &fileSink{f: f}, nil
+}
+
+func (s *fileSink) WriteLine(name string, stream string, line string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := fmt.Fprintf(s.f, "%s [%s] %s: %s\n", time.Now().UTC().Format(time.RFC3339Nano), name, stream, line)
+	return err
+}
+
+func (s *fileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.f.Close()
+}
+
+const (
+	lokiBatchSize     = 500
+	lokiFlushInterval = time.Second
+	lokiPushTimeout   = 10 * time.Second
+)
+
+// lokiSink batches the lines and pushes them to the Loki push API,
+// labeled with the node name and the output stream.
+type lokiSink struct {
+	url    string
+	labels map[string]string
+	cli    *http.Client
+
+	mu sync.Mutex
+	// keyed by "node/stream"
+	batch map[string]*lokiStream
+	size  int
+
+	flushc chan struct{}
+	donec  chan struct{}
+	// closed when the flush loop returns
+	exitc chan struct{}
+	once  sync.Once
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+func newLokiSink(url string, labels map[string]string) (*lokiSink, error) {
+	if url == "" {
+		return nil, fmt.Errorf("%w: empty Loki push URL", ErrInvalidLogSink)
+	}
+	s := &lokiSink{
+		url:    url,
+		labels: labels,
+		cli:    &http.Client{Timeout: lokiPushTimeout},
+		batch:  make(map[string]*lokiStream),
+		flushc: make(chan struct{}, 1),
+		donec:  make(chan struct{}),
+		exitc:  make(chan struct{}),
+	}
+	go s.flushLoop()
+	return s, nil
+}
+
+func (s *lokiSink) WriteLine(name string, stream string, line string) error {
+	ts := strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	s.mu.Lock()
+	k := name + "/" + stream
+	ls, ok := s.batch[k]
+	if !ok {
+		labels := make(map[string]string, len(s.labels)+2)
+		for lk, lv := range s.labels {
+			labels[lk] = lv
+		}
+		labels["node"] = name
+		labels["stream"] = stream
+		ls = &lokiStream{Stream: labels}
+		s.batch[k] = ls
+	}
+	ls.Values = append(ls.Values, [2]string{ts, line})
+	s.size++
+	full := s.size >= lokiBatchSize
+	s.mu.Unlock()
+
+	if full {
+		select {
+		case s.flushc <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+func (s *lokiSink) flushLoop() {
+	defer close(s.exitc)
+	tc := time.NewTicker(lokiFlushInterval)
+	defer tc.Stop()
+	for {
+		select {
+		case <-s.donec:
+			s.flush()
+			return
+		case <-tc.C:
+		case <-s.flushc:
+		}
+		s.flush()
+	}
+}
+
+func (s *lokiSink) flush() {
+	s.mu.Lock()
+	if s.size == 0 {
+		s.mu.Unlock()
+		return
+	}
+	streams := make([]*lokiStream, 0, len(s.batch))
+	for _, ls := range s.batch {
+		streams = append(streams, ls)
+	}
+	s.batch = make(map[string]*lokiStream)
+	s.size = 0
+	s.mu.Unlock()
+
+	if err := s.push(streams); err != nil {
+		// dropping the batch rather than blocking the nodes
+		zap.L().Warn("failed to push node logs to Loki", zap.String("url", s.url), zap.Error(err))
+	}
+}
+
+func (s *lokiSink) push(streams []*lokiStream) error {
+	b, err := json.Marshal(map[string]interface{}{"streams": streams})
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), lokiPushTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.cli.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status %q", resp.Status)
+	}
+	return nil
+}
+
+// Close flushes the pending lines.
+func (s *lokiSink) Close() error {
+	s.once.Do(func() {
+		close(s.donec)
+	})
+	<-s.exitc
+	return nil
+}
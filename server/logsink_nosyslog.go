@@ -0,0 +1,13 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+//go:build windows || plan9
+// +build windows plan9
+
+package server
+
+import "fmt"
+
+func newSyslogSink(addr string) (logSink, error) {
+	return nil, fmt.Errorf("%w: syslog is not supported on this platform", ErrInvalidLogSink)
+}
@@ -0,0 +1,48 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package server
+
+import (
+	"fmt"
+	"log/syslog"
+	"strings"
+)
+
+const syslogTag = "avalanche-network-runner"
+
+// syslogSink writes the lines to syslog, stderr lines with the error priority.
+type syslogSink struct {
+	w *syslog.Writer
+}
+
+// newSyslogSink connects to the syslog daemon at the address
+// (e.g., "udp://localhost:514"), or the local daemon if empty.
+func newSyslogSink(addr string) (*syslogSink, error) {
+	network, raddr := "", ""
+	if addr != "" {
+		ss := strings.SplitN(addr, "://", 2)
+		if len(ss) != 2 {
+			return nil, fmt.Errorf("%w: invalid syslog address %q (expected e.g. \"udp://localhost:514\")", ErrInvalidLogSink, addr)
+		}
+		network, raddr = ss[0], ss[1]
+	}
+	w, err := syslog.Dial(network, raddr, syslog.LOG_INFO|syslog.LOG_USER, syslogTag)
+	if err != nil {
+		return nil, err
+	}
+	return &syslogSink{w: w}, nil
+}
+
+func (s *syslogSink) WriteLine(name string, stream string, line string) error {
+	msg := fmt.Sprintf("[%s] %s", name, line)
+	if stream == "stderr" {
+		return s.w.Err(msg)
+	}
+	return s.w.Info(msg)
+}
+
+func (s *syslogSink) Close() error { return s.w.Close() }
@@ -0,0 +1,182 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+	formatter "github.com/onsi/ginkgo/v2/formatter"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/metadata"
+)
+
+// path of the log stream on the gRPC gateway port
+const logStreamPath = "/v1/logs/stream"
+
+// time for a log line to be written to the websocket
+const logStreamWriteWait = 10 * time.Second
+
+// logStreamLine is a line of the JSON log stream.
+type logStreamLine struct {
+	Time   string `json:"time"`
+	Node   string `json:"node"`
+	Stream string `json:"stream"`
+	Line   string `json:"line"`
+}
+
+func (s *server) registerLogStream() error {
+	return s.gwMux.HandlePath(http.MethodGet, logStreamPath, s.serveLogStream)
+}
+
+// serveLogStream upgrades the request to a websocket, and streams the
+// merged output lines of the requested nodes ("node", repeated), or all
+// nodes if none, as they are written. The lines are prefixed with the
+// colored node name ("format=text", the default), or JSON objects
+// ("format=json"). Lines are dropped for the slow clients, as with
+// "AttachConsole".
+func (s *server) serveLogStream(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	q := r.URL.Query()
+	format := q.Get("format")
+	if format == "" {
+		format = "text"
+	}
+	if format != "text" && format != "json" {
+		http.Error(w, "invalid format (expected text or json)", http.StatusBadRequest)
+		return
+	}
+
+	tenant := ""
+	if s.tenants != nil {
+		// browsers cannot set the header of a websocket request
+		auth := r.Header.Get("Authorization")
+		if auth == "" && q.Get("token") != "" {
+			auth = "Bearer " + q.Get("token")
+		}
+		md := metadata.Pairs("authorization", auth)
+		t, err := s.tenants.authenticate(metadata.NewIncomingContext(r.Context(), md))
+		if err != nil {
+			http.Error(w, "missing or unknown tenant token", http.StatusUnauthorized)
+			return
+		}
+		tenant = t.Name
+	}
+
+	s.mu.RLock()
+	// the cluster of another tenant is hidden, as with the control requests
+	if s.network == nil || (tenant != "" && s.network.opts.tenant != tenant) {
+		s.mu.RUnlock()
+		http.Error(w, ErrNotBootstrapped.Error(), http.StatusPreconditionFailed)
+		return
+	}
+	names := q["node"]
+	if len(names) == 0 {
+		for name := range s.network.writers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+	writers := make([]*writer, 0, 2*len(names))
+	for _, name := range names {
+		ws, ok := s.network.writers[name]
+		if !ok {
+			s.mu.RUnlock()
+			http.Error(w, "node not found: "+name, http.StatusNotFound)
+			return
+		}
+		writers = append(writers, ws[0], ws[1])
+	}
+	s.mu.RUnlock()
+
+	upgrader := websocket.Upgrader{CheckOrigin: s.logStreamOrigin}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// the upgrader already replied
+		zap.L().Debug("failed to upgrade log stream", zap.Error(err))
+		return
+	}
+	defer conn.Close()
+	zap.L().Info("streaming logs", zap.Strings("names", names), zap.String("format", format))
+
+	ch := make(chan *rpcpb.AttachConsoleResponse, consoleBufferSize)
+	for _, wr := range writers {
+		wr.subscribe(ch)
+		defer wr.unsubscribe(ch)
+	}
+
+	// the client only sends the close (and control) messages
+	donec := make(chan struct{})
+	go func() {
+		defer close(donec)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	nodeColors := make(map[string]string, len(names))
+	for i, name := range names {
+		nodeColors[name] = colors[i%len(colors)]
+	}
+	for {
+		var resp *rpcpb.AttachConsoleResponse
+		select {
+		case <-s.rootCtx.Done():
+			return
+		case <-s.closed:
+			return
+		case <-donec:
+			return
+		case resp = <-ch:
+		}
+
+		var msg []byte
+		if format == "json" {
+			msg, err = json.Marshal(logStreamLine{
+				Time:   time.Now().UTC().Format(time.RFC3339Nano),
+				Node:   resp.NodeName,
+				Stream: resp.Stream,
+				Line:   resp.Line,
+			})
+			if err != nil {
+				zap.L().Warn("failed to encode log line", zap.Error(err))
+				return
+			}
+		} else {
+			msg = []byte(formatter.F(nodeColors[resp.NodeName]+"[%s]{{/}}\t", resp.NodeName) + resp.Line)
+		}
+		if err := conn.SetWriteDeadline(time.Now().Add(logStreamWriteWait)); err != nil {
+			return
+		}
+		if err := conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+			zap.L().Debug("failed to write log line", zap.Error(err))
+			return
+		}
+	}
+}
+
+// logStreamOrigin allows the non-browser clients (without an origin), the
+// same origin, and the gRPC-Web allowed origins ("*" for all).
+func (s *server) logStreamOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	for _, o := range s.cfg.GRPCWebAllowedOrigins {
+		if o == "*" || o == origin {
+			return true
+		}
+	}
+	return false
+}
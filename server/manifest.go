@@ -0,0 +1,99 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// written to the root data directory of each network
+const runManifestFile = "run-manifest.json"
+
+// newRunManifest hashes the binary (and its plugins) of the start request.
+func newRunManifest(req *rpcpb.StartRequest, bin *rpcpb.CheckBinaryResponse) (*rpcpb.RunManifest, error) {
+	startRequest, err := protojson.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	m := &rpcpb.RunManifest{
+		CreatedAt:     time.Now().UTC().Format(time.RFC3339),
+		ExecPath:      req.ExecPath,
+		Version:       bin.Version,
+		Commit:        bin.Commit,
+		PluginDir:     bin.PluginDir,
+		PluginSha256S: make(map[string]string, len(bin.Plugins)),
+		StartRequest:  string(startRequest),
+		Nodes:         make(map[string]*rpcpb.NodeManifest),
+	}
+	if m.ExecSha256, err = fileSHA256(req.ExecPath); err != nil {
+		return nil, err
+	}
+	for _, p := range bin.Plugins {
+		if m.PluginSha256S[p], err = fileSHA256(filepath.Join(bin.PluginDir, p)); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// addManifest sets the genesis and the node configs of the network to the
+// manifest, and writes it to the root data directory.
+func (lc *localNetwork) addManifest(m *rpcpb.RunManifest) error {
+	m.RootDataDir = lc.opts.rootDataDir
+	h := sha256.Sum256([]byte(lc.cfg.Genesis))
+	m.GenesisSha256 = hex.EncodeToString(h[:])
+	for _, nc := range lc.cfg.NodeConfigs {
+		m.Nodes[nc.Name] = &rpcpb.NodeManifest{
+			ExecPath:     lc.nodeInfos[nc.Name].ExecPath,
+			Config:       string(nc.ConfigFile),
+			CChainConfig: string(nc.CChainConfigFile),
+		}
+	}
+	lc.manifest = m
+
+	b, err := protojson.MarshalOptions{Multiline: true, Indent: "  "}.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(lc.opts.rootDataDir, runManifestFile), b, 0o644)
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// GetRunManifest returns the manifest of the network, as started: the
+// hashes of the binary, plugins, and genesis, the start request, and the
+// node configs.
+func (s *server) GetRunManifest(ctx context.Context, req *rpcpb.GetRunManifestRequest) (*rpcpb.GetRunManifestResponse, error) {
+	zap.L().Debug("received get run manifest request")
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.network == nil {
+		return nil, ErrNotBootstrapped
+	}
+	return &rpcpb.GetRunManifestResponse{Manifest: proto.Clone(s.network.manifest).(*rpcpb.RunManifest)}, nil
+}
@@ -4,12 +4,13 @@
 package server
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
-	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -20,8 +21,11 @@ import (
 	"github.com/lasthyphen/dijetsnodego/utils/constants"
 	"github.com/lasthyphen/dijetsnodego/utils/logging"
 	"github.com/lasthyphen/djtx-tester/pkg/color"
+	"github.com/lasthyphen/djtx-tester/pkg/testkeys"
+	"github.com/lasthyphen/djtx-tester/pkg/tracing"
 	"github.com/lasthyphen/djtx-tester/rpcpb"
-	formatter "github.com/onsi/ginkgo/v2/formatter"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
 )
 
 type localNetwork struct {
@@ -29,6 +33,7 @@ type localNetwork struct {
 
 	binPath string
 	cfg     network.Config
+	opts    networkOptions
 
 	nw network.Network
 
@@ -38,121 +43,486 @@ type localNetwork struct {
 
 	apiClis map[string]api.Client
 
-	readyc          chan struct{} // closed when local network is ready/healthy
-	readycCloseOnce sync.Once
-
-	stopc chan struct{}
-	donec chan struct{}
-	errc  chan error
+	// stdout and stderr writers of each node (and sidecar), kept across
+	// restarts
+	writers map[string][2]*writer
+	// companion processes of the nodes
+	sidecars *sidecarSet
+	// launches of the nodes, for their exits
+	exits *nodeExits
+	// destination of all node outputs, closed on stop
+	sink logSink
+	// receives the names of the nodes that failed to bind their ports
+	portConflictc chan string
+
+	// blocked staking connections, set by "BlockTraffic"
+	trafficRules map[trafficRule]struct{}
+	// disk devices by node name, attached on start
+	diskDevices map[string]*diskDevice
+	// nodes kept in their cgroups (across restarts) for the traffic rules
+	contained map[string]bool
+
+	lifecycle *lifecycle
+
+	// whether the seed actions are yet to run, written by the start routine
+	seedPending bool
+	seedResults []*rpcpb.SeedResult
+
+	// inputs of the network as started, for "GetRunManifest"
+	manifest *rpcpb.RunManifest
+	// the control calls recorded since are archived with the artifacts
+	createdAt time.Time
+	// how the network was started, for "Status"
+	provenance *rpcpb.NetworkProvenance
+	// host reservation of the network, released on stop, if scheduled
+	reservation string
+
+	// canceled on stop, to abort in-flight health checks
+	stopCtx    context.Context
+	stopCancel context.CancelFunc
+	// tracks the start routine, so stop can wait for its return
+	startWg sync.WaitGroup
+
+	cancelOnce sync.Once
+	stopOnce   sync.Once
+}
 
-	stopOnce sync.Once
+// networkOptions are the parameters of a local network,
+// resolved from the start request (and its preset, if any).
+type networkOptions struct {
+	execPath           string
+	rootDataDir        string
+	whitelistedSubnets string
+	logLevel           string
+	// by node name, overriding the log level
+	nodeLogLevels map[string]*rpcpb.NodeLogLevels
+
+	// number of nodes to launch, zero for all nodes of the default config
+	numNodes         uint32
+	globalNodeConfig map[string]interface{}
+	cChainConfig     map[string]interface{}
+
+	// if not empty, the logs and configs are archived to this path
+	// (in the artifacts directory of the server) when the network stops
+	// or fails to start, and next to it when a node crashes
+	artifactsPath string
+	// control calls recorded by the server (ref. "Config.RecordFixtures"),
+	// archived with the artifacts, if not empty
+	fixtures string
+
+	logSinks []*rpcpb.LogSink
+
+	// if not zero, the nodes use static ports from this base port,
+	// and are not reassigned ports on conflicts
+	basePort uint32
+
+	// added to the default genesis
+	genesisBalances []*rpcpb.GenesisBalance
+
+	// resource limits of every node, and by node name
+	resourceLimits     *rpcpb.ResourceLimits
+	nodeResourceLimits map[string]*rpcpb.ResourceLimits
+
+	// database config of every node, and by node name
+	databaseConfig      *rpcpb.DatabaseConfig
+	nodeDatabaseConfigs map[string]*rpcpb.DatabaseConfig
+
+	// names of the bootstrap beacons, all nodes if empty
+	bootstrapBeacons []string
+	// by node name, the peers bootstrapped from instead of the beacons
+	nodeBootstrapPeers map[string]*rpcpb.NodeBootstrapPeers
+
+	// records the latencies of the network operations
+	latencies *latencies
+	// hashes the VM plugins of the nodes
+	plugins *pluginInventory
+	// sends the lifecycle events, if not nil
+	webhooks *webhooks
+	// reports the state transitions, if not nil
+	readiness *readiness
+	// the server lock, guarding the node infos, names, and API clients
+	// against the server requests, for the routines that run without it
+	// (e.g., the bootstrap)
+	mu *sync.RWMutex
+	// time for the nodes to exit on stop, no limit if zero
+	shutdownGracePeriod time.Duration
+	// supervisor of the node processes, none if empty
+	supervisor string
+	// monitors the chain heights once healthy, if not nil
+	liveness *livenessOptions
+	// backoff of the node health polls
+	healthPoll healthPollOptions
+	// fails the network if a node stays unhealthy for longer, if not zero
+	maxUnhealthyDuration time.Duration
+	// delay between the node launches, all at once if zero
+	launchInterval time.Duration
+	// nodes whose database directory is mounted on a disk device, and the
+	// device size (the default if zero)
+	diskFaultNodes  []string
+	diskDeviceBytes uint64
+	// nil without periodic checkpoints
+	checkpoints *checkpointOptions
+	// checkpoint to resume the databases from, if not empty
+	resumeCheckpoint string
+	// run once the nodes are first healthy, before the network is healthy
+	seedActions []*rpcpb.SeedAction
+	// spent from by the seed actions
+	fundedKey *testkeys.Key
+	// names of the nodes that state-sync their C-chain
+	stateSyncNodes map[string]bool
+	// by node name, launched and stopped with the node
+	nodeSidecars map[string]*rpcpb.NodeSidecars
+	// archival (true) or pruned (false) nodes by name, as the C-chain
+	// config if not set
+	nodeArchival map[string]bool
+
+	// owner of the network, empty if tenancy is disabled
+	tenant string
+	// maximum number of nodes of the tenant, zero for no limit
+	maxNodes uint32
 }
 
-func newNetwork(execPath string, rootDataDir string, whitelistedSubnets string, logLevel string) (*localNetwork, error) {
+var ErrInvalidNumNodes = errors.New("invalid number of nodes")
+
+func newNetwork(opts networkOptions) (*localNetwork, error) {
 	lcfg, err := logging.DefaultConfig()
 	if err != nil {
 		return nil, err
 	}
-	lcfg.Directory = rootDataDir
+	lcfg.Directory = opts.rootDataDir
 	logFactory := logging.NewFactory(lcfg)
 	logger, err := logFactory.Make("main")
 	if err != nil {
 		return nil, err
 	}
 
-	if logLevel == "" {
-		logLevel = "INFO"
+	if opts.logLevel == "" {
+		opts.logLevel = "INFO"
+	}
+
+	cfg := local.NewDefaultConfig(opts.execPath)
+	if opts.numNodes > uint32(len(cfg.NodeConfigs)) {
+		return nil, fmt.Errorf("%w: %d (default config has %d nodes)", ErrInvalidNumNodes, opts.numNodes, len(cfg.NodeConfigs))
+	}
+	if opts.numNodes > 0 {
+		cfg.NodeConfigs = cfg.NodeConfigs[:opts.numNodes]
+	}
+	if opts.maxNodes > 0 && len(cfg.NodeConfigs) > int(opts.maxNodes) {
+		return nil, fmt.Errorf("%w: %d nodes requested, tenant %q allows %d", ErrTenantLimitReached, len(cfg.NodeConfigs), opts.tenant, opts.maxNodes)
+	}
+
+	cfg.Genesis, err = addGenesisBalances(cfg.Genesis, opts.genesisBalances)
+	if err != nil {
+		return nil, err
+	}
+
+	var cChainConfig []byte
+	if len(opts.cChainConfig) > 0 {
+		cChainConfig, err = json.Marshal(opts.cChainConfig)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sink, err := newLogSink(opts.logSinks)
+	if err != nil {
+		return nil, err
+	}
+	// the network owns the sink once created
+	created := false
+	defer func() {
+		if !created {
+			sink.Close()
+		}
+	}()
+
+	ports, err := assignPorts(len(cfg.NodeConfigs), opts.basePort)
+	if err != nil {
+		return nil, err
 	}
 
-	nodeInfos := make(map[string]*rpcpb.NodeInfo)
-	cfg := local.NewDefaultConfig(execPath)
 	nodeNames := make([]string, len(cfg.NodeConfigs))
 	for i := range cfg.NodeConfigs {
-		nodeName := fmt.Sprintf("node%d", i+1)
-		logDir := filepath.Join(rootDataDir, nodeName, "log")
-		dbDir := filepath.Join(rootDataDir, nodeName, "db-dir")
+		nodeNames[i] = fmt.Sprintf("node%d", i+1)
+	}
+	beacons, err := beaconSet(opts.bootstrapBeacons, nodeNames)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkBootstrapPeers(opts.nodeBootstrapPeers, nodeNames); err != nil {
+		return nil, err
+	}
 
-		nodeNames[i] = nodeName
+	nodeInfos := make(map[string]*rpcpb.NodeInfo)
+	writers := make(map[string][2]*writer)
+	portConflictc := make(chan string, len(cfg.NodeConfigs))
+	for i := range cfg.NodeConfigs {
+		nodeName := nodeNames[i]
 		cfg.NodeConfigs[i].Name = nodeName
+		if beacons != nil {
+			_, cfg.NodeConfigs[i].IsBeacon = beacons[nodeName]
+		}
+		nodeInfos[nodeName], writers[nodeName], err = setupNode(opts, &cfg.NodeConfigs[i], ports[i], cChainConfig, sink, portConflictc)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for name := range opts.nodeResourceLimits {
+		if _, ok := nodeInfos[name]; !ok {
+			return nil, fmt.Errorf("%w: unknown node %q", ErrInvalidResourceLimits, name)
+		}
+	}
+	for name := range opts.nodeDatabaseConfigs {
+		if _, ok := nodeInfos[name]; !ok {
+			return nil, fmt.Errorf("%w: unknown node %q", ErrInvalidDatabaseConfig, name)
+		}
+	}
+	for name := range opts.nodeLogLevels {
+		if _, ok := nodeInfos[name]; !ok {
+			return nil, fmt.Errorf("%w: log levels of unknown node %q", ErrInvalidNodeConfig, name)
+		}
+	}
+	for name := range opts.stateSyncNodes {
+		if _, ok := nodeInfos[name]; !ok {
+			return nil, fmt.Errorf("%w: state sync of unknown node %q", ErrInvalidNodeConfig, name)
+		}
+	}
+	for name := range opts.nodeArchival {
+		if _, ok := nodeInfos[name]; !ok {
+			return nil, fmt.Errorf("%w: archival mode of unknown node %q", ErrInvalidNodeConfig, name)
+		}
+	}
+	for _, name := range opts.diskFaultNodes {
+		info, ok := nodeInfos[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown node %q", ErrInvalidDiskFault, name)
+		}
+		info.DiskDevice = diskDevicePath(opts.rootDataDir, name)
+	}
+	if opts.resumeCheckpoint != "" {
+		if err := checkCheckpoint(opts.resumeCheckpoint); err != nil {
+			return nil, err
+		}
+	}
+	sidecars, err := newSidecarSet(opts, sink, writers)
+	if err != nil {
+		return nil, err
+	}
 
-		// need to whitelist subnet ID to create custom VM chain
-		// ref. vms/platformvm/createChain
-		cfg.NodeConfigs[i].ConfigFile = []byte(fmt.Sprintf(`{
-	"network-peer-list-gossip-frequency":"250ms",
-	"network-max-reconnect-delay":"1s",
-	"public-ip":"127.0.0.1",
-	"health-check-frequency":"2s",
-	"api-admin-enabled":true,
-	"api-ipcs-enabled":true,
-	"index-enabled":true,
-	"log-display-level":"INFO",
-	"log-level":"%s",
-	"log-dir":"%s",
-	"db-dir":"%s",
-	"whitelisted-subnets":"%s"
-}`,
-			logLevel,
-			logDir,
-			dbDir,
-			whitelistedSubnets,
-		))
-		wr := &writer{
-			c:    colors[i%len(cfg.NodeConfigs)],
-			name: nodeName,
-			w:    os.Stdout,
-		}
-		cfg.NodeConfigs[i].ImplSpecificConfig = local.NodeConfig{
-			BinaryPath: execPath,
-			Stdout:     wr,
-			Stderr:     wr,
-		}
-
-		nodeInfos[nodeName] = &rpcpb.NodeInfo{
-			Name:               nodeName,
-			ExecPath:           execPath,
-			Uri:                "",
-			Id:                 "",
-			LogDir:             logDir,
-			DbDir:              dbDir,
-			WhitelistedSubnets: whitelistedSubnets,
-			Config:             cfg.NodeConfigs[i].ConfigFile,
-		}
-	}
-
-	return &localNetwork{
+	stopCtx, stopCancel := context.WithCancel(context.Background())
+	lc := &localNetwork{
 		logger: logger,
 
-		binPath: execPath,
+		binPath: opts.execPath,
 		cfg:     cfg,
+		opts:    opts,
 
 		nodeNames: nodeNames,
 		nodeInfos: nodeInfos,
 		apiClis:   make(map[string]api.Client),
+		writers:   writers,
+		sidecars:  sidecars,
+		exits:     newNodeExits(),
+		sink:      sink,
+
+		portConflictc: portConflictc,
 
-		readyc: make(chan struct{}),
+		trafficRules: make(map[trafficRule]struct{}),
+		diskDevices:  make(map[string]*diskDevice),
+		contained:    make(map[string]bool),
 
-		stopc: make(chan struct{}),
-		donec: make(chan struct{}),
-		errc:  make(chan error, 1),
-	}, nil
+		lifecycle:   newLifecycle(),
+		seedPending: len(opts.seedActions) > 0,
+		createdAt:   time.Now(),
+
+		stopCtx:    stopCtx,
+		stopCancel: stopCancel,
+	}
+	if err := lc.writeBootstrapPeers(); err != nil {
+		stopCancel()
+		return nil, err
+	}
+	created = true
+	return lc, nil
 }
 
-func (lc *localNetwork) start() {
-	defer func() {
-		close(lc.donec)
-	}()
+// setupNode fills the node config of the named node: the config files, the
+// binary, and the output writers. It returns the node info, and the writers.
+func setupNode(opts networkOptions, nc *node.Config, ports nodePorts, cChainConfig []byte, sink logSink, portConflictc chan<- string) (*rpcpb.NodeInfo, [2]*writer, error) {
+	nodeName := nc.Name
+	dirs := nodeDirs{
+		logDir:  filepath.Join(opts.rootDataDir, nodeName, "log"),
+		dbDir:   filepath.Join(opts.rootDataDir, nodeName, "db-dir"),
+		ipcsDir: filepath.Join(opts.rootDataDir, nodeName, "ipcs"),
+	}
+
+	db, err := nodeDatabaseConfig(opts.databaseConfig, opts.nodeDatabaseConfigs[nodeName])
+	if err != nil {
+		return nil, [2]*writer{}, err
+	}
+	dbFlags, err := databaseFlags(db, opts.globalNodeConfig)
+	if err != nil {
+		return nil, [2]*writer{}, err
+	}
+	nc.ConfigFile, err = buildNodeConfig(mergeConfigs(opts.globalNodeConfig, dbFlags), opts.logLevel, opts.nodeLogLevels[nodeName], dirs, ports, opts.whitelistedSubnets)
+	if err != nil {
+		return nil, [2]*writer{}, err
+	}
+	stateSync := opts.stateSyncNodes[nodeName]
+	if stateSync {
+		if cChainConfig, err = withStateSync(cChainConfig); err != nil {
+			return nil, [2]*writer{}, err
+		}
+	}
+	if archival, ok := opts.nodeArchival[nodeName]; ok {
+		if cChainConfig, err = withPruning(cChainConfig, !archival); err != nil {
+			return nil, [2]*writer{}, err
+		}
+	}
+	if cChainConfig != nil {
+		nc.CChainConfigFile = cChainConfig
+	}
+	limits, err := nodeResourceLimits(opts.resourceLimits, opts.nodeResourceLimits[nodeName])
+	if err != nil {
+		return nil, [2]*writer{}, err
+	}
+	binPath, unit := opts.execPath, ""
+	if opts.supervisor == SupervisorSystemd {
+		// the cgroup limits would move the node out of its unit
+		if limits != nil {
+			return nil, [2]*writer{}, ErrSupervisedCgroups
+		}
+		unit = nodeUnitName(opts.rootDataDir, nodeName)
+		binPath, err = writeSuperviseScript(filepath.Join(opts.rootDataDir, nodeName), unit, opts.execPath)
+		if err != nil {
+			return nil, [2]*writer{}, err
+		}
+	} else {
+		binPath, err = writeExitScript(filepath.Join(opts.rootDataDir, nodeName), opts.execPath)
+		if err != nil {
+			return nil, [2]*writer{}, err
+		}
+	}
+	stdout := newWriter(nodeName, "stdout", sink, portConflictc)
+	stderr := newWriter(nodeName, "stderr", sink, portConflictc)
+	nc.ImplSpecificConfig = local.NodeConfig{
+		BinaryPath: binPath,
+		Stdout:     stdout,
+		Stderr:     stderr,
+	}
+
+	info := &rpcpb.NodeInfo{
+		Name:               nodeName,
+		ExecPath:           opts.execPath,
+		Uri:                "",
+		Id:                 "",
+		LogDir:             dirs.logDir,
+		DbDir:              dirs.dbDir,
+		IpcsDir:            dirs.ipcsDir,
+		WhitelistedSubnets: opts.whitelistedSubnets,
+		Config:             nc.ConfigFile,
+		HttpPort:           uint32(ports.httpPort),
+		StakingPort:        uint32(ports.stakingPort),
+		ResourceLimits:     limits,
+		Beacon:             nc.IsBeacon,
+		UnitName:           unit,
+		StateSync:          stateSync,
+		Archival:           isArchival(cChainConfig),
+		PluginDir:          nodePluginDir(opts.execPath, nc.ConfigFile),
+	}
+	info.Plugins = opts.plugins.list(info.PluginDir)
+	setNodeAddresses(info)
+	return info, [2]*writer{stdout, stderr}, nil
+}
+
+// start creates the local network and waits for it to become healthy.
+// The caller must call "startWg.Add(1)" before starting the routine.
+// The context only carries the trace of the start request.
+func (lc *localNetwork) start(traceCtx context.Context) (err error) {
+	defer lc.startWg.Done()
+
+	traceCtx, span := tracing.Start(traceCtx, "start network")
+	defer func() { tracing.End(span, err) }()
+	startTime := time.Now()
 
 	color.Outf("{{blue}}{{bold}}create and run local network{{/}}\n")
-	nw, err := local.NewNetwork(lc.logger, lc.cfg)
+	if err := lc.attachDiskDevices(); err != nil {
+		lc.transition(stateErrored, err)
+		lc.collectArtifacts()
+		return err
+	}
+	if lc.opts.resumeCheckpoint != "" {
+		if err := lc.resumeCheckpoint(lc.opts.resumeCheckpoint); err != nil {
+			lc.transition(stateErrored, err)
+			lc.collectArtifacts()
+			return err
+		}
+	}
+	_, createSpan := tracing.Start(traceCtx, "create network")
+	// with a launch interval, the network is created with the first node,
+	// and the others are added one by one
+	first, rest := lc.cfg, []node.Config(nil)
+	if lc.opts.launchInterval > 0 && len(lc.cfg.NodeConfigs) > 1 {
+		first.NodeConfigs, rest = lc.cfg.NodeConfigs[:1], lc.cfg.NodeConfigs[1:]
+	}
+	launched := time.Now()
+	nw, err := local.NewNetwork(lc.logger, first)
+	tracing.End(createSpan, err)
 	if err != nil {
-		lc.errc <- err
-		return
+		lc.transition(stateErrored, err)
+		lc.collectArtifacts()
+		return err
 	}
 	lc.nw = nw
+	launchedNames := make([]string, 0, len(first.NodeConfigs))
+	for _, nc := range first.NodeConfigs {
+		lc.sidecars.launch(nc)
+		lc.watchExit(nc.Name, launched)
+		launchedNames = append(launchedNames, nc.Name)
+	}
+	lc.transition(stateBootstrapping, nil)
+	// limited from the bootstrap on, to emulate weak validators
+	if err := lc.limitNodes(lc.stopCtx, launchedNames); err != nil {
+		lc.transition(stateErrored, err)
+		lc.collectArtifacts()
+		return err
+	}
+	if err := lc.launchStaggered(rest); err != nil {
+		lc.transition(stateErrored, err)
+		if !errors.Is(err, errAborted) {
+			lc.collectArtifacts()
+		}
+		return err
+	}
 
-	if err := lc.waitForHealthy(); err != nil {
-		lc.errc <- err
-		return
+	for retries := 0; ; retries++ {
+		conflicted, err := lc.waitForHealthyOrConflict(traceCtx)
+		if err == nil {
+			if err = lc.seed(traceCtx); err != nil {
+				lc.transition(stateErrored, err)
+				if !errors.Is(err, errAborted) {
+					lc.collectArtifacts()
+				}
+				return err
+			}
+			lc.opts.latencies.observe(opStart, startTime)
+			return nil
+		}
+		if len(conflicted) > 0 && lc.opts.basePort == 0 && retries < maxPortRetries {
+			zap.L().Warn("nodes failed to bind their ports, reassigning",
+				zap.Strings("names", conflicted),
+				zap.Int("retries", retries),
+			)
+			if err = lc.rebindNodes(traceCtx, conflicted); err == nil {
+				continue
+			}
+		}
+		lc.transition(stateErrored, err)
+		if !errors.Is(err, errAborted) {
+			lc.collectArtifacts()
+		}
+		return err
 	}
 }
 
@@ -160,29 +530,92 @@ const healthyWait = 2 * time.Minute
 
 var errAborted = errors.New("aborted")
 
-func (lc *localNetwork) waitForHealthy() error {
+// waitForHealthyUnlocked waits for all nodes to report healthy, and moves
+// the network to the healthy state. On failure, the caller decides the next
+// state. The wait is aborted when the given context is done, or the network
+// stops. Must be called without the lock (ref. "opts.mu"), which is only
+// taken to publish the nodes, so the server requests are not blocked by
+// the wait.
+func (lc *localNetwork) waitForHealthyUnlocked(ctx context.Context) error {
+	lc.opts.mu.RLock()
+	procs := lc.nodeProcesses()
+	lc.opts.mu.RUnlock()
+	nodes, err := lc.awaitHealthy(ctx, procs)
+	if err != nil {
+		return err
+	}
+	lc.opts.mu.Lock()
+	lc.setHealthyNodes(nodes)
+	lc.opts.mu.Unlock()
+	return nil
+}
+
+// awaitHealthy waits for all nodes to report healthy, and returns them.
+// The nodes are polled with backoff (ref. "pollHealthy"), then confirmed
+// healthy by the network.
+func (lc *localNetwork) awaitHealthy(ctx context.Context, procs map[string]nodeProcess) (nodes map[string]node.Node, err error) {
+	ctx, span := tracing.Start(ctx, "wait healthy")
+	defer func() { tracing.End(span, err) }()
+
 	color.Outf("{{blue}}{{bold}}waiting for all nodes to report healthy...{{/}}\n")
+	waitStart := time.Now()
 
-	ctx, cancel := context.WithTimeout(context.Background(), healthyWait)
+	ctx, cancel := context.WithTimeout(ctx, healthyWait)
 	defer cancel()
+	go func() {
+		select {
+		case <-lc.stopCtx.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	if err := lc.pollHealthy(ctx, procs); err != nil {
+		if ctx.Err() == nil {
+			return nil, err
+		}
+		if lc.stopCtx.Err() != nil {
+			return nil, errAborted
+		}
+		return nil, ctx.Err()
+	}
 	hc := lc.nw.Healthy(ctx)
 	select {
-	case <-lc.stopc:
-		return errAborted
 	case <-ctx.Done():
-		return ctx.Err()
+		if lc.stopCtx.Err() != nil {
+			return nil, errAborted
+		}
+		return nil, ctx.Err()
 	case err := <-hc:
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
-	nodes, err := lc.nw.GetAllNodes()
+	nodes, err = lc.nw.GetAllNodes()
 	if err != nil {
-		return err
+		return nil, err
 	}
+	lc.opts.latencies.observe(opWaitHealthy, waitStart)
+	return nodes, nil
+}
+
+// setHealthyNodes sets the URIs, IDs, and API clients of the healthy nodes,
+// and moves the network to the healthy state.
+// Must be called with the lock (ref. "opts.mu") held.
+func (lc *localNetwork) setHealthyNodes(nodes map[string]node.Node) {
 	lc.nodes = nodes
+	lc.setNodeAPIs(nodes)
+
+	// on start, the network is healthy once seeded
+	if !lc.seedPending {
+		lc.transition(stateHealthy, nil)
+	}
+}
 
+// setNodeAPIs sets the URIs, IDs, and API clients of the nodes.
+// Must be called with the lock (ref. "opts.mu") held.
+func (lc *localNetwork) setNodeAPIs(nodes map[string]node.Node) {
 	for name, node := range nodes {
 		uri := fmt.Sprintf("http://%s:%d", node.GetURL(), node.GetAPIPort())
 		nodeID := node.GetNodeID().PrefixedString(constants.NodeIDPrefix)
@@ -193,39 +626,199 @@ func (lc *localNetwork) waitForHealthy() error {
 		lc.apiClis[name] = node.GetAPIClient()
 		color.Outf("{{cyan}}%s: node ID %q, URI %q{{/}}\n", name, nodeID, uri)
 	}
+}
+
+// transition moves the network to the given state, logging (not returning)
+// invalid transitions, e.g., a failed health check racing with stop.
+func (lc *localNetwork) transition(to rpcpb.NetworkState, err error) {
+	from, _ := lc.lifecycle.get()
+	if terr := lc.lifecycle.transition(to, err); terr != nil {
+		zap.L().Debug("ignoring network state transition", zap.Error(terr))
+		return
+	}
+	if ev, ok := stateEvents[to]; ok && from != to {
+		lc.opts.webhooks.send(ev, lc.opts, "", err)
+	}
+	lc.opts.readiness.update()
+}
+
+// markUnhealthy moves a previously healthy network to the degraded state.
+// Failures during the initial bootstrap are handled by "start".
+func (lc *localNetwork) markUnhealthy(err error) {
+	if errors.Is(err, errAborted) || errors.Is(err, context.Canceled) {
+		return
+	}
+	if lc.lifecycle.check(stateHealthy, stateDegraded) == nil {
+		lc.transition(stateDegraded, err)
+	}
+}
 
-	lc.readycCloseOnce.Do(func() {
-		close(lc.readyc)
+// cancelStart moves the network to the stopping state, and aborts the start
+// routine and the in-flight health checks, without waiting for them.
+func (lc *localNetwork) cancelStart() {
+	lc.cancelOnce.Do(func() {
+		lc.transition(stateStopping, nil)
+		lc.stopCancel()
 	})
-	return nil
 }
 
+// stop cancels the start, and tears down the network once the start routine
+// returns. The start routine takes the lock (ref. "opts.mu") to publish the
+// nodes, so a caller holding the lock must first wait for it without the
+// lock (ref. "server.stopNetworkLocked"), and then collect the artifacts
+// without the lock.
 func (lc *localNetwork) stop() {
 	lc.stopOnce.Do(func() {
-		close(lc.stopc)
-		serr := lc.nw.Stop(context.Background())
-		<-lc.donec
+		lc.cancelStart()
+		// wait for the start routine, so "lc.nw" is not read concurrently
+		lc.startWg.Wait()
+		lc.sidecars.stopAll()
+		var serr error
+		if lc.nw != nil {
+			ctx := context.Background()
+			if lc.opts.shutdownGracePeriod > 0 {
+				// the runner kills the nodes still running when the context is done
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, lc.opts.shutdownGracePeriod)
+				defer cancel()
+			}
+			serr = lc.nw.Stop(ctx)
+		}
+		lc.clearTrafficRules()
+		lc.detachDiskDevices()
+		lc.releaseCgroups()
+		lc.transition(stateStopped, serr)
 		color.Outf("{{red}}{{bold}}terminated network{{/}} (error %v)\n", serr)
+		if err := lc.sink.Close(); err != nil {
+			zap.L().Warn("failed to close log sink", zap.Error(err))
+		}
 	})
 }
 
+// collectArtifacts archives the network logs and configs, if requested.
+// Must be called without the lock (ref. "opts.mu").
+func (lc *localNetwork) collectArtifacts() {
+	if lc.opts.artifactsPath == "" {
+		return
+	}
+	if err := lc.archiveArtifacts(lc.opts.artifactsPath); err != nil {
+		zap.L().Warn("failed to collect artifacts", zap.String("path", lc.opts.artifactsPath), zap.Error(err))
+		return
+	}
+	color.Outf("{{blue}}collected artifacts at %q{{/}}\n", lc.opts.artifactsPath)
+}
+
+// collectCrashArtifacts archives the network logs and configs next to the
+// artifacts on stop, if requested, once the node exits unexpectedly.
+// Must be called without the lock (ref. "opts.mu").
+func (lc *localNetwork) collectCrashArtifacts(name string) {
+	if lc.opts.artifactsPath == "" {
+		return
+	}
+	path := crashArtifactsPath(lc.opts.artifactsPath, name, time.Now())
+	if err := lc.archiveArtifacts(path); err != nil {
+		zap.L().Warn("failed to collect crash artifacts", zap.String("path", path), zap.Error(err))
+		return
+	}
+	color.Outf("{{blue}}collected crash artifacts at %q{{/}}\n", path)
+}
+
+// archiveArtifacts writes the artifacts of the network to the path. The
+// node infos are copied with the lock, and the archive written without it,
+// so the server requests are not blocked meanwhile.
+func (lc *localNetwork) archiveArtifacts(path string) error {
+	lc.opts.mu.RLock()
+	state, _ := lc.lifecycle.get()
+	info := &rpcpb.ClusterInfo{
+		NodeNames:   append([]string(nil), lc.nodeNames...),
+		NodeInfos:   make(map[string]*rpcpb.NodeInfo, len(lc.nodeInfos)),
+		RootDataDir: lc.opts.rootDataDir,
+		State:       state,
+	}
+	for name, ni := range lc.nodeInfos {
+		info.NodeInfos[name] = proto.Clone(ni).(*rpcpb.NodeInfo)
+	}
+	lc.opts.mu.RUnlock()
+	return collectArtifacts(path, lc.opts.rootDataDir, info, lc.opts.fixtures, lc.createdAt)
+}
+
+// writer splits node outputs into lines, written to the log sink
+// and fanned out to the attached consoles.
 type writer struct {
-	c    string
-	name string
-	w    io.Writer
+	name   string
+	stream string
+	sink   logSink
+	// signaled when the node reports a port bind failure
+	portConflictc chan<- string
+
+	mu      sync.Mutex
+	partial []byte
+	subs    map[chan *rpcpb.AttachConsoleResponse]struct{}
+	// last lines, oldest first, for the node exits
+	tail []string
+}
+
+func newWriter(name string, stream string, sink logSink, portConflictc chan<- string) *writer {
+	return &writer{
+		name:          name,
+		stream:        stream,
+		sink:          sink,
+		portConflictc: portConflictc,
+		subs:          make(map[chan *rpcpb.AttachConsoleResponse]struct{}),
+	}
 }
 
-// https://github.com/onsi/ginkgo/blob/v2.0.0/formatter/formatter.go#L52-L73
-var colors = []string{
-	"{{green}}",
-	"{{orange}}",
-	"{{blue}}",
-	"{{magenta}}",
-	"{{cyan}}",
+// lastLines returns a copy of the last output lines, oldest first.
+func (wr *writer) lastLines() []string {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+	return append([]string(nil), wr.tail...)
 }
 
+// Write never fails, so that sink errors do not break the node output pipe.
 func (wr *writer) Write(p []byte) (n int, err error) {
-	s := formatter.F(wr.c+"[%s]{{/}}	", wr.name)
-	fmt.Fprint(formatter.ColorableStdOut, s)
-	return wr.w.Write(p)
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+	wr.partial = append(wr.partial, p...)
+	for {
+		idx := bytes.IndexByte(wr.partial, '\n')
+		if idx < 0 {
+			break
+		}
+		line := string(wr.partial[:idx])
+		wr.partial = wr.partial[idx+1:]
+		if len(wr.tail) == outputTailLines {
+			wr.tail = append(wr.tail[:0], wr.tail[1:]...)
+		}
+		wr.tail = append(wr.tail, line)
+		if err := wr.sink.WriteLine(wr.name, wr.stream, line); err != nil {
+			zap.L().Debug("failed to write node output", zap.String("name", wr.name), zap.Error(err))
+		}
+		if strings.Contains(line, addrInUseOutput) {
+			select {
+			case wr.portConflictc <- wr.name:
+			default:
+			}
+		}
+		for ch := range wr.subs {
+			select {
+			case ch <- &rpcpb.AttachConsoleResponse{NodeName: wr.name, Stream: wr.stream, Line: line}:
+			default:
+				// drop lines for slow consoles, rather than blocking the node
+			}
+		}
+	}
+	return len(p), nil
+}
+
+func (wr *writer) subscribe(ch chan *rpcpb.AttachConsoleResponse) {
+	wr.mu.Lock()
+	wr.subs[ch] = struct{}{}
+	wr.mu.Unlock()
+}
+
+func (wr *writer) unsubscribe(ch chan *rpcpb.AttachConsoleResponse) {
+	wr.mu.Lock()
+	delete(wr.subs, ch)
+	wr.mu.Unlock()
 }
@@ -0,0 +1,204 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+	"go.uber.org/zap"
+)
+
+// defaultNodeConfig is the base config file of every node,
+// to be overridden by the global node config of the start request.
+var defaultNodeConfig = map[string]interface{}{
+	"network-peer-list-gossip-frequency": "250ms",
+	"network-max-reconnect-delay":        "1s",
+	"public-ip":                          "127.0.0.1",
+	"health-check-frequency":             "2s",
+	"api-admin-enabled":                  true,
+	"api-ipcs-enabled":                   true,
+	"index-enabled":                      true,
+	"log-display-level":                  "INFO",
+	"log-level":                          "INFO",
+}
+
+// ref. avalanchego config "http-host"
+const defaultHTTPHost = "127.0.0.1"
+
+// nodeDirs are the node config entries managed by the runner,
+// which always take precedence over user-provided configs.
+type nodeDirs struct {
+	logDir  string
+	dbDir   string
+	ipcsDir string
+}
+
+// nodePorts are the ports of a node, managed by the runner.
+type nodePorts struct {
+	httpPort    uint16
+	stakingPort uint16
+}
+
+// buildNodeConfig returns the node config file contents, merging the
+// default config, the global config, the log levels (of the node, if any),
+// and the runner-managed entries.
+func buildNodeConfig(globalConfig map[string]interface{}, logLevel string, nodeLogLevels *rpcpb.NodeLogLevels, dirs nodeDirs, ports nodePorts, whitelistedSubnets string) ([]byte, error) {
+	cfg := mergeConfigs(defaultNodeConfig, globalConfig)
+	if logLevel != "" {
+		cfg["log-level"] = logLevel
+	}
+	if l := nodeLogLevels.GetLogLevel(); l != "" {
+		cfg["log-level"] = l
+	}
+	if l := nodeLogLevels.GetLogDisplayLevel(); l != "" {
+		cfg["log-display-level"] = l
+	}
+	cfg["log-dir"] = dirs.logDir
+	cfg["db-dir"] = dirs.dbDir
+	cfg["ipcs-path"] = dirs.ipcsDir
+	cfg["http-port"] = ports.httpPort
+	cfg["staking-port"] = ports.stakingPort
+	// need to whitelist subnet ID to create custom VM chain
+	// ref. vms/platformvm/createChain
+	cfg["whitelisted-subnets"] = whitelistedSubnets
+	if err := validateNodeConfig(cfg); err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(cfg, "", "\t")
+}
+
+// setNodeAddresses sets the IPs of the node info from its config file,
+// falling back to the node defaults.
+func setNodeAddresses(info *rpcpb.NodeInfo) {
+	info.PublicIp, info.HttpHost = "127.0.0.1", defaultHTTPHost
+	cfg := make(map[string]interface{})
+	if err := json.Unmarshal(info.Config, &cfg); err != nil {
+		zap.L().Warn("failed to parse node config", zap.String("name", info.Name), zap.Error(err))
+		return
+	}
+	if ip, ok := cfg["public-ip"].(string); ok && ip != "" {
+		info.PublicIp = ip
+	}
+	if host, ok := cfg["http-host"].(string); ok {
+		info.HttpHost = host
+	}
+}
+
+// mergeConfigs returns a new map with the entries of all the given configs,
+// later configs overriding earlier ones.
+func mergeConfigs(cfgs ...map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{})
+	for _, cfg := range cfgs {
+		for k, v := range cfg {
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
+// parseConfig parses a JSON object, returning nil for an empty string.
+func parseConfig(field string, s string) (map[string]interface{}, error) {
+	if s == "" {
+		return nil, nil
+	}
+	cfg := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(s), &cfg); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", field, err)
+	}
+	return cfg, nil
+}
+
+// diffNodeConfigs returns the changed keys of the node config files,
+// sorted by key.
+func diffNodeConfigs(oldConfig []byte, newConfig []byte) ([]*rpcpb.ConfigChange, error) {
+	oldCfg, err := parseConfig("old node config", string(oldConfig))
+	if err != nil {
+		return nil, err
+	}
+	newCfg, err := parseConfig("new node config", string(newConfig))
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(oldCfg)+len(newCfg))
+	for k := range mergeConfigs(oldCfg, newCfg) {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	changes := []*rpcpb.ConfigChange{}
+	for _, k := range keys {
+		oldV, err := encodeConfigValue(oldCfg, k)
+		if err != nil {
+			return nil, err
+		}
+		newV, err := encodeConfigValue(newCfg, k)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(oldV, newV) {
+			changes = append(changes, &rpcpb.ConfigChange{Key: k, OldValue: string(oldV), NewValue: string(newV)})
+		}
+	}
+	return changes, nil
+}
+
+// encodeConfigValue returns the JSON-encoded value of the key, nil if not set.
+func encodeConfigValue(cfg map[string]interface{}, k string) ([]byte, error) {
+	v, ok := cfg[k]
+	if !ok {
+		return nil, nil
+	}
+	return json.Marshal(v)
+}
+
+// GetNodeConfig returns the config files that the node runs with, after the
+// defaults, the request overrides, and the runner rewrites (e.g., on port
+// conflicts or restarts).
+func (s *server) GetNodeConfig(ctx context.Context, req *rpcpb.GetNodeConfigRequest) (*rpcpb.GetNodeConfigResponse, error) {
+	zap.L().Info("received get node config request", zap.String("name", req.Name))
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.network == nil {
+		return nil, ErrNotBootstrapped
+	}
+	info, ok := s.network.nodeInfos[req.Name]
+	if !ok {
+		return nil, ErrNodeNotFound
+	}
+	resp := &rpcpb.GetNodeConfigResponse{
+		ClusterInfo: s.copyClusterInfo(),
+		ExecPath:    info.ExecPath,
+	}
+	for _, cfg := range s.network.cfg.NodeConfigs {
+		if cfg.Name != req.Name {
+			continue
+		}
+		var err error
+		if resp.Config, err = indentJSON(cfg.ConfigFile); err != nil {
+			return nil, err
+		}
+		if resp.CChainConfig, err = indentJSON(cfg.CChainConfigFile); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+func indentJSON(b []byte) (string, error) {
+	if len(b) == 0 {
+		return "", nil
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, b, "", "  "); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
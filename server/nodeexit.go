@@ -0,0 +1,153 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+	"go.uber.org/zap"
+)
+
+const (
+	// output lines of a node kept for its exit
+	outputTailLines = 100
+
+	exitStatusFile   = "exit-status"
+	exitPollInterval = time.Second
+)
+
+// nodeExits tells the unexpected exits of the node processes from the
+// removals (and restarts).
+type nodeExits struct {
+	mu sync.Mutex
+	// launch of each node, bumped on removal so the exit is expected
+	launches map[string]uint64
+}
+
+func newNodeExits() *nodeExits {
+	return &nodeExits{launches: make(map[string]uint64)}
+}
+
+func (e *nodeExits) bump(name string) uint64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.launches[name]++
+	return e.launches[name]
+}
+
+func (e *nodeExits) expected(name string, launch uint64) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.launches[name] != launch
+}
+
+// writeExitScript writes the launcher of the node into its data directory,
+// and returns its path to be run instead of the binary. The launcher runs
+// the binary, forwards the stop signals to it, and records its exit status
+// (128 + the signal number if killed by a signal, as the shell reports it).
+// The interrupt is forwarded as SIGTERM, as the background commands of a
+// shell ignore it. The stdin of the binary is the console FIFO, as the
+// background commands of a shell read "/dev/null".
+func writeExitScript(nodeDir string, execPath string) (string, error) {
+	status := shellQuote(filepath.Join(nodeDir, exitStatusFile))
+	script := "#!/bin/sh\n" +
+		stdinScript(nodeDir, 3) +
+		bootstrapPeersScript(nodeDir) +
+		shellQuote(execPath) + " \"$@\" <&3 3<&- &\n" +
+		"pid=$!\n" +
+		"trap 'kill -TERM $pid' INT TERM\n" +
+		"wait $pid\n" +
+		"code=$?\n" +
+		"# a trapped signal interrupts the wait\n" +
+		"while kill -0 $pid 2>/dev/null; do\n" +
+		"\twait $pid\n" +
+		"\tcode=$?\n" +
+		"done\n" +
+		"echo $code > " + status + "\n" +
+		"exit $code\n"
+
+	if err := os.MkdirAll(nodeDir, 0o755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(nodeDir, "launch.sh")
+	if err := ioutil.WriteFile(path, []byte(script), 0o755); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// watchExit waits for the exit status of the node launched since the time,
+// unless the node is removed or the network stops first. The status and
+// the last stderr lines are set as the "exit" of the node info, and sent
+// as the "node_exited" event. A supervised node has no exit status.
+func (lc *localNetwork) watchExit(name string, since time.Time) {
+	launch := lc.exits.bump(name)
+	statusPath := filepath.Join(lc.opts.rootDataDir, name, exitStatusFile)
+	go func() {
+		tc := time.NewTicker(exitPollInterval)
+		defer tc.Stop()
+		for {
+			select {
+			case <-lc.stopCtx.Done():
+				return
+			case <-tc.C:
+			}
+			if lc.exits.expected(name, launch) {
+				return
+			}
+			// left by the previous launch, if older
+			fi, err := os.Stat(statusPath)
+			if err != nil || fi.ModTime().Before(since) {
+				continue
+			}
+			b, err := ioutil.ReadFile(statusPath)
+			if err != nil {
+				continue
+			}
+			code, err := strconv.Atoi(strings.TrimSpace(string(b)))
+			if err != nil {
+				// not fully written yet
+				continue
+			}
+			lc.recordExit(name, launch, code)
+			return
+		}
+	}()
+}
+
+func (lc *localNetwork) recordExit(name string, launch uint64, code int) {
+	exit := &rpcpb.NodeExit{
+		ExitCode: int32(code),
+		Time:     time.Now().UTC().Format(time.RFC3339),
+	}
+	if code > 128 {
+		exit.Signal = syscall.Signal(code - 128).String()
+	}
+
+	lc.opts.mu.Lock()
+	info, ok := lc.nodeInfos[name]
+	if !ok || lc.exits.expected(name, launch) {
+		lc.opts.mu.Unlock()
+		return
+	}
+	exit.StderrTail = lc.writers[name][1].lastLines()
+	info.Exit = exit
+	lc.opts.mu.Unlock()
+
+	zap.L().Warn("node exited",
+		zap.String("name", name),
+		zap.Int32("exitCode", exit.ExitCode),
+		zap.String("signal", exit.Signal),
+	)
+	lc.opts.webhooks.sendExit(lc.opts, name, exit)
+	lc.collectCrashArtifacts(name)
+}
@@ -0,0 +1,110 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lasthyphen/djtx-tester/pkg/jsonrpc"
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+)
+
+const nodeProbeTimeout = 5 * time.Second
+
+// chains that must be bootstrapped for a node to be ready
+var readyChains = []string{"P", "X", "C"}
+
+// probeNodes reports the liveness and readiness of every node, so a dead
+// node can be told apart from a node that is still syncing.
+func probeNodes(ctx context.Context, infos map[string]*rpcpb.NodeInfo) map[string]*rpcpb.NodeHealth {
+	names := make([]string, 0, len(infos))
+	for name := range infos {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var (
+		mu      sync.Mutex
+		reports = make(map[string]*rpcpb.NodeHealth, len(infos))
+	)
+	runParallel(ctx, names, 0, func(name string) error {
+		h := probeNode(ctx, infos[name])
+		mu.Lock()
+		reports[name] = h
+		mu.Unlock()
+		return nil
+	})
+	// nodes not probed before the context was done
+	for _, name := range names {
+		if _, ok := reports[name]; !ok {
+			reports[name] = &rpcpb.NodeHealth{Reasons: []string{ctx.Err().Error()}}
+		}
+	}
+	return reports
+}
+
+func probeNode(ctx context.Context, info *rpcpb.NodeInfo) *rpcpb.NodeHealth {
+	h := &rpcpb.NodeHealth{}
+	// the URI is only known once the network was healthy,
+	// but the runner assigned the HTTP port at creation
+	uri := info.Uri
+	if uri == "" {
+		if info.HttpPort == 0 {
+			h.Reasons = append(h.Reasons, "unknown node URI")
+			return h
+		}
+		uri = fmt.Sprintf("http://127.0.0.1:%d", info.HttpPort)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, nodeProbeTimeout)
+	defer cancel()
+
+	// ref. api/health "Health"
+	var health struct {
+		Checks map[string]struct {
+			Error *string `json:"error"`
+		} `json:"checks"`
+		Healthy bool `json:"healthy"`
+	}
+	if err := jsonrpc.Call(ctx, uri, "/ext/health", "health.health", nil, &health); err != nil {
+		h.Reasons = append(h.Reasons, fmt.Sprintf("API unreachable: %v", err))
+		return h
+	}
+	h.Live = true
+
+	failing := make([]string, 0)
+	for name, check := range health.Checks {
+		if check.Error != nil {
+			failing = append(failing, fmt.Sprintf("health check %q: %s", name, *check.Error))
+		}
+	}
+	sort.Strings(failing)
+	h.Reasons = append(h.Reasons, failing...)
+	if !health.Healthy && len(failing) == 0 {
+		h.Reasons = append(h.Reasons, "not healthy")
+	}
+
+	bootstrapped := true
+	for _, chain := range readyChains {
+		// ref. api/info "IsBootstrapped"
+		var reply struct {
+			IsBootstrapped bool `json:"isBootstrapped"`
+		}
+		if err := jsonrpc.Call(ctx, uri, "/ext/info", "info.isBootstrapped", map[string]string{"chain": chain}, &reply); err != nil {
+			h.Reasons = append(h.Reasons, fmt.Sprintf("failed to check %s-chain bootstrap: %v", chain, err))
+			bootstrapped = false
+			continue
+		}
+		if !reply.IsBootstrapped {
+			h.Reasons = append(h.Reasons, fmt.Sprintf("%s-chain not bootstrapped", chain))
+			bootstrapped = false
+		}
+	}
+	h.Ready = health.Healthy && bootstrapped
+	return h
+}
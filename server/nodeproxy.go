@@ -0,0 +1,85 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/metadata"
+)
+
+// path pattern of the node APIs on the gRPC gateway port
+const nodeProxyPattern = "/node/{name}/ext/{path=**}"
+
+// registerNodeProxy serves the node APIs on the gRPC gateway port,
+// "/node/<name>/ext/..." proxying to "<node URI>/ext/...".
+func (s *server) registerNodeProxy() error {
+	for _, meth := range []string{http.MethodGet, http.MethodPost} {
+		if err := s.gwMux.HandlePath(meth, nodeProxyPattern, s.serveNodeProxy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// serveNodeProxy forwards the request to the node, once the caller is
+// authenticated as the owner of the cluster (if tenancy is enabled).
+// The tenant token is not forwarded.
+func (s *server) serveNodeProxy(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+	name := pathParams["name"]
+	tenant := ""
+	if s.tenants != nil {
+		md := metadata.Pairs("authorization", r.Header.Get("Authorization"))
+		t, err := s.tenants.authenticate(metadata.NewIncomingContext(r.Context(), md))
+		if err != nil {
+			http.Error(w, "missing or unknown tenant token", http.StatusUnauthorized)
+			return
+		}
+		// the node APIs may change the chains (e.g., issue transactions)
+		if !t.allows(RoleOperator) {
+			http.Error(w, "the node proxy requires the operator role", http.StatusForbidden)
+			return
+		}
+		tenant = t.Name
+	}
+
+	s.mu.RLock()
+	uri := ""
+	// the cluster of another tenant is hidden, as with the control requests
+	if s.network != nil && (tenant == "" || s.network.opts.tenant == tenant) {
+		if info, ok := s.network.nodeInfos[name]; ok {
+			uri = info.Uri
+		}
+	}
+	s.mu.RUnlock()
+	if uri == "" {
+		http.Error(w, "node not found", http.StatusNotFound)
+		return
+	}
+	target, err := url.Parse(uri)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	proxy := &httputil.ReverseProxy{
+		Director: func(req *http.Request) {
+			req.URL.Scheme = target.Scheme
+			req.URL.Host = target.Host
+			req.URL.Path = strings.TrimPrefix(req.URL.Path, "/node/"+name)
+			req.URL.RawPath = ""
+			req.Host = target.Host
+			req.Header.Del("Authorization")
+		},
+		ErrorHandler: func(w http.ResponseWriter, req *http.Request, err error) {
+			zap.L().Warn("failed to proxy node request", zap.String("name", name), zap.String("path", req.URL.Path), zap.Error(err))
+			w.WriteHeader(http.StatusBadGateway)
+		},
+	}
+	proxy.ServeHTTP(w, r)
+}
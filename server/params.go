@@ -0,0 +1,179 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/lasthyphen/dijetsnodego/config"
+	"github.com/lasthyphen/dijetsnodego/utils/constants"
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+	"go.uber.org/zap"
+)
+
+// GetGenesis returns the genesis the nodes were started with.
+func (s *server) GetGenesis(ctx context.Context, req *rpcpb.GetGenesisRequest) (*rpcpb.GetGenesisResponse, error) {
+	zap.L().Debug("received get genesis request")
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.network == nil {
+		return nil, ErrNotBootstrapped
+	}
+	genesis := s.network.cfg.Genesis
+	g, err := decodeJSON(genesis)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse genesis: %w", err)
+	}
+	networkID, err := genesisNetworkID(g)
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.Sum256([]byte(genesis))
+	return &rpcpb.GetGenesisResponse{
+		Genesis:       genesis,
+		NetworkId:     networkID,
+		GenesisSha256: hex.EncodeToString(h[:]),
+	}, nil
+}
+
+// GetNetworkParams returns the network and chain IDs, the fees, and the
+// staking and consensus parameters of the network, so the tests do not
+// hardcode them (e.g., the chain ID for the EIP-155 signatures).
+func (s *server) GetNetworkParams(ctx context.Context, req *rpcpb.GetNetworkParamsRequest) (*rpcpb.GetNetworkParamsResponse, error) {
+	zap.L().Debug("received get network params request")
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.network == nil {
+		return nil, ErrNotBootstrapped
+	}
+	params, err := s.network.networkParams()
+	if err != nil {
+		return nil, err
+	}
+	return &rpcpb.GetNetworkParamsResponse{Params: params}, nil
+}
+
+// networkParams reads the params from the genesis, and from the config of
+// the first node by name (or the node defaults). Must be called with the
+// lock held.
+func (lc *localNetwork) networkParams() (*rpcpb.NetworkParams, error) {
+	g, err := decodeJSON(lc.cfg.Genesis)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse genesis: %w", err)
+	}
+	networkID, err := genesisNetworkID(g)
+	if err != nil {
+		return nil, err
+	}
+	cGenesis, err := parseCChainGenesis(g)
+	if err != nil {
+		return nil, err
+	}
+	cConfig, _ := cGenesis["config"].(map[string]interface{})
+	cChainID, err := strconv.ParseUint(fmt.Sprint(cConfig["chainId"]), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid C-chain genesis chain ID %v", cConfig["chainId"])
+	}
+
+	nodeCfg := make(map[string]interface{})
+	first := ""
+	for _, nc := range lc.cfg.NodeConfigs {
+		if first != "" && nc.Name > first {
+			continue
+		}
+		if nodeCfg, err = decodeJSON(string(nc.ConfigFile)); err != nil {
+			return nil, fmt.Errorf("failed to parse the config of %q: %w", nc.Name, err)
+		}
+		first = nc.Name
+	}
+	p := nodeParams{cfg: nodeCfg}
+	params := &rpcpb.NetworkParams{
+		NetworkId: networkID,
+		Hrp:       constants.GetHRP(networkID),
+		CChainId:  cChainID,
+
+		TxFee:                 p.uint64(config.TxFeeKey),
+		CreateAssetTxFee:      p.uint64(config.CreateAssetTxFeeKey),
+		CreateSubnetTxFee:     p.uint64(config.CreateSubnetTxFeeKey),
+		CreateBlockchainTxFee: p.uint64(config.CreateBlockchainTxFeeKey),
+
+		MinValidatorStake: p.uint64(config.MinValidatorStakeKey),
+		MaxValidatorStake: p.uint64(config.MaxValidatorStakeKey),
+		MinDelegatorStake: p.uint64(config.MinDelegatorStakeKey),
+		MinDelegationFee:  uint32(p.uint64(config.MinDelegatorFeeKey)),
+		MinStakeDuration:  p.duration(config.MinStakeDurationKey),
+		MaxStakeDuration:  p.duration(config.MaxStakeDurationKey),
+		UptimeRequirement: p.float64(config.UptimeRequirementKey),
+
+		SnowSampleSize:              uint32(p.uint64(config.SnowSampleSizeKey)),
+		SnowQuorumSize:              uint32(p.uint64(config.SnowQuorumSizeKey)),
+		SnowVirtuousCommitThreshold: uint32(p.uint64(config.SnowVirtuousCommitThresholdKey)),
+		SnowRogueCommitThreshold:    uint32(p.uint64(config.SnowRogueCommitThresholdKey)),
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	return params, nil
+}
+
+// nodeParams reads the node config values, or the flag defaults, keeping
+// the first error.
+type nodeParams struct {
+	cfg map[string]interface{}
+	err error
+}
+
+func (p *nodeParams) value(key string) string {
+	if v, ok := p.cfg[key]; ok {
+		return fmt.Sprint(v)
+	}
+	f := nodeFlagSet().Lookup(key)
+	if f == nil {
+		p.setErr(fmt.Errorf("unknown node flag %q", key))
+		return ""
+	}
+	return f.DefValue
+}
+
+func (p *nodeParams) uint64(key string) uint64 {
+	v := p.value(key)
+	n, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		p.setErr(fmt.Errorf("invalid %q value %q", key, v))
+	}
+	return n
+}
+
+func (p *nodeParams) float64(key string) float64 {
+	v := p.value(key)
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		p.setErr(fmt.Errorf("invalid %q value %q", key, v))
+	}
+	return f
+}
+
+// duration returns the duration normalized (e.g., "24h0m0s"), whatever
+// the form of the config.
+func (p *nodeParams) duration(key string) string {
+	v := p.value(key)
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		p.setErr(fmt.Errorf("invalid %q value %q", key, v))
+	}
+	return d.String()
+}
+
+func (p *nodeParams) setErr(err error) {
+	if p.err == nil {
+		p.err = err
+	}
+}
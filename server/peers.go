@@ -0,0 +1,166 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lasthyphen/dijetsnode-go-runner/network/node"
+	"github.com/lasthyphen/dijetsnodego/ids"
+	"github.com/lasthyphen/dijetsnodego/staking"
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+)
+
+var (
+	ErrInvalidBootstrapBeacons = errors.New("invalid bootstrap beacons")
+	ErrInvalidBootstrapPeers   = errors.New("invalid bootstrap peers")
+)
+
+// bootstrapPeersFile of the node directory has the bootstrap IPs and IDs
+// of the node peers, a line each, read by the node launcher.
+const bootstrapPeersFile = "bootstrap-peers"
+
+// staticPeersConfig stops the peer list gossip, so that the nodes
+// only know the peers of their bootstrap flags.
+var staticPeersConfig = map[string]interface{}{
+	"network-peer-list-num-validator-ips":         0,
+	"network-peer-list-validator-gossip-size":     0,
+	"network-peer-list-non-validator-gossip-size": 0,
+	"network-peer-list-peers-gossip-size":         0,
+}
+
+// runnerFlags are passed by the runner on the node command line,
+// which overrides the node config file.
+var runnerFlags = map[string]string{
+	"bootstrap-ips": "bootstrap beacons or peers",
+	"bootstrap-ids": "bootstrap beacons or peers",
+}
+
+// beaconSet returns the set of beacon node names, nil for all nodes.
+func beaconSet(beacons []string, nodeNames []string) (map[string]struct{}, error) {
+	if len(beacons) == 0 {
+		return nil, nil
+	}
+	known := make(map[string]struct{}, len(nodeNames))
+	for _, name := range nodeNames {
+		known[name] = struct{}{}
+	}
+	set := make(map[string]struct{}, len(beacons))
+	for _, name := range beacons {
+		if _, ok := known[name]; !ok {
+			return nil, fmt.Errorf("%w: unknown node %q", ErrInvalidBootstrapBeacons, name)
+		}
+		if _, ok := set[name]; ok {
+			return nil, fmt.Errorf("%w: duplicate node %q", ErrInvalidBootstrapBeacons, name)
+		}
+		set[name] = struct{}{}
+	}
+	return set, nil
+}
+
+// checkBootstrapPeers returns an error if a node, or one of its peers, is
+// not a node of the network, or if the node lists itself or a peer twice.
+func checkBootstrapPeers(peers map[string]*rpcpb.NodeBootstrapPeers, nodeNames []string) error {
+	known := make(map[string]struct{}, len(nodeNames))
+	for _, name := range nodeNames {
+		known[name] = struct{}{}
+	}
+	names := make([]string, 0, len(peers))
+	for name := range peers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, ok := known[name]; !ok {
+			return fmt.Errorf("%w: unknown node %q", ErrInvalidBootstrapPeers, name)
+		}
+		set := make(map[string]struct{}, len(peers[name].GetNames()))
+		for _, peer := range peers[name].GetNames() {
+			if _, ok := known[peer]; !ok {
+				return fmt.Errorf("%w: unknown peer %q of %q", ErrInvalidBootstrapPeers, peer, name)
+			}
+			if peer == name {
+				return fmt.Errorf("%w: %q bootstraps from itself", ErrInvalidBootstrapPeers, name)
+			}
+			if _, ok := set[peer]; ok {
+				return fmt.Errorf("%w: duplicate peer %q of %q", ErrInvalidBootstrapPeers, peer, name)
+			}
+			set[peer] = struct{}{}
+		}
+	}
+	return nil
+}
+
+// bootstrapPeerFlags returns the "bootstrap-ips" and "bootstrap-ids" of the
+// peers, as the runner sets them for the beacons: the staking address of
+// each peer, and its node ID, of its staking certificate. The peers no
+// longer in the network (removed) are skipped.
+func bootstrapPeerFlags(peers []string, cfgs []node.Config, infos map[string]*rpcpb.NodeInfo) (string, string, error) {
+	ips, nodeIDs := make([]string, 0, len(peers)), make([]string, 0, len(peers))
+	for _, peer := range peers {
+		info, ok := infos[peer]
+		if !ok {
+			continue
+		}
+		idx := -1
+		for i, cfg := range cfgs {
+			if cfg.Name == peer {
+				idx = i
+				break
+			}
+		}
+		if idx < 0 {
+			return "", "", ErrNodeNotFound
+		}
+		cert, err := staking.LoadTLSCertFromBytes(cfgs[idx].StakingKey, cfgs[idx].StakingCert)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to load the staking certificate of %q: %w", peer, err)
+		}
+		ips = append(ips, net.JoinHostPort(info.PublicIp, strconv.FormatUint(uint64(info.StakingPort), 10)))
+		nodeIDs = append(nodeIDs, ids.NodeIDFromCert(cert.Leaf).String())
+	}
+	return strings.Join(ips, ","), strings.Join(nodeIDs, ","), nil
+}
+
+// writeBootstrapPeers writes the bootstrap peers file of each node with
+// bootstrap peers, resolved from the current node configs and ports, so
+// the next launch of the node (e.g., a restart) bootstraps from them. The
+// removed nodes are skipped.
+// Must be called with the lock (ref. "opts.mu") held, once the network is
+// created.
+func (lc *localNetwork) writeBootstrapPeers() error {
+	for name, peers := range lc.opts.nodeBootstrapPeers {
+		if _, ok := lc.nodeInfos[name]; !ok {
+			continue
+		}
+		ips, nodeIDs, err := bootstrapPeerFlags(peers.GetNames(), lc.cfg.NodeConfigs, lc.nodeInfos)
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(lc.opts.rootDataDir, name, bootstrapPeersFile)
+		if err := ioutil.WriteFile(path, []byte(ips+"\n"+nodeIDs+"\n"), 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bootstrapPeersScript returns the launcher lines that append the bootstrap
+// flags of the peers file (ref. "writeBootstrapPeers"), if any, to the
+// arguments of the node. The runner passes the flags of the beacons before
+// them, and the last flag wins.
+func bootstrapPeersScript(nodeDir string) string {
+	peers := shellQuote(filepath.Join(nodeDir, bootstrapPeersFile))
+	return "if [ -f " + peers + " ]; then\n" +
+		"\t{ read -r ips; read -r ids; } < " + peers + "\n" +
+		"\tset -- \"$@\" \"--bootstrap-ips=$ips\" \"--bootstrap-ids=$ids\"\n" +
+		"fi\n"
+}
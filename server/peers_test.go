@@ -0,0 +1,126 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lasthyphen/dijetsnode-go-runner/network/node"
+	"github.com/lasthyphen/dijetsnodego/ids"
+	"github.com/lasthyphen/dijetsnodego/staking"
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+)
+
+func TestCheckBootstrapPeers(t *testing.T) {
+	nodeNames := []string{"node1", "node2", "node3"}
+	for i, tv := range []struct {
+		peers map[string]*rpcpb.NodeBootstrapPeers
+		err   error
+	}{
+		{},
+		{peers: map[string]*rpcpb.NodeBootstrapPeers{"node1": {Names: []string{"node2", "node3"}}, "node2": {}}},
+		{peers: map[string]*rpcpb.NodeBootstrapPeers{"node4": {Names: []string{"node1"}}}, err: ErrInvalidBootstrapPeers},
+		{peers: map[string]*rpcpb.NodeBootstrapPeers{"node1": {Names: []string{"node4"}}}, err: ErrInvalidBootstrapPeers},
+		{peers: map[string]*rpcpb.NodeBootstrapPeers{"node1": {Names: []string{"node1"}}}, err: ErrInvalidBootstrapPeers},
+		{peers: map[string]*rpcpb.NodeBootstrapPeers{"node1": {Names: []string{"node2", "node2"}}}, err: ErrInvalidBootstrapPeers},
+	} {
+		if err := checkBootstrapPeers(tv.peers, nodeNames); !errors.Is(err, tv.err) {
+			t.Fatalf("#%d: expected %v, got %v", i, tv.err, err)
+		}
+	}
+}
+
+func TestBootstrapPeersRing(t *testing.T) {
+	dir := t.TempDir()
+	// prints its arguments, a line each
+	bin := filepath.Join(dir, "node.sh")
+	if err := ioutil.WriteFile(bin, []byte("#!/bin/sh\nfor arg in \"$@\"; do echo \"$arg\"; done > \"$(dirname \"$0\")/args\"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	// each node bootstraps from its neighbors
+	const n = 4
+	lc := &localNetwork{
+		opts:      networkOptions{rootDataDir: dir, nodeBootstrapPeers: make(map[string]*rpcpb.NodeBootstrapPeers)},
+		nodeInfos: make(map[string]*rpcpb.NodeInfo),
+	}
+	nodeIDs := make(map[string]string, n)
+	for i := 1; i <= n; i++ {
+		name := fmt.Sprintf("node%d", i)
+		cert, key, err := staking.NewCertAndKeyBytes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		tlsCert, err := staking.LoadTLSCertFromBytes(key, cert)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nodeIDs[name] = ids.NodeIDFromCert(tlsCert.Leaf).String()
+		lc.cfg.NodeConfigs = append(lc.cfg.NodeConfigs, node.Config{Name: name, StakingCert: cert, StakingKey: key})
+		lc.nodeInfos[name] = &rpcpb.NodeInfo{Name: name, PublicIp: "127.0.0.1", StakingPort: uint32(9650 + 2*i)}
+		lc.opts.nodeBootstrapPeers[name] = &rpcpb.NodeBootstrapPeers{Names: []string{
+			fmt.Sprintf("node%d", (i+n-2)%n+1),
+			fmt.Sprintf("node%d", i%n+1),
+		}}
+		if err := os.MkdirAll(filepath.Join(dir, name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := checkBootstrapPeers(lc.opts.nodeBootstrapPeers, []string{"node1", "node2", "node3", "node4"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := lc.writeBootstrapPeers(); err != nil {
+		t.Fatal(err)
+	}
+
+	launch := func(name string) []string {
+		launcher, err := writeExitScript(filepath.Join(dir, name), bin)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// as the runner passes the beacons
+		if err := exec.Command(launcher, "--bootstrap-ips=127.0.0.1:9650", "--bootstrap-ids=NodeID-beacon").Run(); err != nil {
+			t.Fatal(err)
+		}
+		b, err := ioutil.ReadFile(filepath.Join(dir, "args"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.Split(strings.TrimSpace(string(b)), "\n")
+	}
+	for i, tv := range []struct {
+		name string
+		ips  string
+		ids  string
+	}{
+		{name: "node1", ips: "127.0.0.1:9658,127.0.0.1:9654", ids: nodeIDs["node4"] + "," + nodeIDs["node2"]},
+		{name: "node2", ips: "127.0.0.1:9652,127.0.0.1:9656", ids: nodeIDs["node1"] + "," + nodeIDs["node3"]},
+		{name: "node3", ips: "127.0.0.1:9654,127.0.0.1:9658", ids: nodeIDs["node2"] + "," + nodeIDs["node4"]},
+		{name: "node4", ips: "127.0.0.1:9656,127.0.0.1:9652", ids: nodeIDs["node3"] + "," + nodeIDs["node1"]},
+	} {
+		args := launch(tv.name)
+		// the last flag wins
+		exp := []string{"--bootstrap-ips=127.0.0.1:9650", "--bootstrap-ids=NodeID-beacon", "--bootstrap-ips=" + tv.ips, "--bootstrap-ids=" + tv.ids}
+		if strings.Join(args, " ") != strings.Join(exp, " ") {
+			t.Fatalf("#%d: expected %q, got %q", i, exp, args)
+		}
+	}
+
+	// the removed peers are skipped on the next launches
+	delete(lc.nodeInfos, "node3")
+	if err := lc.writeBootstrapPeers(); err != nil {
+		t.Fatal(err)
+	}
+	args := launch("node2")
+	if exp := "--bootstrap-ips=127.0.0.1:9652"; args[2] != exp {
+		t.Fatalf("expected %q, got %q", exp, args[2])
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+)
+
+const pluginVersionTimeout = 2 * time.Second
+
+type pluginCacheKey struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// pluginInventory caches the hash and version of the VM plugins keyed by
+// their path and file stats, so the nodes sharing a plugin directory (and
+// the restarts) do not hash the plugins again.
+type pluginInventory struct {
+	mu    sync.Mutex
+	cache map[pluginCacheKey]*rpcpb.PluginInfo
+}
+
+func newPluginInventory() *pluginInventory {
+	return &pluginInventory{cache: make(map[pluginCacheKey]*rpcpb.PluginInfo)}
+}
+
+// nodePluginDir returns the plugin directory of the node: the "plugin-dir"
+// of its config, or else the "plugins" directory next to its binary, as the
+// node resolves it.
+func nodePluginDir(execPath string, configFile []byte) string {
+	var cfg map[string]interface{}
+	if err := json.Unmarshal(configFile, &cfg); err == nil {
+		if dir, ok := cfg["plugin-dir"].(string); ok && dir != "" {
+			return dir
+		}
+	}
+	return filepath.Join(filepath.Dir(execPath), "plugins")
+}
+
+// list returns the plugin binaries of the directory, sorted by name, none
+// if the directory does not exist. The plugins that fail to hash are
+// reported with their error.
+func (pi *pluginInventory) list(dir string) []*rpcpb.PluginInfo {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			zap.L().Warn("failed to list the plugins", zap.String("dir", dir), zap.Error(err))
+		}
+		return nil
+	}
+	plugins := make([]*rpcpb.PluginInfo, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() || e.Mode()&0o111 == 0 {
+			continue
+		}
+		plugins = append(plugins, pi.get(filepath.Join(dir, e.Name()), e))
+	}
+	return plugins
+}
+
+func (pi *pluginInventory) get(path string, fi os.FileInfo) *rpcpb.PluginInfo {
+	key := pluginCacheKey{path: path, size: fi.Size(), modTime: fi.ModTime()}
+	pi.mu.Lock()
+	cached, ok := pi.cache[key]
+	pi.mu.Unlock()
+	if ok {
+		return proto.Clone(cached).(*rpcpb.PluginInfo)
+	}
+
+	info := &rpcpb.PluginInfo{
+		Name:    fi.Name(),
+		Path:    path,
+		Size:    uint64(fi.Size()),
+		Version: pluginVersion(path),
+	}
+	sum, err := fileSHA256(path)
+	if err != nil {
+		info.Error = err.Error()
+		return info
+	}
+	info.Sha256 = sum
+
+	pi.mu.Lock()
+	pi.cache[key] = info
+	pi.mu.Unlock()
+	return proto.Clone(info).(*rpcpb.PluginInfo)
+}
+
+// pluginVersion returns the first line of the "--version" output of the
+// plugin (e.g., "Subnet-EVM/v0.4.0 [rpcchainvm=16]"), or empty if the
+// plugin does not tell it. The plugins without the flag refuse to run
+// outside of the node, or fail on it.
+func pluginVersion(path string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), pluginVersionTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, path, "--version").Output()
+	if err != nil {
+		return ""
+	}
+	line := bytes.TrimSpace(out)
+	if i := bytes.IndexByte(line, '\n'); i >= 0 {
+		line = bytes.TrimSpace(line[:i])
+	}
+	return string(line)
+}
@@ -0,0 +1,198 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/lasthyphen/djtx-tester/pkg/color"
+	"github.com/lasthyphen/djtx-tester/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+const (
+	// node output on a port bind failure (EADDRINUSE)
+	addrInUseOutput = "address already in use"
+
+	// maximum number of port reassignments of each node on start
+	maxPortRetries = 3
+)
+
+var (
+	ErrInvalidBasePort = errors.New("invalid base port")
+	ErrPortConflict    = errors.New("port conflict")
+)
+
+// assignPorts returns the ports of n nodes: the static ports from the base
+// port if not zero, or free ports otherwise.
+func assignPorts(n int, basePort uint32) ([]nodePorts, error) {
+	if basePort > 0 {
+		if basePort+uint32(2*n) > math.MaxUint16+1 {
+			return nil, fmt.Errorf("%w: %d (%d nodes)", ErrInvalidBasePort, basePort, n)
+		}
+		ports := make([]nodePorts, n)
+		for i := range ports {
+			ports[i] = nodePorts{
+				httpPort:    uint16(basePort) + uint16(2*i),
+				stakingPort: uint16(basePort) + uint16(2*i+1),
+			}
+		}
+		return ports, nil
+	}
+
+	free, err := getFreePorts(2 * n)
+	if err != nil {
+		return nil, err
+	}
+	ports := make([]nodePorts, n)
+	for i := range ports {
+		ports[i] = nodePorts{httpPort: free[2*i], stakingPort: free[2*i+1]}
+	}
+	return ports, nil
+}
+
+// getFreePorts returns n distinct ports that are free at the time of the call.
+// Another process may still grab them before the nodes bind, which is
+// handled by "rebindNodes".
+func getFreePorts(n int) ([]uint16, error) {
+	lns := make([]net.Listener, 0, n)
+	defer func() {
+		for _, ln := range lns {
+			ln.Close()
+		}
+	}()
+	ports := make([]uint16, 0, n)
+	for i := 0; i < n; i++ {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			return nil, err
+		}
+		lns = append(lns, ln)
+		ports = append(ports, uint16(ln.Addr().(*net.TCPAddr).Port))
+	}
+	return ports, nil
+}
+
+// waitForHealthyOrConflict is "waitForHealthyUnlocked", aborted early when a
+// node reports a port conflict. It returns the nodes with port conflicts, if
+// any.
+func (lc *localNetwork) waitForHealthyOrConflict(traceCtx context.Context) ([]string, error) {
+	ctx, cancel := context.WithCancel(lc.stopCtx)
+	defer cancel()
+	ctx = trace.ContextWithSpan(ctx, trace.SpanFromContext(traceCtx))
+
+	conflicted := make(map[string]struct{})
+	donec := make(chan struct{})
+	go func() {
+		defer close(donec)
+		select {
+		case name := <-lc.portConflictc:
+			conflicted[name] = struct{}{}
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	err := lc.waitForHealthyUnlocked(ctx)
+	cancel()
+	<-donec
+
+	// other nodes may have failed at the same time
+	for {
+		select {
+		case name := <-lc.portConflictc:
+			conflicted[name] = struct{}{}
+			continue
+		default:
+		}
+		break
+	}
+	if err == nil || len(conflicted) == 0 {
+		return nil, err
+	}
+	names := make([]string, 0, len(conflicted))
+	for name := range conflicted {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, fmt.Errorf("%w: %s", ErrPortConflict, strings.Join(names, ", "))
+}
+
+// rebindNodes restarts the nodes with new free ports.
+func (lc *localNetwork) rebindNodes(ctx context.Context, names []string) (err error) {
+	_, span := tracing.Start(ctx, "rebind nodes", trace.WithAttributes(attribute.StringSlice("names", names)))
+	defer func() { tracing.End(span, err) }()
+
+	ports, err := getFreePorts(2 * len(names))
+	if err != nil {
+		return err
+	}
+	if err := lc.reassignPorts(names, ports); err != nil {
+		return err
+	}
+	return lc.limitNodes(lc.stopCtx, names)
+}
+
+// reassignPorts restarts the nodes with the given HTTP and staking ports.
+func (lc *localNetwork) reassignPorts(names []string, ports []uint16) (err error) {
+	// the start routine runs without the lock
+	lc.opts.mu.Lock()
+	defer lc.opts.mu.Unlock()
+
+	for i, name := range names {
+		idx := -1
+		for j, cfg := range lc.cfg.NodeConfigs {
+			if cfg.Name == name {
+				idx = j
+				break
+			}
+		}
+		if idx < 0 {
+			return ErrNodeNotFound
+		}
+		info := lc.nodeInfos[name]
+		newPorts := nodePorts{httpPort: ports[2*i], stakingPort: ports[2*i+1]}
+		color.Outf("{{yellow}}%s: port conflict, rebinding to HTTP port %d, staking port %d{{/}}\n", name, newPorts.httpPort, newPorts.stakingPort)
+
+		nodeConfig := lc.cfg.NodeConfigs[idx]
+		nodeConfig.ConfigFile, err = buildNodeConfig(
+			lc.opts.globalNodeConfig,
+			lc.opts.logLevel,
+			lc.opts.nodeLogLevels[name],
+			nodeDirs{logDir: info.LogDir, dbDir: info.DbDir, ipcsDir: info.IpcsDir},
+			newPorts,
+			info.WhitelistedSubnets,
+		)
+		if err != nil {
+			return err
+		}
+
+		// the node already exited, but the network still tracks it
+		if err := lc.removeNode(name); err != nil {
+			zap.L().Debug("failed to remove conflicted node", zap.String("name", name), zap.Error(err))
+		}
+		if err := lc.addNode(nodeConfig); err != nil {
+			return err
+		}
+		lc.cfg.NodeConfigs[idx] = nodeConfig
+		oldStakingPort := info.StakingPort
+		info.Config = nodeConfig.ConfigFile
+		info.HttpPort = uint32(newPorts.httpPort)
+		info.StakingPort = uint32(newPorts.stakingPort)
+		setNodeAddresses(info)
+		if err := lc.rekeyTrafficRules(name, oldStakingPort); err != nil {
+			return err
+		}
+	}
+	// for the next launches of the nodes bootstrapping from the rebound
+	// nodes, which already run
+	return lc.writeBootstrapPeers()
+}
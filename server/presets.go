@@ -0,0 +1,261 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrPresetNotFound  = errors.New("preset not found")
+	ErrEmptyPresetName = errors.New("empty preset name")
+)
+
+// Preset is a named set of start options.
+// Custom presets are loaded from "<name>.json" files in the presets directory.
+type Preset struct {
+	Name         string                 `json:"name"`
+	Description  string                 `json:"description"`
+	NumNodes     uint32                 `json:"numNodes"`
+	LogLevel     string                 `json:"logLevel"`
+	NodeConfig   map[string]interface{} `json:"nodeConfig"`
+	CChainConfig map[string]interface{} `json:"cChainConfig"`
+}
+
+var builtinPresets = []Preset{
+	{
+		Name:        "minimal-1node",
+		Description: "single node with staking disabled",
+		NumNodes:    1,
+		NodeConfig: map[string]interface{}{
+			"staking-enabled":  false,
+			"snow-sample-size": 1,
+			"snow-quorum-size": 1,
+		},
+	},
+	{
+		Name:        "default-5node",
+		Description: "five validators of the default local network",
+		NumNodes:    5,
+	},
+	{
+		Name:        "heavy-indexer",
+		Description: "five validators with all indexers and debug APIs enabled",
+		NumNodes:    5,
+		NodeConfig: map[string]interface{}{
+			"index-enabled":          true,
+			"index-allow-incomplete": false,
+			"api-keystore-enabled":   true,
+		},
+		CChainConfig: map[string]interface{}{
+			"snowman-api-enabled": true,
+			"eth-apis": []interface{}{
+				"public-eth",
+				"public-eth-filter",
+				"net",
+				"web3",
+				"internal-public-eth",
+				"internal-public-blockchain",
+				"internal-public-transaction-pool",
+				"internal-public-account",
+				"debug-tracer",
+			},
+		},
+	},
+	{
+		Name:        "archival",
+		Description: "five validators keeping the full C-chain state history",
+		NumNodes:    5,
+		CChainConfig: map[string]interface{}{
+			"pruning-enabled": false,
+		},
+	},
+}
+
+// loadPresets returns the built-in presets, overridden by the custom presets
+// in the given directory (if not empty), and then by the configured ones.
+func loadPresets(dir string, configured []Preset) (map[string]Preset, error) {
+	presets := make(map[string]Preset, len(builtinPresets))
+	for _, p := range builtinPresets {
+		presets[p.Name] = p
+	}
+
+	files := []string{}
+	if dir != "" {
+		var err error
+		files, err = filepath.Glob(filepath.Join(dir, "*.json"))
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, f := range files {
+		b, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		var p Preset
+		if err := json.Unmarshal(b, &p); err != nil {
+			return nil, fmt.Errorf("invalid preset %q: %w", f, err)
+		}
+		if p.Name == "" {
+			p.Name = strings.TrimSuffix(filepath.Base(f), ".json")
+		}
+		if _, ok := presets[p.Name]; ok {
+			zap.L().Info("overriding preset", zap.String("name", p.Name), zap.String("file", f))
+		}
+		presets[p.Name] = p
+	}
+
+	for _, p := range configured {
+		if p.Name == "" {
+			return nil, ErrEmptyPresetName
+		}
+		if _, ok := presets[p.Name]; ok {
+			zap.L().Info("overriding preset", zap.String("name", p.Name))
+		}
+		presets[p.Name] = p
+	}
+	return presets, nil
+}
+
+// resolveNetworkOptions merges the start request onto its preset, if any;
+// the request fields take precedence.
+func (s *server) resolveNetworkOptions(req *rpcpb.StartRequest, rootDataDir string) (networkOptions, error) {
+	opts := networkOptions{
+		execPath:           req.GetExecPath(),
+		rootDataDir:        rootDataDir,
+		fundedKey:          s.fundedKey,
+		whitelistedSubnets: req.GetWhitelistedSubnets(),
+		logSinks:           req.GetLogSinks(),
+		basePort:           req.GetBasePort(),
+		genesisBalances:    req.GetGenesisBalances(),
+		resourceLimits:     req.GetResourceLimits(),
+		nodeResourceLimits: req.GetNodeResourceLimits(),
+		nodeLogLevels:      req.GetNodeLogLevels(),
+		bootstrapBeacons:   req.GetBootstrapBeacons(),
+		nodeBootstrapPeers: req.GetNodeBootstrapPeers(),
+		latencies:          s.latencies,
+		plugins:            s.plugins,
+		webhooks:           s.webhooks,
+		readiness:          s.readiness,
+		mu:                 &s.mu,
+	}
+	opts.databaseConfig, opts.nodeDatabaseConfigs = req.GetDatabaseConfig(), req.GetNodeDatabaseConfigs()
+	opts.diskFaultNodes, opts.diskDeviceBytes = req.GetDiskFaultNodes(), req.GetDiskFaultDeviceBytes()
+	opts.shutdownGracePeriod = s.cfg.ShutdownGracePeriod
+	opts.supervisor = s.cfg.NodeSupervisor
+	if opts.resourceLimits == nil && len(opts.nodeResourceLimits) == 0 {
+		opts.resourceLimits = s.cfg.DefaultResourceLimits
+	}
+	liveness, err := parseLivenessMonitor(req.GetLivenessMonitor())
+	if err != nil {
+		return networkOptions{}, err
+	}
+	opts.liveness = liveness
+	artifactsDir := s.cfg.ArtifactsDir
+	if t := rootDataDirTenant(rootDataDir); t != "" {
+		artifactsDir = filepath.Join(artifactsDir, t)
+	}
+	if p := req.GetArtifactsPath(); p != "" {
+		if opts.artifactsPath, err = artifactsPath(artifactsDir, p); err != nil {
+			return networkOptions{}, err
+		}
+	}
+	if liveness != nil && liveness.artifactsDir != "" {
+		if liveness.artifactsDir, err = artifactsPath(artifactsDir, liveness.artifactsDir); err != nil {
+			return networkOptions{}, err
+		}
+	}
+	opts.fixtures = s.cfg.RecordFixtures
+	if opts.healthPoll, err = parseHealthPolling(req.GetHealthPolling()); err != nil {
+		return networkOptions{}, err
+	}
+	if opts.maxUnhealthyDuration, err = parseMaxUnhealthyDuration(req.MaxUnhealthyDuration, s.cfg.MaxUnhealthyDuration); err != nil {
+		return networkOptions{}, err
+	}
+	if opts.launchInterval, err = parseLaunchInterval(req.LaunchInterval); err != nil {
+		return networkOptions{}, err
+	}
+	if opts.checkpoints, err = parseCheckpoints(req.CheckpointInterval, req.CheckpointRetention); err != nil {
+		return networkOptions{}, err
+	}
+	opts.resumeCheckpoint = req.GetResumeCheckpoint()
+	if err := checkSeedActions(req.GetSeedActions()); err != nil {
+		return networkOptions{}, err
+	}
+	opts.seedActions = req.GetSeedActions()
+	if err := checkSidecars(req.GetNodeSidecars()); err != nil {
+		return networkOptions{}, err
+	}
+	for _, ns := range req.GetNodeSidecars() {
+		for _, sc := range ns.GetSidecars() {
+			if err := s.checkExecPath(sc.ExecPath); err != nil {
+				return networkOptions{}, err
+			}
+		}
+	}
+	opts.nodeSidecars = req.GetNodeSidecars()
+	opts.nodeArchival = req.GetNodeArchival()
+	if len(req.GetStateSyncNodes()) > 0 {
+		opts.stateSyncNodes = make(map[string]bool)
+		for _, name := range req.GetStateSyncNodes() {
+			opts.stateSyncNodes[name] = true
+		}
+	}
+
+	var p Preset
+	if req.Preset != nil {
+		var ok bool
+		p, ok = s.presets[req.GetPreset()]
+		if !ok {
+			return networkOptions{}, fmt.Errorf("%w: %q", ErrPresetNotFound, req.GetPreset())
+		}
+		zap.L().Info("using preset", zap.String("name", p.Name))
+	}
+
+	opts.numNodes = p.NumNodes
+	if req.NumNodes != nil {
+		opts.numNodes = req.GetNumNodes()
+	}
+	opts.logLevel = p.LogLevel
+	if req.LogLevel != nil {
+		opts.logLevel = req.GetLogLevel()
+	}
+
+	globalNodeConfig, err := parseConfig("global node config", req.GetGlobalNodeConfig())
+	if err != nil {
+		return networkOptions{}, err
+	}
+	if err := s.checkNodeConfigDirs("global node config", globalNodeConfig); err != nil {
+		return networkOptions{}, err
+	}
+	stakingConfig, err := stakingConfig(req.GetStakingParams())
+	if err != nil {
+		return networkOptions{}, err
+	}
+	var peersConfig map[string]interface{}
+	if req.StaticPeers {
+		peersConfig = staticPeersConfig
+	}
+	binaryDirs, err := s.binaryDirsConfig(req.GetPluginDir(), req.GetBuildDir())
+	if err != nil {
+		return networkOptions{}, err
+	}
+	opts.globalNodeConfig = mergeConfigs(p.NodeConfig, globalNodeConfig, stakingConfig, peersConfig, binaryDirs)
+
+	cChainConfig, err := parseConfig("C-chain config", req.GetCChainConfig())
+	if err != nil {
+		return networkOptions{}, err
+	}
+	opts.cChainConfig = mergeConfigs(p.CChainConfig, cChainConfig)
+	return opts, nil
+}
@@ -0,0 +1,180 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// ReadinessService is the gRPC health service of the server readiness,
+// not serving while a network operation is in flight (as "/readyz"). The
+// overall service ("") reports the server liveness (as "/livez").
+const ReadinessService = "network-runner.readiness"
+
+const (
+	livezPath  = "/livez"
+	readyzPath = "/readyz"
+)
+
+// operationMethods are the control methods that change the network, in
+// flight until they return.
+var operationMethods = map[string]bool{
+	"Start":                true,
+	"Stop":                 true,
+	"AbortStart":           true,
+	"AddNode":              true,
+	"RemoveNode":           true,
+	"RemoveNodes":          true,
+	"RestartNode":          true,
+	"RestartNodes":         true,
+	"RotateNodeKey":        true,
+	"StopValidatingSubnet": true,
+	"CreateBlockchain":     true,
+	"InjectFault":          true,
+	"SetDiskFault":         true,
+	"SetTime":              true,
+	"AdvanceTime":          true,
+}
+
+// readiness tracks the network operations in flight, without the server
+// lock, which the operations hold.
+type readiness struct {
+	health *health.Server
+
+	mu sync.Mutex
+	// in-flight requests by method name
+	ops map[string]int
+	// of the current network, nil if none
+	lifecycle *lifecycle
+	// of the start request waiting for the host resources, nil if none
+	queued *startQueuePosition
+}
+
+type readinessReport struct {
+	Ready bool `json:"ready"`
+	// in-flight operations (e.g., "RestartNode"), sorted
+	Operations []string `json:"operations,omitempty"`
+	// state of the current network, if any (e.g., "bootstrapping" while
+	// the nodes start, after the start request returned)
+	NetworkState string `json:"networkState,omitempty"`
+	// position of the start request in the host queue, while it waits for
+	// the other networks of the host to stop
+	StartQueue *startQueuePosition `json:"startQueue,omitempty"`
+}
+
+func newReadiness(h *health.Server) *readiness {
+	return &readiness{health: h, ops: make(map[string]int)}
+}
+
+// setNetwork sets the network whose state is reported, nil once stopped.
+func (r *readiness) setNetwork(l *lifecycle) {
+	r.mu.Lock()
+	r.lifecycle = l
+	r.mu.Unlock()
+	r.update()
+}
+
+// update reports the readiness to the gRPC health service, e.g., on the
+// network state transitions.
+func (r *readiness) update() {
+	if r == nil {
+		return
+	}
+	st := healthpb.HealthCheckResponse_NOT_SERVING
+	if r.report().Ready {
+		st = healthpb.HealthCheckResponse_SERVING
+	}
+	r.health.SetServingStatus(ReadinessService, st)
+}
+
+func (r *readiness) report() readinessReport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rep := readinessReport{Operations: make([]string, 0, len(r.ops))}
+	for method := range r.ops {
+		rep.Operations = append(rep.Operations, method)
+	}
+	sort.Strings(rep.Operations)
+	rep.StartQueue = r.queued
+	busy := len(rep.Operations) > 0
+	if r.lifecycle != nil {
+		state, _ := r.lifecycle.get()
+		rep.NetworkState = stateName(state)
+		switch state {
+		case stateCreating, stateBootstrapping, stateStopping:
+			busy = true
+		}
+	}
+	rep.Ready = !busy
+	return rep
+}
+
+// setQueued sets the queue position of the start request, nil once it
+// leaves the queue.
+func (r *readiness) setQueued(pos *startQueuePosition) {
+	r.mu.Lock()
+	r.queued = pos
+	r.mu.Unlock()
+}
+
+func (r *readiness) begin(method string) {
+	r.mu.Lock()
+	r.ops[method]++
+	r.mu.Unlock()
+	r.update()
+}
+
+func (r *readiness) end(method string) {
+	r.mu.Lock()
+	if r.ops[method]--; r.ops[method] <= 0 {
+		delete(r.ops, method)
+	}
+	r.mu.Unlock()
+	r.update()
+}
+
+// unaryOperationInterceptor tracks the network operations in flight. It
+// runs after the authentication, so the rejected requests do not count.
+func (s *server) unaryOperationInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	prefix := "/" + rpcpb.ControlService_ServiceDesc.ServiceName + "/"
+	method := strings.TrimPrefix(info.FullMethod, prefix)
+	if !strings.HasPrefix(info.FullMethod, prefix) || !operationMethods[method] {
+		return handler(ctx, req)
+	}
+	s.readiness.begin(method)
+	defer s.readiness.end(method)
+	return handler(ctx, req)
+}
+
+// registerProbes serves "/livez" (the server is up) and "/readyz" (no
+// network operation is in flight, 503 otherwise) on the gRPC gateway port,
+// for the orchestrators to sequence their startup. Both stay open with
+// tenancy, as the gRPC health service.
+func (s *server) registerProbes() error {
+	if err := s.gwMux.HandlePath(http.MethodGet, livezPath, func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte("ok\n"))
+	}); err != nil {
+		return err
+	}
+	return s.gwMux.HandlePath(http.MethodGet, readyzPath, func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		rep := s.readiness.report()
+		w.Header().Set("Content-Type", "application/json")
+		if !rep.Ready {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_ = json.NewEncoder(w).Encode(rep)
+	})
+}
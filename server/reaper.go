@@ -0,0 +1,112 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// prefix of the network root data directories, in the temporary directory
+// (or the tenant directory in it)
+const rootDataDirPrefix = "network-runner-root-data"
+
+// policies for the node processes left by a previous server (e.g., crashed)
+const (
+	// logs the orphaned processes, and leaves them running
+	OrphanPolicyIgnore = "ignore"
+	// stops the orphaned processes, within the shutdown grace period
+	OrphanPolicyKill = "kill"
+	// adopts the supervised processes of a network into the network of
+	// the server (ref. "adoptOrphans"), and logs the others
+	OrphanPolicyAdopt = "adopt"
+)
+
+var (
+	ErrInvalidOrphanPolicy = errors.New("invalid orphan policy")
+	ErrOrphansUnsupported  = errors.New("finding orphaned node processes is only supported on Linux")
+)
+
+// orphan is a node process of a previous server.
+type orphan struct {
+	pid         int
+	rootDataDir string
+	// database directory flag of the process
+	dbDir string
+	// systemd unit of the process, if supervised
+	unit string
+}
+
+func checkOrphanPolicy(policy string) error {
+	switch policy {
+	case "", OrphanPolicyIgnore, OrphanPolicyKill, OrphanPolicyAdopt:
+		return nil
+	}
+	return fmt.Errorf("%w: %q (expected %q, %q, or %q)", ErrInvalidOrphanPolicy, policy, OrphanPolicyIgnore, OrphanPolicyKill, OrphanPolicyAdopt)
+}
+
+// reapOrphans finds the node processes running in a network root data
+// directory, which no network of this (not yet started) server owns,
+// and handles them by the policy. Returns the supervised processes to
+// adopt, if the policy adopts them. Failures are logged, not returned.
+func reapOrphans(policy string, gracePeriod time.Duration) []orphan {
+	orphans, err := findOrphans()
+	if errors.Is(err, ErrOrphansUnsupported) {
+		zap.L().Debug("skipping orphaned node processes", zap.Error(err))
+		return nil
+	}
+	if err != nil {
+		zap.L().Warn("failed to find orphaned node processes", zap.Error(err))
+		return nil
+	}
+	var adoptable []orphan
+	for _, o := range orphans {
+		if policy == OrphanPolicyAdopt && o.unit != "" {
+			adoptable = append(adoptable, o)
+			continue
+		}
+		if policy != OrphanPolicyKill {
+			zap.L().Warn("found orphaned node process (may conflict with the network ports)",
+				zap.Int("pid", o.pid),
+				zap.String("rootDataDir", o.rootDataDir),
+				zap.String("unit", o.unit),
+			)
+			continue
+		}
+		zap.L().Info("stopping orphaned node process",
+			zap.Int("pid", o.pid),
+			zap.String("rootDataDir", o.rootDataDir),
+			zap.String("unit", o.unit),
+		)
+		if err := stopProcess(o.pid, gracePeriod); err != nil {
+			zap.L().Warn("failed to stop orphaned node process", zap.Int("pid", o.pid), zap.Error(err))
+		}
+	}
+	return adoptable
+}
+
+// runnerRootDataDir returns the network root data directory of the path,
+// or "" if the path is not in one.
+func runnerRootDataDir(path string) string {
+	tmp := filepath.Clean(os.TempDir())
+	rel, err := filepath.Rel(tmp, filepath.Clean(path))
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return ""
+	}
+	parts := strings.Split(rel, string(filepath.Separator))
+	switch {
+	case len(parts) > 1 && strings.HasPrefix(parts[0], rootDataDirPrefix):
+		return filepath.Join(tmp, parts[0])
+	// of a tenant
+	case len(parts) > 2 && strings.HasPrefix(parts[0], "network-runner-") && strings.HasPrefix(parts[1], rootDataDirPrefix):
+		return filepath.Join(tmp, parts[0], parts[1])
+	}
+	return ""
+}
@@ -0,0 +1,93 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+//go:build linux
+// +build linux
+
+package server
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// findOrphans returns the processes whose database directory flag
+// (as the runner launches the nodes) is in a network root data directory.
+func findOrphans() ([]orphan, error) {
+	entries, err := ioutil.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+	self := os.Getpid()
+	orphans := make([]orphan, 0)
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil || pid == self {
+			continue
+		}
+		// the process may exit at any time, so skip any read errors
+		b, err := ioutil.ReadFile(filepath.Join("/proc", e.Name(), "cmdline"))
+		if err != nil {
+			continue
+		}
+		args := strings.Split(string(bytes.TrimRight(b, "\x00")), "\x00")
+		for i, arg := range args {
+			dbDir := ""
+			switch {
+			case strings.HasPrefix(arg, "--db-dir="):
+				dbDir = strings.TrimPrefix(arg, "--db-dir=")
+			case arg == "--db-dir" && i+1 < len(args):
+				dbDir = args[i+1]
+			}
+			if dir := runnerRootDataDir(dbDir); dir != "" {
+				orphans = append(orphans, orphan{pid: pid, rootDataDir: dir, dbDir: dbDir, unit: processUnit(e.Name())})
+				break
+			}
+		}
+	}
+	return orphans, nil
+}
+
+// processUnit returns the supervisor unit of the process, if any,
+// from its cgroup (v2) path.
+func processUnit(pid string) string {
+	b, err := ioutil.ReadFile(filepath.Join("/proc", pid, "cgroup"))
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		unit := filepath.Base(strings.TrimPrefix(line, "0::"))
+		if strings.HasPrefix(line, "0::") && strings.HasPrefix(unit, "network-runner-") && strings.HasSuffix(unit, ".scope") {
+			return unit
+		}
+	}
+	return ""
+}
+
+// stopProcess interrupts the process, and kills it if it does not exit
+// within the grace period.
+func stopProcess(pid int, gracePeriod time.Duration) error {
+	if err := syscall.Kill(pid, syscall.SIGINT); err != nil {
+		if err == syscall.ESRCH {
+			return nil
+		}
+		return err
+	}
+	deadline := time.Now().Add(gracePeriod)
+	for time.Now().Before(deadline) {
+		if err := syscall.Kill(pid, 0); err == syscall.ESRCH {
+			return nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if err := syscall.Kill(pid, syscall.SIGKILL); err != nil && err != syscall.ESRCH {
+		return err
+	}
+	return nil
+}
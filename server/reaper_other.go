@@ -0,0 +1,17 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+//go:build !linux
+// +build !linux
+
+package server
+
+import "time"
+
+func findOrphans() ([]orphan, error) {
+	return nil, ErrOrphansUnsupported
+}
+
+func stopProcess(pid int, gracePeriod time.Duration) error {
+	return nil
+}
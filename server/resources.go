@@ -0,0 +1,138 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+
+	"github.com/lasthyphen/dijetsnode-go-runner/local"
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+	"go.uber.org/zap"
+)
+
+// footprint of a bootstrapped node of an idle local network, measured with
+// avalanchego v1.7 (RSS, database and logs, and CPU time)
+const (
+	baselineNodeMemory        = 512 * 1024 * 1024
+	baselineNodeDisk          = 256 * 1024 * 1024
+	baselineNodeCPUMillicores = 250
+)
+
+var (
+	ErrInsufficientResources    = errors.New("insufficient host resources")
+	ErrHostResourcesUnsupported = errors.New("host resources are not supported on this platform")
+)
+
+// EstimateResources returns the expected footprint of the network spec,
+// and whether the host can run it.
+func (s *server) EstimateResources(ctx context.Context, req *rpcpb.EstimateResourcesRequest) (*rpcpb.EstimateResourcesResponse, error) {
+	zap.L().Info("received estimate resources request")
+	spec := req.GetSpec()
+	if spec == nil {
+		spec = &rpcpb.StartRequest{}
+	}
+	spec, err := s.resolveTemplate(spec, false)
+	if err != nil {
+		return nil, err
+	}
+	opts, err := s.resolveNetworkOptions(spec, os.TempDir())
+	if err != nil {
+		return nil, err
+	}
+	return estimateResources(opts)
+}
+
+// estimateResources estimates the footprint of the network options on the
+// host of their data directory. The network does not fit if it needs more
+// than all of the host memory, or of the free disk space.
+func estimateResources(opts networkOptions) (*rpcpb.EstimateResourcesResponse, error) {
+	// as created by "newNetwork"
+	numNodes := len(local.NewDefaultConfig(opts.execPath).NodeConfigs)
+	if int(opts.numNodes) > numNodes {
+		return nil, fmt.Errorf("%w: %d (default config has %d nodes)", ErrInvalidNumNodes, opts.numNodes, numNodes)
+	}
+	if opts.numNodes > 0 {
+		numNodes = int(opts.numNodes)
+	}
+	resp := &rpcpb.EstimateResourcesResponse{
+		NumNodes:      uint32(numNodes),
+		NodeEstimates: make(map[string]*rpcpb.ResourceEstimate, numNodes),
+		Total:         &rpcpb.ResourceEstimate{},
+		Fits:          true,
+	}
+	// node of each pinned CPU
+	pinned := make(map[int]string)
+	for i := 0; i < numNodes; i++ {
+		name := fmt.Sprintf("node%d", i+1)
+		l, err := nodeResourceLimits(opts.resourceLimits, opts.nodeResourceLimits[name])
+		if err != nil {
+			return nil, err
+		}
+		est := &rpcpb.ResourceEstimate{
+			MemoryBytes:   baselineNodeMemory,
+			DiskBytes:     baselineNodeDisk,
+			CpuMillicores: baselineNodeCPUMillicores,
+		}
+		if l.GetMemoryMaxBytes() > 0 && l.GetMemoryMaxBytes() < est.MemoryBytes {
+			est.MemoryBytes = l.GetMemoryMaxBytes()
+			resp.Warnings = append(resp.Warnings, fmt.Sprintf("%s memory cap %s is below the %s baseline, it might be OOM-killed", name, formatBytes(l.GetMemoryMaxBytes()), formatBytes(baselineNodeMemory)))
+		}
+		if l.GetCpuMillicores() > 0 && l.GetCpuMillicores() < est.CpuMillicores {
+			est.CpuMillicores = l.GetCpuMillicores()
+		}
+		if l.Cpuset != nil {
+			// validated by the limits
+			cpus, _ := parseCPUList(l.GetCpuset())
+			if pinnedMillicores := uint32(len(cpus)) * 1000; pinnedMillicores < est.CpuMillicores {
+				est.CpuMillicores = pinnedMillicores
+			}
+			for _, c := range cpus {
+				if other, ok := pinned[c]; ok {
+					resp.Warnings = append(resp.Warnings, fmt.Sprintf("%s and %s are both pinned to CPU %d, their benchmark numbers might interfere", other, name, c))
+					continue
+				}
+				pinned[c] = name
+			}
+		}
+		resp.NodeEstimates[name] = est
+		resp.Total.MemoryBytes += est.MemoryBytes
+		resp.Total.DiskBytes += est.DiskBytes
+		resp.Total.CpuMillicores += est.CpuMillicores
+	}
+
+	host, err := readHostResources(opts.rootDataDir)
+	if err != nil {
+		zap.L().Debug("failed to read the host resources", zap.Error(err))
+		return resp, nil
+	}
+	host.NumCpus = uint32(runtime.NumCPU())
+	resp.Host = host
+
+	switch {
+	case resp.Total.MemoryBytes > host.MemoryTotalBytes:
+		resp.Fits = false
+		resp.Warnings = append(resp.Warnings, fmt.Sprintf("estimated memory %s exceeds the host memory %s", formatBytes(resp.Total.MemoryBytes), formatBytes(host.MemoryTotalBytes)))
+	case resp.Total.MemoryBytes > host.MemoryAvailableBytes:
+		resp.Warnings = append(resp.Warnings, fmt.Sprintf("estimated memory %s exceeds the available memory %s, the nodes might be OOM-killed", formatBytes(resp.Total.MemoryBytes), formatBytes(host.MemoryAvailableBytes)))
+	}
+	if resp.Total.DiskBytes > host.DiskFreeBytes {
+		resp.Fits = false
+		resp.Warnings = append(resp.Warnings, fmt.Sprintf("estimated disk %s exceeds the free disk %s", formatBytes(resp.Total.DiskBytes), formatBytes(host.DiskFreeBytes)))
+	}
+	if resp.Total.CpuMillicores > host.NumCpus*1000 {
+		resp.Warnings = append(resp.Warnings, fmt.Sprintf("estimated CPU %dm exceeds the %d host CPUs, the nodes might fail the health checks", resp.Total.CpuMillicores, host.NumCpus))
+	}
+	return resp, nil
+}
+
+func formatBytes(b uint64) string {
+	if b >= 1024*1024*1024 {
+		return fmt.Sprintf("%.1f GiB", float64(b)/(1024*1024*1024))
+	}
+	return fmt.Sprintf("%d MiB", b/(1024*1024))
+}
@@ -0,0 +1,61 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+//go:build linux
+// +build linux
+
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+)
+
+// readHostResources reads the host memory from "/proc/meminfo",
+// and the free disk space of the file system of the directory.
+func readHostResources(dir string) (*rpcpb.HostResources, error) {
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	host := &rpcpb.HostResources{}
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		// e.g., "MemAvailable:    8012345 kB"
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		kb, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		switch fields[0] {
+		case "MemTotal:":
+			host.MemoryTotalBytes = kb * 1024
+		case "MemAvailable:":
+			host.MemoryAvailableBytes = kb * 1024
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if host.MemoryTotalBytes == 0 {
+		return nil, fmt.Errorf("no MemTotal in /proc/meminfo")
+	}
+
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return nil, err
+	}
+	host.DiskFreeBytes = st.Bavail * uint64(st.Bsize)
+	return host, nil
+}
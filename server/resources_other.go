@@ -0,0 +1,13 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+//go:build !linux
+// +build !linux
+
+package server
+
+import "github.com/lasthyphen/djtx-tester/rpcpb"
+
+func readHostResources(dir string) (*rpcpb.HostResources, error) {
+	return nil, ErrHostResourcesUnsupported
+}
@@ -0,0 +1,103 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/lasthyphen/dijetsnodego/staking"
+	"github.com/lasthyphen/djtx-tester/pkg/jsonrpc"
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+	"go.uber.org/zap"
+)
+
+// RotateNodeKey restarts the node with a new staking key and certificate,
+// thus a new node ID, keeping its database and config. The new node ID does
+// not inherit the validator status of the old one.
+func (s *server) RotateNodeKey(ctx context.Context, req *rpcpb.RotateNodeKeyRequest) (*rpcpb.RotateNodeKeyResponse, error) {
+	zap.L().Info("received rotate node key request", zap.String("name", req.Name))
+	if info := s.getClusterInfo(); info == nil {
+		return nil, ErrNotBootstrapped
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkStateLocked(stateHealthy, stateDegraded); err != nil {
+		return nil, err
+	}
+	info, ok := s.network.nodeInfos[req.Name]
+	if !ok {
+		return nil, ErrNodeNotFound
+	}
+	oldNodeID := info.Id
+	subnets := info.WhitelistedSubnets
+	plan, err := s.planRestartLocked(req.Name, &rpcpb.StartRequest{
+		ExecPath:           info.ExecPath,
+		WhitelistedSubnets: &subnets,
+	})
+	if err != nil {
+		return nil, err
+	}
+	plan.nodeConfig.StakingCert, plan.nodeConfig.StakingKey, err = staking.NewCertAndKeyBytes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate the staking key: %w", err)
+	}
+
+	// do not remove the node if the client already gave up
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	zap.L().Info("restarting the node with a new staking key", zap.String("name", req.Name))
+	if err := s.network.removeNode(req.Name); err != nil {
+		return nil, err
+	}
+	s.network.transition(stateBootstrapping, nil)
+	if err := s.network.addNode(plan.nodeConfig); err != nil {
+		s.network.transition(stateDegraded, err)
+		return nil, err
+	}
+	if err := s.waitForHealthyLocked(ctx); err != nil {
+		if !errors.Is(err, errAborted) {
+			s.network.transition(stateDegraded, err)
+		}
+		return nil, err
+	}
+	s.applyRestartLocked(plan)
+	if err := s.network.limitNodes(ctx, []string{req.Name}); err != nil {
+		s.network.transition(stateDegraded, err)
+		return nil, err
+	}
+
+	resp := &rpcpb.RotateNodeKeyResponse{
+		OldNodeId: oldNodeID,
+		NewNodeId: info.Id,
+	}
+	zap.L().Info("rotated node key",
+		zap.String("name", req.Name),
+		zap.String("oldNodeID", resp.OldNodeId),
+		zap.String("newNodeID", resp.NewNodeId),
+	)
+
+	// ref. platformvm "GetCurrentValidators"
+	var reply struct {
+		Validators []apiValidator `json:"validators"`
+	}
+	if err := jsonrpc.Call(ctx, info.Uri, "/ext/bc/P", "platform.getCurrentValidators", map[string]interface{}{}, &reply); err != nil {
+		return nil, fmt.Errorf("node key rotated, failed to get the validators: %w", err)
+	}
+	for _, v := range reply.Validators {
+		switch v.NodeID {
+		case resp.OldNodeId:
+			resp.OldNodeIdValidator = true
+		case resp.NewNodeId:
+			resp.NewNodeIdValidator = true
+		}
+	}
+	resp.ClusterInfo = s.copyClusterInfo()
+	return resp, nil
+}
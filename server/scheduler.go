@@ -0,0 +1,243 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	reservationSuffix   = ".json"
+	schedulerPollPeriod = time.Second
+)
+
+// reservation is the estimated footprint of a network on the host, queued
+// until admitted, then held until the network stops. The reservations are
+// files of the scheduler directory, shared by the servers of the host.
+type reservation struct {
+	Pid           int       `json:"pid"`
+	RootDataDir   string    `json:"rootDataDir"`
+	MemoryBytes   uint64    `json:"memoryBytes"`
+	CPUMillicores uint32    `json:"cpuMillicores"`
+	QueuedAt      time.Time `json:"queuedAt"`
+	Admitted      bool      `json:"admitted"`
+
+	// file name, ordered by the queue time
+	name string
+}
+
+// startQueuePosition is the position of a queued start request, behind the
+// requests queued before it (of any server of the host).
+type startQueuePosition struct {
+	// 1 for the next request to be admitted
+	Position int    `json:"position"`
+	Length   int    `json:"length"`
+	QueuedAt string `json:"queuedAt"`
+	// headroom of the host once the admitted networks are reserved
+	MemoryHeadroomBytes uint64 `json:"memoryHeadroomBytes"`
+	CPUHeadroom         int64  `json:"cpuHeadroomMillicores"`
+}
+
+// scheduler admits the networks of the host in order, so the networks of
+// the servers sharing the host (e.g., one per team) do not overcommit its
+// memory, and its CPUs beyond the overcommit ratio. The estimates are those
+// of "EstimateResources", rather than the current usage, as the nodes only
+// grow into their footprint once bootstrapped.
+type scheduler struct {
+	dir           string
+	timeout       time.Duration
+	cpuOvercommit float64
+}
+
+// newScheduler returns nil if the directory is empty (disabled).
+func newScheduler(dir string, timeout time.Duration, cpuOvercommit float64) (*scheduler, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	if cpuOvercommit <= 0 {
+		cpuOvercommit = 1
+	}
+	return &scheduler{dir: dir, timeout: timeout, cpuOvercommit: cpuOvercommit}, nil
+}
+
+// reserve queues the footprint of the network, and waits for its turn and
+// for the headroom of the host, until the queue timeout or the request is
+// canceled. Returns the reservation to release once the network stops.
+// Without a queue timeout, the requests that do not fit are rejected
+// rather than queued. The networks that skip the resource check are
+// admitted at once, but reserved for the others.
+func (sc *scheduler) reserve(ctx context.Context, r *readiness, res *reservation, skipCheck bool) (string, error) {
+	host, err := readHostResources(sc.dir)
+	if err != nil {
+		zap.L().Warn("failed to read the host resources, not scheduling", zap.Error(err))
+		return "", nil
+	}
+	cpus := int64(float64(runtime.NumCPU()*1000) * sc.cpuOvercommit)
+	if !skipCheck && (res.MemoryBytes > host.MemoryTotalBytes || int64(res.CPUMillicores) > cpus) {
+		return "", fmt.Errorf("%w: network of %s and %dm never fits the host %s and %dm", ErrInsufficientResources, formatBytes(res.MemoryBytes), res.CPUMillicores, formatBytes(host.MemoryTotalBytes), cpus)
+	}
+
+	res.Pid = os.Getpid()
+	res.QueuedAt = time.Now().UTC()
+	res.name = fmt.Sprintf("%020d-%d%s", res.QueuedAt.UnixNano(), res.Pid, reservationSuffix)
+	if err := sc.write(res); err != nil {
+		return "", err
+	}
+	admitted := false
+	defer func() {
+		r.setQueued(nil)
+		if !admitted {
+			sc.release(res.name)
+		}
+	}()
+
+	var timeout <-chan time.Time
+	if sc.timeout > 0 {
+		tm := time.NewTimer(sc.timeout)
+		defer tm.Stop()
+		timeout = tm.C
+	}
+	tc := time.NewTicker(schedulerPollPeriod)
+	defer tc.Stop()
+	for {
+		others, err := sc.load()
+		if err != nil {
+			return "", err
+		}
+		var memory uint64
+		var cpu int64
+		ahead := 0
+		queued := 0
+		for _, o := range others {
+			switch {
+			case o.name == res.name:
+			case o.Admitted:
+				memory += o.MemoryBytes
+				cpu += int64(o.CPUMillicores)
+			default:
+				queued++
+				if o.name < res.name {
+					ahead++
+				}
+			}
+		}
+		fits := memory+res.MemoryBytes <= host.MemoryTotalBytes && cpu+int64(res.CPUMillicores) <= cpus
+		if skipCheck || (ahead == 0 && fits) {
+			res.Admitted = true
+			if err := sc.write(res); err != nil {
+				return "", err
+			}
+			admitted = true
+			zap.L().Info("admitted the network",
+				zap.String("reservation", res.name),
+				zap.Duration("queued", time.Since(res.QueuedAt)),
+			)
+			return res.name, nil
+		}
+		reason := fmt.Sprintf("%d requests queued ahead", ahead)
+		if ahead == 0 {
+			reason = fmt.Sprintf("%s and %dm reserved of the host %s and %dm", formatBytes(memory), cpu, formatBytes(host.MemoryTotalBytes), cpus)
+		}
+		if sc.timeout == 0 {
+			return "", fmt.Errorf("%w: network of %s and %dm would overcommit the host, %s", ErrInsufficientResources, formatBytes(res.MemoryBytes), res.CPUMillicores, reason)
+		}
+		pos := &startQueuePosition{
+			Position:    ahead + 1,
+			Length:      queued + 1,
+			QueuedAt:    res.QueuedAt.Format(time.RFC3339),
+			CPUHeadroom: cpus - cpu,
+		}
+		if memory < host.MemoryTotalBytes {
+			pos.MemoryHeadroomBytes = host.MemoryTotalBytes - memory
+		}
+		r.setQueued(pos)
+		zap.L().Debug("start request queued", zap.Int("position", pos.Position), zap.String("reason", reason))
+
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-timeout:
+			return "", fmt.Errorf("%w: still queued after %s, %s", ErrInsufficientResources, sc.timeout, reason)
+		case <-tc.C:
+		}
+	}
+}
+
+// release removes the reservation, if any.
+func (sc *scheduler) release(name string) {
+	if sc == nil || name == "" {
+		return
+	}
+	if err := os.Remove(filepath.Join(sc.dir, name)); err != nil && !os.IsNotExist(err) {
+		zap.L().Warn("failed to release the reservation", zap.String("reservation", name), zap.Error(err))
+		return
+	}
+	zap.L().Info("released the reservation", zap.String("reservation", name))
+}
+
+// write replaces the reservation file, so the other servers never read a
+// partial file.
+func (sc *scheduler) write(res *reservation) error {
+	b, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(sc.dir, "."+res.name)
+	if err := ioutil.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(sc.dir, res.name))
+}
+
+// load returns the reservations of the host, ordered by their queue time.
+// The reservations of the exited servers (e.g., crashed) are removed.
+func (sc *scheduler) load() ([]*reservation, error) {
+	entries, err := ioutil.ReadDir(sc.dir)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*reservation, 0, len(entries))
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || strings.HasPrefix(name, ".") || !strings.HasSuffix(name, reservationSuffix) {
+			continue
+		}
+		b, err := ioutil.ReadFile(filepath.Join(sc.dir, name))
+		if err != nil {
+			// released in the meantime
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		res := &reservation{name: name}
+		if err := json.Unmarshal(b, res); err != nil {
+			zap.L().Warn("invalid reservation", zap.String("reservation", name), zap.Error(err))
+			continue
+		}
+		if !processRunning(res.Pid) {
+			zap.L().Info("removing the reservation of an exited server", zap.String("reservation", name), zap.Int("pid", res.Pid))
+			sc.release(name)
+			continue
+		}
+		list = append(list, res)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].name < list[j].name })
+	return list, nil
+}
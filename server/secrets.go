@@ -0,0 +1,41 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/lasthyphen/djtx-tester/pkg/secrets"
+	"github.com/lasthyphen/djtx-tester/pkg/testkeys"
+	"go.uber.org/zap"
+)
+
+// bounds the secret lookups on startup (e.g., of an unreachable vault)
+const secretTimeout = 30 * time.Second
+
+// resolveSecret returns the secret of the reference (ref. pkg/secrets),
+// or the value as is.
+func resolveSecret(v string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), secretTimeout)
+	defer cancel()
+	return secrets.Resolve(ctx, v)
+}
+
+// loadFundedKey returns the key of the funded key config, or the ewoq key.
+func loadFundedKey(v string) (*testkeys.Key, error) {
+	if v == "" {
+		return testkeys.Ewoq(), nil
+	}
+	s, err := resolveSecret(v)
+	if err != nil {
+		return nil, err
+	}
+	k, err := testkeys.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	zap.L().Info("loaded funded key", zap.String("xAddress", k.XAddress()), zap.String("cAddress", k.EthAddress()))
+	return k, nil
+}
@@ -0,0 +1,134 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lasthyphen/djtx-tester/client/wallet"
+	"github.com/lasthyphen/djtx-tester/pkg/color"
+	"github.com/lasthyphen/djtx-tester/pkg/tracing"
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	seedDeployContract = "deploy_contract"
+	seedCreateAsset    = "create_asset"
+	seedIssueTx        = "issue_tx"
+)
+
+var ErrInvalidSeedAction = errors.New("invalid seed action")
+
+// checkSeedActions validates the seed actions of the start request,
+// so an invalid action fails the request instead of the network.
+func checkSeedActions(actions []*rpcpb.SeedAction) error {
+	for i, a := range actions {
+		switch a.Type {
+		case seedDeployContract:
+			if _, err := decodeBytecode(a.Bytecode); err != nil {
+				return fmt.Errorf("%w: action %d: invalid bytecode: %v", ErrInvalidSeedAction, i, err)
+			}
+		case seedCreateAsset:
+			if a.Name == "" || a.Symbol == "" {
+				return fmt.Errorf("%w: action %d: empty asset name or symbol", ErrInvalidSeedAction, i)
+			}
+		case seedIssueTx:
+			if a.Chain != wallet.ChainX && a.Chain != wallet.ChainP && a.Chain != wallet.ChainC {
+				return fmt.Errorf("%w: action %d: invalid chain %q (expected X, P, or C)", ErrInvalidSeedAction, i, a.Chain)
+			}
+			if a.Tx == "" {
+				return fmt.Errorf("%w: action %d: empty tx", ErrInvalidSeedAction, i)
+			}
+		default:
+			return fmt.Errorf("%w: action %d: unknown type %q", ErrInvalidSeedAction, i, a.Type)
+		}
+	}
+	return nil
+}
+
+func decodeBytecode(s string) ([]byte, error) {
+	b, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, errors.New("empty")
+	}
+	return b, nil
+}
+
+// seed runs the seed actions of the start request in order, once the nodes
+// are healthy, and then moves the network to the healthy state.
+// The results are kept for the cluster info.
+func (lc *localNetwork) seed(traceCtx context.Context) (err error) {
+	if !lc.seedPending {
+		return nil
+	}
+	_, span := tracing.Start(traceCtx, "seed network")
+	defer func() { tracing.End(span, err) }()
+
+	color.Outf("{{blue}}{{bold}}seeding the network with %d actions...{{/}}\n", len(lc.opts.seedActions))
+	ctx := lc.stopCtx
+	// the health requests may update the node infos meanwhile
+	lc.opts.mu.RLock()
+	nodeInfos := make(map[string]*rpcpb.NodeInfo, len(lc.nodeInfos))
+	for name, info := range lc.nodeInfos {
+		nodeInfos[name] = proto.Clone(info).(*rpcpb.NodeInfo)
+	}
+	lc.opts.mu.RUnlock()
+	w, err := wallet.FromClusterInfo(ctx, &rpcpb.ClusterInfo{NodeInfos: nodeInfos}, lc.opts.fundedKey)
+	if err != nil {
+		return lc.seedError(fmt.Errorf("failed to create the seed wallet: %w", err))
+	}
+	defer func() {
+		if err := w.Close(context.Background()); err != nil {
+			zap.L().Warn("failed to close the seed wallet", zap.Error(err))
+		}
+	}()
+
+	results := make([]*rpcpb.SeedResult, 0, len(lc.opts.seedActions))
+	for i, a := range lc.opts.seedActions {
+		res := &rpcpb.SeedResult{Type: a.Type, Name: a.Name}
+		switch a.Type {
+		case seedDeployContract:
+			bytecode, _ := decodeBytecode(a.Bytecode)
+			res.TxId, res.ContractAddress, err = w.DeployContract(ctx, bytecode, a.GetGasLimit())
+		case seedCreateAsset:
+			res.AssetId, err = w.CreateAsset(ctx, a.Name, a.Symbol, a.Denomination, a.InitialSupply)
+			res.TxId = res.AssetId
+		case seedIssueTx:
+			res.TxId, err = w.IssueTx(ctx, a.Chain, a.Tx)
+		}
+		if err != nil {
+			return lc.seedError(fmt.Errorf("seed action %d (%s %q) failed: %w", i, a.Type, a.Name, err))
+		}
+		zap.L().Info("seeded",
+			zap.String("type", res.Type),
+			zap.String("name", res.Name),
+			zap.String("txID", res.TxId),
+		)
+		results = append(results, res)
+	}
+	lc.opts.mu.Lock()
+	lc.seedResults = results
+	lc.seedPending = false
+	lc.opts.mu.Unlock()
+
+	lc.transition(stateHealthy, nil)
+	return nil
+}
+
+// seedError aborts the seeding if the network stopped in the meantime.
+func (lc *localNetwork) seedError(err error) error {
+	if lc.stopCtx.Err() != nil {
+		return errAborted
+	}
+	return err
+}
@@ -13,26 +13,180 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"path/filepath"
 	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"github.com/lasthyphen/dijetsnode-go-runner/local"
 	"github.com/lasthyphen/dijetsnode-go-runner/network/node"
-	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/lasthyphen/djtx-tester/pkg/conformance"
+	"github.com/lasthyphen/djtx-tester/pkg/discovery"
+	"github.com/lasthyphen/djtx-tester/pkg/testkeys"
+	"github.com/lasthyphen/djtx-tester/pkg/tracing"
 	"github.com/lasthyphen/djtx-tester/rpcpb"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 )
 
 type Config struct {
+	// comma-separated listen addresses (e.g., ":8080", "127.0.0.1:8080",
+	// "0.0.0.0:8080,[::]:8080", or "unix:///tmp/djtx.sock")
 	Port        string
 	GwPort      string
 	DialTimeout time.Duration
+
+	// EnableFaucet serves "POST /v1/faucet" on the gRPC gateway port,
+	// dispensing FaucetAmount to the requested X/P/C-chain address
+	// at most once per FaucetInterval.
+	EnableFaucet   bool
+	FaucetAmount   uint64
+	FaucetInterval time.Duration
+
+	// FundedKey is the private key ("PrivateKey-..."), or its secret
+	// reference (e.g., "env:FUNDED_KEY", ref. pkg/secrets), that the
+	// faucet, the blockchain creation, and the seed actions spend from.
+	// The ewoq key if empty, any other key must be funded in the genesis.
+	FundedKey string
+
+	// EnableNodeProxy serves the node APIs on the gRPC gateway port,
+	// "/node/<name>/ext/..." proxying to the node "/ext/...", for the
+	// clients that can only reach the server. The tenant token is required
+	// if tenancy is enabled.
+	EnableNodeProxy bool
+
+	// EnableLogStream serves the merged node output lines over a
+	// websocket, "GET /v1/logs/stream" on the gRPC gateway port, for the
+	// browser tools and "wscat". The browsers of the other origins are
+	// denied, unless in the GRPCWebAllowedOrigins.
+	EnableLogStream bool
+
+	// EnableGRPCWeb serves the gRPC-Web protocol on the gRPC gateway port,
+	// for the browser clients, from the GRPCWebAllowedOrigins ("*" for all).
+	EnableGRPCWeb         bool
+	GRPCWebAllowedOrigins []string
+
+	// PresetsDir is the directory of custom start presets ("<name>.json"),
+	// in addition to the built-in ones.
+	PresetsDir string
+	// Presets are custom start presets, overriding the built-in and
+	// the presets directory ones.
+	Presets []Preset
+	// TemplatesDir is the directory of the start templates
+	// ("<name>.json"), the start requests with parameters.
+	TemplatesDir string
+	// Templates override the templates directory ones.
+	Templates []Template
+
+	// TenantsFile is the JSON list of tenants ("name", "token", "role",
+	// "tokens", and "maxNodes"), to share the server among users. If set,
+	// the control requests must carry a tenant token, allowed by its role,
+	// and only see the tenant cluster.
+	TenantsFile string
+	// Tenants are added to the tenants of the tenants file.
+	Tenants []Tenant
+
+	// DefaultResourceLimits apply to every node of the networks
+	// started without resource limits.
+	DefaultResourceLimits *rpcpb.ResourceLimits
+
+	// ShutdownGracePeriod is the time for the nodes to exit on stop,
+	// before they are killed. Zero waits as long as the runner does.
+	ShutdownGracePeriod time.Duration
+	// MaxUnhealthyDuration fails the networks (by default, unless set by
+	// the start request) once a node stays unhealthy for longer, so the
+	// runs without a watchdog fail rather than silently degrade. Zero
+	// disables it.
+	MaxUnhealthyDuration time.Duration
+	// OrphanPolicy handles the node processes of a previous (e.g., crashed)
+	// server on startup: "ignore" (default) logs them, "kill" stops them,
+	// "adopt" adopts the supervised ones into a network.
+	OrphanPolicy string
+
+	// UploadDir is the directory of the files uploaded by "UploadFile"
+	// (in a subdirectory per tenant), "network-runner-uploads" in the
+	// temporary directory if empty.
+	UploadDir string
+	// ArtifactsDir is the directory the artifacts of the networks are
+	// archived to (in a subdirectory per tenant), as the relative
+	// "ArtifactsPath" of the start requests, "network-runner-artifacts" in
+	// the temporary directory if empty.
+	ArtifactsDir string
+	// ExecDirs restricts the exec paths of the nodes and sidecars (and of
+	// "CheckBinary"), and the plugin and build directories of the nodes, to
+	// the directories (e.g., the UploadDir), so the tenants only run the
+	// binaries they uploaded. Any path if empty.
+	ExecDirs []string
+
+	// NodeSupervisor launches the node processes under the host init, so
+	// they survive the server: "none" (default), or "systemd" to run each
+	// node in a transient scope unit (named in the node info).
+	NodeSupervisor string
+
+	// SchedulerDir is the directory of the host resource reservations,
+	// shared by the servers of the host (e.g., one per team), so their
+	// networks do not overcommit the host memory and CPUs: the start
+	// requests that would are queued, in order, until the other networks
+	// stop. Disabled if empty.
+	SchedulerDir string
+	// SchedulerQueueTimeout rejects the start requests still queued after
+	// this long. Zero rejects them rather than queue them.
+	SchedulerQueueTimeout time.Duration
+	// SchedulerCPUOvercommit is the ratio of the CPU millicores that the
+	// networks may reserve to the host CPUs, 1 if zero. The memory is not
+	// overcommitted.
+	SchedulerCPUOvercommit float64
+
+	// RecordFixtures appends every control call (its request, and its
+	// responses or status) to this file, as the conformance fixtures to
+	// replay against another server build ("verify-fixtures"). Disabled
+	// if empty.
+	RecordFixtures string
+
+	// IdempotencyWindow is the time the results of the Start, Stop, and
+	// AddNode requests with an idempotency key are kept, for their retries
+	// to get the same result. 10 minutes if zero.
+	IdempotencyWindow time.Duration
+
+	// GCInterval is the interval of the janitor removing the data
+	// directories of the stopped networks, and the rotated node logs,
+	// by the retention below. Zero disables the janitor ("GCNow" still
+	// collects on demand).
+	GCInterval time.Duration
+	// GCMaxAge removes the files not modified for longer, if not zero.
+	GCMaxAge time.Duration
+	// GCMaxBytes removes the oldest data directories of the stopped
+	// networks beyond the total size, if not zero.
+	GCMaxBytes uint64
+
+	// Webhooks receive the network lifecycle events (e.g., healthy,
+	// stopped, or a node crashed) as HTTP POST requests.
+	Webhooks []Webhook
+
+	// StateFile is written with the server endpoints on startup, for the
+	// clients to discover them (ref. "pkg/discovery"), if not empty.
+	StateFile string
+
+	// GatewayMuxOptions are applied to the gRPC gateway mux after the
+	// server ones, so they override them (e.g., the incoming header
+	// matcher), for the programs embedding the server.
+	GatewayMuxOptions []runtime.ServeMuxOption
+	// GatewayRoutes register the routes of the embedding program on the
+	// gRPC gateway mux (e.g., with "HandlePath"), after the server routes.
+	GatewayRoutes []func(mux *runtime.ServeMux) error
+	// GatewayMiddleware wraps the gRPC gateway handler (e.g., with the
+	// authentication or the request logging of the embedding program), the
+	// first one outermost. It also wraps the gRPC-Web requests.
+	GatewayMiddleware []func(next http.Handler) http.Handler
 }
 
 type Server interface {
@@ -46,9 +200,15 @@ type server struct {
 	closeOnce sync.Once
 	closed    chan struct{}
 
-	ln               net.Listener
+	ports            []string
+	lns              []net.Listener
+	gwPorts          []string
 	gRPCServer       *grpc.Server
 	gRPCRegisterOnce sync.Once
+	// standard gRPC health service, for probes (e.g., grpc_health_probe)
+	health *health.Server
+	// network operations in flight, for "/readyz"
+	readiness *readiness
 
 	gwMux    *runtime.ServeMux
 	gwServer *http.Server
@@ -57,6 +217,31 @@ type server struct {
 	clusterInfo *rpcpb.ClusterInfo
 	network     *localNetwork
 
+	binaries *binaryChecker
+	plugins  *pluginInventory
+	presets  map[string]Preset
+	// start templates by name
+	templates map[string]Template
+	// latencies of the runner operations, across the networks
+	latencies *latencies
+	statusHub *statusHub
+	// nil if tenancy is disabled
+	tenants tenants
+	// spent from by the faucet, the blockchain creation, and the seed actions
+	fundedKey *testkeys.Key
+	// results of the requests by idempotency key
+	idempotency *idempotencyCache
+	// nil if the host is not scheduled
+	scheduler *scheduler
+	// nil if the calls are not recorded
+	recorder *conformance.Recorder
+	// state-syncing nodes of the network
+	syncs *syncTracker
+	// serializes the garbage collections
+	gcMu sync.Mutex
+	// nil if no webhooks are configured
+	webhooks *webhooks
+
 	rpcpb.UnimplementedPingServiceServer
 	rpcpb.UnimplementedControlServiceServer
 }
@@ -71,26 +256,137 @@ func New(cfg Config) (Server, error) {
 	if cfg.Port == "" || cfg.GwPort == "" {
 		return nil, ErrInvalidPort
 	}
+	ports, err := splitPorts(cfg.Port)
+	if err != nil {
+		return nil, err
+	}
+	gwPorts, err := splitPorts(cfg.GwPort)
+	if err != nil {
+		return nil, err
+	}
 
-	ln, err := net.Listen("tcp", cfg.Port)
+	presets, err := loadPresets(cfg.PresetsDir, cfg.Presets)
 	if err != nil {
 		return nil, err
 	}
-	gwMux := runtime.NewServeMux()
-	return &server{
+	templates, err := loadTemplates(cfg.TemplatesDir, cfg.Templates)
+	if err != nil {
+		return nil, err
+	}
+	sched, err := newScheduler(cfg.SchedulerDir, cfg.SchedulerQueueTimeout, cfg.SchedulerCPUOvercommit)
+	if err != nil {
+		return nil, err
+	}
+	var recorder *conformance.Recorder
+	if cfg.RecordFixtures != "" {
+		recorder, err = conformance.NewRecorder(cfg.RecordFixtures)
+		if err != nil {
+			return nil, err
+		}
+		zap.L().Info("recording the control calls", zap.String("path", cfg.RecordFixtures))
+	}
+	tenants, err := loadTenants(cfg.TenantsFile, cfg.Tenants)
+	if err != nil {
+		return nil, err
+	}
+	fundedKey, err := loadFundedKey(cfg.FundedKey)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkOrphanPolicy(cfg.OrphanPolicy); err != nil {
+		return nil, err
+	}
+	webhooks, err := newWebhooks(cfg.Webhooks)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSupervisor(cfg.NodeSupervisor); err != nil {
+		return nil, err
+	}
+	if cfg.UploadDir == "" {
+		cfg.UploadDir = filepath.Join(os.TempDir(), "network-runner-uploads")
+	}
+	if cfg.ArtifactsDir == "" {
+		cfg.ArtifactsDir = filepath.Join(os.TempDir(), "network-runner-artifacts")
+	}
+	// before any network, so the orphans do not hold the ports of the next one
+	adoptable := reapOrphans(cfg.OrphanPolicy, cfg.ShutdownGracePeriod)
+
+	lns, err := listenAll(ports)
+	if err != nil {
+		return nil, err
+	}
+	gwMux := runtime.NewServeMux(append([]runtime.ServeMuxOption{runtime.WithIncomingHeaderMatcher(gatewayHeaderMatcher)}, cfg.GatewayMuxOptions...)...)
+	healthServer := health.NewServer()
+	s := &server{
 		cfg: cfg,
 
 		closed: make(chan struct{}),
 
-		ln:         ln,
-		gRPCServer: grpc.NewServer(),
+		ports:   ports,
+		lns:     lns,
+		gwPorts: gwPorts,
+		health:  healthServer,
+
+		readiness: newReadiness(healthServer),
 
 		gwMux: gwMux,
 		gwServer: &http.Server{
 			Addr:    cfg.GwPort,
 			Handler: gwMux,
 		},
-	}, nil
+
+		binaries:  newBinaryChecker(),
+		plugins:   newPluginInventory(),
+		presets:   presets,
+		templates: templates,
+		latencies: newLatencies(),
+		tenants:   tenants,
+		fundedKey: fundedKey,
+
+		idempotency: newIdempotencyCache(cfg.IdempotencyWindow),
+		scheduler:   sched,
+		recorder:    recorder,
+		syncs:       newSyncTracker(),
+		webhooks:    webhooks,
+	}
+	s.statusHub = newStatusHub(s)
+	unary := s.unaryInterceptors()
+	stream := []grpc.StreamServerInterceptor{tracing.StreamServerInterceptor(), streamStatusInterceptor, s.streamAuthInterceptor, streamValidationInterceptor}
+	if recorder != nil {
+		// first, so the calls are recorded with the status of the others
+		onErr := func(err error) { zap.L().Warn("failed to record the call", zap.Error(err)) }
+		unary = append([]grpc.UnaryServerInterceptor{recorder.UnaryServerInterceptor(onErr)}, unary...)
+		stream = append([]grpc.StreamServerInterceptor{recorder.StreamServerInterceptor(onErr)}, stream...)
+	}
+	s.gRPCServer = grpc.NewServer(
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.ChainStreamInterceptor(stream...),
+	)
+	if cfg.EnableGRPCWeb {
+		s.gwServer.Handler = grpcWebHandler(s.gRPCServer, cfg.GRPCWebAllowedOrigins, gwMux)
+	}
+	for i := len(cfg.GatewayMiddleware) - 1; i >= 0; i-- {
+		s.gwServer.Handler = cfg.GatewayMiddleware[i](s.gwServer.Handler)
+	}
+	if len(adoptable) > 0 {
+		s.adoptOrphans(adoptable)
+	}
+	return s, nil
+}
+
+// unaryInterceptors returns the unary interceptors of the control calls, in
+// order. The idempotency interceptor runs the handler detached from the
+// client, so the operations are tracked within it (and only once).
+func (s *server) unaryInterceptors() []grpc.UnaryServerInterceptor {
+	return []grpc.UnaryServerInterceptor{
+		tracing.UnaryServerInterceptor(),
+		unaryStatusInterceptor,
+		s.unaryAuthInterceptor,
+		unaryValidationInterceptor,
+		s.unaryIdempotencyInterceptor,
+		s.unaryOperationInterceptor,
+	}
 }
 
 func (s *server) Run(rootCtx context.Context) (err error) {
@@ -98,13 +394,39 @@ func (s *server) Run(rootCtx context.Context) (err error) {
 	s.gRPCRegisterOnce.Do(func() {
 		rpcpb.RegisterPingServiceServer(s.gRPCServer, s)
 		rpcpb.RegisterControlServiceServer(s.gRPCServer, s)
+		healthpb.RegisterHealthServer(s.gRPCServer, s.health)
+		// for tooling like grpcurl
+		reflection.Register(s.gRPCServer)
 	})
+	// reports the server (not the network) health, for the overall server
+	// ("") and each service
+	for _, svc := range []string{"", rpcpb.PingService_ServiceDesc.ServiceName, rpcpb.ControlService_ServiceDesc.ServiceName} {
+		s.health.SetServingStatus(svc, healthpb.HealthCheckResponse_SERVING)
+	}
+	s.readiness.update()
+	if s.cfg.GCInterval > 0 && (s.cfg.GCMaxAge > 0 || s.cfg.GCMaxBytes > 0) {
+		go s.runJanitor(rootCtx)
+	}
+	if s.cfg.StateFile != "" {
+		st := discovery.State{
+			Endpoint:        advertisedAddr(s.ports[0], s.lns[0].Addr()),
+			GatewayEndpoint: advertisedAddr(s.gwPorts[0], nil),
+			Pid:             os.Getpid(),
+		}
+		if err := discovery.WriteState(s.cfg.StateFile, st); err != nil {
+			zap.L().Warn("failed to write the state file", zap.String("path", s.cfg.StateFile), zap.Error(err))
+		} else {
+			zap.L().Info("wrote the state file", zap.String("path", s.cfg.StateFile), zap.String("endpoint", st.Endpoint))
+			defer func() {
+				if err := discovery.RemoveState(s.cfg.StateFile, st.Pid); err != nil {
+					zap.L().Warn("failed to remove the state file", zap.String("path", s.cfg.StateFile), zap.Error(err))
+				}
+			}()
+		}
+	}
 
-	gRPCErrc := make(chan error)
-	go func() {
-		zap.L().Info("serving gRPC server", zap.String("port", s.cfg.Port))
-		gRPCErrc <- s.gRPCServer.Serve(s.ln)
-	}()
+	zap.L().Info("serving gRPC server", zap.String("port", s.cfg.Port))
+	gRPCErrc := serveAll(s.lns, s.gRPCServer.Serve)
 
 	gwErrc := make(chan error)
 	go func() {
@@ -112,7 +434,8 @@ func (s *server) Run(rootCtx context.Context) (err error) {
 		ctx, cancel := context.WithTimeout(rootCtx, s.cfg.DialTimeout)
 		gwConn, err := grpc.DialContext(
 			ctx,
-			"0.0.0.0"+s.cfg.Port,
+			// the first listener, as the others may not be reachable locally
+			advertisedAddr(s.ports[0], s.lns[0].Addr()),
 			grpc.WithBlock(),
 			grpc.WithTransportCredentials(insecure.NewCredentials()),
 		)
@@ -131,14 +454,52 @@ func (s *server) Run(rootCtx context.Context) (err error) {
 			gwErrc <- err
 			return
 		}
+		if err := s.registerProbes(); err != nil {
+			gwErrc <- err
+			return
+		}
+		if s.cfg.EnableFaucet {
+			f := newFaucet(s.cfg.FaucetAmount, s.cfg.FaucetInterval, s.fundedKey, s.tenants, s.getTenantClusterInfo)
+			if err := s.gwMux.HandlePath(http.MethodPost, "/v1/faucet", f.ServeHTTP); err != nil {
+				gwErrc <- err
+				return
+			}
+			zap.L().Info("serving faucet", zap.String("port", s.cfg.GwPort))
+		}
+		if s.cfg.EnableNodeProxy {
+			if err := s.registerNodeProxy(); err != nil {
+				gwErrc <- err
+				return
+			}
+			zap.L().Info("serving node proxy", zap.String("port", s.cfg.GwPort))
+		}
+		if s.cfg.EnableLogStream {
+			if err := s.registerLogStream(); err != nil {
+				gwErrc <- err
+				return
+			}
+			zap.L().Info("serving log stream", zap.String("port", s.cfg.GwPort))
+		}
+		for _, register := range s.cfg.GatewayRoutes {
+			if err := register(s.gwMux); err != nil {
+				gwErrc <- fmt.Errorf("failed to register the gateway routes: %w", err)
+				return
+			}
+		}
 
+		gwLns, err := listenAll(s.gwPorts)
+		if err != nil {
+			gwErrc <- err
+			return
+		}
 		zap.L().Info("serving gRPC gateway", zap.String("port", s.cfg.GwPort))
-		gwErrc <- s.gwServer.ListenAndServe()
+		gwErrc <- <-serveAll(gwLns, s.gwServer.Serve)
 	}()
 
 	select {
 	case <-rootCtx.Done():
 		zap.L().Warn("root context is done")
+		s.health.Shutdown()
 
 		zap.L().Warn("closed gRPC gateway server", zap.Error(s.gwServer.Close()))
 		<-gwErrc
@@ -149,6 +510,8 @@ func (s *server) Run(rootCtx context.Context) (err error) {
 
 	case err = <-gRPCErrc:
 		zap.L().Warn("gRPC server failed", zap.Error(err))
+		// stops the other listeners
+		s.gRPCServer.Stop()
 		zap.L().Warn("closed gRPC gateway server", zap.Error(s.gwServer.Close()))
 		<-gwErrc
 
@@ -162,6 +525,11 @@ func (s *server) Run(rootCtx context.Context) (err error) {
 	s.closeOnce.Do(func() {
 		close(s.closed)
 	})
+	if s.recorder != nil {
+		if cerr := s.recorder.Close(); cerr != nil {
+			zap.L().Warn("failed to close the fixtures", zap.Error(cerr))
+		}
+	}
 	return err
 }
 
@@ -171,6 +539,7 @@ var (
 	ErrNodeNotFound        = errors.New("node not found")
 	ErrUnexpectedType      = errors.New("unexpected type")
 	ErrStatusCanceled      = errors.New("gRPC stream status canceled")
+	ErrNoStartInProgress   = errors.New("no start in progress")
 )
 
 func (s *server) Ping(ctx context.Context, req *rpcpb.PingRequest) (*rpcpb.PingResponse, error) {
@@ -180,60 +549,184 @@ func (s *server) Ping(ctx context.Context, req *rpcpb.PingRequest) (*rpcpb.PingR
 
 func (s *server) Start(ctx context.Context, req *rpcpb.StartRequest) (*rpcpb.StartResponse, error) {
 	zap.L().Info("received start request")
-	if s.getClusterInfo() != nil {
-		return nil, ErrAlreadyBootstrapped
+	s.mu.RLock()
+	err := s.startConflictLocked(ctx)
+	s.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	template := req.GetTemplate()
+	req, err = s.resolveTemplate(req, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkExecPath(req.ExecPath); err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(req.ExecPath); err != nil {
+		return nil, ErrNotExists
 	}
 
-	rootDataDir, err := ioutil.TempDir(os.TempDir(), "network-runner-root-data")
+	// each tenant gets its own data directory namespace
+	dataDir := os.TempDir()
+	t := tenantFromContext(ctx)
+	if t != nil {
+		dataDir = filepath.Join(dataDir, "network-runner-"+t.Name)
+		if err := os.MkdirAll(dataDir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+	rootDataDir, err := ioutil.TempDir(dataDir, rootDataDirPrefix)
 	if err != nil {
 		return nil, err
 	}
+	// the network owns the directory once created
+	created := false
+	defer func() {
+		if created {
+			return
+		}
+		if err := os.RemoveAll(rootDataDir); err != nil {
+			zap.L().Warn("failed to remove the root data dir", zap.String("rootDataDir", rootDataDir), zap.Error(err))
+		}
+	}()
 
 	info := &rpcpb.ClusterInfo{
 		Pid:         int32(os.Getpid()),
 		RootDataDir: rootDataDir,
 		Healthy:     false,
+		State:       stateCreating,
 	}
 	zap.L().Info("starting",
 		zap.String("execPath", req.ExecPath),
 		zap.String("whitelistedSubnets", req.GetWhitelistedSubnets()),
-		zap.Int32("pid", s.clusterInfo.GetPid()),
-		zap.String("rootDataDir", s.clusterInfo.GetRootDataDir()),
+		zap.Int32("pid", info.Pid),
+		zap.String("rootDataDir", info.RootDataDir),
 	)
-	if _, err := os.Stat(req.ExecPath); err != nil {
-		return nil, ErrNotExists
+	bin := s.binaries.check(ctx, req.ExecPath, req.GetPluginDir())
+	if !bin.Valid {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidBinary, bin.Error)
+	}
+	// hashed outside the lock, as the binaries may be large
+	manifest, err := newRunManifest(req, bin)
+	if err != nil {
+		return nil, err
+	}
+	s.plugins.list(bin.PluginDir)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	opts, err := s.resolveNetworkOptions(req, rootDataDir)
+	if err != nil {
+		return nil, err
+	}
+	if t != nil {
+		opts.tenant = t.Name
+		opts.maxNodes = t.MaxNodes
+	}
+	est, err := estimateResources(opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range est.Warnings {
+		zap.L().Warn("resource estimate", zap.String("warning", w))
+	}
+	if !est.Fits && !req.SkipResourceCheck {
+		return nil, fmt.Errorf("%w: %s", ErrInsufficientResources, strings.Join(est.Warnings, "; "))
+	}
+	// queued without the lock, so the server still answers meanwhile
+	var reserved string
+	if s.scheduler != nil {
+		reserved, err = s.scheduler.reserve(ctx, s.readiness, &reservation{
+			RootDataDir:   rootDataDir,
+			MemoryBytes:   est.Total.MemoryBytes,
+			CPUMillicores: est.Total.CpuMillicores,
+		}, req.SkipResourceCheck)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if s.network != nil {
-		return nil, ErrAlreadyBootstrapped
+	if err := s.startConflictLocked(ctx); err != nil {
+		s.scheduler.release(reserved)
+		return nil, err
 	}
-
-	s.network, err = newNetwork(req.GetExecPath(), rootDataDir, req.GetWhitelistedSubnets(), req.GetLogLevel())
+	s.network, err = newNetwork(opts)
 	if err != nil {
+		s.scheduler.release(reserved)
 		return nil, err
 	}
-	go s.network.start()
-
+	created = true
+	s.network.reservation = reserved
+	s.readiness.setNetwork(s.network.lifecycle)
+	if err := s.network.addManifest(manifest); err != nil {
+		zap.L().Warn("failed to write the run manifest", zap.Error(err))
+	}
+	s.network.provenance = &rpcpb.NetworkProvenance{
+		Kind:          rpcpb.ProvenanceKind_PROVENANCE_KIND_FRESH,
+		Preset:        req.GetPreset(),
+		Template:      template,
+		GenesisSha256: manifest.GenesisSha256,
+		StartedAt:     manifest.CreatedAt,
+	}
+	if cp := s.network.opts.resumeCheckpoint; cp != "" {
+		s.network.provenance.Kind = rpcpb.ProvenanceKind_PROVENANCE_KIND_SNAPSHOT
+		s.network.provenance.SnapshotName = cp
+	}
 	s.clusterInfo = info
+	s.syncs.reset()
+	for _, ni := range s.network.nodeInfos {
+		if ni.StateSync {
+			s.syncs.add(ni, s.network.nodeInfos)
+		}
+	}
+
+	nw := s.network
+	// the bootstrap outlives the request, but is traced as part of it
+	traceCtx := tracing.Detach(ctx)
+	nw.startWg.Add(1)
 	go func() {
-		select {
-		case <-s.closed:
+		if err := nw.start(traceCtx); err != nil {
+			zap.L().Warn("failed to start network", zap.Error(err))
 			return
-		case <-s.network.stopc:
-			// TODO: fix race from shutdown
-			return
-		case <-s.network.readyc:
-			s.mu.Lock()
-			s.clusterInfo.NodeNames = s.network.nodeNames
-			s.clusterInfo.NodeInfos = s.network.nodeInfos
-			s.clusterInfo.Healthy = true
-			s.mu.Unlock()
 		}
+		s.mu.Lock()
+		// the network may have been stopped (and replaced) in the meantime
+		if s.network == nw {
+			s.clusterInfo.NodeNames = nw.nodeNames
+			s.clusterInfo.NodeInfos = nw.nodeInfos
+			s.clusterInfo.SeedResults = nw.seedResults
+			for name, ni := range nw.nodeInfos {
+				if ni.StateSync {
+					s.syncs.markSynced(name)
+				}
+			}
+		}
+		s.mu.Unlock()
+		s.watchNetwork(nw)
 	}()
-	return &rpcpb.StartResponse{ClusterInfo: s.clusterInfo}, nil
+	return &rpcpb.StartResponse{ClusterInfo: s.copyClusterInfo()}, nil
+}
+
+// watchNetwork runs the watchers of the network, once started (or adopted).
+func (s *server) watchNetwork(nw *localNetwork) {
+	if nw.opts.webhooks.wants(EventNodeCrashed) {
+		go s.watchCrashes(nw)
+	}
+	if nw.opts.maxUnhealthyDuration > 0 {
+		go s.enforceUnhealthyBudget(nw)
+	}
+	if nw.opts.checkpoints != nil {
+		go s.runCheckpoints(nw)
+	}
+	if nw.opts.liveness != nil {
+		s.monitorLiveness(nw)
+	}
 }
 
 func (s *server) Health(ctx context.Context, req *rpcpb.HealthRequest) (*rpcpb.HealthResponse, error) {
@@ -241,23 +734,57 @@ func (s *server) Health(ctx context.Context, req *rpcpb.HealthRequest) (*rpcpb.H
 	if info := s.getClusterInfo(); info == nil {
 		return nil, ErrNotBootstrapped
 	}
-
-	zap.L().Info("waiting for healthy")
-	if err := s.network.waitForHealthy(); err != nil {
+	if err := s.checkState(stateBootstrapping, stateHealthy, stateDegraded); err != nil {
 		return nil, err
 	}
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	s.mu.RLock()
+	nw := s.network
+	s.mu.RUnlock()
+	if nw == nil {
+		return nil, ErrNotBootstrapped
+	}
 
-	s.network.nodeNames = make([]string, 0)
-	for name := range s.network.nodeInfos {
-		s.network.nodeNames = append(s.network.nodeNames, name)
+	if !req.NoWait {
+		zap.L().Info("waiting for healthy")
+		// waits without the lock, which is only taken to publish the nodes
+		if err := nw.waitForHealthyUnlocked(ctx); err != nil {
+			// a client cancel (or deadline) says nothing about the network health
+			if ctx.Err() == nil {
+				nw.markUnhealthy(err)
+				if !errors.Is(err, errAborted) {
+					return nil, fmt.Errorf("%w: %v", ErrNetworkUnhealthy, err)
+				}
+			}
+			return nil, err
+		}
 	}
-	s.clusterInfo.NodeNames = s.network.nodeNames
-	s.clusterInfo.NodeInfos = s.network.nodeInfos
 
-	return &rpcpb.HealthResponse{ClusterInfo: s.clusterInfo}, nil
+	s.mu.Lock()
+	// the network may have been stopped (and replaced) during the wait
+	if s.network != nw {
+		s.mu.Unlock()
+		return nil, errAborted
+	}
+	if !req.NoWait {
+		s.network.nodeNames = make([]string, 0)
+		for name := range s.network.nodeInfos {
+			s.network.nodeNames = append(s.network.nodeNames, name)
+		}
+		s.clusterInfo.NodeNames = s.network.nodeNames
+		s.clusterInfo.NodeInfos = s.network.nodeInfos
+	}
+	info := s.copyClusterInfo()
+	// the cluster info only lists the nodes once the network is first
+	// healthy, but the nodes being bootstrapped are probed too
+	nodeInfos := make(map[string]*rpcpb.NodeInfo, len(s.network.nodeInfos))
+	for name, nodeInfo := range s.network.nodeInfos {
+		nodeInfos[name] = proto.Clone(nodeInfo).(*rpcpb.NodeInfo)
+	}
+	s.mu.Unlock()
+
+	// probing the nodes outside the lock, as they may be slow to respond
+	return &rpcpb.HealthResponse{ClusterInfo: info, NodeHealth: probeNodes(ctx, nodeInfos)}, nil
 }
 
 func (s *server) URIs(ctx context.Context, req *rpcpb.URIsRequest) (*rpcpb.URIsResponse, error) {
@@ -266,6 +793,9 @@ func (s *server) URIs(ctx context.Context, req *rpcpb.URIsRequest) (*rpcpb.URIsR
 	if info == nil {
 		return nil, ErrNotBootstrapped
 	}
+	if err := s.checkState(stateBootstrapping, stateHealthy, stateDegraded); err != nil {
+		return nil, err
+	}
 	uris := make([]string, 0, len(info.NodeInfos))
 	for _, i := range info.NodeInfos {
 		uris = append(uris, i.Uri)
@@ -280,23 +810,43 @@ func (s *server) Status(ctx context.Context, req *rpcpb.StatusRequest) (*rpcpb.S
 	if info == nil {
 		return nil, ErrNotBootstrapped
 	}
-	return &rpcpb.StatusResponse{ClusterInfo: info}, nil
+	addResourceUsage(info)
+	return &rpcpb.StatusResponse{
+		ClusterInfo:       info,
+		Latencies:         s.latencies.snapshot(),
+		Provenance:        s.getProvenance(),
+		StatusSubscribers: uint32(s.statusHub.count()),
+	}, nil
+}
+
+func (s *server) getProvenance() *rpcpb.NetworkProvenance {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.network == nil || s.network.provenance == nil {
+		return nil
+	}
+	return proto.Clone(s.network.provenance).(*rpcpb.NetworkProvenance)
 }
 
 func (s *server) StreamStatus(req *rpcpb.StreamStatusRequest, stream rpcpb.ControlService_StreamStatusServer) (err error) {
 	zap.L().Info("received bootstrap status request")
-	if s.getClusterInfo() == nil {
+	if s.getTenantClusterInfo(tenantName(stream.Context())) == nil {
 		return ErrNotBootstrapped
 	}
 
 	interval := time.Duration(req.PushInterval)
 
 	// returns this method, then server closes the stream
-	zap.L().Info("pushing status updates to the stream", zap.String("interval", interval.String()))
+	zap.L().Info("pushing status updates to the stream",
+		zap.String("interval", interval.String()),
+		zap.Bool("sendOnlyOnChange", req.SendOnlyOnChange),
+		zap.Bool("delta", req.Delta),
+	)
 	wg := sync.WaitGroup{}
 	wg.Add(1)
 	go func() {
-		s.sendLoop(stream, interval)
+		s.sendLoop(stream, interval, req.SendOnlyOnChange, req.Delta)
 		wg.Done()
 	}()
 
@@ -329,24 +879,45 @@ func (s *server) StreamStatus(req *rpcpb.StreamStatusRequest, stream rpcpb.Contr
 	return err
 }
 
-func (s *server) sendLoop(stream rpcpb.ControlService_StreamStatusServer, interval time.Duration) {
+func (s *server) sendLoop(stream rpcpb.ControlService_StreamStatusServer, interval time.Duration, onlyOnChange bool, delta bool) {
 	zap.L().Info("start status send loop")
 
-	tc := time.NewTicker(1)
-	defer tc.Stop()
+	// the current snapshot is pushed on subscription,
+	// without waiting for the first interval
+	tenant := tenantName(stream.Context())
+	cur := s.getTenantClusterInfo(tenant)
+	sub := s.statusHub.subscribe(interval, tenant)
+	defer s.statusHub.unsubscribe(sub)
+
+	// last cluster info sent, nil until the first push
+	var prev *rpcpb.ClusterInfo
+	for first := true; ; first = false {
+		if !first {
+			select {
+			case <-s.rootCtx.Done():
+				return
+			case <-s.closed:
+				return
+			case <-stream.Context().Done():
+				return
+			case cur = <-sub.c:
+			}
+		}
 
-	for {
-		select {
-		case <-s.rootCtx.Done():
-			return
-		case <-s.closed:
-			return
-		case <-tc.C:
-			tc.Reset(interval)
+		resp := &rpcpb.StreamStatusResponse{ClusterInfo: cur}
+		if prev != nil {
+			if onlyOnChange && proto.Equal(prev, cur) {
+				continue
+			}
+			if delta {
+				resp = diffClusterInfo(prev, cur)
+			}
 		}
+		resp.Healthy = cur.GetHealthy()
+		prev = cur
 
-		zap.L().Debug("sending cluster info")
-		if err := stream.Send(&rpcpb.StreamStatusResponse{ClusterInfo: s.getClusterInfo()}); err != nil {
+		zap.L().Debug("sending cluster info", zap.Bool("delta", resp.Delta))
+		if err := stream.Send(resp); err != nil {
 			if isClientCanceled(stream.Context().Err(), err) {
 				zap.L().Debug("client stream canceled", zap.Error(err))
 				return
@@ -391,27 +962,30 @@ func (s *server) RemoveNode(ctx context.Context, req *rpcpb.RemoveNodeRequest) (
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if err := s.checkStateLocked(stateHealthy, stateDegraded); err != nil {
+		return nil, err
+	}
 	if _, ok := s.network.nodeInfos[req.Name]; !ok {
 		return nil, ErrNodeNotFound
 	}
 
-	if err := s.network.nw.RemoveNode(req.Name); err != nil {
+	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
-	delete(s.network.nodeInfos, req.Name)
-	s.network.nodeNames = make([]string, 0)
-	for name := range s.network.nodeInfos {
-		s.network.nodeNames = append(s.network.nodeNames, name)
+	if err := s.network.removeNode(req.Name); err != nil {
+		return nil, err
 	}
-	s.clusterInfo.NodeNames = s.network.nodeNames
-	s.clusterInfo.NodeInfos = s.network.nodeInfos
+	s.deleteNodeInfoLocked(req.Name)
 
 	zap.L().Info("waiting for healthy")
-	if err := s.network.waitForHealthy(); err != nil {
+	if err := s.waitForHealthyLocked(ctx); err != nil {
+		if ctx.Err() == nil && !errors.Is(err, errAborted) {
+			s.network.markUnhealthy(err)
+		}
 		return nil, err
 	}
 
-	return &rpcpb.RemoveNodeResponse{ClusterInfo: s.clusterInfo}, nil
+	return &rpcpb.RemoveNodeResponse{ClusterInfo: s.copyClusterInfo()}, nil
 }
 
 func (s *server) RestartNode(ctx context.Context, req *rpcpb.RestartNodeRequest) (*rpcpb.RestartNodeResponse, error) {
@@ -423,7 +997,74 @@ func (s *server) RestartNode(ctx context.Context, req *rpcpb.RestartNodeRequest)
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	nodeInfo, ok := s.network.nodeInfos[req.Name]
+	if err := s.checkStateLocked(stateHealthy, stateDegraded); err != nil {
+		return nil, err
+	}
+	plan, err := s.planRestartLocked(req.Name, req.StartRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	// do not remove the node if the client already gave up
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// now remove the node before restart
+	zap.L().Info("removing the node")
+	restartStart := time.Now()
+	if err := s.network.removeNode(req.Name); err != nil {
+		return nil, err
+	}
+
+	// now adding the new node
+	zap.L().Info("adding the node")
+	if err := ctx.Err(); err != nil {
+		s.network.transition(stateDegraded, err)
+		return nil, err
+	}
+	s.network.transition(stateBootstrapping, nil)
+	if err := s.network.addNode(plan.nodeConfig); err != nil {
+		s.network.transition(stateDegraded, err)
+		return nil, err
+	}
+
+	// update with the new config, and limit the new process before it
+	// bootstraps, as "Start" does
+	s.applyRestartLocked(plan)
+	if err := s.network.limitNodes(ctx, []string{req.Name}); err != nil {
+		s.network.transition(stateDegraded, err)
+		return nil, err
+	}
+
+	zap.L().Info("waiting for healthy")
+	if err := s.waitForHealthyLocked(ctx); err != nil {
+		if !errors.Is(err, errAborted) {
+			s.network.transition(stateDegraded, err)
+		}
+		return nil, err
+	}
+	s.latencies.observe(opRestartNode, restartStart)
+
+	return &rpcpb.RestartNodeResponse{ClusterInfo: s.copyClusterInfo(), ConfigChanges: plan.changes}, nil
+}
+
+// restartPlan is the new config of a node to restart.
+type restartPlan struct {
+	name string
+	// index in the network node configs
+	idx                int
+	nodeConfig         node.Config
+	execPath           string
+	whitelistedSubnets string
+	changes            []*rpcpb.ConfigChange
+}
+
+// planRestartLocked builds the new config of the node, keeping everything
+// same except the config file and binary path.
+// Must be called with the write lock held.
+func (s *server) planRestartLocked(name string, req *rpcpb.StartRequest) (*restartPlan, error) {
+	nodeInfo, ok := s.network.nodeInfos[name]
 	if !ok {
 		return nil, ErrNodeNotFound
 	}
@@ -431,7 +1072,7 @@ func (s *server) RestartNode(ctx context.Context, req *rpcpb.RestartNodeRequest)
 	found, idx := false, 0
 	oldNodeConfig := node.Config{}
 	for i, cfg := range s.network.cfg.NodeConfigs {
-		if cfg.Name == req.Name {
+		if cfg.Name == name {
 			oldNodeConfig = cfg
 			found = true
 			idx = i
@@ -441,59 +1082,92 @@ func (s *server) RestartNode(ctx context.Context, req *rpcpb.RestartNodeRequest)
 	if !found {
 		return nil, ErrNodeNotFound
 	}
-	nodeConfig := oldNodeConfig
-
-	// keep everything same except config file and binary path
-	nodeInfo.ExecPath = req.StartRequest.ExecPath
-	nodeInfo.WhitelistedSubnets = *req.StartRequest.WhitelistedSubnets
-	nodeConfig.ConfigFile = []byte(fmt.Sprintf(`{
-	"network-peer-list-gossip-frequency":"250ms",
-	"network-max-reconnect-delay":"1s",
-	"public-ip":"127.0.0.1",
-	"health-check-frequency":"2s",
-	"api-admin-enabled":true,
-	"api-ipcs-enabled":true,
-	"index-enabled":true,
-	"log-display-level":"INFO",
-	"log-level":"INFO",
-	"log-dir":"%s",
-	"db-dir":"%s",
-	"whitelisted-subnets":"%s"
-}`,
-		nodeInfo.LogDir,
-		nodeInfo.DbDir,
-		nodeInfo.WhitelistedSubnets,
-	))
-	implCfg := nodeConfig.ImplSpecificConfig
-	lcfg, ok := implCfg.(local.NodeConfig)
-	if !ok {
-		return nil, ErrUnexpectedType
+	if err := s.checkExecPath(req.GetExecPath()); err != nil {
+		return nil, err
+	}
+	plan := &restartPlan{
+		name:               name,
+		idx:                idx,
+		nodeConfig:         oldNodeConfig,
+		execPath:           req.GetExecPath(),
+		whitelistedSubnets: req.GetWhitelistedSubnets(),
 	}
-	lcfg.BinaryPath = nodeInfo.ExecPath
-	nodeConfig.ImplSpecificConfig = lcfg
 
-	// now remove the node before restart
-	zap.L().Info("removing the node")
-	if err := s.network.nw.RemoveNode(req.Name); err != nil {
+	var err error
+	plan.nodeConfig.ConfigFile, err = buildNodeConfig(
+		s.network.opts.globalNodeConfig,
+		s.network.opts.logLevel,
+		s.network.opts.nodeLogLevels[name],
+		nodeDirs{
+			logDir:  nodeInfo.LogDir,
+			dbDir:   nodeInfo.DbDir,
+			ipcsDir: nodeInfo.IpcsDir,
+		},
+		nodePorts{
+			httpPort:    uint16(nodeInfo.HttpPort),
+			stakingPort: uint16(nodeInfo.StakingPort),
+		},
+		plan.whitelistedSubnets,
+	)
+	if err != nil {
 		return nil, err
 	}
-
-	// now adding the new node
-	zap.L().Info("adding the node")
-	if _, err := s.network.nw.AddNode(nodeConfig); err != nil {
+	plan.changes, err = diffNodeConfigs(nodeInfo.Config, plan.nodeConfig.ConfigFile)
+	if err != nil {
 		return nil, err
 	}
+	if nodeInfo.ExecPath != plan.execPath {
+		plan.changes = append(plan.changes, &rpcpb.ConfigChange{
+			Key:      "exec-path",
+			OldValue: fmt.Sprintf("%q", nodeInfo.ExecPath),
+			NewValue: fmt.Sprintf("%q", plan.execPath),
+		})
+	}
+	for _, c := range plan.changes {
+		zap.L().Info("node config changed on restart",
+			zap.String("name", name),
+			zap.String("key", c.Key),
+			zap.String("old", c.OldValue),
+			zap.String("new", c.NewValue),
+		)
+	}
 
-	zap.L().Info("waiting for healthy")
-	if err := s.network.waitForHealthy(); err != nil {
+	implCfg := plan.nodeConfig.ImplSpecificConfig
+	lcfg, ok := implCfg.(local.NodeConfig)
+	if !ok {
+		return nil, ErrUnexpectedType
+	}
+	if nodeInfo.UnitName != "" {
+		lcfg.BinaryPath, err = writeSuperviseScript(filepath.Dir(nodeInfo.DbDir), nodeInfo.UnitName, plan.execPath)
+	} else {
+		lcfg.BinaryPath, err = writeExitScript(filepath.Dir(nodeInfo.DbDir), plan.execPath)
+	}
+	if err != nil {
 		return nil, err
 	}
+	plan.nodeConfig.ImplSpecificConfig = lcfg
+	return plan, nil
+}
 
-	// update with the new config
-	s.network.cfg.NodeConfigs[idx] = nodeConfig
+// applyRestartLocked records the new config of a restarted node.
+// Must be called with the write lock held.
+func (s *server) applyRestartLocked(plan *restartPlan) {
+	nodeInfo := s.network.nodeInfos[plan.name]
+	nodeInfo.ExecPath = plan.execPath
+	nodeInfo.WhitelistedSubnets = plan.whitelistedSubnets
+	nodeInfo.Config = plan.nodeConfig.ConfigFile
+	nodeInfo.PluginDir = nodePluginDir(plan.execPath, nodeInfo.Config)
+	nodeInfo.Plugins = s.network.opts.plugins.list(nodeInfo.PluginDir)
+	setNodeAddresses(nodeInfo)
+	// published IPC sockets do not survive the restart
+	nodeInfo.ChainIpcs = nil
+
+	s.network.cfg.NodeConfigs[plan.idx] = plan.nodeConfig
 	s.clusterInfo.NodeInfos = s.network.nodeInfos
-
-	return &rpcpb.RestartNodeResponse{ClusterInfo: s.clusterInfo}, nil
+	// e.g., a new key or address, for the nodes bootstrapping from the node
+	if err := s.network.writeBootstrapPeers(); err != nil {
+		zap.L().Warn("failed to write the bootstrap peers", zap.String("name", plan.name), zap.Error(err))
+	}
 }
 
 func (s *server) Stop(ctx context.Context, req *rpcpb.StopRequest) (*rpcpb.StopResponse, error) {
@@ -506,21 +1180,144 @@ func (s *server) Stop(ctx context.Context, req *rpcpb.StopRequest) (*rpcpb.StopR
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.network.stop()
-	s.network = nil
-	info.Healthy = false
-	s.clusterInfo = nil
+	if err := s.checkStateLocked(
+		stateCreating,
+		stateBootstrapping,
+		stateHealthy,
+		stateDegraded,
+		stateErrored,
+	); err != nil {
+		return nil, err
+	}
 
+	s.stopNetworkLocked(info)
 	return &rpcpb.StopResponse{ClusterInfo: info}, nil
 }
 
+// AbortStart stops a network that is still being created or bootstrapped
+// (e.g., with a misconfigured VM that never gets healthy), tearing down the
+// launched nodes, so that the server is ready for a new "Start".
+func (s *server) AbortStart(ctx context.Context, req *rpcpb.AbortStartRequest) (*rpcpb.AbortStartResponse, error) {
+	zap.L().Info("received abort start request")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkStateLocked(stateCreating, stateBootstrapping); err != nil {
+		if errors.Is(err, ErrInvalidState) {
+			return nil, fmt.Errorf("%w: %v", ErrNoStartInProgress, err)
+		}
+		return nil, err
+	}
+	// a network bootstrapping after a restart already started successfully
+	if s.clusterInfo.NodeInfos != nil {
+		return nil, fmt.Errorf("%w: network already started", ErrNoStartInProgress)
+	}
+
+	info := s.copyClusterInfo()
+	s.stopNetworkLocked(info)
+	zap.L().Info("aborted start", zap.String("rootDataDir", info.RootDataDir))
+	return &rpcpb.AbortStartResponse{ClusterInfo: info}, nil
+}
+
+// waitForHealthyLocked waits for the nodes to report healthy, after the
+// caller changed the node set. Must be called with the write lock held,
+// which is released during the wait (ref. "waitForHealthyUnlocked"), so the
+// other requests are not blocked by it. Returns "errAborted" if the network
+// was stopped (or replaced) in the meantime, so the caller must not change
+// the state of the network, nor of its replacement.
+func (s *server) waitForHealthyLocked(ctx context.Context) error {
+	nw := s.network
+	s.mu.Unlock()
+	err := nw.waitForHealthyUnlocked(ctx)
+	s.mu.Lock()
+	if s.network != nw {
+		return errAborted
+	}
+	return err
+}
+
+// stopNetworkLocked stops the network, and records the final state in "info".
+// Must be called with the write lock held, which is released while waiting
+// for the start routine, as it takes the lock to publish the nodes, and
+// while collecting the artifacts. The network is stopping in the meantime,
+// so the other requests are rejected.
+func (s *server) stopNetworkLocked(info *rpcpb.ClusterInfo) {
+	nw := s.network
+	nw.cancelStart()
+	s.mu.Unlock()
+	nw.startWg.Wait()
+	s.mu.Lock()
+
+	nw.stop()
+	info.State, _ = nw.lifecycle.get()
+	info.Healthy = false
+	s.scheduler.release(nw.reservation)
+	if s.network == nw {
+		s.network = nil
+		s.readiness.setNetwork(nil)
+		s.clusterInfo = nil
+	}
+
+	// archived without the lock, as the logs may be large
+	s.mu.Unlock()
+	nw.collectArtifacts()
+	s.mu.Lock()
+}
+
 func (s *server) getClusterInfo() *rpcpb.ClusterInfo {
 	s.mu.RLock()
-	info := s.clusterInfo
-	s.mu.RUnlock()
+	defer s.mu.RUnlock()
+	return s.copyClusterInfo()
+}
+
+// getTenantClusterInfo is "getClusterInfo" for the tenant, nil if the
+// cluster is of another tenant (with tenancy enabled).
+func (s *server) getTenantClusterInfo(tenant string) *rpcpb.ClusterInfo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if tenant != "" && (s.network == nil || s.network.opts.tenant != tenant) {
+		return nil
+	}
+	return s.copyClusterInfo()
+}
+
+// copyClusterInfo returns a copy of the cluster info with the current
+// network state, safe to use after the lock is released.
+// Must be called with the lock (read or write) held.
+func (s *server) copyClusterInfo() *rpcpb.ClusterInfo {
+	if s.clusterInfo == nil {
+		return nil
+	}
+	info := proto.Clone(s.clusterInfo).(*rpcpb.ClusterInfo)
+	if s.network != nil {
+		state, err := s.network.lifecycle.get()
+		info.State = state
+		info.Healthy = state == stateHealthy
+		info.Error = ""
+		if err != nil {
+			info.Error = err.Error()
+		}
+		s.network.sidecars.addStatuses(info)
+	}
 	return info
 }
 
+// checkState returns an error if the network is not in one of the allowed states.
+func (s *server) checkState(allowed ...rpcpb.NetworkState) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.checkStateLocked(allowed...)
+}
+
+// checkStateLocked is "checkState" with the lock already held.
+func (s *server) checkStateLocked(allowed ...rpcpb.NetworkState) error {
+	if s.network == nil {
+		return ErrNotBootstrapped
+	}
+	return s.network.lifecycle.check(allowed...)
+}
+
 func isClientCanceled(ctxErr error, err error) bool {
 	if ctxErr != nil {
 		return true
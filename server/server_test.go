@@ -0,0 +1,199 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lasthyphen/dijetsnode-go-runner/network"
+	"github.com/lasthyphen/dijetsnode-go-runner/network/node"
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+	"google.golang.org/grpc/health"
+)
+
+// newStartingServer returns a server with a network whose start routine is
+// blocked, as in the health wait, until the network stops, then takes the
+// lock to publish the nodes.
+func newStartingServer(t *testing.T) (*server, <-chan struct{}) {
+	s := &server{readiness: newReadiness(health.NewServer())}
+	nw := &localNetwork{
+		opts:      networkOptions{mu: &s.mu},
+		sink:      noopSink{},
+		lifecycle: newLifecycle(),
+	}
+	var err error
+	if nw.sidecars, err = newSidecarSet(nw.opts, nw.sink, nil); err != nil {
+		t.Fatal(err)
+	}
+	nw.stopCtx, nw.stopCancel = context.WithCancel(context.Background())
+	s.network = nw
+	s.clusterInfo = &rpcpb.ClusterInfo{}
+	s.readiness.setNetwork(nw.lifecycle)
+
+	published := make(chan struct{})
+	nw.startWg.Add(1)
+	go func() {
+		defer nw.startWg.Done()
+		<-nw.stopCtx.Done()
+		nw.opts.mu.Lock()
+		nw.opts.mu.Unlock()
+		close(published)
+	}()
+	return s, published
+}
+
+func TestStopDuringStart(t *testing.T) {
+	for _, tv := range []struct {
+		name string
+		stop func(s *server) (*rpcpb.ClusterInfo, error)
+	}{
+		{
+			name: "stop",
+			stop: func(s *server) (*rpcpb.ClusterInfo, error) {
+				resp, err := s.Stop(context.Background(), &rpcpb.StopRequest{})
+				return resp.GetClusterInfo(), err
+			},
+		},
+		{
+			name: "abort start",
+			stop: func(s *server) (*rpcpb.ClusterInfo, error) {
+				resp, err := s.AbortStart(context.Background(), &rpcpb.AbortStartRequest{})
+				return resp.GetClusterInfo(), err
+			},
+		},
+	} {
+		t.Run(tv.name, func(t *testing.T) {
+			s, published := newStartingServer(t)
+			done := make(chan struct{})
+			var (
+				info *rpcpb.ClusterInfo
+				err  error
+			)
+			go func() {
+				info, err = tv.stop(s)
+				close(done)
+			}()
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("stop blocked by the start routine")
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			select {
+			case <-published:
+			default:
+				t.Fatal("stop returned before the start routine")
+			}
+			if info.State != stateStopped {
+				t.Fatalf("expected %v, got %v", stateStopped, info.State)
+			}
+			if s.network != nil || s.clusterInfo != nil {
+				t.Fatal("network not cleared")
+			}
+		})
+	}
+}
+
+// fakeHealthNetwork has no nodes, and reports healthy once told to.
+type fakeHealthNetwork struct {
+	network.Network
+
+	healthy chan error
+}
+
+func (n *fakeHealthNetwork) GetAllNodes() (map[string]node.Node, error) {
+	return map[string]node.Node{}, nil
+}
+
+func (n *fakeHealthNetwork) Healthy(context.Context) chan error { return n.healthy }
+
+func TestWaitForHealthyLocked(t *testing.T) {
+	for _, tv := range []struct {
+		name    string
+		replace bool
+		err     error
+	}{
+		{name: "healthy"},
+		{name: "stopped during the wait", replace: true, err: errAborted},
+	} {
+		t.Run(tv.name, func(t *testing.T) {
+			s := &server{}
+			nw := &localNetwork{
+				opts:      networkOptions{mu: &s.mu},
+				nw:        &fakeHealthNetwork{healthy: make(chan error, 1)},
+				lifecycle: newLifecycle(),
+				nodeInfos: map[string]*rpcpb.NodeInfo{},
+			}
+			nw.stopCtx, nw.stopCancel = context.WithCancel(context.Background())
+			defer nw.stopCancel()
+			s.network = nw
+
+			errc := make(chan error, 1)
+			s.mu.Lock()
+			go func() {
+				err := s.waitForHealthyLocked(context.Background())
+				s.mu.Unlock()
+				errc <- err
+			}()
+
+			locked := make(chan struct{})
+			go func() {
+				s.mu.Lock()
+				if tv.replace {
+					s.network = nil
+				}
+				s.mu.Unlock()
+				close(locked)
+			}()
+			select {
+			case <-locked:
+			case <-time.After(5 * time.Second):
+				t.Fatal("lock held during the health wait")
+			}
+
+			nw.nw.(*fakeHealthNetwork).healthy <- nil
+			if err := <-errc; !errors.Is(err, tv.err) {
+				t.Fatalf("expected %v, got %v", tv.err, err)
+			}
+		})
+	}
+}
+
+func TestStartFailureRemovesRootDataDir(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+	execPath := filepath.Join(t.TempDir(), "avalanchego")
+	if err := ioutil.WriteFile(execPath, nil, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	s := &server{binaries: newBinaryChecker()}
+	ctx := context.WithValue(context.Background(), tenantKey{}, &Tenant{Name: "a"})
+	for i, tv := range []struct {
+		execPath string
+		err      error
+	}{
+		// before the root data dir is created
+		{execPath: filepath.Join(tmp, "missing"), err: ErrNotExists},
+		{execPath: execPath, err: ErrInvalidBinary},
+	} {
+		if _, err := s.Start(ctx, &rpcpb.StartRequest{ExecPath: tv.execPath}); !errors.Is(err, tv.err) {
+			t.Fatalf("#%d: expected %v, got %v", i, tv.err, err)
+		}
+		entries, err := ioutil.ReadDir(filepath.Join(tmp, "network-runner-a"))
+		if err != nil && !os.IsNotExist(err) {
+			t.Fatal(err)
+		}
+		if len(entries) != 0 {
+			t.Fatalf("#%d: expected no root data dir, got %q", i, entries[0].Name())
+		}
+	}
+}
@@ -0,0 +1,392 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/lasthyphen/dijetsnode-go-runner/network/node"
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+	"go.uber.org/zap"
+)
+
+const (
+	sidecarProbeInterval = 5 * time.Second
+	// time for the sidecars to exit before they are killed, if the
+	// server has no shutdown grace period
+	defaultSidecarStopTimeout = 5 * time.Second
+)
+
+var (
+	ErrInvalidSidecar = errors.New("invalid sidecar")
+
+	errSidecarExited = errors.New("exited")
+)
+
+// checkSidecars validates the sidecars of the start request. The node
+// names are checked against the network (ref. "newNetwork").
+func checkSidecars(nodeSidecars map[string]*rpcpb.NodeSidecars) error {
+	for nodeName, ns := range nodeSidecars {
+		names := make(map[string]bool)
+		for _, sc := range ns.GetSidecars() {
+			if sc.Name == "" || strings.ContainsAny(sc.Name, `/\`) {
+				return fmt.Errorf("%w: invalid name %q of a sidecar of %q", ErrInvalidSidecar, sc.Name, nodeName)
+			}
+			if names[sc.Name] {
+				return fmt.Errorf("%w: duplicate sidecar %q of %q", ErrInvalidSidecar, sc.Name, nodeName)
+			}
+			names[sc.Name] = true
+			if sc.ExecPath == "" {
+				return fmt.Errorf("%w: empty exec path of sidecar %q of %q", ErrInvalidSidecar, sc.Name, nodeName)
+			}
+			// parsed once expanded, as the variables are not valid ports
+			if sc.HealthUrl != "" && !strings.HasPrefix(sc.HealthUrl, "http://") && !strings.HasPrefix(sc.HealthUrl, "https://") {
+				return fmt.Errorf("%w: health URL %q of sidecar %q of %q is not HTTP", ErrInvalidSidecar, sc.HealthUrl, sc.Name, nodeName)
+			}
+		}
+	}
+	return nil
+}
+
+// sidecarSet is the sidecars of the nodes, with its own lock, as the nodes
+// are launched with and without the server lock (e.g., by the start
+// routine, or the parallel restarts).
+type sidecarSet struct {
+	stopTimeout time.Duration
+
+	mu sync.Mutex
+	// by node name, in the order of the request
+	byNode map[string][]*sidecar
+}
+
+// newSidecarSet creates the sidecars of the nodes, and adds their output
+// writers to the node writers, as "<node>/<sidecar>" (e.g., for
+// "AttachConsole" and the log stream).
+func newSidecarSet(opts networkOptions, sink logSink, writers map[string][2]*writer) (*sidecarSet, error) {
+	set := &sidecarSet{
+		stopTimeout: defaultSidecarStopTimeout,
+		byNode:      make(map[string][]*sidecar),
+	}
+	if opts.shutdownGracePeriod > 0 {
+		set.stopTimeout = opts.shutdownGracePeriod
+	}
+	for nodeName, ns := range opts.nodeSidecars {
+		if _, ok := writers[nodeName]; !ok {
+			return nil, fmt.Errorf("%w: sidecars of unknown node %q", ErrInvalidSidecar, nodeName)
+		}
+		for _, spec := range ns.GetSidecars() {
+			name := nodeName + "/" + spec.Name
+			sc := &sidecar{
+				node:    nodeName,
+				spec:    spec,
+				logPath: filepath.Join(opts.rootDataDir, nodeName, "sidecars", spec.Name+".log"),
+				stdout:  newWriter(name, "stdout", sink, nil),
+				stderr:  newWriter(name, "stderr", sink, nil),
+			}
+			writers[name] = [2]*writer{sc.stdout, sc.stderr}
+			set.byNode[nodeName] = append(set.byNode[nodeName], sc)
+		}
+	}
+	return set, nil
+}
+
+func (set *sidecarSet) get(nodeName string) []*sidecar {
+	set.mu.Lock()
+	defer set.mu.Unlock()
+	return set.byNode[nodeName]
+}
+
+// launch launches the sidecars of the node. The failures are reported in
+// the sidecar status, rather than failing the node.
+func (set *sidecarSet) launch(nc node.Config) {
+	scs := set.get(nc.Name)
+	if len(scs) == 0 {
+		return
+	}
+	vars, err := sidecarVars(nc)
+	for _, sc := range scs {
+		if err != nil {
+			sc.setFailed(err)
+			continue
+		}
+		sc.launch(vars)
+	}
+}
+
+// stop stops the sidecars of the node, and waits for them to exit.
+func (set *sidecarSet) stop(nodeName string) {
+	var wg sync.WaitGroup
+	for _, sc := range set.get(nodeName) {
+		wg.Add(1)
+		go func(sc *sidecar) {
+			defer wg.Done()
+			sc.stop(set.stopTimeout)
+		}(sc)
+	}
+	wg.Wait()
+}
+
+func (set *sidecarSet) stopAll() {
+	set.mu.Lock()
+	names := make([]string, 0, len(set.byNode))
+	for name := range set.byNode {
+		names = append(names, name)
+	}
+	set.mu.Unlock()
+	for _, name := range names {
+		set.stop(name)
+	}
+}
+
+// addStatuses sets the sidecar statuses of the node infos.
+func (set *sidecarSet) addStatuses(info *rpcpb.ClusterInfo) {
+	for name, ni := range info.NodeInfos {
+		scs := set.get(name)
+		if len(scs) == 0 {
+			continue
+		}
+		ni.Sidecars = make([]*rpcpb.SidecarStatus, 0, len(scs))
+		for _, sc := range scs {
+			ni.Sidecars = append(ni.Sidecars, sc.status())
+		}
+	}
+}
+
+// sidecarVars returns the "NODE_..." variables of the sidecars of the
+// node, from its config file.
+func sidecarVars(nc node.Config) (map[string]string, error) {
+	cfg := make(map[string]interface{})
+	if err := json.Unmarshal(nc.ConfigFile, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse the config of %q: %w", nc.Name, err)
+	}
+	host := defaultHTTPHost
+	if h, ok := cfg["http-host"].(string); ok && h != "" && !net.ParseIP(h).IsUnspecified() {
+		host = h
+	}
+	httpPort := fmt.Sprint(cfg["http-port"])
+	logDir := fmt.Sprint(cfg["log-dir"])
+	return map[string]string{
+		"NODE_NAME":         nc.Name,
+		"NODE_URI":          "http://" + net.JoinHostPort(host, httpPort),
+		"NODE_HTTP_PORT":    httpPort,
+		"NODE_STAKING_PORT": fmt.Sprint(cfg["staking-port"]),
+		"NODE_DATA_DIR":     filepath.Dir(logDir),
+		"NODE_LOG_DIR":      logDir,
+		"NODE_DB_DIR":       fmt.Sprint(cfg["db-dir"]),
+	}, nil
+}
+
+// sidecar is a companion process of a node.
+type sidecar struct {
+	node    string
+	spec    *rpcpb.Sidecar
+	logPath string
+	stdout  *writer
+	stderr  *writer
+
+	mu sync.Mutex
+	// nil unless launched
+	cmd     *exec.Cmd
+	running bool
+	healthy bool
+	err     error
+	// closed once the launched process exits
+	exited chan struct{}
+}
+
+func (sc *sidecar) launch(vars map[string]string) {
+	expand := func(s string) string {
+		return os.Expand(s, func(k string) string {
+			if v, ok := vars[k]; ok {
+				return v
+			}
+			return "$" + k
+		})
+	}
+	args := make([]string, len(sc.spec.Args))
+	for i, arg := range sc.spec.Args {
+		args[i] = expand(arg)
+	}
+	cmd := exec.Command(sc.spec.ExecPath, args...)
+	cmd.Dir = filepath.Dir(sc.logPath)
+	cmd.Env = os.Environ()
+	for k, v := range vars {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+	for k, v := range sc.spec.Env {
+		cmd.Env = append(cmd.Env, k+"="+expand(v))
+	}
+
+	if err := os.MkdirAll(cmd.Dir, 0o755); err != nil {
+		sc.setFailed(err)
+		return
+	}
+	f, err := os.OpenFile(sc.logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		sc.setFailed(err)
+		return
+	}
+	cmd.Stdout = io.MultiWriter(f, sc.stdout)
+	cmd.Stderr = io.MultiWriter(f, sc.stderr)
+	if err := cmd.Start(); err != nil {
+		f.Close()
+		sc.setFailed(err)
+		return
+	}
+	zap.L().Info("launched sidecar",
+		zap.String("node", sc.node),
+		zap.String("name", sc.spec.Name),
+		zap.Int("pid", cmd.Process.Pid),
+	)
+
+	exited := make(chan struct{})
+	sc.mu.Lock()
+	sc.cmd, sc.exited = cmd, exited
+	sc.running, sc.healthy, sc.err = true, sc.spec.HealthUrl == "", nil
+	sc.mu.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		err := cmd.Wait()
+		cancel()
+		f.Close()
+		if err == nil {
+			err = errSidecarExited
+		}
+		sc.mu.Lock()
+		// not stopped with the node
+		if sc.cmd == cmd {
+			sc.running, sc.healthy, sc.err = false, false, err
+			zap.L().Warn("sidecar exited", zap.String("node", sc.node), zap.String("name", sc.spec.Name), zap.Error(err))
+		}
+		sc.mu.Unlock()
+		close(exited)
+	}()
+	if sc.spec.HealthUrl != "" {
+		go sc.probe(ctx, cmd, expand(sc.spec.HealthUrl))
+	}
+}
+
+// probe checks the health URL of the process, until it exits.
+func (sc *sidecar) probe(ctx context.Context, cmd *exec.Cmd, url string) {
+	cli := &http.Client{Timeout: nodeProbeTimeout}
+	t := time.NewTicker(sidecarProbeInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		err := probeHealthURL(ctx, cli, url)
+		sc.mu.Lock()
+		if sc.cmd == cmd && sc.running {
+			sc.healthy, sc.err = err == nil, err
+		}
+		sc.mu.Unlock()
+	}
+}
+
+func probeHealthURL(ctx context.Context, cli *http.Client, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := cli.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("health check %q: unexpected status %s", url, resp.Status)
+	}
+	return nil
+}
+
+// stop signals the process to exit, and kills it after the timeout.
+func (sc *sidecar) stop(timeout time.Duration) {
+	sc.mu.Lock()
+	cmd, exited := sc.cmd, sc.exited
+	sc.cmd, sc.running, sc.healthy, sc.err = nil, false, false, nil
+	sc.mu.Unlock()
+	if cmd == nil {
+		return
+	}
+	select {
+	case <-exited:
+		return
+	default:
+	}
+	if err := cmd.Process.Signal(syscall.SIGTERM); err != nil {
+		_ = cmd.Process.Kill()
+	}
+	select {
+	case <-exited:
+	case <-time.After(timeout):
+		zap.L().Warn("sidecar did not exit, killing", zap.String("node", sc.node), zap.String("name", sc.spec.Name))
+		_ = cmd.Process.Kill()
+		<-exited
+	}
+}
+
+func (sc *sidecar) setFailed(err error) {
+	zap.L().Warn("failed to launch sidecar", zap.String("node", sc.node), zap.String("name", sc.spec.Name), zap.Error(err))
+	sc.mu.Lock()
+	sc.cmd, sc.running, sc.healthy, sc.err = nil, false, false, err
+	sc.mu.Unlock()
+}
+
+func (sc *sidecar) status() *rpcpb.SidecarStatus {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	st := &rpcpb.SidecarStatus{
+		Name:    sc.spec.Name,
+		Running: sc.running,
+		Healthy: sc.healthy,
+		LogPath: sc.logPath,
+	}
+	if sc.running {
+		st.Pid = uint32(sc.cmd.Process.Pid)
+	}
+	if sc.err != nil {
+		st.Error = sc.err.Error()
+	}
+	return st
+}
+
+// addNode launches the node, then its sidecars, and watches its exit.
+// Must be called with the lock held.
+func (lc *localNetwork) addNode(nc node.Config) error {
+	since := time.Now()
+	if _, err := lc.nw.AddNode(nc); err != nil {
+		return err
+	}
+	lc.sidecars.launch(nc)
+	if info, ok := lc.nodeInfos[nc.Name]; ok {
+		info.Exit = nil
+	}
+	lc.watchExit(nc.Name, since)
+	return nil
+}
+
+// removeNode stops the sidecars of the node, then the node, as an
+// expected exit.
+func (lc *localNetwork) removeNode(name string) error {
+	lc.sidecars.stop(name)
+	lc.exits.bump(name)
+	return lc.nw.RemoveNode(name)
+}
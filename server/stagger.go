@@ -0,0 +1,52 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lasthyphen/dijetsnode-go-runner/network/node"
+	"go.uber.org/zap"
+)
+
+// parseLaunchInterval returns the delay between the node launches of the
+// start request, zero to launch them all at once.
+func parseLaunchInterval(v *string) (time.Duration, error) {
+	if v == nil {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(*v)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("%w: invalid launch interval %q", ErrInvalidDuration, *v)
+	}
+	return d, nil
+}
+
+// launchStaggered adds the nodes one by one, after the launch interval
+// each, so they join the network (and bootstrap) at different times, in
+// the node order, as their beacons are (ref. "beaconSet"). Each
+// node is limited once launched, as the nodes of the network creation.
+// As the rest of the start, it runs without the lock, which the stop holds
+// while waiting for the start to return.
+func (lc *localNetwork) launchStaggered(ncs []node.Config) error {
+	for _, nc := range ncs {
+		select {
+		case <-lc.stopCtx.Done():
+			return errAborted
+		case <-time.After(lc.opts.launchInterval):
+		}
+		zap.L().Info("launching node", zap.String("name", nc.Name), zap.Duration("interval", lc.opts.launchInterval))
+		since := time.Now()
+		if _, err := lc.nw.AddNode(nc); err != nil {
+			return fmt.Errorf("failed to launch %q: %w", nc.Name, err)
+		}
+		lc.sidecars.launch(nc)
+		lc.watchExit(nc.Name, since)
+		if err := lc.limitNodes(lc.stopCtx, []string{nc.Name}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
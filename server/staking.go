@@ -0,0 +1,77 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+)
+
+// maximum delegation fee, 100% in units of 1/10,000th of a percent
+const maxDelegationFee = 1_000_000
+
+var ErrInvalidStakingParams = errors.New("invalid staking params")
+
+// stakingConfig returns the node config entries for the staking params,
+// validated as the node would at startup (so the error surfaces on "Start"
+// rather than as a node crash). The node only honors these entries on
+// non-public networks, which includes the local network.
+func stakingConfig(p *rpcpb.StakingParams) (map[string]interface{}, error) {
+	cfg := make(map[string]interface{})
+	if p == nil {
+		return cfg, nil
+	}
+
+	if p.MinValidatorStake != nil {
+		cfg["min-validator-stake"] = p.GetMinValidatorStake()
+	}
+	if p.MaxValidatorStake != nil {
+		cfg["max-validator-stake"] = p.GetMaxValidatorStake()
+	}
+	if p.MinValidatorStake != nil && p.MaxValidatorStake != nil && p.GetMinValidatorStake() > p.GetMaxValidatorStake() {
+		return nil, fmt.Errorf("%w: min validator stake %d above max %d", ErrInvalidStakingParams, p.GetMinValidatorStake(), p.GetMaxValidatorStake())
+	}
+	if p.MinDelegatorStake != nil {
+		cfg["min-delegator-stake"] = p.GetMinDelegatorStake()
+	}
+
+	if p.MinDelegationFee != nil {
+		if p.GetMinDelegationFee() > maxDelegationFee {
+			return nil, fmt.Errorf("%w: min delegation fee %d above %d", ErrInvalidStakingParams, p.GetMinDelegationFee(), maxDelegationFee)
+		}
+		cfg["min-delegation-fee"] = p.GetMinDelegationFee()
+	}
+
+	var minDuration, maxDuration time.Duration
+	if p.MinStakeDuration != nil {
+		d, err := time.ParseDuration(p.GetMinStakeDuration())
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("%w: invalid min stake duration %q", ErrInvalidStakingParams, p.GetMinStakeDuration())
+		}
+		minDuration = d
+		cfg["min-stake-duration"] = d.String()
+	}
+	if p.MaxStakeDuration != nil {
+		d, err := time.ParseDuration(p.GetMaxStakeDuration())
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("%w: invalid max stake duration %q", ErrInvalidStakingParams, p.GetMaxStakeDuration())
+		}
+		maxDuration = d
+		cfg["max-stake-duration"] = d.String()
+	}
+	if minDuration > 0 && maxDuration > 0 && maxDuration < minDuration {
+		return nil, fmt.Errorf("%w: max stake duration %v below min %v", ErrInvalidStakingParams, maxDuration, minDuration)
+	}
+
+	if p.UptimeRequirement != nil {
+		if u := p.GetUptimeRequirement(); u < 0 || u > 1 {
+			return nil, fmt.Errorf("%w: uptime requirement %v not in [0, 1]", ErrInvalidStakingParams, u)
+		}
+		cfg["uptime-requirement"] = p.GetUptimeRequirement()
+	}
+	return cfg, nil
+}
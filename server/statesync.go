@@ -0,0 +1,181 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lasthyphen/djtx-tester/pkg/jsonrpc"
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+	"go.uber.org/zap"
+)
+
+// withStateSync enables the state sync in the C-chain config of a node.
+func withStateSync(cChainConfig []byte) ([]byte, error) {
+	cfg := make(map[string]interface{})
+	if len(cChainConfig) > 0 {
+		if err := json.Unmarshal(cChainConfig, &cfg); err != nil {
+			return nil, err
+		}
+	}
+	cfg["state-sync-enabled"] = true
+	return json.Marshal(cfg)
+}
+
+// withPruning sets the pruning of the C-chain config of a node, disabled
+// for an archival node.
+func withPruning(cChainConfig []byte, enabled bool) ([]byte, error) {
+	cfg := make(map[string]interface{})
+	if len(cChainConfig) > 0 {
+		if err := json.Unmarshal(cChainConfig, &cfg); err != nil {
+			return nil, err
+		}
+	}
+	cfg["pruning-enabled"] = enabled
+	return json.Marshal(cfg)
+}
+
+// isArchival returns whether the C-chain config disables the pruning,
+// enabled by default.
+func isArchival(cChainConfig []byte) bool {
+	cfg := make(map[string]interface{})
+	if err := json.Unmarshal(cChainConfig, &cfg); err != nil {
+		return false
+	}
+	enabled, ok := cfg["pruning-enabled"].(bool)
+	return ok && !enabled
+}
+
+// syncTracker keeps the launch times of the state-syncing nodes, for
+// "GetSyncProgress" to read them without the server lock, which "AddNode"
+// holds while the node syncs.
+type syncTracker struct {
+	mu    sync.Mutex
+	nodes map[string]*syncNode
+}
+
+type syncNode struct {
+	uri string
+	// URIs of the other nodes when the node was launched
+	peers  []string
+	launch time.Time
+	// zero until synced
+	syncTime time.Duration
+}
+
+func newSyncTracker() *syncTracker {
+	return &syncTracker{nodes: make(map[string]*syncNode)}
+}
+
+// add tracks the node, launched now, with the HTTP port of its node info.
+func (t *syncTracker) add(info *rpcpb.NodeInfo, infos map[string]*rpcpb.NodeInfo) {
+	n := &syncNode{
+		uri:    fmt.Sprintf("http://127.0.0.1:%d", info.HttpPort),
+		launch: time.Now(),
+	}
+	for name, ni := range infos {
+		if name != info.Name && ni.HttpPort != 0 {
+			n.peers = append(n.peers, fmt.Sprintf("http://127.0.0.1:%d", ni.HttpPort))
+		}
+	}
+	t.mu.Lock()
+	t.nodes[info.Name] = n
+	t.mu.Unlock()
+}
+
+func (t *syncTracker) remove(name string) {
+	t.mu.Lock()
+	delete(t.nodes, name)
+	t.mu.Unlock()
+}
+
+// reset forgets the nodes of the previous network.
+func (t *syncTracker) reset() {
+	t.mu.Lock()
+	t.nodes = make(map[string]*syncNode)
+	t.mu.Unlock()
+}
+
+func (t *syncTracker) get(name string) (syncNode, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	n, ok := t.nodes[name]
+	if !ok {
+		return syncNode{}, false
+	}
+	return *n, true
+}
+
+// markSynced records the sync time of the node, when first found synced
+// (or healthy).
+func (t *syncTracker) markSynced(name string) {
+	t.mu.Lock()
+	if n, ok := t.nodes[name]; ok && n.syncTime == 0 {
+		n.syncTime = time.Since(n.launch)
+	}
+	t.mu.Unlock()
+}
+
+// GetSyncProgress reports whether the node bootstrapped its C-chain,
+// and its height against the other nodes, e.g., while "AddNode" waits for
+// a state-syncing node.
+func (s *server) GetSyncProgress(ctx context.Context, req *rpcpb.GetSyncProgressRequest) (*rpcpb.GetSyncProgressResponse, error) {
+	zap.L().Debug("received get sync progress request", zap.String("name", req.Name))
+	resp := &rpcpb.GetSyncProgressResponse{}
+	n, ok := s.syncs.get(req.Name)
+	if ok {
+		resp.StateSync = true
+	} else {
+		s.mu.RLock()
+		if s.network == nil {
+			s.mu.RUnlock()
+			return nil, ErrNotBootstrapped
+		}
+		info, ok := s.network.nodeInfos[req.Name]
+		if !ok {
+			s.mu.RUnlock()
+			return nil, ErrNodeNotFound
+		}
+		n.uri = info.Uri
+		for name, ni := range s.network.nodeInfos {
+			if name != req.Name && ni.Uri != "" {
+				n.peers = append(n.peers, ni.Uri)
+			}
+		}
+		s.mu.RUnlock()
+	}
+
+	// ref. info "IsBootstrapped"
+	var reply struct {
+		IsBootstrapped bool `json:"isBootstrapped"`
+	}
+	if err := jsonrpc.Call(ctx, n.uri, "/ext/info", "info.isBootstrapped", map[string]string{"chain": "C"}, &reply); err != nil {
+		// the node does not serve its APIs yet
+		zap.L().Debug("failed to get the bootstrap status", zap.String("name", req.Name), zap.Error(err))
+	}
+	resp.Synced = reply.IsBootstrapped
+	if resp.Synced {
+		if h, err := chainHeight(ctx, n.uri, "C"); err == nil {
+			resp.Height = h
+		}
+	}
+	resp.NetworkHeight, _ = maxChainHeight(ctx, n.peers, "C")
+
+	if resp.StateSync {
+		if resp.Synced {
+			s.syncs.markSynced(req.Name)
+			n, _ = s.syncs.get(req.Name)
+		}
+		elapsed := n.syncTime
+		if elapsed == 0 {
+			elapsed = time.Since(n.launch)
+		}
+		resp.Elapsed = elapsed.Round(time.Millisecond).String()
+	}
+	return resp, nil
+}
@@ -0,0 +1,96 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"sort"
+
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+	"google.golang.org/protobuf/proto"
+)
+
+// diffClusterInfo returns the delta status response from "prev" to "cur",
+// with only the changed node infos and fields set in the cluster info.
+func diffClusterInfo(prev *rpcpb.ClusterInfo, cur *rpcpb.ClusterInfo) *rpcpb.StreamStatusResponse {
+	if prev == nil || cur == nil {
+		return &rpcpb.StreamStatusResponse{ClusterInfo: cur}
+	}
+
+	info := &rpcpb.ClusterInfo{}
+	resp := &rpcpb.StreamStatusResponse{ClusterInfo: info, Delta: true}
+	if !equalStrings(prev.NodeNames, cur.NodeNames) {
+		info.NodeNames = cur.NodeNames
+		resp.ChangedFields = append(resp.ChangedFields, "node_names")
+	}
+	if prev.Pid != cur.Pid {
+		info.Pid = cur.Pid
+		resp.ChangedFields = append(resp.ChangedFields, "pid")
+	}
+	if prev.RootDataDir != cur.RootDataDir {
+		info.RootDataDir = cur.RootDataDir
+		resp.ChangedFields = append(resp.ChangedFields, "root_data_dir")
+	}
+	if prev.Healthy != cur.Healthy {
+		info.Healthy = cur.Healthy
+		resp.ChangedFields = append(resp.ChangedFields, "healthy")
+	}
+	if prev.State != cur.State {
+		info.State = cur.State
+		resp.ChangedFields = append(resp.ChangedFields, "state")
+	}
+	if prev.Error != cur.Error {
+		info.Error = cur.Error
+		resp.ChangedFields = append(resp.ChangedFields, "error")
+	}
+	if !equalAlerts(prev.LivenessAlerts, cur.LivenessAlerts) {
+		info.LivenessAlerts = cur.LivenessAlerts
+		resp.ChangedFields = append(resp.ChangedFields, "liveness_alerts")
+	}
+	// set once, when the network is seeded
+	if len(prev.SeedResults) != len(cur.SeedResults) {
+		info.SeedResults = cur.SeedResults
+		resp.ChangedFields = append(resp.ChangedFields, "seed_results")
+	}
+
+	for name, ni := range cur.NodeInfos {
+		if proto.Equal(prev.NodeInfos[name], ni) {
+			continue
+		}
+		if info.NodeInfos == nil {
+			info.NodeInfos = make(map[string]*rpcpb.NodeInfo)
+		}
+		info.NodeInfos[name] = ni
+	}
+	for name := range prev.NodeInfos {
+		if _, ok := cur.NodeInfos[name]; !ok {
+			resp.RemovedNodes = append(resp.RemovedNodes, name)
+		}
+	}
+	sort.Strings(resp.RemovedNodes)
+	return resp
+}
+
+func equalStrings(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func equalAlerts(a []*rpcpb.LivenessAlert, b []*rpcpb.LivenessAlert) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !proto.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
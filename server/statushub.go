@@ -0,0 +1,153 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"sync"
+	"time"
+
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+	"go.uber.org/zap"
+)
+
+// the push interval of the subscribers without one
+const defaultPushInterval = 5 * time.Second
+
+// statusHub fans the cluster info out to the "StreamStatus" subscribers,
+// computed once per push interval for all of the subscribers of the
+// interval, instead of once per stream.
+type statusHub struct {
+	s *server
+
+	mu sync.Mutex
+	// subscribers by push interval
+	groups map[time.Duration]*statusGroup
+}
+
+type statusGroup struct {
+	subs map[*statusSub]struct{}
+	stop chan struct{}
+}
+
+// statusSub receives the latest cluster info. The previous one is dropped
+// if the stream has not sent it yet, so a slow client does not hold up
+// the others. With tenancy enabled, the subscriber receives nil while the
+// cluster is of another tenant.
+type statusSub struct {
+	c        chan *rpcpb.ClusterInfo
+	interval time.Duration
+	// empty if tenancy is disabled
+	tenant string
+}
+
+func newStatusHub(s *server) *statusHub {
+	return &statusHub{s: s, groups: make(map[time.Duration]*statusGroup)}
+}
+
+// subscribe adds a subscriber of the interval ("defaultPushInterval" if
+// not positive) and the tenant, starting its broadcast if it is the first
+// one.
+func (h *statusHub) subscribe(interval time.Duration, tenant string) *statusSub {
+	if interval <= 0 {
+		interval = defaultPushInterval
+	}
+	sub := &statusSub{c: make(chan *rpcpb.ClusterInfo, 1), interval: interval, tenant: tenant}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	g, ok := h.groups[interval]
+	if !ok {
+		g = &statusGroup{subs: make(map[*statusSub]struct{}), stop: make(chan struct{})}
+		h.groups[interval] = g
+		go h.broadcast(interval, g)
+	}
+	g.subs[sub] = struct{}{}
+	zap.L().Debug("status subscriber added", zap.Duration("interval", interval), zap.Int("subscribers", h.countLocked()))
+	return sub
+}
+
+// unsubscribe removes the subscriber, stopping the broadcast of its
+// interval once it has no subscribers.
+func (h *statusHub) unsubscribe(sub *statusSub) {
+	interval := sub.interval
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	g, ok := h.groups[interval]
+	if !ok {
+		return
+	}
+	delete(g.subs, sub)
+	if len(g.subs) == 0 {
+		delete(h.groups, interval)
+		close(g.stop)
+	}
+	zap.L().Debug("status subscriber removed", zap.Duration("interval", interval), zap.Int("subscribers", h.countLocked()))
+}
+
+// count returns the number of "StreamStatus" subscribers.
+func (h *statusHub) count() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.countLocked()
+}
+
+func (h *statusHub) countLocked() int {
+	n := 0
+	for _, g := range h.groups {
+		n += len(g.subs)
+	}
+	return n
+}
+
+func (h *statusHub) broadcast(interval time.Duration, g *statusGroup) {
+	tc := time.NewTicker(interval)
+	defer tc.Stop()
+	for {
+		select {
+		case <-h.s.rootCtx.Done():
+			return
+		case <-h.s.closed:
+			return
+		case <-g.stop:
+			return
+		case <-tc.C:
+		}
+
+		// shared by the subscribers of the tenant, which must not change it
+		h.mu.Lock()
+		infos := make(map[string]*rpcpb.ClusterInfo)
+		for sub := range g.subs {
+			infos[sub.tenant] = nil
+		}
+		h.mu.Unlock()
+		for tenant := range infos {
+			infos[tenant] = h.s.getTenantClusterInfo(tenant)
+		}
+		h.mu.Lock()
+		for sub := range g.subs {
+			// added since, pushed its snapshot on subscription
+			info, ok := infos[sub.tenant]
+			if !ok {
+				continue
+			}
+			sub.offer(info)
+		}
+		h.mu.Unlock()
+	}
+}
+
+// offer replaces the pending cluster info, if any. Only the broadcast
+// sends to the subscriber, so the send after the drain does not block.
+func (sub *statusSub) offer(info *rpcpb.ClusterInfo) {
+	select {
+	case sub.c <- info:
+		return
+	default:
+	}
+	select {
+	case <-sub.c:
+	default:
+	}
+	sub.c <- info
+}
@@ -0,0 +1,119 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+	"google.golang.org/grpc"
+)
+
+// fakeStatusStream is the "StreamStatus" stream of a client that sends no
+// requests until canceled.
+type fakeStatusStream struct {
+	grpc.ServerStream
+
+	ctx  context.Context
+	sent chan *rpcpb.StreamStatusResponse
+}
+
+func (s *fakeStatusStream) Context() context.Context { return s.ctx }
+
+func (s *fakeStatusStream) Send(resp *rpcpb.StreamStatusResponse) error {
+	select {
+	case s.sent <- resp:
+		return nil
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	}
+}
+
+func (s *fakeStatusStream) RecvMsg(interface{}) error {
+	<-s.ctx.Done()
+	return s.ctx.Err()
+}
+
+func TestStreamStatusZeroInterval(t *testing.T) {
+	s := &server{
+		rootCtx:     context.Background(),
+		closed:      make(chan struct{}),
+		clusterInfo: &rpcpb.ClusterInfo{RootDataDir: "/tmp/network-runner-root-data"},
+	}
+	s.statusHub = newStatusHub(s)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream := &fakeStatusStream{ctx: ctx, sent: make(chan *rpcpb.StreamStatusResponse, 1)}
+	errc := make(chan error, 1)
+	go func() {
+		errc <- s.StreamStatus(&rpcpb.StreamStatusRequest{PushInterval: 0}, stream)
+	}()
+
+	select {
+	case resp := <-stream.sent:
+		if resp.GetClusterInfo().GetRootDataDir() != "/tmp/network-runner-root-data" {
+			t.Fatalf("unexpected cluster info %v", resp.GetClusterInfo())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no status pushed")
+	}
+	s.statusHub.mu.Lock()
+	g, ok := s.statusHub.groups[defaultPushInterval]
+	n := 0
+	if ok {
+		n = len(g.subs)
+	}
+	s.statusHub.mu.Unlock()
+	if n != 1 {
+		t.Fatalf("expected a subscriber of %v, got %d", defaultPushInterval, n)
+	}
+
+	cancel()
+	if err := <-errc; !errors.Is(err, ErrStatusCanceled) {
+		t.Fatalf("expected %v, got %v", ErrStatusCanceled, err)
+	}
+	if n := s.statusHub.count(); n != 0 {
+		t.Fatalf("expected no subscribers, got %d", n)
+	}
+}
+
+func TestStatusHubTenant(t *testing.T) {
+	s := &server{
+		rootCtx:     context.Background(),
+		closed:      make(chan struct{}),
+		clusterInfo: &rpcpb.ClusterInfo{RootDataDir: "/tmp/network-runner-root-data"},
+		network:     &localNetwork{opts: networkOptions{tenant: "a"}, lifecycle: newLifecycle()},
+	}
+	s.statusHub = newStatusHub(s)
+
+	owner := s.statusHub.subscribe(10*time.Millisecond, "a")
+	defer s.statusHub.unsubscribe(owner)
+	other := s.statusHub.subscribe(10*time.Millisecond, "b")
+	defer s.statusHub.unsubscribe(other)
+	// tenancy disabled
+	all := s.statusHub.subscribe(10*time.Millisecond, "")
+	defer s.statusHub.unsubscribe(all)
+
+	for i, tv := range []struct {
+		sub  *statusSub
+		info bool
+	}{
+		{sub: owner, info: true},
+		{sub: other},
+		{sub: all, info: true},
+	} {
+		select {
+		case info := <-tv.sub.c:
+			if (info != nil) != tv.info {
+				t.Fatalf("#%d: expected the cluster info %v, got %v", i, tv.info, info)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("#%d: no status pushed", i)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// supervisors of the node processes
+const (
+	// the nodes are children of the server only
+	SupervisorNone = "none"
+	// each node runs in a transient systemd scope unit, outside of the
+	// server cgroup, so it survives the server (e.g., a crashed server unit)
+	SupervisorSystemd = "systemd"
+)
+
+var (
+	ErrInvalidSupervisor     = errors.New("invalid node supervisor")
+	ErrSupervisorUnsupported = errors.New("node supervisor not supported (systemd-run not found)")
+	ErrSupervisedCgroups     = errors.New("resource limits and traffic rules are not supported with a node supervisor")
+)
+
+// checkSupervisor checks that the supervisor runs on this host. There is no
+// launchd supervisor, as launchd runs the jobs itself, and the runner must
+// launch the node processes to control them.
+func checkSupervisor(supervisor string) error {
+	switch supervisor {
+	case "", SupervisorNone:
+		return nil
+	case SupervisorSystemd:
+		if _, err := exec.LookPath("systemd-run"); err != nil {
+			return fmt.Errorf("%w: %v", ErrSupervisorUnsupported, err)
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: %q (expected %q or %q)", ErrInvalidSupervisor, supervisor, SupervisorNone, SupervisorSystemd)
+}
+
+// nodeUnitName returns the name of the systemd unit of the node,
+// unique per network.
+func nodeUnitName(rootDataDir string, name string) string {
+	unit := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		}
+		return '_'
+	}, nodeCgroupName(rootDataDir, name))
+	return unit + ".scope"
+}
+
+// writeSuperviseScript writes the launcher of the node into its data
+// directory, and returns its path to be run instead of the binary. The
+// launcher execs the binary in the unit, so the runner still signals and
+// waits for the node process itself. The (user) service manager of the
+// server user runs the unit.
+func writeSuperviseScript(nodeDir string, unit string, execPath string) (string, error) {
+	args := []string{"systemd-run"}
+	if os.Geteuid() != 0 {
+		args = append(args, "--user")
+	}
+	args = append(args, "--scope", "--collect", "--quiet", "--unit="+unit, "--", execPath)
+	for i, arg := range args {
+		args[i] = shellQuote(arg)
+	}
+	script := "#!/bin/sh\n" + stdinScript(nodeDir, 0) + bootstrapPeersScript(nodeDir) + "exec " + strings.Join(args, " ") + " \"$@\"\n"
+
+	if err := os.MkdirAll(nodeDir, 0o755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(nodeDir, "supervise.sh")
+	if err := ioutil.WriteFile(path, []byte(script), 0o755); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,215 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	ErrTemplateNotFound      = errors.New("template not found")
+	ErrEmptyTemplateName     = errors.New("empty template name")
+	ErrInvalidTemplate       = errors.New("invalid template")
+	ErrInvalidTemplateParams = errors.New("invalid template params")
+)
+
+// Template is a named start request with parameters, so the teams share
+// their standard topologies and only vary a few of their fields per run.
+// Custom templates are loaded from "<name>.json" files in the templates
+// directory.
+type Template struct {
+	Name        string                   `json:"name"`
+	Description string                   `json:"description"`
+	Params      map[string]TemplateParam `json:"params"`
+	// Spec is the start request, in its JSON form (e.g., "numNodes"), where
+	// each "${param}" is replaced by the param value: a string that is
+	// only the placeholder takes the type of the value (e.g., a number),
+	// and the others are interpolated.
+	Spec map[string]interface{} `json:"spec"`
+}
+
+// TemplateParam is a parameter of a template, required without a default.
+type TemplateParam struct {
+	Description string      `json:"description"`
+	Default     interface{} `json:"default"`
+}
+
+var templateParamRegexp = regexp.MustCompile(`\$\{(\w+)\}`)
+
+// loadTemplates returns the templates of the given directory (if not
+// empty), overridden by the configured ones.
+func loadTemplates(dir string, configured []Template) (map[string]Template, error) {
+	templates := make(map[string]Template)
+	files := []string{}
+	if dir != "" {
+		var err error
+		files, err = filepath.Glob(filepath.Join(dir, "*.json"))
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, f := range files {
+		b, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		var t Template
+		if err := json.Unmarshal(b, &t); err != nil {
+			return nil, fmt.Errorf("%w %q: %v", ErrInvalidTemplate, f, err)
+		}
+		if t.Name == "" {
+			t.Name = strings.TrimSuffix(filepath.Base(f), ".json")
+		}
+		templates[t.Name] = t
+	}
+
+	for _, t := range configured {
+		if t.Name == "" {
+			return nil, ErrEmptyTemplateName
+		}
+		if _, ok := templates[t.Name]; ok {
+			zap.L().Info("overriding template", zap.String("name", t.Name))
+		}
+		templates[t.Name] = t
+	}
+	return templates, nil
+}
+
+// resolveTemplate returns the start request of the template of the
+// request, if any, with the request fields taking precedence (the repeated
+// ones are appended, and the map entries merged). The resolved request
+// has no template, and is validated as the requests are before their
+// handler (ref. "validateStartRequest").
+func (s *server) resolveTemplate(req *rpcpb.StartRequest, needExecPath bool) (*rpcpb.StartRequest, error) {
+	if req.Template == nil {
+		if len(req.TemplateParams) > 0 {
+			return nil, fmt.Errorf("%w: params without a template", ErrInvalidTemplateParams)
+		}
+		return req, nil
+	}
+	t, ok := s.templates[req.GetTemplate()]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrTemplateNotFound, req.GetTemplate())
+	}
+	values, err := t.values(req.TemplateParams)
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(expandTemplate(t.Spec, values))
+	if err != nil {
+		return nil, fmt.Errorf("%w %q: %v", ErrInvalidTemplate, t.Name, err)
+	}
+	resolved := &rpcpb.StartRequest{}
+	if err := protojson.Unmarshal(b, resolved); err != nil {
+		return nil, fmt.Errorf("%w %q: %v", ErrInvalidTemplate, t.Name, err)
+	}
+
+	override := proto.Clone(req).(*rpcpb.StartRequest)
+	override.Template, override.TemplateParams = nil, nil
+	proto.Merge(resolved, override)
+	resolved.Template, resolved.TemplateParams = nil, nil
+	if err := validateStartRequest("", resolved, needExecPath); err != nil {
+		return nil, err
+	}
+	zap.L().Info("using template", zap.String("name", t.Name), zap.Any("params", values))
+	return resolved, nil
+}
+
+// values returns the param values of the template, the defaults for the
+// params not set. A value takes the type of its default unless a string
+// (e.g., "3" is a number for a number default), and is parsed as JSON
+// without a default, or else kept as a string.
+func (t Template) values(params map[string]string) (map[string]interface{}, error) {
+	names := make([]string, 0, len(params))
+	for name := range params {
+		if _, ok := t.Params[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	if len(names) > 0 {
+		sort.Strings(names)
+		return nil, fmt.Errorf("%w: unknown params %q of template %q", ErrInvalidTemplateParams, names, t.Name)
+	}
+
+	values := make(map[string]interface{}, len(t.Params))
+	for name, p := range t.Params {
+		s, ok := params[name]
+		if !ok {
+			if p.Default == nil {
+				names = append(names, name)
+				continue
+			}
+			values[name] = p.Default
+			continue
+		}
+		if _, isString := p.Default.(string); isString {
+			values[name] = s
+			continue
+		}
+		var v interface{}
+		if err := json.Unmarshal([]byte(s), &v); err != nil {
+			if p.Default != nil {
+				return nil, fmt.Errorf("%w: param %q of template %q: %q is not of the type of its default %v", ErrInvalidTemplateParams, name, t.Name, s, p.Default)
+			}
+			v = s
+		}
+		values[name] = v
+	}
+	if len(names) > 0 {
+		sort.Strings(names)
+		return nil, fmt.Errorf("%w: missing params %q of template %q", ErrInvalidTemplateParams, names, t.Name)
+	}
+	return values, nil
+}
+
+// expandTemplate returns a copy of the spec value with the "${param}"
+// placeholders replaced. The placeholders of unknown params (e.g., of the
+// sidecar args) are kept.
+func expandTemplate(v interface{}, values map[string]interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[k] = expandTemplate(e, values)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(v))
+		for i, e := range v {
+			l[i] = expandTemplate(e, values)
+		}
+		return l
+	case string:
+		if m := templateParamRegexp.FindStringSubmatch(v); m != nil && m[0] == v {
+			if value, ok := values[m[1]]; ok {
+				return value
+			}
+			return v
+		}
+		return templateParamRegexp.ReplaceAllStringFunc(v, func(p string) string {
+			value, ok := values[templateParamRegexp.FindStringSubmatch(p)[1]]
+			if !ok {
+				return p
+			}
+			if s, ok := value.(string); ok {
+				return s
+			}
+			b, _ := json.Marshal(value)
+			return string(b)
+		})
+	}
+	return v
+}
@@ -0,0 +1,311 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	ErrInvalidTenants     = errors.New("invalid tenants file")
+	ErrTenantLimitReached = errors.New("tenant limit reached")
+	ErrServerBusy         = errors.New("server busy, it runs one cluster at a time")
+)
+
+// Tenant roles, each allowed the methods of the previous ones.
+const (
+	// reads the cluster status, health, URIs, configs, and logs
+	RoleViewer = "viewer"
+	// also adds, removes, and restarts the nodes, and changes the
+	// network (e.g., faults, time, blockchains)
+	RoleOperator = "operator"
+	// also starts and stops the cluster, and uploads files
+	RoleAdmin = "admin"
+)
+
+var roleLevels = map[string]int{
+	RoleViewer:   1,
+	RoleOperator: 2,
+	RoleAdmin:    3,
+}
+
+// Tenant is a user (or team) of a shared server, authenticated by its token.
+type Tenant struct {
+	Name  string `json:"name"`
+	Token string `json:"token"`
+	// role of the token, admin if empty
+	Role string `json:"role"`
+	// other tokens of the tenant, with their own roles (e.g., the
+	// read-only tokens of the dashboards)
+	Tokens []TenantToken `json:"tokens"`
+	// maximum number of nodes of the tenant cluster, zero for no limit
+	MaxNodes uint32 `json:"maxNodes"`
+}
+
+// TenantToken is another token of a tenant, sharing its cluster.
+type TenantToken struct {
+	Token string `json:"token"`
+	// admin if empty
+	Role string `json:"role"`
+}
+
+// tenants maps the tokens to their tenants, with the role of the token.
+type tenants map[string]*Tenant
+
+// loadTenants reads the tenants file, a JSON list of tenants, and adds the
+// configured tenants. Tenancy is disabled if there are none.
+func loadTenants(path string, configured []Tenant) (tenants, error) {
+	if path == "" && len(configured) == 0 {
+		return nil, nil
+	}
+	var list []*Tenant
+	if path != "" {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(b, &list); err != nil {
+			return nil, fmt.Errorf("%w %q: %v", ErrInvalidTenants, path, err)
+		}
+	}
+	for i := range configured {
+		list = append(list, &configured[i])
+	}
+	ts := make(tenants, len(list))
+	names := make(map[string]struct{}, len(list))
+	for _, t := range list {
+		if t.Name == "" {
+			return nil, fmt.Errorf("%w %q: empty tenant name", ErrInvalidTenants, path)
+		}
+		// the name is part of the tenant data directory
+		if filepath.Base(t.Name) != t.Name || t.Name == "." || t.Name == ".." {
+			return nil, fmt.Errorf("%w %q: invalid tenant name %q", ErrInvalidTenants, path, t.Name)
+		}
+		if _, ok := names[t.Name]; ok {
+			return nil, fmt.Errorf("%w %q: duplicate tenant %q", ErrInvalidTenants, path, t.Name)
+		}
+		names[t.Name] = struct{}{}
+
+		tokens := append([]TenantToken{{Token: t.Token, Role: t.Role}}, t.Tokens...)
+		for _, tt := range tokens {
+			token, err := resolveSecret(tt.Token)
+			if err != nil {
+				return nil, fmt.Errorf("%w %q: tenant %q: %v", ErrInvalidTenants, path, t.Name, err)
+			}
+			if token == "" {
+				return nil, fmt.Errorf("%w %q: empty token of %q", ErrInvalidTenants, path, t.Name)
+			}
+			role := tt.Role
+			if role == "" {
+				role = RoleAdmin
+			}
+			if _, ok := roleLevels[role]; !ok {
+				return nil, fmt.Errorf("%w %q: tenant %q: invalid role %q (expected viewer, operator, or admin)", ErrInvalidTenants, path, t.Name, tt.Role)
+			}
+			if _, ok := ts[token]; ok {
+				return nil, fmt.Errorf("%w %q: duplicate token of %q", ErrInvalidTenants, path, t.Name)
+			}
+			// each token authenticates as the tenant, with its own role
+			ct := *t
+			ct.Token, ct.Role, ct.Tokens = token, role, nil
+			ts[token] = &ct
+		}
+	}
+	zap.L().Info("loaded tenants", zap.Int("tenants", len(names)), zap.Int("tokens", len(ts)))
+	return ts, nil
+}
+
+type tenantKey struct{}
+
+// tenantFromContext returns the authenticated tenant, nil if tenancy is disabled.
+func tenantFromContext(ctx context.Context) *Tenant {
+	t, _ := ctx.Value(tenantKey{}).(*Tenant)
+	return t
+}
+
+// tenantName returns the name of the authenticated tenant, empty if tenancy
+// is disabled.
+func tenantName(ctx context.Context) string {
+	if t := tenantFromContext(ctx); t != nil {
+		return t.Name
+	}
+	return ""
+}
+
+// authenticate returns the tenant of the "authorization: Bearer <token>"
+// metadata, which the gRPC gateway forwards from the HTTP header.
+func (ts tenants) authenticate(ctx context.Context) (*Tenant, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, v := range md.Get("authorization") {
+		token := strings.TrimSpace(strings.TrimPrefix(v, "Bearer "))
+		if t, ok := ts[token]; ok {
+			return t, nil
+		}
+	}
+	return nil, status.Error(codes.Unauthenticated, "missing or unknown tenant token")
+}
+
+// requiresTenant returns true for the control service methods,
+// leaving ping, health, and reflection open for probes.
+func requiresTenant(fullMethod string) bool {
+	return strings.HasPrefix(fullMethod, "/"+rpcpb.ControlService_ServiceDesc.ServiceName+"/")
+}
+
+// isClusterMethod returns true if the method reads or changes the
+// running cluster, which only its owner may see.
+func isClusterMethod(fullMethod string) bool {
+	prefix := "/" + rpcpb.ControlService_ServiceDesc.ServiceName + "/"
+	switch strings.TrimPrefix(fullMethod, prefix) {
+	case "Start", "CheckBinary", "EstimateResources", "GCNow":
+		return false
+	}
+	return true
+}
+
+// viewerMethods only read the cluster, or the server.
+var viewerMethods = map[string]bool{
+	"Health":            true,
+	"WaitNodesHealthy":  true,
+	"URIs":              true,
+	"Status":            true,
+	"StreamStatus":      true,
+	"AttachConsole":     true,
+	"GetValidators":     true,
+	"RenderTopology":    true,
+	"GetNodeConfig":     true,
+	"EstimateResources": true,
+	"SearchLogs":        true,
+	"GetSyncProgress":   true,
+	"DownloadLogs":      true,
+	"GetRunManifest":    true,
+	"GetGenesis":        true,
+	"GetNetworkParams":  true,
+	"ListChainIPCs":     true,
+	"TailChainIPC":      true,
+}
+
+// operatorMethods change the running cluster (e.g., the node lifecycle).
+var operatorMethods = map[string]bool{
+	"AddNode":              true,
+	"RemoveNode":           true,
+	"RemoveNodes":          true,
+	"RestartNode":          true,
+	"RestartNodes":         true,
+	"RotateNodeKey":        true,
+	"StopValidatingSubnet": true,
+	"CreateBlockchain":     true,
+	"CreateChainIPC":       true,
+	"RemoveChainIPC":       true,
+	"InjectFault":          true,
+	"SetDiskFault":         true,
+	"BlockTraffic":         true,
+	"UnblockTraffic":       true,
+	"SetTime":              true,
+	"AdvanceTime":          true,
+}
+
+// methodRole returns the minimum role of the control method, admin for
+// the others (e.g., start, stop, and upload).
+func methodRole(method string) string {
+	switch {
+	case viewerMethods[method]:
+		return RoleViewer
+	case operatorMethods[method]:
+		return RoleOperator
+	}
+	return RoleAdmin
+}
+
+// allows returns true if the token role is at least the role.
+func (t *Tenant) allows(role string) bool {
+	return roleLevels[t.Role] >= roleLevels[role]
+}
+
+// authorize authenticates the caller, checks the role of its token, and
+// hides the cluster of another tenant as if no cluster was running.
+func (s *server) authorize(ctx context.Context, fullMethod string) (context.Context, error) {
+	if s.tenants == nil || !requiresTenant(fullMethod) {
+		return ctx, nil
+	}
+	t, err := s.tenants.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	method := strings.TrimPrefix(fullMethod, "/"+rpcpb.ControlService_ServiceDesc.ServiceName+"/")
+	if role := methodRole(method); !t.allows(role) {
+		return nil, status.Errorf(codes.PermissionDenied, "%s requires the %s role, the tenant %q token is %s", method, role, t.Name, t.Role)
+	}
+	if isClusterMethod(fullMethod) {
+		s.mu.RLock()
+		owned := s.network == nil || s.network.opts.tenant == t.Name
+		s.mu.RUnlock()
+		if !owned {
+			return nil, ErrNotBootstrapped
+		}
+	}
+	return context.WithValue(ctx, tenantKey{}, t), nil
+}
+
+// startConflictLocked returns the error of a start while a cluster runs,
+// nil if none runs. The tenants other than the owner are only told that the
+// server is busy, as the server runs a single cluster for all tenants.
+// Must be called with the lock (read or write) held.
+func (s *server) startConflictLocked(ctx context.Context) error {
+	if s.network == nil {
+		return nil
+	}
+	if t := tenantFromContext(ctx); t != nil && s.network.opts.tenant != t.Name {
+		return ErrServerBusy
+	}
+	return ErrAlreadyBootstrapped
+}
+
+// checkOwnerLocked returns ErrNotBootstrapped, as "authorize" does, if the
+// cluster is of another tenant. The streams check it again once they look
+// up the cluster, as another tenant may have started its cluster since the
+// stream was authorized.
+// Must be called with the lock (read or write) held.
+func (s *server) checkOwnerLocked(ctx context.Context) error {
+	if t := tenantFromContext(ctx); t != nil && s.network != nil && s.network.opts.tenant != t.Name {
+		return ErrNotBootstrapped
+	}
+	return nil
+}
+
+func (s *server) unaryAuthInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := s.authorize(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (s *server) streamAuthInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := s.authorize(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &tenantStream{ServerStream: ss, ctx: ctx})
+}
+
+// tenantStream carries the authenticated tenant in the stream context.
+type tenantStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (ts *tenantStream) Context() context.Context { return ts.ctx }
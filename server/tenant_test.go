@@ -0,0 +1,99 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+)
+
+func TestStartConflict(t *testing.T) {
+	s := &server{}
+	owner := context.WithValue(context.Background(), tenantKey{}, &Tenant{Name: "a"})
+	other := context.WithValue(context.Background(), tenantKey{}, &Tenant{Name: "b"})
+	if err := s.startConflictLocked(other); err != nil {
+		t.Fatalf("expected no conflict, got %v", err)
+	}
+
+	s.network = &localNetwork{opts: networkOptions{tenant: "a"}}
+	for i, tv := range []struct {
+		ctx context.Context
+		err error
+	}{
+		{ctx: owner, err: ErrAlreadyBootstrapped},
+		{ctx: other, err: ErrServerBusy},
+		// tenancy disabled
+		{ctx: context.Background(), err: ErrAlreadyBootstrapped},
+	} {
+		if err := s.startConflictLocked(tv.ctx); !errors.Is(err, tv.err) {
+			t.Fatalf("#%d: expected %v, got %v", i, tv.err, err)
+		}
+	}
+}
+
+func TestCheckOwner(t *testing.T) {
+	s := &server{}
+	owner := context.WithValue(context.Background(), tenantKey{}, &Tenant{Name: "a"})
+	other := context.WithValue(context.Background(), tenantKey{}, &Tenant{Name: "b"})
+	if err := s.checkOwnerLocked(other); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// started by tenant "a" since the stream of tenant "b" was authorized
+	s.network = &localNetwork{opts: networkOptions{tenant: "a"}}
+	for i, tv := range []struct {
+		ctx context.Context
+		err error
+	}{
+		{ctx: owner},
+		{ctx: other, err: ErrNotBootstrapped},
+		// tenancy disabled
+		{ctx: context.Background()},
+	} {
+		if err := s.checkOwnerLocked(tv.ctx); !errors.Is(err, tv.err) {
+			t.Fatalf("#%d: expected %v, got %v", i, tv.err, err)
+		}
+	}
+}
+
+func TestFaucetAuthentication(t *testing.T) {
+	ts := tenants{
+		"viewer-token":   {Name: "a", Role: RoleViewer},
+		"operator-token": {Name: "a", Role: RoleOperator},
+	}
+	var asked []string
+	f := newFaucet(1, 0, nil, ts, func(tenant string) *rpcpb.ClusterInfo {
+		asked = append(asked, tenant)
+		return nil
+	})
+	for i, tv := range []struct {
+		token string
+		code  int
+	}{
+		{token: "", code: http.StatusUnauthorized},
+		{token: "unknown", code: http.StatusUnauthorized},
+		{token: "viewer-token", code: http.StatusForbidden},
+		// authorized, without a cluster
+		{token: "operator-token", code: http.StatusServiceUnavailable},
+	} {
+		r := httptest.NewRequest(http.MethodPost, "/v1/faucet", strings.NewReader(`{"chain":"X","address":"X-local1"}`))
+		if tv.token != "" {
+			r.Header.Set("Authorization", "Bearer "+tv.token)
+		}
+		w := httptest.NewRecorder()
+		f.ServeHTTP(w, r, nil)
+		if w.Code != tv.code {
+			t.Fatalf("#%d: expected %d, got %d (%s)", i, tv.code, w.Code, w.Body)
+		}
+	}
+	if len(asked) != 1 || asked[0] != "a" {
+		t.Fatalf("expected the cluster of tenant %q, got %q", "a", asked)
+	}
+}
@@ -0,0 +1,316 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/lasthyphen/djtx-tester/pkg/jsonrpc"
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+	"go.uber.org/zap"
+)
+
+// ID of the primary network subnet
+const primaryNetworkID = "11111111111111111111111111111111LpoYY"
+
+var ErrInvalidTopologyFormat = errors.New("invalid topology format")
+
+// topology is the snapshot of the network rendered by "RenderTopology".
+type topology struct {
+	// sorted by name
+	nodes []topologyNode
+	// sorted by ID, the primary network first
+	subnets []topologySubnet
+}
+
+type topologyNode struct {
+	name   string
+	nodeID string
+	// node IDs of the connected peers
+	peers []string
+}
+
+type topologySubnet struct {
+	id     string
+	chains []topologyChain
+	// node IDs of the current validators
+	validators []string
+}
+
+type topologyChain struct {
+	id   string
+	name string
+}
+
+// RenderTopology renders the nodes, their peer connections, the subnets
+// with their chains, and the subnet validators, as fetched from the nodes.
+func (s *server) RenderTopology(ctx context.Context, req *rpcpb.RenderTopologyRequest) (*rpcpb.RenderTopologyResponse, error) {
+	zap.L().Info("received render topology request", zap.String("format", req.Format.String()))
+	var render func(*topology) string
+	switch req.Format {
+	case rpcpb.TopologyFormat_TOPOLOGY_FORMAT_UNSPECIFIED, rpcpb.TopologyFormat_TOPOLOGY_FORMAT_DOT:
+		render = renderDOT
+	case rpcpb.TopologyFormat_TOPOLOGY_FORMAT_MERMAID:
+		render = renderMermaid
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrInvalidTopologyFormat, req.Format)
+	}
+
+	s.mu.RLock()
+	if err := s.checkStateLocked(stateHealthy, stateDegraded); err != nil {
+		s.mu.RUnlock()
+		return nil, err
+	}
+	nodes := make([]topologyNode, 0, len(s.network.nodeInfos))
+	uris := make(map[string]string, len(s.network.nodeInfos))
+	for name, info := range s.network.nodeInfos {
+		nodes = append(nodes, topologyNode{name: name, nodeID: info.Id})
+		uris[name] = info.Uri
+	}
+	s.mu.RUnlock()
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].name < nodes[j].name })
+
+	t, err := fetchTopology(ctx, nodes, uris)
+	if err != nil {
+		return nil, err
+	}
+	return &rpcpb.RenderTopologyResponse{Graph: render(t)}, nil
+}
+
+func fetchTopology(ctx context.Context, nodes []topologyNode, uris map[string]string) (*topology, error) {
+	names := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		if uris[n.name] == "" {
+			return nil, fmt.Errorf("%w: %q", ErrNoNodeURI, n.name)
+		}
+		names = append(names, n.name)
+	}
+	if len(names) == 0 {
+		return nil, ErrNoNodeURI
+	}
+
+	var (
+		mu    sync.Mutex
+		peers = make(map[string][]string, len(nodes))
+	)
+	errs := runParallel(ctx, names, 0, func(name string) error {
+		// ref. api/info "Peers"
+		var reply struct {
+			Peers []struct {
+				NodeID string `json:"nodeID"`
+			} `json:"peers"`
+		}
+		if err := jsonrpc.Call(ctx, uris[name], "/ext/info", "info.peers", nil, &reply); err != nil {
+			return err
+		}
+		ids := make([]string, 0, len(reply.Peers))
+		for _, p := range reply.Peers {
+			ids = append(ids, p.NodeID)
+		}
+		sort.Strings(ids)
+		mu.Lock()
+		peers[name] = ids
+		mu.Unlock()
+		return nil
+	})
+	for i := range nodes {
+		if err := errs[nodes[i].name]; err != nil {
+			return nil, fmt.Errorf("failed to get the peers of %q: %w", nodes[i].name, err)
+		}
+		nodes[i].peers = peers[nodes[i].name]
+	}
+
+	// the P-chain of any node has the subnets and chains of the network
+	uri := uris[names[0]]
+	// ref. platformvm "GetSubnets"
+	var subnetsReply struct {
+		Subnets []struct {
+			ID string `json:"id"`
+		} `json:"subnets"`
+	}
+	if err := jsonrpc.Call(ctx, uri, "/ext/bc/P", "platform.getSubnets", map[string]interface{}{}, &subnetsReply); err != nil {
+		return nil, err
+	}
+	// ref. platformvm "GetBlockchains"
+	var chainsReply struct {
+		Blockchains []struct {
+			ID       string `json:"id"`
+			Name     string `json:"name"`
+			SubnetID string `json:"subnetID"`
+		} `json:"blockchains"`
+	}
+	if err := jsonrpc.Call(ctx, uri, "/ext/bc/P", "platform.getBlockchains", nil, &chainsReply); err != nil {
+		return nil, err
+	}
+
+	subnets := map[string]*topologySubnet{primaryNetworkID: {id: primaryNetworkID}}
+	for _, sn := range subnetsReply.Subnets {
+		if _, ok := subnets[sn.ID]; !ok {
+			subnets[sn.ID] = &topologySubnet{id: sn.ID}
+		}
+	}
+	for _, c := range chainsReply.Blockchains {
+		sn, ok := subnets[c.SubnetID]
+		if !ok {
+			sn = &topologySubnet{id: c.SubnetID}
+			subnets[c.SubnetID] = sn
+		}
+		sn.chains = append(sn.chains, topologyChain{id: c.ID, name: c.Name})
+	}
+
+	t := &topology{nodes: nodes}
+	for _, sn := range subnets {
+		// ref. platformvm "GetCurrentValidators"
+		var reply struct {
+			Validators []apiValidator `json:"validators"`
+		}
+		params := map[string]interface{}{"subnetID": sn.id}
+		if err := jsonrpc.Call(ctx, uri, "/ext/bc/P", "platform.getCurrentValidators", params, &reply); err != nil {
+			return nil, fmt.Errorf("failed to get the validators of %q: %w", sn.id, err)
+		}
+		for _, v := range reply.Validators {
+			sn.validators = append(sn.validators, v.NodeID)
+		}
+		sort.Strings(sn.validators)
+		sort.Slice(sn.chains, func(i, j int) bool { return sn.chains[i].name < sn.chains[j].name })
+		t.subnets = append(t.subnets, *sn)
+	}
+	sort.Slice(t.subnets, func(i, j int) bool {
+		if t.subnets[i].id == primaryNetworkID || t.subnets[j].id == primaryNetworkID {
+			return t.subnets[i].id == primaryNetworkID
+		}
+		return t.subnets[i].id < t.subnets[j].id
+	})
+	return t, nil
+}
+
+// graphIDs assigns the graph vertex IDs: node names for the network nodes,
+// and "peerN" for the connected nodes outside of the network.
+func (t *topology) graphIDs() (nodeIDs map[string]string, externals []string) {
+	nodeIDs = make(map[string]string, len(t.nodes))
+	for _, n := range t.nodes {
+		nodeIDs[n.nodeID] = n.name
+	}
+	for _, n := range t.nodes {
+		for _, p := range n.peers {
+			if _, ok := nodeIDs[p]; !ok {
+				nodeIDs[p] = fmt.Sprintf("peer%d", len(externals)+1)
+				externals = append(externals, p)
+			}
+		}
+	}
+	for _, sn := range t.subnets {
+		for _, v := range sn.validators {
+			if _, ok := nodeIDs[v]; !ok {
+				nodeIDs[v] = fmt.Sprintf("peer%d", len(externals)+1)
+				externals = append(externals, v)
+			}
+		}
+	}
+	return nodeIDs, externals
+}
+
+// peerEdges returns the peer connections once per pair, as reported by either side.
+func (t *topology) peerEdges(nodeIDs map[string]string) [][2]string {
+	seen := make(map[[2]string]struct{})
+	edges := make([][2]string, 0)
+	for _, n := range t.nodes {
+		for _, p := range n.peers {
+			e := [2]string{n.name, nodeIDs[p]}
+			if e[0] > e[1] {
+				e[0], e[1] = e[1], e[0]
+			}
+			if _, ok := seen[e]; ok {
+				continue
+			}
+			seen[e] = struct{}{}
+			edges = append(edges, e)
+		}
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i][0] != edges[j][0] {
+			return edges[i][0] < edges[j][0]
+		}
+		return edges[i][1] < edges[j][1]
+	})
+	return edges
+}
+
+func subnetLabel(id string) string {
+	if id == primaryNetworkID {
+		return "primary network"
+	}
+	return "subnet " + id
+}
+
+func renderDOT(t *topology) string {
+	nodeIDs, externals := t.graphIDs()
+	var sb strings.Builder
+	sb.WriteString("digraph topology {\n")
+	sb.WriteString("  rankdir=LR;\n")
+	// for the validator edges to the subnet clusters
+	sb.WriteString("  compound=true;\n")
+	for _, n := range t.nodes {
+		fmt.Fprintf(&sb, "  %q [shape=box, label=%q];\n", n.name, n.name+"\n"+n.nodeID)
+	}
+	for _, id := range externals {
+		fmt.Fprintf(&sb, "  %q [shape=box, style=dashed, label=%q];\n", nodeIDs[id], id)
+	}
+	for i, sn := range t.subnets {
+		fmt.Fprintf(&sb, "  subgraph \"cluster_subnet%d\" {\n", i)
+		fmt.Fprintf(&sb, "    label=%q;\n", subnetLabel(sn.id))
+		fmt.Fprintf(&sb, "    \"subnet%d\" [shape=point];\n", i)
+		for _, c := range sn.chains {
+			fmt.Fprintf(&sb, "    %q [shape=ellipse, label=%q];\n", "chain "+c.id, c.name+"\n"+c.id)
+		}
+		sb.WriteString("  }\n")
+	}
+	for _, e := range t.peerEdges(nodeIDs) {
+		fmt.Fprintf(&sb, "  %q -> %q [dir=none];\n", e[0], e[1])
+	}
+	for i, sn := range t.subnets {
+		for _, v := range sn.validators {
+			fmt.Fprintf(&sb, "  %q -> \"subnet%d\" [style=dashed, lhead=\"cluster_subnet%d\", label=\"validates\"];\n", nodeIDs[v], i, i)
+		}
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+func renderMermaid(t *topology) string {
+	nodeIDs, externals := t.graphIDs()
+	var sb strings.Builder
+	sb.WriteString("graph LR\n")
+	for _, n := range t.nodes {
+		fmt.Fprintf(&sb, "  %s[\"%s<br/>%s\"]\n", n.name, n.name, n.nodeID)
+	}
+	for _, id := range externals {
+		fmt.Fprintf(&sb, "  %s([\"%s\"])\n", nodeIDs[id], id)
+	}
+	for i, sn := range t.subnets {
+		fmt.Fprintf(&sb, "  subgraph subnet%d[\"%s\"]\n", i, subnetLabel(sn.id))
+		// mermaid renders an empty subgraph as a vertex
+		if len(sn.chains) == 0 {
+			fmt.Fprintf(&sb, "    subnet%dnochain[\"no chains\"]\n", i)
+		}
+		for j, c := range sn.chains {
+			fmt.Fprintf(&sb, "    subnet%dchain%d([\"%s<br/>%s\"])\n", i, j, c.name, c.id)
+		}
+		sb.WriteString("  end\n")
+	}
+	for _, e := range t.peerEdges(nodeIDs) {
+		fmt.Fprintf(&sb, "  %s --- %s\n", e[0], e[1])
+	}
+	for i, sn := range t.subnets {
+		for _, v := range sn.validators {
+			fmt.Fprintf(&sb, "  %s -.->|validates| subnet%d\n", nodeIDs[v], i)
+		}
+	}
+	return sb.String()
+}
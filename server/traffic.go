@@ -0,0 +1,189 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrInvalidTrafficDirection = errors.New("invalid traffic direction")
+	ErrSameNode                = errors.New("same node")
+	ErrTrafficRulesUnsupported = errors.New("traffic rules are not supported on this platform")
+)
+
+// trafficRule blocks the staking connections from a node to another.
+type trafficRule struct {
+	from string
+	to   string
+}
+
+// BlockTraffic blocks the staking connections between two nodes, in both
+// directions (ref. "checkTrafficDirection"), with a firewall rule per
+// dialing node, matching its cgroup and the staking port of the other.
+// The open connections are reset.
+func (s *server) BlockTraffic(ctx context.Context, req *rpcpb.BlockTrafficRequest) (*rpcpb.BlockTrafficResponse, error) {
+	zap.L().Info("received block traffic request",
+		zap.String("nodeA", req.NodeA),
+		zap.String("nodeB", req.NodeB),
+		zap.String("direction", req.Direction.String()),
+	)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rules, err := s.trafficRulesLocked(req.NodeA, req.NodeB, req.Direction)
+	if err != nil {
+		return nil, err
+	}
+	if s.network.opts.supervisor == SupervisorSystemd {
+		return nil, ErrSupervisedCgroups
+	}
+	for _, r := range rules {
+		if err := s.network.blockTraffic(ctx, r); err != nil {
+			return nil, fmt.Errorf("failed to block the traffic from %q to %q: %w", r.from, r.to, err)
+		}
+	}
+	return &rpcpb.BlockTrafficResponse{ClusterInfo: s.copyClusterInfo(), Rules: s.network.listTrafficRules()}, nil
+}
+
+// UnblockTraffic removes the rules of BlockTraffic between two nodes.
+func (s *server) UnblockTraffic(ctx context.Context, req *rpcpb.UnblockTrafficRequest) (*rpcpb.UnblockTrafficResponse, error) {
+	zap.L().Info("received unblock traffic request",
+		zap.String("nodeA", req.NodeA),
+		zap.String("nodeB", req.NodeB),
+		zap.String("direction", req.Direction.String()),
+	)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rules, err := s.trafficRulesLocked(req.NodeA, req.NodeB, req.Direction)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range rules {
+		if err := s.network.unblockTraffic(r); err != nil {
+			return nil, fmt.Errorf("failed to unblock the traffic from %q to %q: %w", r.from, r.to, err)
+		}
+	}
+	return &rpcpb.UnblockTrafficResponse{ClusterInfo: s.copyClusterInfo(), Rules: s.network.listTrafficRules()}, nil
+}
+
+// trafficRulesLocked returns the rules of the request.
+// Must be called with the lock held.
+func (s *server) trafficRulesLocked(nodeA string, nodeB string, dir rpcpb.TrafficDirection) ([]trafficRule, error) {
+	if err := s.checkStateLocked(stateHealthy, stateDegraded); err != nil {
+		return nil, err
+	}
+	for _, name := range []string{nodeA, nodeB} {
+		if _, ok := s.network.nodeInfos[name]; !ok {
+			return nil, fmt.Errorf("%w: %q", ErrNodeNotFound, name)
+		}
+	}
+	if nodeA == nodeB {
+		return nil, fmt.Errorf("%w: %q", ErrSameNode, nodeA)
+	}
+	if err := checkTrafficDirection(dir); err != nil {
+		return nil, err
+	}
+	return []trafficRule{{from: nodeA, to: nodeB}, {from: nodeB, to: nodeA}}, nil
+}
+
+// trafficChain is the firewall chain of the network rules,
+// unique per network (and at most 28 characters).
+func trafficChain(rootDataDir string) string {
+	return fmt.Sprintf("NR-%x", sha256.Sum256([]byte(rootDataDir)))[:19]
+}
+
+func (lc *localNetwork) blockTraffic(ctx context.Context, r trafficRule) error {
+	if _, ok := lc.trafficRules[r]; ok {
+		return nil
+	}
+	// the rule matches the dialing node by its cgroup
+	if !lc.contained[r.from] {
+		lc.contained[r.from] = true
+		if err := lc.limitNodes(ctx, []string{r.from}); err != nil {
+			delete(lc.contained, r.from)
+			return err
+		}
+	}
+	chain := trafficChain(lc.opts.rootDataDir)
+	cgroup := nodeCgroupName(lc.opts.rootDataDir, r.from)
+	if err := addTrafficRule(chain, cgroup, lc.nodeInfos[r.to].StakingPort); err != nil {
+		return err
+	}
+	lc.trafficRules[r] = struct{}{}
+	zap.L().Info("blocked traffic", zap.String("from", r.from), zap.String("to", r.to), zap.String("chain", chain))
+	return nil
+}
+
+func (lc *localNetwork) unblockTraffic(r trafficRule) error {
+	if _, ok := lc.trafficRules[r]; !ok {
+		return nil
+	}
+	chain := trafficChain(lc.opts.rootDataDir)
+	cgroup := nodeCgroupName(lc.opts.rootDataDir, r.from)
+	if err := deleteTrafficRule(chain, cgroup, lc.nodeInfos[r.to].StakingPort); err != nil {
+		return err
+	}
+	delete(lc.trafficRules, r)
+	zap.L().Info("unblocked traffic", zap.String("from", r.from), zap.String("to", r.to))
+	return nil
+}
+
+// rekeyTrafficRules moves the rules to the node onto its new staking port,
+// once its ports are reassigned (ref. "reassignPorts"), as the rules match
+// the port rather than the node.
+func (lc *localNetwork) rekeyTrafficRules(name string, oldPort uint32) error {
+	newPort := lc.nodeInfos[name].StakingPort
+	if newPort == oldPort {
+		return nil
+	}
+	chain := trafficChain(lc.opts.rootDataDir)
+	for r := range lc.trafficRules {
+		if r.to != name {
+			continue
+		}
+		cgroup := nodeCgroupName(lc.opts.rootDataDir, r.from)
+		if err := deleteTrafficRule(chain, cgroup, oldPort); err != nil {
+			zap.L().Warn("failed to remove the traffic rule of the old port", zap.String("from", r.from), zap.String("to", r.to), zap.Error(err))
+		}
+		if err := addTrafficRule(chain, cgroup, newPort); err != nil {
+			return fmt.Errorf("failed to block the traffic from %q to %q: %w", r.from, r.to, err)
+		}
+		zap.L().Info("moved traffic rule", zap.String("from", r.from), zap.String("to", r.to), zap.Uint32("stakingPort", newPort))
+	}
+	return nil
+}
+
+// clearTrafficRules removes the firewall chain of the network, if any rule was added.
+func (lc *localNetwork) clearTrafficRules() {
+	if len(lc.contained) == 0 {
+		return
+	}
+	if err := deleteTrafficChain(trafficChain(lc.opts.rootDataDir)); err != nil {
+		zap.L().Warn("failed to remove the traffic rules", zap.Error(err))
+	}
+	lc.trafficRules = make(map[trafficRule]struct{})
+}
+
+func (lc *localNetwork) listTrafficRules() []*rpcpb.TrafficRule {
+	rules := make([]*rpcpb.TrafficRule, 0, len(lc.trafficRules))
+	for r := range lc.trafficRules {
+		rules = append(rules, &rpcpb.TrafficRule{From: r.from, To: r.to})
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		if rules[i].From != rules[j].From {
+			return rules[i].From < rules[j].From
+		}
+		return rules[i].To < rules[j].To
+	})
+	return rules
+}
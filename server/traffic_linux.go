@@ -0,0 +1,83 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+//go:build linux
+// +build linux
+
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// trafficRuleArgs rejects the loopback TCP packets sent from the cgroup to the
+// staking port, resetting the connections the node dialed (the "cgroup" match
+// is of the sending socket, and the node accepts its inbound connections on
+// its own staking port).
+func trafficRuleArgs(chain string, cgroup string, port uint32) []string {
+	return []string{
+		chain,
+		"-o", "lo",
+		"-p", "tcp",
+		"--dport", strconv.FormatUint(uint64(port), 10),
+		"-m", "cgroup", "--path", cgroup,
+		"-j", "REJECT", "--reject-with", "tcp-reset",
+	}
+}
+
+// addTrafficRule adds the rule to the chain, creating the chain
+// and its jump from the output chain first.
+// Requires iptables with the cgroup match (e.g., root privileges).
+func addTrafficRule(chain string, cgroup string, port uint32) error {
+	if err := iptables("-n", "-L", chain); err != nil {
+		if err := iptables("-N", chain); err != nil {
+			return err
+		}
+	}
+	if err := iptables("-C", "OUTPUT", "-j", chain); err != nil {
+		if err := iptables("-I", "OUTPUT", "-j", chain); err != nil {
+			return err
+		}
+	}
+	return iptables(append([]string{"-A"}, trafficRuleArgs(chain, cgroup, port)...)...)
+}
+
+func deleteTrafficRule(chain string, cgroup string, port uint32) error {
+	return iptables(append([]string{"-D"}, trafficRuleArgs(chain, cgroup, port)...)...)
+}
+
+// deleteTrafficChain removes the chain and its jump, if any.
+func deleteTrafficChain(chain string) error {
+	if err := iptables("-n", "-L", chain); err != nil {
+		return nil
+	}
+	// the jump may have been added more than once
+	for {
+		if err := iptables("-D", "OUTPUT", "-j", chain); err != nil {
+			break
+		}
+	}
+	if err := iptables("-F", chain); err != nil {
+		return err
+	}
+	return iptables("-X", chain)
+}
+
+func iptables(args ...string) error {
+	path, err := exec.LookPath("iptables")
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrTrafficRulesUnsupported, err)
+	}
+	// waits for the xtables lock, held by the other iptables processes
+	cmd := exec.Command(path, append([]string{"-w"}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("iptables %s: %w (%s)", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+//go:build !linux
+// +build !linux
+
+package server
+
+func addTrafficRule(chain string, cgroup string, port uint32) error {
+	return ErrTrafficRulesUnsupported
+}
+
+func deleteTrafficRule(chain string, cgroup string, port uint32) error {
+	return ErrTrafficRulesUnsupported
+}
+
+func deleteTrafficChain(chain string) error {
+	return nil
+}
@@ -0,0 +1,114 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrInvalidUploadPath = errors.New("invalid upload path")
+	ErrChecksumMismatch  = errors.New("checksum mismatch")
+)
+
+// UploadFile writes the streamed file into the upload directory (of the tenant,
+// if any), so remote clients can push binaries, VM plugins, or genesis files.
+// The file is only moved into place once its checksum matches.
+func (s *server) UploadFile(stream rpcpb.ControlService_UploadFileServer) error {
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	zap.L().Info("received upload file request", zap.String("path", first.Path), zap.String("sha256", first.Sha256))
+
+	dir := s.cfg.UploadDir
+	if t := tenantFromContext(stream.Context()); t != nil {
+		dir = filepath.Join(dir, t.Name)
+	}
+	path, err := uploadPath(dir, first.Path)
+	if err != nil {
+		return err
+	}
+	want, err := hex.DecodeString(first.Sha256)
+	if err != nil || len(want) != sha256.Size {
+		return fmt.Errorf("%w: invalid SHA-256 %q", ErrChecksumMismatch, first.Sha256)
+	}
+	mode := os.FileMode(first.Mode).Perm()
+	if mode == 0 {
+		mode = 0o644
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".upload-")
+	if err != nil {
+		return err
+	}
+	// removes the partial upload on failure
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	h := sha256.New()
+	w := io.MultiWriter(f, h)
+	size := uint64(0)
+	for req := first; ; {
+		n, err := w.Write(req.Chunk)
+		if err != nil {
+			return err
+		}
+		size += uint64(n)
+
+		req, err = stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if got := h.Sum(nil); !bytes.Equal(got, want) {
+		return fmt.Errorf("%w: got %x, expected %x", ErrChecksumMismatch, got, want)
+	}
+	if err := f.Chmod(mode); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return err
+	}
+	zap.L().Info("uploaded file", zap.String("path", path), zap.Uint64("size", size))
+	return stream.SendAndClose(&rpcpb.UploadFileResponse{Path: path, Size: size, Sha256: hex.EncodeToString(want)})
+}
+
+// uploadPath returns the absolute path of the upload in the directory,
+// rejecting the paths outside of it.
+func uploadPath(dir string, rel string) (string, error) {
+	if rel == "" || filepath.IsAbs(rel) {
+		return "", fmt.Errorf("%w: %q (expected a relative path)", ErrInvalidUploadPath, rel)
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, rel)
+	if !strings.HasPrefix(path, dir+string(filepath.Separator)) {
+		return "", fmt.Errorf("%w: %q is outside of the upload directory", ErrInvalidUploadPath, rel)
+	}
+	return path, nil
+}
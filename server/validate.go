@@ -0,0 +1,313 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lasthyphen/dijetsnodego/ids"
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+	"google.golang.org/grpc"
+)
+
+var (
+	ErrEmptyExecPath   = errors.New("empty exec path")
+	ErrInvalidSubnetID = errors.New("invalid subnet ID")
+	ErrInvalidID       = errors.New("invalid ID")
+)
+
+// fieldError is an invalid field of a request, by its path (e.g.,
+// "start_request.exec_path"), returned as "InvalidArgument" with the path
+// as a "BadRequest" field violation.
+type fieldError struct {
+	path string
+	err  error
+}
+
+func (e *fieldError) Error() string { return e.path + ": " + e.err.Error() }
+
+func (e *fieldError) Unwrap() error { return e.err }
+
+func invalidField(path string, err error) error {
+	return &fieldError{path: path, err: err}
+}
+
+// validateRequest checks the fields of the request that are invalid
+// regardless of the network (e.g., an empty exec path, a malformed subnet
+// ID, or a non-positive interval), before the request is handled. The checks
+// that depend on the network (e.g., an unknown node name) are left to the
+// handlers.
+func validateRequest(req interface{}) error {
+	switch r := req.(type) {
+	case *rpcpb.StartRequest:
+		// the exec path may be of the template
+		return validateStartRequest("", r, r.Template == nil)
+	case *rpcpb.EstimateResourcesRequest:
+		if r.Spec == nil {
+			return nil
+		}
+		return validateStartRequest("spec.", r.Spec, false)
+	case *rpcpb.CheckBinaryRequest:
+		if r.ExecPath == "" {
+			return invalidField("exec_path", ErrEmptyExecPath)
+		}
+	case *rpcpb.WaitNodesHealthyRequest:
+		if err := checkNodeNames(r.Names); err != nil {
+			return invalidField("names", err)
+		}
+		if r.Timeout != nil {
+			if err := checkDuration(r.GetTimeout()); err != nil {
+				return invalidField("timeout", err)
+			}
+		}
+	case *rpcpb.StreamStatusRequest:
+		if r.PushInterval <= 0 {
+			return invalidField("push_interval", fmt.Errorf("%w: non-positive push interval %d", ErrInvalidDuration, r.PushInterval))
+		}
+	case *rpcpb.RemoveNodeRequest:
+		return checkNodeName("name", r.Name)
+	case *rpcpb.RestartNodeRequest:
+		if err := checkNodeName("name", r.Name); err != nil {
+			return err
+		}
+		return validateRestartRequest("start_request.", r.StartRequest)
+	case *rpcpb.RemoveNodesRequest:
+		if err := checkNodeNames(r.Names); err != nil {
+			return invalidField("names", err)
+		}
+	case *rpcpb.RestartNodesRequest:
+		if err := checkNodeNames(r.Names); err != nil {
+			return invalidField("names", err)
+		}
+		return validateRestartRequest("start_request.", r.StartRequest)
+	case *rpcpb.AddNodeRequest:
+		return checkNodeName("name", r.Name)
+	case *rpcpb.RotateNodeKeyRequest:
+		return checkNodeName("name", r.Name)
+	case *rpcpb.GetNodeConfigRequest:
+		return checkNodeName("name", r.Name)
+	case *rpcpb.GetSyncProgressRequest:
+		return checkNodeName("name", r.Name)
+	case *rpcpb.InjectFaultRequest:
+		return checkNodeName("node_name", r.NodeName)
+	case *rpcpb.SetDiskFaultRequest:
+		if err := checkNodeName("node_name", r.NodeName); err != nil {
+			return err
+		}
+		if _, err := parseDiskFault(r.Fault); err != nil {
+			return invalidField("fault", err)
+		}
+	case *rpcpb.DownloadLogsRequest:
+		return checkNodeName("node_name", r.NodeName)
+	case *rpcpb.CreateChainIPCRequest:
+		return checkID("blockchain_id", r.BlockchainId, ErrEmptyBlockchainID)
+	case *rpcpb.RemoveChainIPCRequest:
+		return checkID("blockchain_id", r.BlockchainId, ErrEmptyBlockchainID)
+	case *rpcpb.TailChainIPCRequest:
+		if err := checkNodeName("node_name", r.NodeName); err != nil {
+			return err
+		}
+		// as published, so the aliases (e.g., "X") are not parsed as IDs
+		if r.BlockchainId == "" {
+			return invalidField("blockchain_id", ErrEmptyBlockchainID)
+		}
+	case *rpcpb.GetValidatorsRequest:
+		// the primary network if empty
+		if r.SubnetId != "" {
+			return checkID("subnet_id", r.SubnetId, ErrEmptySubnetID)
+		}
+	case *rpcpb.CreateBlockchainRequest:
+		if err := checkID("subnet_id", r.SubnetId, ErrEmptySubnetID); err != nil {
+			return err
+		}
+		if r.VmName == "" {
+			return invalidField("vm_name", fmt.Errorf("%w: empty", ErrInvalidVMName))
+		}
+	case *rpcpb.StopValidatingSubnetRequest:
+		if err := checkNodeName("name", r.Name); err != nil {
+			return err
+		}
+		return checkID("subnet_id", r.SubnetId, ErrEmptySubnetID)
+	case *rpcpb.AdvanceTimeRequest:
+		if err := checkDuration(r.Duration); err != nil {
+			return invalidField("duration", err)
+		}
+	case *rpcpb.GCNowRequest:
+		if r.MaxAge != nil {
+			if d, err := time.ParseDuration(r.GetMaxAge()); err != nil || d < 0 {
+				return invalidField("max_age", fmt.Errorf("%w: invalid max age %q", ErrInvalidGCPolicy, r.GetMaxAge()))
+			}
+		}
+	case *rpcpb.BlockTrafficRequest:
+		if err := checkNodePair(r.NodeA, r.NodeB); err != nil {
+			return err
+		}
+		return checkTrafficDirection(r.Direction)
+	case *rpcpb.UnblockTrafficRequest:
+		if err := checkNodePair(r.NodeA, r.NodeB); err != nil {
+			return err
+		}
+		return checkTrafficDirection(r.Direction)
+	}
+	return nil
+}
+
+// validateStartRequest checks the fields of the start request (of the path
+// prefix) that are parsed on start, so they fail before the network is
+// created. The exec path is optional for the resource estimates.
+func validateStartRequest(prefix string, req *rpcpb.StartRequest, needExecPath bool) error {
+	if needExecPath && req.ExecPath == "" {
+		return invalidField(prefix+"exec_path", ErrEmptyExecPath)
+	}
+	if err := checkSubnetIDs(prefix+"whitelisted_subnets", req.GetWhitelistedSubnets()); err != nil {
+		return err
+	}
+	if req.MaxUnhealthyDuration != nil {
+		if _, err := parseMaxUnhealthyDuration(req.MaxUnhealthyDuration, 0); err != nil {
+			return invalidField(prefix+"max_unhealthy_duration", err)
+		}
+	}
+	if _, err := parseLaunchInterval(req.LaunchInterval); err != nil {
+		return invalidField(prefix+"launch_interval", err)
+	}
+	if _, err := parseCheckpoints(req.CheckpointInterval, req.CheckpointRetention); err != nil {
+		path := prefix + "checkpoint_interval"
+		if errors.Is(err, ErrInvalidCheckpoint) {
+			path = prefix + "checkpoint_retention"
+		}
+		return invalidField(path, err)
+	}
+	if _, err := parseHealthPolling(req.HealthPolling); err != nil {
+		return invalidField(prefix+"health_polling", err)
+	}
+	if _, err := parseLivenessMonitor(req.LivenessMonitor); err != nil {
+		return invalidField(prefix+"liveness_monitor", err)
+	}
+	if _, err := nodeResourceLimits(req.ResourceLimits, nil); err != nil {
+		return invalidField(prefix+"resource_limits", err)
+	}
+	for name, limits := range req.NodeResourceLimits {
+		if _, err := nodeResourceLimits(req.ResourceLimits, limits); err != nil {
+			return invalidField(fmt.Sprintf("%snode_resource_limits[%q]", prefix, name), err)
+		}
+	}
+	if len(req.DiskFaultNodes) > 0 {
+		if err := checkNodeNames(req.DiskFaultNodes); err != nil {
+			return invalidField(prefix+"disk_fault_nodes", err)
+		}
+	}
+	if req.DiskFaultDeviceBytes != nil && req.GetDiskFaultDeviceBytes() < minDiskDeviceBytes {
+		return invalidField(prefix+"disk_fault_device_bytes", fmt.Errorf("%w: device of %d bytes, below %d", ErrInvalidDiskFault, req.GetDiskFaultDeviceBytes(), minDiskDeviceBytes))
+	}
+	if _, err := nodeDatabaseConfig(req.DatabaseConfig, nil); err != nil {
+		return invalidField(prefix+"database_config", err)
+	}
+	for name, db := range req.NodeDatabaseConfigs {
+		if _, err := nodeDatabaseConfig(req.DatabaseConfig, db); err != nil {
+			return invalidField(fmt.Sprintf("%snode_database_configs[%q]", prefix, name), err)
+		}
+	}
+	return nil
+}
+
+// validateRestartRequest checks the start request of a restart, which only
+// sets the binary and the whitelisted subnets of the nodes.
+func validateRestartRequest(prefix string, req *rpcpb.StartRequest) error {
+	if req.GetExecPath() == "" {
+		return invalidField(prefix+"exec_path", ErrEmptyExecPath)
+	}
+	return checkSubnetIDs(prefix+"whitelisted_subnets", req.GetWhitelistedSubnets())
+}
+
+func checkNodeName(path string, name string) error {
+	if name == "" {
+		return invalidField(path, ErrEmptyNodeName)
+	}
+	return nil
+}
+
+func checkNodePair(a string, b string) error {
+	if err := checkNodeName("node_a", a); err != nil {
+		return err
+	}
+	if err := checkNodeName("node_b", b); err != nil {
+		return err
+	}
+	if a == b {
+		return invalidField("node_b", fmt.Errorf("%w: %q", ErrSameNode, b))
+	}
+	return nil
+}
+
+// checkTrafficDirection only accepts both directions: the nodes keep their
+// peering over a connection dialed the other way, which carries the traffic
+// both ways, so a one-way rule would not block any traffic.
+func checkTrafficDirection(dir rpcpb.TrafficDirection) error {
+	switch dir {
+	case rpcpb.TrafficDirection_TRAFFIC_DIRECTION_UNSPECIFIED, rpcpb.TrafficDirection_TRAFFIC_DIRECTION_BOTH:
+		return nil
+	case rpcpb.TrafficDirection_TRAFFIC_DIRECTION_OUTBOUND, rpcpb.TrafficDirection_TRAFFIC_DIRECTION_INBOUND:
+		return invalidField("direction", fmt.Errorf("%w: %s is not supported, as the nodes would reconnect the other way (only both directions are blocked)", ErrInvalidTrafficDirection, dir))
+	}
+	return invalidField("direction", fmt.Errorf("%w: %s", ErrInvalidTrafficDirection, dir))
+}
+
+// checkID checks the ID field (e.g., a subnet ID), with the error of its
+// empty value.
+func checkID(path string, id string, empty error) error {
+	if id == "" {
+		return invalidField(path, empty)
+	}
+	if _, err := ids.FromString(id); err != nil {
+		return invalidField(path, fmt.Errorf("%w %q: %v", ErrInvalidID, id, err))
+	}
+	return nil
+}
+
+func checkSubnetIDs(path string, s string) error {
+	if err := validateSubnetIDs(s); err != nil {
+		return invalidField(path, err)
+	}
+	return nil
+}
+
+// checkDuration checks that the duration string is positive.
+func checkDuration(s string) error {
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return fmt.Errorf("%w: %q", ErrInvalidDuration, s)
+	}
+	return nil
+}
+
+// unaryValidationInterceptor rejects the invalid requests before their
+// handler. It runs after the authentication, so the unauthenticated
+// clients learn nothing of the requests.
+func unaryValidationInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := validateRequest(req); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func streamValidationInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return handler(srv, &validatingStream{ServerStream: ss})
+}
+
+// validatingStream validates the received requests, e.g., the request of
+// a server stream. The messages of the client streams (e.g., the console
+// input) have nothing to validate.
+type validatingStream struct {
+	grpc.ServerStream
+}
+
+func (s *validatingStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return validateRequest(m)
+}
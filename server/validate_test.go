@@ -0,0 +1,59 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+)
+
+func TestValidateTrafficDirection(t *testing.T) {
+	for i, tv := range []struct {
+		dir rpcpb.TrafficDirection
+		err error
+	}{
+		{dir: rpcpb.TrafficDirection_TRAFFIC_DIRECTION_UNSPECIFIED},
+		{dir: rpcpb.TrafficDirection_TRAFFIC_DIRECTION_BOTH},
+		{dir: rpcpb.TrafficDirection_TRAFFIC_DIRECTION_OUTBOUND, err: ErrInvalidTrafficDirection},
+		{dir: rpcpb.TrafficDirection_TRAFFIC_DIRECTION_INBOUND, err: ErrInvalidTrafficDirection},
+		{dir: rpcpb.TrafficDirection(42), err: ErrInvalidTrafficDirection},
+	} {
+		for _, req := range []interface{}{
+			&rpcpb.BlockTrafficRequest{NodeA: "node1", NodeB: "node2", Direction: tv.dir},
+			&rpcpb.UnblockTrafficRequest{NodeA: "node1", NodeB: "node2", Direction: tv.dir},
+		} {
+			err := validateRequest(req)
+			if !errors.Is(err, tv.err) {
+				t.Fatalf("#%d: expected %v, got %v", i, tv.err, err)
+			}
+			var fe *fieldError
+			if tv.err != nil && (!errors.As(err, &fe) || fe.path != "direction") {
+				t.Fatalf("#%d: expected a direction field error, got %v", i, err)
+			}
+		}
+	}
+}
+
+func TestValidateStreamStatus(t *testing.T) {
+	for i, tv := range []struct {
+		interval int64
+		err      error
+	}{
+		{interval: int64(time.Second)},
+		{interval: 0, err: ErrInvalidDuration},
+		{interval: -1, err: ErrInvalidDuration},
+	} {
+		err := validateRequest(&rpcpb.StreamStatusRequest{PushInterval: tv.interval})
+		if !errors.Is(err, tv.err) {
+			t.Fatalf("#%d: expected %v, got %v", i, tv.err, err)
+		}
+		var fe *fieldError
+		if tv.err != nil && (!errors.As(err, &fe) || fe.path != "push_interval") {
+			t.Fatalf("#%d: expected a push_interval field error, got %v", i, err)
+		}
+	}
+}
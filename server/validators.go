@@ -0,0 +1,154 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/lasthyphen/djtx-tester/pkg/jsonrpc"
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+	"go.uber.org/zap"
+)
+
+var ErrNoNodeURI = errors.New("no node URI available")
+
+// GetValidators returns the current and pending validators of the subnet,
+// as reported by the P-chain of a node.
+func (s *server) GetValidators(ctx context.Context, req *rpcpb.GetValidatorsRequest) (*rpcpb.GetValidatorsResponse, error) {
+	zap.L().Info("received get validators request",
+		zap.String("subnetID", req.SubnetId),
+		zap.String("nodeName", req.GetNodeName()),
+	)
+	if err := s.checkState(stateHealthy, stateDegraded); err != nil {
+		return nil, err
+	}
+	uri, err := s.nodeURI(req.GetNodeName())
+	if err != nil {
+		return nil, err
+	}
+
+	params := map[string]interface{}{}
+	if req.SubnetId != "" {
+		params["subnetID"] = req.SubnetId
+	}
+
+	// ref. platformvm "GetCurrentValidators"
+	var current struct {
+		Validators []apiValidator `json:"validators"`
+	}
+	if err := jsonrpc.Call(ctx, uri, "/ext/bc/P", "platform.getCurrentValidators", params, &current); err != nil {
+		return nil, err
+	}
+	// ref. platformvm "GetPendingValidators"
+	var pending struct {
+		Validators []apiValidator `json:"validators"`
+	}
+	if err := jsonrpc.Call(ctx, uri, "/ext/bc/P", "platform.getPendingValidators", params, &pending); err != nil {
+		return nil, err
+	}
+
+	resp := &rpcpb.GetValidatorsResponse{}
+	if resp.CurrentValidators, err = convertValidators(current.Validators); err != nil {
+		return nil, err
+	}
+	if resp.PendingValidators, err = convertValidators(pending.Validators); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// nodeURI returns the URI of the named node,
+// or the first node (by name) if the name is empty.
+func (s *server) nodeURI(name string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if name != "" {
+		info, ok := s.network.nodeInfos[name]
+		if !ok {
+			return "", ErrNodeNotFound
+		}
+		if info.Uri == "" {
+			return "", fmt.Errorf("%w: %q", ErrNoNodeURI, name)
+		}
+		return info.Uri, nil
+	}
+
+	names := make([]string, 0, len(s.network.nodeInfos))
+	for name, info := range s.network.nodeInfos {
+		if info.Uri != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return "", ErrNoNodeURI
+	}
+	sort.Strings(names)
+	return s.network.nodeInfos[names[0]].Uri, nil
+}
+
+// apiValidator is a validator as returned by the platformvm APIs,
+// with the numbers encoded as strings.
+type apiValidator struct {
+	TxID          string        `json:"txID"`
+	NodeID        string        `json:"nodeID"`
+	StartTime     string        `json:"startTime"`
+	EndTime       string        `json:"endTime"`
+	StakeAmount   string        `json:"stakeAmount"`
+	Weight        string        `json:"weight"`
+	DelegationFee string        `json:"delegationFee"`
+	Uptime        string        `json:"uptime"`
+	Connected     bool          `json:"connected"`
+	Delegators    []interface{} `json:"delegators"`
+}
+
+func convertValidators(vs []apiValidator) ([]*rpcpb.Validator, error) {
+	converted := make([]*rpcpb.Validator, 0, len(vs))
+	for _, v := range vs {
+		cv := &rpcpb.Validator{
+			TxId:            v.TxID,
+			NodeId:          v.NodeID,
+			DelegationFee:   v.DelegationFee,
+			Uptime:          v.Uptime,
+			Connected:       v.Connected,
+			DelegatorsCount: uint32(len(v.Delegators)),
+		}
+		var err error
+		if cv.StartTime, err = parseInt64(v.StartTime); err != nil {
+			return nil, fmt.Errorf("invalid start time of %q: %w", v.NodeID, err)
+		}
+		if cv.EndTime, err = parseInt64(v.EndTime); err != nil {
+			return nil, fmt.Errorf("invalid end time of %q: %w", v.NodeID, err)
+		}
+		if cv.StakeAmount, err = parseUint64(v.StakeAmount); err != nil {
+			return nil, fmt.Errorf("invalid stake amount of %q: %w", v.NodeID, err)
+		}
+		if cv.Weight, err = parseUint64(v.Weight); err != nil {
+			return nil, fmt.Errorf("invalid weight of %q: %w", v.NodeID, err)
+		}
+		converted = append(converted, cv)
+	}
+	sort.Slice(converted, func(i, j int) bool { return converted[i].NodeId < converted[j].NodeId })
+	return converted, nil
+}
+
+// parseInt64 parses a JSON-encoded number, zero if empty.
+func parseInt64(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(s, 10, 64)
+}
+
+// parseUint64 parses a JSON-encoded number, zero if empty.
+func parseUint64(s string) (uint64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
@@ -0,0 +1,131 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lasthyphen/dijetsnode-go-runner/network/node"
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+)
+
+var ErrNodesUnhealthy = errors.New("nodes unhealthy")
+
+// WaitNodesHealthy waits for the named nodes only to report healthy, e.g.,
+// the nodes just added or restarted, while other nodes may be broken on
+// purpose. Unlike "Health", it does not change the network state.
+func (s *server) WaitNodesHealthy(ctx context.Context, req *rpcpb.WaitNodesHealthyRequest) (*rpcpb.WaitNodesHealthyResponse, error) {
+	zap.L().Info("received wait nodes healthy request", zap.Strings("names", req.Names), zap.Stringp("timeout", req.Timeout))
+	if err := checkNodeNames(req.Names); err != nil {
+		return nil, err
+	}
+	timeout := healthyWait
+	if req.Timeout != nil {
+		d, err := time.ParseDuration(req.GetTimeout())
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("%w: invalid timeout %q", ErrInvalidDuration, req.GetTimeout())
+		}
+		timeout = d
+	}
+	if info := s.getClusterInfo(); info == nil {
+		return nil, ErrNotBootstrapped
+	}
+	if err := s.checkState(stateBootstrapping, stateHealthy, stateDegraded); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	nw := s.network
+	if nw == nil {
+		s.mu.RUnlock()
+		return nil, ErrNotBootstrapped
+	}
+	all := nw.nodeProcesses()
+	procs := make(map[string]nodeProcess, len(req.Names))
+	for _, name := range req.Names {
+		proc, ok := all[name]
+		if !ok {
+			s.mu.RUnlock()
+			return nil, fmt.Errorf("%w: %q", ErrNodeNotFound, name)
+		}
+		procs[name] = proc
+	}
+	s.mu.RUnlock()
+
+	// waits without the lock, as "Health"
+	nodes, err := nw.awaitNodesHealthy(ctx, procs, timeout)
+	if err != nil {
+		if errors.Is(err, errAborted) || ctx.Err() != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %v: %v", ErrNodesUnhealthy, req.Names, err)
+	}
+
+	s.mu.Lock()
+	// the network may have been stopped (and replaced) during the wait
+	if s.network != nw {
+		s.mu.Unlock()
+		return nil, errAborted
+	}
+	nodeInfos := make(map[string]*rpcpb.NodeInfo, len(nodes))
+	for name, nd := range nodes {
+		// removed during the wait
+		if _, ok := nw.nodeInfos[name]; !ok {
+			delete(nodes, name)
+			continue
+		}
+		if nw.nodes == nil {
+			nw.nodes = make(map[string]node.Node)
+		}
+		nw.nodes[name] = nd
+	}
+	nw.setNodeAPIs(nodes)
+	for name := range nodes {
+		nodeInfos[name] = proto.Clone(nw.nodeInfos[name]).(*rpcpb.NodeInfo)
+	}
+	info := s.copyClusterInfo()
+	s.mu.Unlock()
+
+	// probing the nodes outside the lock, as they may be slow to respond
+	return &rpcpb.WaitNodesHealthyResponse{ClusterInfo: info, NodeHealth: probeNodes(ctx, nodeInfos)}, nil
+}
+
+// awaitNodesHealthy waits for the nodes of the processes to report
+// healthy, within the timeout, and returns them. Unlike "awaitHealthy",
+// the network is not checked as a whole.
+func (lc *localNetwork) awaitNodesHealthy(ctx context.Context, procs map[string]nodeProcess, timeout time.Duration) (map[string]node.Node, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	go func() {
+		select {
+		case <-lc.stopCtx.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	if err := lc.pollHealthy(ctx, procs); err != nil {
+		if lc.stopCtx.Err() != nil {
+			return nil, errAborted
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			return nil, fmt.Errorf("not healthy within %v", timeout)
+		}
+		return nil, err
+	}
+	nodes := make(map[string]node.Node, len(procs))
+	for name := range procs {
+		nd, err := lc.nw.GetNode(name)
+		if err != nil {
+			return nil, err
+		}
+		nodes[name] = nd
+	}
+	return nodes, nil
+}
@@ -0,0 +1,323 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/lasthyphen/djtx-tester/pkg/jsonrpc"
+	"github.com/lasthyphen/djtx-tester/rpcpb"
+	"go.uber.org/zap"
+)
+
+// lifecycle events sent to the webhooks
+const (
+	EventNetworkHealthy  = "network_healthy"
+	EventNetworkDegraded = "network_degraded"
+	EventNetworkErrored  = "network_errored"
+	EventNetworkStopped  = "network_stopped"
+	EventNodeCrashed     = "node_crashed"
+	EventNodeExited      = "node_exited"
+)
+
+const (
+	webhookTimeout  = 5 * time.Second
+	webhookAttempts = 3
+	// events not yet sent, beyond which the new ones are dropped
+	webhookQueueSize = 256
+
+	crashPollInterval = 5 * time.Second
+	// consecutive failed probes of a node before it is reported crashed
+	crashProbeFailures = 2
+)
+
+var ErrInvalidWebhook = errors.New("invalid webhook")
+
+// events of the network states
+var stateEvents = map[rpcpb.NetworkState]string{
+	stateHealthy:  EventNetworkHealthy,
+	stateDegraded: EventNetworkDegraded,
+	stateErrored:  EventNetworkErrored,
+	stateStopped:  EventNetworkStopped,
+}
+
+// Webhook receives the lifecycle events as HTTP POST requests,
+// with a JSON payload.
+type Webhook struct {
+	URL string `json:"url"`
+	// events to send, all if empty
+	Events []string `json:"events"`
+	// added to the requests (e.g., "Authorization")
+	Headers map[string]string `json:"headers"`
+}
+
+type webhookEvent struct {
+	Event       string `json:"event"`
+	Time        string `json:"time"`
+	Tenant      string `json:"tenant,omitempty"`
+	RootDataDir string `json:"rootDataDir"`
+	NodeName    string `json:"nodeName,omitempty"`
+	Error       string `json:"error,omitempty"`
+	// of the "node_exited" events
+	Exit *webhookExit `json:"exit,omitempty"`
+}
+
+type webhookExit struct {
+	ExitCode   int32    `json:"exitCode"`
+	Signal     string   `json:"signal,omitempty"`
+	StderrTail []string `json:"stderrTail,omitempty"`
+}
+
+// webhooks sends the events to the webhooks in the background, so a slow
+// receiver does not block the network. A nil webhooks sends nothing.
+type webhooks struct {
+	hooks  []Webhook
+	cli    *http.Client
+	eventc chan webhookEvent
+}
+
+func newWebhooks(hooks []Webhook) (*webhooks, error) {
+	if len(hooks) == 0 {
+		return nil, nil
+	}
+	hooks = append([]Webhook(nil), hooks...)
+	for i, h := range hooks {
+		// e.g., "Authorization: env:WEBHOOK_AUTH"
+		headers := make(map[string]string, len(h.Headers))
+		for k, v := range h.Headers {
+			s, err := resolveSecret(v)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %q: header %q: %v", ErrInvalidWebhook, h.URL, k, err)
+			}
+			headers[k] = s
+		}
+		hooks[i].Headers = headers
+
+		u, err := url.Parse(h.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("%w: invalid URL %q", ErrInvalidWebhook, h.URL)
+		}
+		for _, ev := range h.Events {
+			if !isWebhookEvent(ev) {
+				return nil, fmt.Errorf("%w: %q: unknown event %q", ErrInvalidWebhook, h.URL, ev)
+			}
+		}
+	}
+	zap.L().Info("loaded webhooks", zap.Int("webhooks", len(hooks)))
+	w := &webhooks{
+		hooks:  hooks,
+		cli:    &http.Client{Timeout: webhookTimeout},
+		eventc: make(chan webhookEvent, webhookQueueSize),
+	}
+	go w.sendLoop()
+	return w, nil
+}
+
+func isWebhookEvent(ev string) bool {
+	if ev == EventNodeCrashed || ev == EventNodeExited {
+		return true
+	}
+	for _, e := range stateEvents {
+		if e == ev {
+			return true
+		}
+	}
+	return false
+}
+
+// wants returns true if a webhook subscribes to the event.
+func (w *webhooks) wants(ev string) bool {
+	if w == nil {
+		return false
+	}
+	for _, h := range w.hooks {
+		if subscribes(h, ev) {
+			return true
+		}
+	}
+	return false
+}
+
+func subscribes(h Webhook, ev string) bool {
+	if len(h.Events) == 0 {
+		return true
+	}
+	for _, e := range h.Events {
+		if e == ev {
+			return true
+		}
+	}
+	return false
+}
+
+// send queues the event of the network (and node, if any).
+func (w *webhooks) send(ev string, opts networkOptions, nodeName string, err error) {
+	if !w.wants(ev) {
+		return
+	}
+	e := webhookEvent{
+		Event:       ev,
+		Time:        time.Now().UTC().Format(time.RFC3339Nano),
+		Tenant:      opts.tenant,
+		RootDataDir: opts.rootDataDir,
+		NodeName:    nodeName,
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	w.queue(e)
+}
+
+// sendExit queues the "node_exited" event of the node.
+func (w *webhooks) sendExit(opts networkOptions, nodeName string, exit *rpcpb.NodeExit) {
+	if !w.wants(EventNodeExited) {
+		return
+	}
+	w.queue(webhookEvent{
+		Event:       EventNodeExited,
+		Time:        time.Now().UTC().Format(time.RFC3339Nano),
+		Tenant:      opts.tenant,
+		RootDataDir: opts.rootDataDir,
+		NodeName:    nodeName,
+		Exit: &webhookExit{
+			ExitCode:   exit.ExitCode,
+			Signal:     exit.Signal,
+			StderrTail: exit.StderrTail,
+		},
+	})
+}
+
+func (w *webhooks) queue(e webhookEvent) {
+	select {
+	case w.eventc <- e:
+	default:
+		zap.L().Warn("dropping webhook event, too many pending", zap.String("event", e.Event))
+	}
+}
+
+func (w *webhooks) sendLoop() {
+	for e := range w.eventc {
+		b, err := json.Marshal(e)
+		if err != nil {
+			zap.L().Warn("failed to encode webhook event", zap.Error(err))
+			continue
+		}
+		for _, h := range w.hooks {
+			if !subscribes(h, e.Event) {
+				continue
+			}
+			if err := w.post(h, b); err != nil {
+				zap.L().Warn("failed to send webhook event",
+					zap.String("url", h.URL),
+					zap.String("event", e.Event),
+					zap.Error(err),
+				)
+			}
+		}
+	}
+}
+
+// post sends the payload, retrying on failures with a backoff.
+func (w *webhooks) post(h Webhook, b []byte) (err error) {
+	backoff := time.Second
+	for i := 0; i < webhookAttempts; i++ {
+		if i > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+		if err = w.postOnce(h, b); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+func (w *webhooks) postOnce(h Webhook, b []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range h.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := w.cli.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status %q", resp.Status)
+	}
+	return nil
+}
+
+// watchCrashes probes the node APIs until the network stops, and sends a
+// "node_crashed" event for each node that stops replying while the network
+// is healthy or degraded (e.g., after a fault injection). A node is reported
+// once, until it replies again (e.g., restarted).
+func (s *server) watchCrashes(nw *localNetwork) {
+	failures := make(map[string]int)
+	tc := time.NewTicker(crashPollInterval)
+	defer tc.Stop()
+	for {
+		select {
+		case <-nw.stopCtx.Done():
+			return
+		case <-tc.C:
+		}
+
+		s.mu.RLock()
+		if s.network != nw {
+			s.mu.RUnlock()
+			return
+		}
+		state, _ := nw.lifecycle.get()
+		uris := make(map[string]string, len(nw.nodeInfos))
+		for name, info := range nw.nodeInfos {
+			if info.Uri != "" {
+				uris[name] = info.Uri
+			}
+		}
+		s.mu.RUnlock()
+		// restarting nodes are expected to be unreachable
+		if state != stateHealthy && state != stateDegraded {
+			continue
+		}
+
+		for name := range failures {
+			if _, ok := uris[name]; !ok {
+				delete(failures, name)
+			}
+		}
+		for name, uri := range uris {
+			ctx, cancel := context.WithTimeout(nw.stopCtx, nodeProbeTimeout)
+			// ref. api/info "GetNodeID"
+			err := jsonrpc.Call(ctx, uri, "/ext/info", "info.getNodeID", nil, nil)
+			cancel()
+			if nw.stopCtx.Err() != nil {
+				return
+			}
+			if err == nil {
+				failures[name] = 0
+				continue
+			}
+			failures[name]++
+			if failures[name] != crashProbeFailures {
+				continue
+			}
+			zap.L().Warn("node crashed", zap.String("name", name), zap.Error(err))
+			nw.opts.webhooks.send(EventNodeCrashed, nw.opts, name, fmt.Errorf("API unreachable: %w", err))
+		}
+	}
+}
@@ -12,6 +12,7 @@ import (
 
 	"github.com/lasthyphen/djtx-tester/client"
 	"github.com/lasthyphen/djtx-tester/pkg/color"
+	"github.com/lasthyphen/djtx-tester/pkg/ginkgoreport"
 	"github.com/lasthyphen/djtx-tester/pkg/logutil"
 	ginkgo "github.com/onsi/ginkgo/v2"
 	"github.com/onsi/gomega"
@@ -65,6 +66,9 @@ func init() {
 
 var cli client.Client
 
+// the network status and node logs of the failed specs
+var _ = ginkgoreport.ReportFailures(func() client.Client { return cli })
+
 var _ = ginkgo.BeforeSuite(func() {
 	var err error
 	cli, err = client.New(client.Config{